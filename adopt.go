@@ -0,0 +1,197 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// AdoptOptions configures DB.AdoptFiles.
+type AdoptOptions struct {
+	// Move removes the source files once they have been adopted, transferring
+	// their ownership to the DB.
+	Move bool
+}
+
+// AdoptFiles ingests the sstables at the given paths (see Ingest) without
+// copying their contents, except for tables in the RocksDB table format (see
+// below). The files must reside on the same filesystem as the
+// DB, so that they can be hard linked into it; AdoptFiles returns an error
+// instead of falling back to copying the files like Ingest does. This allows
+// fast migration of data from another Pebble or RocksDB instance on the same
+// filesystem.
+//
+// Before any file is linked, AdoptFiles verifies that each table was written
+// with the DB's comparer (and merger, if the table contains merge operands),
+// and that its table format can be read at the DB's format major version.
+// Tables in the RocksDB table format are converted to the equivalent Pebble
+// format by rewriting their footer. Since a hard link shares its contents with
+// the source file, these tables are copied into the DB instead of being linked,
+// and the source files are left untouched.
+//
+// Unlike with Ingest, the keys of the tables may have non-zero sequence
+// numbers, as in tables copied out of a live DB: the DB assigns the ingestion
+// sequence number to all the keys of a table through its metadata. This
+// requires that the keys of such a table don't shadow each other, so a table
+// with non-zero sequence numbers is rejected if it contains several keys with
+// the same user key, range deletions or range keys.
+func (d *DB) AdoptFiles(
+	ctx context.Context, paths []string, opts AdoptOptions,
+) (IngestOperationStats, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return IngestOperationStats{}, ErrReadOnly
+	}
+
+	rewrite := make([]bool, len(paths))
+	for i := range paths {
+		var err error
+		if rewrite[i], err = d.adoptCheckTable(ctx, paths[i]); err != nil {
+			return IngestOperationStats{}, errors.Wrapf(err, "pebble: cannot adopt %s", paths[i])
+		}
+	}
+
+	// Link the files into the DB directory under temporary names. Linking fails
+	// if the files reside on a different filesystem. Temporary files are
+	// removed when the DB is opened, so a crash doesn't leak the links.
+	fs := d.opts.FS
+	staged := make([]string, 0, len(paths))
+	defer func() {
+		for _, path := range staged {
+			if err := fs.Remove(path); err != nil && !oserror.IsNotExist(err) {
+				d.opts.Logger.Errorf("adopt cleanup failed: %v", err)
+			}
+		}
+	}()
+	for i := range paths {
+		path := base.MakeFilepath(fs, d.dirname, base.FileTypeTemp, d.mu.versions.getNextDiskFileNum())
+		if !rewrite[i] {
+			if err := fs.Link(paths[i], path); err != nil {
+				return IngestOperationStats{}, errors.Wrapf(err,
+					"pebble: cannot adopt %s: it must reside on the same filesystem as the DB", paths[i])
+			}
+			staged = append(staged, path)
+			continue
+		}
+		// Convert the footer of a RocksDB table on a copy, so that the source
+		// file remains readable by RocksDB.
+		if err := vfs.Copy(fs, paths[i], path); err != nil {
+			return IngestOperationStats{}, errors.Wrapf(err, "pebble: cannot adopt %s", paths[i])
+		}
+		staged = append(staged, path)
+		if err := adoptRewriteFooter(fs, path, sstable.TableFormatPebblev1); err != nil {
+			return IngestOperationStats{}, errors.Wrapf(err, "pebble: cannot adopt %s", paths[i])
+		}
+	}
+
+	stats, err := d.ingest(ctx, staged, nil /* streams */, nil /* shared */, KeyRange{}, nil /* external */, true /* allowSeqNums */, IngestOptions{})
+	if err != nil {
+		return IngestOperationStats{}, err
+	}
+	if opts.Move {
+		for i := range paths {
+			if err := fs.Remove(paths[i]); err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// adoptCheckTable verifies that the table at the given path can be adopted by
+// the DB. It returns whether the footer of the table needs to be rewritten.
+func (d *DB) adoptCheckTable(ctx context.Context, path string) (rewriteFooter bool, err error) {
+	f, err := d.opts.FS.Open(path)
+	if err != nil {
+		return false, err
+	}
+	readable, err := sstable.NewSimpleReadable(f)
+	if err != nil {
+		return false, err
+	}
+	r, err := sstable.NewReader(ctx, readable, d.opts.MakeReaderOptions())
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	if name := r.Properties.ComparerName; name != "" && name != d.opts.Comparer.Name {
+		return false, errors.Newf("table was written with comparer %q, but the DB uses comparer %q",
+			name, d.opts.Comparer.Name)
+	}
+	if name := r.Properties.MergerName; r.Properties.NumMergeOperands > 0 && name != d.opts.Merger.Name {
+		return false, errors.Newf("table contains merge operands of merger %q, but the DB uses merger %q",
+			name, d.opts.Merger.Name)
+	}
+	tf, err := r.TableFormat()
+	if err != nil {
+		return false, err
+	}
+	fmv := d.FormatMajorVersion()
+	if tf == sstable.TableFormatRocksDBv2 && fmv.MinTableFormat() > tf {
+		rewriteFooter = true
+	} else if tf < fmv.MinTableFormat() || tf > fmv.MaxTableFormat() {
+		return false, errors.Newf("table format %s is not within range supported at DB format major version %d, (%s,%s)",
+			tf, fmv, fmv.MinTableFormat(), fmv.MaxTableFormat())
+	}
+	if err := d.adoptCheckSeqNums(r); err != nil {
+		return false, err
+	}
+	return rewriteFooter, nil
+}
+
+// adoptCheckSeqNums verifies that if the keys of the table have non-zero
+// sequence numbers, they don't shadow each other, so that they can all be
+// assigned the ingestion sequence number.
+func (d *DB) adoptCheckSeqNums(r *sstable.Reader) error {
+	iter, err := r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	var seqNumKey, dupKey []byte
+	var prevKey []byte
+	for kv := iter.First(); kv != nil; kv = iter.Next() {
+		if kv.SeqNum() != 0 && seqNumKey == nil {
+			seqNumKey = slices.Clone(kv.K.UserKey)
+		}
+		if prevKey != nil && dupKey == nil && d.cmp(prevKey, kv.K.UserKey) == 0 {
+			dupKey = slices.Clone(kv.K.UserKey)
+		}
+		prevKey = append(prevKey[:0], kv.K.UserKey...)
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if seqNumKey == nil {
+		return nil
+	}
+	switch {
+	case dupKey != nil:
+		return errors.Newf("table has keys with non-zero sequence numbers (e.g. %s) and several keys with user key %s",
+			d.opts.Comparer.FormatKey(seqNumKey), d.opts.Comparer.FormatKey(dupKey))
+	case r.Properties.NumRangeDeletions > 0 || r.Properties.NumRangeKeys() > 0:
+		return errors.Newf("table has keys with non-zero sequence numbers (e.g. %s) and range deletions or range keys",
+			d.opts.Comparer.FormatKey(seqNumKey))
+	}
+	return nil
+}
+
+func adoptRewriteFooter(fs vfs.FS, path string, format sstable.TableFormat) error {
+	f, err := fs.OpenReadWrite(path, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	return errors.CombineErrors(sstable.RewriteFooterFormat(f, format), f.Close())
+}
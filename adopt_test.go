@@ -0,0 +1,132 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptFiles(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("db", &Options{FS: mem})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	writeTable := func(path string, format sstable.TableFormat, comparer *base.Comparer, key string) {
+		f, err := mem.Create(path, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+			Comparer:    comparer,
+			TableFormat: format,
+		})
+		require.NoError(t, w.Set([]byte(key), []byte("v-"+key)))
+		require.NoError(t, w.Close())
+	}
+	fileContents := func(path string) []byte {
+		f, err := mem.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		stat, err := f.Stat()
+		require.NoError(t, err)
+		buf := make([]byte, stat.Size())
+		_, err = f.ReadAt(buf, 0)
+		require.NoError(t, err)
+		return buf
+	}
+	requireValue := func(key string) {
+		t.Helper()
+		v, closer, err := d.Get([]byte(key))
+		require.NoError(t, err)
+		require.Equal(t, "v-"+key, string(v))
+		require.NoError(t, closer.Close())
+	}
+	ctx := context.Background()
+
+	// A Pebble table is linked into the DB, and left in place.
+	writeTable("a.sst", d.TableFormat(), DefaultComparer, "a")
+	_, err = d.AdoptFiles(ctx, []string{"a.sst"}, AdoptOptions{})
+	require.NoError(t, err)
+	requireValue("a")
+	_, err = mem.Stat("a.sst")
+	require.NoError(t, err)
+
+	// A table written with another comparer is rejected.
+	otherComparer := *DefaultComparer
+	otherComparer.Name = "other-comparer"
+	writeTable("b.sst", d.TableFormat(), &otherComparer, "b")
+	_, err = d.AdoptFiles(ctx, []string{"b.sst"}, AdoptOptions{})
+	require.ErrorContains(t, err, "other-comparer")
+
+	// A RocksDB table is copied into the DB, since its footer is rewritten,
+	// and the source table is left untouched.
+	writeTable("c.sst", sstable.TableFormatRocksDBv2, DefaultComparer, "c")
+	orig := fileContents("c.sst")
+	writeTable("d.sst", sstable.TableFormatRocksDBv2, DefaultComparer, "c")
+	// The ingestion fails because the tables overlap.
+	_, err = d.AdoptFiles(ctx, []string{"c.sst", "d.sst"}, AdoptOptions{Move: true})
+	require.Error(t, err)
+	require.True(t, bytes.Equal(orig, fileContents("c.sst")))
+	_, err = d.AdoptFiles(ctx, []string{"c.sst"}, AdoptOptions{})
+	require.NoError(t, err)
+	requireValue("c")
+	require.True(t, bytes.Equal(orig, fileContents("c.sst")))
+
+	writeTable("e.sst", sstable.TableFormatRocksDBv2, DefaultComparer, "e")
+	_, err = d.AdoptFiles(ctx, []string{"e.sst"}, AdoptOptions{Move: true})
+	require.NoError(t, err)
+	requireValue("e")
+	_, err = mem.Stat("e.sst")
+	require.Error(t, err)
+
+	// The tables of another DB have non-zero sequence numbers. They can be
+	// adopted as long as their keys don't shadow each other.
+	src, err := Open("src", &Options{FS: mem, DisableAutomaticCompactions: true})
+	require.NoError(t, err)
+	require.NoError(t, src.Set([]byte("f"), []byte("v-f"), nil))
+	require.NoError(t, src.Set([]byte("g"), []byte("v-g"), nil))
+	require.NoError(t, src.Flush())
+	snap := src.NewSnapshot()
+	require.NoError(t, src.Set([]byte("h"), []byte("v-h-old"), nil))
+	snap2 := src.NewSnapshot()
+	require.NoError(t, src.Set([]byte("h"), []byte("v-h"), nil))
+	require.NoError(t, src.Flush())
+	require.NoError(t, snap.Close())
+	require.NoError(t, snap2.Close())
+	var srcTables []string
+	levels, err := src.SSTables()
+	require.NoError(t, err)
+	for _, l := range levels {
+		for _, info := range l {
+			srcTables = append(srcTables, mem.PathJoin("src", base.MakeFilename(base.FileTypeTable, base.PhysicalTableDiskFileNum(info.FileNum))))
+		}
+	}
+	require.NoError(t, src.Close())
+	slices.Sort(srcTables)
+	require.Len(t, srcTables, 2)
+
+	_, err = d.AdoptFiles(ctx, srcTables[1:], AdoptOptions{})
+	require.ErrorContains(t, err, "several keys with user key h")
+	_, err = d.AdoptFiles(ctx, srcTables[:1], AdoptOptions{})
+	require.NoError(t, err)
+	requireValue("f")
+	requireValue("g")
+
+	// The temporary links are removed.
+	ls, err := mem.List("db")
+	require.NoError(t, err)
+	for _, name := range ls {
+		fileType, _, ok := base.ParseFilename(mem, name)
+		require.False(t, ok && fileType == base.FileTypeTemp, name)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "time"
+
+const (
+	// ampWindowBucketDuration is the granularity at which the windowed
+	// amplification metrics advance.
+	ampWindowBucketDuration = time.Minute
+	// ampWindowBuckets is the number of buckets making up the window over which
+	// the windowed amplification metrics are computed.
+	ampWindowBuckets = 10
+)
+
+// WindowedAmpMetrics contains write and read amplification computed over a
+// recent window of time (approximately the last 10 minutes), rather than since
+// the DB was opened. Unlike the cumulative per-level metrics, which are
+// dominated by the history of the DB, they reflect the current behavior of the
+// LSM.
+type WindowedAmpMetrics struct {
+	// Window is the duration covered by the metrics. It's shorter than the
+	// full window shortly after the DB is opened, and may be longer if the DB
+	// has been idle.
+	Window time.Duration
+	// BytesIn is the number of bytes written to the WAL or ingested during the
+	// window.
+	BytesIn uint64
+	// WriteAmp is the number of bytes written to the WAL, ingested, flushed
+	// and compacted during the window, divided by BytesIn.
+	WriteAmp float64
+	// ReadAmp is the time-weighted average read amplification (as returned by
+	// Metrics.ReadAmp) during the window.
+	ReadAmp float64
+	// Levels contains the per-level write amplification during the window.
+	Levels [numLevels]struct {
+		// BytesIn is the number of incoming bytes from other levels (or the
+		// memtables for L0) read by compactions into the level.
+		BytesIn uint64
+		// BytesWritten is the number of bytes flushed and compacted into the
+		// level.
+		BytesWritten uint64
+		// WriteAmp is BytesWritten divided by BytesIn.
+		WriteAmp float64
+	}
+	// WriteAmpByKind attributes the write amplification to the kind of
+	// compaction (e.g. "flush", "default", "rewrite") that wrote the bytes: it
+	// maps each kind to the bytes written by such compactions during the
+	// window, divided by BytesIn. Adding one to the sum of the values (for the
+	// bytes written to the WAL or ingested) approximately yields WriteAmp.
+	WriteAmpByKind map[string]float64
+}
+
+// ampCounters contains the cumulative counters from which the windowed
+// amplification metrics are derived.
+type ampCounters struct {
+	at      time.Time
+	bytesIn uint64
+	levels  [numLevels]struct {
+		bytesIn      uint64
+		bytesWritten uint64
+	}
+	kindBytes [compactionKindSuggested + 1]uint64
+	// readAmpNanos is the integral of the read amplification over time.
+	readAmpNanos float64
+}
+
+// ampWindow tracks the cumulative amplification counters and keeps snapshots
+// of them taken at most every ampWindowBucketDuration. The windowed metrics
+// are the difference between the current counters and the oldest snapshot.
+//
+// ampWindow is protected by DB.mu.
+type ampWindow struct {
+	cur ampCounters
+	// readAmp is the read amplification since cur.at.
+	readAmp int
+	// snapshots is a ring buffer of the counters, ordered from oldest to newest
+	// starting at index start. It holds one snapshot more than the number of
+	// buckets, so that the oldest one marks the start of the window.
+	snapshots [ampWindowBuckets + 1]ampCounters
+	start     int
+	n         int
+}
+
+// recordCompaction records the bytes written by a completed flush or
+// compaction of the given kind.
+func (w *ampWindow) recordCompaction(kind compactionKind, bytesWritten uint64) {
+	w.cur.kindBytes[kind] += bytesWritten
+}
+
+// update advances the counters to now. m contains the cumulative metrics of
+// the DB, and bytesIn the cumulative bytes written to the WAL.
+func (w *ampWindow) update(now time.Time, m *Metrics, bytesIn uint64) {
+	if !w.cur.at.IsZero() && now.After(w.cur.at) {
+		w.cur.readAmpNanos += float64(w.readAmp) * float64(now.Sub(w.cur.at))
+	}
+	w.cur.at = now
+	w.readAmp = m.ReadAmp()
+	w.cur.bytesIn = bytesIn
+	for i := range m.Levels {
+		l := &m.Levels[i]
+		w.cur.bytesIn += l.BytesIngested
+		w.cur.levels[i].bytesIn = l.BytesIn
+		w.cur.levels[i].bytesWritten = l.BytesFlushed + l.BytesCompacted
+	}
+
+	if w.n > 0 && now.Sub(w.snapshots[(w.start+w.n-1)%len(w.snapshots)].at) < ampWindowBucketDuration {
+		return
+	}
+	if w.n == len(w.snapshots) {
+		w.start = (w.start + 1) % len(w.snapshots)
+		w.n--
+	}
+	w.snapshots[(w.start+w.n)%len(w.snapshots)] = w.cur
+	w.n++
+	// Drop snapshots that fall entirely outside of the window; the oldest
+	// retained snapshot is the newest one at or before the window's start.
+	for w.n > 1 && now.Sub(w.snapshots[(w.start+1)%len(w.snapshots)].at) >= ampWindowBuckets*ampWindowBucketDuration {
+		w.start = (w.start + 1) % len(w.snapshots)
+		w.n--
+	}
+}
+
+// metrics returns the windowed metrics as of the last update.
+func (w *ampWindow) metrics() WindowedAmpMetrics {
+	var m WindowedAmpMetrics
+	if w.n == 0 {
+		return m
+	}
+	base := &w.snapshots[w.start]
+	m.Window = w.cur.at.Sub(base.at)
+	m.BytesIn = w.cur.bytesIn - base.bytesIn
+	if m.Window > 0 {
+		m.ReadAmp = (w.cur.readAmpNanos - base.readAmpNanos) / float64(m.Window)
+	}
+	bytesWritten := m.BytesIn
+	for i := range m.Levels {
+		l := &m.Levels[i]
+		l.BytesIn = w.cur.levels[i].bytesIn - base.levels[i].bytesIn
+		l.BytesWritten = w.cur.levels[i].bytesWritten - base.levels[i].bytesWritten
+		if l.BytesIn > 0 {
+			l.WriteAmp = float64(l.BytesWritten) / float64(l.BytesIn)
+		}
+		bytesWritten += l.BytesWritten
+	}
+	if m.BytesIn == 0 {
+		return m
+	}
+	m.WriteAmp = float64(bytesWritten) / float64(m.BytesIn)
+	for k := range w.cur.kindBytes {
+		if b := w.cur.kindBytes[k] - base.kindBytes[k]; b > 0 {
+			if m.WriteAmpByKind == nil {
+				m.WriteAmpByKind = make(map[string]float64)
+			}
+			m.WriteAmpByKind[compactionKind(k).String()] = float64(b) / float64(m.BytesIn)
+		}
+	}
+	return m
+}
+
+// updateAmpWindowLocked advances the windowed amplification metrics.
+//
+// d.mu must be held when calling this.
+func (d *DB) updateAmpWindowLocked() {
+	d.mu.ampWindow.update(d.timeNow(), &d.mu.versions.metrics, d.logBytesIn.Load())
+}
+
+// recordCompactionBytesLocked attributes the bytes written by a completed
+// flush or compaction to its kind in the windowed amplification metrics.
+//
+// d.mu must be held when calling this.
+func (d *DB) recordCompactionBytesLocked(c *compaction) {
+	var bytesWritten uint64
+	for _, l := range c.metrics {
+		bytesWritten += l.BytesFlushed + l.BytesCompacted
+	}
+	d.mu.ampWindow.recordCompaction(c.kind, bytesWritten)
+}
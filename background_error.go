@@ -0,0 +1,112 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrWritesPaused is returned by write operations while the DB is paused after
+// a background error (see Options.BackgroundErrorRecovery). The returned error
+// also wraps the background error. Use errors.Is(err, ErrWritesPaused) to check
+// for this error.
+var ErrWritesPaused = errors.New("pebble: writes paused after background error")
+
+// Resume resumes the background work and the writes of a DB that was paused
+// after a background error (see Options.BackgroundErrorRecovery). The flushes
+// and compactions that failed are rescheduled; if they fail again, the DB goes
+// through the retries configured by Options.BackgroundErrorRecovery before
+// pausing again. Resume is a no-op if the DB is not paused.
+func (d *DB) Resume() error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pausedErr.Load() == nil {
+		return nil
+	}
+	d.opts.Logger.Infof("resuming writes and background work")
+	d.pausedErr.Store(nil)
+	d.mu.bgErr.consecutiveFailures = 0
+	d.maybeScheduleFlush()
+	d.maybeScheduleCompaction()
+	d.mu.compact.cond.Broadcast()
+	return nil
+}
+
+// writesPausedErr returns the error that write operations must return while
+// the DB is paused after a background error, or nil if it's not paused.
+func (d *DB) writesPausedErr() error {
+	if err := d.pausedErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// backgroundWorkSuspended returns true while background work is backing off or
+// is paused after a background error.
+//
+// d.mu must be held when calling this.
+func (d *DB) backgroundWorkSuspended() bool {
+	return d.mu.bgErr.retryTimer != nil || d.pausedErr.Load() != nil
+}
+
+// handleBackgroundError reports an error encountered by a flush or compaction,
+// and, if Options.BackgroundErrorRecovery is set, either schedules a retry of
+// the background work after a backoff or pauses the DB.
+//
+// d.mu must be held when calling this.
+func (d *DB) handleBackgroundError(err error) {
+	d.opts.EventListener.BackgroundError(err)
+	o := d.opts.BackgroundErrorRecovery
+	if o == nil || d.pausedErr.Load() != nil {
+		return
+	}
+	d.mu.bgErr.consecutiveFailures++
+	n := d.mu.bgErr.consecutiveFailures
+	if !o.IsRetryable(err) || (o.MaxRetries >= 0 && n > o.MaxRetries) {
+		pausedErr := errors.Mark(errors.Wrap(err, "pebble: writes paused after background error"), ErrWritesPaused)
+		d.pausedErr.Store(&pausedErr)
+		d.opts.Logger.Errorf("pausing writes and background work after %d consecutive background errors: %v", n, err)
+		return
+	}
+	if d.mu.bgErr.retryTimer != nil {
+		// A retry is already scheduled.
+		return
+	}
+	backoff := o.InitialBackoff
+	for i := 1; i < n && backoff < o.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, o.MaxBackoff)
+	d.mu.bgErr.retryTimer = time.AfterFunc(backoff, d.retryBackgroundWork)
+}
+
+// handleBackgroundSuccess records the success of a flush or compaction.
+//
+// d.mu must be held when calling this.
+func (d *DB) handleBackgroundSuccess() {
+	d.mu.bgErr.consecutiveFailures = 0
+}
+
+// retryBackgroundWork reschedules the background work once the backoff after a
+// background error has elapsed.
+func (d *DB) retryBackgroundWork() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.bgErr.retryTimer = nil
+	if d.closed.Load() != nil {
+		return
+	}
+	d.maybeScheduleFlush()
+	d.maybeScheduleCompaction()
+	d.mu.compact.cond.Broadcast()
+}
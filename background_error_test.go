@@ -0,0 +1,108 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/errorfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundErrorRecovery(t *testing.T) {
+	// sstErr, if set, is returned when creating sstables.
+	var sstErr atomic.Pointer[error]
+	fs := errorfs.Wrap(vfs.NewMem(), errorfs.InjectorFunc(func(op errorfs.Op) error {
+		if err := sstErr.Load(); err != nil && op.Kind == errorfs.OpCreate && strings.HasSuffix(op.Path, ".sst") {
+			return *err
+		}
+		return nil
+	}))
+	setSSTErr := func(err error) {
+		if err == nil {
+			sstErr.Store(nil)
+		} else {
+			sstErr.Store(&err)
+		}
+	}
+	var bgErrors atomic.Int32
+	d, err := Open("", &Options{
+		FS:     fs,
+		Logger: testLogger{t},
+		BackgroundErrorRecovery: &BackgroundErrorRecoveryOptions{
+			InitialBackoff: 10 * time.Millisecond,
+			MaxBackoff:     20 * time.Millisecond,
+			MaxRetries:     3,
+		},
+		EventListener: &EventListener{
+			BackgroundError: func(error) { bgErrors.Add(1) },
+		},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	requireValue := func(key string) {
+		t.Helper()
+		v, closer, err := d.Get([]byte(key))
+		require.NoError(t, err)
+		require.Equal(t, key, string(v))
+		require.NoError(t, closer.Close())
+	}
+	requirePaused := func() {
+		t.Helper()
+		require.Eventually(t, func() bool { return d.writesPausedErr() != nil }, 10*time.Second, time.Millisecond)
+	}
+
+	// A flush that fails with a retryable error is retried, and succeeds once
+	// the error clears.
+	setSSTErr(syscall.ENOSPC)
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	flushed, err := d.AsyncFlush()
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return bgErrors.Load() > 0 }, 10*time.Second, time.Millisecond)
+	setSSTErr(nil)
+	<-flushed
+	require.Nil(t, d.writesPausedErr())
+
+	// Once the retries are exhausted, writes are paused.
+	bgErrors.Store(0)
+	setSSTErr(syscall.ENOSPC)
+	require.NoError(t, d.Set([]byte("b"), []byte("b"), nil))
+	flushed, err = d.AsyncFlush()
+	require.NoError(t, err)
+	requirePaused()
+	require.Equal(t, int32(4), bgErrors.Load())
+	err = d.Set([]byte("c"), []byte("c"), nil)
+	require.True(t, errors.Is(err, ErrWritesPaused))
+	require.True(t, errors.Is(err, syscall.ENOSPC))
+	_, err = d.AsyncFlush()
+	require.True(t, errors.Is(err, ErrWritesPaused))
+	// Reads are still allowed.
+	requireValue("a")
+	requireValue("b")
+
+	// Resuming completes the flush and allows writes.
+	setSSTErr(nil)
+	require.NoError(t, d.Resume())
+	<-flushed
+	require.NoError(t, d.Set([]byte("c"), []byte("c"), nil))
+	requireValue("c")
+
+	// A non-retryable error pauses writes immediately.
+	bgErrors.Store(0)
+	setSSTErr(errors.New("injected error"))
+	_, err = d.AsyncFlush()
+	require.NoError(t, err)
+	requirePaused()
+	require.Equal(t, int32(1), bgErrors.Load())
+	setSSTErr(nil)
+	require.NoError(t, d.Resume())
+	require.NoError(t, d.Flush())
+}
@@ -0,0 +1,267 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// BackgroundJobKind identifies the kind of a BackgroundJob.
+type BackgroundJobKind int8
+
+const (
+	// BackgroundJobFlush is a flush of one or more memtables (or ingested
+	// flushables) to L0.
+	BackgroundJobFlush BackgroundJobKind = iota
+	// BackgroundJobCompaction is a compaction. BackgroundJob.Reason holds the
+	// kind of compaction.
+	BackgroundJobCompaction
+	// BackgroundJobDownload is a compaction started as part of DB.Download.
+	BackgroundJobDownload
+	// BackgroundJobTableStats is the loading of table statistics.
+	BackgroundJobTableStats
+	// BackgroundJobDeleteObsoleteFiles is the deletion of obsolete files.
+	BackgroundJobDeleteObsoleteFiles
+)
+
+// String implements fmt.Stringer.
+func (k BackgroundJobKind) String() string {
+	switch k {
+	case BackgroundJobFlush:
+		return "flush"
+	case BackgroundJobCompaction:
+		return "compaction"
+	case BackgroundJobDownload:
+		return "download"
+	case BackgroundJobTableStats:
+		return "table-stats"
+	case BackgroundJobDeleteObsoleteFiles:
+		return "delete-obsolete-files"
+	default:
+		return fmt.Sprintf("BackgroundJobKind(%d)", int8(k))
+	}
+}
+
+// BackgroundJob describes a running or pending internal job.
+type BackgroundJob struct {
+	// JobID is the ID of the job, as reported to the EventListener. It is
+	// zero for pending jobs that have not been assigned an ID yet.
+	JobID JobID
+	Kind  BackgroundJobKind
+	// Reason further qualifies Kind. For compactions and downloads it is the
+	// kind of compaction (e.g. "default", "move", "delete-only"), and it is
+	// "manual" for pending manual compactions.
+	Reason string
+	// Running is true if the job is in progress, and false if it is queued.
+	Running bool
+	// StartTime is the time at which the job started or, for pending jobs, was
+	// queued. It is zero if unknown.
+	StartTime time.Time
+	// StartLevel and OutputLevel are the input and output levels of flushes
+	// and compactions, and -1 otherwise. StartLevel is -1 for flushes and
+	// OutputLevel is -1 for delete-only compactions.
+	StartLevel  int
+	OutputLevel int
+	// Smallest and Largest are the bounds of the user keys of the inputs of a
+	// running flush or compaction, and are nil otherwise.
+	Smallest, Largest []byte
+	// InputBytes is the total size of the inputs of the job: the memtables
+	// being flushed, the tables being compacted or the files being deleted.
+	InputBytes uint64
+	// BytesRead is the number of bytes of input blocks read so far by a
+	// compaction.
+	BytesRead uint64
+	// BytesWritten is the number of bytes written so far by a compaction.
+	BytesWritten uint64
+	// Progress is an estimate of the fraction of the inputs of a running
+	// compaction that has been processed, in [0, 1], derived from BytesRead and
+	// InputBytes. It is -1 if unknown, which is the case for jobs other than
+	// compactions that rewrite their inputs (e.g. flushes and move
+	// compactions).
+	Progress float64
+	// Cancelable is true if the job can be cancelled through DB.CancelJob.
+	Cancelable bool
+}
+
+// String implements fmt.Stringer.
+func (j BackgroundJob) String() string {
+	state := "pending"
+	if j.Running {
+		state = "running"
+	}
+	s := fmt.Sprintf("[JOB %d] %s", j.JobID, j.Kind)
+	if j.Reason != "" {
+		s += fmt.Sprintf(" (%s)", j.Reason)
+	}
+	s += fmt.Sprintf(" %s: L%d->L%d, %d input bytes, %d bytes read, %d bytes written",
+		state, j.StartLevel, j.OutputLevel, j.InputBytes, j.BytesRead, j.BytesWritten)
+	if j.Progress >= 0 {
+		s += fmt.Sprintf(", %.0f%% done", 100*j.Progress)
+	}
+	return s
+}
+
+// BackgroundJobs returns the flushes, compactions, table stats collection and
+// obsolete file deletions that are running or queued. Running jobs are
+// returned first. The result is a point-in-time snapshot.
+func (d *DB) BackgroundJobs() []BackgroundJob {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	// The cleanup manager's mutex is acquired without holding DB.mu; the
+	// cleanup goroutine acquires DB.mu to update metrics.
+	jobs := d.cleanupManager.jobs()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for c := range d.mu.compact.inProgress {
+		j := BackgroundJob{
+			JobID:        c.jobID,
+			Kind:         BackgroundJobCompaction,
+			Reason:       c.kind.String(),
+			Running:      c.jobID != 0,
+			StartTime:    c.beganAt,
+			StartLevel:   -1,
+			OutputLevel:  -1,
+			BytesRead:    uint64(c.bytesRead.Load()),
+			BytesWritten: uint64(c.bytesWritten.Load()),
+			Progress:     -1,
+		}
+		if j.Running {
+			j.Smallest = slices.Clone(c.smallest.UserKey)
+			j.Largest = slices.Clone(c.largest.UserKey)
+		}
+		switch {
+		case c.kind == compactionKindFlush || c.kind == compactionKindIngestedFlushable:
+			j.Kind = BackgroundJobFlush
+			j.Reason = ""
+			j.OutputLevel = 0
+			for i := range c.flushing {
+				j.InputBytes += c.flushing[i].totalBytes()
+			}
+		default:
+			if c.isDownload {
+				j.Kind = BackgroundJobDownload
+			}
+			if c.startLevel != nil {
+				j.StartLevel = c.startLevel.level
+			}
+			if c.outputLevel != nil {
+				j.OutputLevel = c.outputLevel.level
+			}
+			for i := range c.inputs {
+				j.InputBytes += c.inputs[i].files.SizeSum()
+			}
+			j.Cancelable = j.Running && !c.versionEditApplied
+			if j.Running && j.InputBytes > 0 && c.rewritesInputs() {
+				j.Progress = min(1, float64(j.BytesRead)/float64(j.InputBytes))
+			}
+		}
+		jobs = append(jobs, j)
+	}
+	if d.mu.tableStats.loading {
+		jobs = append(jobs, BackgroundJob{
+			JobID:       d.mu.tableStats.loadingJobID,
+			Kind:        BackgroundJobTableStats,
+			Running:     true,
+			StartTime:   d.mu.tableStats.loadingStartTime,
+			StartLevel:  -1,
+			OutputLevel: -1,
+			Progress:    -1,
+		})
+	}
+	for _, m := range d.mu.compact.manual {
+		jobs = append(jobs, BackgroundJob{
+			Kind:        BackgroundJobCompaction,
+			Reason:      "manual",
+			StartLevel:  m.level,
+			OutputLevel: m.outputLevel,
+			Progress:    -1,
+		})
+	}
+	// Sort running jobs before pending ones, and each by start time. Pending
+	// jobs with an unknown start time retain their queue order.
+	slices.SortStableFunc(jobs, func(a, b BackgroundJob) int {
+		if a.Running != b.Running {
+			if a.Running {
+				return -1
+			}
+			return +1
+		}
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return jobs
+}
+
+// CancelJob cancels the running job with the given ID. Only compactions
+// (including downloads) can be cancelled; flushes, table stats collection and
+// file deletions cannot. Cancellation is asynchronous: the compaction stops at
+// the next opportunity and its outputs are discarded. Note that an automatic
+// compaction that is cancelled may be picked again; automatic compactions can
+// be paused with DB.DisableAutomaticCompactions.
+func (d *DB) CancelJob(id JobID) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Only holders of the manifest lock may cancel a compaction; this ensures
+	// the compaction is not concurrently applying its version edit.
+	d.mu.versions.logLock()
+	defer d.mu.versions.logUnlock()
+	for c := range d.mu.compact.inProgress {
+		if id == 0 || c.jobID != id {
+			continue
+		}
+		switch {
+		case c.kind == compactionKindFlush || c.kind == compactionKindIngestedFlushable:
+			return errors.Errorf("pebble: job %d is a flush and cannot be cancelled", id)
+		case c.versionEditApplied:
+			return errors.Errorf("pebble: job %d has already completed", id)
+		}
+		c.cancel.Store(true)
+		return nil
+	}
+	return errors.Errorf("pebble: job %d is not a running compaction", id)
+}
+
+// rewritesInputs returns true if the compaction reads its input tables and
+// writes new tables, as opposed to e.g. moving or deleting them.
+func (c *compaction) rewritesInputs() bool {
+	switch c.kind {
+	case compactionKindDefault, compactionKindElisionOnly, compactionKindRead,
+		compactionKindTombstoneDensity, compactionKindRewrite, compactionKindSuggested:
+		return true
+	default:
+		return false
+	}
+}
+
+// jobs returns the cleanup jobs that are running or queued.
+func (cm *cleanupManager) jobs() []BackgroundJob {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	jobs := make([]BackgroundJob, 0, len(cm.mu.queue))
+	for _, job := range cm.mu.queue {
+		j := BackgroundJob{
+			JobID:       job.jobID,
+			Kind:        BackgroundJobDeleteObsoleteFiles,
+			Running:     job == cm.mu.running,
+			StartTime:   job.enqueuedAt,
+			StartLevel:  -1,
+			OutputLevel: -1,
+			Progress:    -1,
+		}
+		for _, of := range job.obsoleteFiles {
+			j.InputBytes += of.nonLogFile.fileSize
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
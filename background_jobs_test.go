@@ -0,0 +1,79 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundJobs(t *testing.T) {
+	started := make(chan JobID, 1)
+	unblock := make(chan struct{})
+	var once sync.Once
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+		EventListener: &EventListener{
+			TableCreated: func(info TableCreateInfo) {
+				if info.Reason != "compacting" {
+					return
+				}
+				// Block the first compaction until it has been cancelled.
+				once.Do(func() {
+					started <- JobID(info.JobID)
+					<-unblock
+				})
+			},
+		},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 10; j++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", j)), []byte("v"), nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+
+	compacted := make(chan error, 1)
+	go func() {
+		compacted <- d.Compact([]byte("k"), []byte("l"), false /* parallelize */)
+	}()
+	id := <-started
+
+	jobs := d.BackgroundJobs()
+	require.NotEmpty(t, jobs)
+	j := jobs[0]
+	require.Equal(t, id, j.JobID)
+	require.Equal(t, BackgroundJobCompaction, j.Kind)
+	require.True(t, j.Running)
+	require.True(t, j.Cancelable)
+	require.Equal(t, 0, j.StartLevel)
+	require.NotZero(t, j.InputBytes)
+	require.Equal(t, "k000", string(j.Smallest))
+	require.Equal(t, "k009", string(j.Largest))
+	// The compaction has read its inputs by the time it creates its output.
+	require.NotZero(t, j.BytesRead)
+	require.Greater(t, j.Progress, 0.0)
+	require.LessOrEqual(t, j.Progress, 1.0)
+
+	require.Error(t, d.CancelJob(id+1000))
+	require.NoError(t, d.CancelJob(id))
+	close(unblock)
+
+	// The manual compaction is retried after the cancellation.
+	require.NoError(t, <-compacted)
+	require.EqualValues(t, 1, d.Metrics().Compact.CancelledCount)
+	for _, j := range d.BackgroundJobs() {
+		require.NotEqual(t, BackgroundJobCompaction, j.Kind)
+	}
+}
@@ -333,6 +333,12 @@ type batchInternal struct {
 	// memtable.
 	flushable *flushableBatch
 
+	// The compressed representation of the batch that's appended to the WAL
+	// instead of data, if the batch is large enough to be compressed (see
+	// Options.Experimental.WALBatchCompressionThreshold). Its sequence number
+	// is set when the batch is written to the WAL.
+	walRepr []byte
+
 	// minimumFormatMajorVersion indicates the format major version required in
 	// order to commit this batch. If an operation requires a particular format
 	// major version, it ratchets the batch's minimumFormatMajorVersion. When
@@ -340,6 +346,18 @@ type batchInternal struct {
 	// format major version.
 	minimumFormatMajorVersion FormatMajorVersion
 
+	// priority is the priority of the batch in the commit pipeline. See
+	// Batch.SetPriority.
+	priority BatchPriority
+
+	// idempotencyKey is the idempotency key of the batch. See
+	// Batch.SetIdempotencyKey.
+	idempotencyKey []byte
+
+	// callbacks holds the callbacks registered through Batch.SetWithCallback,
+	// in the order of the operations.
+	callbacks []batchCallback
+
 	// Synchronous Apply uses the commit WaitGroup for both publishing the
 	// seqnum and waiting for the WAL fsync (if needed). Asynchronous
 	// ApplyNoSyncWait, which implies WriteOptions.Sync is true, uses the commit
@@ -415,6 +433,14 @@ type BatchCommitStats struct {
 	// high read amplification in L0 (due to not compacting fast enough out of
 	// L0).
 	L0ReadAmpWriteStallDuration time.Duration
+	// WriteControllerWaitDuration is the wait caused by the WriteController
+	// (see Options.Experimental.WriteController), either delaying the batch
+	// or stalling writes for a reason other than the above.
+	WriteControllerWaitDuration time.Duration
+	// PriorityWaitDuration is the wait before entering the commit pipeline,
+	// caused by the priority of the batch while writes were stalled or about to
+	// be stalled (see Batch.SetPriority).
+	PriorityWaitDuration time.Duration
 	// WALRotationDuration is the wait time for WAL rotation, which includes
 	// syncing and closing the old WAL and creating (or reusing) a new one.
 	WALRotationDuration time.Duration
@@ -673,7 +699,7 @@ func (b *Batch) Get(key []byte) ([]byte, io.Closer, error) {
 	if b.index == nil {
 		return nil, nil, ErrNotIndexed
 	}
-	return b.db.getInternal(key, b, nil /* snapshot */)
+	return b.db.getInternal(context.Background(), key, b, nil /* snapshot */)
 }
 
 func (b *Batch) prepareDeferredKeyValueRecord(keyLen, valueLen int, kind InternalKeyKind) {
@@ -824,6 +850,67 @@ func (b *Batch) Set(key, value []byte, _ *WriteOptions) error {
 	return nil
 }
 
+// SetWithCallback is like Set, but additionally registers fn to be invoked
+// once the batch is committed, with the sequence number assigned to the set
+// operation. This allows streaming producers to acknowledge individual records
+// rather than whole batches.
+//
+// fn is invoked once the batch is committed (and synced, if
+// WriteOptions.Sync is set) by DB.Apply or Batch.Commit, on the committing
+// goroutine. When the batch is committed through DB.ApplyNoSyncWait, fn is
+// invoked by Batch.SyncWait. If the commit fails, fn is invoked with the error
+// and a zero sequence number. The callbacks of a batch are invoked in the order
+// of the operations. They are discarded if the batch is reset or closed
+// without being committed, and are not carried over when the batch is applied
+// to another batch.
+//
+// It is safe to modify the contents of the arguments after SetWithCallback
+// returns.
+func (b *Batch) SetWithCallback(
+	key, value []byte, fn func(err error, seqNum base.SeqNum),
+) error {
+	offset := uint32(b.count)
+	if err := b.Set(key, value, nil); err != nil {
+		return err
+	}
+	b.callbacks = append(b.callbacks, batchCallback{offset: offset, fn: fn})
+	return nil
+}
+
+// batchCallback is a callback registered through Batch.SetWithCallback.
+type batchCallback struct {
+	// offset is the offset of the operation's sequence number from the batch's
+	// sequence number.
+	offset uint32
+	// seqNum is the sequence number of the operation, set once the batch is
+	// committed.
+	seqNum base.SeqNum
+	fn     func(err error, seqNum base.SeqNum)
+}
+
+// assignCallbackSeqNums sets the sequence numbers of the operations with
+// callbacks, once the batch has been assigned its sequence number.
+func (b *Batch) assignCallbackSeqNums() {
+	for i := range b.callbacks {
+		b.callbacks[i].seqNum = b.SeqNum() + base.SeqNum(b.callbacks[i].offset)
+	}
+}
+
+// runCallbacks invokes and clears the callbacks registered through
+// SetWithCallback. If err is non-nil, the callbacks are passed err and a zero
+// sequence number.
+func (b *Batch) runCallbacks(err error) {
+	callbacks := b.callbacks
+	b.callbacks = nil
+	for _, cb := range callbacks {
+		if err != nil {
+			cb.fn(err, 0)
+		} else {
+			cb.fn(nil, cb.seqNum)
+		}
+	}
+}
+
 // SetDeferred is similar to Set in that it adds a set operation to the batch,
 // except it only takes in key/value lengths instead of complete slices,
 // letting the caller encode into those objects and then call Finish() on the
@@ -1249,6 +1336,9 @@ func (b *Batch) Repr() []byte {
 // of the supplied slice. It is not safe to modify it afterwards until the
 // Batch is no longer in use.
 //
+// The supplied representation may be compressed (see batchrepr.Compress), in
+// which case it's decompressed into a new slice.
+//
 // SetRepr may return ErrInvalidBatch if the supplied slice fails to decode in
 // any way. It will not return an error in any other circumstance.
 func (b *Batch) SetRepr(data []byte) error {
@@ -1256,6 +1346,12 @@ func (b *Batch) SetRepr(data []byte) error {
 	if !ok {
 		return ErrInvalidBatch
 	}
+	if batchrepr.IsCompressed(data) {
+		var err error
+		if data, err = batchrepr.Decompress(data); err != nil {
+			return err
+		}
+	}
 	b.data = data
 	b.count = uint64(h.Count)
 	var err error
@@ -1283,7 +1379,7 @@ func (b *Batch) NewIterWithContext(ctx context.Context, o *IterOptions) (*Iterat
 	if b.index == nil {
 		return nil, ErrNotIndexed
 	}
-	return b.db.newIter(ctx, b, newIterOpts{}, o), nil
+	return b.db.newIter(ctx, b, newIterOpts{}, o)
 }
 
 // NewBatchOnlyIter constructs an iterator that only reads the contents of the
@@ -1296,7 +1392,7 @@ func (b *Batch) NewBatchOnlyIter(ctx context.Context, o *IterOptions) (*Iterator
 	if b.index == nil {
 		return nil, ErrNotIndexed
 	}
-	return b.db.newIter(ctx, b, newIterOpts{batch: batchIterOpts{batchOnly: true}}, o), nil
+	return b.db.newIter(ctx, b, newIterOpts{batch: batchIterOpts{batchOnly: true}}, o)
 }
 
 // newInternalIter creates a new internalIterator that iterates over the
@@ -1666,9 +1762,25 @@ func (b *Batch) SyncWait() error {
 	waitDuration := now.Elapsed()
 	b.commitStats.CommitWaitDuration += waitDuration
 	b.commitStats.TotalDuration += waitDuration
+	if d := b.db; d != nil {
+		d.commitMetrics.addSyncWait(waitDuration)
+	}
+	b.runCallbacks(b.commitErr)
 	return b.commitErr
 }
 
+// SetPriority sets the priority of the batch in the commit pipeline, which
+// determines the order in which batches are admitted while writes are stalled.
+// It must be called before the batch is committed, and is cleared by Reset.
+func (b *Batch) SetPriority(p BatchPriority) {
+	b.priority = p
+}
+
+// Priority returns the priority of the batch in the commit pipeline.
+func (b *Batch) Priority() BatchPriority {
+	return b.priority
+}
+
 // CommitStats returns stats related to committing the batch. Should be called
 // after Batch.Commit, DB.Apply. If DB.ApplyNoSyncWait is used, should be
 // called after Batch.SyncWait.
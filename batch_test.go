@@ -346,6 +346,73 @@ func TestBatchApplyNoSyncWait(t *testing.T) {
 	}
 }
 
+func TestBatchSetWithCallback(t *testing.T) {
+	db, err := Open("", &Options{
+		FS: vfs.NewMem(),
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	type ack struct {
+		key    string
+		err    error
+		seqNum base.SeqNum
+	}
+	var acks []ack
+	setWithCallback := func(b *Batch, key string) {
+		require.NoError(t, b.SetWithCallback([]byte(key), []byte(key), func(err error, seqNum base.SeqNum) {
+			acks = append(acks, ack{key: key, err: err, seqNum: seqNum})
+		}))
+	}
+
+	b := db.NewBatch()
+	require.NoError(t, b.Set([]byte("a"), nil, nil))
+	setWithCallback(b, "b")
+	require.NoError(t, b.LogData([]byte("data"), nil))
+	require.NoError(t, b.Delete([]byte("c"), nil))
+	setWithCallback(b, "d")
+	require.Empty(t, acks)
+	require.NoError(t, b.Commit(Sync))
+	require.Equal(t, []ack{
+		{key: "b", seqNum: b.SeqNum() + 1},
+		{key: "d", seqNum: b.SeqNum() + 3},
+	}, acks)
+	require.NoError(t, b.Close())
+
+	// With ApplyNoSyncWait, the callbacks are invoked by SyncWait.
+	acks = nil
+	b = db.NewBatch()
+	setWithCallback(b, "e")
+	require.NoError(t, db.ApplyNoSyncWait(b, Sync))
+	require.Empty(t, acks)
+	require.NoError(t, b.SyncWait())
+	require.Equal(t, []ack{{key: "e", seqNum: b.SeqNum()}}, acks)
+	require.NoError(t, b.Close())
+
+	// Callbacks are discarded when the batch is reset.
+	acks = nil
+	b = db.NewBatch()
+	setWithCallback(b, "f")
+	b.Reset()
+	require.NoError(t, b.Commit(Sync))
+	require.Empty(t, acks)
+	require.NoError(t, b.Close())
+
+	// Callbacks are passed the commit error.
+	noWAL, err := Open("", &Options{
+		FS:         vfs.NewMem(),
+		DisableWAL: true,
+	})
+	require.NoError(t, err)
+	defer noWAL.Close()
+	b = noWAL.NewBatch()
+	setWithCallback(b, "g")
+	commitErr := b.Commit(Sync)
+	require.Error(t, commitErr)
+	require.Equal(t, []ack{{key: "g", err: commitErr}}, acks)
+	require.NoError(t, b.Close())
+}
+
 func TestBatchReset(t *testing.T) {
 	db, err := Open("", &Options{
 		FS: vfs.NewMem(),
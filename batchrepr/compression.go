@@ -0,0 +1,74 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package batchrepr
+
+import (
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+// compressedMarker follows the header of a compressed batch representation.
+// A compressed batch representation has the same header as the batch it
+// encodes followed by compressedMarker and the Snappy-compressed contents of
+// the batch. The header is left uncompressed so that the sequence number and
+// the count can be read without decompressing the batch.
+//
+// The marker isn't a valid key kind, so a compressed batch fails to decode
+// with ErrInvalidBatch, rather than being misinterpreted, if it's read as an
+// uncompressed batch.
+const compressedMarker = 0xff
+
+// IsCompressed returns true iff the provided batch representation is
+// compressed.
+func IsCompressed(repr []byte) bool {
+	return len(repr) > HeaderLen && repr[HeaderLen] == compressedMarker
+}
+
+// Compress appends to dst the compressed encoding of the provided batch
+// representation and returns it. It returns ok=false, and leaves dst
+// untouched, if compression saves less than 12.5% of the size of the batch,
+// in which case the batch should be used uncompressed.
+func Compress(dst, repr []byte) (_ []byte, ok bool) {
+	if len(repr) <= HeaderLen || IsCompressed(repr) {
+		return dst, false
+	}
+	n := len(dst)
+	maxLen := HeaderLen + 1 + snappy.MaxEncodedLen(len(repr)-HeaderLen)
+	if cap(dst)-n < maxLen {
+		dst = append(make([]byte, 0, n+maxLen), dst...)
+	}
+	out := dst[n : n+maxLen]
+	copy(out, repr[:HeaderLen])
+	out[HeaderLen] = compressedMarker
+	compressed := snappy.Encode(out[HeaderLen+1:], repr[HeaderLen:])
+	if HeaderLen+1+len(compressed) >= len(repr)-len(repr)/8 {
+		return dst[:n], false
+	}
+	return dst[:n+HeaderLen+1+len(compressed)], true
+}
+
+// Decompress returns the batch encoded by the provided batch representation,
+// decompressing it into a newly allocated slice if it's compressed, or
+// returning repr unchanged otherwise. It returns ErrInvalidBatch if the
+// compressed contents fail to decode.
+func Decompress(repr []byte) ([]byte, error) {
+	if len(repr) < HeaderLen {
+		return nil, ErrInvalidBatch
+	}
+	if !IsCompressed(repr) {
+		return repr, nil
+	}
+	body := repr[HeaderLen+1:]
+	n, err := snappy.DecodedLen(body)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidBatch, "decoding compressed batch length")
+	}
+	data := make([]byte, HeaderLen+n)
+	copy(data, repr[:HeaderLen])
+	if _, err := snappy.Decode(data[HeaderLen:], body); err != nil {
+		return nil, errors.Wrap(ErrInvalidBatch, "decompressing batch")
+	}
+	return data, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package batchrepr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	// Build a batch of compressible sets.
+	repr := make([]byte, HeaderLen)
+	value := bytes.Repeat([]byte("v"), 100)
+	for i := 0; i < 100; i++ {
+		repr = append(repr, byte(base.InternalKeyKindSet), 3, 'k', byte('0'+i/10), byte('0'+i%10))
+		repr = append(repr, byte(len(value)))
+		repr = append(repr, value...)
+	}
+	SetSeqNum(repr, 42)
+	SetCount(repr, 100)
+
+	compressed, ok := Compress([]byte("prefix"), repr)
+	require.True(t, ok)
+	require.Equal(t, "prefix", string(compressed[:6]))
+	compressed = compressed[6:]
+	require.Less(t, len(compressed), len(repr)/2)
+	require.True(t, IsCompressed(compressed))
+	require.False(t, IsCompressed(repr))
+	h, ok := ReadHeader(compressed)
+	require.True(t, ok)
+	require.Equal(t, "[seqNum=42,count=100]", h.String())
+
+	// A compressed batch isn't compressed again.
+	_, ok = Compress(nil, compressed)
+	require.False(t, ok)
+
+	decompressed, err := Decompress(compressed)
+	require.NoError(t, err)
+	require.Equal(t, repr, decompressed)
+	// Uncompressed batches are returned unchanged.
+	decompressed, err = Decompress(repr)
+	require.NoError(t, err)
+	require.Equal(t, repr, decompressed)
+
+	// Corrupt compressed contents are reported as invalid batches.
+	corrupt := append([]byte(nil), compressed[:len(compressed)-10]...)
+	_, err = Decompress(corrupt)
+	require.True(t, errors.Is(err, ErrInvalidBatch))
+
+	// Batches that don't compress well are left uncompressed.
+	incompressible := make([]byte, HeaderLen)
+	for i := 0; i < 64; i++ {
+		incompressible = append(incompressible, byte(base.InternalKeyKindSet), 1, byte(i), 8)
+		incompressible = append(incompressible, byte(i*7), byte(i*13), byte(i*31), byte(i*57),
+			byte(i*91), byte(i*113), byte(i*151), byte(i*199))
+	}
+	SetCount(incompressible, 64)
+	dst, ok := Compress(nil, incompressible)
+	require.False(t, ok)
+	require.Empty(t, dst)
+}
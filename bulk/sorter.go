@@ -0,0 +1,651 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package bulk provides helpers to build sstables for ingestion out of data
+// that isn't sorted, without requiring a DB.
+package bulk
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+const (
+	// defaultMemoryLimit is the default value of Options.MemoryLimit.
+	defaultMemoryLimit = 64 << 20
+	// defaultTargetFileSize is the default value of Options.TargetFileSize.
+	defaultTargetFileSize = 64 << 20
+)
+
+// Options configures a Sorter.
+type Options struct {
+	// WriterOptions are used to write the output sstables, as well as the
+	// sorted runs spilled to temporary files. WriterOptions.Comparer orders
+	// the keys, and WriterOptions.TableFormat must be at least
+	// sstable.TableFormatPebblev2 if range keys are added.
+	WriterOptions sstable.WriterOptions
+	// FS and TempDir are the filesystem and directory the sorted runs are
+	// spilled to. TempDir is created if it doesn't exist, and must not be
+	// used by other Sorters concurrently.
+	FS      vfs.FS
+	TempDir string
+	// MemoryLimit is the number of bytes of point keys and values buffered in
+	// memory before they're sorted and spilled to a temporary file. If zero,
+	// defaults to 64 MiB.
+	MemoryLimit int
+	// TargetFileSize is the size the output sstables are split at: once an
+	// output reaches it, the next point key starts a new output. If zero,
+	// defaults to 64 MiB.
+	TargetFileSize int64
+	// NewOutput creates the Writable the next output sstable is written to.
+	// It is called with the index of the output, in key order.
+	NewOutput func(i int) (objstorage.Writable, error)
+}
+
+// Sorter builds sstables for ingestion out of keys added in any order. Point
+// keys are buffered in memory, and once the buffer reaches
+// Options.MemoryLimit, they're sorted and spilled to a temporary file. When
+// the Sorter is finished, the spilled runs are merged and written to
+// non-overlapping sstables of about Options.TargetFileSize, which can be
+// ingested together.
+//
+// If a user key is added several times, the last key added is kept. Range
+// deletions and range keys are kept in memory until the Sorter is finished;
+// overlapping range keys are resolved in the order they were added, as if they
+// were applied by a batch. As with any sstable built for ingestion, range
+// deletions and range keys apply to the data beneath the ingested sstables, not
+// to the keys added to the Sorter.
+//
+// A Sorter is not safe for concurrent use.
+type Sorter struct {
+	opts Options
+	cmp  *base.Comparer
+	// buf holds the point keys added since the last spill, in the order they
+	// were added. bufSize is the size of their user keys and values.
+	buf     []sorterKV
+	bufSize int
+	// rangeDels and rangeKeys hold the spans added, in the order they were
+	// added.
+	rangeDels []keyspan.Span
+	rangeKeys []keyspan.Span
+	// runs holds the paths of the temporary files the sorted runs were spilled
+	// to, in the order they were spilled.
+	runs       []string
+	tempDirSet bool
+	done       bool
+	err        error
+}
+
+type sorterKV struct {
+	kind  base.InternalKeyKind
+	key   []byte
+	value []byte
+}
+
+// NewSorter returns a new Sorter.
+func NewSorter(opts Options) *Sorter {
+	if opts.WriterOptions.Comparer == nil {
+		opts.WriterOptions.Comparer = base.DefaultComparer
+	}
+	if opts.WriterOptions.TableFormat == sstable.TableFormatUnspecified {
+		opts.WriterOptions.TableFormat = sstable.TableFormatMinSupported
+	}
+	// The keys added to the sstables don't carry obsolete bits.
+	opts.WriterOptions.IsStrictObsolete = false
+	if opts.MemoryLimit <= 0 {
+		opts.MemoryLimit = defaultMemoryLimit
+	}
+	if opts.TargetFileSize <= 0 {
+		opts.TargetFileSize = defaultTargetFileSize
+	}
+	return &Sorter{
+		opts: opts,
+		cmp:  opts.WriterOptions.Comparer,
+	}
+}
+
+// Set adds a SET key with the provided value. The Sorter makes copies of key
+// and value.
+func (s *Sorter) Set(key, value []byte) error {
+	return s.addPoint(base.InternalKeyKindSet, key, value)
+}
+
+// Delete adds a DEL key. The Sorter makes a copy of key.
+func (s *Sorter) Delete(key []byte) error {
+	return s.addPoint(base.InternalKeyKindDelete, key, nil)
+}
+
+// DeleteRange adds a range deletion of the keys in [start, end). The Sorter
+// makes copies of start and end.
+func (s *Sorter) DeleteRange(start, end []byte) error {
+	return s.addSpan(&s.rangeDels, start, end, keyspan.Key{
+		Trailer: base.MakeTrailer(0, base.InternalKeyKindRangeDelete),
+	})
+}
+
+// RangeKeySet adds a range key setting the provided suffix and value over
+// [start, end). The Sorter makes copies of its arguments.
+func (s *Sorter) RangeKeySet(start, end, suffix, value []byte) error {
+	return s.addSpan(&s.rangeKeys, start, end, keyspan.Key{
+		Trailer: base.MakeTrailer(0, base.InternalKeyKindRangeKeySet),
+		Suffix:  slices.Clone(suffix),
+		Value:   slices.Clone(value),
+	})
+}
+
+// RangeKeyUnset adds a range key unsetting the provided suffix over [start,
+// end). The Sorter makes copies of its arguments.
+func (s *Sorter) RangeKeyUnset(start, end, suffix []byte) error {
+	return s.addSpan(&s.rangeKeys, start, end, keyspan.Key{
+		Trailer: base.MakeTrailer(0, base.InternalKeyKindRangeKeyUnset),
+		Suffix:  slices.Clone(suffix),
+	})
+}
+
+// RangeKeyDelete adds a range key deletion of the range keys in [start, end).
+// The Sorter makes copies of start and end.
+func (s *Sorter) RangeKeyDelete(start, end []byte) error {
+	return s.addSpan(&s.rangeKeys, start, end, keyspan.Key{
+		Trailer: base.MakeTrailer(0, base.InternalKeyKindRangeKeyDelete),
+	})
+}
+
+func (s *Sorter) addPoint(kind base.InternalKeyKind, key, value []byte) error {
+	if err := s.checkUsable(); err != nil {
+		return err
+	}
+	s.buf = append(s.buf, sorterKV{
+		kind:  kind,
+		key:   slices.Clone(key),
+		value: slices.Clone(value),
+	})
+	s.bufSize += len(key) + len(value)
+	if s.bufSize >= s.opts.MemoryLimit {
+		s.err = s.spill()
+	}
+	return s.err
+}
+
+func (s *Sorter) addSpan(spans *[]keyspan.Span, start, end []byte, k keyspan.Key) error {
+	if err := s.checkUsable(); err != nil {
+		return err
+	}
+	if s.cmp.Compare(start, end) >= 0 {
+		return errors.Errorf("pebble: start key must be strictly less than end key")
+	}
+	if spans == &s.rangeKeys && s.opts.WriterOptions.TableFormat < sstable.TableFormatPebblev2 {
+		return errors.Errorf("pebble: range keys require at least table format %s, not %s",
+			sstable.TableFormatPebblev2, s.opts.WriterOptions.TableFormat)
+	}
+	*spans = append(*spans, keyspan.Span{
+		Start: slices.Clone(start),
+		End:   slices.Clone(end),
+		Keys:  []keyspan.Key{k},
+	})
+	return nil
+}
+
+func (s *Sorter) checkUsable() error {
+	if s.done {
+		return errors.New("pebble: Sorter already finished")
+	}
+	return s.err
+}
+
+// sortBuf sorts the buffered point keys, keeping only the last key added for
+// each user key.
+func (s *Sorter) sortBuf() {
+	slices.SortStableFunc(s.buf, func(a, b sorterKV) int {
+		return s.cmp.Compare(a.key, b.key)
+	})
+	j := 0
+	for i := range s.buf {
+		if i+1 < len(s.buf) && s.cmp.Equal(s.buf[i].key, s.buf[i+1].key) {
+			continue
+		}
+		s.buf[j] = s.buf[i]
+		j++
+	}
+	clear(s.buf[j:])
+	s.buf = s.buf[:j]
+}
+
+// spill sorts the buffered point keys and writes them to a new temporary file.
+func (s *Sorter) spill() error {
+	if !s.tempDirSet {
+		if err := s.opts.FS.MkdirAll(s.opts.TempDir, 0755); err != nil {
+			return err
+		}
+		s.tempDirSet = true
+	}
+	path := s.opts.FS.PathJoin(s.opts.TempDir, fmt.Sprintf("bulk-run-%06d.sst", len(s.runs)))
+	f, err := s.opts.FS.Create(path, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, path)
+	s.sortBuf()
+	w := sstable.NewRawWriter(objstorageprovider.NewFileWritable(f), s.opts.WriterOptions)
+	for _, kv := range s.buf {
+		if err := w.Add(base.MakeInternalKey(kv.key, 0, kv.kind), kv.value, false /* forceObsolete */); err != nil {
+			_ = w.Close()
+			return err
+		}
+	}
+	clear(s.buf)
+	s.buf = s.buf[:0]
+	s.bufSize = 0
+	return w.Close()
+}
+
+// Abort discards the keys added to the Sorter and removes its temporary
+// files.
+func (s *Sorter) Abort() error {
+	s.done = true
+	s.buf = nil
+	return s.removeRuns()
+}
+
+func (s *Sorter) removeRuns() error {
+	var err error
+	for _, path := range s.runs {
+		err = errors.CombineErrors(err, s.opts.FS.Remove(path))
+	}
+	s.runs = nil
+	return err
+}
+
+// Finish sorts the keys added to the Sorter and writes them to the output
+// sstables, created through Options.NewOutput. It returns the metadata of the
+// outputs, in key order. The temporary files are removed in all cases, and the
+// Sorter can't be used afterwards.
+func (s *Sorter) Finish(ctx context.Context) (_ []*sstable.WriterMetadata, err error) {
+	if err := s.checkUsable(); err != nil {
+		return nil, errors.CombineErrors(err, s.Abort())
+	}
+	defer func() {
+		err = errors.CombineErrors(err, s.Abort())
+	}()
+
+	var iter pointIter
+	if len(s.runs) == 0 {
+		// Everything fit in memory.
+		s.sortBuf()
+		iter = &bufIter{buf: s.buf}
+	} else {
+		if len(s.buf) > 0 {
+			if err := s.spill(); err != nil {
+				return nil, err
+			}
+		}
+		m, err := s.openRuns(ctx)
+		if err != nil {
+			return nil, err
+		}
+		iter = m
+	}
+	defer func() {
+		err = errors.CombineErrors(err, iter.close())
+	}()
+
+	ow := outputWriter{
+		s:         s,
+		rangeDels: s.fragment(s.rangeDels, coalesceRangeDels),
+		rangeKeys: s.fragment(s.rangeKeys, func(keys []keyspan.Key) []keyspan.Key {
+			return coalesceRangeKeys(s.cmp.CompareRangeSuffixes, keys)
+		}),
+	}
+	defer ow.abort()
+	var valBuf []byte
+	for {
+		kv, err := iter.next()
+		if err != nil {
+			return nil, err
+		}
+		if kv == nil {
+			break
+		}
+		if ow.w != nil && ow.w.EstimatedSize() >= uint64(s.opts.TargetFileSize) {
+			// The output is large enough; the next key starts a new output.
+			if err := ow.finishOutput(kv.K.UserKey); err != nil {
+				return nil, err
+			}
+		}
+		if ow.w == nil {
+			if err := ow.newOutput(); err != nil {
+				return nil, err
+			}
+		}
+		val, _, err := kv.Value(valBuf[:0])
+		if err != nil {
+			return nil, err
+		}
+		if err := ow.w.Add(kv.K, val, false /* forceObsolete */); err != nil {
+			return nil, err
+		}
+	}
+	if ow.w == nil && (len(ow.rangeDels) > 0 || len(ow.rangeKeys) > 0) {
+		// There are only spans.
+		if err := ow.newOutput(); err != nil {
+			return nil, err
+		}
+	}
+	if ow.w != nil {
+		if err := ow.finishOutput(nil /* splitKey */); err != nil {
+			return nil, err
+		}
+	}
+	return ow.metas, nil
+}
+
+// fragment fragments the provided spans, and coalesces the keys of each
+// fragment into keys with sequence number zero. The keys of the provided spans
+// are given sequence numbers in the order the spans were added, so that the
+// spans added later take precedence over the ones added earlier.
+func (s *Sorter) fragment(
+	spans []keyspan.Span, coalesce func([]keyspan.Key) []keyspan.Key,
+) []keyspan.Span {
+	if len(spans) == 0 {
+		return nil
+	}
+	for i := range spans {
+		spans[i].Keys[0].Trailer = base.MakeTrailer(base.SeqNum(i+1), spans[i].Keys[0].Kind())
+	}
+	slices.SortStableFunc(spans, func(a, b keyspan.Span) int {
+		return s.cmp.Compare(a.Start, b.Start)
+	})
+	var fragments []keyspan.Span
+	f := keyspan.Fragmenter{
+		Cmp:    s.cmp.Compare,
+		Format: s.cmp.FormatKey,
+		Emit: func(span keyspan.Span) {
+			span.Keys = coalesce(span.Keys)
+			for i := range span.Keys {
+				span.Keys[i].Trailer = base.MakeTrailer(0, span.Keys[i].Kind())
+			}
+			keyspan.SortKeysByTrailerAndSuffix(s.cmp.CompareRangeSuffixes, span.Keys)
+			fragments = append(fragments, span)
+		},
+	}
+	for _, span := range spans {
+		f.Add(span)
+	}
+	f.Finish()
+	return fragments
+}
+
+// coalesceRangeDels coalesces the range deletions of a fragment into a single
+// range deletion.
+func coalesceRangeDels(keys []keyspan.Key) []keyspan.Key {
+	return keys[:1]
+}
+
+// coalesceRangeKeys coalesces the range keys of a fragment, sorted by
+// decreasing sequence number, into the keys that are visible once the
+// fragment is applied: at most one key per suffix, and the latest range key
+// deletion.
+func coalesceRangeKeys(suffixCmp base.CompareRangeSuffixes, keys []keyspan.Key) []keyspan.Key {
+	var dst []keyspan.Key
+	rangekey.Coalesce(suffixCmp, keys, &dst)
+	return dst
+}
+
+// outputWriter writes the output sstables of a Sorter.
+type outputWriter struct {
+	s *Sorter
+	// rangeDels and rangeKeys are the fragmented spans that haven't been
+	// written yet. The first span of each may have been partially written, in
+	// which case its start key is the start key of the current output.
+	rangeDels []keyspan.Span
+	rangeKeys []keyspan.Span
+	w         sstable.RawWriter
+	metas     []*sstable.WriterMetadata
+}
+
+func (ow *outputWriter) newOutput() error {
+	writable, err := ow.s.opts.NewOutput(len(ow.metas))
+	if err != nil {
+		return err
+	}
+	ow.w = sstable.NewRawWriter(writable, ow.s.opts.WriterOptions)
+	return nil
+}
+
+// finishOutput writes the spans before splitKey to the current output and
+// finishes it. If splitKey is nil, all the remaining spans are written.
+func (ow *outputWriter) finishOutput(splitKey []byte) error {
+	// The split key becomes the start key of the spans straddling it, which
+	// outlive the iterator position it points into.
+	splitKey = slices.Clone(splitKey)
+	var err error
+	if ow.rangeDels, err = ow.encodeSpans(ow.rangeDels, splitKey); err != nil {
+		return err
+	}
+	if ow.rangeKeys, err = ow.encodeSpans(ow.rangeKeys, splitKey); err != nil {
+		return err
+	}
+	w := ow.w
+	ow.w = nil
+	if err := w.Close(); err != nil {
+		return err
+	}
+	meta, err := w.Metadata()
+	if err != nil {
+		return err
+	}
+	ow.metas = append(ow.metas, meta)
+	return nil
+}
+
+// encodeSpans encodes the spans that start before splitKey, truncated to end
+// at splitKey, and returns the remaining spans.
+func (ow *outputWriter) encodeSpans(spans []keyspan.Span, splitKey []byte) ([]keyspan.Span, error) {
+	cmp := ow.s.cmp.Compare
+	for len(spans) > 0 {
+		span := spans[0]
+		if splitKey != nil && cmp(span.Start, splitKey) >= 0 {
+			break
+		}
+		if splitKey != nil && cmp(span.End, splitKey) > 0 {
+			// The span straddles the split key: the rest of it is written to
+			// the next output.
+			span.End = splitKey
+			spans[0].Start = splitKey
+		} else {
+			spans = spans[1:]
+		}
+		if len(span.Keys) == 0 {
+			continue
+		}
+		if err := ow.w.EncodeSpan(span); err != nil {
+			return nil, err
+		}
+	}
+	return spans, nil
+}
+
+// abort aborts the output being written, if any.
+func (ow *outputWriter) abort() {
+	if ow.w != nil {
+		_ = ow.w.Close()
+		ow.w = nil
+	}
+}
+
+// pointIter iterates over sorted point keys with unique user keys.
+type pointIter interface {
+	// next returns the next key, or nil once the keys are exhausted.
+	next() (*base.InternalKV, error)
+	close() error
+}
+
+// bufIter is a pointIter over the sorted point keys buffered in memory.
+type bufIter struct {
+	buf []sorterKV
+	kv  base.InternalKV
+}
+
+func (i *bufIter) next() (*base.InternalKV, error) {
+	if len(i.buf) == 0 {
+		return nil, nil
+	}
+	i.kv = base.InternalKV{
+		K: base.MakeInternalKey(i.buf[0].key, 0, i.buf[0].kind),
+		V: base.MakeInPlaceValue(i.buf[0].value),
+	}
+	i.buf = i.buf[1:]
+	return &i.kv, nil
+}
+
+func (i *bufIter) close() error { return nil }
+
+// openRuns returns a pointIter merging the spilled runs. When several runs
+// contain a user key, the key of the run spilled last is kept.
+func (s *Sorter) openRuns(ctx context.Context) (_ *runMerger, err error) {
+	m := &runMerger{cmp: s.cmp.Compare}
+	defer func() {
+		if err != nil {
+			err = errors.CombineErrors(err, m.close())
+		}
+	}()
+	readerOpts := sstable.ReaderOptions{Comparer: s.cmp}
+	if ks := s.opts.WriterOptions.KeySchema; ks != nil {
+		readerOpts.KeySchemas = sstable.MakeKeySchemas(ks)
+	}
+	for i, path := range s.runs {
+		f, err := s.opts.FS.Open(path, vfs.SequentialReadsOption)
+		if err != nil {
+			return nil, err
+		}
+		readable, err := sstable.NewSimpleReadable(f)
+		if err != nil {
+			return nil, errors.CombineErrors(err, f.Close())
+		}
+		// NewReader closes readable on error.
+		r, err := sstable.NewReader(ctx, readable, readerOpts)
+		if err != nil {
+			return nil, err
+		}
+		run := &sortedRun{index: i, r: r}
+		m.runs = append(m.runs, run)
+		if run.iter, err = r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */); err != nil {
+			return nil, err
+		}
+		if err := run.advance(run.iter.First()); err != nil {
+			return nil, err
+		}
+		if run.kv != nil {
+			m.items = append(m.items, run)
+		}
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+// sortedRun is a spilled run being merged.
+type sortedRun struct {
+	index int
+	r     *sstable.Reader
+	iter  sstable.Iterator
+	kv    *base.InternalKV
+}
+
+func (r *sortedRun) advance(kv *base.InternalKV) error {
+	r.kv = kv
+	return r.iter.Error()
+}
+
+// runMerger is a pointIter merging spilled runs, implemented as a heap of the
+// runs that aren't exhausted.
+type runMerger struct {
+	cmp   base.Compare
+	runs  []*sortedRun
+	items []*sortedRun
+	// prevUserKey is a copy of the user key returned last, if started is set.
+	prevUserKey []byte
+	started     bool
+}
+
+func (m *runMerger) next() (*base.InternalKV, error) {
+	if m.started {
+		// Advance the run whose key was returned last, along with the runs
+		// positioned at the same user key, which are shadowed by it.
+		for len(m.items) > 0 && m.cmp(m.items[0].kv.K.UserKey, m.prevUserKey) == 0 {
+			if err := m.advanceTop(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(m.items) == 0 {
+		return nil, nil
+	}
+	kv := m.items[0].kv
+	m.prevUserKey = append(m.prevUserKey[:0], kv.K.UserKey...)
+	m.started = true
+	return kv, nil
+}
+
+// advanceTop advances the run at the top of the heap.
+func (m *runMerger) advanceTop() error {
+	r := m.items[0]
+	if err := r.advance(r.iter.Next()); err != nil {
+		return err
+	}
+	if r.kv == nil {
+		heap.Pop(m)
+	} else {
+		heap.Fix(m, 0)
+	}
+	return nil
+}
+
+func (m *runMerger) close() error {
+	var err error
+	for _, r := range m.runs {
+		if r.iter != nil {
+			err = errors.CombineErrors(err, r.iter.Close())
+		}
+		err = errors.CombineErrors(err, r.r.Close())
+	}
+	m.runs = nil
+	m.items = nil
+	return err
+}
+
+// Len implements heap.Interface.
+func (m *runMerger) Len() int { return len(m.items) }
+
+// Less implements heap.Interface. Among runs positioned at the same user key,
+// the run spilled last comes first.
+func (m *runMerger) Less(i, j int) bool {
+	if c := m.cmp(m.items[i].kv.K.UserKey, m.items[j].kv.K.UserKey); c != 0 {
+		return c < 0
+	}
+	return m.items[i].index > m.items[j].index
+}
+
+// Swap implements heap.Interface.
+func (m *runMerger) Swap(i, j int) { m.items[i], m.items[j] = m.items[j], m.items[i] }
+
+// Push implements heap.Interface.
+func (m *runMerger) Push(x any) { m.items = append(m.items, x.(*sortedRun)) }
+
+// Pop implements heap.Interface.
+func (m *runMerger) Pop() any {
+	n := len(m.items)
+	r := m.items[n-1]
+	m.items = m.items[:n-1]
+	return r
+}
@@ -0,0 +1,221 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSorter(t *testing.T) {
+	fs := vfs.NewMem()
+	var outputs []string
+	s := NewSorter(Options{
+		WriterOptions: sstable.WriterOptions{
+			TableFormat: sstable.TableFormatMax,
+			BlockSize:   256,
+		},
+		FS:             fs,
+		TempDir:        "tmp",
+		MemoryLimit:    1 << 10,
+		TargetFileSize: 4 << 10,
+		NewOutput: func(i int) (objstorage.Writable, error) {
+			name := fmt.Sprintf("out-%d.sst", i)
+			outputs = append(outputs, name)
+			f, err := fs.Create(name, vfs.WriteCategoryUnspecified)
+			if err != nil {
+				return nil, err
+			}
+			return objstorageprovider.NewFileWritable(f), nil
+		},
+	})
+
+	// Add each key twice in a random order; the second value must win.
+	const n = 1000
+	expected := make(map[string]string)
+	rng := rand.New(rand.NewPCG(0, 0))
+	for round := 0; round < 2; round++ {
+		for _, i := range rng.Perm(n) {
+			k := fmt.Sprintf("key-%04d", i)
+			if round == 1 && i%10 == 0 {
+				require.NoError(t, s.Delete([]byte(k)))
+				expected[k] = "<del>"
+				continue
+			}
+			v := fmt.Sprintf("value-%d-%d", i, round)
+			require.NoError(t, s.Set([]byte(k), []byte(v)))
+			expected[k] = v
+		}
+	}
+	ls, err := fs.List("tmp")
+	require.NoError(t, err)
+	require.NotEmpty(t, ls, "expected spilled runs")
+
+	require.NoError(t, s.DeleteRange([]byte("key-0100"), []byte("key-0900")))
+	require.NoError(t, s.DeleteRange([]byte("key-0050"), []byte("key-0200")))
+	require.NoError(t, s.RangeKeySet([]byte("key-0000"), []byte("key-0500"), []byte("@1"), []byte("a")))
+	require.NoError(t, s.RangeKeyUnset([]byte("key-0200"), []byte("key-0300"), []byte("@1")))
+	require.NoError(t, s.RangeKeyDelete([]byte("key-0400"), []byte("key-0600")))
+	require.NoError(t, s.RangeKeySet([]byte("key-0450"), []byte("key-0550"), []byte("@2"), []byte("b")))
+
+	metas, err := s.Finish(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metas, len(outputs))
+	require.Greater(t, len(outputs), 1)
+	ls, err = fs.List("tmp")
+	require.NoError(t, err)
+	require.Empty(t, ls)
+	_, err = s.Finish(context.Background())
+	require.Error(t, err)
+
+	cmp := base.DefaultComparer.Compare
+	got := make(map[string]string)
+	var rangeDels, rangeKeys []keyspan.Span
+	var prevEnd []byte
+	for _, name := range outputs {
+		f, err := fs.Open(name)
+		require.NoError(t, err)
+		readable, err := sstable.NewSimpleReadable(f)
+		require.NoError(t, err)
+		r, err := sstable.NewReader(context.Background(), readable, sstable.ReaderOptions{})
+		require.NoError(t, err)
+
+		// The outputs are in key order and don't overlap: every key of an
+		// output is at or after the end of the previous output.
+		var start, end []byte
+		extend := func(s, e []byte) {
+			if start == nil || cmp(s, start) < 0 {
+				start = s
+			}
+			if end == nil || cmp(e, end) > 0 {
+				end = e
+			}
+		}
+		iter, err := r.NewIter(sstable.NoTransforms, nil, nil)
+		require.NoError(t, err)
+		for kv := iter.First(); kv != nil; kv = iter.Next() {
+			require.Equal(t, base.SeqNum(0), kv.SeqNum())
+			k := string(kv.K.UserKey)
+			extend([]byte(k), []byte(k+"\x00"))
+			if kv.Kind() == base.InternalKeyKindDelete {
+				got[k] = "<del>"
+				continue
+			}
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			got[k] = string(v)
+		}
+		require.NoError(t, iter.Close())
+
+		collect := func(iter keyspan.FragmentIterator, err error, dst *[]keyspan.Span) {
+			require.NoError(t, err)
+			if iter == nil {
+				return
+			}
+			defer iter.Close()
+			span, err := iter.First()
+			for ; span != nil; span, err = iter.Next() {
+				c := span.Clone()
+				extend(c.Start, c.End)
+				*dst = append(*dst, c)
+			}
+			require.NoError(t, err)
+		}
+		rdIter, err := r.NewRawRangeDelIter(context.Background(), sstable.NoFragmentTransforms, block.NoReadEnv)
+		collect(rdIter, err, &rangeDels)
+		rkIter, err := r.NewRawRangeKeyIter(context.Background(), sstable.NoFragmentTransforms, block.NoReadEnv)
+		collect(rkIter, err, &rangeKeys)
+		require.NoError(t, r.Close())
+
+		if prevEnd != nil {
+			require.LessOrEqual(t, cmp(prevEnd, start), 0)
+		}
+		prevEnd = end
+	}
+	require.Equal(t, expected, got)
+
+	// Join the fragments split across outputs to compare the spans.
+	defragment := func(spans []keyspan.Span) string {
+		var res []keyspan.Span
+		for _, s := range spans {
+			if n := len(res); n > 0 && cmp(res[n-1].End, s.Start) == 0 &&
+				fmt.Sprint(res[n-1].Keys) == fmt.Sprint(s.Keys) {
+				res[n-1].End = s.End
+				continue
+			}
+			res = append(res, s)
+		}
+		var buf strings.Builder
+		for _, s := range res {
+			fmt.Fprintln(&buf, s)
+		}
+		return buf.String()
+	}
+	require.Equal(t, strings.Join([]string{
+		"key-0050-key-0900:{(#0,RANGEDEL)}",
+		"",
+	}, "\n"), defragment(rangeDels))
+	// The unset of @1 shadows its set over [key-0200,key-0300). The range key
+	// deletion removes the set of @1 over [key-0400,key-0500) and is kept to
+	// apply to the data beneath the sstables, alongside the later set of @2.
+	require.Equal(t, strings.Join([]string{
+		"key-0000-key-0200:{(#0,RANGEKEYSET,@1,a)}",
+		"key-0200-key-0300:{(#0,RANGEKEYUNSET,@1)}",
+		"key-0300-key-0400:{(#0,RANGEKEYSET,@1,a)}",
+		"key-0400-key-0450:{(#0,RANGEKEYDEL)}",
+		"key-0450-key-0550:{(#0,RANGEKEYSET,@2,b) (#0,RANGEKEYDEL)}",
+		"key-0550-key-0600:{(#0,RANGEKEYDEL)}",
+		"",
+	}, "\n"), defragment(rangeKeys))
+}
+
+func TestSorterInMemory(t *testing.T) {
+	fs := vfs.NewMem()
+	newOutput := func(i int) (objstorage.Writable, error) {
+		f, err := fs.Create(fmt.Sprintf("out-%d.sst", i), vfs.WriteCategoryUnspecified)
+		if err != nil {
+			return nil, err
+		}
+		return objstorageprovider.NewFileWritable(f), nil
+	}
+
+	// Range keys require a table format that supports them.
+	s := NewSorter(Options{
+		WriterOptions: sstable.WriterOptions{TableFormat: sstable.TableFormatPebblev1},
+		FS:            fs,
+		TempDir:       "tmp",
+		NewOutput:     newOutput,
+	})
+	require.Error(t, s.RangeKeySet([]byte("a"), []byte("b"), nil, nil))
+	require.Error(t, s.DeleteRange([]byte("b"), []byte("a")))
+	require.NoError(t, s.Abort())
+
+	s = NewSorter(Options{FS: fs, TempDir: "tmp", NewOutput: newOutput})
+	require.NoError(t, s.Set([]byte("c"), []byte("1")))
+	require.NoError(t, s.Set([]byte("a"), []byte("2")))
+	require.NoError(t, s.Set([]byte("c"), []byte("3")))
+	metas, err := s.Finish(context.Background())
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.Equal(t, uint64(2), metas[0].Properties.NumEntries)
+	require.Equal(t, "a#0,SET", metas[0].SmallestPoint.String())
+	require.Equal(t, "c#0,SET", metas[0].LargestPoint.String())
+	// Nothing was spilled.
+	_, err = fs.Stat("tmp")
+	require.True(t, oserror.IsNotExist(err))
+}
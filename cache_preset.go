@@ -0,0 +1,192 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// Preset selects a set of defaults for the Options, and the code paths
+// tailored to a particular use of the DB. See Options.Preset.
+type Preset int8
+
+const (
+	// DefaultPreset is the general-purpose configuration.
+	DefaultPreset Preset = iota
+	// CachePreset configures the DB as a persistent cache with a strict space
+	// cap and very low write amplification (see CacheModeOptions):
+	//
+	//   - Flushed tables stay in L0; they're never compacted, except by manual
+	//     compactions, and the number of L0 tables and sublevels never
+	//     triggers compactions nor stalls writes.
+	//   - Once the total size of the tables exceeds CacheModeOptions.MaxSize,
+	//     or once tables are older than CacheModeOptions.TTL, the oldest
+	//     tables are dropped, in FIFO order.
+	//   - Snapshots are not supported: NewSnapshot and
+	//     NewEventuallyFileOnlySnapshot panic.
+	//   - Table stats, which only inform compaction heuristics, are disabled.
+	//
+	// The WAL remains enabled, unless Options.DisableWAL is set: without it,
+	// the contents of the memtables are lost when the DB is closed without
+	// flushing, which may be acceptable for a cache.
+	//
+	// Dropping a table drops all the keys it contains, including those that
+	// were written (or deleted) more recently than the keys that remain in
+	// the cache. Because the oldest tables are dropped first, the cache never
+	// returns a value that was overwritten by a value it dropped.
+	CachePreset
+)
+
+// String implements fmt.Stringer.
+func (p Preset) String() string {
+	switch p {
+	case DefaultPreset:
+		return "default"
+	case CachePreset:
+		return "cache"
+	}
+	return "unknown"
+}
+
+func parsePreset(s string) (Preset, error) {
+	switch s {
+	case "default":
+		return DefaultPreset, nil
+	case "cache":
+		return CachePreset, nil
+	}
+	return 0, errors.Errorf("pebble: unknown preset %q", s)
+}
+
+// CacheModeOptions configures the eviction of the tables of a DB configured
+// with CachePreset.
+type CacheModeOptions struct {
+	// MaxSize is the maximum total size of the tables. Once a flush (or an
+	// ingestion) brings the total size past MaxSize, the oldest tables are
+	// dropped until the total size is at most MaxSize. Zero means no limit.
+	MaxSize uint64
+	// TTL, if positive, is the time after which tables are dropped. The age
+	// of a table is the time since it was written (by a flush, an ingestion
+	// or a manual compaction), so keys are dropped once the table containing
+	// them is older than TTL, at a granularity of about TTL/10 (and at most a
+	// minute).
+	TTL time.Duration
+}
+
+// ensureCachePresetDefaults sets the defaults implied by CachePreset. It must
+// be called before the general defaults are set.
+func (o *Options) ensureCachePresetDefaults() {
+	if o.L0CompactionThreshold <= 0 {
+		o.L0CompactionThreshold = math.MaxInt32
+	}
+	if o.L0CompactionFileThreshold <= 0 {
+		o.L0CompactionFileThreshold = math.MaxInt32
+	}
+	if o.L0StopWritesThreshold <= 0 {
+		o.L0StopWritesThreshold = math.MaxInt32
+	}
+	o.DisableTableStats = true
+}
+
+// cacheEvictionMaxCheckInterval is the maximum interval at which a DB
+// configured with CachePreset checks for expired tables.
+const cacheEvictionMaxCheckInterval = time.Minute
+
+// cacheEvictionLoop periodically schedules the eviction of the tables older
+// than CacheModeOptions.TTL. It exits when the DB is closed.
+func (d *DB) cacheEvictionLoop() {
+	defer d.compactionSchedulers.Done()
+
+	interval := max(min(d.opts.CacheMode.TTL/10, cacheEvictionMaxCheckInterval), time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.closedCh:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.maybeScheduleCompaction()
+			d.mu.Unlock()
+		}
+	}
+}
+
+// pickCacheEvictionCompaction returns a delete-only compaction that drops the
+// oldest tables, if the DB is configured with CachePreset and the tables
+// exceed CacheModeOptions.MaxSize or are older than CacheModeOptions.TTL. It
+// returns nil otherwise.
+//
+// The tables are dropped in the order of their largest sequence numbers. If
+// one of the tables to drop is being compacted, only the older tables are
+// dropped.
+//
+// Requires d.mu to be held.
+func (d *DB) pickCacheEvictionCompaction() *compaction {
+	if d.opts.Preset != CachePreset {
+		return nil
+	}
+	maxSize, ttl := d.opts.CacheMode.MaxSize, d.opts.CacheMode.TTL
+	if maxSize == 0 && ttl <= 0 {
+		return nil
+	}
+	v := d.mu.versions.currentVersion()
+	order := &d.mu.compact.cacheEvictionOrder
+	if order.version != v {
+		// Sort the tables once per version, rather than every time compactions
+		// are scheduled.
+		order.version = v
+		order.tables = order.tables[:0]
+		order.totalSize = 0
+		for level := range v.Levels {
+			for f := range v.Levels[level].All() {
+				order.tables = append(order.tables, manifest.NewTableEntry{Level: level, Meta: f})
+				order.totalSize += f.Size
+			}
+		}
+		slices.SortFunc(order.tables, func(a, b manifest.NewTableEntry) int {
+			return cmp.Compare(a.Meta.LargestSeqNum, b.Meta.LargestSeqNum)
+		})
+	}
+	totalSize := order.totalSize
+	var expiredBefore int64
+	if ttl > 0 {
+		expiredBefore = d.timeNow().Add(-ttl).Unix()
+	}
+
+	var byLevel [numLevels][]*tableMetadata
+	evicted := false
+	for _, t := range order.tables {
+		overSize := maxSize > 0 && totalSize > maxSize
+		expired := ttl > 0 && t.Meta.CreationTime < expiredBefore
+		if (!overSize && !expired) || t.Meta.IsCompacting() {
+			break
+		}
+		byLevel[t.Level] = append(byLevel[t.Level], t.Meta)
+		totalSize -= t.Meta.Size
+		evicted = true
+	}
+	if !evicted {
+		return nil
+	}
+	var inputs []compactionLevel
+	for level, files := range byLevel {
+		if len(files) > 0 {
+			inputs = append(inputs, compactionLevel{
+				level: level,
+				files: manifest.NewLevelSliceKeySorted(d.cmp, files),
+			})
+		}
+	}
+	c := newDeleteOnlyCompaction(d.opts, v, inputs, d.timeNow(), nil /* hints */, false /* exciseEnabled */)
+	c.cacheEviction = true
+	return c
+}
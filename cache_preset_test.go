@@ -0,0 +1,109 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/testutils"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachePreset(t *testing.T) {
+	const maxSize = 64 << 10
+	d, err := Open("", &Options{
+		FS:        vfs.NewMem(),
+		Preset:    CachePreset,
+		CacheMode: CacheModeOptions{MaxSize: maxSize},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	rng := rand.New(rand.NewPCG(0, uint64(time.Now().UnixNano())))
+	value := testutils.RandBytes(rng, 1024)
+	key := func(i int) []byte { return []byte(fmt.Sprintf("key%04d", i)) }
+	const n = 400
+	for i := 0; i < n; i++ {
+		require.NoError(t, d.Set(key(i), value, nil))
+		if i%20 == 19 {
+			require.NoError(t, d.Flush())
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		m := d.Metrics()
+		return m.Total().Size <= maxSize && m.Compact.NumInProgress == 0
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// The tables were never compacted out of L0.
+	m := d.Metrics()
+	for level := 1; level < numLevels; level++ {
+		require.Zero(t, m.Levels[level].NumFiles, "L%d", level)
+	}
+
+	// The oldest keys were dropped, the most recent ones remain.
+	_, _, err = d.Get(key(0))
+	require.ErrorIs(t, err, ErrNotFound)
+	v, closer, err := d.Get(key(n - 1))
+	require.NoError(t, err)
+	require.Equal(t, value, v)
+	require.NoError(t, closer.Close())
+
+	require.Panics(t, func() { d.NewSnapshot() })
+	require.Panics(t, func() { d.NewEventuallyFileOnlySnapshot(nil) })
+}
+
+func TestCachePresetTTL(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:        vfs.NewMem(),
+		Preset:    CachePreset,
+		CacheMode: CacheModeOptions{TTL: time.Hour},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("b"), []byte("2"), nil))
+	require.NoError(t, d.Flush())
+	require.Equal(t, int64(2), d.Metrics().Levels[0].NumFiles)
+
+	// The order in which the tables are dropped is computed once per version.
+	d.mu.Lock()
+	require.Nil(t, d.pickCacheEvictionCompaction())
+	order := &d.mu.compact.cacheEvictionOrder
+	require.Equal(t, d.mu.versions.currentVersion(), order.version)
+	require.Len(t, order.tables, 2)
+	require.Less(t, order.tables[0].Meta.LargestSeqNum, order.tables[1].Meta.LargestSeqNum)
+	// Scramble the cached order: it's not sorted again for the same version.
+	order.tables[0], order.tables[1] = order.tables[1], order.tables[0]
+	require.Nil(t, d.pickCacheEvictionCompaction())
+	require.Greater(t, order.tables[0].Meta.LargestSeqNum, order.tables[1].Meta.LargestSeqNum)
+	order.tables[0], order.tables[1] = order.tables[1], order.tables[0]
+	d.mu.Unlock()
+
+	// Once the tables are older than the TTL, they are dropped.
+	d.mu.Lock()
+	d.timeNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	d.maybeScheduleCompaction()
+	d.mu.Unlock()
+	require.Eventually(t, func() bool {
+		return d.Metrics().Total().NumFiles == 0
+	}, 10*time.Second, 10*time.Millisecond)
+	_, _, err = d.Get([]byte("a"))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCachePresetValidate(t *testing.T) {
+	opts := &Options{Preset: CachePreset}
+	opts.EnsureDefaults()
+	require.Error(t, opts.Validate())
+	opts.CacheMode.MaxSize = 1 << 20
+	require.NoError(t, opts.Validate())
+}
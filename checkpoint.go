@@ -7,6 +7,7 @@ package pebble
 import (
 	"bytes"
 	"io"
+	"math"
 	"os"
 
 	"github.com/cockroachdb/errors"
@@ -15,6 +16,7 @@ import (
 	"github.com/cockroachdb/pebble/record"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/vfs/atomicfs"
+	"github.com/cockroachdb/pebble/wal"
 )
 
 // checkpointOptions hold the optional parameters to construct checkpoint
@@ -140,24 +142,13 @@ func mkdirAllAndSyncParents(fs vfs.FS, destDir string) (vfs.File, error) {
 // restarted after a checkpoint operation, as the reference for the checkpoint
 // is only maintained in memory. This is okay as long as users of Checkpoint
 // crash shortly afterwards with a "poison file" preventing further restarts.
-func (d *DB) Checkpoint(
-	destDir string, opts ...CheckpointOption,
-) (
-	ckErr error, /* used in deferred cleanup */
-) {
+func (d *DB) Checkpoint(destDir string, opts ...CheckpointOption) error {
 	opt := &checkpointOptions{}
 	for _, fn := range opts {
 		fn(opt)
 	}
 
-	if _, err := d.opts.FS.Stat(destDir); !oserror.IsNotExist(err) {
-		if err == nil {
-			return &os.PathError{
-				Op:   "checkpoint",
-				Path: destDir,
-				Err:  oserror.ErrExist,
-			}
-		}
+	if err := checkCheckpointDestDir(d.opts.FS, destDir); err != nil {
 		return err
 	}
 
@@ -185,28 +176,77 @@ func (d *DB) Checkpoint(
 	// we read, otherwise we might copy a versionEdit not reflected in the
 	// sstables we copy/link.
 	d.mu.versions.logLock()
-	// Get the the current version and the current manifest file number.
-	current := d.mu.versions.currentVersion()
-	formatVers := d.FormatMajorVersion()
-	manifestFileNum := d.mu.versions.manifestFileNum
-	manifestSize := d.mu.versions.manifest.Size()
-	optionsFileNum := d.optionsFileNum
+	state := d.captureCheckpointStateLocked()
+	// Release the manifest and DB.mu so we don't block other operations on
+	// the database.
+	d.mu.versions.logUnlock()
+	d.mu.Unlock()
 
-	virtualBackingFiles := make(map[base.DiskFileNum]struct{})
+	return d.writeCheckpoint(destDir, opt, state)
+}
+
+// checkCheckpointDestDir returns an error if the checkpoint directory exists.
+func checkCheckpointDestDir(fs vfs.FS, destDir string) error {
+	if _, err := fs.Stat(destDir); !oserror.IsNotExist(err) {
+		if err == nil {
+			return &os.PathError{
+				Op:   "checkpoint",
+				Path: destDir,
+				Err:  oserror.ErrExist,
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// checkpointState is the state of the DB that is captured at the start of a
+// checkpoint, and written into the checkpoint directory.
+type checkpointState struct {
+	current             *version
+	formatVers          FormatMajorVersion
+	manifestFileNum     base.DiskFileNum
+	manifestSize        int64
+	optionsFileNum      base.DiskFileNum
+	virtualBackingFiles map[base.DiskFileNum]struct{}
+	logs                wal.Logs
+}
+
+// captureCheckpointStateLocked captures the state of the DB to checkpoint.
+// File deletions must be disabled until the checkpoint is written.
+//
+// d.mu and the manifest lock must be held when calling this.
+func (d *DB) captureCheckpointStateLocked() checkpointState {
+	// Get the the current version and the current manifest file number.
+	state := checkpointState{
+		current:             d.mu.versions.currentVersion(),
+		formatVers:          d.FormatMajorVersion(),
+		manifestFileNum:     d.mu.versions.manifestFileNum,
+		manifestSize:        d.mu.versions.manifest.Size(),
+		optionsFileNum:      d.optionsFileNum,
+		virtualBackingFiles: make(map[base.DiskFileNum]struct{}),
+	}
+	if d.opts.ReadOnly {
+		// The manifest isn't written in read-only mode, so all of it is
+		// copied.
+		state.manifestSize = math.MaxInt64
+	}
 	d.mu.versions.virtualBackings.ForEach(func(backing *fileBacking) {
-		virtualBackingFiles[backing.DiskFileNum] = struct{}{}
+		state.virtualBackingFiles[backing.DiskFileNum] = struct{}{}
 	})
-
 	// Acquire the logs while holding mutexes to ensure we don't race with a
 	// flush that might mark a log that's relevant to `current` as obsolete
 	// before our call to List.
-	allLogicalLogs := d.mu.log.manager.List()
-
-	// Release the manifest and DB.mu so we don't block other operations on
-	// the database.
-	d.mu.versions.logUnlock()
-	d.mu.Unlock()
+	state.logs = append(d.mu.log.manager.List(), d.mu.log.readOnlyWALs...)
+	return state
+}
 
+// writeCheckpoint writes the captured state of the DB into destDir.
+func (d *DB) writeCheckpoint(
+	destDir string, opt *checkpointOptions, state checkpointState,
+) (
+	ckErr error, /* used in deferred cleanup */
+) {
 	// Wrap the normal filesystem with one which wraps newly created files with
 	// vfs.NewSyncingFile.
 	fs := vfs.NewSyncingFS(d.opts.FS, vfs.SyncingFileOptions{
@@ -232,12 +272,15 @@ func (d *DB) Checkpoint(
 
 	{
 		// Copy the OPTIONS.
-		srcPath := base.MakeFilepath(fs, d.dirname, base.FileTypeOptions, optionsFileNum)
+		srcPath := base.MakeFilepath(fs, d.dirname, base.FileTypeOptions, state.optionsFileNum)
 		destPath := fs.PathJoin(destDir, fs.PathBase(srcPath))
 		ckErr = copyCheckpointOptions(fs, srcPath, destPath)
 		if ckErr != nil {
 			return ckErr
 		}
+		if d.opts.Local.DropCheckpointReadsFromPageCache {
+			dropFromPageCache(fs, srcPath)
+		}
 	}
 
 	{
@@ -252,7 +295,7 @@ func (d *DB) Checkpoint(
 		// marker filename. Unlike other uses of the atomic marker,
 		// there is no file with the filename `formatVers.String()` on
 		// the filesystem.
-		ckErr = versionMarker.Move(formatVers.String())
+		ckErr = versionMarker.Move(state.formatVers.String())
 		if ckErr != nil {
 			return ckErr
 		}
@@ -268,8 +311,8 @@ func (d *DB) Checkpoint(
 	// in the checkpoint.
 	requiredVirtualBackingFiles := make(map[base.DiskFileNum]struct{})
 	// Link or copy the sstables.
-	for l := range current.Levels {
-		iter := current.Levels[l].Iter()
+	for l := range state.current.Levels {
+		iter := state.current.Levels[l].Iter()
 		for f := iter.First(); f != nil; f = iter.Next() {
 			if excludeFromCheckpoint(f, opt, d.cmp) {
 				if excludedTables == nil {
@@ -304,10 +347,14 @@ func (d *DB) Checkpoint(
 				remoteFiles = append(remoteFiles, meta.DiskFileNum)
 				continue
 			}
+			if d.inlineTables.isInline(base.FileTypeTable, fileBacking.DiskFileNum) {
+				// Inline tables are carried over by the copy of the MANIFEST.
+				continue
+			}
 
 			srcPath := base.MakeFilepath(fs, d.dirname, base.FileTypeTable, fileBacking.DiskFileNum)
 			destPath := fs.PathJoin(destDir, fs.PathBase(srcPath))
-			ckErr = vfs.LinkOrCopy(fs, srcPath, destPath)
+			ckErr = d.linkOrCopyCheckpointTable(fs, srcPath, destPath)
 			if ckErr != nil {
 				return ckErr
 			}
@@ -315,7 +362,7 @@ func (d *DB) Checkpoint(
 	}
 
 	var removeBackingTables []base.DiskFileNum
-	for diskFileNum := range virtualBackingFiles {
+	for diskFileNum := range state.virtualBackingFiles {
 		if _, ok := requiredVirtualBackingFiles[diskFileNum]; !ok {
 			// The backing sstable associated with fileNum is no longer
 			// required.
@@ -324,7 +371,7 @@ func (d *DB) Checkpoint(
 	}
 
 	ckErr = d.writeCheckpointManifest(
-		fs, formatVers, destDir, dir, manifestFileNum, manifestSize,
+		fs, state.formatVers, destDir, dir, state.manifestFileNum, state.manifestSize,
 		excludedTables, removeBackingTables,
 	)
 	if ckErr != nil {
@@ -339,7 +386,7 @@ func (d *DB) Checkpoint(
 
 	// Copy the WAL files. We copy rather than link because WAL file recycling
 	// will cause the WAL files to be reused which would invalidate the
-	// checkpoint. It's possible allLogicalLogs includes logs that are not
+	// checkpoint. It's possible state.logs includes logs that are not
 	// relevant (beneath the version's MinUnflushedLogNum). These extra files
 	// are harmless. The earlier (wal.Manager).List call will not include
 	// obsolete logs that are sitting in the recycler or have already been
@@ -347,7 +394,7 @@ func (d *DB) Checkpoint(
 	//
 	// TODO(jackson): It would be desirable to copy all recycling and obsolete
 	// WALs to aid corruption postmortem debugging should we need them.
-	for _, log := range allLogicalLogs {
+	for _, log := range state.logs {
 		for i := 0; i < log.NumSegments(); i++ {
 			srcFS, srcPath := log.SegmentLocation(i)
 			destPath := fs.PathJoin(destDir, srcFS.PathBase(srcPath))
@@ -355,6 +402,9 @@ func (d *DB) Checkpoint(
 			if ckErr != nil {
 				return ckErr
 			}
+			if d.opts.Local.DropCheckpointReadsFromPageCache {
+				dropFromPageCache(srcFS, srcPath)
+			}
 		}
 	}
 
@@ -446,6 +496,11 @@ func (d *DB) writeCheckpointManifest(
 			return err
 		}
 		defer src.Close()
+		if d.opts.Local.DropCheckpointReadsFromPageCache {
+			// NB: Deferred calls run in LIFO order, so this runs before src is
+			// closed.
+			defer func() { _ = vfs.AdviseDontNeed(src, 0, 0) }()
+		}
 
 		dst, err := fs.Create(destPath, vfs.WriteCategoryUnspecified)
 		if err != nil {
@@ -510,3 +565,37 @@ func (d *DB) writeCheckpointManifest(
 	}
 	return manifestMarker.Close()
 }
+
+// linkOrCopyCheckpointTable hard links the table at srcPath to destPath, or
+// copies it if it can't be linked (see vfs.LinkOrCopy). If the table is copied
+// and Options.Local.DropCheckpointReadsFromPageCache is set, the source table
+// is dropped from the page cache. A linked table isn't read, so its pages are
+// left alone.
+func (d *DB) linkOrCopyCheckpointTable(fs vfs.FS, srcPath, destPath string) error {
+	err := fs.Link(srcPath, destPath)
+	if err == nil {
+		return nil
+	}
+	if oserror.IsExist(err) || oserror.IsNotExist(err) || oserror.IsPermission(err) {
+		return err
+	}
+	if err := vfs.Copy(fs, srcPath, destPath); err != nil {
+		return err
+	}
+	if d.opts.Local.DropCheckpointReadsFromPageCache {
+		dropFromPageCache(fs, srcPath)
+	}
+	return nil
+}
+
+// dropFromPageCache advises the OS that the file at path will not be read in
+// the near future, allowing the OS to drop it from its page cache. Failures are
+// ignored, since the advice is only an optimization.
+func dropFromPageCache(fs vfs.FS, path string) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return
+	}
+	_ = vfs.AdviseDontNeed(f, 0, 0)
+	_ = f.Close()
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/wal"
+)
+
+// GroupCheckpointSpec describes the checkpoint of one of the DBs of a group
+// checkpoint. See CheckpointGroup.
+type GroupCheckpointSpec struct {
+	DB *DB
+	// DestDir is the directory in which the checkpoint of DB is constructed.
+	// It must not exist.
+	DestDir string
+	// Options configures the checkpoint of DB. WithFlushedWAL has no effect,
+	// since the WALs of the group are always synced at the consistent point.
+	Options []CheckpointOption
+}
+
+// GroupCheckpointResult describes the consistent point of a group checkpoint.
+type GroupCheckpointResult struct {
+	// Time is the wall-clock time at which commits to the DBs were paused.
+	Time time.Time
+	// SeqNums holds, for each DB of the group, the sequence number that would
+	// have been assigned to the next write when commits were paused.
+	SeqNums []base.SeqNum
+}
+
+// groupCheckpointMu serializes group checkpoints, so that concurrent group
+// checkpoints of overlapping sets of DBs can't pause their commit pipelines in
+// conflicting orders.
+var groupCheckpointMu sync.Mutex
+
+// CheckpointGroup checkpoints several DBs at a consistent point: every write
+// committed to any of the DBs before the point is contained in the
+// checkpoints, and no write committed after it. This is useful for hosts that
+// store related data across multiple DBs (for instance DBs sharing a block
+// cache), and need consistent backups of all of them.
+//
+// The checkpoints are constructed in two phases. First, commits to all the DBs
+// are paused; while they are paused, the WAL of each DB is rotated (which syncs
+// the WAL once) and the state to checkpoint is captured. Commits are resumed
+// before the second phase, which constructs the checkpoints of the DBs
+// concurrently (see DB.Checkpoint). If any of the checkpoints fails, all the
+// checkpoint directories are removed.
+func CheckpointGroup(specs []GroupCheckpointSpec) (GroupCheckpointResult, error) {
+	seen := make(map[*DB]struct{}, len(specs))
+	for i := range specs {
+		d := specs[i].DB
+		if err := d.closed.Load(); err != nil {
+			panic(err)
+		}
+		if _, ok := seen[d]; ok {
+			return GroupCheckpointResult{}, errors.New("pebble: a DB can only be checkpointed once per group")
+		}
+		seen[d] = struct{}{}
+		if err := checkCheckpointDestDir(d.opts.FS, specs[i].DestDir); err != nil {
+			return GroupCheckpointResult{}, err
+		}
+	}
+
+	groupCheckpointMu.Lock()
+	defer groupCheckpointMu.Unlock()
+
+	// Phase 1: pause the commits to all the DBs, and capture their state.
+	res := GroupCheckpointResult{SeqNums: make([]base.SeqNum, len(specs))}
+	states := make([]checkpointState, len(specs))
+	for i := range specs {
+		specs[i].DB.commit.mu.Lock()
+	}
+	res.Time = time.Now()
+	var wg sync.WaitGroup
+	for i := range specs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			states[i], res.SeqNums[i] = specs[i].DB.captureGroupCheckpointState()
+		}(i)
+	}
+	wg.Wait()
+	for i := range specs {
+		specs[i].DB.commit.mu.Unlock()
+	}
+	defer func() {
+		for i := range specs {
+			d := specs[i].DB
+			d.mu.Lock()
+			d.enableFileDeletions()
+			d.mu.Unlock()
+		}
+	}()
+
+	// Phase 2: construct the checkpoints.
+	errs := make([]error, len(specs))
+	for i := range specs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			opt := &checkpointOptions{}
+			for _, fn := range specs[i].Options {
+				fn(opt)
+			}
+			errs[i] = specs[i].DB.writeCheckpoint(specs[i].DestDir, opt, states[i])
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		for i := range specs {
+			if errs[i] == nil {
+				_ = specs[i].DB.opts.FS.RemoveAll(specs[i].DestDir)
+			}
+		}
+		return GroupCheckpointResult{}, err
+	}
+	return res, nil
+}
+
+// captureGroupCheckpointState rotates the WAL and captures the state of the DB
+// to checkpoint, as part of a group checkpoint. It returns the captured state
+// and the sequence number that would be assigned to the next write. File
+// deletions are disabled until the caller re-enables them.
+//
+// The commit pipeline must be locked by the caller.
+func (d *DB) captureGroupCheckpointState() (checkpointState, base.SeqNum) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Rotating the WAL closes (and syncs) the WAL holding the writes committed
+	// before the commit pipeline was locked. The subsequent writes go to WALs
+	// with higher file numbers, which are excluded from the checkpoint.
+	var walCutoff base.DiskFileNum
+	rotate := !d.opts.DisableWAL && !d.opts.ReadOnly
+	if rotate {
+		walCutoff = d.rotateWALAndMemtableLocked()
+	}
+	d.disableFileDeletions()
+	d.mu.versions.logLock()
+	state := d.captureCheckpointStateLocked()
+	d.mu.versions.logUnlock()
+	if rotate {
+		logs := state.logs[:0]
+		for _, log := range state.logs {
+			if log.Num < wal.NumWAL(walCutoff) {
+				logs = append(logs, log)
+			}
+		}
+		state.logs = logs
+	}
+	return state, base.SeqNum(d.mu.versions.logSeqNum.Load())
+}
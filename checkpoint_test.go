@@ -7,9 +7,11 @@ package pebble
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand/v2"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
@@ -17,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/cockroachdb/datadriven"
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/vfs"
@@ -420,6 +423,43 @@ func TestCheckpointFlushWAL(t *testing.T) {
 	}
 }
 
+func TestCheckpointDropReadsFromPageCache(t *testing.T) {
+	dir := t.TempDir()
+	// Fail hard links, so that the tables are copied (and dropped from the
+	// page cache) too.
+	opts := &Options{FS: noLinkFS{vfs.Default}, Logger: testLogger{t: t}}
+	opts.Local.DropCheckpointReadsFromPageCache = true
+	d, err := Open(filepath.Join(dir, "db"), opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("flushed"), []byte("value"), Sync))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("unflushed"), []byte("value"), Sync))
+	checkpointDir := filepath.Join(dir, "checkpoint")
+	require.NoError(t, d.Checkpoint(checkpointDir))
+	require.NoError(t, d.Close())
+
+	// The files copied into the checkpoint, and dropped from the page cache
+	// afterwards, contain both writes.
+	d, err = Open(checkpointDir, &Options{FS: vfs.Default, Logger: testLogger{t: t}})
+	require.NoError(t, err)
+	for _, k := range []string{"flushed", "unflushed"} {
+		v, closer, err := d.Get([]byte(k))
+		require.NoError(t, err)
+		require.Equal(t, "value", string(v))
+		require.NoError(t, closer.Close())
+	}
+	require.NoError(t, d.Close())
+}
+
+// noLinkFS is a vfs.FS that doesn't support hard links.
+type noLinkFS struct {
+	vfs.FS
+}
+
+func (noLinkFS) Link(oldname, newname string) error {
+	return errors.New("hard links not supported")
+}
+
 func TestCheckpointManyFiles(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping because of short flag")
@@ -479,3 +519,158 @@ func TestCheckpointManyFiles(t *testing.T) {
 		require.Equal(t, 10, n)
 	}
 }
+
+func TestCheckpointGroup(t *testing.T) {
+	fs := vfs.NewCrashableMem()
+	opts := func() *Options {
+		return &Options{FS: fs, Logger: testLogger{t: t}, MemTableSize: 64 << 10}
+	}
+	d1, err := Open("db1", opts())
+	require.NoError(t, err)
+	d2, err := Open("db2", opts())
+	require.NoError(t, err)
+
+	// Write keys to both DBs, first to d1 and then to d2, without syncing.
+	key := func(i int) []byte { return []byte(fmt.Sprintf("%06d", i)) }
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			require.NoError(t, d1.Set(key(i), key(i), NoSync))
+			require.NoError(t, d2.Set(key(i), key(i), NoSync))
+		}
+	}()
+	// Wait for some of the keys to be flushed.
+	for n := 0; d2.Metrics().Flush.Count == 0 || n < 1000; {
+		if _, closer, err := d2.Get(key(n)); err == nil {
+			require.NoError(t, closer.Close())
+			n++
+		}
+	}
+
+	_, err = CheckpointGroup([]GroupCheckpointSpec{
+		{DB: d1, DestDir: "checkpoints/db1"},
+		{DB: d1, DestDir: "checkpoints/db1-dup"},
+	})
+	require.Error(t, err)
+
+	res, err := CheckpointGroup([]GroupCheckpointSpec{
+		{DB: d1, DestDir: "checkpoints/db1"},
+		{DB: d2, DestDir: "checkpoints/db2"},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.SeqNums, 2)
+	close(stop)
+	wg.Wait()
+	require.NoError(t, d1.Close())
+	require.NoError(t, d2.Close())
+
+	// The checkpoints don't rely on unsynced data.
+	fs = fs.CrashClone(vfs.CrashCloneCfg{UnsyncedDataPercent: 0})
+	countKeys := func(dir string) int {
+		d, err := Open(dir, opts())
+		require.NoError(t, err)
+		defer func() { require.NoError(t, d.Close()) }()
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		n := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			require.Equal(t, key(n), iter.Key())
+			n++
+		}
+		require.NoError(t, iter.Close())
+		return n
+	}
+	// Every key written to d2 before the consistent point was written to d1
+	// before it too, and at most one key written to d1 before the point was
+	// not yet written to d2.
+	n1, n2 := countKeys("checkpoints/db1"), countKeys("checkpoints/db2")
+	require.GreaterOrEqual(t, n2, 1000)
+	require.True(t, n1 == n2 || n1 == n2+1, "n1=%d n2=%d", n1, n2)
+	require.Equal(t, base.SeqNumStart+base.SeqNum(n1), res.SeqNums[0])
+	require.Equal(t, base.SeqNumStart+base.SeqNum(n2), res.SeqNums[1])
+}
+
+func TestVerifyCheckpoint(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{FS: mem, Logger: testLogger{t: t}, DisableAutomaticCompactions: true}
+	d, err := Open("db", opts)
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 100; j++ {
+			key := []byte(fmt.Sprintf("%d-%03d", i, j))
+			require.NoError(t, d.Set(key, key, nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	// The last write is only in the WAL.
+	require.NoError(t, d.Set([]byte("wal"), nil, nil))
+	require.NoError(t, d.Checkpoint("checkpoint"))
+	formatVers := d.FormatMajorVersion()
+	require.NoError(t, d.Close())
+
+	verifyOpts := &Options{FS: mem, Logger: testLogger{t: t}}
+	report, err := VerifyCheckpoint("checkpoint", verifyOpts, WithBlockChecksumVerification(1))
+	require.NoError(t, err)
+	require.True(t, report.OK())
+	require.Equal(t, formatVers, report.FormatMajorVersion)
+	require.Len(t, report.Tables, 2)
+	for _, tv := range report.Tables {
+		require.True(t, tv.ChecksumsVerified)
+		require.Empty(t, tv.Error)
+	}
+	// The report is machine-readable.
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"checksums_verified":true`)
+
+	// Corrupt a block of the first table, and truncate the second one.
+	path := func(fileNum base.DiskFileNum) string {
+		return base.MakeFilepath(mem, "checkpoint", base.FileTypeTable, fileNum)
+	}
+	readFile := func(name string) []byte {
+		f, err := mem.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		return data
+	}
+	writeFile := func(name string, data []byte) {
+		f, err := mem.Create(name, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	corrupted, truncated := report.Tables[0].FileNum, report.Tables[1].FileNum
+	data = readFile(path(corrupted))
+	data[10] ^= 0xff
+	writeFile(path(corrupted), data)
+	data = readFile(path(truncated))
+	writeFile(path(truncated), data[:len(data)-1])
+
+	report, err = VerifyCheckpoint("checkpoint", verifyOpts, WithBlockChecksumVerification(1))
+	require.Error(t, err)
+	require.False(t, report.OK())
+	require.Len(t, report.Problems, 2)
+	require.Contains(t, report.Tables[0].Error, "checksum")
+	require.Contains(t, report.Tables[1].Error, "size mismatch")
+
+	// Without checksum verification, only the truncated table is reported.
+	report, err = VerifyCheckpoint("checkpoint", verifyOpts)
+	require.Error(t, err)
+	require.Len(t, report.Problems, 1)
+	require.Empty(t, report.Tables[0].Error)
+
+	report, err = VerifyCheckpoint("missing", verifyOpts)
+	require.Error(t, err)
+	require.False(t, report.OK())
+}
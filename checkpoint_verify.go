@@ -0,0 +1,200 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// verifyCheckpointOptions hold the optional parameters of VerifyCheckpoint.
+type verifyCheckpointOptions struct {
+	// checksumSampleFraction is the fraction of the tables whose block checksums
+	// are verified.
+	checksumSampleFraction float64
+}
+
+// VerifyCheckpointOption sets optional parameters used by VerifyCheckpoint.
+type VerifyCheckpointOption func(*verifyCheckpointOptions)
+
+// WithBlockChecksumVerification enables the verification of the checksums of
+// all the blocks of a random sample of the tables of the checkpoint. fraction
+// is the fraction of the tables that are sampled; a fraction of 1 verifies all
+// the tables.
+func WithBlockChecksumVerification(fraction float64) VerifyCheckpointOption {
+	return func(opt *verifyCheckpointOptions) {
+		opt.checksumSampleFraction = fraction
+	}
+}
+
+// CheckpointVerification is the report produced by VerifyCheckpoint. It can be
+// marshaled to JSON.
+type CheckpointVerification struct {
+	// Dir is the verified directory.
+	Dir string `json:"dir"`
+	// FormatMajorVersion is the format major version of the checkpoint.
+	FormatMajorVersion FormatMajorVersion `json:"format_major_version"`
+	// Manifest and Options are the paths of the MANIFEST and OPTIONS files of
+	// the checkpoint.
+	Manifest string `json:"manifest,omitempty"`
+	Options  string `json:"options,omitempty"`
+	// Tables describes the tables referenced by the MANIFEST.
+	Tables []TableVerification `json:"tables,omitempty"`
+	// Problems lists all the problems found, including those of the tables.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// OK returns true if no problems were found.
+func (v *CheckpointVerification) OK() bool {
+	return len(v.Problems) == 0
+}
+
+// TableVerification describes the verification of one of the tables referenced
+// by a checkpoint's MANIFEST. Virtual tables that share a backing are verified
+// once, through their backing.
+type TableVerification struct {
+	FileNum base.DiskFileNum `json:"file_num"`
+	Level   int              `json:"level"`
+	// Size is the size of the table recorded in the MANIFEST.
+	Size uint64 `json:"size"`
+	// Remote is true if the table is stored on remote storage. Remote tables
+	// aren't verified.
+	Remote bool `json:"remote,omitempty"`
+	// ChecksumsVerified is true if the checksums of all the blocks of the table
+	// were verified.
+	ChecksumsVerified bool `json:"checksums_verified,omitempty"`
+	// Error describes the problem found with the table, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// VerifyCheckpoint validates a checkpoint (or any database directory that is
+// not in use) end-to-end, without modifying it: the directory must contain a
+// database in a supported format major version whose MANIFEST, OPTIONS and WAL
+// files can be loaded, and all the local tables referenced by the MANIFEST
+// must exist with the expected sizes. The block checksums of a sample of the
+// tables are also verified if WithBlockChecksumVerification is passed.
+//
+// opts must be compatible with the options the database was created with (in
+// particular, the comparer and merger must match); opts.FS determines the
+// filesystem holding the checkpoint.
+//
+// The returned report is always non-nil. The returned error is non-nil if the
+// checkpoint failed the verification, in which case it combines all the
+// problems of the report.
+func VerifyCheckpoint(
+	dirname string, opts *Options, verifyOpts ...VerifyCheckpointOption,
+) (*CheckpointVerification, error) {
+	vopt := &verifyCheckpointOptions{}
+	for _, fn := range verifyOpts {
+		fn(vopt)
+	}
+	report := &CheckpointVerification{Dir: dirname}
+	var errs []error
+	addProblem := func(err error) {
+		errs = append(errs, err)
+		report.Problems = append(report.Problems, err.Error())
+	}
+	finish := func() (*CheckpointVerification, error) {
+		return report, errors.Join(errs...)
+	}
+
+	opts = opts.Clone()
+	opts.EnsureDefaults()
+	desc, err := Peek(dirname, opts.FS)
+	if err != nil {
+		addProblem(err)
+		return finish()
+	}
+	if !desc.Exists {
+		addProblem(errors.Newf("pebble: no database found in %q", dirname))
+		return finish()
+	}
+	report.FormatMajorVersion = desc.FormatMajorVersion
+	report.Manifest = desc.ManifestFilename
+	report.Options = desc.OptionsFilename
+
+	// Opening the checkpoint read-only loads the MANIFEST and the OPTIONS file,
+	// and replays the WALs in memory. The consistency of the tables is checked
+	// below, to report all the problematic tables.
+	opts.ReadOnly = true
+	opts.ErrorIfNotExists = true
+	opts.DisableConsistencyCheck = true
+	d, err := Open(dirname, opts)
+	if err != nil {
+		addProblem(err)
+		return finish()
+	}
+
+	readState := d.loadReadState()
+	seen := make(map[base.DiskFileNum]struct{})
+	for level, lm := range readState.current.Levels {
+		for f := range lm.All() {
+			fileNum := f.FileBacking.DiskFileNum
+			if _, ok := seen[fileNum]; ok {
+				continue
+			}
+			seen[fileNum] = struct{}{}
+			tv := TableVerification{FileNum: fileNum, Level: level, Size: f.FileBacking.Size}
+			if err := d.verifyCheckpointTable(f, &tv, vopt); err != nil {
+				err = errors.Wrapf(err, "L%d: %s", errors.Safe(level), fileNum)
+				tv.Error = err.Error()
+				addProblem(err)
+			}
+			report.Tables = append(report.Tables, tv)
+		}
+	}
+	readState.unref()
+	if err := d.Close(); err != nil {
+		addProblem(err)
+	}
+	return finish()
+}
+
+// verifyCheckpointTable verifies the backing of a table of a checkpoint opened
+// by VerifyCheckpoint, filling in tv.
+func (d *DB) verifyCheckpointTable(
+	f *tableMetadata, tv *TableVerification, opt *verifyCheckpointOptions,
+) error {
+	meta, err := d.objProvider.Lookup(base.FileTypeTable, tv.FileNum)
+	if err != nil {
+		return err
+	}
+	if meta.IsRemote() {
+		tv.Remote = true
+		return nil
+	}
+	size, err := d.objProvider.Size(meta)
+	if err != nil {
+		return err
+	}
+	if size != int64(tv.Size) {
+		return errors.Errorf("object size mismatch (%s): %d (disk) != %d (MANIFEST)",
+			d.objProvider.Path(meta), errors.Safe(size), errors.Safe(tv.Size))
+	}
+	if opt.checksumSampleFraction <= 0 || rand.Float64() >= opt.checksumSampleFraction {
+		return nil
+	}
+	if f.Virtual {
+		err = d.fileCache.withVirtualReader(context.TODO(), block.NoReadEnv,
+			f.VirtualMeta(), func(v sstable.VirtualReader, _ block.ReadEnv) error {
+				return v.ValidateBlockChecksumsOnBacking()
+			})
+	} else {
+		err = d.fileCache.withReader(context.TODO(), block.NoReadEnv,
+			f.PhysicalMeta(), func(r *sstable.Reader, _ block.ReadEnv) error {
+				return r.ValidateBlockChecksums()
+			})
+	}
+	if err != nil {
+		return errors.Wrap(err, "verifying block checksums")
+	}
+	tv.ChecksumsVerified = true
+	return nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/humanize"
+)
+
+// LeakReport describes the resources that were still in use when a DB was
+// closed through DB.CloseReport.
+type LeakReport struct {
+	// OpenIterators is the number of iterators that were not closed.
+	OpenIterators int
+	// Iterators describes the iterators that were not closed. It is only
+	// populated if Options.Experimental.IteratorLeakThreshold is set; see
+	// OpenIterators otherwise.
+	Iterators []LeakedIterator
+	// Snapshots describes the snapshots (including the eventually file-only
+	// snapshots that hadn't transitioned to file-only) that were not closed.
+	Snapshots []LeakedSnapshot
+	// Unflushed describes the memtables, and the large batches queued for
+	// flushing as is, whose contents hadn't been flushed. Their contents are
+	// recovered from the WAL when the DB is reopened (and lost if the WAL is
+	// disabled).
+	Unflushed []UnflushedMemTable
+	// PendingJobs are the background jobs that were running or queued when the
+	// DB was closed. Close waits for the running flushes and compactions to
+	// complete, and drops the queued jobs.
+	PendingJobs []BackgroundJob
+}
+
+// LeakedIterator describes an iterator that was not closed.
+type LeakedIterator struct {
+	// CreatedAt is the time at which the iterator was created.
+	CreatedAt time.Time
+	// Stack is the stack trace of the iterator's creation, if
+	// Options.Experimental.IteratorLeakStacks is set.
+	Stack []byte
+}
+
+// LeakedSnapshot describes a snapshot that was not closed.
+type LeakedSnapshot struct {
+	SeqNum base.SeqNum
+	// CreatedAt is the time at which the snapshot was created.
+	CreatedAt time.Time
+	// Stack is the stack trace of the snapshot's creation, if
+	// Options.Experimental.IteratorLeakThreshold and IteratorLeakStacks are
+	// set.
+	Stack []byte
+}
+
+// UnflushedMemTable describes a memtable whose contents weren't flushed.
+type UnflushedMemTable struct {
+	// LargeBatch is true if the memtable is a batch that was too large to be
+	// applied to a memtable, and was queued for flushing as is.
+	LargeBatch bool
+	// Bytes is the size of the memtable's contents.
+	Bytes uint64
+	// LogNum is the WAL that contains the memtable's contents.
+	LogNum base.DiskFileNum
+}
+
+// Empty returns true if no resources were still in use.
+func (r *LeakReport) Empty() bool {
+	return r.OpenIterators == 0 && len(r.Snapshots) == 0 && len(r.Unflushed) == 0 &&
+		len(r.PendingJobs) == 0
+}
+
+// String implements fmt.Stringer.
+func (r *LeakReport) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "open iterators: %d\n", r.OpenIterators)
+	for _, it := range r.Iterators {
+		fmt.Fprintf(&buf, "  iterator created at %s\n", it.CreatedAt.Format(time.RFC3339Nano))
+		writeStack(&buf, it.Stack)
+	}
+	fmt.Fprintf(&buf, "open snapshots: %d\n", len(r.Snapshots))
+	for _, s := range r.Snapshots {
+		fmt.Fprintf(&buf, "  snapshot at seqnum %s", s.SeqNum)
+		if !s.CreatedAt.IsZero() {
+			fmt.Fprintf(&buf, " created at %s", s.CreatedAt.Format(time.RFC3339Nano))
+		}
+		buf.WriteString("\n")
+		writeStack(&buf, s.Stack)
+	}
+	fmt.Fprintf(&buf, "unflushed memtables: %d\n", len(r.Unflushed))
+	for _, m := range r.Unflushed {
+		kind := "memtable"
+		if m.LargeBatch {
+			kind = "large batch"
+		}
+		fmt.Fprintf(&buf, "  %s of %s in WAL %s\n", kind, humanize.Bytes.Uint64(m.Bytes), m.LogNum)
+	}
+	fmt.Fprintf(&buf, "pending background jobs: %d\n", len(r.PendingJobs))
+	for _, j := range r.PendingJobs {
+		fmt.Fprintf(&buf, "  %s\n", j)
+	}
+	return buf.String()
+}
+
+func writeStack(buf *strings.Builder, stack []byte) {
+	if len(stack) == 0 {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(stack), "\n"), "\n") {
+		fmt.Fprintf(buf, "    %s\n", line)
+	}
+}
+
+// CloseReport closes the DB, like Close, and returns a report of the
+// resources that were still in use: the iterators and snapshots that were not
+// closed, the memtables that weren't flushed and the background jobs that
+// were pending. The same restrictions as for Close apply; in particular, Close
+// returns an error if iterators or snapshots were leaked, which CloseReport
+// returns as well.
+func (d *DB) CloseReport() (LeakReport, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	r := LeakReport{PendingJobs: d.BackgroundJobs()}
+
+	r.OpenIterators = int(d.iters.count.Load())
+	d.iters.mu.Lock()
+	for _, ti := range d.iters.mu.iters {
+		r.Iterators = append(r.Iterators, LeakedIterator{CreatedAt: ti.createdAt, Stack: ti.stack})
+	}
+	d.iters.mu.Unlock()
+	slices.SortFunc(r.Iterators, func(a, b LeakedIterator) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	d.mu.Lock()
+	for s := d.mu.snapshots.root.next; s != &d.mu.snapshots.root; s = s.next {
+		if s.sample || s.named {
+			// The DB closes the snapshots retaining its history.
+			continue
+		}
+		r.Snapshots = append(r.Snapshots, LeakedSnapshot{
+			SeqNum:    s.seqNum,
+			CreatedAt: s.createdAt,
+			Stack:     s.stack,
+		})
+	}
+	for _, mem := range d.mu.mem.queue {
+		switch f := mem.flushable.(type) {
+		case *memTable:
+			if !f.empty() {
+				r.Unflushed = append(r.Unflushed, UnflushedMemTable{Bytes: f.inuseBytes(), LogNum: mem.logNum})
+			}
+		case *flushableBatch:
+			r.Unflushed = append(r.Unflushed, UnflushedMemTable{
+				LargeBatch: true, Bytes: f.inuseBytes(), LogNum: mem.logNum,
+			})
+		}
+	}
+	d.mu.Unlock()
+
+	return r, d.Close()
+}
+
+// trackSnapshot records the creation of a snapshot, for reporting it by
+// CloseReport if it's leaked.
+func (d *DB) trackSnapshot(s *Snapshot) {
+	s.createdAt = d.timeNow()
+	if d.iters.leakThreshold > 0 && d.iters.captureStacks {
+		s.stack = debug.Stack()
+	}
+}
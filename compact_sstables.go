@@ -0,0 +1,270 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/compact"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/keyspan/keyspanimpl"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// CompactSSTablesOptions configures CompactSSTables.
+type CompactSSTablesOptions struct {
+	// ReaderOptions are used to open the inputs. ReaderOptions.Merger is used
+	// to combine merge operands and defaults to DefaultMerger.
+	ReaderOptions sstable.ReaderOptions
+	// WriterOptions are used to write the outputs. WriterOptions.Comparer is
+	// used to order the keys and must be the comparer the inputs were written
+	// with; it defaults to DefaultComparer. A filter policy (e.g. a bloom
+	// filter) is added to the outputs if WriterOptions.FilterPolicy is set.
+	WriterOptions sstable.WriterOptions
+	// TargetFileSize is the desired size of an output table. In practice, the
+	// sizes can vary between 50%-200% of this value. Defaults to 64 MB.
+	TargetFileSize int64
+	// ElideTombstones indicates that no data lies beneath the inputs, so that
+	// the tombstones are applied and dropped instead of being carried over to
+	// the outputs, and the sequence numbers of the point keys are zeroed. This
+	// is the case when the outputs are ingested into an empty key range.
+	ElideTombstones bool
+	// OrderBySource ignores the sequence numbers of the inputs and orders the
+	// keys by input instead, as if the inputs had been ingested in order: the
+	// keys of an input shadow the keys of the inputs that precede it. This is
+	// useful when the inputs were built for ingestion, in which case all their
+	// keys have the sequence number zero.
+	OrderBySource bool
+	// NewOutput is called to create the writable of the i-th output table.
+	NewOutput func(i int) (objstorage.Writable, error)
+}
+
+func (o *CompactSSTablesOptions) ensureDefaults() {
+	if o.WriterOptions.Comparer == nil {
+		o.WriterOptions.Comparer = base.DefaultComparer
+	}
+	o.ReaderOptions.Comparer = o.WriterOptions.Comparer
+	if o.ReaderOptions.Merger == nil {
+		o.ReaderOptions.Merger = base.DefaultMerger
+	}
+	o.WriterOptions.MergerName = o.ReaderOptions.Merger.Name
+	if o.TargetFileSize <= 0 {
+		o.TargetFileSize = 64 << 20
+	}
+}
+
+// CompactSSTables merges the input sstables into a sequence of non-overlapping
+// output sstables, without requiring a DB. The keys are processed like in a
+// compaction of the inputs: for each user key, the keys shadowed by a newer key
+// are dropped, merge operands are combined, and the point keys and range keys
+// covered by newer range deletions and range key deletions are removed. This is
+// useful to consolidate the sstables produced by an offline pipeline before
+// ingesting them.
+//
+// The inputs must not contain the same user key with the same sequence number,
+// unless OrderBySource is set. Inputs with blob references are not supported.
+//
+// CompactSSTables takes ownership of the inputs, which are closed when it
+// returns. It returns the metadata of the outputs, in key order; on error, the
+// outputs created so far may be incomplete and must be discarded.
+func CompactSSTables(
+	ctx context.Context, inputs []objstorage.Readable, opts CompactSSTablesOptions,
+) (_ []*sstable.WriterMetadata, err error) {
+	opts.ensureDefaults()
+	cmp := opts.WriterOptions.Comparer.Compare
+
+	readers := make([]*sstable.Reader, 0, len(inputs))
+	defer func() {
+		for _, r := range readers {
+			err = errors.CombineErrors(err, r.Close())
+		}
+	}()
+	for i, input := range inputs {
+		r, err := sstable.NewReader(ctx, input, opts.ReaderOptions)
+		if err != nil {
+			// NewReader closes the input on error.
+			for _, input := range inputs[i+1:] {
+				_ = input.Close()
+			}
+			return nil, err
+		}
+		readers = append(readers, r)
+		if r.Properties.NumValuesInBlobFiles > 0 {
+			for _, input := range inputs[i+1:] {
+				_ = input.Close()
+			}
+			return nil, errors.New("pebble: compacting sstables with blob references is not supported")
+		}
+	}
+
+	// Compute the bounds of the inputs, which contain the outputs.
+	var bounds base.UserKeyBounds
+	var nonEmpty bool
+	extend := func(start []byte, end base.UserKeyBoundary) {
+		if !nonEmpty {
+			bounds = base.UserKeyBounds{Start: start, End: end}
+			nonEmpty = true
+			return
+		}
+		if cmp(start, bounds.Start) < 0 {
+			bounds.Start = start
+		}
+		if end.CompareUpperBounds(cmp, bounds.End) > 0 {
+			bounds.End = end
+		}
+	}
+	for _, r := range readers {
+		if err := sstableUserKeyBounds(ctx, r, extend); err != nil {
+			return nil, err
+		}
+	}
+	if !nonEmpty {
+		// All the inputs are empty.
+		return nil, nil
+	}
+
+	iters := make([]internalIterator, 0, len(readers))
+	var rangeDelIters, rangeKeyIters []keyspan.FragmentIterator
+	closeIters := func() {
+		for _, iter := range iters {
+			_ = iter.Close()
+		}
+		for _, iter := range rangeDelIters {
+			iter.Close()
+		}
+		for _, iter := range rangeKeyIters {
+			iter.Close()
+		}
+	}
+	for i, r := range readers {
+		var seqNum sstable.SyntheticSeqNum
+		if opts.OrderBySource {
+			seqNum = sstable.SyntheticSeqNum(i + 1)
+		}
+		pointIter, err := r.NewCompactionIter(
+			sstable.IterTransforms{SyntheticSeqNum: seqNum}, block.NoReadEnv,
+			sstable.MakeTrivialReaderProvider(r),
+		)
+		if err != nil {
+			closeIters()
+			return nil, err
+		}
+		iters = append(iters, pointIter)
+		transforms := sstable.FragmentIterTransforms{SyntheticSeqNum: seqNum}
+		rangeDelIter, err := r.NewRawRangeDelIter(ctx, transforms, block.NoReadEnv)
+		if err != nil {
+			closeIters()
+			return nil, err
+		}
+		if rangeDelIter != nil {
+			rangeDelIters = append(rangeDelIters, rangeDelIter)
+		}
+		rangeKeyIter, err := r.NewRawRangeKeyIter(ctx, transforms, block.NoReadEnv)
+		if err != nil {
+			closeIters()
+			return nil, err
+		}
+		if rangeKeyIter != nil {
+			rangeKeyIters = append(rangeKeyIters, rangeKeyIter)
+		}
+	}
+
+	// Combine the input iterators like a compaction does (see
+	// compaction.newInputIters).
+	var stats base.InternalIteratorStats
+	pointIter := newMergingIter(nil /* logger */, &stats, cmp, nil /* split */, iters...)
+	var rangeDelIter, rangeKeyIter keyspan.FragmentIterator
+	if len(rangeDelIters) > 0 {
+		mi := &keyspanimpl.MergingIter{}
+		mi.Init(opts.WriterOptions.Comparer, keyspan.NoopTransform, new(keyspanimpl.MergingBuffers), rangeDelIters...)
+		rangeDelIter = mi
+	}
+	if len(rangeKeyIters) > 0 {
+		mi := &keyspanimpl.MergingIter{}
+		mi.Init(opts.WriterOptions.Comparer, keyspan.NoopTransform, new(keyspanimpl.MergingBuffers), rangeKeyIters...)
+		di := &keyspan.DefragmentingIter{}
+		di.Init(opts.WriterOptions.Comparer, mi, keyspan.DefragmentInternal, keyspan.StaticDefragmentReducer, new(keyspan.DefragmentingBuffers))
+		rangeKeyIter = di
+	}
+
+	elision := compact.NoTombstoneElision()
+	if opts.ElideTombstones {
+		elision = compact.ElideTombstonesOutsideOf(nil)
+	}
+	iter := compact.NewIter(compact.IterConfig{
+		Comparer:         opts.WriterOptions.Comparer,
+		Merge:            opts.ReaderOptions.Merger.Merge,
+		TombstoneElision: elision,
+		RangeKeyElision:  elision,
+		AllowZeroSeqNum:  opts.ElideTombstones,
+	}, pointIter, rangeDelIter, rangeKeyIter)
+	runner := compact.NewRunner(compact.RunnerConfig{
+		CompactionBounds:     bounds,
+		TargetOutputFileSize: uint64(opts.TargetFileSize),
+		GrantHandle:          noopGrantHandle{},
+	}, iter)
+	for i := 0; runner.MoreDataToWrite(); i++ {
+		w, err := opts.NewOutput(i)
+		if err != nil {
+			return nil, runner.Finish().WithError(err).Err
+		}
+		runner.WriteTable(objstorage.ObjectMetadata{}, sstable.NewRawWriter(w, opts.WriterOptions))
+	}
+	result := runner.Finish()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	metas := make([]*sstable.WriterMetadata, len(result.Tables))
+	for i := range result.Tables {
+		metas[i] = &result.Tables[i].WriterMeta
+	}
+	return metas, nil
+}
+
+// sstableUserKeyBounds calls extend with the bounds of the point keys, of the
+// range deletions and of the range keys of the given table, if any.
+func sstableUserKeyBounds(
+	ctx context.Context, r *sstable.Reader, extend func(start []byte, end base.UserKeyBoundary),
+) error {
+	iter, err := r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */)
+	if err != nil {
+		return err
+	}
+	if kv := iter.First(); kv != nil {
+		start := kv.K.Clone().UserKey
+		if kv = iter.Last(); kv != nil {
+			extend(start, base.UserKeyInclusive(kv.K.Clone().UserKey))
+		}
+	}
+	if err := errors.CombineErrors(iter.Error(), iter.Close()); err != nil {
+		return err
+	}
+
+	spanBounds := func(iter keyspan.FragmentIterator, err error) error {
+		if err != nil || iter == nil {
+			return err
+		}
+		defer iter.Close()
+		first, err := iter.First()
+		if err != nil || first == nil {
+			return err
+		}
+		start := append([]byte(nil), first.Start...)
+		last, err := iter.Last()
+		if err != nil || last == nil {
+			return err
+		}
+		extend(start, base.UserKeyExclusive(append([]byte(nil), last.End...)))
+		return nil
+	}
+	if err := spanBounds(r.NewRawRangeDelIter(ctx, sstable.NoFragmentTransforms, block.NoReadEnv)); err != nil {
+		return err
+	}
+	return spanBounds(r.NewRawRangeKeyIter(ctx, sstable.NoFragmentTransforms, block.NoReadEnv))
+}
@@ -0,0 +1,189 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactSSTables(t *testing.T) {
+	fs := vfs.NewMem()
+	writerOpts := sstable.WriterOptions{TableFormat: sstable.TableFormatPebblev4}
+	writeInput := func(name string, fn func(w *sstable.Writer)) {
+		f, err := fs.Create(name, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), writerOpts)
+		fn(w)
+		require.NoError(t, w.Close())
+	}
+	key := func(s string) base.InternalKey {
+		return base.ParseInternalKey(s)
+	}
+	openInputs := func(names ...string) []objstorage.Readable {
+		var inputs []objstorage.Readable
+		for _, name := range names {
+			f, err := fs.Open(name)
+			require.NoError(t, err)
+			readable, err := sstable.NewSimpleReadable(f)
+			require.NoError(t, err)
+			inputs = append(inputs, readable)
+		}
+		return inputs
+	}
+	var outputs []string
+	newOutput := func(i int) (objstorage.Writable, error) {
+		name := fmt.Sprintf("out-%d.sst", i)
+		outputs = append(outputs, name)
+		f, err := fs.Create(name, vfs.WriteCategoryUnspecified)
+		if err != nil {
+			return nil, err
+		}
+		return objstorageprovider.NewFileWritable(f), nil
+	}
+	// describe returns the keys and spans of the outputs.
+	describe := func() string {
+		var buf strings.Builder
+		for _, name := range outputs {
+			fmt.Fprintf(&buf, "%s:\n", name)
+			f, err := fs.Open(name)
+			require.NoError(t, err)
+			readable, err := sstable.NewSimpleReadable(f)
+			require.NoError(t, err)
+			r, err := sstable.NewReader(context.Background(), readable, sstable.ReaderOptions{})
+			require.NoError(t, err)
+			iter, err := r.NewIter(sstable.NoTransforms, nil, nil)
+			require.NoError(t, err)
+			for kv := iter.First(); kv != nil; kv = iter.Next() {
+				v, _, err := kv.Value(nil)
+				require.NoError(t, err)
+				fmt.Fprintf(&buf, "  %s:%s\n", kv.K, v)
+			}
+			require.NoError(t, iter.Close())
+			for _, newIter := range []func(context.Context, sstable.FragmentIterTransforms, block.ReadEnv) (keyspan.FragmentIterator, error){
+				r.NewRawRangeDelIter, r.NewRawRangeKeyIter,
+			} {
+				iter, err := newIter(context.Background(), sstable.NoFragmentTransforms, block.NoReadEnv)
+				require.NoError(t, err)
+				if iter == nil {
+					continue
+				}
+				span, err := iter.First()
+				for ; span != nil; span, err = iter.Next() {
+					fmt.Fprintf(&buf, "  %s\n", span)
+				}
+				require.NoError(t, err)
+				iter.Close()
+			}
+			require.NoError(t, r.Close())
+		}
+		outputs = outputs[:0]
+		return buf.String()
+	}
+
+	writeInput("a.sst", func(w *sstable.Writer) {
+		require.NoError(t, w.Raw().Add(key("a#1,SET"), []byte("a1"), false))
+		require.NoError(t, w.Raw().Add(key("b#2,SET"), []byte("b2"), false))
+		require.NoError(t, w.Raw().Add(key("c#3,MERGE"), []byte("c3"), false))
+		require.NoError(t, w.Raw().Add(key("e#4,SET"), []byte("e4"), false))
+		require.NoError(t, w.RangeKeySet([]byte("x"), []byte("z"), []byte("@1"), []byte("v")))
+	})
+	writeInput("b.sst", func(w *sstable.Writer) {
+		require.NoError(t, w.Raw().Add(key("a#5,SET"), []byte("a5"), false))
+		require.NoError(t, w.Raw().Add(key("c#6,MERGE"), []byte("c6"), false))
+		require.NoError(t, w.Raw().Add(key("d#7,DEL"), nil, false))
+		require.NoError(t, w.Raw().EncodeSpan(keyspan.Span{
+			Start: []byte("b"), End: []byte("c"),
+			Keys: []keyspan.Key{{Trailer: base.MakeTrailer(8, base.InternalKeyKindRangeDelete)}},
+		}))
+	})
+
+	// Without elision, the tombstones are kept to apply to the data beneath the
+	// outputs.
+	metas, err := CompactSSTables(context.Background(), openInputs("a.sst", "b.sst"), CompactSSTablesOptions{
+		WriterOptions: writerOpts,
+		NewOutput:     newOutput,
+	})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.Equal(t, strings.Join([]string{
+		"out-0.sst:",
+		"  a#5,SET:a5",
+		"  c#6,MERGE:c3c6",
+		"  d#7,DEL:",
+		"  e#4,SET:e4",
+		"  b-c:{(#8,RANGEDEL)}",
+		"  x-z:{(#0,RANGEKEYSET,@1,v)}",
+		"",
+	}, "\n"), describe())
+
+	// With elision, the tombstones are dropped and the sequence numbers are
+	// zeroed. The outputs get a filter block.
+	metas, err = CompactSSTables(context.Background(), openInputs("a.sst", "b.sst"), CompactSSTablesOptions{
+		WriterOptions: sstable.WriterOptions{
+			TableFormat:  sstable.TableFormatPebblev4,
+			FilterPolicy: bloom.FilterPolicy(10),
+		},
+		ElideTombstones: true,
+		NewOutput:       newOutput,
+	})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.Equal(t, "rocksdb.BuiltinBloomFilter", metas[0].Properties.FilterPolicyName)
+	require.Equal(t, strings.Join([]string{
+		"out-0.sst:",
+		"  a#0,SET:a5",
+		"  c#0,MERGE:c3c6",
+		"  e#0,SET:e4",
+		"  x-z:{(#0,RANGEKEYSET,@1,v)}",
+		"",
+	}, "\n"), describe())
+
+	// Inputs built for ingestion all use the sequence number zero; ordering by
+	// source makes the later inputs win. The outputs are split by size.
+	for i, name := range []string{"c.sst", "d.sst"} {
+		writeInput(name, func(w *sstable.Writer) {
+			for j := 0; j < 1000; j++ {
+				if j%2 == i {
+					require.NoError(t, w.Set([]byte(fmt.Sprintf("key-%04d", j)), []byte(name)))
+				}
+			}
+			require.NoError(t, w.Set([]byte("key-1000"), []byte(name)))
+		})
+	}
+	metas, err = CompactSSTables(context.Background(), openInputs("c.sst", "d.sst"), CompactSSTablesOptions{
+		WriterOptions:   sstable.WriterOptions{TableFormat: sstable.TableFormatPebblev4, BlockSize: 256},
+		TargetFileSize:  4 << 10,
+		ElideTombstones: true,
+		OrderBySource:   true,
+		NewOutput:       newOutput,
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(metas), 1)
+	var n uint64
+	for i := range metas {
+		n += metas[i].Properties.NumEntries
+		if i > 0 {
+			require.Less(t, string(metas[i-1].LargestPoint.UserKey), string(metas[i].SmallestPoint.UserKey))
+		}
+	}
+	require.Equal(t, uint64(1001), n)
+	require.Equal(t, "key-1000#0,SET", metas[len(metas)-1].LargestPoint.String())
+	desc := describe()
+	require.Contains(t, desc, "key-0000#0,SET:c.sst")
+	require.Contains(t, desc, "key-1000#0,SET:d.sst")
+}
@@ -13,6 +13,7 @@ import (
 	"runtime/pprof"
 	"slices"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -67,7 +68,11 @@ func expandedCompactionByteSizeLimit(opts *Options, level int, availBytes uint64
 // maxGrandparentOverlapBytes is the maximum bytes of overlap with level+1
 // before we stop building a single file in a level-1 to level compaction.
 func maxGrandparentOverlapBytes(opts *Options, level int) uint64 {
-	return uint64(10 * opts.Level(level).TargetFileSize)
+	factor := opts.Experimental.MaxGrandparentOverlapFactor
+	if factor <= 0 {
+		factor = defaultMaxGrandparentOverlapFactor
+	}
+	return uint64(factor) * uint64(opts.Level(level).TargetFileSize)
 }
 
 // maxReadCompactionBytes is used to prevent read compactions which
@@ -112,7 +117,7 @@ type compactionWritable struct {
 	objstorage.Writable
 
 	versions *versionSet
-	written  *int64
+	written  *atomic.Int64
 }
 
 // Write is part of the objstorage.Writable interface.
@@ -121,7 +126,7 @@ func (c *compactionWritable) Write(p []byte) error {
 		return err
 	}
 
-	*c.written += int64(len(p))
+	c.written.Add(int64(len(p)))
 	c.versions.incrementCompactionBytes(int64(len(p)))
 	return nil
 }
@@ -145,6 +150,13 @@ const (
 	compactionKindTombstoneDensity
 	compactionKindRewrite
 	compactionKindIngestedFlushable
+	// compactionKindTiering denotes a copy compaction of a bottommost-level
+	// sstable from local storage onto shared storage, performed by remote
+	// tiering. The output is in the same level as the input.
+	compactionKindTiering
+	// compactionKindSuggested denotes a compaction of a key range suggested
+	// through DB.SuggestCompaction.
+	compactionKindSuggested
 )
 
 func (k compactionKind) String() string {
@@ -169,6 +181,10 @@ func (k compactionKind) String() string {
 		return "ingested-flushable"
 	case compactionKindCopy:
 		return "copy"
+	case compactionKindTiering:
+		return "tiering"
+	case compactionKindSuggested:
+		return "suggested"
 	}
 	return "?"
 }
@@ -182,10 +198,15 @@ type compaction struct {
 	cancel atomic.Bool
 
 	kind compactionKind
+	// jobID is the ID of the job running the compaction. It is zero until the
+	// compaction starts running. Protected by DB.mu.
+	jobID JobID
 	// isDownload is true if this compaction was started as part of a Download
 	// operation. In this case kind is compactionKindCopy or
 	// compactionKindRewrite.
 	isDownload bool
+	// manual is true if this is a manual compaction.
+	manual bool
 
 	cmp       Compare
 	equal     Equal
@@ -244,8 +265,17 @@ type compaction struct {
 
 	// flushing contains the flushables (aka memtables) that are being flushed.
 	flushing flushableList
-	// bytesWritten contains the number of bytes that have been written to outputs.
-	bytesWritten int64
+	// bytesWritten contains the number of bytes that have been written to
+	// outputs. It is updated by the compaction goroutine and may be read
+	// concurrently (see DB.BackgroundJobs).
+	bytesWritten atomic.Int64
+	// bytesRead contains the number of bytes of input blocks that have been
+	// read. It is updated by the compaction goroutine and may be read
+	// concurrently (see DB.BackgroundJobs).
+	bytesRead atomic.Int64
+	// subcompactionCPUTime accumulates the CPU time consumed by the goroutines
+	// running the subcompactions, if any.
+	subcompactionCPUTime atomic.Int64
 
 	// The boundaries of the input data.
 	smallest InternalKey
@@ -253,8 +283,11 @@ type compaction struct {
 
 	// A list of fragment iterators to close when the compaction finishes. Used by
 	// input iteration to keep rangeDelIters open for the lifetime of the
-	// compaction, and only close them when the compaction finishes.
-	closers []*noCloseIter
+	// compaction, and only close them when the compaction finishes. Protected
+	// by closersMu, since the subcompactions of a compaction append to it
+	// concurrently.
+	closersMu sync.Mutex
+	closers   []*noCloseIter
 
 	// grandparents are the tables in level+2 that overlap with the files being
 	// compacted. Used to determine output table boundaries. Do not assume that the actual files
@@ -283,6 +316,22 @@ type compaction struct {
 	// this compaction is allowed to excise files.
 	exciseEnabled bool
 
+	// dropEmptyVirtualTables is set to true if this is a
+	// compactionKindDeleteOnly that removes empty virtual sstables (see
+	// emptyVirtualTable) rather than sstables covered by deletionHints.
+	dropEmptyVirtualTables bool
+	// cacheEviction is set to true if this is a compactionKindDeleteOnly that
+	// drops the oldest tables of a DB configured with CachePreset (see
+	// pickCacheEvictionCompaction) rather than sstables covered by
+	// deletionHints.
+	cacheEviction bool
+
+	// keyRanges are the key ranges registered through DB.RegisterKeyRangeStats
+	// when the compaction started, and keyRangeBytes the bytes written by the
+	// compaction that are attributed to each of them.
+	keyRanges     []*keyRangeStatsEntry
+	keyRangeBytes []uint64
+
 	metrics map[int]*LevelMetrics
 
 	pickerMetrics compactionPickerMetrics
@@ -302,6 +351,20 @@ func (c *compaction) inputLargestSeqNumAbsolute() base.SeqNum {
 	return seqNum
 }
 
+// usage returns the resources used by the compaction, given the CPU time
+// consumed by the goroutine that ran it. The compaction must have completed.
+func (c *compaction) usage(cpuTime time.Duration) CompactionUsage {
+	s := &c.stats
+	return CompactionUsage{
+		Count:           1,
+		CPUTime:         cpuTime + time.Duration(c.subcompactionCPUTime.Load()),
+		BytesReadLocal:  s.BlockBytes - s.BlockBytesInCache - s.RemoteBlockBytes,
+		BytesReadRemote: s.RemoteBlockBytes,
+		BytesReadCache:  s.BlockBytesInCache,
+		BytesWritten:    uint64(c.bytesWritten.Load()),
+	}
+}
+
 func (c *compaction) makeInfo(jobID JobID) CompactionInfo {
 	info := CompactionInfo{
 		JobID:       int(jobID),
@@ -343,6 +406,12 @@ func (c *compaction) makeInfo(jobID JobID) CompactionInfo {
 	if len(info.Input) > 2 {
 		info.Annotations = append(info.Annotations, "multilevel")
 	}
+	if c.dropEmptyVirtualTables {
+		info.Annotations = append(info.Annotations, "empty-virtual")
+	}
+	if c.cacheEviction {
+		info.Annotations = append(info.Annotations, "cache-eviction")
+	}
 	return info
 }
 
@@ -376,6 +445,7 @@ func newCompaction(
 		maxOverlapBytes:   pc.maxOverlapBytes,
 		pickerMetrics:     pc.pickerMetrics,
 		grantHandle:       grantHandle,
+		manual:            pc.manualID > 0,
 	}
 	c.startLevel = &c.inputs[0]
 	if pc.startLevel.l0SublevelInfo != nil {
@@ -427,6 +497,13 @@ func newCompaction(
 			c.kind = compactionKindMove
 		}
 	}
+	// The grandparent overlap limit only applies to the output tables, so it's
+	// only adjusted once the compaction is known to write tables. Adjusting it
+	// before would prevent moves that the unadjusted limit permits.
+	if opts.Experimental.AdaptiveGrandparentOverlap && c.kind == compactionKindDefault &&
+		c.outputLevel.level+1 < numLevels {
+		adjustGrandparentOverlapBytesForSkew(c, opts.Experimental.LevelMultiplier)
+	}
 	return c
 }
 
@@ -529,6 +606,35 @@ func adjustGrandparentOverlapBytesForFlush(c *compaction, flushingBytes uint64)
 	}
 }
 
+// adjustGrandparentOverlapBytesForSkew lowers the grandparent overlap limit of a
+// compaction whose key range holds more data in the grandparent level than
+// expected from the level multiplier. With a uniform key distribution, the
+// grandparent level holds roughly levelMultiplier times the data of the
+// compaction's inputs across their key range, and the limit lets the output
+// tables reach their target size. With a skewed distribution, the data in the
+// grandparent level can be concentrated in the key range of the compaction;
+// each output table then overlaps a large amount of grandparent data, which
+// would all be rewritten when the table is compacted into the grandparent
+// level. Lowering the limit in proportion to the skew bounds the size of those
+// future compactions, at the cost of smaller tables. The limit is not lowered
+// below twice the target file size, to avoid producing tiny tables.
+func adjustGrandparentOverlapBytesForSkew(c *compaction, levelMultiplier int) {
+	var inputBytes uint64
+	for i := range c.inputs {
+		inputBytes += c.inputs[i].files.SizeSum()
+	}
+	grandparentBytes := c.grandparents.SizeSum()
+	if inputBytes == 0 || grandparentBytes == 0 || levelMultiplier <= 0 {
+		return
+	}
+	skew := float64(grandparentBytes) / (float64(inputBytes) * float64(levelMultiplier))
+	if skew <= 1 {
+		return
+	}
+	minOverlapBytes := min(2*c.maxOutputFileSize, c.maxOverlapBytes)
+	c.maxOverlapBytes = max(uint64(float64(c.maxOverlapBytes)/skew), minOverlapBytes)
+}
+
 func newFlush(
 	opts *Options,
 	cur *version,
@@ -848,7 +954,7 @@ func (c *compaction) newInputIters(
 					continue
 				}
 				rangeDelIters = append(rangeDelIters, rangeDelIter)
-				c.closers = append(c.closers, rangeDelIter)
+				c.addCloser(rangeDelIter)
 			}
 
 			// Check if this level has any range keys.
@@ -872,7 +978,7 @@ func (c *compaction) newInputIters(
 					// requires the range keys to be held in memory for up to the
 					// lifetime of the compaction.
 					noCloseIter := &noCloseIter{rangeKeyIter}
-					c.closers = append(c.closers, noCloseIter)
+					c.addCloser(noCloseIter)
 
 					// We do not need to truncate range keys to sstable boundaries, or
 					// only read within the file's atomic compaction units, unlike with
@@ -916,7 +1022,7 @@ func (c *compaction) newInputIters(
 	// iter.
 	pointIter = iters[0]
 	if len(iters) > 1 {
-		pointIter = newMergingIter(c.logger, &c.stats, c.cmp, nil, iters...)
+		pointIter = newMergingIter(c.logger, iiopts.readEnv.Stats, c.cmp, nil, iters...)
 	}
 
 	// In normal operation, levelIter iterates over the point operations in a
@@ -962,7 +1068,7 @@ func (c *compaction) newRangeDelIter(
 	iterSet, err := newIters(context.Background(), f.TableMetadata, &opts,
 		internalIterOpts{
 			compaction: true,
-			readEnv:    block.ReadEnv{BufferPool: &c.bufferPool},
+			readEnv:    block.ReadEnv{BufferPool: iiopts.readEnv.BufferPool},
 		}, iterRangeDeletions)
 	if err != nil {
 		return nil, err
@@ -977,6 +1083,23 @@ func (c *compaction) newRangeDelIter(
 	return &noCloseIter{iterSet.rangeDeletion}, nil
 }
 
+// addCloser adds a fragment iterator to close when the compaction finishes.
+func (c *compaction) addCloser(iter *noCloseIter) {
+	c.closersMu.Lock()
+	defer c.closersMu.Unlock()
+	c.closers = append(c.closers, iter)
+}
+
+// closeClosers closes the fragment iterators kept open for the lifetime of
+// the compaction.
+func (c *compaction) closeClosers() {
+	c.closersMu.Lock()
+	defer c.closersMu.Unlock()
+	for _, closer := range c.closers {
+		closer.FragmentIterator.Close()
+	}
+}
+
 func (c *compaction) String() string {
 	if len(c.flushing) != 0 {
 		return "flush\n"
@@ -1127,6 +1250,9 @@ func (d *DB) maybeScheduleFlush() {
 	if d.mu.compact.flushing || d.closed.Load() != nil || d.opts.ReadOnly {
 		return
 	}
+	if d.backgroundWorkSuspended() {
+		return
+	}
 	if len(d.mu.mem.queue) <= 1 {
 		return
 	}
@@ -1167,7 +1293,7 @@ func (d *DB) passedFlushThreshold() bool {
 	return size >= minFlushSize
 }
 
-func (d *DB) maybeScheduleDelayedFlush(tbl *memTable, dur time.Duration) {
+func (d *DB) maybeScheduleDelayedFlush(tbl *memTable, dur time.Duration, reason FlushReason) {
 	var mem *flushableEntry
 	for _, m := range d.mu.mem.queue {
 		if m.flushable == tbl {
@@ -1210,9 +1336,9 @@ func (d *DB) maybeScheduleDelayedFlush(tbl *memTable, dur time.Duration) {
 			}
 
 			if d.mu.mem.mutable == tbl {
-				d.makeRoomForWrite(nil)
+				d.makeRoomForWrite(nil, reason)
 			} else {
-				mem.flushForced = true
+				mem.forceFlush(reason)
 			}
 			d.maybeScheduleFlush()
 		}
@@ -1228,8 +1354,9 @@ func (d *DB) flush() {
 		var bytesFlushed uint64
 		var err error
 		if bytesFlushed, err = d.flush1(); err != nil {
-			// TODO(peter): count consecutive flush errors and backoff.
-			d.opts.EventListener.BackgroundError(err)
+			d.handleBackgroundError(err)
+		} else {
+			d.handleBackgroundSuccess()
 		}
 		d.mu.compact.flushing = false
 		d.mu.compact.noOngoingFlushStartTime = crtime.NowMono()
@@ -1351,6 +1478,9 @@ func (d *DB) runIngestFlush(c *compaction) (*manifest.VersionEdit, error) {
 				if err != nil {
 					return nil, err
 				}
+				if len(ingestFlushable.files) == 0 {
+					markExciseRemnants(newFiles)
+				}
 
 				if _, ok := ve.DeletedTables[deletedFileEntry{
 					Level:   l,
@@ -1450,6 +1580,10 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 		// queue.
 		inputs = n
 	}
+	// The flush is attributed to the newest flushable, which is the one that
+	// triggered it: the older ones were not enough to pass the flush threshold
+	// on their own.
+	reason := d.mu.mem.queue[n-1].flushReason
 
 	// Require that every memtable being flushed has a log number less than the
 	// new minimum unflushed log number.
@@ -1473,10 +1607,14 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 	d.addInProgressCompaction(c)
 
 	jobID := d.newJobIDLocked()
+	c.jobID = jobID
 	d.opts.EventListener.FlushBegin(FlushInfo{
 		JobID:      int(jobID),
+		Reason:     reason.String(),
 		Input:      inputs,
 		InputBytes: inputBytes,
+		Smallest:   c.smallest,
+		Largest:    c.largest,
 		Ingest:     ingest,
 	})
 	startTime := d.timeNow()
@@ -1503,6 +1641,7 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 
 	info := FlushInfo{
 		JobID:      int(jobID),
+		Reason:     reason.String(),
 		Input:      inputs,
 		InputBytes: inputBytes,
 		Duration:   d.timeNow().Sub(startTime),
@@ -1581,6 +1720,9 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 		if err != nil {
 			info.Err = err
 		}
+		if len(ve.InlineTables) > 0 {
+			d.finishInliningTables(ve.InlineTables, err == nil)
+		}
 	} else {
 		// We won't be performing the logAndApply step because of the error, so
 		// logUnlock. We don't need to invalidate the pickedCompactionCache since
@@ -1594,6 +1736,8 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 		d.mu.snapshots.cumulativePinnedCount += stats.CumulativePinnedKeys
 		d.mu.snapshots.cumulativePinnedSize += stats.CumulativePinnedSize
 		d.mu.versions.metrics.Keys.MissizedTombstonesCount += stats.CountMissizedDels
+		d.recordCompactionBytesLocked(c)
+		d.recordKeyRangeBytesLocked(c)
 	}
 
 	d.clearCompactingState(c, err != nil)
@@ -1613,6 +1757,12 @@ func (d *DB) flush1() (bytesFlushed uint64, err error) {
 				d.mu.versions.metrics.Flush.AsIngestTableCount += l.TablesIngested
 			}
 		}
+		rm := &d.mu.versions.metrics.Flush.ByReason[reason]
+		rm.Count++
+		for _, l := range c.metrics {
+			rm.Bytes += l.BytesFlushed + l.BytesIngested
+		}
+		rm.Duration += info.Duration
 		d.maybeTransitionSnapshotsToFileOnlyLocked()
 
 	}
@@ -1789,7 +1939,7 @@ func (d *DB) maybeScheduleCompaction() {
 //
 // REQUIRES: d.mu and d.mu.versions.logLock are held.
 func (d *DB) makeCompactionEnvLocked() *compactionEnv {
-	if d.closed.Load() != nil || d.opts.ReadOnly {
+	if d.closed.Load() != nil || d.opts.ReadOnly || d.backgroundWorkSuspended() || d.mu.compact.closing {
 		return nil
 	}
 	return &compactionEnv{
@@ -1802,14 +1952,20 @@ func (d *DB) makeCompactionEnvLocked() *compactionEnv {
 			flushing:                 d.mu.compact.flushing || d.passedFlushThreshold(),
 			rescheduleReadCompaction: &d.mu.compact.rescheduleReadCompaction,
 		},
+		suggestedCompactions: &d.mu.compact.suggestedCompactions,
 	}
 }
 
 // pickAnyCompaction tries to pick a manual or automatic compaction.
 func (d *DB) pickAnyCompaction(env compactionEnv) (pc *pickedCompaction) {
 	pc = d.pickManualCompaction(env)
-	if pc == nil && !d.opts.DisableAutomaticCompactions {
+	// A DB configured with CachePreset never compacts its tables
+	// automatically; it only drops the oldest ones.
+	if pc == nil && !d.automaticCompactionsDisabled() && d.opts.Preset != CachePreset {
 		pc = d.mu.versions.picker.pickAuto(env)
+		if pc == nil {
+			pc = d.pickTieringCompaction(env)
+		}
 	}
 	return pc
 }
@@ -1907,7 +2063,7 @@ func (d *DB) GetWaitingCompaction() (bool, WaitingCompaction) {
 func (d *DB) GetAllowedWithoutPermission() int {
 	allowedBasedOnBacklog := int(d.mu.versions.curCompactionConcurrency.Load())
 	allowedBasedOnManual := 0
-	manualBacklog := int(d.mu.compact.manualLen.Load())
+	manualBacklog := int(d.mu.compact.manualLen.Load()) + int(d.mu.compact.subcompactionDemand.Load())
 	if manualBacklog > 0 {
 		maxAllowed := d.opts.MaxConcurrentCompactions()
 		allowedBasedOnManual = min(maxAllowed, manualBacklog+allowedBasedOnBacklog)
@@ -1972,28 +2128,141 @@ func (d *DB) pickManualCompaction(env compactionEnv) (pc *pickedCompaction) {
 //
 // Returns true iff a compaction was started.
 func (d *DB) tryScheduleDeleteOnlyCompaction() bool {
-	if d.opts.private.disableDeleteOnlyCompactions || d.opts.DisableAutomaticCompactions ||
-		d.mu.compact.compactingCount >= d.opts.MaxConcurrentCompactions() ||
-		len(d.mu.compact.deletionHints) == 0 {
+	if d.opts.private.disableDeleteOnlyCompactions || d.automaticCompactionsDisabled() ||
+		d.mu.compact.compactingCount >= d.opts.MaxConcurrentCompactions() {
 		return false
 	}
-	v := d.mu.versions.currentVersion()
-	snapshots := d.mu.snapshots.toSlice()
-	// We need to save the value of exciseEnabled in the compaction itself, as
-	// it can change dynamically between now and when the compaction runs.
-	exciseEnabled := d.FormatMajorVersion() >= FormatVirtualSSTables &&
-		d.opts.Experimental.EnableDeleteOnlyCompactionExcises != nil && d.opts.Experimental.EnableDeleteOnlyCompactionExcises()
-	inputs, resolvedHints, unresolvedHints := checkDeleteCompactionHints(d.cmp, v, d.mu.compact.deletionHints, snapshots, exciseEnabled)
-	d.mu.compact.deletionHints = unresolvedHints
+	c := d.pickCacheEvictionCompaction()
+	if c == nil {
+		c = d.pickDeleteOnlyCompaction()
+	}
+	if c == nil {
+		return false
+	}
+	d.mu.compact.compactingCount++
+	d.addInProgressCompaction(c)
+	go d.compact(c, nil)
+	return true
+}
 
+// pickDeleteOnlyCompaction returns a delete-only compaction for the files that
+// can be deleted as suggested by deletionHints or, if there are none, for the
+// empty virtual tables. It returns nil if there are no files to delete.
+//
+// Requires d.mu to be held. Updates d.mu.compact.deletionHints and
+// d.mu.compact.emptyVirtualTables.
+func (d *DB) pickDeleteOnlyCompaction() *compaction {
+	if len(d.mu.compact.deletionHints) == 0 && len(d.mu.compact.emptyVirtualTables) == 0 {
+		return nil
+	}
+	v := d.mu.versions.currentVersion()
+	if len(d.mu.compact.deletionHints) > 0 {
+		snapshots := d.mu.snapshots.toSlice()
+		// We need to save the value of exciseEnabled in the compaction itself, as
+		// it can change dynamically between now and when the compaction runs.
+		exciseEnabled := d.FormatMajorVersion() >= FormatVirtualSSTables &&
+			d.opts.Experimental.EnableDeleteOnlyCompactionExcises != nil && d.opts.Experimental.EnableDeleteOnlyCompactionExcises()
+		inputs, resolvedHints, unresolvedHints := checkDeleteCompactionHints(d.cmp, v, d.mu.compact.deletionHints, snapshots, exciseEnabled)
+		d.mu.compact.deletionHints = unresolvedHints
+		d.mu.compact.deleteOnlyEligible.valid = false
+
+		if len(inputs) > 0 {
+			return newDeleteOnlyCompaction(d.opts, v, inputs, d.timeNow(), resolvedHints, exciseEnabled)
+		}
+	}
+	// Any empty virtual tables are dropped by a separate delete-only
+	// compaction. If a compaction for deletion hints was picked above, the
+	// empty virtual tables will be considered the next time a compaction is
+	// scheduled.
+	inputs, unresolved := checkEmptyVirtualTables(d.cmp, v, d.mu.compact.emptyVirtualTables)
+	d.mu.compact.emptyVirtualTables = unresolved
 	if len(inputs) > 0 {
-		c := newDeleteOnlyCompaction(d.opts, v, inputs, d.timeNow(), resolvedHints, exciseEnabled)
+		c := newDeleteOnlyCompaction(d.opts, v, inputs, d.timeNow(), nil /* hints */, false /* exciseEnabled */)
+		c.dropEmptyVirtualTables = true
+		return c
+	}
+	return nil
+}
+
+// deleteOnlyEligibleFiles returns the number and total size of the files that
+// the pending deletion hints would allow to delete, if a delete-only
+// compaction was run now. Files that would only be excised are not counted.
+//
+// The result is cached until the deletion hints, the current version or the
+// open snapshots change.
+//
+// Requires d.mu to be held. d.mu.compact.deletionHints is not modified.
+func (d *DB) deleteOnlyEligibleFiles() (count int64, size uint64) {
+	if len(d.mu.compact.deletionHints) == 0 {
+		return 0, 0
+	}
+	cached := &d.mu.compact.deleteOnlyEligible
+	if cached.valid && cached.snapshotsGen == d.mu.snapshots.gen {
+		return cached.count, cached.size
+	}
+	hints := slices.Clone(d.mu.compact.deletionHints)
+	inputs, _, _ := checkDeleteCompactionHints(d.cmp, d.mu.versions.currentVersion(), hints,
+		d.mu.snapshots.toSlice(), false /* exciseEnabled */)
+	for _, cl := range inputs {
+		count += int64(cl.files.Len())
+		size += cl.files.SizeSum()
+	}
+	cached.valid, cached.snapshotsGen = true, d.mu.snapshots.gen
+	cached.count, cached.size = count, size
+	return count, size
+}
+
+// CompactDeletedRanges synchronously drops the tables that are entirely
+// deleted by range deletions and range key deletions, without waiting for the
+// automatic delete-only compactions to do so. It returns once no such table
+// remains, except for the tables that can't be dropped yet because an open
+// snapshot may still read them.
+//
+// The tables deleted by range deletions are identified when the stats of the
+// tables containing the range deletions are loaded (see
+// Options.DisableTableStats), so CompactDeletedRanges first waits for pending
+// table stats to be loaded. Unlike the automatic delete-only compactions, the
+// compactions run by CompactDeletedRanges are not subject to
+// Options.MaxConcurrentCompactions, and run even if automatic compactions are
+// disabled.
+func (d *DB) CompactDeletedRanges() error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for !d.opts.DisableTableStats && d.closed.Load() == nil &&
+		(d.mu.tableStats.loading || len(d.mu.tableStats.pending) > 0 || !d.mu.tableStats.loadedInitial) {
+		d.maybeCollectTableStatsLocked()
+		d.mu.tableStats.cond.Wait()
+	}
+	for {
+		if d.closed.Load() != nil {
+			return ErrClosed
+		}
+		d.mu.versions.logLock()
+		c := d.pickDeleteOnlyCompaction()
+		if c == nil {
+			d.mu.versions.logUnlock()
+			return nil
+		}
 		d.mu.compact.compactingCount++
 		d.addInProgressCompaction(c)
-		go d.compact(c, nil)
-		return true
+		d.mu.versions.pickedCompactionCache.invalidate()
+		d.mu.versions.logUnlock()
+
+		errCh := make(chan error, 1)
+		go d.compact(c, errCh)
+		d.mu.Unlock()
+		err := <-errCh
+		d.mu.Lock()
+		if err != nil {
+			return err
+		}
 	}
-	return false
 }
 
 // deleteCompactionHintType indicates whether the deleteCompactionHint was
@@ -2305,6 +2574,57 @@ func checkDeleteCompactionHints(
 	return compactLevels, resolvedHints, unresolvedHints
 }
 
+// emptyVirtualTable describes a virtual sstable, discovered during table stats
+// collection, that contains no live keys (see DB.isEmptyVirtualTable). Once no
+// sstable in a lower level overlaps the table, its tombstones cannot shadow
+// any keys and the table may be removed from the LSM by a delete-only
+// compaction without rewriting any data. If the table holds the last
+// reference to its backing, the backing is removed by the same version edit
+// (see VersionEdit.RemovedBackingTables) and becomes obsolete.
+type emptyVirtualTable struct {
+	level int
+	meta  *tableMetadata
+}
+
+// checkEmptyVirtualTables returns the empty virtual tables in v that may be
+// dropped, grouped by level. Tables that are currently being compacted are
+// returned in unresolved so that they may be reconsidered once the compaction
+// completes. All other tables are forgotten: either they are no longer in v,
+// or they overlap data in a lower level and will be compacted normally.
+func checkEmptyVirtualTables(
+	cmp Compare, v *version, tables []emptyVirtualTable,
+) (levels []compactionLevel, unresolved []emptyVirtualTable) {
+	var byLevel [numLevels][]*tableMetadata
+	for _, t := range tables {
+		if !v.Contains(t.level, t.meta) {
+			continue
+		}
+		if t.meta.IsCompacting() {
+			unresolved = append(unresolved, t)
+			continue
+		}
+		overlapsBeneath := false
+		for l := t.level + 1; l < numLevels && !overlapsBeneath; l++ {
+			overlaps := v.Overlaps(l, t.meta.UserKeyBounds())
+			overlapsBeneath = !overlaps.Empty()
+		}
+		if overlapsBeneath || slices.Contains(byLevel[t.level], t.meta) {
+			continue
+		}
+		byLevel[t.level] = append(byLevel[t.level], t.meta)
+	}
+	for l, files := range byLevel {
+		if len(files) == 0 {
+			continue
+		}
+		levels = append(levels, compactionLevel{
+			level: l,
+			files: manifest.NewLevelSliceKeySorted(cmp, files),
+		})
+	}
+	return levels, unresolved
+}
+
 func (d *DB) compactionPprofLabels(c *compaction) pprof.LabelSet {
 	activity := "compact"
 	if len(c.flushing) != 0 {
@@ -2329,6 +2649,8 @@ func (d *DB) compact(c *compaction, errChannel chan error) {
 		c.grantHandle.Started()
 		if err := d.compact1(c, errChannel); err != nil {
 			d.handleCompactFailure(err)
+		} else {
+			d.handleBackgroundSuccess()
 		}
 		if c.isDownload {
 			d.mu.compact.downloadingCount--
@@ -2373,8 +2695,7 @@ func (d *DB) handleCompactFailure(err error) {
 		d.opts.Logger.Infof("%v", err)
 		return
 	}
-	// TODO(peter): count consecutive compaction errors and backoff.
-	d.opts.EventListener.BackgroundError(err)
+	d.handleBackgroundError(err)
 }
 
 // cleanupVersionEdit cleans up any on-disk artifacts that were created
@@ -2438,11 +2759,16 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 	}
 
 	jobID := d.newJobIDLocked()
+	c.jobID = jobID
 	info := c.makeInfo(jobID)
 	d.opts.EventListener.CompactionBegin(info)
 	startTime := d.timeNow()
 
+	// NB: On Linux, this locks the goroutine to its OS thread until the
+	// compaction completes. See startThreadCPUMeasurement.
+	stopCPUMeasurement := startThreadCPUMeasurement()
 	ve, stats, err := d.runCompaction(jobID, c)
+	cpuTime := stopCPUMeasurement()
 
 	info.Duration = d.timeNow().Sub(startTime)
 	if err == nil {
@@ -2455,9 +2781,8 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 			// the manifest lock, we don't expect this bool to change its value
 			// as only the holder of the manifest lock will ever write to it.
 			if c.cancel.Load() {
-				d.mu.versions.metrics.Compact.CancelledCount++
-				d.mu.versions.metrics.Compact.CancelledBytes += c.bytesWritten
-
+				// The cancellation is counted in the metrics below, along with
+				// the cancellations observed by runCompaction.
 				err = firstError(err, ErrCancelledCompaction)
 				// This is the first time we've seen a cancellation during the
 				// life of this compaction (or the original condition on err == nil
@@ -2487,6 +2812,8 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 		d.mu.snapshots.cumulativePinnedCount += stats.CumulativePinnedKeys
 		d.mu.snapshots.cumulativePinnedSize += stats.CumulativePinnedSize
 		d.mu.versions.metrics.Keys.MissizedTombstonesCount += stats.CountMissizedDels
+		d.recordCompactionBytesLocked(c)
+		d.recordKeyRangeBytesLocked(c)
 	}
 
 	// NB: clearing compacting state must occur before updating the read state;
@@ -2494,10 +2821,12 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 	d.clearCompactingState(c, err != nil)
 	if err != nil && errors.Is(err, ErrCancelledCompaction) {
 		d.mu.versions.metrics.Compact.CancelledCount++
-		d.mu.versions.metrics.Compact.CancelledBytes += c.bytesWritten
+		d.mu.versions.metrics.Compact.CancelledBytes += c.bytesWritten.Load()
 	}
 	d.mu.versions.incrementCompactions(c.kind, c.extraLevels, c.pickerMetrics)
-	d.mu.versions.incrementCompactionBytes(-c.bytesWritten)
+	d.mu.versions.incrementCompactionBytes(-c.bytesWritten.Load())
+	info.Usage = c.usage(cpuTime)
+	d.mu.versions.recordCompactionUsage(c, info.Usage)
 
 	info.TotalDuration = d.timeNow().Sub(c.beganAt)
 	d.opts.EventListener.CompactionEnd(info)
@@ -2518,10 +2847,12 @@ func (d *DB) compact1(c *compaction, errChannel chan error) (err error) {
 // runCopyCompaction runs a copy compaction where a new FileNum is created that
 // is a byte-for-byte copy of the input file or span thereof in some cases. This
 // is used in lieu of a move compaction when a file is being moved across the
-// local/remote storage boundary. It could also be used in lieu of a rewrite
+// local/remote storage boundary, and by remote tiering to move a bottommost
+// file onto shared storage. It could also be used in lieu of a rewrite
 // compaction as part of a Download() call, which allows copying only a span of
 // the external file, provided the file does not contain range keys or value
-// blocks (see sstable.CopySpan).
+// blocks (see sstable.CopySpan), or copying a file on shared storage to local
+// storage.
 //
 // d.mu must be held when calling this method. The mutex will be released when
 // doing IO.
@@ -2547,8 +2878,19 @@ func (d *DB) runCopyCompaction(
 		return nil, compact.Stats{}, err
 	}
 	if !objMeta.IsExternal() {
-		if objMeta.IsRemote() || !remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level) {
-			panic("pebble: scheduled a copy compaction that is not actually moving files to shared storage")
+		switch {
+		case c.isDownload:
+			if !objMeta.IsRemote() {
+				panic("pebble: scheduled a download copy compaction of a local file")
+			}
+		case c.kind == compactionKindTiering:
+			if objMeta.IsRemote() {
+				panic("pebble: scheduled a tiering compaction of a file already on shared storage")
+			}
+		default:
+			if objMeta.IsRemote() || !remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level) {
+				panic("pebble: scheduled a copy compaction that is not actually moving files to shared storage")
+			}
 		}
 		// Note that based on logic in the compaction picker, we're guaranteed
 		// inputMeta.Virtual is false.
@@ -2572,6 +2914,8 @@ func (d *DB) runCopyCompaction(
 		Stats:                    inputMeta.Stats,
 		Virtual:                  inputMeta.Virtual,
 		SyntheticPrefixAndSuffix: inputMeta.SyntheticPrefixAndSuffix,
+		ContentPrefix:            inputMeta.ContentPrefix,
+		SuffixReplacements:       inputMeta.SuffixReplacements,
 	}
 	if inputMeta.HasPointKeys {
 		newMeta.ExtendPointKeyBounds(c.cmp, inputMeta.SmallestPointKey, inputMeta.LargestPointKey)
@@ -2585,9 +2929,15 @@ func (d *DB) runCopyCompaction(
 		// We will update this size later after we produce the new backing file.
 		newMeta.InitProviderBacking(base.DiskFileNum(newMeta.FileNum), inputMeta.FileBacking.Size)
 	} else {
-		// local -> shared copy. New file is guaranteed to not be virtual.
+		// local -> shared or shared -> local copy. New file is guaranteed to not
+		// be virtual.
 		newMeta.InitPhysicalBacking()
 	}
+	if c.isDownload {
+		// The downloaded file is new to local storage; reset its creation time so
+		// that remote tiering does not immediately move it back.
+		newMeta.CreationTime = d.timeNow().Unix()
+	}
 
 	// Before dropping the db mutex, grab a ref to the current version. This
 	// prevents any concurrent excises from deleting files that this compaction
@@ -2633,14 +2983,20 @@ func (d *DB) runCopyCompaction(
 			return nil, compact.Stats{}, err
 		}
 		deleteOnExit = true
+		if l := d.opts.IORateLimiter; l != nil {
+			w = &rateLimitedWritable{Writable: w, limiter: l, priority: IOPriorityLow}
+		}
 
 		start, end := newMeta.Smallest, newMeta.Largest
-		if newMeta.SyntheticPrefixAndSuffix.HasPrefix() {
+		if pr := newMeta.PrefixReplacement(); pr.IsSet() {
+			start.UserKey = pr.Invert(start.UserKey)
+			end.UserKey = pr.Invert(end.UserKey)
+		} else if newMeta.SyntheticPrefixAndSuffix.HasPrefix() {
 			syntheticPrefix := newMeta.SyntheticPrefixAndSuffix.Prefix()
 			start.UserKey = syntheticPrefix.Invert(start.UserKey)
 			end.UserKey = syntheticPrefix.Invert(end.UserKey)
 		}
-		if newMeta.SyntheticPrefixAndSuffix.HasSuffix() {
+		if newMeta.SyntheticPrefixAndSuffix.HasSuffix() || len(newMeta.SuffixReplacements) > 0 {
 			// Extend the bounds as necessary so that the keys don't include suffixes.
 			start.UserKey = start.UserKey[:c.comparer.Split(start.UserKey)]
 			if n := c.comparer.Split(end.UserKey); n < len(end.UserKey) {
@@ -2678,6 +3034,12 @@ func (d *DB) runCopyCompaction(
 		}
 		newMeta.FileBacking.Size = wrote
 		newMeta.Size = wrote
+	} else if objMeta.IsRemote() {
+		// shared -> local copy.
+		deleteOnExit = true
+		if err := d.copyRemoteTableToLocal(context.TODO(), inputMeta, newMeta); err != nil {
+			return nil, compact.Stats{}, err
+		}
 	} else {
 		_, err := d.objProvider.LinkOrCopyFromLocal(context.TODO(), d.opts.FS,
 			d.objProvider.Path(objMeta), base.FileTypeTable, newMeta.FileBacking.DiskFileNum,
@@ -2885,7 +3247,12 @@ func (d *DB) runDeleteOnlyCompaction(
 	}
 	for _, cl := range c.inputs {
 		levelMetrics := &LevelMetrics{}
-		if err := d.runDeleteOnlyCompactionForLevel(cl, levelMetrics, ve, snapshots, fragments, c.exciseEnabled); err != nil {
+		if c.dropEmptyVirtualTables || c.cacheEviction {
+			for f := range cl.files.All() {
+				ve.DeletedTables[deletedFileEntry{Level: cl.level, FileNum: f.FileNum}] = f
+				levelMetrics.TablesDeleted++
+			}
+		} else if err := d.runDeleteOnlyCompactionForLevel(cl, levelMetrics, ve, snapshots, fragments, c.exciseEnabled); err != nil {
 			return nil, stats, err
 		}
 		c.metrics[cl.level] = levelMetrics
@@ -2978,13 +3345,14 @@ func (d *DB) runCompaction(
 		return d.runDeleteOnlyCompaction(jobID, c, snapshots)
 	case compactionKindMove:
 		return d.runMoveCompaction(jobID, c)
-	case compactionKindCopy:
+	case compactionKindCopy, compactionKindTiering:
 		return d.runCopyCompaction(jobID, c)
 	case compactionKindIngestedFlushable:
 		panic("pebble: runCompaction cannot handle compactionKindIngestedFlushable.")
 	}
 
 	snapshots := d.mu.snapshots.toSlice()
+	c.keyRanges = d.mu.keyRangeStats
 
 	if c.flushing == nil {
 		// Before dropping the db mutex, grab a ref to the current version. This
@@ -3008,16 +3376,43 @@ func (d *DB) runCompaction(
 	d.mu.Unlock()
 	defer d.mu.Lock()
 
-	// Determine whether we should separate values into blob files.
-	//
-	// TODO(jackson): Currently we never separate values in non-tests. Choose
-	// and initialize the appropriate ValueSeparation implementation based on
-	// Options and the compaction inputs.
-	valueSeparation := c.getValueSeparation(jobID, c, tableFormat)
-
-	result := d.compactAndWrite(jobID, c, snapshots, tableFormat, valueSeparation)
+	var result compact.Result
+	var splitKeys [][]byte
+	var subcompactionGrants []CompactionGrantHandle
+	if splitKeys = c.subcompactionSplitKeys(d.opts.Experimental.MaxSubcompactions); len(splitKeys) > 0 {
+		// Every subcompaction other than the first one runs on its own
+		// goroutine, with the permission of the CompactionScheduler. If fewer
+		// subcompactions are permitted, the compaction is split in fewer ones.
+		subcompactionGrants = d.acquireSubcompactionGrants(len(splitKeys))
+		if len(subcompactionGrants) < len(splitKeys) {
+			splitKeys = c.subcompactionSplitKeys(len(subcompactionGrants) + 1)
+			for _, h := range subcompactionGrants[len(splitKeys):] {
+				h.Done()
+			}
+			subcompactionGrants = subcompactionGrants[:len(splitKeys)]
+		}
+		defer func() {
+			for _, h := range subcompactionGrants {
+				h.Done()
+			}
+		}()
+	}
+	if len(splitKeys) > 0 {
+		result = d.compactAndWriteSubcompactions(jobID, c, snapshots, tableFormat, splitKeys, subcompactionGrants)
+	} else {
+		// Determine whether we should separate values into blob files.
+		//
+		// TODO(jackson): Currently we never separate values in non-tests. Choose
+		// and initialize the appropriate ValueSeparation implementation based on
+		// Options and the compaction inputs.
+		valueSeparation := c.getValueSeparation(jobID, c, tableFormat)
+		result = d.compactAndWrite(jobID, c, snapshots, tableFormat, valueSeparation)
+	}
 	if result.Err == nil {
-		ve, result.Err = c.makeVersionEdit(result)
+		ve, result.Err = c.makeVersionEdit(result, d.opts.UserPropertyAggregators)
+	}
+	if result.Err == nil && c.flushing != nil {
+		result.Err = d.inlineFlushedTables(ve)
 	}
 	if result.Err != nil {
 		// Delete any created tables.
@@ -3085,28 +3480,57 @@ func (d *DB) compactAndWrite(
 	// translate to 3 MiB per compaction.
 	c.bufferPool.Init(12)
 	defer c.bufferPool.Release()
-	iiopts := internalIterOpts{
+	iiopts := d.compactionIterOpts(c, &c.bufferPool, &c.stats)
+
+	pointIter, rangeDelIter, rangeKeyIter, err := c.newInputIters(d.newIters, d.tableNewRangeKeyIter, iiopts)
+	defer c.closeClosers()
+	if err != nil {
+		return compact.Result{Err: err}
+	}
+	c.allowedZeroSeqNum = c.allowZeroSeqNum()
+	result = d.writeCompactionOutputs(
+		jobID, c, snapshots, tableFormat, valueSeparation, c.userKeyBounds(), c.grantHandle,
+		pointIter, rangeDelIter, rangeKeyIter,
+	)
+	if result.Err == nil {
+		result.Err = d.objProvider.Sync()
+	}
+	return result
+}
+
+// compactionIterOpts returns the options used to construct the input
+// iterators of a compaction (or of one of its subcompactions) that read blocks
+// using the given buffer pool and accumulate stats into the given stats.
+func (d *DB) compactionIterOpts(
+	c *compaction, bufferPool *sstable.BufferPool, stats *base.InternalIteratorStats,
+) internalIterOpts {
+	return internalIterOpts{
 		compaction: true,
 		readEnv: block.ReadEnv{
-			BufferPool: &c.bufferPool,
-			Stats:      &c.stats,
+			BufferPool: bufferPool,
+			Stats:      stats,
+			BytesRead:  &c.bytesRead,
 			IterStats: d.fileCache.SSTStatsCollector().Accumulator(
 				uint64(uintptr(unsafe.Pointer(c))),
 				categoryCompaction,
 			),
 		},
 	}
+}
 
-	pointIter, rangeDelIter, rangeKeyIter, err := c.newInputIters(d.newIters, d.tableNewRangeKeyIter, iiopts)
-	defer func() {
-		for _, closer := range c.closers {
-			closer.FragmentIterator.Close()
-		}
-	}()
-	if err != nil {
-		return compact.Result{Err: err}
-	}
-	c.allowedZeroSeqNum = c.allowZeroSeqNum()
+// writeCompactionOutputs sets up a compaction iterator over the given input
+// iterators and uses it to write output tables within the given bounds.
+func (d *DB) writeCompactionOutputs(
+	jobID JobID,
+	c *compaction,
+	snapshots compact.Snapshots,
+	tableFormat sstable.TableFormat,
+	valueSeparation compact.ValueSeparation,
+	bounds base.UserKeyBounds,
+	grantHandle CompactionGrantHandle,
+	pointIter internalIterator,
+	rangeDelIter, rangeKeyIter keyspan.FragmentIterator,
+) compact.Result {
 	cfg := compact.IterConfig{
 		Comparer:         c.comparer,
 		Merge:            d.merge,
@@ -3130,13 +3554,14 @@ func (d *DB) compactAndWrite(
 	iter := compact.NewIter(cfg, pointIter, rangeDelIter, rangeKeyIter)
 
 	runnerCfg := compact.RunnerConfig{
-		CompactionBounds:           base.UserKeyBoundsFromInternal(c.smallest, c.largest),
+		CompactionBounds:           bounds,
 		L0SplitKeys:                c.l0Limits,
 		Grandparents:               c.grandparents,
 		MaxGrandparentOverlapBytes: c.maxOverlapBytes,
 		TargetOutputFileSize:       c.maxOutputFileSize,
-		GrantHandle:                c.grantHandle,
+		GrantHandle:                grantHandle,
 		ValueSeparation:            valueSeparation,
+		AttributedRanges:           attributedRanges(c.keyRanges),
 	}
 	runner := compact.NewRunner(runnerCfg, iter)
 	for runner.MoreDataToWrite() {
@@ -3145,22 +3570,20 @@ func (d *DB) compactAndWrite(
 		}
 		// Create a new table.
 		writerOpts := d.opts.MakeWriterOptions(c.outputLevel.level, tableFormat)
-		objMeta, tw, err := d.newCompactionOutput(jobID, c, writerOpts)
+		objMeta, tw, err := d.newCompactionOutput(jobID, c, writerOpts, grantHandle)
 		if err != nil {
 			return runner.Finish().WithError(err)
 		}
 		runner.WriteTable(objMeta, tw)
 	}
-	result = runner.Finish()
-	if result.Err == nil {
-		result.Err = d.objProvider.Sync()
-	}
-	return result
+	return runner.Finish()
 }
 
 // makeVersionEdit creates the version edit for a compaction, based on the
 // tables in compact.Result.
-func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error) {
+func (c *compaction) makeVersionEdit(
+	result compact.Result, aggs []UserPropertyAggregator,
+) (*versionEdit, error) {
 	ve := &versionEdit{
 		DeletedTables: map[deletedFileEntry]*tableMetadata{},
 	}
@@ -3202,10 +3625,16 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		ve.NewBlobFiles[i] = result.Blobs[i].Metadata
 	}
 
+	if len(c.keyRanges) > 0 {
+		c.keyRangeBytes = make([]uint64, len(c.keyRanges))
+	}
 	inputLargestSeqNumAbsolute := c.inputLargestSeqNumAbsolute()
 	ve.NewTables = make([]newTableEntry, len(result.Tables))
 	for i := range result.Tables {
 		t := &result.Tables[i]
+		for j, b := range t.AttributedBytes {
+			c.keyRangeBytes[j] += b
+		}
 
 		fileMeta := &tableMetadata{
 			FileNum:            base.PhysicalTableFileNum(t.ObjMeta.DiskFileNum),
@@ -3230,7 +3659,7 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 		// If the file didn't contain any range deletions, we can fill its
 		// table stats now, avoiding unnecessarily loading the table later.
 		maybeSetStatsFromProperties(
-			fileMeta.PhysicalMeta(), &t.WriterMeta.Properties,
+			fileMeta.PhysicalMeta(), &t.WriterMeta.Properties, aggs,
 		)
 
 		if t.WriterMeta.HasPointKeys {
@@ -3276,11 +3705,13 @@ func (c *compaction) makeVersionEdit(result compact.Result) (*versionEdit, error
 }
 
 // newCompactionOutput creates an object for a new table produced by a
-// compaction or flush.
+// compaction or flush. The CPU consumption of the table writer is measured
+// using the given grant handle.
 func (d *DB) newCompactionOutput(
-	jobID JobID, c *compaction, writerOpts sstable.WriterOptions,
+	jobID JobID, c *compaction, writerOpts sstable.WriterOptions, grantHandle CompactionGrantHandle,
 ) (objstorage.ObjectMetadata, sstable.RawWriter, error) {
-	writable, objMeta, err := d.newCompactionOutputObj(jobID, c, base.FileTypeTable)
+	preferShared := remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level)
+	writable, objMeta, err := d.newCompactionOutputObj(jobID, c, base.FileTypeTable, preferShared)
 	if err != nil {
 		return objstorage.ObjectMetadata{}, nil, err
 	}
@@ -3305,13 +3736,14 @@ func (d *DB) newCompactionOutput(
 		},
 	})
 
-	tw := sstable.NewRawWriterWithCPUMeasurer(writable, writerOpts, c.grantHandle)
+	tw := sstable.NewRawWriterWithCPUMeasurer(writable, writerOpts, grantHandle)
 	return objMeta, tw, nil
 }
 
-// newCompactionOutputObj creates an object produced by a compaction or flush.
+// newCompactionOutputObj creates an object produced by a compaction or flush,
+// on shared storage if preferShared is true and shared storage is configured.
 func (d *DB) newCompactionOutputObj(
-	jobID JobID, c *compaction, typ base.FileType,
+	jobID JobID, c *compaction, typ base.FileType, preferShared bool,
 ) (objstorage.Writable, objstorage.ObjectMetadata, error) {
 	diskFileNum := d.mu.versions.getNextDiskFileNum()
 
@@ -3337,16 +3769,27 @@ func (d *DB) newCompactionOutputObj(
 		}
 	}
 
-	// Prefer shared storage if present.
 	createOpts := objstorage.CreateOptions{
-		PreferSharedStorage: remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level),
+		PreferSharedStorage: preferShared,
 		WriteCategory:       writeCategory,
+		ForCompaction:       c.kind != compactionKindFlush,
 	}
 	writable, objMeta, err := d.objProvider.Create(ctx, typ, diskFileNum, createOpts)
 	if err != nil {
 		return nil, objstorage.ObjectMetadata{}, err
 	}
 
+	if l := d.opts.IORateLimiter; l != nil {
+		priority := IOPriorityLow
+		if c.kind == compactionKindFlush {
+			priority = IOPriorityHigh
+		}
+		writable = &rateLimitedWritable{
+			Writable: writable,
+			limiter:  l,
+			priority: priority,
+		}
+	}
 	if c.kind != compactionKindFlush {
 		writable = &compactionWritable{
 			Writable: writable,
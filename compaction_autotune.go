@@ -0,0 +1,202 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// CompactionConcurrencyAutotuning configures the automatic tuning of the
+// upper bound on the compaction concurrency (see
+// Options.Experimental.CompactionConcurrencyAutotuning).
+//
+// When enabled, the bound returned by Options.MaxConcurrentCompactions is
+// replaced by a value in [MinConcurrency, MaxConcurrency] that is adjusted
+// periodically, by at most one at a time: it's raised towards the concurrency
+// warranted by the compaction debt and the L0 sublevel count (see
+// Options.Experimental.CompactionDebtConcurrency and L0CompactionConcurrency)
+// as long as the disk and the CPU have headroom, and lowered when there's no
+// such demand or when the disk or the CPU are saturated. A value set through
+// DB.SetOptions takes precedence over the tuned value.
+type CompactionConcurrencyAutotuning struct {
+	// MinConcurrency and MaxConcurrency bound the tuned concurrency. Autotuning
+	// is enabled iff MaxConcurrency is positive. MinConcurrency defaults to 1.
+	MinConcurrency int
+	MaxConcurrency int
+	// MaxDiskSyncLatency is the mean latency of the syncs of sstables above
+	// which the disk is considered saturated. The latency is measured over each
+	// tuning interval, and only if Options.FS is (or wraps) a
+	// vfs.InstrumentedFS. Defaults to 50ms; a negative value disables the disk
+	// signal.
+	MaxDiskSyncLatency time.Duration
+	// CPUIdleFraction, if set, returns the fraction of the CPU capacity
+	// available to the process that is currently idle, in [0, 1]. If nil, the
+	// CPU signal is disabled.
+	CPUIdleFraction func() float64
+	// MinCPUIdleFraction is the idle CPU fraction below which the CPU is
+	// considered saturated. Defaults to 0.1.
+	MinCPUIdleFraction float64
+}
+
+// enabled returns true if autotuning is enabled.
+func (a *CompactionConcurrencyAutotuning) enabled() bool {
+	return a.MaxConcurrency > 0
+}
+
+func (a *CompactionConcurrencyAutotuning) ensureDefaults() {
+	if !a.enabled() {
+		return
+	}
+	if a.MinConcurrency <= 0 {
+		a.MinConcurrency = 1
+	}
+	if a.MaxDiskSyncLatency == 0 {
+		a.MaxDiskSyncLatency = 50 * time.Millisecond
+	}
+	if a.MinCPUIdleFraction <= 0 {
+		a.MinCPUIdleFraction = 0.1
+	}
+}
+
+// compactionAutotuneInterval is the interval at which the compaction
+// concurrency is tuned.
+const compactionAutotuneInterval = 5 * time.Second
+
+// compactionAutotuneSignals are the inputs of one tuning step.
+type compactionAutotuneSignals struct {
+	// demand is the concurrency warranted by the compaction debt and the L0
+	// sublevel count.
+	demand int
+	// diskSaturated and cpuSaturated are true if the disk or the CPU have no
+	// headroom left for additional compactions.
+	diskSaturated bool
+	cpuSaturated  bool
+}
+
+// compactionAutotuner tunes the compaction concurrency. See
+// CompactionConcurrencyAutotuning.
+type compactionAutotuner struct {
+	opts CompactionConcurrencyAutotuning
+
+	mu struct {
+		sync.Mutex
+		// concurrency is the current tuned concurrency.
+		concurrency int
+		// syncSum and syncCount are the cumulative sum and count of the
+		// sstable sync latency histogram at the previous tuning step.
+		syncSum   float64
+		syncCount uint64
+	}
+}
+
+func newCompactionAutotuner(opts CompactionConcurrencyAutotuning) *compactionAutotuner {
+	t := &compactionAutotuner{opts: opts}
+	t.mu.concurrency = opts.MinConcurrency
+	return t
+}
+
+// concurrency returns the current tuned concurrency.
+func (t *compactionAutotuner) concurrency() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.mu.concurrency
+}
+
+// step adjusts the concurrency given the signals, and returns the new
+// concurrency.
+func (t *compactionAutotuner) step(s compactionAutotuneSignals) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	target := min(max(s.demand, t.opts.MinConcurrency), t.opts.MaxConcurrency)
+	c := t.mu.concurrency
+	switch {
+	case s.diskSaturated || s.cpuSaturated:
+		c--
+	case target > c:
+		c++
+	case target < c:
+		c--
+	}
+	t.mu.concurrency = min(max(c, t.opts.MinConcurrency), t.opts.MaxConcurrency)
+	return t.mu.concurrency
+}
+
+// diskSaturated returns true if the mean latency of the sstable syncs since
+// the previous call exceeds MaxDiskSyncLatency.
+func (t *compactionAutotuner) diskSaturated(io *vfs.IOMetrics) bool {
+	if io == nil || t.opts.MaxDiskSyncLatency < 0 {
+		return false
+	}
+	var m dto.Metric
+	if err := io[vfs.IOFileTypeTable].SyncLatency.Write(&m); err != nil {
+		return false
+	}
+	sum, count := m.GetHistogram().GetSampleSum(), m.GetHistogram().GetSampleCount()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	deltaSum, deltaCount := sum-t.mu.syncSum, count-t.mu.syncCount
+	t.mu.syncSum, t.mu.syncCount = sum, count
+	if deltaCount == 0 {
+		return false
+	}
+	return time.Duration(deltaSum/float64(deltaCount)) > t.opts.MaxDiskSyncLatency
+}
+
+// compactionAutotuneLoop periodically tunes the compaction concurrency. It
+// exits when the DB is closed.
+func (d *DB) compactionAutotuneLoop() {
+	defer d.compactionSchedulers.Done()
+
+	ticker := time.NewTicker(compactionAutotuneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.closedCh:
+			return
+		case <-ticker.C:
+			d.autotuneCompactionConcurrency()
+		}
+	}
+}
+
+// autotuneCompactionConcurrency performs one tuning step of the compaction
+// concurrency.
+func (d *DB) autotuneCompactionConcurrency() {
+	t := d.compactionAutotuner
+	var io *vfs.IOMetrics
+	for fs := d.opts.FS; fs != nil; fs = fs.Unwrap() {
+		if ifs, ok := fs.(*vfs.InstrumentedFS); ok {
+			io = ifs.Metrics()
+			break
+		}
+	}
+	s := compactionAutotuneSignals{diskSaturated: t.diskSaturated(io)}
+	if f := t.opts.CPUIdleFraction; f != nil {
+		s.cpuSaturated = f() < t.opts.MinCPUIdleFraction
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.mu.versions.picker.(*compactionPickerByScore)
+	if !ok {
+		return
+	}
+	s.demand = p.compactionConcurrencyDemand()
+	prev := t.concurrency()
+	c := t.step(s)
+	if c == prev {
+		return
+	}
+	d.opts.Logger.Infof("pebble: compaction concurrency tuned from %d to %d (demand %d, disk saturated %t, cpu saturated %t)",
+		prev, c, s.demand, s.diskSaturated, s.cpuSaturated)
+	d.mu.versions.curCompactionConcurrency.Store(int32(p.getCompactionConcurrency()))
+	if c > prev {
+		d.maybeScheduleCompaction()
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactionAutotunerStep(t *testing.T) {
+	tuner := newCompactionAutotuner(CompactionConcurrencyAutotuning{
+		MinConcurrency: 2,
+		MaxConcurrency: 4,
+	})
+	require.Equal(t, 2, tuner.concurrency())
+
+	testCases := []struct {
+		signals  compactionAutotuneSignals
+		expected int
+	}{
+		// The concurrency is raised one at a time towards the demand, up to
+		// MaxConcurrency.
+		{compactionAutotuneSignals{demand: 10}, 3},
+		{compactionAutotuneSignals{demand: 10}, 4},
+		{compactionAutotuneSignals{demand: 10}, 4},
+		// A saturated disk or CPU lowers the concurrency, regardless of the
+		// demand.
+		{compactionAutotuneSignals{demand: 10, diskSaturated: true}, 3},
+		{compactionAutotuneSignals{demand: 10, cpuSaturated: true}, 2},
+		{compactionAutotuneSignals{demand: 10, cpuSaturated: true}, 2},
+		{compactionAutotuneSignals{demand: 3}, 3},
+		{compactionAutotuneSignals{demand: 3}, 3},
+		// The concurrency is lowered when the demand drops, down to
+		// MinConcurrency.
+		{compactionAutotuneSignals{demand: 1}, 2},
+		{compactionAutotuneSignals{demand: 1}, 2},
+	}
+	for i, tc := range testCases {
+		require.Equal(t, tc.expected, tuner.step(tc.signals), "step %d", i)
+	}
+}
+
+func TestCompactionConcurrencyAutotuning(t *testing.T) {
+	var cpuIdle atomic.Value
+	cpuIdle.Store(1.0)
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		Logger:                      testLogger{t: t},
+		DisableAutomaticCompactions: true,
+		MaxConcurrentCompactions:    func() int { return 1 },
+	}
+	opts.Experimental.L0CompactionConcurrency = 1
+	opts.Experimental.CompactionConcurrencyAutotuning = CompactionConcurrencyAutotuning{
+		MaxConcurrency:  3,
+		CPUIdleFraction: func() float64 { return cpuIdle.Load().(float64) },
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.Equal(t, 1, d.Metrics().Compact.MaxConcurrency)
+
+	// Create overlapping L0 sublevels to raise the demand.
+	for i := 0; i < 4; i++ {
+		for _, k := range []string{"a", "z"} {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("%s%d", k, i)), nil, nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	d.autotuneCompactionConcurrency()
+	require.Equal(t, 2, d.Metrics().Compact.MaxConcurrency)
+	d.autotuneCompactionConcurrency()
+	d.autotuneCompactionConcurrency()
+	require.Equal(t, 3, d.Metrics().Compact.MaxConcurrency)
+	require.Equal(t, int32(3), d.mu.versions.curCompactionConcurrency.Load())
+
+	// A busy CPU lowers the concurrency.
+	cpuIdle.Store(0.0)
+	d.autotuneCompactionConcurrency()
+	require.Equal(t, 2, d.Metrics().Compact.MaxConcurrency)
+
+	// DB.SetOptions overrides the tuned concurrency.
+	five := 5
+	require.NoError(t, d.SetOptions(DynamicOptions{MaxConcurrentCompactions: &five}))
+	require.Equal(t, 5, d.Metrics().Compact.MaxConcurrency)
+}
@@ -39,6 +39,7 @@ type compactionEnv struct {
 	earliestSnapshotSeqNum  base.SeqNum
 	inProgressCompactions   []compactionInfo
 	readCompactionEnv       readCompactionEnv
+	suggestedCompactions    *suggestedCompactionQueue
 }
 
 type compactionPicker interface {
@@ -724,9 +725,14 @@ func (p *compactionPickerByScore) estimatedCompactionDebt(l0ExtraSize uint64) ui
 	var compactionDebt uint64
 	if bytesAddedToNextLevel > 0 && lbaseSize > 0 {
 		// We only incur compaction debt if both L0 and Lbase contain data. If L0
-		// is empty, no compaction is necessary. If Lbase is empty, a move-based
-		// compaction from L0 would occur.
+		// is empty, no compaction is necessary.
 		compactionDebt += bytesAddedToNextLevel + lbaseSize
+	} else if l0Files := p.vers.Levels[0].Len(); l0Files > 1 || (l0Files == 1 && l0ExtraSize > 0) {
+		// If Lbase is empty, a move-based compaction from L0 would occur, but
+		// only for a single file. Several files (including the flushed
+		// memtables counted in l0ExtraSize) are merged by a compaction that
+		// rewrites all of them.
+		compactionDebt += bytesAddedToNextLevel
 	}
 
 	// loop invariant: At the beginning of the loop, bytesAddedToNextLevel is the
@@ -1180,7 +1186,13 @@ func responsibleForGarbageBytes(
 }
 
 func (p *compactionPickerByScore) getCompactionConcurrency() int {
-	maxConcurrentCompactions := p.opts.MaxConcurrentCompactions()
+	return max(min(p.opts.MaxConcurrentCompactions(), p.compactionConcurrencyDemand()), 1)
+}
+
+// compactionConcurrencyDemand returns the number of concurrent compactions
+// warranted by the L0 read-amp and the compaction debt, ignoring
+// Options.MaxConcurrentCompactions.
+func (p *compactionPickerByScore) compactionConcurrencyDemand() int {
 	// Compaction concurrency is controlled by L0 read-amp. We allow one
 	// additional compaction per L0CompactionConcurrency sublevels, as well as
 	// one additional compaction per CompactionDebtConcurrency bytes of
@@ -1214,7 +1226,7 @@ func (p *compactionPickerByScore) getCompactionConcurrency() int {
 		compactionDebt := p.estimatedCompactionDebt(0)
 		compactionDebtCompactions = int(compactionDebt/p.opts.Experimental.CompactionDebtConcurrency) + 1
 	}
-	return max(min(maxConcurrentCompactions, max(l0ReadAmpCompactions, compactionDebtCompactions)), 1)
+	return max(l0ReadAmpCompactions, compactionDebtCompactions)
 }
 
 // pickAuto picks the best compaction, if any.
@@ -1330,6 +1342,11 @@ func (p *compactionPickerByScore) pickAuto(env compactionEnv) (pc *pickedCompact
 		}
 	}
 
+	// Check for key ranges that the application suggested compacting.
+	if pc := p.pickSuggestedCompaction(env); pc != nil {
+		return pc
+	}
+
 	// Check for files which contain excessive point tombstones that could slow
 	// down reads. Unlike elision-only compactions, these compactions may select
 	// a file at any level rather than only the lowest level.
@@ -1546,6 +1563,12 @@ func (p *compactionPickerByScore) pickRewriteCompaction(env compactionEnv) (pc *
 			// Try the next level.
 			continue
 		}
+		if candidate.Virtual && !candidate.StatsValid() && !p.opts.DisableTableStats {
+			// Wait for the stats of virtual tables (e.g. those left by an
+			// excise) to be loaded: if they turn out to hold no live data, they're
+			// dropped by a delete-only compaction rather than rewritten.
+			continue
+		}
 		pc := p.pickedCompactionFromCandidateFile(candidate, env, l, l, compactionKindRewrite)
 		if pc != nil {
 			return pc
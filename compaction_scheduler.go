@@ -137,6 +137,8 @@ func init() {
 	scheduledCompactionMap[compactionKindMove] = compactionOptionalAndPriority{priority: 90}
 	scheduledCompactionMap[compactionKindCopy] = compactionOptionalAndPriority{priority: 80}
 	scheduledCompactionMap[compactionKindDefault] = compactionOptionalAndPriority{priority: 70}
+	scheduledCompactionMap[compactionKindSuggested] =
+		compactionOptionalAndPriority{optional: true, priority: 65}
 	scheduledCompactionMap[compactionKindTombstoneDensity] =
 		compactionOptionalAndPriority{optional: true, priority: 60}
 	scheduledCompactionMap[compactionKindElisionOnly] =
@@ -145,6 +147,8 @@ func init() {
 		compactionOptionalAndPriority{optional: true, priority: 40}
 	scheduledCompactionMap[compactionKindRewrite] =
 		compactionOptionalAndPriority{optional: true, priority: 30}
+	scheduledCompactionMap[compactionKindTiering] =
+		compactionOptionalAndPriority{optional: true, priority: 20}
 }
 
 func makeWaitingCompaction(manual bool, kind compactionKind, score float64) WaitingCompaction {
@@ -0,0 +1,242 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/compact"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// subcompactionSplitKeys returns the user keys at which the compaction is
+// split into subcompactions (see Options.Experimental.MaxSubcompactions), or
+// nil if the compaction isn't split.
+//
+// Only manual compactions and compactions out of L0 are split. The split keys
+// are chosen among the smallest user keys of the input tables, such that the
+// subcompactions have input of roughly the same size, and each of them has at
+// least the target file size of input.
+func (c *compaction) subcompactionSplitKeys(maxSubcompactions int) [][]byte {
+	if maxSubcompactions <= 1 || len(c.flushing) != 0 || c.maxOutputFileSize == 0 {
+		return nil
+	}
+	if !c.manual && c.startLevel.level != 0 {
+		return nil
+	}
+	type boundary struct {
+		key  []byte
+		size uint64
+	}
+	var boundaries []boundary
+	var total uint64
+	for _, cl := range c.inputs {
+		for f := range cl.files.All() {
+			boundaries = append(boundaries, boundary{key: f.Smallest.UserKey, size: f.Size})
+			total += f.Size
+		}
+	}
+	n := min(uint64(maxSubcompactions), total/c.maxOutputFileSize)
+	if n <= 1 {
+		return nil
+	}
+	slices.SortStableFunc(boundaries, func(a, b boundary) int {
+		return c.cmp(a.key, b.key)
+	})
+
+	// Split before the table whose smallest key is the first to follow input of
+	// at least total/n bytes since the previous split key. The size of the
+	// input preceding a key is estimated as the sum of the sizes of the tables
+	// that start before it.
+	var splitKeys [][]byte
+	var cumulative uint64
+	for _, b := range boundaries {
+		target := total * uint64(len(splitKeys)+1) / n
+		if cumulative >= target && c.cmp(b.key, c.smallest.UserKey) > 0 &&
+			(len(splitKeys) == 0 || c.cmp(b.key, splitKeys[len(splitKeys)-1]) > 0) {
+			splitKeys = append(splitKeys, b.key)
+			if uint64(len(splitKeys)) == n-1 {
+				break
+			}
+		}
+		cumulative += b.size
+	}
+	return splitKeys
+}
+
+// acquireSubcompactionGrants asks the CompactionScheduler for the permission
+// to run up to n additional subcompactions. Each of them is accounted for like
+// a compaction by the scheduler, so that they're subject to its limits (e.g.
+// Options.MaxConcurrentCompactions). It returns the grant handles of the
+// permitted subcompactions, which must be released with Done.
+//
+// d.mu must not be held when calling this.
+func (d *DB) acquireSubcompactionGrants(n int) []CompactionGrantHandle {
+	// The demand allows the scheduler to grant more compactions than the
+	// backlog requires, like for manual compactions.
+	d.mu.compact.subcompactionDemand.Add(int32(n))
+	defer d.mu.compact.subcompactionDemand.Add(-int32(n))
+	var handles []CompactionGrantHandle
+	for len(handles) < n {
+		ok, h := d.opts.Experimental.CompactionScheduler.TrySchedule()
+		if !ok {
+			break
+		}
+		h.Started()
+		handles = append(handles, h)
+	}
+	return handles
+}
+
+// compactAndWriteSubcompactions runs the data part of a compaction as
+// concurrent subcompactions, one for each of the key ranges delimited by the
+// given split keys. Each subcompaction writes its own output tables; the
+// returned result contains the tables of all the subcompactions, in key order,
+// so that they're installed in a single version edit.
+//
+// The first subcompaction uses the grant handle of the compaction, and the
+// others use the given handles, one for each split key.
+func (d *DB) compactAndWriteSubcompactions(
+	jobID JobID,
+	c *compaction,
+	snapshots compact.Snapshots,
+	tableFormat sstable.TableFormat,
+	splitKeys [][]byte,
+	grantHandles []CompactionGrantHandle,
+) (result compact.Result) {
+	n := len(splitKeys) + 1
+	// Each subcompaction uses its own buffer pool (see compactAndWrite). The
+	// pools are released once the iterators kept open for the lifetime of the
+	// compaction, which may hold buffers from them, are closed.
+	bufferPools := make([]sstable.BufferPool, n)
+	for i := range bufferPools {
+		bufferPools[i].Init(12)
+	}
+	defer func() {
+		for i := range bufferPools {
+			bufferPools[i].Release()
+		}
+	}()
+	defer c.closeClosers()
+	c.allowedZeroSeqNum = c.allowZeroSeqNum()
+
+	compactionBounds := c.userKeyBounds()
+	results := make([]compact.Result, n)
+	stats := make([]base.InternalIteratorStats, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		bounds := compactionBounds
+		var lower, upper []byte
+		if i > 0 {
+			lower = splitKeys[i-1]
+			bounds.Start = lower
+		}
+		if i < n-1 {
+			upper = splitKeys[i]
+			bounds.End = base.UserKeyExclusive(upper)
+		}
+		// Each grant handle expects a single primary compaction goroutine.
+		grantHandle := c.grantHandle
+		if i > 0 {
+			grantHandle = grantHandles[i-1]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stopCPUMeasurement := startThreadCPUMeasurement()
+			defer func() {
+				c.subcompactionCPUTime.Add(int64(stopCPUMeasurement()))
+			}()
+			iiopts := d.compactionIterOpts(c, &bufferPools[i], &stats[i])
+			results[i] = d.runSubcompaction(jobID, c, snapshots, tableFormat, iiopts, bounds, lower, upper, grantHandle)
+		}()
+	}
+	wg.Wait()
+
+	for i := range results {
+		result.Err = errors.CombineErrors(result.Err, results[i].Err)
+		result.Tables = append(result.Tables, results[i].Tables...)
+		result.Blobs = append(result.Blobs, results[i].Blobs...)
+		result.Stats.CumulativePinnedKeys += results[i].Stats.CumulativePinnedKeys
+		result.Stats.CumulativePinnedSize += results[i].Stats.CumulativePinnedSize
+		result.Stats.CumulativeWrittenSize += results[i].Stats.CumulativeWrittenSize
+		result.Stats.CumulativeBlobReferenceSize += results[i].Stats.CumulativeBlobReferenceSize
+		result.Stats.CumulativeBlobFileSize += results[i].Stats.CumulativeBlobFileSize
+		result.Stats.CountMissizedDels += results[i].Stats.CountMissizedDels
+		c.stats.Merge(stats[i])
+	}
+	if result.Err == nil {
+		result.Err = d.objProvider.Sync()
+	}
+	return result
+}
+
+// runSubcompaction runs one subcompaction, compacting the input keys within
+// bounds. The point keys are restricted to [lower, upper), where a nil bound
+// is the corresponding bound of the compaction, and the range deletions and
+// range keys are truncated to bounds.
+func (d *DB) runSubcompaction(
+	jobID JobID,
+	c *compaction,
+	snapshots compact.Snapshots,
+	tableFormat sstable.TableFormat,
+	iiopts internalIterOpts,
+	bounds base.UserKeyBounds,
+	lower, upper []byte,
+	grantHandle CompactionGrantHandle,
+) compact.Result {
+	pointIter, rangeDelIter, rangeKeyIter, err := c.newInputIters(d.newIters, d.tableNewRangeKeyIter, iiopts)
+	if err != nil {
+		return compact.Result{Err: err}
+	}
+	pointIter = &subcompactionIter{internalIterator: pointIter, cmp: c.cmp, lower: lower, upper: upper}
+	if rangeDelIter != nil {
+		rangeDelIter = keyspan.Truncate(c.cmp, rangeDelIter, bounds)
+	}
+	if rangeKeyIter != nil {
+		rangeKeyIter = keyspan.Truncate(c.cmp, rangeKeyIter, bounds)
+	}
+	// Each subcompaction separates values into its own blob files, if any.
+	valueSeparation := c.getValueSeparation(jobID, c, tableFormat)
+	return d.writeCompactionOutputs(
+		jobID, c, snapshots, tableFormat, valueSeparation, bounds, grantHandle,
+		pointIter, rangeDelIter, rangeKeyIter,
+	)
+}
+
+// subcompactionIter wraps the point iterator over the input of a compaction,
+// restricting it to the bounds of a subcompaction: First seeks to the lower
+// bound, and iteration stops at the upper bound. The iterators over the input
+// tables of a compaction don't support bounds themselves. Compaction iterators
+// only ever position the point iterator using First and Next.
+type subcompactionIter struct {
+	internalIterator
+	cmp          Compare
+	lower, upper []byte
+}
+
+// First implements internalIterator.
+func (i *subcompactionIter) First() *base.InternalKV {
+	if i.lower != nil {
+		return i.checkUpper(i.internalIterator.SeekGE(i.lower, base.SeekGEFlagsNone))
+	}
+	return i.checkUpper(i.internalIterator.First())
+}
+
+// Next implements internalIterator.
+func (i *subcompactionIter) Next() *base.InternalKV {
+	return i.checkUpper(i.internalIterator.Next())
+}
+
+func (i *subcompactionIter) checkUpper(kv *base.InternalKV) *base.InternalKV {
+	if kv != nil && i.upper != nil && i.cmp(kv.K.UserKey, i.upper) >= 0 {
+		return nil
+	}
+	return kv
+}
@@ -14,6 +14,7 @@ import (
 	"math/rand/v2"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"slices"
 	"sort"
 	"strconv"
@@ -1533,6 +1534,132 @@ func TestCompactionDeleteOnlyHints(t *testing.T) {
 		})
 }
 
+// TestCompactionDropsEmptyVirtualTables tests that a virtual sstable that
+// contains only tombstones, with no data beneath it, is dropped by a
+// delete-only compaction and that its backing is removed.
+func TestCompactionDropsEmptyVirtualTables(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{
+		FS:                 mem,
+		FormatMajorVersion: FormatNewest,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+		TableFormat: d.TableFormat(),
+	})
+	require.NoError(t, w.Set([]byte("a"), []byte("foo")))
+	require.NoError(t, w.Delete([]byte("b")))
+	require.NoError(t, w.Delete([]byte("c")))
+	require.NoError(t, w.Close())
+	require.NoError(t, d.Ingest(context.Background(), []string{"ext"}))
+
+	// Excise the only live key, leaving a virtual sstable in L6 containing
+	// only the tombstones b and c.
+	require.NoError(t, d.Excise(context.Background(), KeyRange{Start: []byte("a"), End: []byte("b")}))
+
+	d.mu.Lock()
+	d.waitTableStats()
+	for d.mu.compact.compactingCount > 0 {
+		d.mu.compact.cond.Wait()
+	}
+	d.mu.Unlock()
+
+	m := d.Metrics()
+	require.Equal(t, int64(1), m.Compact.DeleteOnlyCount)
+	require.Equal(t, int64(0), m.Levels[numLevels-1].NumFiles)
+	require.Equal(t, uint64(0), m.Table.BackingTableCount)
+}
+
+// TestExciseReclaimsSpace tests that the virtual tables left by an excise
+// without an accompanying ingestion are rewritten, so that the space of their
+// backing tables is reclaimed.
+func TestExciseReclaimsSpace(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                 vfs.NewMem(),
+		FormatMajorVersion: FormatNewest,
+		Logger:             testLogger{t: t},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for c := 'a'; c <= 'z'; c++ {
+		require.NoError(t, d.Set([]byte{byte(c)}, []byte{byte(c)}, nil))
+	}
+	require.NoError(t, d.Compact([]byte("a"), []byte("{"), false /* parallelize */))
+	require.Equal(t, int64(1), d.Metrics().Levels[numLevels-1].NumFiles)
+
+	require.NoError(t, d.Excise(context.Background(), KeyRange{Start: []byte("c"), End: []byte("x")}))
+	require.Eventually(t, func() bool {
+		m := d.Metrics()
+		return m.NumVirtual() == 0 && m.Table.BackingTableCount == 0
+	}, 10*time.Second, time.Millisecond)
+	m := d.Metrics()
+	require.Equal(t, int64(2), m.Compact.RewriteCount)
+	require.Zero(t, m.Compact.MarkedFiles)
+
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	var keys []string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, []string{"a", "b", "x", "y", "z"}, keys)
+}
+
+func TestCompactDeletedRanges(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		Logger:                      testLogger{t: t},
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Create two L6 tables, [b, c] and [x, y].
+	for _, keys := range [][2]string{{"b", "c"}, {"x", "y"}} {
+		for _, k := range keys {
+			require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+		}
+		require.NoError(t, d.Flush())
+		require.NoError(t, d.Compact([]byte(keys[0]), []byte(keys[1]+"\x00"), false /* parallelize */))
+	}
+	require.Equal(t, int64(2), d.Metrics().Levels[numLevels-1].NumFiles)
+
+	// Delete the range containing the first table, while a snapshot prevents
+	// the table from being dropped.
+	snap := d.NewSnapshot()
+	require.NoError(t, d.DeleteRange([]byte("a"), []byte("e"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.CompactDeletedRanges())
+	m := d.Metrics()
+	require.Equal(t, 1, m.Compact.DeletionHints)
+	require.Equal(t, int64(0), m.Compact.DeleteOnlyEligibleFiles)
+	require.Equal(t, int64(2), m.Levels[numLevels-1].NumFiles)
+
+	require.NoError(t, snap.Close())
+	m = d.Metrics()
+	require.Equal(t, int64(1), m.Compact.DeleteOnlyEligibleFiles)
+	require.Greater(t, m.Compact.DeleteOnlyEligibleSize, uint64(0))
+	// The eligible files are cached until the hints, the version or the
+	// snapshots change.
+	d.mu.Lock()
+	require.True(t, d.mu.compact.deleteOnlyEligible.valid)
+	d.mu.Unlock()
+	require.Equal(t, m.Compact.DeleteOnlyEligibleSize, d.Metrics().Compact.DeleteOnlyEligibleSize)
+
+	require.NoError(t, d.CompactDeletedRanges())
+	m = d.Metrics()
+	require.Equal(t, int64(1), m.Compact.DeleteOnlyCount)
+	require.Equal(t, 0, m.Compact.DeletionHints)
+	require.Equal(t, int64(0), m.Compact.DeleteOnlyEligibleFiles)
+	require.Equal(t, int64(1), m.Levels[numLevels-1].NumFiles)
+}
+
 func TestCompactionTombstones(t *testing.T) {
 	var d *DB
 	defer func() {
@@ -2416,6 +2543,46 @@ func TestAdjustGrandparentOverlapBytesForFlush(t *testing.T) {
 	}
 }
 
+func TestAdjustGrandparentOverlapBytesForSkew(t *testing.T) {
+	makeLevelSlice := func(n int, size uint64) manifest.LevelSlice {
+		var files []*manifest.TableMetadata
+		for i := 0; i < n; i++ {
+			m := &manifest.TableMetadata{Size: size, FileNum: base.FileNum(i)}
+			m.InitPhysicalBacking()
+			files = append(files, m)
+		}
+		return manifest.NewLevelSliceSpecificOrder(files)
+	}
+	const maxOutputFileSize = 2 << 20
+	const maxOverlapBytes = 20 << 20
+	// 10MB of inputs.
+	inputs := makeLevelSlice(5, 2<<20)
+	testCases := []struct {
+		grandparentBytes     uint64
+		adjustedOverlapBytes uint64
+	}{
+		// No skew: the grandparent level holds 10x the inputs.
+		{grandparentBytes: 100 << 20, adjustedOverlapBytes: 20 << 20},
+		{grandparentBytes: 10 << 20, adjustedOverlapBytes: 20 << 20},
+		// 2x skew halves the limit.
+		{grandparentBytes: 200 << 20, adjustedOverlapBytes: 10 << 20},
+		// The limit is not lowered below twice the target file size.
+		{grandparentBytes: 1000 << 20, adjustedOverlapBytes: 4 << 20},
+	}
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			c := compaction{
+				inputs:            []compactionLevel{{level: 1, files: inputs}, {level: 2}},
+				grandparents:      makeLevelSlice(10, tc.grandparentBytes/10),
+				maxOverlapBytes:   maxOverlapBytes,
+				maxOutputFileSize: maxOutputFileSize,
+			}
+			adjustGrandparentOverlapBytesForSkew(&c, 10 /* levelMultiplier */)
+			require.Equal(t, tc.adjustedOverlapBytes, c.maxOverlapBytes)
+		})
+	}
+}
+
 func TestCompactionInvalidBounds(t *testing.T) {
 	opts := &Options{
 		FS: vfs.NewMem(),
@@ -2841,3 +3008,139 @@ func TestCompactionErrorStats(t *testing.T) {
 	d.mu.Unlock()
 	require.NoError(t, d.Close())
 }
+
+// maxRunningScheduler is a ConcurrencyLimitScheduler that records the maximum
+// number of compactions it permitted to run concurrently.
+type maxRunningScheduler struct {
+	*ConcurrencyLimitScheduler
+	maxRunning atomic.Int32
+}
+
+func (s *maxRunningScheduler) TrySchedule() (bool, CompactionGrantHandle) {
+	ok, h := s.ConcurrencyLimitScheduler.TrySchedule()
+	if ok {
+		s.ConcurrencyLimitScheduler.mu.Lock()
+		running := int32(s.ConcurrencyLimitScheduler.mu.runningCompactions)
+		s.ConcurrencyLimitScheduler.mu.Unlock()
+		for prev := s.maxRunning.Load(); running > prev && !s.maxRunning.CompareAndSwap(prev, running); {
+			prev = s.maxRunning.Load()
+		}
+	}
+	return ok, h
+}
+
+func TestSubcompactions(t *testing.T) {
+	for _, maxConcurrentCompactions := range []int{1, 4} {
+		t.Run(fmt.Sprintf("max-concurrent-compactions=%d", maxConcurrentCompactions), func(t *testing.T) {
+			scheduler := &maxRunningScheduler{
+				ConcurrencyLimitScheduler: newConcurrencyLimitScheduler(defaultTimeSource{}),
+			}
+			opts := &Options{
+				FS:                          vfs.NewMem(),
+				Logger:                      testLogger{t: t},
+				DisableAutomaticCompactions: true,
+				MaxConcurrentCompactions:    func() int { return maxConcurrentCompactions },
+			}
+			opts.Experimental.MaxSubcompactions = 4
+			opts.Experimental.CompactionScheduler = scheduler
+			opts.EnsureDefaults()
+			for i := range opts.Levels {
+				opts.Levels[i].TargetFileSize = 16 << 10
+			}
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+
+			// Write overlapping L0 tables starting at different keys, and a
+			// range deletion that spans several of them.
+			rng := rand.New(rand.NewPCG(0, 0))
+			expected := map[string][]byte{}
+			for i := 0; i < 8; i++ {
+				for j := i * 50; j < i*50+100; j++ {
+					key := fmt.Sprintf("k%04d", j)
+					value := testutils.RandBytes(rng, 1000)
+					require.NoError(t, d.Set([]byte(key), value, nil))
+					expected[key] = value
+				}
+				if i == 7 {
+					require.NoError(t, d.DeleteRange([]byte("k0120"), []byte("k0330"), nil))
+					for j := 120; j < 330; j++ {
+						delete(expected, fmt.Sprintf("k%04d", j))
+					}
+				}
+				require.NoError(t, d.Flush())
+			}
+
+			// The subcompactions are permitted by the scheduler, within the
+			// limit of concurrent compactions.
+			require.NoError(t, d.Compact([]byte("k"), []byte("l"), false /* parallelize */))
+			require.Equal(t, int32(maxConcurrentCompactions), scheduler.maxRunning.Load())
+			m := d.Metrics()
+			require.Zero(t, m.Levels[0].NumFiles)
+			require.GreaterOrEqual(t, m.Levels[numLevels-1].NumFiles, int64(maxConcurrentCompactions))
+
+			iter, err := d.NewIter(nil)
+			require.NoError(t, err)
+			n := 0
+			for valid := iter.First(); valid; valid = iter.Next() {
+				require.Equal(t, expected[string(iter.Key())], iter.Value(), "key %s", iter.Key())
+				n++
+			}
+			require.NoError(t, iter.Close())
+			require.Equal(t, len(expected), n)
+		})
+	}
+}
+
+func TestCompactionUsageMetrics(t *testing.T) {
+	for _, maxSubcompactions := range []int{1, 4} {
+		t.Run(fmt.Sprintf("subcompactions=%d", maxSubcompactions), func(t *testing.T) {
+			opts := &Options{
+				FS:                          vfs.NewMem(),
+				Logger:                      testLogger{t: t},
+				DisableAutomaticCompactions: true,
+			}
+			opts.Experimental.MaxSubcompactions = maxSubcompactions
+			opts.EnsureDefaults()
+			for i := range opts.Levels {
+				opts.Levels[i].TargetFileSize = 16 << 10
+			}
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+
+			rng := rand.New(rand.NewPCG(0, 0))
+			for i := 0; i < 4; i++ {
+				for j := 0; j < 200; j++ {
+					key := fmt.Sprintf("k%04d", j)
+					require.NoError(t, d.Set([]byte(key), testutils.RandBytes(rng, 1000), nil))
+				}
+				require.NoError(t, d.Flush())
+			}
+			require.NoError(t, d.Compact([]byte("k"), []byte("l"), false /* parallelize */))
+
+			m := d.Metrics()
+			var total CompactionUsage
+			for _, u := range m.Compact.UsageByReason {
+				total.Add(u)
+			}
+			require.Equal(t, m.Compact.Count, total.Count)
+			var byLevels CompactionUsage
+			for _, u := range m.Compact.UsageByLevels {
+				byLevels.Add(u)
+			}
+			require.Equal(t, total, byLevels)
+
+			u, ok := m.Compact.UsageByLevels[CompactionLevels{StartLevel: 0, OutputLevel: 6}]
+			require.True(t, ok, "%v", m.Compact.UsageByLevels)
+			require.Equal(t, int64(1), u.Count)
+			require.NotZero(t, u.BytesReadLocal+u.BytesReadCache)
+			require.Zero(t, u.BytesReadRemote)
+			require.NotZero(t, u.BytesWritten)
+			if runtime.GOOS == "linux" {
+				require.NotZero(t, u.CPUTime)
+			}
+			require.Equal(t, u, m.Compact.UsageByReason["default"])
+		})
+	}
+}
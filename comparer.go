@@ -32,3 +32,6 @@ var DefaultComparer = base.DefaultComparer
 
 // CheckComparer exports the base.CheckComparer type.
 var CheckComparer = base.CheckComparer
+
+// CheckComparerKeys exports the base.CheckComparerKeys function.
+var CheckComparerKeys = base.CheckComparerKeys
@@ -93,6 +93,27 @@ func runIterCmd(d *datadriven.TestData, iter *Iterator, closeIter bool) string {
 				return "seek-ge <key>\n"
 			}
 			valid = iter.SeekGE([]byte(parts[1]))
+		case "seek-ge-multi":
+			if len(parts) < 2 {
+				return "seek-ge-multi <key>...\n"
+			}
+			keys := make([][]byte, len(parts)-1)
+			for j := range keys {
+				keys[j] = []byte(parts[j+1])
+			}
+			// Print the iterator state at each key, like a seek-ge would.
+			err := iter.SeekGEMulti(keys, func(int) error {
+				state := IterExhausted
+				if iter.Valid() {
+					state = IterValid
+				}
+				printIterState(&b, iter, state, false)
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(&b, "err=%v\n", err)
+			}
+			continue
 		case "seek-prefix-ge":
 			if len(parts) != 2 {
 				return "seek-prefix-ge <key>\n"
@@ -824,7 +845,7 @@ func runCompactCmd(td *datadriven.TestData, d *DB) error {
 		if err != nil {
 			return err
 		}
-		return d.manualCompact(iStart.UserKey, iEnd.UserKey, level, parallelize)
+		return d.manualCompact(context.Background(), iStart.UserKey, iEnd.UserKey, level, parallelize)
 	}
 	return d.Compact([]byte(parts[0]), []byte(parts[1]), parallelize)
 }
@@ -1176,7 +1197,7 @@ func runDBDefineCmdReuseFS(td *datadriven.TestData, opts *Options) (*DB, error)
 		err := blobtest.WriteFiles(&valueSeparator.bv, func(fileNum base.DiskFileNum) (objstorage.Writable, error) {
 			writable, _, err := d.objProvider.Create(context.Background(), base.FileTypeBlob, fileNum, objstorage.CreateOptions{})
 			return writable, err
-		}, d.opts.MakeBlobWriterOptions(0), valueSeparator.metas)
+		}, d.opts.MakeBlobWriterOptions(0, d.FormatMajorVersion().MaxBlobFileFormat()), valueSeparator.metas)
 		if err != nil {
 			return nil, err
 		}
@@ -1327,7 +1348,7 @@ func runSSTablePropertiesCmd(t *testing.T, td *datadriven.TestData, d *DB) strin
 	var v sstable.VirtualReader
 	props := r.Properties.String()
 	if m != nil && m.Virtual {
-		v = sstable.MakeVirtualReader(r, m.VirtualMeta().VirtualReaderParams(false /* isShared */))
+		v = sstable.MakeVirtualReader(r, m.VirtualMeta().VirtualReaderParams(false /* isShared */, d.opts.Comparer))
 		props = v.Properties.String()
 	}
 	if len(td.Input) == 0 {
@@ -1449,7 +1470,7 @@ func runIngestExternalCmd(
 		usageErr := func(info interface{}) {
 			t.Helper()
 			td.Fatalf(t, "error parsing %q: %v; "+
-				"usage: obj bounds=(smallest,largest) [size=x] [synthetic-prefix=prefix] [synthetic-suffix=suffix] [no-point-keys] [has-range-keys]",
+				"usage: obj bounds=(smallest,largest) [size=x] [synthetic-prefix=prefix] [content-prefix=prefix] [synthetic-suffix=suffix] [suffix-replacement=(from,to)]... [no-point-keys] [has-range-keys]",
 				line, info,
 			)
 		}
@@ -1494,10 +1515,21 @@ func runIngestExternalCmd(
 				nArgs(1)
 				ef.SyntheticPrefix = []byte(arg.Vals[0])
 
+			case "content-prefix":
+				nArgs(1)
+				ef.ContentPrefix = []byte(arg.Vals[0])
+
 			case "synthetic-suffix":
 				nArgs(1)
 				ef.SyntheticSuffix = []byte(arg.Vals[0])
 
+			case "suffix-replacement":
+				nArgs(2)
+				ef.SuffixReplacements = append(ef.SuffixReplacements, SuffixReplacement{
+					From: []byte(arg.Vals[0]),
+					To:   []byte(arg.Vals[1]),
+				})
+
 			case "no-point-keys":
 				ef.HasPointKey = false
 
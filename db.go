@@ -9,6 +9,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,9 +18,11 @@ import (
 
 	"github.com/cockroachdb/crlib/crtime"
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/batchrepr"
 	"github.com/cockroachdb/pebble/internal/arenaskl"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
+	"github.com/cockroachdb/pebble/internal/hotkeys"
 	"github.com/cockroachdb/pebble/internal/invalidating"
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/internal/keyspan"
@@ -57,6 +61,9 @@ var (
 	// ErrReadOnly is returned when a write operation is performed on a read-only
 	// database.
 	ErrReadOnly = errors.New("pebble: read-only")
+	// ErrDiskSpaceLow is returned when a write or an ingestion is refused
+	// because the available disk space is below Options.MinFreeSpaceForWrites.
+	ErrDiskSpaceLow = errors.New("pebble: available disk space is below the minimum for writes")
 	// errNoSplit indicates that the user is trying to perform a range key
 	// operation but the configured Comparer does not provide a Split
 	// implementation.
@@ -265,9 +272,79 @@ type DB struct {
 	// envelopes.
 	logBytesIn atomic.Uint64
 
+	// tableAccess records sampled reads of sstables.
+	tableAccess tableAccessTracker
+
+	// iters tracks the open iterators reading the DB state.
+	iters iterTracker
+
+	// hotKeys samples the keys read and written, if hot key detection is
+	// enabled. See Options.Experimental.HotKeys.
+	hotKeys *hotkeys.Tracker
+
+	// scanCache memoizes the results of DB.Scan, if enabled. See
+	// Options.Experimental.ScanCache.
+	scanCache *scanCache
+	// externalValues fetches the values referenced by handles, if an
+	// external value store is configured. See
+	// Options.Experimental.ExternalValues.
+	externalValues *externalValues
+
+	// id is the ID of the DB persisted in its directory, or zero if the DB is
+	// read-only and has none. See DB.ID.
+	id uint64
+
+	// idempotency remembers the idempotency keys of recently applied batches.
+	idempotency idempotencyWindow
+
+	// writeController decides whether writes are stalled or delayed. It is
+	// Options.Experimental.WriteController, or defaultWriteController.
+	writeController WriteController
+	// lsmHealth is the health of the LSM as of the last update of the read
+	// state. It is passed to WriteController.BatchDelay.
+	lsmHealth atomic.Pointer[LSMHealth]
+	// durability tracks the writes that are durable in the WAL. See
+	// DB.OnDurable.
+	durability durabilityTracker
+	// commitMetrics accumulates the statistics of committed batches.
+	commitMetrics commitPipelineCounters
+	// writeAdmission holds back batches from entering the commit pipeline
+	// according to their priority. See DB.waitForWriteAdmission.
+	writeAdmission struct {
+		sync.Mutex
+		cond sync.Cond
+		// stalled is true while a write stall is in progress.
+		stalled bool
+		// lowPriorityStalled is true while a write stall is imminent.
+		lowPriorityStalled bool
+	}
+
+	// maxConcurrentCompactions, if positive, overrides
+	// Options.MaxConcurrentCompactions. It is set through DB.SetOptions.
+	maxConcurrentCompactions atomic.Int64
+	// compactionAutotuner is non-nil if
+	// Options.Experimental.CompactionConcurrencyAutotuning is enabled.
+	compactionAutotuner *compactionAutotuner
+
+	// pausedErr is set while writes and background work are paused after a
+	// background error. See Options.BackgroundErrorRecovery.
+	pausedErr atomic.Pointer[error]
+
 	// The number of bytes available on disk.
 	diskAvailBytes       atomic.Uint64
 	lowDiskSpaceReporter lowDiskSpaceReporter
+	// diskAvailRecheckedAt is the time at which writes refused because of low
+	// disk space last refreshed diskAvailBytes. See diskSpaceLowForWrites.
+	diskAvailRecheckedAt atomic.Int64
+
+	// eventListeners dispatches the events to opts.EventListener's original
+	// value and to the listeners added by AddEventListener. opts.EventListener
+	// forwards every event to it.
+	eventListeners *dynamicEventListener
+
+	// fromRocksDB is set if the DB was opened read-only from the directory of
+	// a RocksDB DB. See Options.ReadOnly.
+	fromRocksDB bool
 
 	cacheHandle    *cache.Handle
 	dirname        string
@@ -287,10 +364,17 @@ type DB struct {
 
 	// objProvider is used to access and manage SSTs.
 	objProvider objstorage.Provider
+	// inlineTables is the objProvider wrapper that serves the tables stored
+	// inline in the MANIFEST. See Options.Experimental.InlineTableMaxSize.
+	inlineTables *inlineTableProvider
 
 	fileLock *Lock
 	dataDir  vfs.File
 
+	// tempFiles manages temporary files created by subsystems that spill data
+	// to disk.
+	tempFiles tempFiles
+
 	fileCache            *fileCacheHandle
 	newIters             tableNewIters
 	tableNewRangeKeyIter keyspanimpl.TableNewSpanIter
@@ -314,6 +398,15 @@ type DB struct {
 	// compactionShedulers.Wait() should not be called while the DB.mu is held.
 	compactionSchedulers sync.WaitGroup
 
+	// seqNumTimesFile serializes the writes of the sequence number samples
+	// file, which happen without holding DB.mu. gen is the generation of the
+	// last samples written, so that a write of older samples that lost a race
+	// is skipped. See DB.persistSeqNumTimes.
+	seqNumTimesFile struct {
+		sync.Mutex
+		gen uint64
+	}
+
 	// The main mutex protecting internal DB state. This mutex encompasses many
 	// fields because those fields need to be accessed and updated atomically. In
 	// particular, the current version, log.*, mem.*, and snapshot list need to
@@ -348,6 +441,10 @@ type DB struct {
 			// to vers + 1. As a part of ratcheting the format major version,
 			// migrations may drop and re-acquire the mutex.
 			ratcheting bool
+			// ingests counts the in-progress ingestions by the format major
+			// version their sstables were validated against. See
+			// RollbackFormatMajorVersion.
+			ingests [internalFormatNewest + 1]int
 		}
 
 		// The ID of the next job. Job IDs are passed to event listener
@@ -377,6 +474,10 @@ type DB struct {
 				// Updated whenever a wal.Writer is closed.
 				record.LogWriterMetrics
 			}
+			// readOnlyWALs are the WALs replayed by Open in read-only mode,
+			// which the manager doesn't list. Their contents are only in the
+			// memtables, so checkpoints must copy them.
+			readOnlyWALs wal.Logs
 		}
 
 		mem struct {
@@ -410,6 +511,9 @@ type DB struct {
 			cond sync.Cond
 			// True when a flush is in progress.
 			flushing bool
+			// closing is set by CloseWithTimeout to prevent new compactions from
+			// being scheduled while it waits for the in-progress ones.
+			closing bool
 			// The number of ongoing non-download compactions.
 			compactingCount int
 			// The number of download compactions.
@@ -417,15 +521,51 @@ type DB struct {
 			// The list of deletion hints, suggesting ranges for delete-only
 			// compactions.
 			deletionHints []deleteCompactionHint
+			// deleteOnlyEligible caches the result of deleteOnlyEligibleFiles,
+			// which is reported by every call to Metrics. It's invalidated when
+			// the deletion hints or the current version change, and is only
+			// valid for the snapshots of generation snapshotsGen.
+			deleteOnlyEligible struct {
+				valid        bool
+				snapshotsGen uint64
+				count        int64
+				size         uint64
+			}
+			// The list of virtual sstables whose visible keys have all been
+			// deleted. These tables may be dropped through a delete-only
+			// compaction without rewriting any data. See emptyVirtualTable.
+			emptyVirtualTables []emptyVirtualTable
+			// cacheEvictionOrder caches the tables of the version, in the order
+			// in which pickCacheEvictionCompaction drops them, and their total
+			// size. It's recomputed when the current version changes.
+			cacheEvictionOrder struct {
+				version   *version
+				tables    []manifest.NewTableEntry
+				totalSize uint64
+			}
 			// The list of manual compactions. The next manual compaction to perform
 			// is at the start of the list. New entries are added to the end.
 			manual    []*manualCompaction
 			manualLen atomic.Int32
+			// subcompactionDemand is the number of additional subcompactions
+			// that running compactions are asking the CompactionScheduler to
+			// run. It's counted like the backlog of manual compactions. See
+			// DB.acquireSubcompactionGrants.
+			subcompactionDemand atomic.Int32
 			// manualID is used to identify manualCompactions in the manual slice.
 			manualID uint64
 			// downloads is the list of pending download tasks. The next download to
 			// perform is at the start of the list. New entries are added to the end.
 			downloads []*downloadSpanTask
+			// tieringNextScan is the earliest time at which a local bottommost
+			// table may become eligible for remote tiering. Until then, the
+			// bottommost level is not scanned for tiering candidates.
+			tieringNextScan time.Time
+			// paused is true while automatic compactions are paused through
+			// DB.DisableAutomaticCompactions.
+			paused bool
+			// bulkLoad is true during a bulk load (see DB.BeginBulkLoad).
+			bulkLoad bool
 			// inProgress is the set of in-progress flushes and compactions.
 			// It's used in the calculation of some metrics and to initialize L0
 			// sublevels' state. Some of the compactions contained within this
@@ -441,6 +581,10 @@ type DB struct {
 			// compactions which we might have to perform.
 			readCompactions readCompactionQueue
 
+			// suggestedCompactions holds the key ranges suggested for compaction
+			// through DB.SuggestCompaction.
+			suggestedCompactions suggestedCompactionQueue
+
 			// The cumulative duration of all completed compactions since Open.
 			// Does not include flushes.
 			duration time.Duration
@@ -451,6 +595,26 @@ type DB struct {
 			noOngoingFlushStartTime crtime.Mono
 		}
 
+		// bgErr holds the state of the recovery from background errors. See
+		// Options.BackgroundErrorRecovery.
+		bgErr struct {
+			// consecutiveFailures is the number of flushes and compactions that
+			// failed since the last successful one.
+			consecutiveFailures int
+			// retryTimer is set while background work is backing off after a
+			// failure.
+			retryTimer *time.Timer
+		}
+
+		// ampWindow tracks the amplification metrics over a recent window of
+		// time. See Metrics.Windowed.
+		ampWindow ampWindow
+
+		// keyRangeStats are the key ranges registered through
+		// DB.RegisterKeyRangeStats. The slice is replaced rather than modified
+		// in place, as flushes and compactions retain it.
+		keyRangeStats []*keyRangeStatsEntry
+
 		// Non-zero when file cleaning is disabled. The disabled count acts as a
 		// reference count to prohibit file cleaning. See
 		// DB.{disable,Enable}FileDeletions().
@@ -460,18 +624,39 @@ type DB struct {
 			// The list of active snapshots.
 			snapshotList
 
+			// efos is the set of open EventuallyFileOnlySnapshots, including the
+			// ones that transitioned to file-only snapshots (and are no longer in
+			// snapshotList).
+			efos map[*EventuallyFileOnlySnapshot]struct{}
+
 			// The cumulative count and size of snapshot-pinned keys written to
 			// sstables.
 			cumulativePinnedCount uint64
 			cumulativePinnedSize  uint64
 		}
 
+		// seqNumTimes holds the samples of the visible sequence number, in
+		// increasing order. See SeqNumTimeOptions.
+		seqNumTimes []seqNumTimeSample
+		// seqNumTimesGen is incremented every time the samples are encoded to
+		// be persisted.
+		seqNumTimesGen uint64
+
+		// namedSnapshots holds the snapshots retaining the state as of the
+		// named snapshots recorded in the MANIFEST, by name. See
+		// DB.NewNamedSnapshot.
+		namedSnapshots map[string]*Snapshot
+
 		tableStats struct {
 			// Condition variable used to signal the completion of a
 			// job to collect table stats.
 			cond sync.Cond
 			// True when a stat collection operation is in progress.
 			loading bool
+			// The job ID and start time of the in-progress stat collection
+			// operation, if loading is true.
+			loadingJobID     JobID
+			loadingStartTime time.Time
 			// True if stat collection has loaded statistics for all tables
 			// other than those listed explicitly in pending. This flag starts
 			// as false when a database is opened and flips to true once stat
@@ -482,6 +667,14 @@ type DB struct {
 			// active stat collection goroutine clears the list and processes
 			// them.
 			pending []manifest.NewTableEntry
+			// backlog is the list of files that existed at Open and still need
+			// stats, in the order in which their stats are loaded. It is
+			// populated by scanning the current version when the pending list
+			// is empty and loadedInitial is false.
+			backlog []manifest.NewTableEntry
+			// loadedCount is the cumulative count of the tables whose stats
+			// were loaded.
+			loadedCount int64
 		}
 
 		tableValidation struct {
@@ -502,6 +695,9 @@ type DB struct {
 			totalSize    *manifest.Annotator[uint64]
 			remoteSize   *manifest.Annotator[uint64]
 			externalSize *manifest.Annotator[uint64]
+			// userProperties aggregates the properties of
+			// Options.UserPropertyAggregators.
+			userProperties userPropertyAnnotators
 		}
 	}
 
@@ -528,7 +724,15 @@ func (d *DB) TestOnlyWaitForCleaning() {
 // slice will remain valid until the returned Closer is closed. On success, the
 // caller MUST call closer.Close() or a memory leak will occur.
 func (d *DB) Get(key []byte) ([]byte, io.Closer, error) {
-	return d.getInternal(key, nil /* batch */, nil /* snapshot */)
+	return d.getInternal(context.Background(), key, nil /* batch */, nil /* snapshot */)
+}
+
+// GetWithContext is like Get, but allows the caller to pass a context, which
+// may carry an IOPriority (see WithReadIOPriority). If the context is canceled,
+// the lookup stops issuing I/O (block loads, remote reads and value fetches)
+// and returns the context's error.
+func (d *DB) GetWithContext(ctx context.Context, key []byte) ([]byte, io.Closer, error) {
+	return d.getInternal(ctx, key, nil /* batch */, nil /* snapshot */)
 }
 
 type getIterAlloc struct {
@@ -543,11 +747,15 @@ var getIterAllocPool = sync.Pool{
 	},
 }
 
-func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer, error) {
+func (d *DB) getInternal(
+	ctx context.Context, key []byte, b *Batch, s *Snapshot,
+) ([]byte, io.Closer, error) {
 	if err := d.closed.Load(); err != nil {
 		panic(err)
 	}
 
+	recordHotKeyRead(d.hotKeys, key)
+
 	// Grab and reference the current readState. This prevents the underlying
 	// files in the associated version from being deleted if there is a current
 	// compaction. The readState is unref'd by Iterator.Close().
@@ -561,7 +769,15 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 	} else {
 		seqNum = d.mu.versions.visibleSeqNum.Load()
 	}
+	return d.getWithReadState(ctx, key, b, seqNum, readState)
+}
 
+// getWithReadState looks up the given key at the given seqnum in the given
+// readState. The caller's reference on readState is transferred to the
+// returned Closer (or released if the key is not found).
+func (d *DB) getWithReadState(
+	ctx context.Context, key []byte, b *Batch, seqNum base.SeqNum, readState *readState,
+) ([]byte, io.Closer, error) {
 	buf := getIterAllocPool.Get().(*getIterAlloc)
 
 	get := &buf.get
@@ -575,13 +791,16 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 			logger:                        d.opts.Logger,
 			snapshotForHideObsoletePoints: seqNum,
 		},
+		ctx: ctx,
 		key: key,
 		// Compute the key prefix for bloom filtering.
-		prefix:  key[:d.opts.Comparer.Split(key)],
-		batch:   b,
-		mem:     readState.memtables,
-		l0:      readState.current.L0SublevelFiles,
-		version: readState.current,
+		prefix:      key[:d.opts.Comparer.Split(key)],
+		batch:       b,
+		mem:         readState.memtables,
+		l0:          readState.current.L0SublevelFiles,
+		version:     readState.current,
+		access:      d.sampleGet(),
+		sampleReads: d.sampleSeek(),
 	}
 
 	// Strip off memtables which cannot possibly contain the seqNum being read
@@ -597,24 +816,40 @@ func (d *DB) getInternal(key []byte, b *Batch, s *Snapshot) ([]byte, io.Closer,
 	i := &buf.dbi
 	pointIter := get
 	*i = Iterator{
-		ctx:          context.Background(),
-		getIterAlloc: buf,
-		iter:         pointIter,
-		pointIter:    pointIter,
-		merge:        d.merge,
-		comparer:     *d.opts.Comparer,
-		readState:    readState,
-		keyBuf:       buf.keyBuf,
-	}
-
-	if !i.First() {
+		ctx:            ctx,
+		getIterAlloc:   buf,
+		iter:           pointIter,
+		pointIter:      pointIter,
+		merge:          d.merge,
+		comparer:       *d.opts.Comparer,
+		readState:      readState,
+		keyBuf:         buf.keyBuf,
+		externalValues: d.externalValues,
+	}
+
+	found := i.First()
+	if get.sampleReads {
+		// The pending read compactions are handed off to the DB when i is
+		// closed.
+		get.readSample.finish(d.opts, &i.readSampling.pendingCompactions, d.cmp)
+	}
+	if !found {
 		err := i.Close()
 		if err != nil {
 			return nil, nil, err
 		}
 		return nil, nil, ErrNotFound
 	}
-	return i.Value(), i, nil
+	if i.externalValues == nil {
+		return i.Value(), i, nil
+	}
+	value, err := i.ValueAndErr()
+	if err != nil {
+		// The iterator records the error, which Close returns as well.
+		_ = i.Close()
+		return nil, nil, err
+	}
+	return value, i, nil
 }
 
 // Set sets the value for the given key. It overwrites any previous value
@@ -811,8 +1046,52 @@ func (d *DB) ApplyNoSyncWait(batch *Batch, opts *WriteOptions) error {
 	return d.applyInternal(batch, opts, true)
 }
 
+// diskSpaceRecheckInterval is the minimum interval between the refreshes of
+// the available disk space by writes that are refused because of low disk
+// space.
+const diskSpaceRecheckInterval = time.Second
+
+// diskSpaceLowForWrites returns true if the available disk space is below
+// Options.MinFreeSpaceForWrites.
+func (d *DB) diskSpaceLowForWrites() bool {
+	minFree := d.opts.MinFreeSpaceForWrites
+	if minFree == 0 || d.diskAvailBytes.Load() >= minFree {
+		return false
+	}
+	// The cached value is only refreshed when files are written or deleted by
+	// the DB; space may have been freed by other means since. Refreshing it
+	// requires a statfs, so refused writes only do so periodically.
+	now := int64(crtime.NowMono())
+	last := d.diskAvailRecheckedAt.Load()
+	if (last != 0 && time.Duration(now-last) < diskSpaceRecheckInterval) || !d.diskAvailRecheckedAt.CompareAndSwap(last, now) {
+		return true
+	}
+	return d.calculateDiskAvailableBytes() < minFree
+}
+
+// checkDiskSpaceForWrite returns ErrDiskSpaceLow if the available disk space is
+// below Options.MinFreeSpaceForWrites and the batch doesn't only contain
+// deletions.
+func (d *DB) checkDiskSpaceForWrite(batch *Batch) error {
+	if !d.diskSpaceLowForWrites() {
+		return nil
+	}
+	for r := batch.Reader(); ; {
+		kind, _, _, ok, err := r.Next()
+		if !ok {
+			return err
+		}
+		switch kind {
+		case InternalKeyKindDelete, InternalKeyKindSingleDelete, InternalKeyKindDeleteSized,
+			InternalKeyKindRangeDelete, InternalKeyKindRangeKeyDelete, InternalKeyKindLogData:
+		default:
+			return ErrDiskSpaceLow
+		}
+	}
+}
+
 // REQUIRES: noSyncWait => opts.Sync
-func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) error {
+func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) (err error) {
 	if err := d.closed.Load(); err != nil {
 		panic(err)
 	}
@@ -822,9 +1101,24 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 	if batch.applied.Load() {
 		panic("pebble: batch already applied")
 	}
+	if len(batch.callbacks) > 0 {
+		defer func() {
+			// With ApplyNoSyncWait, the callbacks of a successfully applied
+			// batch are invoked by Batch.SyncWait.
+			if err != nil || !noSyncWait {
+				batch.runCallbacks(err)
+			}
+		}()
+	}
 	if d.opts.ReadOnly {
 		return ErrReadOnly
 	}
+	if err := d.writesPausedErr(); err != nil {
+		return err
+	}
+	if err := d.checkDiskSpaceForWrite(batch); err != nil {
+		return err
+	}
 	if batch.db != nil && batch.db != d {
 		panic(fmt.Sprintf("pebble: batch db mismatch: %p != %p", batch.db, d))
 	}
@@ -846,10 +1140,26 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 			return errNoSplit
 		}
 	}
+	var idempotencyEntry *idempotencyEntry
+	if key := batch.idempotencyKey; key != nil {
+		var seqNum base.SeqNum
+		var dup bool
+		idempotencyEntry, seqNum, dup = d.idempotency.begin(key)
+		if dup {
+			if err := d.applyDuplicate(batch, seqNum, sync); err != nil {
+				return err
+			}
+			batch.assignCallbackSeqNums()
+			return nil
+		}
+	}
 	batch.committing = true
 
 	if batch.db == nil {
 		if err := batch.refreshMemTableSize(); err != nil {
+			if idempotencyEntry != nil {
+				d.idempotency.finish(batch.idempotencyKey, idempotencyEntry, 0, false /* applied */)
+			}
 			return err
 		}
 	}
@@ -857,14 +1167,45 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 		var err error
 		batch.flushable, err = newFlushableBatch(batch, d.opts.Comparer)
 		if err != nil {
+			if idempotencyEntry != nil {
+				d.idempotency.finish(batch.idempotencyKey, idempotencyEntry, 0, false /* applied */)
+			}
 			return err
 		}
 	}
+	if threshold := d.opts.Experimental.WALBatchCompressionThreshold; threshold > 0 &&
+		len(batch.data) >= threshold && !d.opts.DisableWAL &&
+		d.FormatMajorVersion() >= FormatWALBatchCompression {
+		// Compress the batch before entering the commit pipeline, which
+		// serializes the writes to the WAL.
+		if walRepr, ok := batchrepr.Compress(nil, batch.Repr()); ok {
+			batch.walRepr = walRepr
+		}
+	}
+	batchSize := len(batch.data)
+	admissionWait := d.waitForWriteAdmission(batch)
+	delay := d.maybeDelayBatch(batch)
 	if err := d.commit.Commit(batch, sync, noSyncWait); err != nil {
 		// There isn't much we can do on an error here. The commit pipeline will be
 		// horked at this point.
 		d.opts.Logger.Fatalf("pebble: fatal commit error: %v", err)
 	}
+	batch.assignCallbackSeqNums()
+	if idempotencyEntry != nil {
+		d.idempotency.finish(batch.idempotencyKey, idempotencyEntry, batch.SeqNum(), true /* applied */)
+	}
+	if delay > 0 {
+		batch.commitStats.WriteControllerWaitDuration += delay
+		batch.commitStats.TotalDuration += delay
+	}
+	if admissionWait > 0 {
+		batch.commitStats.PriorityWaitDuration += admissionWait
+		batch.commitStats.TotalDuration += admissionWait
+	}
+	d.commitMetrics.addBatch(int64(batchSize), sync, &batch.commitStats)
+	if d.hotKeys != nil {
+		d.recordHotKeyWrites(batch)
+	}
 	// If this is a large batch, we need to clear the batch contents as the
 	// flushable batch may still be present in the flushables queue.
 	//
@@ -879,6 +1220,9 @@ func (d *DB) applyInternal(batch *Batch, opts *WriteOptions, noSyncWait bool) er
 }
 
 func (d *DB) commitApply(b *Batch, mem *memTable) error {
+	if d.scanCache != nil {
+		d.scanCache.invalidateBatch(b)
+	}
 	if b.flushable != nil {
 		// This is a large batch which was already added to the immutable queue.
 		return nil
@@ -893,7 +1237,7 @@ func (d *DB) commitApply(b *Batch, mem *memTable) error {
 	// may be reclaimed without additional writes or an explicit flush.
 	if b.countRangeDels > 0 && d.opts.FlushDelayDeleteRange > 0 {
 		d.mu.Lock()
-		d.maybeScheduleDelayedFlush(mem, d.opts.FlushDelayDeleteRange)
+		d.maybeScheduleDelayedFlush(mem, d.opts.FlushDelayDeleteRange, FlushReasonDelayed)
 		d.mu.Unlock()
 	}
 
@@ -902,7 +1246,7 @@ func (d *DB) commitApply(b *Batch, mem *memTable) error {
 	// from the memtable.
 	if b.countRangeKeys > 0 && d.opts.FlushDelayRangeKey > 0 {
 		d.mu.Lock()
-		d.maybeScheduleDelayedFlush(mem, d.opts.FlushDelayRangeKey)
+		d.maybeScheduleDelayedFlush(mem, d.opts.FlushDelayRangeKey, FlushReasonDelayed)
 		d.mu.Unlock()
 	}
 
@@ -917,6 +1261,12 @@ func (d *DB) commitApply(b *Batch, mem *memTable) error {
 func (d *DB) commitWrite(b *Batch, syncWG *sync.WaitGroup, syncErr *error) (*memTable, error) {
 	var size int64
 	repr := b.Repr()
+	walRepr := repr
+	if b.walRepr != nil {
+		// The batch was compressed before its sequence number was assigned.
+		batchrepr.SetSeqNum(b.walRepr, b.SeqNum())
+		walRepr = b.walRepr
+	}
 
 	if b.flushable != nil {
 		// We have a large batch. Such batches are special in that they don't get
@@ -930,8 +1280,11 @@ func (d *DB) commitWrite(b *Batch, syncWG *sync.WaitGroup, syncErr *error) (*mem
 		// (see comment in newFlushableBatch()).
 		b.flushable.setSeqNum(b.SeqNum())
 		if !d.opts.DisableWAL {
+			if syncWG != nil {
+				d.durability.addSync(b.SeqNum() + base.SeqNum(b.Count()))
+			}
 			var err error
-			size, err = d.mu.log.writer.WriteRecord(repr, wal.SyncOptions{Done: syncWG, Err: syncErr}, b)
+			size, err = d.mu.log.writer.WriteRecord(walRepr, wal.SyncOptions{Done: syncWG, Err: syncErr}, b)
 			if err != nil {
 				panic(err)
 			}
@@ -959,7 +1312,7 @@ func (d *DB) commitWrite(b *Batch, syncWG *sync.WaitGroup, syncErr *error) (*mem
 			func() {
 				d.mu.Lock()
 				defer d.mu.Unlock()
-				err = d.makeRoomForWrite(b)
+				err = d.makeRoomForWrite(b, FlushReasonMemTableFull)
 				mem = d.mu.mem.mutable
 			}()
 		}
@@ -973,7 +1326,10 @@ func (d *DB) commitWrite(b *Batch, syncWG *sync.WaitGroup, syncErr *error) (*mem
 	d.logBytesIn.Add(uint64(len(repr)))
 
 	if b.flushable == nil {
-		size, err = d.mu.log.writer.WriteRecord(repr, wal.SyncOptions{Done: syncWG, Err: syncErr}, b)
+		if syncWG != nil {
+			d.durability.addSync(b.SeqNum() + base.SeqNum(b.Count()))
+		}
+		size, err = d.mu.log.writer.WriteRecord(walRepr, wal.SyncOptions{Done: syncWG, Err: syncErr}, b)
 		if err != nil {
 			panic(err)
 		}
@@ -1030,7 +1386,7 @@ type newIterOpts struct {
 // level.
 func (d *DB) newIter(
 	ctx context.Context, batch *Batch, newIterOpts newIterOpts, o *IterOptions,
-) *Iterator {
+) (*Iterator, error) {
 	if newIterOpts.batch.batchOnly {
 		if batch == nil {
 			panic("batchOnly is true, but batch is nil")
@@ -1053,6 +1409,14 @@ func (d *DB) newIter(
 		// DB.mem.queue[0].logSeqNum.
 		panic("OnlyReadGuaranteedDurable is not supported for batches or snapshots")
 	}
+	if (batch != nil || seqNum != 0) && (o != nil && o.ReadAtDurableFrontier) {
+		panic("ReadAtDurableFrontier is not supported for batches or snapshots")
+	}
+	if !newIterOpts.batch.batchOnly {
+		if err := d.iters.acquire(); err != nil {
+			return nil, err
+		}
+	}
 	var readState *readState
 	var newIters tableNewIters
 	var newIterRangeKey keyspanimpl.TableNewSpanIter
@@ -1077,6 +1441,11 @@ func (d *DB) newIter(
 		// memtables) above.
 		if seqNum == 0 {
 			seqNum = d.mu.versions.visibleSeqNum.Load()
+			if o != nil && o.ReadAtDurableFrontier {
+				// NB: reading below the logSeqNum of every memtable of the read
+				// state excludes all the memtables (see finishInitializingIter).
+				seqNum = durableVisibleSeqNum(readState, seqNum)
+			}
 		}
 		newIters = d.newIters
 		newIterRangeKey = d.tableNewRangeKeyIter
@@ -1114,7 +1483,12 @@ func (d *DB) newIter(
 	if batch != nil {
 		dbi.batchSeqNum = dbi.batch.nextSeqNum()
 	}
-	return finishInitializingIter(ctx, buf)
+	if !newIterOpts.batch.batchOnly {
+		d.iters.register(dbi)
+		dbi.hotKeys = d.hotKeys
+	}
+	dbi.externalValues = d.externalValues
+	return finishInitializingIter(ctx, buf), nil
 }
 
 // finishInitializingIter is a helper for doing the non-trivial initialization
@@ -1414,6 +1788,9 @@ func (i *Iterator) constructPointIter(
 			),
 		},
 	}
+	if i.family != nil {
+		internalOpts.readEnv.IndexBlockPins = &i.family.indexBlocks
+	}
 	if i.opts.RangeKeyMasking.Filter != nil {
 		internalOpts.boundLimitedFilter = &i.rangeKeyMasking
 	}
@@ -1475,7 +1852,7 @@ func (i *Iterator) constructPointIter(
 				rangeDelIter = &i.batchRangeDelIter
 			}
 			mlevels = append(mlevels, mergingIterLevel{
-				iter:         &i.batchPointIter,
+				iter:         i.maybeWrapForTrace(&i.batchPointIter, func() string { return "batch" }),
 				rangeDelIter: rangeDelIter,
 			})
 		}
@@ -1485,8 +1862,11 @@ func (i *Iterator) constructPointIter(
 		// Next are the memtables.
 		for j := len(memtables) - 1; j >= 0; j-- {
 			mem := memtables[j]
+			memIter := i.maybeWrapForTrace(mem.newIter(&i.opts), func() string {
+				return fmt.Sprintf("memtable %d", j)
+			})
 			mlevels = append(mlevels, mergingIterLevel{
-				iter:         mem.newIter(&i.opts),
+				iter:         memIter,
 				rangeDelIter: mem.newRangeDelIter(&i.opts),
 			})
 		}
@@ -1504,7 +1884,7 @@ func (i *Iterator) constructPointIter(
 			li.initRangeDel(&mlevels[mlevelsIndex])
 			li.initCombinedIterState(&i.lazyCombinedIter.combinedIterState)
 			mlevels[mlevelsIndex].levelIter = li
-			mlevels[mlevelsIndex].iter = invalidating.MaybeWrapIfInvariants(li)
+			mlevels[mlevelsIndex].iter = i.maybeWrapForTrace(invalidating.MaybeWrapIfInvariants(li), level.String)
 
 			levelsIndex++
 			mlevelsIndex++
@@ -1575,9 +1955,37 @@ func (d *DB) NewIter(o *IterOptions) (*Iterator, error) {
 }
 
 // NewIterWithContext is like NewIter, and additionally accepts a context for
-// tracing.
+// tracing and cancellation. Once the context is canceled, the iterator stops
+// issuing I/O: positioning operations that need to load a block fail, and
+// Iterator.Error returns the context's error. The context can be replaced
+// with Iterator.SetContext.
 func (d *DB) NewIterWithContext(ctx context.Context, o *IterOptions) (*Iterator, error) {
-	return d.newIter(ctx, nil /* batch */, newIterOpts{}, o), nil
+	return d.newIter(ctx, nil /* batch */, newIterOpts{}, o)
+}
+
+// DurableVisibleSeqNum returns the durable frontier of the DB: the sequence
+// number below which all the visible writes are in flushed sstables, and hence
+// don't depend on the WAL for durability. The frontier never regresses. An
+// iterator created with IterOptions.ReadAtDurableFrontier reads at the
+// frontier at the time it's created.
+func (d *DB) DurableVisibleSeqNum() SeqNum {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	readState := d.loadReadState()
+	defer readState.unref()
+	return durableVisibleSeqNum(readState, d.mu.versions.visibleSeqNum.Load())
+}
+
+// durableVisibleSeqNum returns the durable frontier of the given read state:
+// the writes with sequence numbers below the logSeqNum of the oldest memtable
+// of the read state are all in its sstables. visibleSeqNum must be loaded
+// after the read state.
+func durableVisibleSeqNum(readState *readState, visibleSeqNum base.SeqNum) base.SeqNum {
+	if len(readState.memtables) > 0 {
+		return min(visibleSeqNum, readState.memtables[0].logSeqNum)
+	}
+	return visibleSeqNum
 }
 
 // NewSnapshot returns a point-in-time view of the current DB state. Iterators
@@ -1603,11 +2011,15 @@ func (d *DB) NewSnapshot() *Snapshot {
 	if err := d.closed.Load(); err != nil {
 		panic(err)
 	}
+	if d.opts.Preset == CachePreset {
+		panic("pebble: snapshots are not supported with CachePreset")
+	}
 	d.mu.Lock()
 	s := &Snapshot{
 		db:     d,
 		seqNum: d.mu.versions.visibleSeqNum.Load(),
 	}
+	d.trackSnapshot(s)
 	d.mu.snapshots.pushBack(s)
 	d.mu.Unlock()
 	return s
@@ -1621,6 +2033,9 @@ func (d *DB) NewEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFile
 	if err := d.closed.Load(); err != nil {
 		panic(err)
 	}
+	if d.opts.Preset == CachePreset {
+		panic("pebble: snapshots are not supported with CachePreset")
+	}
 	for i := range keyRanges {
 		if i > 0 && d.cmp(keyRanges[i-1].End, keyRanges[i].Start) > 0 {
 			panic("pebble: key ranges for eventually-file-only-snapshot not in order")
@@ -1629,7 +2044,69 @@ func (d *DB) NewEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFile
 	return d.makeEventuallyFileOnlySnapshot(keyRanges)
 }
 
-// Close closes the DB.
+// CloseResult describes the background work that CloseWithTimeout skipped.
+type CloseResult struct {
+	// Flushed is true if the memtables were flushed before the DB was closed.
+	// If false, their contents are recovered from the WAL when the DB is
+	// reopened (and lost if the WAL is disabled).
+	Flushed bool
+	// CancelledJobs are the compactions that were still running at the
+	// deadline, and were cancelled.
+	CancelledJobs []BackgroundJob
+}
+
+// CloseWithTimeout closes the DB, like Close, after attempting to complete its
+// background work within the given timeout: it flushes the memtables, stops
+// scheduling new compactions and waits for the in-progress ones. Compactions
+// still running when the timeout expires are cancelled, and reported in the
+// returned CloseResult. Flushes can't be cancelled, so CloseWithTimeout may
+// still block past the timeout on a flush (or on a compaction that does not
+// observe its cancellation promptly).
+//
+// The same restrictions as for Close apply.
+func (d *DB) CloseWithTimeout(timeout time.Duration) (CloseResult, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	var res CloseResult
+	deadline := time.Now().Add(timeout)
+	if !d.opts.ReadOnly {
+		if flushed, err := d.AsyncFlush(); err == nil {
+			timer := time.NewTimer(timeout)
+			select {
+			case <-flushed:
+				res.Flushed = true
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+	}
+
+	d.mu.Lock()
+	d.mu.compact.closing = true
+	expired := false
+	wakeup := time.AfterFunc(time.Until(deadline), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		expired = true
+		d.mu.compact.cond.Broadcast()
+	})
+	for !expired && (d.mu.compact.compactingCount > 0 || d.mu.compact.downloadingCount > 0) {
+		d.mu.compact.cond.Wait()
+	}
+	d.mu.Unlock()
+	wakeup.Stop()
+
+	for _, j := range d.BackgroundJobs() {
+		if j.Cancelable && d.CancelJob(j.JobID) == nil {
+			res.CancelledJobs = append(res.CancelledJobs, j)
+		}
+	}
+	return res, d.Close()
+}
+
+// Close closes the DB. Close waits for the in-progress flushes and
+// compactions to complete; see CloseWithTimeout to bound the wait.
 //
 // It is not safe to close a DB until all outstanding iterators are closed
 // or to call Close concurrently with any other DB method. It is not valid
@@ -1674,6 +2151,9 @@ func (d *DB) Close() error {
 
 	d.closed.Store(errors.WithStack(ErrClosed))
 	close(d.closedCh)
+	if d.mu.bgErr.retryTimer != nil {
+		d.mu.bgErr.retryTimer.Stop()
+	}
 
 	defer d.cacheHandle.Close()
 
@@ -1691,6 +2171,8 @@ func (d *DB) Close() error {
 	if n := len(d.mu.compact.inProgress); n > 0 {
 		err = errors.Errorf("pebble: %d unexpected in-progress compactions", errors.Safe(n))
 	}
+	err = firstError(err, d.closeSeqNumTimesLocked())
+	d.closeNamedSnapshotsLocked()
 	err = firstError(err, d.mu.formatVers.marker.Close())
 	if !d.opts.ReadOnly {
 		if d.mu.log.writer != nil {
@@ -1701,6 +2183,7 @@ func (d *DB) Close() error {
 		panic("pebble: log-writer should be nil in read-only mode")
 	}
 	err = firstError(err, d.mu.log.manager.Close())
+	d.durability.close()
 	err = firstError(err, d.fileLock.Close())
 
 	// Note that versionSet.close() only closes the MANIFEST. The versions list
@@ -1794,23 +2277,322 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 	if d.opts.ReadOnly {
 		return ErrReadOnly
 	}
+	if err := d.writesPausedErr(); err != nil {
+		return err
+	}
 	if d.cmp(start, end) >= 0 {
 		return errors.Errorf("Compact start %s is not less than end %s",
 			d.opts.Comparer.FormatKey(start), d.opts.Comparer.FormatKey(end))
 	}
+	return d.compactRange(context.Background(), start, end, parallelize, nil /* onLevel */)
+}
+
+// DynamicOptions holds the subset of options that can be changed on an open
+// DB through DB.SetOptions. Fields that are left unset (nil) are not changed.
+type DynamicOptions struct {
+	// MaxConcurrentCompactions, if set, overrides the upper bound returned by
+	// Options.MaxConcurrentCompactions.
+	MaxConcurrentCompactions *int
+	// L0CompactionThreshold, if set, replaces Options.L0CompactionThreshold.
+	L0CompactionThreshold *int
+	// L0CompactionFileThreshold, if set, replaces
+	// Options.L0CompactionFileThreshold.
+	L0CompactionFileThreshold *int
+	// L0StopWritesThreshold, if set, replaces Options.L0StopWritesThreshold.
+	L0StopWritesThreshold *int
+	// BytesPerSync, if set, replaces Options.BytesPerSync for sstables and
+	// blob files created from now on.
+	BytesPerSync *int
+	// BlockCacheSize, if set, changes the size of the block cache. Note that
+	// the block cache may be shared with other DBs (see Options.Cache), which
+	// are affected as well.
+	BlockCacheSize *int64
+	// CompactionRateLimit, if set, changes the rate (in bytes per second) and
+	// burst of Options.IORateLimiter. It's an error to set it if the DB was
+	// opened without an IORateLimiter.
+	CompactionRateLimit *IORate
+}
+
+// SetOptions changes the given options on the open DB, without requiring it
+// to be reopened. The changes are not persisted to the OPTIONS file, and so
+// are lost when the DB is closed.
+func (d *DB) SetOptions(opts DynamicOptions) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	for _, o := range []struct {
+		name  string
+		value *int
+	}{
+		{"MaxConcurrentCompactions", opts.MaxConcurrentCompactions},
+		{"L0CompactionThreshold", opts.L0CompactionThreshold},
+		{"L0CompactionFileThreshold", opts.L0CompactionFileThreshold},
+		{"L0StopWritesThreshold", opts.L0StopWritesThreshold},
+	} {
+		if o.value != nil && *o.value <= 0 {
+			return errors.Errorf("pebble: %s must be positive, got %d", errors.Safe(o.name), *o.value)
+		}
+	}
+	if opts.BlockCacheSize != nil && *opts.BlockCacheSize < 0 {
+		return errors.Errorf("pebble: BlockCacheSize must not be negative, got %d", *opts.BlockCacheSize)
+	}
+	if opts.CompactionRateLimit != nil && d.opts.IORateLimiter == nil {
+		return errors.New("pebble: CompactionRateLimit requires Options.IORateLimiter")
+	}
+
+	if opts.BytesPerSync != nil {
+		d.objProvider.SetBytesPerSync(*opts.BytesPerSync)
+	}
+	if opts.BlockCacheSize != nil {
+		d.opts.Cache.SetMaxSize(*opts.BlockCacheSize)
+	}
+	if r := opts.CompactionRateLimit; r != nil {
+		d.opts.IORateLimiter.SetRate(r.BytesPerSecond, r.Burst)
+	}
+	if opts.MaxConcurrentCompactions != nil {
+		d.maxConcurrentCompactions.Store(int64(*opts.MaxConcurrentCompactions))
+	}
+
+	// The L0 thresholds are only read with d.mu held.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if opts.L0CompactionThreshold != nil {
+		d.opts.L0CompactionThreshold = *opts.L0CompactionThreshold
+	}
+	if opts.L0CompactionFileThreshold != nil {
+		d.opts.L0CompactionFileThreshold = *opts.L0CompactionFileThreshold
+	}
+	if opts.L0StopWritesThreshold != nil {
+		d.opts.L0StopWritesThreshold = *opts.L0StopWritesThreshold
+		// Wake up writers that are stalled on L0, so they can observe the new
+		// threshold.
+		d.mu.compact.cond.Broadcast()
+	}
+	d.maybeScheduleCompaction()
+	return nil
+}
 
+// AddEventListener attaches l to the open DB: the events that occur from now
+// on are forwarded to l, in addition to Options.EventListener and to the other
+// attached listeners, as with TeeEventListener. The returned function detaches
+// l; it may be called more than once. Events that are being delivered when l
+// is attached or detached may or may not be forwarded to l, so l may observe
+// the end of an operation without its beginning (e.g. FlushEnd without
+// FlushBegin).
+//
+// The DiskSlow events of a filesystem wrapped by Options.WithFSDefaults are
+// only delivered to the Options' EventListener.
+func (d *DB) AddEventListener(l EventListener) (remove func()) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	return d.eventListeners.add(l)
+}
+
+// CompactAllOptions configures DB.CompactAll.
+type CompactAllOptions struct {
+	// Parallelize, if true, splits the compaction of each level into multiple
+	// non-overlapping compactions that may run concurrently. See DB.Compact.
+	Parallelize bool
+	// OnProgress, if set, is invoked before each level is compacted, and once
+	// more after the last level has been compacted. It's invoked on the
+	// goroutine that called CompactAll, without any locks held.
+	OnProgress func(CompactAllProgress)
+}
+
+// CompactAllProgress describes the progress of a DB.CompactAll call.
+type CompactAllProgress struct {
+	// Level is the level that is about to be compacted, or -1 if CompactAll is
+	// done.
+	Level int
+	// BytesRemaining holds, for each level, the total size of the tables in the
+	// level that remain to be compacted into the next level. Levels that have
+	// already been compacted, and the bottommost level, have no bytes
+	// remaining. Flushes and compactions that run concurrently with
+	// CompactAll may add bytes to a level after it has been compacted; these
+	// are not reported.
+	BytesRemaining [numLevels]uint64
+}
+
+// CompactAll compacts the entire keyspace of the database down to the
+// bottommost level, flushing the memtables first. It's equivalent to calling
+// Compact over a key range that spans the whole database, but reports
+// progress through opts.OnProgress and stops early if ctx is canceled, in
+// which case it returns ctx.Err(). Compactions that already started when ctx
+// is canceled are allowed to complete in the background.
+func (d *DB) CompactAll(ctx context.Context, opts CompactAllOptions) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	if err := d.Flush(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Determine the bounds of the keyspace.
+	var start, end []byte
+	d.mu.Lock()
+	cur := d.mu.versions.currentVersion()
+	for level := 0; level < numLevels; level++ {
+		for f := range cur.Levels[level].All() {
+			if start == nil || d.cmp(f.Smallest.UserKey, start) < 0 {
+				start = f.Smallest.UserKey
+			}
+			if end == nil || d.cmp(f.Largest.UserKey, end) > 0 {
+				end = f.Largest.UserKey
+			}
+		}
+	}
+	d.mu.Unlock()
+	if start == nil {
+		// The database is empty.
+		if opts.OnProgress != nil {
+			opts.OnProgress(CompactAllProgress{Level: -1})
+		}
+		return nil
+	}
+	// The keys are retained by the table metadata, which may become obsolete
+	// during the compaction.
+	start, end = slices.Clone(start), slices.Clone(end)
+
+	var onLevel func(level int)
+	if opts.OnProgress != nil {
+		onLevel = func(level int) {
+			p := CompactAllProgress{Level: level}
+			if level >= 0 {
+				d.mu.Lock()
+				cur := d.mu.versions.currentVersion()
+				for l := level; l < numLevels-1; l++ {
+					p.BytesRemaining[l] = cur.Levels[l].Size()
+				}
+				d.mu.Unlock()
+			}
+			opts.OnProgress(p)
+		}
+	}
+	if err := d.compactRange(ctx, start, end, opts.Parallelize, onLevel); err != nil {
+		return err
+	}
+	if onLevel != nil {
+		onLevel(-1)
+	}
+	return nil
+}
+
+// CompactRangesOptions configures DB.CompactRanges.
+type CompactRangesOptions struct {
+	// TargetLevel is the level down to which the ranges are compacted: the
+	// tables of the levels above TargetLevel that overlap the ranges are
+	// compacted into the next level. Zero (the default) compacts the ranges
+	// down to the bottommost level.
+	TargetLevel int
+	// Parallelize, if true, splits the compaction of each range into
+	// compactions that can run in parallel, like the parallelize parameter of
+	// Compact.
+	Parallelize bool
+}
+
+// CompactRanges compacts several key ranges [start, end) in one call. It's
+// equivalent to calling Compact on each range, but the ranges are planned as a
+// batch: overlapping ranges are coalesced, the memtables are flushed at most
+// once, and the compactions of the different ranges at a given level are
+// queued together, so that they run concurrently subject to the compaction
+// concurrency. CompactRanges returns ctx.Err() if ctx is canceled; compactions
+// that already started are allowed to complete in the background.
+func (d *DB) CompactRanges(ctx context.Context, ranges []KeyRange, opts CompactRangesOptions) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	if err := d.writesPausedErr(); err != nil {
+		return err
+	}
+	if opts.TargetLevel < 0 || opts.TargetLevel >= numLevels {
+		return errors.Errorf("CompactRanges target level %d is out of range", errors.Safe(opts.TargetLevel))
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := make([]KeyRange, len(ranges))
+	for i, r := range ranges {
+		if d.cmp(r.Start, r.End) >= 0 {
+			return errors.Errorf("CompactRanges start %s is not less than end %s",
+				d.opts.Comparer.FormatKey(r.Start), d.opts.Comparer.FormatKey(r.End))
+		}
+		sorted[i] = KeyRange{Start: slices.Clone(r.Start), End: slices.Clone(r.End)}
+	}
+	targetLevel := opts.TargetLevel
+	if targetLevel == 0 {
+		targetLevel = numLevels - 1
+	}
+	return d.compactRanges(ctx, coalesceKeyRanges(d.cmp, sorted), targetLevel, opts.Parallelize, nil /* onLevel */)
+}
+
+// coalesceKeyRanges sorts the given ranges and merges those that overlap or
+// abut, returning the resulting disjoint ranges. The ranges are treated as
+// inclusive, matching compactRanges.
+func coalesceKeyRanges(cmp base.Compare, ranges []KeyRange) []KeyRange {
+	slices.SortFunc(ranges, func(a, b KeyRange) int {
+		return cmp(a.Start, b.Start)
+	})
+	coalesced := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &coalesced[len(coalesced)-1]
+		if cmp(r.Start, last.End) <= 0 {
+			if cmp(r.End, last.End) > 0 {
+				last.End = r.End
+			}
+			continue
+		}
+		coalesced = append(coalesced, r)
+	}
+	return coalesced
+}
+
+// compactRange compacts the tables overlapping the inclusive range
+// [start,end], one level at a time, after waiting for any overlapping
+// memtables to flush. If onLevel is non-nil, it's invoked before each level is
+// compacted.
+func (d *DB) compactRange(
+	ctx context.Context, start, end []byte, parallelize bool, onLevel func(level int),
+) error {
+	return d.compactRanges(ctx, []KeyRange{{Start: start, End: end}}, numLevels-1, parallelize, onLevel)
+}
+
+// compactRanges compacts the tables overlapping the given non-overlapping
+// inclusive ranges, one level at a time, after waiting for any overlapping
+// memtables to flush. The ranges are compacted concurrently at each level. The
+// levels below targetLevel are compacted. If onLevel is non-nil, it's invoked
+// before each level is compacted.
+func (d *DB) compactRanges(
+	ctx context.Context,
+	ranges []KeyRange,
+	targetLevel int,
+	parallelize bool,
+	onLevel func(level int),
+) error {
 	d.mu.Lock()
 	maxLevelWithFiles := 1
 	cur := d.mu.versions.currentVersion()
 	for level := 0; level < numLevels; level++ {
-		overlaps := cur.Overlaps(level, base.UserKeyBoundsInclusive(start, end))
-		if !overlaps.Empty() {
-			maxLevelWithFiles = level + 1
+		for _, r := range ranges {
+			overlaps := cur.Overlaps(level, base.UserKeyBoundsInclusive(r.Start, r.End))
+			if !overlaps.Empty() {
+				maxLevelWithFiles = level + 1
+				break
+			}
 		}
 	}
 
-	// Determine if any memtable overlaps with the compaction range. We wait for
-	// any such overlap to flush (initiating a flush if necessary).
+	// Determine if any memtable overlaps with the compaction ranges. We wait
+	// for any such overlap to flush (initiating a flush if necessary).
 	mem, err := func() (*flushableEntry, error) {
 		// Check to see if any files overlap with any of the memtables. The queue
 		// is ordered from oldest to newest with the mutable memtable being the
@@ -1819,10 +2601,15 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 		for i := len(d.mu.mem.queue) - 1; i >= 0; i-- {
 			mem := d.mu.mem.queue[i]
 			var anyOverlaps bool
-			mem.computePossibleOverlaps(func(b bounded) shouldContinue {
-				anyOverlaps = true
-				return stopIteration
-			}, KeyRange{Start: start, End: end})
+			for _, r := range ranges {
+				mem.computePossibleOverlaps(func(b bounded) shouldContinue {
+					anyOverlaps = true
+					return stopIteration
+				}, r)
+				if anyOverlaps {
+					break
+				}
+			}
 			if !anyOverlaps {
 				continue
 			}
@@ -1837,10 +2624,10 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 				defer d.commit.mu.Unlock() //nolint:deferloop
 				if mem.flushable == d.mu.mem.mutable {
 					// Only flush if the active memtable is unchanged.
-					err = d.makeRoomForWrite(nil)
+					err = d.makeRoomForWrite(nil, FlushReasonManual)
 				}
 			}
-			mem.flushForced = true
+			mem.forceFlush(FlushReasonManual)
 			d.maybeScheduleFlush()
 			return mem, err
 		}
@@ -1856,10 +2643,13 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 		<-mem.flushed
 	}
 
-	for level := 0; level < maxLevelWithFiles; {
+	for level := 0; level < min(maxLevelWithFiles, targetLevel); {
+		if onLevel != nil {
+			onLevel(level)
+		}
 		for {
-			if err := d.manualCompact(
-				start, end, level, parallelize); err != nil {
+			if err := d.manualCompactRanges(
+				ctx, ranges, level, parallelize); err != nil {
 				if errors.Is(err, ErrCancelledCompaction) {
 					continue
 				}
@@ -1877,26 +2667,43 @@ func (d *DB) Compact(start, end []byte, parallelize bool) error {
 	return nil
 }
 
-func (d *DB) manualCompact(start, end []byte, level int, parallelize bool) error {
+func (d *DB) manualCompact(
+	ctx context.Context, start, end []byte, level int, parallelize bool,
+) error {
+	return d.manualCompactRanges(ctx, []KeyRange{{Start: start, End: end}}, level, parallelize)
+}
+
+// manualCompactRanges compacts the tables of the given level overlapping the
+// given inclusive ranges, and waits for the compactions to complete.
+func (d *DB) manualCompactRanges(
+	ctx context.Context, ranges []KeyRange, level int, parallelize bool,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	d.mu.Lock()
 	curr := d.mu.versions.currentVersion()
-	files := curr.Overlaps(level, base.UserKeyBoundsInclusive(start, end))
-	if files.Empty() {
+	var compactions []*manualCompaction
+	for _, r := range ranges {
+		files := curr.Overlaps(level, base.UserKeyBoundsInclusive(r.Start, r.End))
+		if files.Empty() {
+			continue
+		}
+		if parallelize {
+			compactions = append(compactions, d.splitManualCompaction(r.Start, r.End, level)...)
+		} else {
+			compactions = append(compactions, &manualCompaction{
+				level: level,
+				done:  make(chan error, 1),
+				start: r.Start,
+				end:   r.End,
+			})
+		}
+	}
+	if len(compactions) == 0 {
 		d.mu.Unlock()
 		return nil
 	}
-
-	var compactions []*manualCompaction
-	if parallelize {
-		compactions = append(compactions, d.splitManualCompaction(start, end, level)...)
-	} else {
-		compactions = append(compactions, &manualCompaction{
-			level: level,
-			done:  make(chan error, 1),
-			start: start,
-			end:   end,
-		})
-	}
 	for i := range compactions {
 		d.mu.compact.manualID++
 		compactions[i].id = d.mu.compact.manualID
@@ -1914,13 +2721,30 @@ func (d *DB) manualCompact(start, end []byte, level int, parallelize bool) error
 	// necessary to read from each channel, and so we can exit early in the event
 	// of an error.
 	for _, compaction := range compactions {
-		if err := <-compaction.done; err != nil {
-			return err
+		select {
+		case err := <-compaction.done:
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			d.removeQueuedManualCompactions(compactions)
+			return ctx.Err()
 		}
 	}
 	return nil
 }
 
+// removeQueuedManualCompactions removes those of the given manual compactions
+// that have not been picked yet from the queue of manual compactions.
+func (d *DB) removeQueuedManualCompactions(compactions []*manualCompaction) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.compact.manual = slices.DeleteFunc(d.mu.compact.manual, func(m *manualCompaction) bool {
+		return slices.Contains(compactions, m)
+	})
+	d.mu.compact.manualLen.Store(int32(len(d.mu.compact.manual)))
+}
+
 // splitManualCompaction splits a manual compaction over [start,end] on level
 // such that the resulting compactions have no key overlap.
 func (d *DB) splitManualCompaction(
@@ -1945,6 +2769,102 @@ func (d *DB) splitManualCompaction(
 	return splitCompactions
 }
 
+// DisableAutomaticCompactions pauses the scheduling of automatic compactions
+// (including delete-only compactions) until EnableAutomaticCompactions is
+// called. Compactions that are already running are allowed to complete, and
+// flushes, manual compactions and downloads continue to be scheduled. It's
+// useful for pausing compactions during bulk ingestion or backup windows,
+// without reopening the DB; Metrics.Compact.Paused and EstimatedDebt reflect
+// the paused state and the debt accumulated in the meantime.
+func (d *DB) DisableAutomaticCompactions() {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.compact.paused = true
+}
+
+// EnableAutomaticCompactions resumes the scheduling of automatic compactions
+// after a call to DisableAutomaticCompactions. It has no effect on automatic
+// compactions disabled through Options.DisableAutomaticCompactions.
+func (d *DB) EnableAutomaticCompactions() {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.compact.paused = false
+	d.maybeScheduleCompaction()
+}
+
+// automaticCompactionsDisabled returns true if automatic compactions must not
+// be scheduled, either through Options.DisableAutomaticCompactions, because
+// they were paused through DB.DisableAutomaticCompactions, or during a bulk
+// load.
+//
+// d.mu must be held when calling this.
+func (d *DB) automaticCompactionsDisabled() bool {
+	return d.opts.DisableAutomaticCompactions || d.mu.compact.paused || d.mu.compact.bulkLoad
+}
+
+// BeginBulkLoad puts the DB in bulk-load mode, for the initial load of a large
+// amount of data, until EndBulkLoad is called. During a bulk load:
+//   - automatic compactions are paused, like with DisableAutomaticCompactions,
+//     so that the loaded data isn't compacted repeatedly as the LSM grows;
+//   - writes aren't stalled because of the read amplification of L0, or by the
+//     decisions of a custom WriteController. Writes are still stalled when too
+//     many memtables are queued for flushing, since that bounds the memory
+//     used by the memtables;
+//   - ingest-time splitting (see Options.Experimental.IngestSplit) is enabled,
+//     so that an ingested table whose bounds overlap a single table of a
+//     level, without overlapping its data, is placed in that level rather than
+//     above it. Ingested tables are placed in the lowest level whose data they
+//     don't overlap.
+//
+// Reads may become slower as L0 grows during the bulk load.
+func (d *DB) BeginBulkLoad() error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.mu.compact.bulkLoad {
+		return errors.New("pebble: bulk load already in progress")
+	}
+	d.mu.compact.bulkLoad = true
+	// Wake up the writes stalled on L0, so they can observe the bulk load.
+	d.mu.compact.cond.Broadcast()
+	d.updateLowPriorityStallLocked(d.lsmHealthLocked())
+	return nil
+}
+
+// EndBulkLoad ends the bulk load started by BeginBulkLoad. It resumes
+// automatic compactions and write stalls, and reshapes the LSM by compacting
+// the entire keyspace down to the bottommost level, like CompactAll with
+// Parallelize set; tables that were ingested into the bottommost level aren't
+// rewritten unless data above them overlaps them. EndBulkLoad waits for the
+// compactions to complete, and returns ctx.Err() if ctx is canceled before
+// then, in which case the bulk load is still ended and the compactions that
+// already started complete in the background.
+func (d *DB) EndBulkLoad(ctx context.Context) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	if !d.mu.compact.bulkLoad {
+		d.mu.Unlock()
+		return errors.New("pebble: no bulk load in progress")
+	}
+	d.mu.compact.bulkLoad = false
+	d.maybeScheduleCompaction()
+	d.mu.Unlock()
+	return d.CompactAll(ctx, CompactAllOptions{Parallelize: true})
+}
+
 // Flush the memtable to stable storage.
 func (d *DB) Flush() error {
 	flushDone, err := d.AsyncFlush()
@@ -1966,33 +2886,124 @@ func (d *DB) AsyncFlush() (<-chan struct{}, error) {
 	if d.opts.ReadOnly {
 		return nil, ErrReadOnly
 	}
+	if err := d.writesPausedErr(); err != nil {
+		return nil, err
+	}
 
 	d.commit.mu.Lock()
 	defer d.commit.mu.Unlock()
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	flushed := d.mu.mem.queue[len(d.mu.mem.queue)-1].flushed
-	err := d.makeRoomForWrite(nil)
+	err := d.makeRoomForWrite(nil, FlushReasonManual)
 	if err != nil {
 		return nil, err
 	}
 	return flushed, nil
 }
 
+// RotateWAL seals the current mutable memtable and its write-ahead log,
+// directing subsequent writes to a new memtable and WAL. It returns the file
+// number of the new WAL: every write committed before RotateWAL returns is
+// contained in a WAL with a lower file number, and every write committed
+// after is contained in a WAL with this file number or higher.
+//
+// Unlike AsyncFlush, RotateWAL does not force a flush of the sealed memtable;
+// it's flushed once enough immutable memtables have accumulated, as usual.
+// RotateWAL may block if writes are stalled.
+//
+// RotateWAL returns an error if the DB is read-only or the WAL is disabled.
+func (d *DB) RotateWAL() (base.DiskFileNum, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return 0, ErrReadOnly
+	}
+	if d.opts.DisableWAL {
+		return 0, errors.New("pebble: cannot rotate WAL when the WAL is disabled")
+	}
+
+	d.commit.mu.Lock()
+	defer d.commit.mu.Unlock()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.rotateWALAndMemtableLocked(), nil
+}
+
+// rotateWALAndMemtableLocked seals the mutable memtable and its WAL, and
+// returns the file number of the new WAL.
+//
+// Both DB.mu and commitPipeline.mu must be held by the caller. Note that DB.mu
+// may be released and reacquired.
+func (d *DB) rotateWALAndMemtableLocked() base.DiskFileNum {
+	d.maybeInduceWriteStall(nil)
+
+	newLogNum, prevLogSize := d.rotateWAL()
+	immMem := d.mu.mem.mutable
+	imm := d.mu.mem.queue[len(d.mu.mem.queue)-1]
+	imm.logSize = prevLogSize
+	logSeqNum := base.SeqNum(d.mu.versions.logSeqNum.Load())
+	d.rotateMemtable(newLogNum, logSeqNum, immMem, 0 /* minSize */)
+	return newLogNum
+}
+
+// ForceWALFailover forces WAL writes to the directory with the given index,
+// suspending automatic WAL failover and failback until
+// ResumeAutomaticWALFailover is called. Index 0 is the primary WAL directory,
+// 1 is WALFailoverOptions.Secondary, and i+2 is
+// WALFailoverOptions.AdditionalSecondaries[i]. Forcing index 0 fails back to
+// the primary. This allows draining a WAL directory proactively, e.g. before
+// maintenance of the underlying disk.
+//
+// The switch is asynchronous: records already queued for the previous
+// directory continue to be written to it. Metrics.WAL.Failover reports the
+// directory in use. ForceWALFailover returns an error if WAL failover is not
+// configured.
+func (d *DB) ForceWALFailover(index int) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if index < 0 {
+		return errors.Errorf("pebble: invalid WAL dir index %d", index)
+	}
+	return d.mu.log.manager.SetForcedDir(index)
+}
+
+// ResumeAutomaticWALFailover resumes automatic WAL failover and failback after
+// ForceWALFailover. WAL writes remain in the forced directory until the
+// failover heuristics decide to switch.
+func (d *DB) ResumeAutomaticWALFailover() error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	return d.mu.log.manager.SetForcedDir(-1)
+}
+
 // Metrics returns metrics about the database.
 func (d *DB) Metrics() *Metrics {
 	metrics := &Metrics{}
 	walStats := d.mu.log.manager.Stats()
+	d.iters.checkLeaks()
 
 	d.mu.Lock()
 	vers := d.mu.versions.currentVersion()
 	*metrics = d.mu.versions.metrics
+	metrics.Compact.UsageByLevels = maps.Clone(metrics.Compact.UsageByLevels)
+	metrics.Compact.UsageByReason = maps.Clone(metrics.Compact.UsageByReason)
 	metrics.Compact.EstimatedDebt = d.mu.versions.picker.estimatedCompactionDebt(0)
 	metrics.Compact.InProgressBytes = d.mu.versions.atomicInProgressBytes.Load()
 	// TODO(radu): split this to separate the download compactions.
 	metrics.Compact.NumInProgress = int64(d.mu.compact.compactingCount + d.mu.compact.downloadingCount)
+	metrics.Compact.Paused = d.mu.compact.paused || d.mu.compact.bulkLoad
+	metrics.Compact.MaxConcurrency = d.opts.MaxConcurrentCompactions()
 	metrics.Compact.MarkedFiles = vers.Stats.MarkedForCompaction
+	metrics.Compact.DeletionHints = len(d.mu.compact.deletionHints)
+	metrics.Compact.DeleteOnlyEligibleFiles, metrics.Compact.DeleteOnlyEligibleSize = d.deleteOnlyEligibleFiles()
 	metrics.Compact.Duration = d.mu.compact.duration
+	d.updateAmpWindowLocked()
+	metrics.Windowed = d.mu.ampWindow.metrics()
+	d.staleIteratorMetricsLocked(metrics)
 	for c := range d.mu.compact.inProgress {
 		if c.kind != compactionKindFlush && c.kind != compactionKindIngestedFlushable {
 			metrics.Compact.Duration += d.timeNow().Sub(c.beganAt)
@@ -2002,6 +3013,10 @@ func (d *DB) Metrics() *Metrics {
 	for _, m := range d.mu.mem.queue {
 		metrics.MemTable.Size += m.totalBytes()
 	}
+	metrics.TableStats.PendingCount = len(d.mu.tableStats.pending)
+	metrics.TableStats.BacklogCount = len(d.mu.tableStats.backlog)
+	metrics.TableStats.LoadedCount = d.mu.tableStats.loadedCount
+	metrics.TableStats.InitialLoadCompleted = d.mu.tableStats.loadedInitial
 	metrics.Snapshots.Count = d.mu.snapshots.count()
 	if metrics.Snapshots.Count > 0 {
 		metrics.Snapshots.EarliestSeqNum = d.mu.snapshots.earliest()
@@ -2035,6 +3050,9 @@ func (d *DB) Metrics() *Metrics {
 			metrics.Levels[level].Score = score
 		}
 	}
+	metrics.TempFiles.Count = d.tempFiles.count.Load()
+	metrics.TempFiles.Size = uint64(d.tempFiles.size.Load())
+
 	metrics.Table.ZombieCount = int64(len(d.mu.versions.zombieTables))
 	for _, info := range d.mu.versions.zombieTables {
 		metrics.Table.ZombieSize += info.FileSize
@@ -2059,18 +3077,21 @@ func (d *DB) Metrics() *Metrics {
 	if err := metrics.LogWriter.Merge(&d.mu.log.metrics.LogWriterMetrics); err != nil {
 		d.opts.Logger.Errorf("metrics error: %s", err)
 	}
+	metrics.CommitPipeline = d.commitMetrics.load()
 	metrics.Flush.WriteThroughput = d.mu.compact.flushWriteThroughput
 	if d.mu.compact.flushing {
 		metrics.Flush.NumInProgress = 1
 	}
 	for i := 0; i < numLevels; i++ {
 		metrics.Levels[i].Additional.ValueBlocksSize = *valueBlockSizeAnnotator.LevelAnnotation(vers.Levels[i])
+		metrics.Levels[i].Additional.SampledReads = d.tableAccess.sampledReads[i].Load()
 		compressionTypes := compressionTypeAnnotator.LevelAnnotation(vers.Levels[i])
 		metrics.Table.CompressedCountUnknown += int64(compressionTypes.unknown)
 		metrics.Table.CompressedCountSnappy += int64(compressionTypes.snappy)
 		metrics.Table.CompressedCountZstd += int64(compressionTypes.zstd)
 		metrics.Table.CompressedCountNone += int64(compressionTypes.none)
 	}
+	metrics.UserProperties = d.mu.annotators.userProperties.aggregate(vers)
 
 	d.mu.Unlock()
 
@@ -2080,6 +3101,19 @@ func (d *DB) Metrics() *Metrics {
 	metrics.CategoryStats = d.fileCache.SSTStatsCollector().GetStats()
 
 	metrics.SecondaryCacheMetrics = d.objProvider.Metrics()
+	metrics.RemoteStorageBreakers = d.objProvider.RemoteBreakers()
+	if d.scanCache != nil {
+		metrics.ScanCache = d.scanCache.metrics()
+	}
+	if d.externalValues != nil {
+		metrics.ExternalValues = d.externalValues.metrics()
+	}
+	for fs := d.opts.FS; fs != nil; fs = fs.Unwrap() {
+		if ifs, ok := fs.(*vfs.InstrumentedFS); ok {
+			metrics.IO = ifs.Metrics()
+			break
+		}
+	}
 
 	metrics.Uptime = d.timeNow().Sub(d.openedAt)
 
@@ -2184,6 +3218,12 @@ type SSTableInfo struct {
 	// sstable that fall within a particular span. It's populated only when the
 	// ApproximateSpanBytes option is passed into DB.SSTables.
 	ApproximateSpanBytes uint64 `json:"ApproximateSpanBytes,omitempty"`
+	// SampledReads is the number of sampled reads of the sstable since it was
+	// added to the LSM or the DB was opened, whichever is later. LastAccess is
+	// the time of the most recent sampled read, or the zero time if there has
+	// been none. Together they describe how "hot" the sstable is.
+	SampledReads uint64
+	LastAccess   time.Time
 
 	// Properties is the sstable properties of this table. If Virtual is true,
 	// then the Properties are associated with the backing sst.
@@ -2240,6 +3280,8 @@ func (d *DB) SSTables(opts ...SSTablesOption) ([][]SSTableInfo, error) {
 				destTables[j].Properties = p
 			}
 			destTables[j].Virtual = m.Virtual
+			destTables[j].SampledReads = m.Access.Count()
+			destTables[j].LastAccess = m.Access.LastAccess()
 			destTables[j].BackingSSTNum = m.FileBacking.DiskFileNum
 			objMeta, err := d.objProvider.Lookup(base.FileTypeTable, m.FileBacking.DiskFileNum)
 			if err != nil {
@@ -2473,65 +3515,46 @@ func (d *DB) newFlushableEntry(
 //     to reduce the read amplification before accepting more writes that will
 //     increase write pressure.
 //
-// maybeInduceWriteStall checks these stall conditions, and if present, waits
-// for them to abate.
+// maybeInduceWriteStall checks these stall conditions through the
+// WriteController (see defaultWriteController), and if present, waits for them
+// to abate.
 func (d *DB) maybeInduceWriteStall(b *Batch) {
 	stalled := false
 	// This function will call EventListener.WriteStallBegin at most once.  If
 	// it does call it, it will call EventListener.WriteStallEnd once before
 	// returning.
 	for {
-		var size uint64
-		for i := range d.mu.mem.queue {
-			size += d.mu.mem.queue[i].totalBytes()
-		}
-		// If ElevateWriteStallThresholdForFailover is true, we give an
-		// unlimited memory budget for memtables. This is simpler than trying to
-		// configure an explicit value, given that memory resources can vary.
-		// When using WAL failover in CockroachDB, an OOM risk is worth
-		// tolerating for workloads that have a strict latency SLO. Also, an
-		// unlimited budget here does not mean that the disk stall in the
-		// primary will go unnoticed until the OOM -- CockroachDB is monitoring
-		// disk stalls, and we expect it to fail the node after ~60s if the
-		// primary is stalled.
-		if size >= uint64(d.opts.MemTableStopWritesThreshold)*d.opts.MemTableSize &&
-			!d.mu.log.manager.ElevateWriteStallThresholdForFailover() {
-			// We have filled up the current memtable, but already queued memtables
-			// are still flushing, so we wait.
-			if !stalled {
-				stalled = true
-				d.opts.EventListener.WriteStallBegin(WriteStallBeginInfo{
-					Reason: "memtable count limit reached",
-				})
-			}
-			beforeWait := crtime.NowMono()
-			d.mu.compact.cond.Wait()
-			if b != nil {
-				b.commitStats.MemTableWriteStallDuration += beforeWait.Elapsed()
-			}
-			continue
+		reason := d.writeController.ShouldStall(d.lsmHealthLocked())
+		if d.mu.compact.bulkLoad && reason != WriteStallMemTable {
+			// Bulk loads are only stalled on the memtables.
+			reason = WriteStallNone
 		}
-		l0ReadAmp := d.mu.versions.l0Organizer.ReadAmplification()
-		if l0ReadAmp >= d.opts.L0StopWritesThreshold {
-			// There are too many level-0 files, so we wait.
-			if !stalled {
-				stalled = true
-				d.opts.EventListener.WriteStallBegin(WriteStallBeginInfo{
-					Reason: "L0 file count limit exceeded",
-				})
-			}
-			beforeWait := crtime.NowMono()
-			d.mu.compact.cond.Wait()
-			if b != nil {
+		if reason == WriteStallNone {
+			break
+		}
+		if !stalled {
+			stalled = true
+			d.setWriteStalledLocked(true)
+			d.opts.EventListener.WriteStallBegin(WriteStallBeginInfo{
+				Reason: reason.String(),
+			})
+		}
+		beforeWait := crtime.NowMono()
+		d.mu.compact.cond.Wait()
+		if b != nil {
+			switch reason {
+			case WriteStallMemTable:
+				b.commitStats.MemTableWriteStallDuration += beforeWait.Elapsed()
+			case WriteStallL0:
 				b.commitStats.L0ReadAmpWriteStallDuration += beforeWait.Elapsed()
+			default:
+				b.commitStats.WriteControllerWaitDuration += beforeWait.Elapsed()
 			}
-			continue
-		}
-		// Not stalled.
-		if stalled {
-			d.opts.EventListener.WriteStallEnd()
 		}
-		return
+	}
+	if stalled {
+		d.setWriteStalledLocked(false)
+		d.opts.EventListener.WriteStallEnd()
 	}
 }
 
@@ -2546,9 +3569,11 @@ func (d *DB) maybeInduceWriteStall(b *Batch) {
 // rotation regardless, the caller may pass a nil Batch, and no space in the
 // resulting mutable memtable will be reserved.
 //
+// If b is nil, reason is the reason the flush of the rotated memtable is forced.
+//
 // Both DB.mu and commitPipeline.mu must be held by the caller. Note that DB.mu
 // may be released and reacquired.
-func (d *DB) makeRoomForWrite(b *Batch) error {
+func (d *DB) makeRoomForWrite(b *Batch, reason FlushReason) error {
 	if b != nil && b.ingestedSSTBatch {
 		panic("pebble: invalid function call")
 	}
@@ -2597,7 +3622,7 @@ func (d *DB) makeRoomForWrite(b *Batch) error {
 		//
 		// This is a manual forced flush.
 		logSeqNum = base.SeqNum(d.mu.versions.logSeqNum.Load())
-		imm.flushForced = true
+		imm.forceFlush(reason)
 		// If we are manually flushing and we used less than half of the bytes in
 		// the memtable, don't increase the size for the next memtable. This
 		// reduces memtable memory pressure when an application is frequently
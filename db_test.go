@@ -24,11 +24,13 @@ import (
 	"github.com/cockroachdb/crlib/fifo"
 	"github.com/cockroachdb/datadriven"
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/batchrepr"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
 	"github.com/cockroachdb/pebble/internal/testkeys"
 	"github.com/cockroachdb/pebble/internal/testutils"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/record"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
@@ -443,6 +445,121 @@ func TestLargeBatch(t *testing.T) {
 	require.NoError(t, d.Close())
 }
 
+func TestWALBatchCompression(t *testing.T) {
+	for _, fmv := range []FormatMajorVersion{FormatSuffixReplacements, FormatWALBatchCompression} {
+		t.Run(fmv.String(), func(t *testing.T) {
+			mem := vfs.NewMem()
+			opts := &Options{
+				FS:                 mem,
+				FormatMajorVersion: fmv,
+				MemTableSize:       256 << 10,
+			}
+			opts.Experimental.WALBatchCompressionThreshold = 1 << 10
+			d, err := Open("", opts)
+			require.NoError(t, err)
+
+			logSize := func() int64 {
+				d.mu.Lock()
+				defer d.mu.Unlock()
+				logs := d.mu.log.manager.List()
+				size, err := logs[len(logs)-1].PhysicalSize()
+				require.NoError(t, err)
+				return int64(size)
+			}
+			compressed := fmv >= FormatWALBatchCompression
+
+			// A batch above the threshold is compressed if the format major
+			// version allows it.
+			b := d.NewBatch()
+			for i := 0; i < 100; i++ {
+				require.NoError(t, b.Set([]byte(fmt.Sprintf("key-%03d", i)), bytes.Repeat([]byte("v"), 100), nil))
+			}
+			batchSize := int64(len(b.Repr()))
+			before := logSize()
+			require.NoError(t, d.Apply(b, Sync))
+			require.Equal(t, compressed, logSize()-before < batchSize/2)
+
+			// A batch below the threshold isn't compressed.
+			before = logSize()
+			require.NoError(t, d.Set([]byte("small"), bytes.Repeat([]byte("s"), 500), Sync))
+			require.Less(t, int64(500), logSize()-before)
+
+			// Neither is a batch that doesn't compress well.
+			rng := rand.New(rand.NewPCG(0, 0))
+			incompressible := make([]byte, 4<<10)
+			for i := range incompressible {
+				incompressible[i] = byte(rng.Uint32())
+			}
+			before = logSize()
+			require.NoError(t, d.Set([]byte("random"), incompressible, Sync))
+			require.Less(t, int64(len(incompressible)), logSize()-before)
+
+			// A large batch that's flushed as its own memtable is compressed
+			// too.
+			b = d.NewBatch()
+			for i := 0; i < 2000; i++ {
+				require.NoError(t, b.Set([]byte(fmt.Sprintf("large-%04d", i)), bytes.Repeat([]byte("l"), 100), nil))
+			}
+			require.NoError(t, d.Apply(b, Sync))
+
+			// The batches are decompressed when the WAL is replayed.
+			require.NoError(t, d.Close())
+			d, err = Open("", opts)
+			require.NoError(t, err)
+			check := func(key string, expected []byte) {
+				v, closer, err := d.Get([]byte(key))
+				require.NoError(t, err)
+				require.Equal(t, expected, v)
+				require.NoError(t, closer.Close())
+			}
+			check("key-042", bytes.Repeat([]byte("v"), 100))
+			check("small", bytes.Repeat([]byte("s"), 500))
+			check("random", incompressible)
+			check("large-1999", bytes.Repeat([]byte("l"), 100))
+			require.NoError(t, d.Close())
+		})
+	}
+}
+
+// TestWALBatchCompressionCorrupt tests that a compressed batch that fails to
+// decompress during WAL replay is reported as corruption.
+func TestWALBatchCompressionCorrupt(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                 mem,
+		FormatMajorVersion: FormatWALBatchCompression,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+
+	b := newBatch(nil)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, b.Set([]byte(fmt.Sprintf("key-%03d", i)), bytes.Repeat([]byte("v"), 100), nil))
+	}
+	b.setSeqNum(100)
+	repr, ok := batchrepr.Compress(nil, b.Repr())
+	require.True(t, ok)
+	// Truncate the compressed body, which is detected by the decoder.
+	repr = repr[:len(repr)-10]
+
+	// Write the corrupt batch to a WAL that's newer than the flushed ones.
+	f, err := mem.Create("000100.log", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := record.NewWriter(f)
+	rw, err := w.Next()
+	require.NoError(t, err)
+	_, err = rw.Write(repr)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	_, err = Open("", opts)
+	require.Error(t, err)
+	require.True(t, base.IsCorruptionError(err), "%+v", err)
+	require.ErrorContains(t, err, "decompressing batch")
+}
+
 func TestGetNoCache(t *testing.T) {
 	cache := NewCache(0)
 	defer cache.Unref()
@@ -950,6 +1067,214 @@ func TestCacheEvict(t *testing.T) {
 	}
 }
 
+func TestCompactAll(t *testing.T) {
+	d, err := Open("", testingRandomized(t, &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+	}))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 100; j++ {
+			key := []byte(fmt.Sprintf("%04d", i*100+j))
+			require.NoError(t, d.Set(key, key, nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	// Leave some data in the memtable.
+	require.NoError(t, d.Set([]byte("9999"), nil, nil))
+
+	// A canceled context stops CompactAll.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, d.CompactAll(ctx, CompactAllOptions{}), context.Canceled)
+
+	var progress []CompactAllProgress
+	require.NoError(t, d.CompactAll(context.Background(), CompactAllOptions{
+		OnProgress: func(p CompactAllProgress) { progress = append(progress, p) },
+	}))
+	require.Greater(t, len(progress), 1)
+	require.Equal(t, 0, progress[0].Level)
+	require.NotZero(t, progress[0].BytesRemaining[0])
+	require.Equal(t, CompactAllProgress{Level: -1}, progress[len(progress)-1])
+
+	m := d.Metrics()
+	for level := 0; level < numLevels-1; level++ {
+		require.Zero(t, m.Levels[level].NumFiles, "level %d", level)
+	}
+	require.NotZero(t, m.Levels[numLevels-1].NumFiles)
+	v, closer, err := d.Get([]byte("9999"))
+	require.NoError(t, err)
+	require.Empty(t, v)
+	require.NoError(t, closer.Close())
+}
+
+func TestCompactRanges(t *testing.T) {
+	d, err := Open("", testingRandomized(t, &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+	}))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for _, prefix := range []string{"a", "b", "c", "d"} {
+		for j := 0; j < 100; j++ {
+			key := []byte(fmt.Sprintf("%s%03d", prefix, j))
+			require.NoError(t, d.Set(key, key, nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.Equal(t, int64(4), d.Metrics().Levels[0].NumFiles)
+
+	ctx := context.Background()
+	require.Error(t, d.CompactRanges(ctx, []KeyRange{{Start: []byte("b"), End: []byte("a")}}, CompactRangesOptions{}))
+	require.Error(t, d.CompactRanges(ctx, nil, CompactRangesOptions{TargetLevel: numLevels}))
+	require.NoError(t, d.CompactRanges(ctx, nil, CompactRangesOptions{}))
+
+	// Only the tables overlapping the ranges are compacted.
+	require.NoError(t, d.CompactRanges(ctx, []KeyRange{
+		{Start: []byte("c"), End: []byte("c5")},
+		{Start: []byte("a"), End: []byte("a5")},
+		{Start: []byte("c4"), End: []byte("c9")},
+	}, CompactRangesOptions{Parallelize: true}))
+	m := d.Metrics()
+	require.Equal(t, int64(2), m.Levels[0].NumFiles)
+	require.Equal(t, int64(2), m.Levels[numLevels-1].NumFiles)
+	require.Equal(t, int64(2), m.Compact.Count)
+}
+
+func TestCoalesceKeyRanges(t *testing.T) {
+	kr := func(start, end string) KeyRange {
+		return KeyRange{Start: []byte(start), End: []byte(end)}
+	}
+	ranges := []KeyRange{kr("m", "p"), kr("a", "c"), kr("x", "z"), kr("b", "e"), kr("e", "g"), kr("n", "o")}
+	require.Equal(t,
+		[]KeyRange{kr("a", "g"), kr("m", "p"), kr("x", "z")},
+		coalesceKeyRanges(DefaultComparer.Compare, ranges))
+}
+
+func TestPauseAutomaticCompactions(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                    vfs.NewMem(),
+		L0CompactionThreshold: 2,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	d.DisableAutomaticCompactions()
+	require.True(t, d.Metrics().Compact.Paused)
+	for i := 0; i < 4; i++ {
+		key := []byte(fmt.Sprintf("%04d", i))
+		require.NoError(t, d.Set(key, key, nil))
+		require.NoError(t, d.Flush())
+	}
+	m := d.Metrics()
+	require.Equal(t, int64(4), m.Levels[0].NumFiles)
+	require.Zero(t, m.Compact.Count)
+	require.NotZero(t, m.Compact.EstimatedDebt)
+
+	d.EnableAutomaticCompactions()
+	require.False(t, d.Metrics().Compact.Paused)
+	require.Eventually(t, func() bool {
+		return d.Metrics().Levels[0].NumFiles == 0
+	}, 10*time.Second, time.Millisecond)
+}
+
+func TestBulkLoad(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{
+		FS:                    mem,
+		FormatMajorVersion:    FormatNewest,
+		L0CompactionThreshold: 1,
+		L0StopWritesThreshold: 2,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	ingest := func(keys ...string) {
+		f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), d.opts.MakeWriterOptions(0, d.TableFormat()))
+		for _, k := range keys {
+			require.NoError(t, w.Set([]byte(k), nil))
+		}
+		require.NoError(t, w.Close())
+		require.NoError(t, d.Ingest(context.Background(), []string{"ext"}))
+	}
+
+	require.NoError(t, d.BeginBulkLoad())
+	require.Error(t, d.BeginBulkLoad())
+	require.True(t, d.Metrics().Compact.Paused)
+	// Writes aren't stalled even though L0 exceeds L0StopWritesThreshold.
+	for i := 0; i < 4; i++ {
+		require.NoError(t, d.Set([]byte("0"), []byte(strconv.Itoa(i)), nil))
+		require.NoError(t, d.Flush())
+	}
+	require.Equal(t, int32(4), d.Metrics().Levels[0].Sublevels)
+	// The second table overlaps the bounds of the first one without
+	// overlapping its data, so the first table is split to make room for it
+	// in the bottommost level.
+	ingest("a", "z")
+	ingest("m")
+	m := d.Metrics()
+	require.Equal(t, int64(3), m.Levels[numLevels-1].NumFiles)
+	require.Equal(t, uint64(2), m.Levels[numLevels-1].NumVirtualFiles)
+	require.Zero(t, m.Compact.Count)
+
+	// Ending the bulk load compacts L0 down to the bottommost level.
+	require.NoError(t, d.EndBulkLoad(context.Background()))
+	require.Error(t, d.EndBulkLoad(context.Background()))
+	m = d.Metrics()
+	require.False(t, m.Compact.Paused)
+	require.Zero(t, m.Levels[0].NumFiles)
+	require.Equal(t, int64(4), m.Levels[numLevels-1].NumFiles)
+	v, closer, err := d.Get([]byte("0"))
+	require.NoError(t, err)
+	require.Equal(t, "3", string(v))
+	require.NoError(t, closer.Close())
+}
+
+func TestSetOptions(t *testing.T) {
+	c := cache.New(1 << 20)
+	defer c.Unref()
+	limiter := NewIORateLimiter(0, 0)
+	d, err := Open("", &Options{
+		FS:            vfs.NewMem(),
+		Cache:         c,
+		IORateLimiter: limiter,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	intPtr := func(v int) *int { return &v }
+	cacheSize := int64(2 << 20)
+	require.NoError(t, d.SetOptions(DynamicOptions{
+		MaxConcurrentCompactions:  intPtr(3),
+		L0CompactionThreshold:     intPtr(8),
+		L0CompactionFileThreshold: intPtr(100),
+		L0StopWritesThreshold:     intPtr(20),
+		BytesPerSync:              intPtr(1 << 20),
+		BlockCacheSize:            &cacheSize,
+		CompactionRateLimit:       &IORate{BytesPerSecond: 10 << 20, Burst: 1 << 20},
+	}))
+	require.Equal(t, 3, d.opts.MaxConcurrentCompactions())
+	require.Equal(t, 8, d.opts.L0CompactionThreshold)
+	require.Equal(t, 100, d.opts.L0CompactionFileThreshold)
+	require.Equal(t, 20, d.opts.L0StopWritesThreshold)
+	require.Equal(t, cacheSize, c.MaxSize())
+	bytesPerSecond, burst := limiter.Rate()
+	require.Equal(t, int64(10<<20), bytesPerSecond)
+	require.Equal(t, int64(1<<20), burst)
+
+	// Unset fields are left unchanged.
+	require.NoError(t, d.SetOptions(DynamicOptions{L0CompactionThreshold: intPtr(6)}))
+	require.Equal(t, 3, d.opts.MaxConcurrentCompactions())
+	require.Equal(t, 6, d.opts.L0CompactionThreshold)
+
+	require.Error(t, d.SetOptions(DynamicOptions{L0StopWritesThreshold: intPtr(0)}))
+	require.Equal(t, 20, d.opts.L0StopWritesThreshold)
+}
+
 func TestFlushEmpty(t *testing.T) {
 	d, err := Open("", testingRandomized(t, &Options{
 		FS: vfs.NewMem(),
@@ -961,6 +1286,48 @@ func TestFlushEmpty(t *testing.T) {
 	require.NoError(t, d.Close())
 }
 
+func TestRotateWAL(t *testing.T) {
+	mem := vfs.NewMem()
+	// Use a large memtable size so that the sealed memtables never exceed the
+	// flush threshold.
+	opts := &Options{FS: mem, MemTableSize: 64 << 20}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+
+	var prevLogNum base.DiskFileNum
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("k%d", i)), nil, Sync))
+		logNum, err := d.RotateWAL()
+		require.NoError(t, err)
+		require.Greater(t, logNum, prevLogNum)
+		prevLogNum = logNum
+		_, err = mem.Stat(fmt.Sprintf("%s.log", logNum))
+		require.NoError(t, err)
+	}
+
+	// Rotating the WAL seals the memtable without forcing a flush.
+	m := d.Metrics()
+	require.Equal(t, int64(4), m.MemTable.Count)
+	require.Zero(t, m.Flush.Count)
+	require.NoError(t, d.Close())
+
+	// The writes are recovered from the sealed WALs.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, closer, err := d.Get([]byte(fmt.Sprintf("k%d", i)))
+		require.NoError(t, err)
+		require.NoError(t, closer.Close())
+	}
+	require.NoError(t, d.Close())
+
+	d, err = Open("", &Options{FS: vfs.NewMem(), DisableWAL: true})
+	require.NoError(t, err)
+	_, err = d.RotateWAL()
+	require.Error(t, err)
+	require.NoError(t, d.Close())
+}
+
 func TestRollManifest(t *testing.T) {
 	toPreserve := rand.Int32N(5) + 1
 	opts := &Options{
@@ -1413,6 +1780,41 @@ func TestSSTables(t *testing.T) {
 	}
 }
 
+func TestSSTablesAccessStats(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("hello"), nil, nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("world"), nil, nil))
+	require.NoError(t, d.Flush())
+
+	// A sample of Gets record an access of the tables they read. The
+	// probability that none of these Gets is sampled is negligible.
+	for i := 0; i < 100*getAccessSamplingPeriod; i++ {
+		_, closer, err := d.Get([]byte("hello"))
+		require.NoError(t, err)
+		require.NoError(t, closer.Close())
+	}
+
+	tableInfos, err := d.SSTables()
+	require.NoError(t, err)
+	var sampledReads uint64
+	for _, levelTables := range tableInfos {
+		for _, info := range levelTables {
+			sampledReads += info.SampledReads
+			require.Equal(t, info.SampledReads == 0, info.LastAccess.IsZero())
+		}
+	}
+	require.Greater(t, sampledReads, uint64(0))
+	var metricsSampledReads uint64
+	for _, l := range d.Metrics().Levels {
+		metricsSampledReads += l.Additional.SampledReads
+	}
+	require.Equal(t, sampledReads, metricsSampledReads)
+}
+
 type testTracer struct {
 	enabledOnlyForNonBackgroundContext bool
 	buf                                strings.Builder
@@ -2491,3 +2893,172 @@ func TestLoadBlockSema(t *testing.T) {
 		})
 	}
 }
+
+func TestMinFreeSpaceForWrites(t *testing.T) {
+	const budget = 4 << 20
+	fs := vfs.WithQuota(vfs.NewMem(), budget)
+	var lowDiskSpace atomic.Int32
+	d, err := Open("", &Options{
+		FS:                    fs,
+		MinFreeSpaceForWrites: budget / 20,
+		EventListener: &EventListener{
+			LowDiskSpace: func(LowDiskSpaceInfo) { lowDiskSpace.Add(1) },
+		},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Write incompressible values until the writes are refused.
+	rng := rand.New(rand.NewPCG(0, 0))
+	value := make([]byte, 64<<10)
+	var n int
+	for ; ; n++ {
+		for i := range value {
+			value[i] = byte(rng.Uint32())
+		}
+		err := d.Set([]byte(fmt.Sprintf("%06d", n)), value, NoSync)
+		if err != nil {
+			require.True(t, errors.Is(err, ErrDiskSpaceLow))
+			break
+		}
+		require.NoError(t, d.Flush())
+		require.Less(t, n, budget/len(value))
+	}
+	require.LessOrEqual(t, uint64(budget-fs.UsedBytes()), d.opts.MinFreeSpaceForWrites)
+	require.Greater(t, lowDiskSpace.Load(), int32(0))
+
+	// Reads and deletions are still allowed.
+	v, closer, err := d.Get([]byte("000000"))
+	require.NoError(t, err)
+	require.Len(t, v, len(value))
+	require.NoError(t, closer.Close())
+	require.NoError(t, d.DeleteRange([]byte("000000"), []byte(fmt.Sprintf("%06d", n)), NoSync))
+
+	// Ingestions are refused like writes.
+	f, err := fs.Create("ext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+		TableFormat: d.TableFormat(),
+	})
+	require.NoError(t, w.Set([]byte("b"), []byte("b")))
+	require.NoError(t, w.Close())
+	require.True(t, errors.Is(d.Ingest(context.Background(), []string{"ext"}), ErrDiskSpaceLow))
+
+	// Once the deleted data is compacted away, writes are accepted again. The
+	// obsolete tables are deleted asynchronously.
+	require.NoError(t, d.Compact([]byte("000000"), []byte("999999"), false))
+	require.Eventually(t, func() bool {
+		return d.Set([]byte("a"), value, NoSync) == nil
+	}, 10*time.Second, time.Millisecond)
+}
+
+func TestCloseWithTimeout(t *testing.T) {
+	unblock := make(chan struct{})
+	var once sync.Once
+	var blockCompactions atomic.Bool
+	opts := &Options{
+		FS:                    vfs.NewMem(),
+		L0CompactionThreshold: 2,
+		EventListener: &EventListener{
+			TableCreated: func(info TableCreateInfo) {
+				// Block the first compaction until the DB is closed.
+				if info.Reason == "compacting" && blockCompactions.Load() {
+					once.Do(func() { <-unblock })
+				}
+			},
+		},
+	}
+	writeTables := func(d *DB) {
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 10; j++ {
+				require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", j)), []byte("v"), nil))
+			}
+			require.NoError(t, d.Flush())
+		}
+	}
+
+	// Without any in-progress compaction, the DB is flushed and closed.
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	res, err := d.CloseWithTimeout(time.Minute)
+	require.NoError(t, err)
+	require.True(t, res.Flushed)
+	require.Empty(t, res.CancelledJobs)
+
+	// An in-progress compaction is cancelled at the deadline.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	blockCompactions.Store(true)
+	writeTables(d)
+	require.Eventually(t, func() bool {
+		return d.Metrics().Compact.NumInProgress > 0
+	}, 10*time.Second, time.Millisecond)
+	go func() {
+		for d.closed.Load() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		close(unblock)
+	}()
+	res, err = d.CloseWithTimeout(10 * time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, res.Flushed)
+	require.Len(t, res.CancelledJobs, 1)
+	require.Equal(t, BackgroundJobCompaction, res.CancelledJobs[0].Kind)
+
+	// The data is intact.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	v, closer, err := d.Get([]byte("k000"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), v)
+	require.NoError(t, closer.Close())
+	require.NoError(t, d.Close())
+}
+
+func TestCloseReport(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem()}
+	opts.Experimental.IteratorLeakThreshold = time.Hour
+	opts.Experimental.IteratorLeakStacks = true
+
+	// No leaks are reported if all resources were released. Background jobs
+	// started by the flush (e.g. loading table stats) may still be pending.
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	require.NoError(t, d.Flush())
+	r, err := d.CloseReport()
+	require.NoError(t, err)
+	require.Zero(t, r.OpenIterators)
+	require.Empty(t, r.Snapshots)
+	require.Empty(t, r.Unflushed)
+
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("b"), []byte("b"), nil))
+	// Leak an iterator and a snapshot.
+	_, err = d.NewIter(nil)
+	require.NoError(t, err)
+	snap := d.NewSnapshot()
+	r, err = d.CloseReport()
+	require.Error(t, err)
+	require.False(t, r.Empty())
+
+	require.Equal(t, 1, r.OpenIterators)
+	require.Len(t, r.Iterators, 1)
+	require.False(t, r.Iterators[0].CreatedAt.IsZero())
+	require.Contains(t, string(r.Iterators[0].Stack), "TestCloseReport")
+
+	require.Len(t, r.Snapshots, 1)
+	require.Equal(t, snap.seqNum, r.Snapshots[0].SeqNum)
+	require.Contains(t, string(r.Snapshots[0].Stack), "TestCloseReport")
+
+	require.Len(t, r.Unflushed, 1)
+	require.False(t, r.Unflushed[0].LargeBatch)
+	require.Greater(t, r.Unflushed[0].Bytes, uint64(0))
+
+	s := r.String()
+	require.Contains(t, s, "open iterators: 1\n")
+	require.Contains(t, s, "open snapshots: 1\n")
+	require.Contains(t, s, "unflushed memtables: 1\n")
+}
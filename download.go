@@ -35,9 +35,15 @@ type DownloadSpan struct {
 	// while copying only the backing file will obligate future reads to continue
 	// to compute such transforms.
 	ViaBackingFileDownload bool
+	// IncludeSharedTables, if true, indicates that sstables on shared storage
+	// (for example, those moved there by remote tiering) should be downloaded
+	// too, in addition to external sstables. Physical shared sstables are
+	// downloaded byte-for-byte if ViaBackingFileDownload is set.
+	IncludeSharedTables bool
 }
 
-// Download ensures that the LSM does not use any external sstables for the
+// Download ensures that the LSM does not use any external sstables (or, with
+// DownloadSpan.IncludeSharedTables, any sstables on shared storage) for the
 // given key ranges. It does so by performing appropriate compactions so that
 // all external data becomes available locally.
 //
@@ -205,6 +211,9 @@ func (d *DB) removeDownloadTasks(tasks []*downloadSpanTask) {
 type downloadSpanTask struct {
 	downloadSpan DownloadSpan
 
+	// needsDownload returns true for tables that must be downloaded.
+	needsDownload func(*tableMetadata) bool
+
 	// The download task pertains to sstables which *start* (as per
 	// Smallest.UserKey) within these bounds.
 	bounds base.UserKeyBounds
@@ -243,14 +252,14 @@ type downloadBookmark struct {
 }
 
 func (d *DB) newDownloadSpanTask(vers *version, sp DownloadSpan) (_ *downloadSpanTask, ok bool) {
+	needsDownload := d.makeDownloadFilter(sp)
 	bounds := base.UserKeyBoundsEndExclusive(sp.StartKey, sp.EndKey)
 	// We are interested in all external sstables that *overlap* with
 	// [sp.StartKey, sp.EndKey). Expand the bounds to the left so that we
 	// include the start keys of any external sstables that overlap with
 	// sp.StartKey.
 	vers.IterAllLevelsAndSublevels(func(iter manifest.LevelIterator, level manifest.Layer) {
-		if f := iter.SeekGE(d.cmp, sp.StartKey); f != nil &&
-			objstorage.IsExternalTable(d.objProvider, f.FileBacking.DiskFileNum) &&
+		if f := iter.SeekGE(d.cmp, sp.StartKey); f != nil && needsDownload(f) &&
 			d.cmp(f.Smallest.UserKey, bounds.Start) < 0 {
 			bounds.Start = f.Smallest.UserKey
 		}
@@ -260,7 +269,7 @@ func (d *DB) newDownloadSpanTask(vers *version, sp DownloadSpan) (_ *downloadSpa
 		key:    bounds.Start,
 		seqNum: 0,
 	}
-	f, level := startCursor.NextExternalFile(d.cmp, d.objProvider, bounds, vers)
+	f, level := startCursor.NextExternalFile(d.cmp, needsDownload, bounds, vers)
 	if f == nil {
 		// No external files in the given span.
 		return nil, false
@@ -268,12 +277,24 @@ func (d *DB) newDownloadSpanTask(vers *version, sp DownloadSpan) (_ *downloadSpa
 
 	return &downloadSpanTask{
 		downloadSpan:      sp,
+		needsDownload:     needsDownload,
 		bounds:            bounds,
 		taskCompletedChan: make(chan error, 1),
 		cursor:            makeCursorAtFile(f, level),
 	}, true
 }
 
+// makeDownloadFilter returns a function that returns true for the tables that
+// must be downloaded for the given span.
+func (d *DB) makeDownloadFilter(sp DownloadSpan) func(*tableMetadata) bool {
+	return func(f *tableMetadata) bool {
+		if f.Virtual && objstorage.IsExternalTable(d.objProvider, f.FileBacking.DiskFileNum) {
+			return true
+		}
+		return sp.IncludeSharedTables && !objstorage.IsLocalTable(d.objProvider, f.FileBacking.DiskFileNum)
+	}
+}
+
 // downloadCursor represents a position in the download process, which does not
 // depend on a specific version.
 //
@@ -341,13 +362,14 @@ func (c downloadCursor) Compare(keyCmp base.Compare, other downloadCursor) int {
 	return cmp.Compare(c.seqNum, other.seqNum)
 }
 
-// NextExternalFile returns the first file after the cursor, returning the file
-// and the level. If no such file exists, returns nil fileMetadata.
+// NextExternalFile returns the first file after the cursor that needs to be
+// downloaded, returning the file and the level. If no such file exists, returns
+// nil fileMetadata.
 func (c downloadCursor) NextExternalFile(
-	cmp base.Compare, objProvider objstorage.Provider, bounds base.UserKeyBounds, v *version,
+	cmp base.Compare, needsDownload func(*tableMetadata) bool, bounds base.UserKeyBounds, v *version,
 ) (_ *tableMetadata, level int) {
 	for !c.AtEnd() {
-		if f := c.NextExternalFileOnLevel(cmp, objProvider, bounds.End, v); f != nil {
+		if f := c.NextExternalFileOnLevel(cmp, needsDownload, bounds.End, v); f != nil {
 			return f, c.level
 		}
 		// Go to the next level.
@@ -358,20 +380,20 @@ func (c downloadCursor) NextExternalFile(
 	return nil, manifest.NumLevels
 }
 
-// NextExternalFileOnLevel returns the first external file on c.level which is
-// after c and with Smallest.UserKey within the end bound.
+// NextExternalFileOnLevel returns the first file that needs to be downloaded on
+// c.level which is after c and with Smallest.UserKey within the end bound.
 func (c downloadCursor) NextExternalFileOnLevel(
-	cmp base.Compare, objProvider objstorage.Provider, endBound base.UserKeyBoundary, v *version,
+	cmp base.Compare, needsDownload func(*tableMetadata) bool, endBound base.UserKeyBoundary, v *version,
 ) *tableMetadata {
 	if c.level > 0 {
 		it := v.Levels[c.level].Iter()
-		return firstExternalFileInLevelIter(cmp, objProvider, c, it, endBound)
+		return firstExternalFileInLevelIter(cmp, needsDownload, c, it, endBound)
 	}
 	// For L0, we look at all sublevel iterators and take the first file.
 	var first *tableMetadata
 	var firstCursor downloadCursor
 	for _, sublevel := range v.L0SublevelFiles {
-		f := firstExternalFileInLevelIter(cmp, objProvider, c, sublevel.Iter(), endBound)
+		f := firstExternalFileInLevelIter(cmp, needsDownload, c, sublevel.Iter(), endBound)
 		if f != nil {
 			c := makeCursorAtFile(f, c.level)
 			if first == nil || c.Compare(cmp, firstCursor) < 0 {
@@ -385,12 +407,12 @@ func (c downloadCursor) NextExternalFileOnLevel(
 	return first
 }
 
-// firstExternalFileInLevelIter finds the first external file after the cursor
-// but which starts before the endBound. It is assumed that the iterator
-// corresponds to cursor.level.
+// firstExternalFileInLevelIter finds the first file that needs to be downloaded
+// after the cursor but which starts before the endBound. It is assumed that the
+// iterator corresponds to cursor.level.
 func firstExternalFileInLevelIter(
 	cmp base.Compare,
-	objProvider objstorage.Provider,
+	needsDownload func(*tableMetadata) bool,
 	cursor downloadCursor,
 	it manifest.LevelIterator,
 	endBound base.UserKeyBoundary,
@@ -402,7 +424,7 @@ func firstExternalFileInLevelIter(
 		f = it.Next()
 	}
 	for ; f != nil && endBound.IsUpperBoundFor(cmp, f.Smallest.UserKey); f = it.Next() {
-		if f.Virtual && objstorage.IsExternalTable(objProvider, f.FileBacking.DiskFileNum) {
+		if needsDownload(f) {
 			return f
 		}
 	}
@@ -427,7 +449,10 @@ func (d *DB) tryLaunchDownloadForFile(
 		return download.testing.launchDownloadCompaction(f)
 	}
 	kind := compactionKindRewrite
-	if download.downloadSpan.ViaBackingFileDownload {
+	// Virtual tables with a shared (non-external) backing can't be copied
+	// byte-for-byte and are rewritten instead.
+	if download.downloadSpan.ViaBackingFileDownload &&
+		(!f.Virtual || objstorage.IsExternalTable(d.objProvider, f.FileBacking.DiskFileNum)) {
 		kind = compactionKindCopy
 	}
 	pc := pickDownloadCompaction(vers, l0Organizer, d.opts, env, d.mu.versions.picker.getBaseLevel(), kind, level, f)
@@ -496,7 +521,7 @@ func (d *DB) tryLaunchDownloadCompaction(
 		// files within the bookmark. This is ok because this method is called (for
 		// this download task) at most once every time a compaction completes.
 
-		f := b.start.NextExternalFileOnLevel(d.cmp, d.objProvider, b.endBound, vers)
+		f := b.start.NextExternalFileOnLevel(d.cmp, download.needsDownload, b.endBound, vers)
 		if f == nil {
 			// No more external files for this bookmark, remove it.
 			download.bookmarks = slices.Delete(download.bookmarks, i, i+1)
@@ -518,7 +543,7 @@ func (d *DB) tryLaunchDownloadCompaction(
 
 	// Try to advance the cursor and launch more downloads.
 	for len(download.bookmarks) < maxConcurrentDownloads {
-		f, level := download.cursor.NextExternalFile(d.cmp, d.objProvider, download.bounds, vers)
+		f, level := download.cursor.NextExternalFile(d.cmp, download.needsDownload, download.bounds, vers)
 		if f == nil {
 			download.cursor = endCursor
 			if len(download.bookmarks) == 0 {
@@ -25,6 +25,9 @@ import (
 func TestDownloadCursor(t *testing.T) {
 	cmp := bytes.Compare
 	objProvider := initDownloadTestProvider(t)
+	needsDownload := func(f *tableMetadata) bool {
+		return f.Virtual && objstorage.IsExternalTable(objProvider, f.FileBacking.DiskFileNum)
+	}
 
 	var vers *manifest.Version
 	var cursor downloadCursor
@@ -60,10 +63,10 @@ func TestDownloadCursor(t *testing.T) {
 					fmt.Fprintf(&buf, "  %s\n", cursor)
 
 				case "next-file":
-					f, level := cursor.NextExternalFile(cmp, objProvider, bounds, vers)
+					f, level := cursor.NextExternalFile(cmp, needsDownload, bounds, vers)
 					if f != nil {
 						// Verify that fCursor still points to this file.
-						f2, level2 := makeCursorAtFile(f, level).NextExternalFile(cmp, objProvider, bounds, vers)
+						f2, level2 := makeCursorAtFile(f, level).NextExternalFile(cmp, needsDownload, bounds, vers)
 						if f != f2 {
 							td.Fatalf(t, "nextExternalFile returned different file")
 						}
@@ -76,7 +79,7 @@ func TestDownloadCursor(t *testing.T) {
 
 				case "iterate":
 					for {
-						f, level := cursor.NextExternalFile(cmp, objProvider, bounds, vers)
+						f, level := cursor.NextExternalFile(cmp, needsDownload, bounds, vers)
 						if f == nil {
 							fmt.Fprintf(&buf, "  no more files\n")
 							break
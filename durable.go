@@ -0,0 +1,146 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// durabilityTracker tracks the sequence numbers that are durable in the WAL,
+// and invokes the callbacks registered through DB.OnDurable.
+//
+// Writes that request a WAL sync are recorded in WAL order, together with the
+// sequence number following the write. The WAL notifies the tracker as sync
+// requests complete (see wal.Options.OnSynced), in the same order, at which
+// point all writes up to and including the synced write are durable.
+type durabilityTracker struct {
+	mu sync.Mutex
+	// durable is the sequence number below which all writes are durable.
+	durable base.SeqNum
+	// syncs contains, for each write that requested a sync which has not
+	// completed yet, the sequence number following the write, in WAL order.
+	syncs []base.SeqNum
+	// waiters contains the registered callbacks, sorted by sequence number.
+	waiters []durabilityWaiter
+	// closed is set once the DB is closed, after which callbacks are only
+	// invoked with ErrClosed.
+	closed bool
+}
+
+type durabilityWaiter struct {
+	seqNum base.SeqNum
+	fn     func(error)
+}
+
+func (t *durabilityTracker) init(durable base.SeqNum) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.durable = durable
+}
+
+// addSync records a write that requests a WAL sync. The write must not have
+// been handed to the WAL yet. nextSeqNum is the sequence number following the
+// write.
+func (t *durabilityTracker) addSync(nextSeqNum base.SeqNum) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.syncs = append(t.syncs, nextSeqNum)
+}
+
+// onSynced implements wal.Options.OnSynced.
+func (t *durabilityTracker) onSynced(numSyncs int) {
+	t.mu.Lock()
+	numSyncs = min(numSyncs, len(t.syncs))
+	if numSyncs == 0 || t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.durable = max(t.durable, t.syncs[numSyncs-1])
+	t.syncs = slices.Delete(t.syncs, 0, numSyncs)
+	i := 0
+	for i < len(t.waiters) && t.waiters[i].seqNum < t.durable {
+		i++
+	}
+	var ready []durabilityWaiter
+	if i > 0 {
+		ready = slices.Clone(t.waiters[:i])
+		t.waiters = slices.Delete(t.waiters, 0, i)
+	}
+	t.mu.Unlock()
+	if len(ready) > 0 {
+		// Don't block the WAL on the callbacks.
+		go invokeDurabilityWaiters(ready, nil)
+	}
+}
+
+// close invokes the callbacks of the writes that aren't durable yet with
+// ErrClosed. It's called once the WAL is closed, so the syncs it completed
+// while closing have invoked their callbacks already.
+func (t *durabilityTracker) close() {
+	t.mu.Lock()
+	waiters := t.waiters
+	t.waiters = nil
+	t.closed = true
+	t.mu.Unlock()
+	if len(waiters) > 0 {
+		go invokeDurabilityWaiters(waiters, ErrClosed)
+	}
+}
+
+func invokeDurabilityWaiters(waiters []durabilityWaiter, err error) {
+	for _, w := range waiters {
+		w.fn(err)
+	}
+}
+
+// OnDurable registers fn to be invoked once the write with the given sequence
+// number, and all earlier writes, are durable in the WAL. A write becomes
+// durable when a WAL sync covering it completes, either because the write
+// itself requested a sync (e.g. through DB.ApplyNoSyncWait) or because a later
+// write did. This allows acknowledging writes committed with
+// DB.ApplyNoSyncWait without waiting in Batch.SyncWait. With WAL failover, fn
+// is invoked once the write is synced to whichever WAL device it was
+// eventually written to.
+//
+// The sequence number of a committed batch is returned by Batch.SeqNum. fn is
+// invoked on a separate goroutine, possibly before OnDurable returns if the
+// write is already durable, with a nil error. If the DB is closed before the
+// write is durable, fn is invoked with ErrClosed instead: the write may or may
+// not be recovered when the DB is reopened. OnDurable returns an error if the
+// WAL is disabled.
+func (d *DB) OnDurable(seqNum base.SeqNum, fn func(error)) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.DisableWAL {
+		return errors.New("pebble: WAL disabled")
+	}
+	t := &d.durability
+	t.mu.Lock()
+	if seqNum < t.durable {
+		t.mu.Unlock()
+		go fn(nil)
+		return nil
+	}
+	if t.closed {
+		t.mu.Unlock()
+		go fn(ErrClosed)
+		return nil
+	}
+	i, _ := slices.BinarySearchFunc(t.waiters, seqNum, func(w durabilityWaiter, seqNum base.SeqNum) int {
+		// Insert after waiters with the same sequence number.
+		if w.seqNum <= seqNum {
+			return -1
+		}
+		return +1
+	})
+	t.waiters = slices.Insert(t.waiters, i, durabilityWaiter{seqNum: seqNum, fn: fn})
+	t.mu.Unlock()
+	return nil
+}
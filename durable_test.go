@@ -0,0 +1,79 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnDurable(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	durable := func(seqNum SeqNum) <-chan struct{} {
+		ch := make(chan struct{})
+		require.NoError(t, d.OnDurable(seqNum, func(err error) {
+			require.NoError(t, err)
+			close(ch)
+		}))
+		return ch
+	}
+	requireNotDurable := func(ch <-chan struct{}) {
+		select {
+		case <-ch:
+			t.Fatal("unexpected durability callback")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// A write that does not request a sync is not durable until a later write
+	// requests one.
+	b1 := d.NewBatch()
+	require.NoError(t, b1.Set([]byte("a"), nil, nil))
+	require.NoError(t, d.Apply(b1, NoSync))
+	ch1 := durable(b1.SeqNum())
+	requireNotDurable(ch1)
+
+	b2 := d.NewBatch()
+	require.NoError(t, b2.Set([]byte("b"), nil, nil))
+	require.NoError(t, d.ApplyNoSyncWait(b2, Sync))
+	ch2 := durable(b2.SeqNum())
+	<-ch1
+	<-ch2
+	require.NoError(t, b2.SyncWait())
+	require.NoError(t, b1.Close())
+	require.NoError(t, b2.Close())
+
+	// Callbacks for writes that are already durable are invoked right away.
+	<-durable(b1.SeqNum())
+}
+
+func TestOnDurableWALDisabled(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem(), DisableWAL: true})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.Error(t, d.OnDurable(1, func(error) {}))
+}
+
+func TestOnDurableClose(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+
+	b := d.NewBatch()
+	require.NoError(t, b.Set([]byte("a"), nil, nil))
+	require.NoError(t, d.Apply(b, NoSync))
+	errCh := make(chan error, 1)
+	require.NoError(t, d.OnDurable(b.SeqNum(), func(err error) { errCh <- err }))
+	require.NoError(t, b.Close())
+
+	// The write never becomes durable, so the callback is invoked on Close.
+	require.NoError(t, d.Close())
+	require.ErrorIs(t, <-errCh, ErrClosed)
+}
@@ -6,8 +6,10 @@ package pebble
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/crlib/crtime"
@@ -120,6 +122,9 @@ type CompactionInfo struct {
 	// failed. Note that err can be ErrCancelledCompaction, which can happen
 	// during normal operation.
 	Err error
+	// Usage describes the resources used by the compaction. It is only set
+	// if Done is true.
+	Usage CompactionUsage
 
 	SingleLevelOverlappingRatio float64
 	MultiLevelOverlappingRatio  float64
@@ -210,6 +215,11 @@ type FlushInfo struct {
 	// Output contains the ouptut table generated by the flush. The output info
 	// is empty for the flush begin event.
 	Output []TableInfo
+	// Smallest and Largest are the bounds of the keys being flushed. They're
+	// only set for the flush begin event, and are zero when flushing ingested
+	// tables.
+	Smallest InternalKey
+	Largest  InternalKey
 	// Duration is the time spent flushing. This duration includes writing and
 	// syncing all of the flushed keys to sstables.
 	Duration time.Duration
@@ -432,7 +442,11 @@ type TableIngestInfo struct {
 	// flushable indicates whether the ingested sstable was treated as a
 	// flushable.
 	flushable bool
-	Err       error
+	// Stats are the stats of the ingest operation, as returned by
+	// DB.IngestWithStats. They describe the placement of each ingested sstable
+	// in more detail than Tables.
+	Stats IngestOperationStats
+	Err   error
 }
 
 func (i TableIngestInfo) String() string {
@@ -556,6 +570,58 @@ func (i WALDeleteInfo) SafeFormat(w redact.SafePrinter, _ rune) {
 	w.Printf("[JOB %d] WAL deleted %s", redact.Safe(i.JobID), i.FileNum)
 }
 
+// WALFailoverInfo contains the info for a WAL failover event, when WAL writes
+// switch to a different directory.
+type WALFailoverInfo struct {
+	// FromDirIndex and ToDirIndex identify the directories WAL writes switched
+	// from and to. Index 0 is the primary WAL directory, 1 is
+	// WALFailoverOptions.Secondary, and i+2 is
+	// WALFailoverOptions.AdditionalSecondaries[i].
+	FromDirIndex, ToDirIndex int
+	FromDir, ToDir           string
+	// Reason is "error" or "high latency" for a failover, "failback" when a
+	// preceding directory is healthy again, or "forced" for a switch requested
+	// through DB.ForceWALFailover.
+	Reason string
+}
+
+func (i WALFailoverInfo) String() string {
+	return redact.StringWithoutMarkers(i)
+}
+
+// SafeFormat implements redact.SafeFormatter.
+func (i WALFailoverInfo) SafeFormat(w redact.SafePrinter, _ rune) {
+	w.Printf("WAL switched from dir %d (%s) to dir %d (%s): %s",
+		redact.Safe(i.FromDirIndex), i.FromDir, redact.Safe(i.ToDirIndex), i.ToDir, redact.Safe(i.Reason))
+}
+
+// RemoteStorageBreakerInfo contains the info for a RemoteStorageBreaker event,
+// when the circuit breaker around the reads from a remote storage trips or
+// recovers. See Options.Experimental.RemoteReadCircuitBreaker.
+type RemoteStorageBreakerInfo struct {
+	Locator remote.Locator
+	// Tripped is set if the breaker tripped, and unset if it recovered.
+	Tripped bool
+	// ConsecutiveFailures is the number of consecutive failed reads that
+	// tripped the breaker, and Err the error of the last one.
+	ConsecutiveFailures int
+	Err                 error
+}
+
+func (i RemoteStorageBreakerInfo) String() string {
+	return redact.StringWithoutMarkers(i)
+}
+
+// SafeFormat implements redact.SafeFormatter.
+func (i RemoteStorageBreakerInfo) SafeFormat(w redact.SafePrinter, _ rune) {
+	if !i.Tripped {
+		w.Printf("remote storage %q available again", redact.Safe(i.Locator))
+		return
+	}
+	w.Printf("remote storage %q unavailable after %d failed reads: %s",
+		redact.Safe(i.Locator), redact.Safe(i.ConsecutiveFailures), i.Err)
+}
+
 // WriteStallBeginInfo contains the info for a write stall begin event.
 type WriteStallBeginInfo struct {
 	Reason string
@@ -767,7 +833,7 @@ type EventListener struct {
 	DownloadEnd func(DownloadInfo)
 
 	// FormatUpgrade is invoked after the database's FormatMajorVersion
-	// is upgraded.
+	// is upgraded, or rolled back (see DB.RollbackFormatMajorVersion).
 	FormatUpgrade func(FormatMajorVersion)
 
 	// ManifestCreated is invoked after a manifest has been created.
@@ -799,6 +865,15 @@ type EventListener struct {
 	// WALDeleted is invoked after a WAL has been deleted.
 	WALDeleted func(WALDeleteInfo)
 
+	// WALFailover is invoked when WAL writes switch to a different directory,
+	// if WAL failover is configured.
+	WALFailover func(WALFailoverInfo)
+
+	// RemoteStorageBreaker is invoked when the circuit breaker around the
+	// reads from a remote storage trips or recovers, if
+	// Options.Experimental.RemoteReadCircuitBreaker is configured.
+	RemoteStorageBreaker func(RemoteStorageBreakerInfo)
+
 	// WriteStallBegin is invoked when writes are intentionally delayed.
 	WriteStallBegin func(WriteStallBeginInfo)
 
@@ -887,6 +962,12 @@ func (l *EventListener) EnsureDefaults(logger Logger) {
 	if l.WALDeleted == nil {
 		l.WALDeleted = func(info WALDeleteInfo) {}
 	}
+	if l.WALFailover == nil {
+		l.WALFailover = func(info WALFailoverInfo) {}
+	}
+	if l.RemoteStorageBreaker == nil {
+		l.RemoteStorageBreaker = func(info RemoteStorageBreakerInfo) {}
+	}
 	if l.WriteStallBegin == nil {
 		l.WriteStallBegin = func(info WriteStallBeginInfo) {}
 	}
@@ -966,6 +1047,12 @@ func MakeLoggingEventListener(logger Logger) EventListener {
 		WALDeleted: func(info WALDeleteInfo) {
 			logger.Infof("%s", info)
 		},
+		WALFailover: func(info WALFailoverInfo) {
+			logger.Infof("%s", info)
+		},
+		RemoteStorageBreaker: func(info RemoteStorageBreakerInfo) {
+			logger.Infof("%s", info)
+		},
 		WriteStallBegin: func(info WriteStallBeginInfo) {
 			logger.Infof("%s", info)
 		},
@@ -1062,6 +1149,14 @@ func TeeEventListener(a, b EventListener) EventListener {
 			a.WALDeleted(info)
 			b.WALDeleted(info)
 		},
+		WALFailover: func(info WALFailoverInfo) {
+			a.WALFailover(info)
+			b.WALFailover(info)
+		},
+		RemoteStorageBreaker: func(info RemoteStorageBreakerInfo) {
+			a.RemoteStorageBreaker(info)
+			b.RemoteStorageBreaker(info)
+		},
 		WriteStallBegin: func(info WriteStallBeginInfo) {
 			a.WriteStallBegin(info)
 			b.WriteStallBegin(info)
@@ -1081,6 +1176,226 @@ func TeeEventListener(a, b EventListener) EventListener {
 	}
 }
 
+// SpanFilteredEventListener wraps an EventListener, forwarding flush,
+// compaction and ingestion events only if they involve tables that overlap at
+// least one of the given key spans. This allows hosts that are only
+// interested in a subset of the keyspace (e.g. a tenant's span) to avoid
+// observing every flush, compaction and ingestion in the DB.
+//
+// The events are filtered as follows:
+//   - CompactionBegin and CompactionEnd are forwarded if any of the
+//     compaction's input or output tables overlap the spans.
+//   - FlushBegin is forwarded if the bounds of the keys being flushed overlap
+//     the spans, or if these bounds are unknown (see FlushInfo.Smallest).
+//     FlushEnd is forwarded if any of the flush's output tables overlap the
+//     spans.
+//   - TableIngested is forwarded if any of the ingested tables overlap the
+//     spans; only the overlapping tables are included in the forwarded event.
+//
+// All other events are forwarded unconditionally.
+func SpanFilteredEventListener(l EventListener, cmp Compare, spans []KeyRange) EventListener {
+	l.EnsureDefaults(nil)
+	overlapsBounds := func(smallest, largest InternalKey) bool {
+		for i := range spans {
+			if spans[i].OverlapsInternalKeyRange(cmp, smallest, largest) {
+				return true
+			}
+		}
+		return false
+	}
+	overlaps := func(t *TableInfo) bool {
+		return overlapsBounds(t.Smallest, t.Largest)
+	}
+	compactionOverlaps := func(info *CompactionInfo) bool {
+		for i := range info.Input {
+			for j := range info.Input[i].Tables {
+				if overlaps(&info.Input[i].Tables[j]) {
+					return true
+				}
+			}
+		}
+		for i := range info.Output.Tables {
+			if overlaps(&info.Output.Tables[i]) {
+				return true
+			}
+		}
+		return false
+	}
+	filtered := l
+	filtered.CompactionBegin = func(info CompactionInfo) {
+		if compactionOverlaps(&info) {
+			l.CompactionBegin(info)
+		}
+	}
+	filtered.CompactionEnd = func(info CompactionInfo) {
+		if compactionOverlaps(&info) {
+			l.CompactionEnd(info)
+		}
+	}
+	filtered.FlushBegin = func(info FlushInfo) {
+		if info.Largest.UserKey == nil || overlapsBounds(info.Smallest, info.Largest) {
+			l.FlushBegin(info)
+		}
+	}
+	filtered.FlushEnd = func(info FlushInfo) {
+		for i := range info.Output {
+			if overlaps(&info.Output[i]) {
+				l.FlushEnd(info)
+				return
+			}
+		}
+	}
+	filtered.TableIngested = func(info TableIngestInfo) {
+		tables := info.Tables[:0:0]
+		for i := range info.Tables {
+			if overlaps(&info.Tables[i].TableInfo) {
+				tables = append(tables, info.Tables[i])
+			}
+		}
+		if len(tables) > 0 {
+			info.Tables = tables
+			l.TableIngested(info)
+		}
+	}
+	return filtered
+}
+
+// dynamicEventListener is the EventListener of an open DB, to which listeners
+// can be added and removed while the DB is open (see DB.AddEventListener).
+type dynamicEventListener struct {
+	// current is the tee of the listener configured in the Options and of the
+	// added listeners. It's replaced whenever a listener is added or removed.
+	current atomic.Pointer[EventListener]
+	mu      struct {
+		sync.Mutex
+		base   EventListener
+		nextID uint64
+		// added holds the added listeners, in the order they were added.
+		added []addedEventListener
+	}
+}
+
+type addedEventListener struct {
+	id uint64
+	l  EventListener
+}
+
+func newDynamicEventListener(base EventListener) *dynamicEventListener {
+	l := &dynamicEventListener{}
+	l.mu.base = base
+	l.current.Store(&base)
+	return l
+}
+
+// add adds the listener, returning a function that removes it.
+func (l *dynamicEventListener) add(el EventListener) (remove func()) {
+	el.EnsureDefaults(nil)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mu.nextID++
+	id := l.mu.nextID
+	l.mu.added = append(l.mu.added, addedEventListener{id: id, l: el})
+	l.updateLocked()
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.mu.added = slices.DeleteFunc(l.mu.added, func(a addedEventListener) bool {
+			return a.id == id
+		})
+		l.updateLocked()
+	}
+}
+
+func (l *dynamicEventListener) updateLocked() {
+	current := l.mu.base
+	for _, a := range l.mu.added {
+		current = TeeEventListener(current, a.l)
+	}
+	l.current.Store(&current)
+}
+
+// eventListener returns an EventListener that forwards every event to the
+// current listeners.
+func (l *dynamicEventListener) eventListener() *EventListener {
+	return &EventListener{
+		BackgroundError: func(err error) {
+			l.current.Load().BackgroundError(err)
+		},
+		DataCorruption: func(info DataCorruptionInfo) {
+			l.current.Load().DataCorruption(info)
+		},
+		CompactionBegin: func(info CompactionInfo) {
+			l.current.Load().CompactionBegin(info)
+		},
+		CompactionEnd: func(info CompactionInfo) {
+			l.current.Load().CompactionEnd(info)
+		},
+		DiskSlow: func(info DiskSlowInfo) {
+			l.current.Load().DiskSlow(info)
+		},
+		FlushBegin: func(info FlushInfo) {
+			l.current.Load().FlushBegin(info)
+		},
+		FlushEnd: func(info FlushInfo) {
+			l.current.Load().FlushEnd(info)
+		},
+		DownloadBegin: func(info DownloadInfo) {
+			l.current.Load().DownloadBegin(info)
+		},
+		DownloadEnd: func(info DownloadInfo) {
+			l.current.Load().DownloadEnd(info)
+		},
+		FormatUpgrade: func(v FormatMajorVersion) {
+			l.current.Load().FormatUpgrade(v)
+		},
+		ManifestCreated: func(info ManifestCreateInfo) {
+			l.current.Load().ManifestCreated(info)
+		},
+		ManifestDeleted: func(info ManifestDeleteInfo) {
+			l.current.Load().ManifestDeleted(info)
+		},
+		TableCreated: func(info TableCreateInfo) {
+			l.current.Load().TableCreated(info)
+		},
+		TableDeleted: func(info TableDeleteInfo) {
+			l.current.Load().TableDeleted(info)
+		},
+		TableIngested: func(info TableIngestInfo) {
+			l.current.Load().TableIngested(info)
+		},
+		TableStatsLoaded: func(info TableStatsInfo) {
+			l.current.Load().TableStatsLoaded(info)
+		},
+		TableValidated: func(info TableValidatedInfo) {
+			l.current.Load().TableValidated(info)
+		},
+		WALCreated: func(info WALCreateInfo) {
+			l.current.Load().WALCreated(info)
+		},
+		WALDeleted: func(info WALDeleteInfo) {
+			l.current.Load().WALDeleted(info)
+		},
+		WALFailover: func(info WALFailoverInfo) {
+			l.current.Load().WALFailover(info)
+		},
+		RemoteStorageBreaker: func(info RemoteStorageBreakerInfo) {
+			l.current.Load().RemoteStorageBreaker(info)
+		},
+		WriteStallBegin: func(info WriteStallBeginInfo) {
+			l.current.Load().WriteStallBegin(info)
+		},
+		WriteStallEnd: func() {
+			l.current.Load().WriteStallEnd()
+		},
+		LowDiskSpace: func(info LowDiskSpaceInfo) {
+			l.current.Load().LowDiskSpace(info)
+		},
+		PossibleAPIMisuse: func(info PossibleAPIMisuseInfo) {
+			l.current.Load().PossibleAPIMisuse(info)
+		},
+	}
+}
+
 // lowDiskSpaceReporter contains the logic to report low disk space events.
 // Report is called whenever we get the disk usage statistics.
 //
@@ -0,0 +1,408 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// MakeJSONEventListener creates an EventListener that writes every event to w
+// as a JSON object on its own line, suitable for shipping to a log pipeline.
+//
+// Every record has a "time" field holding the RFC 3339 time at which the event
+// was emitted and an "event" field holding the name of the event (e.g.
+// "flush_end" or "wal_created"); the remaining fields depend on the event.
+// Durations are in nanoseconds and errors are strings. The field names are
+// stable: fields may be added in future versions, but existing fields won't be
+// renamed or removed. User keys are never included in the records.
+//
+// Writes to w are serialized. Errors returned by w are ignored.
+func MakeJSONEventListener(w io.Writer) EventListener {
+	l := &jsonEventLogger{w: w}
+	return EventListener{
+		BackgroundError: func(err error) {
+			emitJSONEvent(l, "background_error", jsonErrorEvent{Error: jsonError(err)})
+		},
+		DataCorruption: func(info DataCorruptionInfo) {
+			emitJSONEvent(l, "data_corruption", jsonDataCorruptionEvent{
+				Path:     info.Path,
+				IsRemote: info.IsRemote,
+				Locator:  string(info.Locator),
+				Error:    jsonError(info.Details),
+			})
+		},
+		CompactionBegin: func(info CompactionInfo) {
+			emitJSONEvent(l, "compaction_begin", makeJSONCompactionEvent(info))
+		},
+		CompactionEnd: func(info CompactionInfo) {
+			emitJSONEvent(l, "compaction_end", makeJSONCompactionEvent(info))
+		},
+		DiskSlow: func(info DiskSlowInfo) {
+			emitJSONEvent(l, "disk_slow", jsonDiskSlowEvent{
+				Path:      info.Path,
+				Op:        info.OpType.String(),
+				WriteSize: info.WriteSize,
+				Duration:  info.Duration,
+			})
+		},
+		FlushBegin: func(info FlushInfo) {
+			emitJSONEvent(l, "flush_begin", makeJSONFlushEvent(info))
+		},
+		FlushEnd: func(info FlushInfo) {
+			emitJSONEvent(l, "flush_end", makeJSONFlushEvent(info))
+		},
+		DownloadBegin: func(info DownloadInfo) {
+			emitJSONEvent(l, "download_begin", makeJSONDownloadEvent(info))
+		},
+		DownloadEnd: func(info DownloadInfo) {
+			emitJSONEvent(l, "download_end", makeJSONDownloadEvent(info))
+		},
+		FormatUpgrade: func(v FormatMajorVersion) {
+			emitJSONEvent(l, "format_upgrade", jsonFormatUpgradeEvent{FormatMajorVersion: uint64(v)})
+		},
+		ManifestCreated: func(info ManifestCreateInfo) {
+			emitJSONEvent(l, "manifest_created", jsonFileEvent{
+				JobID: info.JobID, Path: info.Path, FileNum: uint64(info.FileNum), Error: jsonError(info.Err),
+			})
+		},
+		ManifestDeleted: func(info ManifestDeleteInfo) {
+			emitJSONEvent(l, "manifest_deleted", jsonFileEvent{
+				JobID: info.JobID, Path: info.Path, FileNum: uint64(info.FileNum), Error: jsonError(info.Err),
+			})
+		},
+		TableCreated: func(info TableCreateInfo) {
+			emitJSONEvent(l, "table_created", jsonFileEvent{
+				JobID: info.JobID, Reason: info.Reason, Path: info.Path, FileNum: uint64(info.FileNum),
+			})
+		},
+		TableDeleted: func(info TableDeleteInfo) {
+			emitJSONEvent(l, "table_deleted", jsonFileEvent{
+				JobID: info.JobID, Path: info.Path, FileNum: uint64(info.FileNum), Error: jsonError(info.Err),
+			})
+		},
+		TableIngested: func(info TableIngestInfo) {
+			e := jsonIngestEvent{
+				JobID:        info.JobID,
+				GlobalSeqNum: uint64(info.GlobalSeqNum),
+				AsFlushable:  info.flushable,
+				Error:        jsonError(info.Err),
+			}
+			for i := range info.Tables {
+				t := makeJSONTable(&info.Tables[i].TableInfo)
+				level := info.Tables[i].Level
+				t.Level = &level
+				e.Tables = append(e.Tables, t)
+			}
+			emitJSONEvent(l, "table_ingested", e)
+		},
+		TableStatsLoaded: func(info TableStatsInfo) {
+			emitJSONEvent(l, "table_stats_loaded", jsonFileEvent{JobID: info.JobID})
+		},
+		TableValidated: func(info TableValidatedInfo) {
+			e := jsonFileEvent{JobID: info.JobID}
+			if info.Meta != nil {
+				e.FileNum = uint64(info.Meta.FileNum)
+			}
+			emitJSONEvent(l, "table_validated", e)
+		},
+		WALCreated: func(info WALCreateInfo) {
+			emitJSONEvent(l, "wal_created", jsonFileEvent{
+				JobID:           info.JobID,
+				Path:            info.Path,
+				FileNum:         uint64(info.FileNum),
+				RecycledFileNum: uint64(info.RecycledFileNum),
+				Error:           jsonError(info.Err),
+			})
+		},
+		WALDeleted: func(info WALDeleteInfo) {
+			emitJSONEvent(l, "wal_deleted", jsonFileEvent{
+				JobID: info.JobID, Path: info.Path, FileNum: uint64(info.FileNum), Error: jsonError(info.Err),
+			})
+		},
+		WALFailover: func(info WALFailoverInfo) {
+			emitJSONEvent(l, "wal_failover", jsonWALFailoverEvent{
+				FromDirIndex: info.FromDirIndex,
+				ToDirIndex:   info.ToDirIndex,
+				FromDir:      info.FromDir,
+				ToDir:        info.ToDir,
+				Reason:       info.Reason,
+			})
+		},
+		RemoteStorageBreaker: func(info RemoteStorageBreakerInfo) {
+			emitJSONEvent(l, "remote_storage_breaker", jsonRemoteStorageBreakerEvent{
+				Locator:             string(info.Locator),
+				Tripped:             info.Tripped,
+				ConsecutiveFailures: info.ConsecutiveFailures,
+				Error:               jsonError(info.Err),
+			})
+		},
+		WriteStallBegin: func(info WriteStallBeginInfo) {
+			emitJSONEvent(l, "write_stall_begin", jsonWriteStallEvent{Reason: info.Reason})
+		},
+		WriteStallEnd: func() {
+			emitJSONEvent(l, "write_stall_end", jsonWriteStallEvent{})
+		},
+		LowDiskSpace: func(info LowDiskSpaceInfo) {
+			emitJSONEvent(l, "low_disk_space", jsonLowDiskSpaceEvent{
+				AvailBytes:       info.AvailBytes,
+				TotalBytes:       info.TotalBytes,
+				PercentThreshold: info.PercentThreshold,
+			})
+		},
+		PossibleAPIMisuse: func(info PossibleAPIMisuseInfo) {
+			emitJSONEvent(l, "possible_api_misuse", jsonAPIMisuseEvent{Kind: info.Kind.String()})
+		},
+	}
+}
+
+// jsonEventLogger serializes the records written by MakeJSONEventListener.
+type jsonEventLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+// emitJSONEvent writes a record with the given event name and the fields of e,
+// which must marshal to a JSON object.
+func emitJSONEvent[T any](l *jsonEventLogger, event string, e T) {
+	fields, err := json.Marshal(e)
+	if err != nil || len(fields) < 2 {
+		return
+	}
+	header, _ := json.Marshal(struct {
+		Time  string `json:"time"`
+		Event string `json:"event"`
+	}{
+		Time:  time.Now().UTC().Format(time.RFC3339Nano),
+		Event: event,
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf.Reset()
+	// Splice the fields of e into the header object.
+	l.buf.Write(header[:len(header)-1])
+	if len(fields) > 2 {
+		l.buf.WriteByte(',')
+		l.buf.Write(fields[1:])
+	} else {
+		l.buf.WriteByte('}')
+	}
+	l.buf.WriteByte('\n')
+	_, _ = l.w.Write(l.buf.Bytes())
+}
+
+func jsonError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+type jsonTable struct {
+	FileNum        uint64 `json:"file_num"`
+	Size           uint64 `json:"size"`
+	SmallestSeqNum uint64 `json:"smallest_seq_num"`
+	LargestSeqNum  uint64 `json:"largest_seq_num"`
+	// Level is only set for ingested tables.
+	Level *int `json:"level,omitempty"`
+}
+
+func makeJSONTable(t *TableInfo) jsonTable {
+	return jsonTable{
+		FileNum:        uint64(t.FileNum),
+		Size:           t.Size,
+		SmallestSeqNum: uint64(t.SmallestSeqNum),
+		LargestSeqNum:  uint64(t.LargestSeqNum),
+	}
+}
+
+func makeJSONTables(tables []TableInfo) []jsonTable {
+	res := make([]jsonTable, len(tables))
+	for i := range tables {
+		res[i] = makeJSONTable(&tables[i])
+	}
+	return res
+}
+
+type jsonLevel struct {
+	Level  int         `json:"level"`
+	Tables []jsonTable `json:"tables"`
+	Bytes  uint64      `json:"bytes"`
+	Score  float64     `json:"score"`
+}
+
+func makeJSONLevel(info LevelInfo) jsonLevel {
+	return jsonLevel{
+		Level:  info.Level,
+		Tables: makeJSONTables(info.Tables),
+		Bytes:  tablesTotalSize(info.Tables),
+		Score:  info.Score,
+	}
+}
+
+type jsonCompactionEvent struct {
+	JobID                       int           `json:"job_id"`
+	Reason                      string        `json:"reason"`
+	Input                       []jsonLevel   `json:"input"`
+	Output                      *jsonLevel    `json:"output,omitempty"`
+	Duration                    time.Duration `json:"duration_ns"`
+	TotalDuration               time.Duration `json:"total_duration_ns"`
+	SingleLevelOverlappingRatio float64       `json:"single_level_overlapping_ratio"`
+	MultiLevelOverlappingRatio  float64       `json:"multi_level_overlapping_ratio"`
+	Annotations                 []string      `json:"annotations,omitempty"`
+	Error                       string        `json:"error,omitempty"`
+}
+
+func makeJSONCompactionEvent(info CompactionInfo) jsonCompactionEvent {
+	e := jsonCompactionEvent{
+		JobID:                       info.JobID,
+		Reason:                      info.Reason,
+		Input:                       make([]jsonLevel, len(info.Input)),
+		Duration:                    info.Duration,
+		TotalDuration:               info.TotalDuration,
+		SingleLevelOverlappingRatio: info.SingleLevelOverlappingRatio,
+		MultiLevelOverlappingRatio:  info.MultiLevelOverlappingRatio,
+		Annotations:                 info.Annotations,
+		Error:                       jsonError(info.Err),
+	}
+	for i := range info.Input {
+		e.Input[i] = makeJSONLevel(info.Input[i])
+	}
+	if info.Done {
+		output := makeJSONLevel(info.Output)
+		e.Output = &output
+	}
+	return e
+}
+
+type jsonFlushEvent struct {
+	JobID         int           `json:"job_id"`
+	Reason        string        `json:"reason"`
+	Input         int           `json:"input"`
+	InputBytes    uint64        `json:"input_bytes"`
+	Output        []jsonTable   `json:"output,omitempty"`
+	OutputBytes   uint64        `json:"output_bytes"`
+	Duration      time.Duration `json:"duration_ns"`
+	TotalDuration time.Duration `json:"total_duration_ns"`
+	Ingest        bool          `json:"ingest"`
+	Error         string        `json:"error,omitempty"`
+}
+
+func makeJSONFlushEvent(info FlushInfo) jsonFlushEvent {
+	e := jsonFlushEvent{
+		JobID:         info.JobID,
+		Reason:        info.Reason,
+		Input:         info.Input,
+		InputBytes:    info.InputBytes,
+		Output:        makeJSONTables(info.Output),
+		OutputBytes:   tablesTotalSize(info.Output),
+		Duration:      info.Duration,
+		TotalDuration: info.TotalDuration,
+		Ingest:        info.Ingest,
+		Error:         jsonError(info.Err),
+	}
+	for i := range info.IngestLevels {
+		if i < len(e.Output) {
+			level := info.IngestLevels[i]
+			e.Output[i].Level = &level
+		}
+	}
+	return e
+}
+
+type jsonIngestEvent struct {
+	JobID        int         `json:"job_id"`
+	Tables       []jsonTable `json:"tables"`
+	GlobalSeqNum uint64      `json:"global_seq_num"`
+	AsFlushable  bool        `json:"as_flushable"`
+	Error        string      `json:"error,omitempty"`
+}
+
+type jsonDownloadEvent struct {
+	JobID                       int           `json:"job_id"`
+	Spans                       int           `json:"spans"`
+	Duration                    time.Duration `json:"duration_ns"`
+	DownloadCompactionsLaunched int           `json:"download_compactions_launched"`
+	RestartCount                int           `json:"restart_count"`
+	Error                       string        `json:"error,omitempty"`
+}
+
+func makeJSONDownloadEvent(info DownloadInfo) jsonDownloadEvent {
+	return jsonDownloadEvent{
+		JobID:                       info.JobID,
+		Spans:                       len(info.Spans),
+		Duration:                    info.Duration,
+		DownloadCompactionsLaunched: info.DownloadCompactionsLaunched,
+		RestartCount:                info.RestartCount,
+		Error:                       jsonError(info.Err),
+	}
+}
+
+// jsonFileEvent is used for the events about the creation and deletion of
+// files.
+type jsonFileEvent struct {
+	JobID           int    `json:"job_id"`
+	Reason          string `json:"reason,omitempty"`
+	Path            string `json:"path,omitempty"`
+	FileNum         uint64 `json:"file_num,omitempty"`
+	RecycledFileNum uint64 `json:"recycled_file_num,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+type jsonErrorEvent struct {
+	Error string `json:"error"`
+}
+
+type jsonDataCorruptionEvent struct {
+	Path     string `json:"path"`
+	IsRemote bool   `json:"is_remote"`
+	Locator  string `json:"locator,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type jsonDiskSlowEvent struct {
+	Path      string        `json:"path"`
+	Op        string        `json:"op"`
+	WriteSize int           `json:"write_size"`
+	Duration  time.Duration `json:"duration_ns"`
+}
+
+type jsonFormatUpgradeEvent struct {
+	FormatMajorVersion uint64 `json:"format_major_version"`
+}
+
+type jsonWALFailoverEvent struct {
+	FromDirIndex int    `json:"from_dir_index"`
+	ToDirIndex   int    `json:"to_dir_index"`
+	FromDir      string `json:"from_dir"`
+	ToDir        string `json:"to_dir"`
+	Reason       string `json:"reason"`
+}
+
+type jsonRemoteStorageBreakerEvent struct {
+	Locator             string `json:"locator"`
+	Tripped             bool   `json:"tripped"`
+	ConsecutiveFailures int    `json:"consecutive_failures,omitempty"`
+	Error               string `json:"error,omitempty"`
+}
+
+type jsonWriteStallEvent struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type jsonLowDiskSpaceEvent struct {
+	AvailBytes       uint64 `json:"avail_bytes"`
+	TotalBytes       uint64 `json:"total_bytes"`
+	PercentThreshold int    `json:"percent_threshold"`
+}
+
+type jsonAPIMisuseEvent struct {
+	Kind string `json:"kind"`
+}
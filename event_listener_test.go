@@ -7,6 +7,7 @@ package pebble
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
@@ -418,6 +419,170 @@ func TestTeeEventListenerSetsAllCallbacks(t *testing.T) {
 	testAllCallbacksSetInEventListener(t, e)
 }
 
+func TestMakeJSONEventListener(t *testing.T) {
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	w := writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	})
+	l := MakeJSONEventListener(w)
+	testAllCallbacksSetInEventListener(t, l)
+
+	d, err := Open("", &Options{FS: vfs.NewMem(), EventListener: &l})
+	require.NoError(t, err)
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("b"), []byte("b"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact([]byte("a"), []byte("c"), false /* parallelize */))
+	require.NoError(t, d.Close())
+	l.BackgroundError(errors.New("injected error"))
+	l.TableIngested(TableIngestInfo{
+		JobID: 10,
+		Tables: []struct {
+			TableInfo
+			Level int
+		}{{TableInfo: TableInfo{FileNum: 7, Size: 100}, Level: 6}},
+		GlobalSeqNum: 20,
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	events := make(map[string][]map[string]any)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec), line)
+		require.NotEmpty(t, rec["time"], line)
+		event := rec["event"].(string)
+		events[event] = append(events[event], rec)
+	}
+	for _, event := range []string{
+		"wal_created", "manifest_created", "flush_begin", "flush_end", "table_created",
+		"compaction_begin", "compaction_end", "table_deleted",
+	} {
+		require.NotEmpty(t, events[event], event)
+	}
+	flush := events["flush_end"][0]
+	require.Equal(t, float64(1), flush["input"])
+	require.Len(t, flush["output"], 1)
+	require.Contains(t, flush, "duration_ns")
+	compaction := events["compaction_end"][0]
+	require.Equal(t, "default", compaction["reason"])
+	require.Len(t, compaction["input"], 2)
+	require.Equal(t, float64(6), compaction["output"].(map[string]any)["level"])
+	require.Equal(t, []map[string]any{{
+		"time":  events["background_error"][0]["time"],
+		"event": "background_error",
+		"error": "injected error",
+	}}, events["background_error"])
+	require.Equal(t, map[string]any{
+		"time":           events["table_ingested"][0]["time"],
+		"event":          "table_ingested",
+		"job_id":         float64(10),
+		"global_seq_num": float64(20),
+		"as_flushable":   false,
+		"tables": []any{map[string]any{
+			"file_num":         float64(7),
+			"size":             float64(100),
+			"smallest_seq_num": float64(0),
+			"largest_seq_num":  float64(0),
+			"level":            float64(6),
+		}},
+	}, events["table_ingested"][0])
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestSpanFilteredEventListener(t *testing.T) {
+	var buf bytes.Buffer
+	l := SpanFilteredEventListener(EventListener{
+		CompactionEnd: func(info CompactionInfo) { fmt.Fprintf(&buf, "compaction %d\n", info.JobID) },
+		FlushBegin:    func(info FlushInfo) { fmt.Fprintf(&buf, "flush begin %d\n", info.JobID) },
+		FlushEnd:      func(info FlushInfo) { fmt.Fprintf(&buf, "flush %d\n", info.JobID) },
+		TableIngested: func(info TableIngestInfo) {
+			fmt.Fprintf(&buf, "ingest %d:", info.JobID)
+			for _, tbl := range info.Tables {
+				fmt.Fprintf(&buf, " %s", tbl.FileNum)
+			}
+			fmt.Fprintln(&buf)
+		},
+	}, DefaultComparer.Compare, []KeyRange{{Start: []byte("c"), End: []byte("e")}})
+	testAllCallbacksSetInEventListener(t, l)
+
+	table := func(fileNum FileNum, smallest, largest string) TableInfo {
+		return TableInfo{
+			FileNum:  fileNum,
+			Smallest: base.MakeInternalKey([]byte(smallest), 1, InternalKeyKindSet),
+			Largest:  base.MakeInternalKey([]byte(largest), 1, InternalKeyKindSet),
+		}
+	}
+	tb := table(0, "a", "b")
+	l.FlushBegin(FlushInfo{JobID: 1, Smallest: tb.Smallest, Largest: tb.Largest})
+	tb = table(0, "a", "c")
+	l.FlushBegin(FlushInfo{JobID: 2, Smallest: tb.Smallest, Largest: tb.Largest})
+	// The bounds of ingested tables being flushed are unknown.
+	l.FlushBegin(FlushInfo{JobID: 6, Ingest: true})
+	l.FlushEnd(FlushInfo{JobID: 1, Output: []TableInfo{table(1, "a", "b")}})
+	l.FlushEnd(FlushInfo{JobID: 2, Output: []TableInfo{table(2, "a", "c")}})
+	l.CompactionEnd(CompactionInfo{JobID: 3, Input: []LevelInfo{{Tables: []TableInfo{table(3, "e", "f")}}}})
+	l.CompactionEnd(CompactionInfo{JobID: 4, Output: LevelInfo{Tables: []TableInfo{table(4, "d", "d")}}})
+	info := TableIngestInfo{JobID: 5}
+	for _, tbl := range []TableInfo{table(5, "a", "b"), table(6, "b", "d"), table(7, "e", "z")} {
+		info.Tables = append(info.Tables, struct {
+			TableInfo
+			Level int
+		}{TableInfo: tbl})
+	}
+	l.TableIngested(info)
+	require.Equal(t, "flush begin 2\nflush begin 6\nflush 2\ncompaction 4\ningest 5: 000006\n", buf.String())
+}
+
+func TestDBAddEventListener(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &Options{
+		FS: vfs.NewMem(),
+		EventListener: &EventListener{
+			FlushEnd: func(info FlushInfo) { fmt.Fprintf(&buf, "base: flush %d\n", info.JobID) },
+		},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	testAllCallbacksSetInEventListener(t, *d.opts.EventListener)
+
+	flush := func() {
+		require.NoError(t, d.Set([]byte("a"), nil, nil))
+		require.NoError(t, d.Flush())
+	}
+	flush()
+	add := func(name string) func() {
+		return d.AddEventListener(EventListener{
+			FlushEnd: func(info FlushInfo) { fmt.Fprintf(&buf, "%s: flush %d\n", name, info.JobID) },
+		})
+	}
+	removeA := add("a")
+	removeB := add("b")
+	buf.Reset()
+	flush()
+	require.Regexp(t, "^base: flush [0-9]+\na: flush [0-9]+\nb: flush [0-9]+\n$", buf.String())
+
+	// Removing a listener doesn't affect the others, and can be repeated.
+	removeA()
+	removeA()
+	buf.Reset()
+	flush()
+	require.Regexp(t, "^base: flush [0-9]+\nb: flush [0-9]+\n$", buf.String())
+
+	removeB()
+	buf.Reset()
+	flush()
+	require.Regexp(t, "^base: flush [0-9]+\n$", buf.String())
+}
+
 func testAllCallbacksSetInEventListener(t *testing.T, e EventListener) {
 	t.Helper()
 	v := reflect.ValueOf(e)
@@ -20,6 +20,13 @@ import (
 // open iterator pins all memtables and sstables in its view of the LSM until
 // it's closed). Excise may initiate a flush if there exists unflushed data
 // overlapping the excise span.
+//
+// Excise only edits the metadata of the tables, so it's a constant-time
+// alternative to DeleteRange for dropping a whole range: the tables within the
+// span are dropped, and the tables straddling its bounds are replaced by
+// virtual tables that exclude it. The virtual tables are marked for
+// compaction, so that low-priority rewrite compactions lazily reclaim the
+// space of the excised data they still hold.
 func (d *DB) Excise(ctx context.Context, span KeyRange) error {
 	if err := d.closed.Load(); err != nil {
 		panic(err)
@@ -40,10 +47,19 @@ func (d *DB) Excise(ctx context.Context, span KeyRange) error {
 			v, FormatVirtualSSTables,
 		)
 	}
-	_, err := d.ingest(ctx, nil, nil, span, nil)
+	_, err := d.ingest(ctx, nil, nil, nil, span, nil, false /* allowSeqNums */, IngestOptions{})
 	return err
 }
 
+// markExciseRemnants marks the virtual tables left by an excise without an
+// accompanying ingestion for compaction. Their backing tables are otherwise
+// kept in full until the virtual tables happen to be compacted.
+func markExciseRemnants(newFiles []manifest.NewTableEntry) {
+	for _, nf := range newFiles {
+		nf.Meta.MarkedForCompaction = true
+	}
+}
+
 // excise updates ve to include a replacement of the file m with new virtual
 // sstables that exclude exciseSpan, returning a slice of newly-created files if
 // any. If the entirety of m is deleted by exciseSpan, no new sstables are added
@@ -70,21 +86,14 @@ func (d *DB) excise(
 		return nil, nil
 	}
 
-	var iters iterSet
-	var itersLoaded bool
-	defer iters.CloseAll()
-	loadItersIfNecessary := func() error {
-		if itersLoaded {
-			return nil
-		}
-		var err error
-		iters, err = d.newIters(ctx, m, &IterOptions{
-			Category: categoryIngest,
-			layer:    manifest.Level(level),
-		}, internalIterOpts{}, iterPointKeys|iterRangeDeletions|iterRangeKeys)
-		itersLoaded = true
-		return err
+	iters, err := d.newIters(ctx, m, &IterOptions{
+		Category: categoryIngest,
+		layer:    manifest.Level(level),
+	}, internalIterOpts{}, iterPointKeys|iterRangeDeletions|iterRangeKeys)
+	if err != nil {
+		return nil, err
 	}
+	defer iters.CloseAll()
 
 	needsBacking := false
 	// Create a file to the left of the excise span, if necessary.
@@ -120,53 +129,11 @@ func (d *DB) excise(
 			LargestSeqNum:            m.LargestSeqNum,
 			LargestSeqNumAbsolute:    m.LargestSeqNumAbsolute,
 			SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+			ContentPrefix:            m.ContentPrefix,
+			SuffixReplacements:       m.SuffixReplacements,
 		}
-		if m.HasPointKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.SmallestPointKey) {
-			// This file will probably contain point keys.
-			if err := loadItersIfNecessary(); err != nil {
-				return nil, err
-			}
-			smallestPointKey := m.SmallestPointKey
-			if kv := iters.Point().SeekLT(exciseSpan.Start, base.SeekLTFlagsNone); kv != nil {
-				leftFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, kv.K.Clone())
-			}
-			// Store the min of (exciseSpan.Start, rdel.End) in lastRangeDel. This
-			// needs to be a copy if the key is owned by the range del iter.
-			var lastRangeDel []byte
-			if rdel, err := iters.RangeDeletion().SeekLT(exciseSpan.Start); err != nil {
-				return nil, err
-			} else if rdel != nil {
-				lastRangeDel = append(lastRangeDel[:0], rdel.End...)
-				if d.cmp(lastRangeDel, exciseSpan.Start) > 0 {
-					lastRangeDel = exciseSpan.Start
-				}
-			}
-			if lastRangeDel != nil {
-				leftFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, base.MakeExclusiveSentinelKey(InternalKeyKindRangeDelete, lastRangeDel))
-			}
-		}
-		if m.HasRangeKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.SmallestRangeKey) {
-			// This file will probably contain range keys.
-			if err := loadItersIfNecessary(); err != nil {
-				return nil, err
-			}
-			smallestRangeKey := m.SmallestRangeKey
-			// Store the min of (exciseSpan.Start, rkey.End) in lastRangeKey. This
-			// needs to be a copy if the key is owned by the range key iter.
-			var lastRangeKey []byte
-			var lastRangeKeyKind InternalKeyKind
-			if rkey, err := iters.RangeKey().SeekLT(exciseSpan.Start); err != nil {
-				return nil, err
-			} else if rkey != nil {
-				lastRangeKey = append(lastRangeKey[:0], rkey.End...)
-				if d.cmp(lastRangeKey, exciseSpan.Start) > 0 {
-					lastRangeKey = exciseSpan.Start
-				}
-				lastRangeKeyKind = rkey.Keys[0].Kind()
-			}
-			if lastRangeKey != nil {
-				leftFile.ExtendRangeKeyBounds(d.cmp, smallestRangeKey, base.MakeExclusiveSentinelKey(lastRangeKeyKind, lastRangeKey))
-			}
+		if err := determineLeftTableBounds(d.cmp, m, leftFile, exciseSpan.Start, iters); err != nil {
+			return nil, err
 		}
 		if leftFile.HasRangeKeys || leftFile.HasPointKeys {
 			var err error
@@ -216,60 +183,153 @@ func (d *DB) excise(
 		LargestSeqNum:            m.LargestSeqNum,
 		LargestSeqNumAbsolute:    m.LargestSeqNumAbsolute,
 		SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+		ContentPrefix:            m.ContentPrefix,
+		SuffixReplacements:       m.SuffixReplacements,
 	}
-	if m.HasPointKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.LargestPointKey) {
-		// This file will probably contain point keys
-		if err := loadItersIfNecessary(); err != nil {
+	if err := determineRightTableBounds(d.cmp, m, rightFile, exciseSpan.End, iters); err != nil {
+		return nil, err
+	}
+	if rightFile.HasRangeKeys || rightFile.HasPointKeys {
+		var err error
+		rightFile.Size, err = d.fileCache.estimateSize(m, rightFile.Smallest.UserKey, rightFile.Largest.UserKey)
+		if err != nil {
+			return nil, err
+		}
+		if rightFile.Size == 0 {
+			// On occasion, estimateSize gives us a low estimate, i.e. a 0 file size,
+			// such as if the excised file only has range keys/dels and no point keys.
+			// This can cause panics in places where we divide by file sizes. Correct
+			// for it here.
+			rightFile.Size = 1
+		}
+		if err := rightFile.Validate(d.cmp, d.opts.Comparer.FormatKey); err != nil {
 			return nil, err
 		}
+		rightFile.ValidateVirtual(m)
+		ve.NewTables = append(ve.NewTables, newTableEntry{Level: level, Meta: rightFile})
+		needsBacking = true
+		numCreatedFiles++
+	}
+
+	if needsBacking && !m.Virtual {
+		// If m is virtual, then its file backing is already known to the manifest.
+		// We don't need to create another file backing. Note that there must be
+		// only one CreatedBackingTables entry per backing sstable. This is
+		// indicated by the VersionEdit.CreatedBackingTables invariant.
+		ve.CreatedBackingTables = append(ve.CreatedBackingTables, m.FileBacking)
+	}
+
+	return ve.NewTables[len(ve.NewTables)-numCreatedFiles:], nil
+}
+
+// determineLeftTableBounds extends the bounds of leftTable to tightly cover the
+// keys of m that are smaller than exciseSpanStart. iters must be iterators over
+// m.
+func determineLeftTableBounds(
+	cmp Compare, m, leftTable *tableMetadata, exciseSpanStart []byte, iters iterSet,
+) error {
+	if m.HasPointKeys && cmp(m.SmallestPointKey.UserKey, exciseSpanStart) < 0 {
+		// This file will probably contain point keys.
+		smallestPointKey := m.SmallestPointKey
+		if kv := iters.Point().SeekLT(exciseSpanStart, base.SeekLTFlagsNone); kv != nil {
+			leftTable.ExtendPointKeyBounds(cmp, smallestPointKey, kv.K.Clone())
+		}
+		if err := iters.Point().Error(); err != nil {
+			return err
+		}
+		// Store the min of (exciseSpanStart, rdel.End) in lastRangeDel. This
+		// needs to be a copy if the key is owned by the range del iter.
+		var lastRangeDel []byte
+		if rdel, err := iters.RangeDeletion().SeekLT(exciseSpanStart); err != nil {
+			return err
+		} else if rdel != nil {
+			lastRangeDel = append(lastRangeDel[:0], rdel.End...)
+			if cmp(lastRangeDel, exciseSpanStart) > 0 {
+				lastRangeDel = exciseSpanStart
+			}
+		}
+		if lastRangeDel != nil {
+			leftTable.ExtendPointKeyBounds(cmp, smallestPointKey, base.MakeExclusiveSentinelKey(InternalKeyKindRangeDelete, lastRangeDel))
+		}
+	}
+	if m.HasRangeKeys && cmp(m.SmallestRangeKey.UserKey, exciseSpanStart) < 0 {
+		// This file will probably contain range keys.
+		smallestRangeKey := m.SmallestRangeKey
+		// Store the min of (exciseSpanStart, rkey.End) in lastRangeKey. This
+		// needs to be a copy if the key is owned by the range key iter.
+		var lastRangeKey []byte
+		var lastRangeKeyKind InternalKeyKind
+		if rkey, err := iters.RangeKey().SeekLT(exciseSpanStart); err != nil {
+			return err
+		} else if rkey != nil {
+			lastRangeKey = append(lastRangeKey[:0], rkey.End...)
+			if cmp(lastRangeKey, exciseSpanStart) > 0 {
+				lastRangeKey = exciseSpanStart
+			}
+			lastRangeKeyKind = rkey.Keys[0].Kind()
+		}
+		if lastRangeKey != nil {
+			leftTable.ExtendRangeKeyBounds(cmp, smallestRangeKey, base.MakeExclusiveSentinelKey(lastRangeKeyKind, lastRangeKey))
+		}
+	}
+	return nil
+}
+
+// determineRightTableBounds extends the bounds of rightTable to tightly cover
+// the keys of m that are after exciseSpanEnd. iters must be iterators over m.
+func determineRightTableBounds(
+	cmp Compare, m, rightTable *tableMetadata, exciseSpanEnd base.UserKeyBoundary, iters iterSet,
+) error {
+	if m.HasPointKeys && !exciseSpanEnd.IsUpperBoundForInternalKey(cmp, m.LargestPointKey) {
+		// This file will probably contain point keys
 		largestPointKey := m.LargestPointKey
-		if kv := iters.Point().SeekGE(exciseSpan.End.Key, base.SeekGEFlagsNone); kv != nil {
-			if exciseSpan.End.Kind == base.Inclusive && d.equal(exciseSpan.End.Key, kv.K.UserKey) {
-				return nil, base.AssertionFailedf("cannot excise with an inclusive end key and data overlap at end key")
+		if kv := iters.Point().SeekGE(exciseSpanEnd.Key, base.SeekGEFlagsNone); kv != nil {
+			if exciseSpanEnd.Kind == base.Inclusive && cmp(exciseSpanEnd.Key, kv.K.UserKey) == 0 {
+				return base.AssertionFailedf("cannot excise with an inclusive end key and data overlap at end key")
 			}
-			rightFile.ExtendPointKeyBounds(d.cmp, kv.K.Clone(), largestPointKey)
+			rightTable.ExtendPointKeyBounds(cmp, kv.K.Clone(), largestPointKey)
+		}
+		if err := iters.Point().Error(); err != nil {
+			return err
 		}
-		// Store the max of (exciseSpan.End, rdel.Start) in firstRangeDel. This
+		// Store the max of (exciseSpanEnd, rdel.Start) in firstRangeDel. This
 		// needs to be a copy if the key is owned by the range del iter.
 		var firstRangeDel []byte
-		rdel, err := iters.RangeDeletion().SeekGE(exciseSpan.End.Key)
+		rdel, err := iters.RangeDeletion().SeekGE(exciseSpanEnd.Key)
 		if err != nil {
-			return nil, err
+			return err
 		} else if rdel != nil {
 			firstRangeDel = append(firstRangeDel[:0], rdel.Start...)
-			if d.cmp(firstRangeDel, exciseSpan.End.Key) < 0 {
+			if cmp(firstRangeDel, exciseSpanEnd.Key) < 0 {
 				// NB: This can only be done if the end bound is exclusive.
-				if exciseSpan.End.Kind != base.Exclusive {
-					return nil, base.AssertionFailedf("cannot truncate rangedel during excise with an inclusive upper bound")
+				if exciseSpanEnd.Kind != base.Exclusive {
+					return base.AssertionFailedf("cannot truncate rangedel during excise with an inclusive upper bound")
 				}
-				firstRangeDel = exciseSpan.End.Key
+				firstRangeDel = exciseSpanEnd.Key
 			}
 		}
 		if firstRangeDel != nil {
 			smallestPointKey := rdel.SmallestKey()
 			smallestPointKey.UserKey = firstRangeDel
-			rightFile.ExtendPointKeyBounds(d.cmp, smallestPointKey, largestPointKey)
+			rightTable.ExtendPointKeyBounds(cmp, smallestPointKey, largestPointKey)
 		}
 	}
-	if m.HasRangeKeys && !exciseSpan.ContainsInternalKey(d.cmp, m.LargestRangeKey) {
+	if m.HasRangeKeys && !exciseSpanEnd.IsUpperBoundForInternalKey(cmp, m.LargestRangeKey) {
 		// This file will probably contain range keys.
-		if err := loadItersIfNecessary(); err != nil {
-			return nil, err
-		}
 		largestRangeKey := m.LargestRangeKey
-		// Store the max of (exciseSpan.End, rkey.Start) in firstRangeKey. This
+		// Store the max of (exciseSpanEnd, rkey.Start) in firstRangeKey. This
 		// needs to be a copy if the key is owned by the range key iter.
 		var firstRangeKey []byte
-		rkey, err := iters.RangeKey().SeekGE(exciseSpan.End.Key)
+		rkey, err := iters.RangeKey().SeekGE(exciseSpanEnd.Key)
 		if err != nil {
-			return nil, err
+			return err
 		} else if rkey != nil {
 			firstRangeKey = append(firstRangeKey[:0], rkey.Start...)
-			if d.cmp(firstRangeKey, exciseSpan.End.Key) < 0 {
-				if exciseSpan.End.Kind != base.Exclusive {
-					return nil, base.AssertionFailedf("cannot truncate range key during excise with an inclusive upper bound")
+			if cmp(firstRangeKey, exciseSpanEnd.Key) < 0 {
+				if exciseSpanEnd.Kind != base.Exclusive {
+					return base.AssertionFailedf("cannot truncate range key during excise with an inclusive upper bound")
 				}
-				firstRangeKey = exciseSpan.End.Key
+				firstRangeKey = exciseSpanEnd.Key
 			}
 		}
 		if firstRangeKey != nil {
@@ -277,42 +337,12 @@ func (d *DB) excise(
 			smallestRangeKey.UserKey = firstRangeKey
 			// We call ExtendRangeKeyBounds so any internal boundType fields are
 			// set correctly. Note that this is mildly wasteful as we'll be comparing
-			// rightFile.{Smallest,Largest}RangeKey with themselves, which can be
+			// rightTable.{Smallest,Largest}RangeKey with themselves, which can be
 			// avoided if we exported ExtendOverallKeyBounds or so.
-			rightFile.ExtendRangeKeyBounds(d.cmp, smallestRangeKey, largestRangeKey)
+			rightTable.ExtendRangeKeyBounds(cmp, smallestRangeKey, largestRangeKey)
 		}
 	}
-	if rightFile.HasRangeKeys || rightFile.HasPointKeys {
-		var err error
-		rightFile.Size, err = d.fileCache.estimateSize(m, rightFile.Smallest.UserKey, rightFile.Largest.UserKey)
-		if err != nil {
-			return nil, err
-		}
-		if rightFile.Size == 0 {
-			// On occasion, estimateSize gives us a low estimate, i.e. a 0 file size,
-			// such as if the excised file only has range keys/dels and no point keys.
-			// This can cause panics in places where we divide by file sizes. Correct
-			// for it here.
-			rightFile.Size = 1
-		}
-		if err := rightFile.Validate(d.cmp, d.opts.Comparer.FormatKey); err != nil {
-			return nil, err
-		}
-		rightFile.ValidateVirtual(m)
-		ve.NewTables = append(ve.NewTables, newTableEntry{Level: level, Meta: rightFile})
-		needsBacking = true
-		numCreatedFiles++
-	}
-
-	if needsBacking && !m.Virtual {
-		// If m is virtual, then its file backing is already known to the manifest.
-		// We don't need to create another file backing. Note that there must be
-		// only one CreatedBackingTables entry per backing sstable. This is
-		// indicated by the VersionEdit.CreatedBackingTables invariant.
-		ve.CreatedBackingTables = append(ve.CreatedBackingTables, m.FileBacking)
-	}
-
-	return ve.NewTables[len(ve.NewTables)-numCreatedFiles:], nil
+	return nil
 }
 
 // exciseOverlapBounds examines the provided list of snapshots, examining each
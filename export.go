@@ -0,0 +1,294 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+const (
+	// defaultExportTargetFileSize is the default of
+	// ExportOptions.TargetFileSize.
+	defaultExportTargetFileSize = 64 << 20
+	// exportRateLimitChunk is the number of bytes an export writes between
+	// draws from ExportOptions.RateLimiter.
+	exportRateLimitChunk = 64 << 10
+)
+
+// ExportOptions configures Snapshot.Export.
+type ExportOptions struct {
+	// Storage is the remote storage the sstables are written to, and Locator
+	// the locator under which the DBs ingesting them resolve Storage (see
+	// Options.Experimental.RemoteStorage).
+	Storage remote.Storage
+	Locator remote.Locator
+	// ObjNamePrefix is the prefix of the names of the objects created in
+	// Storage. The objects are named <prefix>000001.sst, <prefix>000002.sst,
+	// etc.
+	ObjNamePrefix string
+	// TargetFileSize is the size after which an sstable is finished and the
+	// next one started. It defaults to 64 MiB.
+	TargetFileSize int64
+	// RateLimiter, if set, throttles the export: the bytes written are drawn
+	// from it with IOPriorityLow, behind flushes.
+	RateLimiter *IORateLimiter
+	// OnProgress, if set, is called after each sstable is written.
+	OnProgress func(ExportProgress)
+}
+
+// ExportProgress describes the progress of an export, reported through
+// ExportOptions.OnProgress.
+type ExportProgress struct {
+	// File is the sstable that was just written.
+	File ExternalFile
+	// Files and Bytes are the number and total size of the sstables written
+	// so far.
+	Files int
+	Bytes uint64
+}
+
+// Export writes the contents of the snapshot within [start, end) to sstables
+// in ExportOptions.Storage, and returns them as ExternalFiles that can be
+// ingested by another DB with IngestExternalFiles. This allows moving a key
+// range between DBs without sharing their storage.
+//
+// The sstables contain the keys visible in the snapshot, with zero sequence
+// numbers: the point keys with their latest values (merges are resolved, and
+// deleted keys omitted) and the range keys. Each sstable is finished once it
+// reaches ExportOptions.TargetFileSize, at a boundary between key prefixes, so
+// that the bounds of the returned ExternalFiles don't overlap. Range keys are
+// truncated to the bounds of the sstables.
+//
+// start and end must not have suffixes, like the bounds of an ExternalFile. If
+// Export fails, the objects it created are deleted.
+func (s *Snapshot) Export(
+	ctx context.Context, start, end []byte, opts ExportOptions,
+) ([]ExternalFile, error) {
+	if s.db == nil {
+		panic(ErrClosed)
+	}
+	d := s.db
+	switch {
+	case opts.Storage == nil:
+		return nil, errors.New("pebble: export requires a remote storage")
+	case start == nil || end == nil:
+		return nil, errors.New("pebble: export requires start and end bounds")
+	case d.cmp(start, end) >= 0:
+		return nil, errors.Errorf("pebble: invalid export bounds [%s, %s)",
+			d.opts.Comparer.FormatKey(start), d.opts.Comparer.FormatKey(end))
+	}
+	if opts.TargetFileSize <= 0 {
+		opts.TargetFileSize = defaultExportTargetFileSize
+	}
+
+	iter, err := s.NewIterWithContext(ctx, &IterOptions{
+		LowerBound: start,
+		UpperBound: end,
+		KeyTypes:   IterKeyTypePointsAndRanges,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e := &exporter{d: d, opts: opts, fileStart: start}
+	err = e.export(ctx, iter)
+	err = firstError(err, iter.Close())
+	if err == nil {
+		err = e.finishFile(end)
+	}
+	if err != nil {
+		e.abort()
+		return nil, err
+	}
+	return e.files, nil
+}
+
+// exporter writes the sstables of an export.
+type exporter struct {
+	d    *DB
+	opts ExportOptions
+
+	files []ExternalFile
+	bytes uint64
+	// w writes the current sstable, which starts at fileStart. It's nil if no
+	// key was written since the previous sstable was finished.
+	w         *sstable.Writer
+	objName   string
+	fileStart []byte
+	hasPoint  bool
+	hasRange  bool
+	// lastPoint is the last point key written, and lastRangeEnd the end of the
+	// last range key span written; the next sstable must start after both.
+	lastPoint    []byte
+	lastRangeEnd []byte
+	// rangeKey is the range key span at the iterator's position, which is
+	// written when the iterator leaves it or the sstable is finished.
+	rangeKey struct {
+		start, end []byte
+		keys       []RangeKeyData
+	}
+	// unthrottled is the number of bytes written since the last draw from
+	// ExportOptions.RateLimiter.
+	unthrottled int64
+}
+
+func (e *exporter) export(ctx context.Context, iter *Iterator) error {
+	for valid := iter.First(); valid; valid = iter.Next() {
+		key := iter.Key()
+		if e.w != nil && e.w.Raw().EstimatedSize() >= uint64(e.opts.TargetFileSize) {
+			cut := append([]byte(nil), key[:e.d.opts.Comparer.Split(key)]...)
+			if e.d.cmp(cut, e.lastPoint) > 0 && e.d.cmp(cut, e.lastRangeEnd) >= 0 {
+				if err := e.finishFile(cut); err != nil {
+					return err
+				}
+			}
+		}
+		if iter.RangeKeyChanged() {
+			if err := e.writeRangeKey(e.rangeKey.end); err != nil {
+				return err
+			}
+			e.rangeKey.start, e.rangeKey.end, e.rangeKey.keys = nil, nil, nil
+			if _, hasRange := iter.HasPointAndRange(); hasRange {
+				start, end := iter.RangeBounds()
+				e.rangeKey.start = append([]byte(nil), start...)
+				e.rangeKey.end = append([]byte(nil), end...)
+				for _, k := range iter.RangeKeys() {
+					e.rangeKey.keys = append(e.rangeKey.keys, RangeKeyData{
+						Suffix: append([]byte(nil), k.Suffix...),
+						Value:  append([]byte(nil), k.Value...),
+					})
+				}
+			}
+		}
+		if hasPoint, _ := iter.HasPointAndRange(); hasPoint {
+			value, err := iter.ValueAndErr()
+			if err != nil {
+				return err
+			}
+			if err := e.writer(); err != nil {
+				return err
+			}
+			if err := e.w.Set(key, value); err != nil {
+				return err
+			}
+			e.lastPoint = append(e.lastPoint[:0], key...)
+			e.hasPoint = true
+			if err := e.throttle(ctx, int64(len(key)+len(value))); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return e.writeRangeKey(e.rangeKey.end)
+}
+
+// writeRangeKey writes the part of the current range key span before end.
+func (e *exporter) writeRangeKey(end []byte) error {
+	if e.rangeKey.start == nil || e.d.cmp(e.rangeKey.start, end) >= 0 {
+		return nil
+	}
+	if e.d.cmp(e.rangeKey.end, end) < 0 {
+		end = e.rangeKey.end
+	}
+	if err := e.writer(); err != nil {
+		return err
+	}
+	for _, k := range e.rangeKey.keys {
+		if err := e.w.RangeKeySet(e.rangeKey.start, end, k.Suffix, k.Value); err != nil {
+			return err
+		}
+	}
+	e.rangeKey.start = end
+	e.lastRangeEnd = end
+	e.hasRange = true
+	return nil
+}
+
+// writer creates the writer of the current sstable, if it's not created yet.
+func (e *exporter) writer() error {
+	if e.w != nil {
+		return nil
+	}
+	e.objName = fmt.Sprintf("%s%06d.sst", e.opts.ObjNamePrefix, len(e.files)+1)
+	obj, err := e.opts.Storage.CreateObject(e.objName)
+	if err != nil {
+		return err
+	}
+	writerOpts := e.d.opts.MakeWriterOptions(numLevels-1, e.d.TableFormat())
+	e.w = sstable.NewWriter(objstorageprovider.NewRemoteWritable(obj), writerOpts)
+	return nil
+}
+
+// finishFile finishes the current sstable, which ends at end, and starts the
+// next one at end.
+func (e *exporter) finishFile(end []byte) error {
+	if err := e.writeRangeKey(end); err != nil {
+		return err
+	}
+	if e.w == nil {
+		e.fileStart = end
+		return nil
+	}
+	w := e.w
+	e.w = nil
+	if err := w.Close(); err != nil {
+		return err
+	}
+	meta, err := w.Metadata()
+	if err != nil {
+		return err
+	}
+	f := ExternalFile{
+		Locator:     e.opts.Locator,
+		ObjName:     e.objName,
+		Size:        meta.Size,
+		StartKey:    e.fileStart,
+		EndKey:      append([]byte(nil), end...),
+		HasPointKey: e.hasPoint,
+		HasRangeKey: e.hasRange,
+	}
+	e.files = append(e.files, f)
+	e.bytes += meta.Size
+	e.fileStart, e.hasPoint, e.hasRange = f.EndKey, false, false
+	if e.opts.OnProgress != nil {
+		e.opts.OnProgress(ExportProgress{File: f, Files: len(e.files), Bytes: e.bytes})
+	}
+	return nil
+}
+
+// throttle records that n bytes were written, drawing them from the rate
+// limiter, if any, once enough of them accumulate.
+func (e *exporter) throttle(ctx context.Context, n int64) error {
+	e.unthrottled += n
+	if e.unthrottled < exportRateLimitChunk {
+		return nil
+	}
+	if e.opts.RateLimiter != nil {
+		e.opts.RateLimiter.Wait(IOPriorityLow, e.unthrottled)
+	}
+	e.unthrottled = 0
+	return ctx.Err()
+}
+
+// abort deletes the objects created by a failed export.
+func (e *exporter) abort() {
+	if e.w != nil {
+		_ = e.w.Close()
+		e.files = append(e.files, ExternalFile{ObjName: e.objName})
+	}
+	for _, f := range e.files {
+		if err := e.opts.Storage.Delete(f.ObjName); err != nil {
+			e.d.opts.Logger.Errorf("failed to delete exported object %q: %v", f.ObjName, err)
+		}
+	}
+	e.files = nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotExport(t *testing.T) {
+	storage := remote.NewInMem()
+	open := func() *DB {
+		opts := &Options{
+			FS:                 vfs.NewMem(),
+			Comparer:           testkeys.Comparer,
+			FormatMajorVersion: FormatNewest,
+		}
+		opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+			"export": storage,
+		})
+		d, err := Open("", opts)
+		require.NoError(t, err)
+		return d
+	}
+	contents := func(r Reader, start, end []byte) string {
+		iter, err := r.NewIter(&IterOptions{
+			LowerBound: start,
+			UpperBound: end,
+			KeyTypes:   IterKeyTypePointsAndRanges,
+		})
+		require.NoError(t, err)
+		defer iter.Close()
+		var s string
+		for valid := iter.First(); valid; valid = iter.Next() {
+			if hasPoint, hasRange := iter.HasPointAndRange(); hasPoint {
+				s += fmt.Sprintf("%s=%s", iter.Key(), iter.Value())
+				if hasRange && iter.RangeKeyChanged() {
+					start, end := iter.RangeBounds()
+					s += fmt.Sprintf(" [%s-%s)=%v", start, end, iter.RangeKeys())
+				}
+				s += "\n"
+			} else if iter.RangeKeyChanged() {
+				start, end := iter.RangeBounds()
+				s += fmt.Sprintf("[%s-%s)=%v\n", start, end, iter.RangeKeys())
+			}
+		}
+		require.NoError(t, iter.Error())
+		return s
+	}
+
+	src := open()
+	defer func() { require.NoError(t, src.Close()) }()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, src.Set([]byte(fmt.Sprintf("k%04d@1", i)), []byte(fmt.Sprint(i)), nil))
+		if i%3 == 0 {
+			require.NoError(t, src.Set([]byte(fmt.Sprintf("k%04d@2", i)), []byte(fmt.Sprint(i)), nil))
+		}
+	}
+	require.NoError(t, src.Flush())
+	require.NoError(t, src.RangeKeySet([]byte("k0100"), []byte("k0700"), []byte("@3"), []byte("rk"), nil))
+	require.NoError(t, src.DeleteRange([]byte("k0200"), []byte("k0300"), nil))
+	require.NoError(t, src.Delete([]byte("k0500@1"), nil))
+	snap := src.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+	// Writes after the snapshot aren't exported.
+	require.NoError(t, src.Set([]byte("k0400@1"), []byte("new"), nil))
+
+	start, end := []byte("k0050"), []byte("k0950")
+	var progress []ExportProgress
+	files, err := snap.Export(context.Background(), start, end, ExportOptions{
+		Storage:        storage,
+		Locator:        "export",
+		ObjNamePrefix:  "shard-",
+		TargetFileSize: 1 << 10,
+		OnProgress:     func(p ExportProgress) { progress = append(progress, p) },
+	})
+	require.NoError(t, err)
+	require.Greater(t, len(files), 2)
+	require.Len(t, progress, len(files))
+	require.Equal(t, "shard-000001.sst", files[0].ObjName)
+	require.Equal(t, start, files[0].StartKey)
+	require.Equal(t, end, files[len(files)-1].EndKey)
+	var bytes uint64
+	for i := range files {
+		bytes += files[i].Size
+		if i > 0 {
+			require.Equal(t, files[i-1].EndKey, files[i].StartKey)
+		}
+	}
+	require.Equal(t, bytes, progress[len(progress)-1].Bytes)
+
+	dst := open()
+	defer func() { require.NoError(t, dst.Close()) }()
+	_, err = dst.IngestExternalFiles(context.Background(), files)
+	require.NoError(t, err)
+	require.Equal(t, contents(snap, start, end), contents(dst, nil, nil))
+}
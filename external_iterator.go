@@ -132,6 +132,8 @@ func validateExternalIterOpts(iterOpts *IterOptions) error {
 		return errors.Errorf("pebble: external iterator: RangeKeyFilters unsupported")
 	case iterOpts.OnlyReadGuaranteedDurable:
 		return errors.Errorf("pebble: external iterator: OnlyReadGuaranteedDurable unsupported")
+	case iterOpts.ReadAtDurableFrontier:
+		return errors.Errorf("pebble: external iterator: ReadAtDurableFrontier unsupported")
 	case iterOpts.UseL6Filters:
 		return errors.Errorf("pebble: external iterator: UseL6Filters unsupported")
 	}
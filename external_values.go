@@ -0,0 +1,182 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// externalValueEntryOverhead approximates the memory used by a cached external
+// value besides its handle and value.
+const externalValueEntryOverhead = 64
+
+// ExternalValueStore is a value store managed by the application, for
+// applications that already keep large values outside of Pebble (e.g. an
+// object per value) and use Pebble for keys and metadata. The application
+// writes a handle referencing the value in its store as the value of the key,
+// and reads through Iterator.Value, Iterator.ValueAndErr and Get return the
+// value fetched from the store.
+//
+// Handles are resolved after merging, so they must not be written as the
+// operands of merges.
+//
+// Pebble never interprets, rewrites or deletes the values of the store: the
+// application owns their lifetime, and must keep a value fetchable for as long
+// as a handle referencing it may be read, including through snapshots and
+// checkpoints.
+type ExternalValueStore interface {
+	// IsHandle returns true if value, as stored in Pebble, is a handle
+	// referencing a value in the store rather than a value. The application
+	// defines the encoding of its handles, e.g. by reserving a tag in a value
+	// header, so that they can't be mistaken for values.
+	IsHandle(value []byte) bool
+	// Fetch appends the value referenced by handle to buf and returns the
+	// result. A handle must always reference the same value.
+	Fetch(ctx context.Context, handle []byte, buf []byte) ([]byte, error)
+}
+
+// ExternalValueOptions configures the reads of values stored in an
+// ExternalValueStore.
+type ExternalValueOptions struct {
+	// Store is the application's value store. Values are never fetched from
+	// an external store if Store is nil.
+	Store ExternalValueStore
+	// CacheSize is the maximum total size in bytes of the fetched values
+	// cached in memory, keyed by their handle. The least recently used values
+	// are evicted to stay under it. Fetched values aren't cached if CacheSize
+	// is not positive.
+	CacheSize int64
+}
+
+// ExternalValueMetrics holds the metrics of the reads of values stored in an
+// ExternalValueStore.
+type ExternalValueMetrics struct {
+	// Fetches is the number of values fetched from the store, and FetchErrors
+	// the number of those fetches that failed.
+	Fetches     int64
+	FetchErrors int64
+	// CacheHits is the number of values served by the cache.
+	CacheHits int64
+	// CacheCount and CacheSize are the number and size in bytes of the
+	// cached values.
+	CacheCount int64
+	CacheSize  int64
+}
+
+type externalValueEntry struct {
+	handle string
+	value  []byte
+	size   int64
+	// prev and next link the entries of externalValues.mu.lru.
+	prev, next *externalValueEntry
+}
+
+// externalValues fetches the values referenced by handles from an
+// ExternalValueStore, through a cache of the recently fetched values. See
+// ExternalValueOptions.
+type externalValues struct {
+	opts ExternalValueOptions
+
+	mu struct {
+		sync.Mutex
+		entries map[string]*externalValueEntry
+		// lru is the sentinel of the list of entries, most recently used
+		// first.
+		lru     externalValueEntry
+		size    int64
+		metrics ExternalValueMetrics
+	}
+}
+
+func newExternalValues(opts ExternalValueOptions) *externalValues {
+	v := &externalValues{opts: opts}
+	v.mu.entries = make(map[string]*externalValueEntry)
+	v.mu.lru.prev, v.mu.lru.next = &v.mu.lru, &v.mu.lru
+	return v
+}
+
+// resolve returns value if it isn't a handle, and otherwise the value it
+// references. A cached value is returned as is and must not be modified. A
+// value that isn't cached is fetched into buf, and callerOwned is true.
+func (v *externalValues) resolve(
+	ctx context.Context, value []byte, buf []byte,
+) (val []byte, callerOwned bool, err error) {
+	if !v.opts.Store.IsHandle(value) {
+		return value, false, nil
+	}
+	if cached, ok := v.get(value); ok {
+		return cached, false, nil
+	}
+	val, err = v.opts.Store.Fetch(ctx, value, buf[:0])
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.mu.metrics.Fetches++
+	if err != nil {
+		v.mu.metrics.FetchErrors++
+		return nil, false, errors.Wrap(err, "pebble: fetching external value")
+	}
+	size := int64(len(value)+len(val)) + externalValueEntryOverhead
+	if size > v.opts.CacheSize {
+		return val, true, nil
+	}
+	// The fetched value is cached, so it can't be returned in the caller's
+	// buffer.
+	e := &externalValueEntry{
+		handle: string(value),
+		value:  append([]byte(nil), val...),
+		size:   size,
+	}
+	if old, ok := v.mu.entries[e.handle]; ok {
+		v.removeLocked(old)
+	}
+	v.mu.entries[e.handle] = e
+	v.pushFrontLocked(e)
+	v.mu.size += e.size
+	for v.mu.size > v.opts.CacheSize {
+		v.removeLocked(v.mu.lru.prev)
+	}
+	return e.value, false, nil
+}
+
+func (v *externalValues) get(handle []byte) ([]byte, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	e, ok := v.mu.entries[string(handle)]
+	if !ok {
+		return nil, false
+	}
+	v.mu.metrics.CacheHits++
+	v.unlinkLocked(e)
+	v.pushFrontLocked(e)
+	return e.value, true
+}
+
+func (v *externalValues) pushFrontLocked(e *externalValueEntry) {
+	e.prev, e.next = &v.mu.lru, v.mu.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+func (v *externalValues) unlinkLocked(e *externalValueEntry) {
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+func (v *externalValues) removeLocked(e *externalValueEntry) {
+	v.unlinkLocked(e)
+	delete(v.mu.entries, e.handle)
+	v.mu.size -= e.size
+}
+
+func (v *externalValues) metrics() ExternalValueMetrics {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	m := v.mu.metrics
+	m.CacheCount = int64(len(v.mu.entries))
+	m.CacheSize = v.mu.size
+	return m
+}
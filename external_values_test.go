@@ -0,0 +1,106 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// testExternalValueStore holds values referenced by handles made of a 0xff
+// tag byte followed by a name.
+type testExternalValueStore struct {
+	values map[string]string
+}
+
+func (s *testExternalValueStore) IsHandle(value []byte) bool {
+	return len(value) > 0 && value[0] == 0xff
+}
+
+func (s *testExternalValueStore) Fetch(
+	ctx context.Context, handle []byte, buf []byte,
+) ([]byte, error) {
+	v, ok := s.values[string(handle[1:])]
+	if !ok {
+		return nil, errors.Newf("no value for %q", handle[1:])
+	}
+	return append(buf, v...), nil
+}
+
+func TestExternalValues(t *testing.T) {
+	store := &testExternalValueStore{values: map[string]string{
+		"v1": strings.Repeat("1", 100),
+		"v2": strings.Repeat("2", 100),
+	}}
+	opts := &Options{FS: vfs.NewMem()}
+	opts.Experimental.ExternalValues = ExternalValueOptions{
+		Store: store,
+		// Room for a single value.
+		CacheSize: 200,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	handle := func(name string) []byte { return append([]byte{0xff}, name...) }
+	require.NoError(t, d.Set([]byte("a"), []byte("inline"), nil))
+	require.NoError(t, d.Set([]byte("b"), handle("v1"), nil))
+	require.NoError(t, d.Set([]byte("c"), handle("v2"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("d"), handle("v1"), nil))
+
+	get := func(key string) string {
+		v, closer, err := d.Get([]byte(key))
+		require.NoError(t, err)
+		defer closer.Close()
+		return string(v)
+	}
+	require.Equal(t, "inline", get("a"))
+	require.Equal(t, store.values["v1"], get("b"))
+	require.Equal(t, store.values["v1"], get("d"))
+	m := d.Metrics().ExternalValues
+	require.Equal(t, int64(1), m.Fetches)
+	require.Equal(t, int64(1), m.CacheHits)
+	require.Equal(t, int64(1), m.CacheCount)
+
+	scan := func() string {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		for valid := iter.First(); valid; valid = iter.Next() {
+			v, err := iter.ValueAndErr()
+			require.NoError(t, err)
+			fmt.Fprintf(&buf, "%s=%c%d ", iter.Key(), v[0], len(v))
+		}
+		require.NoError(t, iter.Close())
+		return buf.String()
+	}
+	require.Equal(t, "a=i6 b=1100 c=2100 d=1100 ", scan())
+	// The fetch of v2 evicted v1 from the cache.
+	m = d.Metrics().ExternalValues
+	require.Equal(t, int64(3), m.Fetches)
+	require.Equal(t, int64(2), m.CacheHits)
+	require.Equal(t, int64(1), m.CacheCount)
+
+	// A value that can't be fetched fails the read.
+	require.NoError(t, d.Set([]byte("e"), handle("missing"), nil))
+	_, _, err = d.Get([]byte("e"))
+	require.ErrorContains(t, err, `no value for "missing"`)
+	iter, err := d.NewIter(&IterOptions{LowerBound: []byte("e")})
+	require.NoError(t, err)
+	require.True(t, iter.First())
+	_, err = iter.ValueAndErr()
+	require.Error(t, err)
+	require.False(t, iter.Valid())
+	require.Error(t, iter.Close())
+	require.Equal(t, int64(2), d.Metrics().ExternalValues.FetchErrors)
+}
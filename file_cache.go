@@ -99,6 +99,8 @@ type fileCacheHandle struct {
 	blockCacheHandle *cache.Handle
 	objProvider      objstorage.Provider
 	readerOpts       sstable.ReaderOptions
+	// ioRateLimiter, if set, limits low priority reads. See WithReadIOPriority.
+	ioRateLimiter *IORateLimiter
 
 	// iterCount keeps track of how many iterators are open. It is used to keep
 	// track of leaked iterators on a per-db level.
@@ -199,6 +201,9 @@ func (h *fileCacheHandle) openFile(
 	if err != nil {
 		return nil, objstorage.ObjectMetadata{}, err
 	}
+	if h.ioRateLimiter != nil {
+		f = &rateLimitedReadable{Readable: f, limiter: h.ioRateLimiter}
+	}
 	objMeta, err := h.objProvider.Lookup(fileType, fileNum)
 	if err != nil {
 		return nil, objstorage.ObjectMetadata{}, err
@@ -209,6 +214,7 @@ func (h *fileCacheHandle) openFile(
 		CacheHandle: h.blockCacheHandle,
 		FileNum:     fileNum,
 	}
+	o.Remote = objMeta.IsRemote()
 	switch fileType {
 	case base.FileTypeTable:
 		r, err := sstable.NewReader(ctx, f, o)
@@ -304,6 +310,15 @@ func (h *fileCacheHandle) Metrics() (CacheMetrics, FilterMetrics) {
 func (h *fileCacheHandle) estimateSize(
 	meta *tableMetadata, lower, upper []byte,
 ) (size uint64, err error) {
+	if len(meta.SuffixReplacements) > 0 {
+		_, lower, upper = invertSuffixBounds(h.comparer(), meta.SuffixReplacements, nil, lower, upper)
+	}
+	if pr := meta.PrefixReplacement(); pr.IsSet() {
+		var empty bool
+		if _, lower, upper, empty = invertBounds(pr, nil, lower, upper); empty {
+			return 0, nil
+		}
+	}
 	err = h.withCommonReader(context.TODO(), block.NoReadEnv, meta, func(cr sstable.CommonReader, env block.ReadEnv) error {
 		size, err = cr.EstimateDiskUsage(lower, upper)
 		return err
@@ -311,14 +326,24 @@ func (h *fileCacheHandle) estimateSize(
 	return size, err
 }
 
+// comparer returns the Comparer of the tables.
+func (h *fileCacheHandle) comparer() *base.Comparer {
+	if h.readerOpts.Comparer != nil {
+		return h.readerOpts.Comparer
+	}
+	return base.DefaultComparer
+}
+
 // createCommonReader creates a Reader for this file.
-func createCommonReader(v *fileCacheValue, file *tableMetadata) sstable.CommonReader {
+func (h *fileCacheHandle) createCommonReader(
+	v *fileCacheValue, file *tableMetadata,
+) sstable.CommonReader {
 	// TODO(bananabrick): We suffer an allocation if file is a virtual sstable.
 	r := v.mustSSTableReader()
 	var cr sstable.CommonReader = r
 	if file.Virtual {
 		virtualReader := sstable.MakeVirtualReader(
-			r, file.VirtualMeta().VirtualReaderParams(v.isShared),
+			r, file.VirtualMeta().VirtualReaderParams(v.isShared, h.comparer()),
 		)
 		cr = &virtualReader
 	}
@@ -338,7 +363,7 @@ func (h *fileCacheHandle) withCommonReader(
 	defer ref.Unref()
 	env.ReportCorruptionFn = h.reportCorruptionFn
 	env.ReportCorruptionArg = meta
-	return fn(createCommonReader(ref.Value(), meta), env)
+	return fn(h.createCommonReader(ref.Value(), meta), env)
 }
 
 func (h *fileCacheHandle) withReader(
@@ -372,7 +397,7 @@ func (h *fileCacheHandle) withVirtualReader(
 	v := ref.Value()
 	env.ReportCorruptionFn = h.reportCorruptionFn
 	env.ReportCorruptionArg = &meta.TableMetadata
-	return fn(sstable.MakeVirtualReader(v.mustSSTableReader(), meta.VirtualReaderParams(v.isShared)), env)
+	return fn(sstable.MakeVirtualReader(v.mustSSTableReader(), meta.VirtualReaderParams(v.isShared, h.comparer())), env)
 }
 
 func (h *fileCacheHandle) IterCount() int64 {
@@ -554,7 +579,7 @@ func (h *fileCacheHandle) newIters(
 	v := vRef.Value()
 	r := v.mustSSTableReader()
 	// Note: This suffers an allocation for virtual sstables.
-	cr := createCommonReader(v, file)
+	cr := h.createCommonReader(v, file)
 	var iters iterSet
 	if kinds.RangeKey() && file.HasRangeKeys {
 		iters.rangeKey, err = newRangeKeyIter(ctx, r, file, cr, opts.SpanIterOptions(), internalOpts)
@@ -627,14 +652,28 @@ func (h *fileCacheHandle) newPointIter(
 		// obsolete points (see sstable.Reader.newCompactionIter) but we don't
 		// apply the obsolete block property filter. We could optimize this by
 		// applying the filter.
+		pointKeyFilters = opts.PointKeyFilters
+		if len(file.SuffixReplacements) > 0 {
+			// The block properties describe the suffixes physically stored in
+			// the table, which the filters can't be applied to.
+			pointKeyFilters = nil
+		}
 		hideObsoletePoints, pointKeyFilters =
 			r.TryAddBlockPropertyFilterForHideObsoletePoints(
-				opts.snapshotForHideObsoletePoints, file.LargestSeqNum, opts.PointKeyFilters)
-
+				opts.snapshotForHideObsoletePoints, file.LargestSeqNum, pointKeyFilters)
+
+		// The bound-limited filter compares the table's index separators against
+		// its bounds. With a prefix or suffix replacement, the separators are in
+		// terms of the keys physically stored in the table, so the filter is not
+		// used.
+		boundLimitedFilter := internalOpts.boundLimitedFilter
+		if len(file.ContentPrefix) > 0 || len(file.SuffixReplacements) > 0 {
+			boundLimitedFilter = nil
+		}
 		var ok bool
 		var err error
 		ok, filterer, err = checkAndIntersectFilters(r, pointKeyFilters,
-			internalOpts.boundLimitedFilter, file.SyntheticPrefixAndSuffix.Suffix())
+			boundLimitedFilter, file.SyntheticPrefixAndSuffix.Suffix())
 		if err != nil {
 			return nil, err
 		} else if !ok {
@@ -657,6 +696,7 @@ func (h *fileCacheHandle) newPointIter(
 		}
 		if opts.layer.IsSet() && !opts.layer.IsFlushableIngests() {
 			ctx = objiotracing.WithLevel(ctx, opts.layer.Level())
+			internalOpts.readEnv.LevelPlusOne = uint8(opts.layer.Level()) + 1
 		}
 	}
 	tableFormat, err := r.TableFormat()
@@ -676,17 +716,29 @@ func (h *fileCacheHandle) newPointIter(
 	if internalOpts.readEnv.IterStats == nil && opts != nil {
 		internalOpts.readEnv.IterStats = handle.SSTStatsCollector().Accumulator(uint64(uintptr(unsafe.Pointer(r))), opts.Category)
 	}
+	lower, upper := opts.GetLowerBound(), opts.GetUpperBound()
+	var srIter *suffixReplacingIter
+	if len(file.SuffixReplacements) > 0 {
+		srIter, lower, upper = newSuffixReplacingIter(h.comparer(), file.SuffixReplacements, lower, upper)
+	}
+	var prIter *prefixReplacingIter
+	if pr := file.PrefixReplacement(); pr.IsSet() {
+		prIter, lower, upper = newPrefixReplacingIter(pr, lower, upper)
+	}
 	if internalOpts.compaction {
 		iter, err = cr.NewCompactionIter(transforms, internalOpts.readEnv, &v.readerProvider)
 	} else {
 		iter, err = cr.NewPointIter(ctx, sstable.IterOptions{
-			Lower:                opts.GetLowerBound(),
-			Upper:                opts.GetUpperBound(),
+			Lower:                lower,
+			Upper:                upper,
 			Transforms:           transforms,
 			FilterBlockSizeLimit: filterBlockSizeLimit,
 			Filterer:             filterer,
-			Env:                  internalOpts.readEnv,
-			ReaderProvider:       &v.readerProvider,
+			// Point tombstones in the bottommost level can't delete anything, so
+			// prefix seeks don't need to observe them.
+			SkipTombstoneOnlyPrefixes: opts != nil && opts.layer == manifest.Level(numLevels-1),
+			Env:                       internalOpts.readEnv,
+			ReaderProvider:            &v.readerProvider,
 		})
 	}
 	if err != nil {
@@ -697,7 +749,16 @@ func (h *fileCacheHandle) newPointIter(
 	// adding a closure.
 	closeHook := h.addReference(v)
 	iter.SetCloseHook(closeHook)
-	return iter, nil
+	var wrapped internalIterator = iter
+	if prIter != nil {
+		prIter.init(wrapped)
+		wrapped = prIter
+	}
+	if srIter != nil {
+		srIter.init(wrapped)
+		wrapped = srIter
+	}
+	return wrapped, nil
 }
 
 func (h *fileCacheHandle) addReference(v *fileCacheValue) (closeHook func()) {
@@ -739,12 +800,15 @@ func newRangeDelIter(
 	if err != nil {
 		return nil, err
 	}
+	if pr := file.PrefixReplacement(); pr.IsSet() {
+		rangeDelIter = newPrefixReplacingSpanIter(rangeDelIter, pr)
+	}
+	if len(file.SuffixReplacements) > 0 {
+		rangeDelIter = newSuffixReplacingSpanIter(rangeDelIter, handle.comparer(), file.SuffixReplacements)
+	}
 	// Assert expected bounds in tests.
 	if invariants.Sometimes(50) && rangeDelIter != nil {
-		cmp := base.DefaultComparer.Compare
-		if handle.readerOpts.Comparer != nil {
-			cmp = handle.readerOpts.Comparer.Compare
-		}
+		cmp := handle.comparer().Compare
 		rangeDelIter = keyspan.AssertBounds(
 			rangeDelIter, file.SmallestPointKey, file.LargestPointKey.UserKey, cmp,
 		)
@@ -778,7 +842,14 @@ func newRangeKeyIter(
 		}
 	}
 	// TODO(radu): wrap in an AssertBounds.
-	return cr.NewRawRangeKeyIter(ctx, transforms, internalOpts.readEnv)
+	iter, err := cr.NewRawRangeKeyIter(ctx, transforms, internalOpts.readEnv)
+	if err != nil {
+		return nil, err
+	}
+	if pr := file.PrefixReplacement(); pr.IsSet() {
+		iter = newPrefixReplacingSpanIter(iter, pr)
+	}
+	return iter, nil
 }
 
 // tableCacheShardReaderProvider implements sstable.ReaderProvider for a
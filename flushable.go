@@ -65,6 +65,56 @@ func sliceAsBounded[B bounded](s []B) []bounded {
 	return ret
 }
 
+// FlushReason is the reason a flush was initiated.
+type FlushReason uint8
+
+const (
+	// FlushReasonMemTableFull is a flush of memtables that filled up, or of a
+	// batch too large to be applied to a memtable.
+	FlushReasonMemTableFull FlushReason = iota
+	// FlushReasonManual is a flush requested through DB.Flush or
+	// DB.AsyncFlush, or by a manual compaction overlapping the memtables.
+	FlushReasonManual
+	// FlushReasonIngest is a flush of memtables overlapping an ingestion that
+	// couldn't be ingested as a flushable.
+	FlushReasonIngest
+	// FlushReasonIngestAsFlushable is a flush of sstables that were ingested as
+	// a flushable.
+	FlushReasonIngestAsFlushable
+	// FlushReasonFileOnlySnapshot is a flush forced by an
+	// EventuallyFileOnlySnapshot transitioning to a file-only snapshot.
+	FlushReasonFileOnlySnapshot
+	// FlushReasonDelayed is a flush forced once Options.FlushDelayDeleteRange
+	// or Options.FlushDelayRangeKey elapsed.
+	FlushReasonDelayed
+	// FlushReasonWALReplay is a flush of the memtables replayed from the WAL
+	// when opening the DB.
+	FlushReasonWALReplay
+	// NumFlushReasons is the number of flush reasons.
+	NumFlushReasons
+)
+
+// String implements fmt.Stringer.
+func (r FlushReason) String() string {
+	switch r {
+	case FlushReasonMemTableFull:
+		return "memtable-full"
+	case FlushReasonManual:
+		return "manual"
+	case FlushReasonIngest:
+		return "ingest"
+	case FlushReasonIngestAsFlushable:
+		return "ingest-as-flushable"
+	case FlushReasonFileOnlySnapshot:
+		return "file-only-snapshot"
+	case FlushReasonDelayed:
+		return "delayed"
+	case FlushReasonWALReplay:
+		return "wal-replay"
+	}
+	return "?"
+}
+
 // flushableEntry wraps a flushable and adds additional metadata and
 // functionality that is common to all flushables.
 type flushableEntry struct {
@@ -74,6 +124,10 @@ type flushableEntry struct {
 	// flushForced indicates whether a flush was forced on this memtable (either
 	// manual, or due to ingestion). Protected by DB.mu.
 	flushForced bool
+	// flushReason is the reason the flushable is flushed: the reason the flush
+	// was forced if flushForced is set, FlushReasonMemTableFull otherwise.
+	// Protected by DB.mu.
+	flushReason FlushReason
 	// delayedFlushForcedAt indicates whether a timer has been set to force a
 	// flush on this memtable at some point in the future. Protected by DB.mu.
 	// Holds the timestamp of when the flush will be issued.
@@ -106,6 +160,15 @@ type flushableEntry struct {
 	deleteFn func(manifest.ObsoleteFiles)
 }
 
+// forceFlush forces a flush of the flushable for the given reason, unless one
+// was already forced. DB.mu must be held.
+func (e *flushableEntry) forceFlush(reason FlushReason) {
+	if !e.flushForced {
+		e.flushForced = true
+		e.flushReason = reason
+	}
+}
+
 func (e *flushableEntry) readerRef() {
 	switch v := e.readerRefs.Add(1); {
 	case v <= 1:
@@ -5,12 +5,15 @@
 package pebble
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/blob"
+	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/vfs/atomicfs"
 )
@@ -214,6 +217,45 @@ const (
 	// This format major version does not yet enable use of value separation.
 	FormatTableFormatV6
 
+	// FormatBlobFileFormatV2 is a format major version enabling the blob file
+	// format blob.FileFormatV2, in which values are compressed individually
+	// (see Options.Experimental.BlobCompressionGranularity).
+	FormatBlobFileFormatV2
+
+	// FormatContentPrefix is a format major version that adds support for
+	// virtual sstables exposing the keys of their backing table under a
+	// different prefix: a ContentPrefix shared by the keys of the backing table
+	// is replaced with the SyntheticPrefix. The content prefix is stored in a
+	// new field in the Manifest and thus requires a format major version.
+	FormatContentPrefix
+
+	// FormatInlineTables is a format major version that adds support for
+	// storing the contents of small flushed sstables inline in the MANIFEST
+	// (see Options.Experimental.InlineTableMaxSize). The inline tables are
+	// written under a new version edit tag and thus require a format major
+	// version.
+	FormatInlineTables
+
+	// FormatNamedSnapshots is a format major version that adds support for
+	// named snapshots (see DB.NewNamedSnapshot), which are recorded in the
+	// MANIFEST under new version edit tags and thus require a format major
+	// version.
+	FormatNamedSnapshots
+
+	// FormatSuffixReplacements is a format major version that adds support for
+	// virtual sstables whose keys are re-stamped with different suffixes
+	// according to a list of suffix replacements (see
+	// ExternalFile.SuffixReplacements). The replacements are stored in a new
+	// field in the Manifest and thus require a format major version.
+	FormatSuffixReplacements
+
+	// FormatWALBatchCompression is a format major version that adds support for
+	// compressing large batches before they're appended to the WAL (see
+	// Options.Experimental.WALBatchCompressionThreshold). Compressed batches
+	// are flagged in their header, which older versions can't read, and thus
+	// require a format major version.
+	FormatWALBatchCompression
+
 	// -- Add new versions here --
 
 	// FormatNewest is the most recent format major version.
@@ -254,7 +296,7 @@ func (v FormatMajorVersion) MaxTableFormat() sstable.TableFormat {
 		return sstable.TableFormatPebblev4
 	case FormatColumnarBlocks, FormatWALSyncChunks:
 		return sstable.TableFormatPebblev5
-	case FormatTableFormatV6:
+	case FormatTableFormatV6, FormatBlobFileFormatV2, FormatContentPrefix, FormatInlineTables, FormatNamedSnapshots, FormatSuffixReplacements, FormatWALBatchCompression:
 		return sstable.TableFormatPebblev6
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
@@ -268,13 +310,22 @@ func (v FormatMajorVersion) MinTableFormat() sstable.TableFormat {
 	case FormatDefault, FormatFlushableIngest, FormatPrePebblev1MarkedCompacted,
 		FormatDeleteSizedAndObsolete, FormatVirtualSSTables, FormatSyntheticPrefixSuffix,
 		FormatFlushableIngestExcises, FormatColumnarBlocks, FormatWALSyncChunks,
-		FormatTableFormatV6:
+		FormatTableFormatV6, FormatBlobFileFormatV2, FormatContentPrefix, FormatInlineTables, FormatNamedSnapshots, FormatSuffixReplacements, FormatWALBatchCompression:
 		return sstable.TableFormatPebblev1
 	default:
 		panic(fmt.Sprintf("pebble: unsupported format major version: %s", v))
 	}
 }
 
+// MaxBlobFileFormat returns the maximum blob.FileFormat that can be used at
+// this FormatMajorVersion.
+func (v FormatMajorVersion) MaxBlobFileFormat() blob.FileFormat {
+	if v >= FormatBlobFileFormatV2 {
+		return blob.FileFormatV2
+	}
+	return blob.FileFormatV1
+}
+
 // formatMajorVersionMigrations defines the migrations from one format
 // major version to the next. Each migration is defined as a closure
 // which will be invoked on the database before the new format major
@@ -317,6 +368,24 @@ var formatMajorVersionMigrations = map[FormatMajorVersion]func(*DB) error{
 	FormatTableFormatV6: func(d *DB) error {
 		return d.finalizeFormatVersUpgrade(FormatTableFormatV6)
 	},
+	FormatBlobFileFormatV2: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatBlobFileFormatV2)
+	},
+	FormatContentPrefix: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatContentPrefix)
+	},
+	FormatInlineTables: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatInlineTables)
+	},
+	FormatNamedSnapshots: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatNamedSnapshots)
+	},
+	FormatSuffixReplacements: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatSuffixReplacements)
+	},
+	FormatWALBatchCompression: func(d *DB) error {
+		return d.finalizeFormatVersUpgrade(FormatWALBatchCompression)
+	},
 }
 
 const formatVersionMarkerName = `format-version`
@@ -439,6 +508,142 @@ func (d *DB) ratchetFormatMajorVersionLocked(formatVers FormatMajorVersion) erro
 	return nil
 }
 
+// ErrFormatMajorVersionRollbackUnsupported is returned by
+// RollbackFormatMajorVersion when one of the format major versions to roll
+// back can't be rolled back (see FormatMajorVersion.rollbackSupported).
+var ErrFormatMajorVersionRollbackUnsupported = errors.New("pebble: format major version cannot be rolled back")
+
+// RollbackFormatMajorVersion lowers the opened database's format major
+// version to the provided version, so that a previous Pebble version that
+// only supports it can open the database again. It's meant for deployments
+// that upgrade the binaries of a fleet and ratchet the format major version to
+// write a new table format, and need to roll back the binaries.
+//
+// The rollback happens after the fact: there is no mode in which tables are
+// written in both the new and the old table formats. The tables in a newer
+// table format than the one allowed by the provided version are rewritten in
+// place, through rewrite compactions, before the new version is recorded; the
+// method blocks (without holding mutexes) until they're done. New tables are
+// written in the older table format from the moment it's called, even if it
+// fails, until the database is reopened.
+//
+// Only the format major versions that raise the maximum table format without
+// changing anything else can be rolled back: FormatColumnarBlocks and
+// FormatTableFormatV6. If any of the versions above the provided one is
+// another version, the method returns an error marked with
+// ErrFormatMajorVersionRollbackUnsupported and leaves the database unchanged.
+// In particular, the versions that change the WAL format or gate new MANIFEST
+// entries (such as FormatWALSyncChunks, FormatInlineTables and
+// FormatNamedSnapshots) can't be rolled back, since the database may hold
+// data the older version can't read; the only way back from them is a backup
+// or checkpoint taken before the format major version was ratcheted. A
+// version that is above the database's current version, or below
+// FormatMinSupported, is refused with a different error.
+func (d *DB) RollbackFormatMajorVersion(fmv FormatMajorVersion) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	currentVers := d.FormatMajorVersion()
+	if fmv < FormatMinSupported || fmv > currentVers {
+		return errors.Newf("pebble: database at format major version %d; cannot roll back to %d",
+			currentVers, fmv)
+	}
+	for v := fmv + 1; v <= currentVers; v++ {
+		if !v.rollbackSupported() {
+			return errors.Mark(errors.Newf("pebble: format major version %s cannot be rolled back", v),
+				ErrFormatMajorVersionRollbackUnsupported)
+		}
+	}
+	if d.mu.formatVers.ratcheting {
+		return errors.Newf("pebble: database format major version upgrade is in-progress")
+	}
+	d.mu.formatVers.ratcheting = true
+	defer func() { d.mu.formatVers.ratcheting = false }()
+
+	// Lower the version in memory first, so that the tables written from now
+	// on are in the older table format.
+	d.mu.formatVers.vers.Store(uint64(fmv))
+	// The flushes, compactions and ingestions already in progress may have
+	// captured the table format before it was lowered. Wait for them to finish
+	// so that the scans of the LSM below find the tables they add.
+	if err := d.waitForFormatVersUsersLocked(fmv, currentVers); err != nil {
+		return err
+	}
+	maxFormat := fmv.MaxTableFormat()
+	for {
+		var found bool
+		if err := d.markFilesLocked(func(v *version) (bool, [numLevels][]*tableMetadata, error) {
+			var files [numLevels][]*tableMetadata
+			for l := range v.Levels {
+				for f := range v.Levels[l].All() {
+					format, err := d.tableFormatOf(f)
+					if err != nil {
+						return false, files, err
+					}
+					if format > maxFormat {
+						files[l] = append(files[l], f)
+						found = true
+					}
+				}
+			}
+			return found, files, nil
+		}); err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+		if err := d.compactMarkedFilesLocked(); err != nil {
+			return err
+		}
+	}
+	if err := d.writeFormatVersionMarker(fmv); err != nil {
+		return err
+	}
+	d.opts.EventListener.FormatUpgrade(fmv)
+	return nil
+}
+
+// rollbackSupported returns true if RollbackFormatMajorVersion can roll back
+// the format major version, i.e. if the version only raises the maximum table
+// format: the tables written in the newer format can be rewritten in the older
+// one.
+func (v FormatMajorVersion) rollbackSupported() bool {
+	switch v {
+	case FormatColumnarBlocks, FormatTableFormatV6:
+		return true
+	default:
+		return false
+	}
+}
+
+// tableFormatOf returns the table format of the table, or of its backing table
+// if it's virtual.
+func (d *DB) tableFormatOf(f *tableMetadata) (sstable.TableFormat, error) {
+	var format sstable.TableFormat
+	var err error
+	if f.Virtual {
+		err = d.fileCache.withVirtualReader(context.TODO(), block.NoReadEnv,
+			f.VirtualMeta(), func(v sstable.VirtualReader, _ block.ReadEnv) error {
+				format, err = v.UnsafeReader().TableFormat()
+				return err
+			})
+	} else {
+		err = d.fileCache.withReader(context.TODO(), block.NoReadEnv,
+			f.PhysicalMeta(), func(r *sstable.Reader, _ block.ReadEnv) error {
+				format, err = r.TableFormat()
+				return err
+			})
+	}
+	return format, err
+}
+
 // finalizeFormatVersUpgrade is typically only be called from within a
 // format major version migration.
 //
@@ -460,6 +665,65 @@ func (d *DB) writeFormatVersionMarker(formatVers FormatMajorVersion) error {
 	return d.mu.formatVers.marker.Move(formatVers.String())
 }
 
+// waitForFormatVersUsersLocked waits for the flushes and compactions in
+// progress, and for the ingestions of sstables validated against a format
+// major version in (fmv, prevVers], to finish. Flushes of ingested sstables
+// still in the flushable queue are waited for as well.
+//
+// d.mu must be held when calling this.
+func (d *DB) waitForFormatVersUsersLocked(fmv, prevVers FormatMajorVersion) error {
+	inFlight := make([]*compaction, 0, len(d.mu.compact.inProgress))
+	for c := range d.mu.compact.inProgress {
+		inFlight = append(inFlight, c)
+	}
+	pending := func() bool {
+		for _, c := range inFlight {
+			if _, ok := d.mu.compact.inProgress[c]; ok {
+				return true
+			}
+		}
+		for v := fmv + 1; v <= prevVers; v++ {
+			if d.mu.formatVers.ingests[v] > 0 {
+				return true
+			}
+		}
+		for _, mem := range d.mu.mem.queue {
+			if _, ok := mem.flushable.(*ingestedFlushable); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for pending() {
+		if err := d.closed.Load(); err != nil {
+			return err.(error)
+		}
+		d.maybeScheduleFlush()
+		d.mu.compact.cond.Wait()
+	}
+	return nil
+}
+
+// beginIngestFormatVers returns the format major version that an ingestion
+// validates its sstables against, and registers the ingestion as in progress
+// at that version until endIngestFormatVers is called.
+func (d *DB) beginIngestFormatVers() FormatMajorVersion {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmv := d.FormatMajorVersion()
+	d.mu.formatVers.ingests[fmv]++
+	return fmv
+}
+
+// endIngestFormatVers unregisters an ingestion registered by
+// beginIngestFormatVers.
+func (d *DB) endIngestFormatVers(fmv FormatMajorVersion) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.formatVers.ingests[fmv]--
+	d.mu.compact.cond.Broadcast()
+}
+
 // compactMarkedFilesLocked performs a migration that schedules rewrite
 // compactions to compact away any sstables marked for compaction.
 // compactMarkedFilesLocked is run while ratcheting the database's format major
@@ -520,10 +784,6 @@ func (d *DB) compactMarkedFilesLocked() error {
 // level.
 type findFilesFunc func(v *version) (found bool, files [numLevels][]*tableMetadata, _ error)
 
-// This method is not used currently, but it will be useful the next time we need
-// to mark files for compaction.
-var _ = (*DB)(nil).markFilesLocked
-
 // markFilesLocked durably marks the files that match the given findFilesFunc for
 // compaction.
 func (d *DB) markFilesLocked(findFn findFilesFunc) error {
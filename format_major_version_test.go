@@ -5,9 +5,12 @@
 package pebble
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/vfs/atomicfs"
@@ -27,11 +30,18 @@ func TestFormatMajorVersionStableValues(t *testing.T) {
 	require.Equal(t, FormatFlushableIngestExcises, FormatMajorVersion(18))
 	require.Equal(t, FormatColumnarBlocks, FormatMajorVersion(19))
 	require.Equal(t, FormatWALSyncChunks, FormatMajorVersion(20))
+	require.Equal(t, FormatTableFormatV6, FormatMajorVersion(21))
+	require.Equal(t, FormatBlobFileFormatV2, FormatMajorVersion(22))
+	require.Equal(t, FormatContentPrefix, FormatMajorVersion(23))
+	require.Equal(t, FormatInlineTables, FormatMajorVersion(24))
+	require.Equal(t, FormatNamedSnapshots, FormatMajorVersion(25))
+	require.Equal(t, FormatSuffixReplacements, FormatMajorVersion(26))
+	require.Equal(t, FormatWALBatchCompression, FormatMajorVersion(27))
 
 	// When we add a new version, we should add a check for the new version in
 	// addition to updating these expected values.
-	require.Equal(t, FormatNewest, FormatMajorVersion(21))
-	require.Equal(t, internalFormatNewest, FormatMajorVersion(21))
+	require.Equal(t, FormatNewest, FormatMajorVersion(27))
+	require.Equal(t, internalFormatNewest, FormatMajorVersion(27))
 }
 
 func TestFormatMajorVersion_MigrationDefined(t *testing.T) {
@@ -66,6 +76,18 @@ func TestRatchetFormat(t *testing.T) {
 	require.Equal(t, FormatWALSyncChunks, d.FormatMajorVersion())
 	require.NoError(t, d.RatchetFormatMajorVersion(FormatTableFormatV6))
 	require.Equal(t, FormatTableFormatV6, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatBlobFileFormatV2))
+	require.Equal(t, FormatBlobFileFormatV2, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatContentPrefix))
+	require.Equal(t, FormatContentPrefix, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatInlineTables))
+	require.Equal(t, FormatInlineTables, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatNamedSnapshots))
+	require.Equal(t, FormatNamedSnapshots, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatSuffixReplacements))
+	require.Equal(t, FormatSuffixReplacements, d.FormatMajorVersion())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatWALBatchCompression))
+	require.Equal(t, FormatWALBatchCompression, d.FormatMajorVersion())
 
 	require.NoError(t, d.Close())
 
@@ -94,6 +116,146 @@ func TestRatchetFormat(t *testing.T) {
 	require.EqualError(t, err, `pebble: database "" written in unknown format major version 999999`)
 }
 
+func TestRollbackFormatMajorVersion(t *testing.T) {
+	fs := vfs.NewMem()
+	open := func(fmv FormatMajorVersion) *DB {
+		opts := &Options{FS: fs, FormatMajorVersion: fmv, Logger: testLogger{t}}
+		opts.Experimental.EnableColumnarBlocks = func() bool { return true }
+		d, err := Open("", opts)
+		require.NoError(t, err)
+		return d
+	}
+	tableFormats := func(d *DB) map[sstable.TableFormat]int {
+		formats := make(map[sstable.TableFormat]int)
+		d.mu.Lock()
+		v := d.mu.versions.currentVersion()
+		v.Ref()
+		d.mu.Unlock()
+		defer func() {
+			d.mu.Lock()
+			v.UnrefLocked()
+			d.mu.Unlock()
+		}()
+		for l := range v.Levels {
+			for f := range v.Levels[l].All() {
+				format, err := d.tableFormatOf(f)
+				require.NoError(t, err)
+				formats[format]++
+			}
+		}
+		return formats
+	}
+
+	d := open(FormatWALSyncChunks)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("a%03d", i)), []byte("v1"), nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatTableFormatV6))
+	for i := 0; i < 100; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("b%03d", i)), []byte("v2"), nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Excise(context.Background(), KeyRange{Start: []byte("b010"), End: []byte("b020")}))
+	require.Equal(t, map[sstable.TableFormat]int{
+		sstable.TableFormatPebblev5: 1,
+		sstable.TableFormatPebblev6: 2,
+	}, tableFormats(d))
+	require.Equal(t, uint64(2), d.Metrics().NumVirtual())
+
+	// FormatWALSyncChunks changes the WAL format; it can't be rolled back.
+	require.True(t, errors.Is(d.RollbackFormatMajorVersion(FormatFlushableIngestExcises), ErrFormatMajorVersionRollbackUnsupported))
+	require.Error(t, d.RollbackFormatMajorVersion(internalFormatNewest+1))
+	require.Equal(t, FormatTableFormatV6, d.FormatMajorVersion())
+
+	require.NoError(t, d.RollbackFormatMajorVersion(FormatWALSyncChunks))
+	require.Equal(t, FormatWALSyncChunks, d.FormatMajorVersion())
+	require.Equal(t, sstable.TableFormatPebblev5, d.TableFormat())
+	require.Equal(t, map[sstable.TableFormat]int{sstable.TableFormatPebblev5: 3}, tableFormats(d))
+	require.Zero(t, d.Metrics().NumVirtual())
+	require.NoError(t, d.Close())
+
+	// The rolled back version is persisted.
+	d = open(FormatWALSyncChunks)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.Equal(t, FormatWALSyncChunks, d.FormatMajorVersion())
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	n := 0
+	for valid := iter.First(); valid; valid = iter.Next() {
+		n++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, 190, n)
+}
+
+func TestRollbackFormatMajorVersionInFlight(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem(), FormatMajorVersion: FormatTableFormatV6, Logger: testLogger{t}}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// An ingestion that validated its sstables against FormatTableFormatV6
+	// may still add a Pebblev6 table; the rollback waits for it.
+	fmv := d.beginIngestFormatVers()
+	errCh := make(chan error, 1)
+	go func() { errCh <- d.RollbackFormatMajorVersion(FormatWALSyncChunks) }()
+	select {
+	case err := <-errCh:
+		t.Fatalf("rollback finished with an ingestion in progress: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	d.endIngestFormatVers(fmv)
+	require.NoError(t, <-errCh)
+	require.Equal(t, FormatWALSyncChunks, d.FormatMajorVersion())
+}
+
+func TestRollbackFormatMajorVersionManifestEntries(t *testing.T) {
+	// The format major versions that gate MANIFEST entries can't be rolled
+	// back.
+	for _, fmv := range []FormatMajorVersion{FormatInlineTables, FormatNamedSnapshots} {
+		d, err := Open("", &Options{FS: vfs.NewMem(), FormatMajorVersion: fmv})
+		require.NoError(t, err)
+		require.Error(t, d.RollbackFormatMajorVersion(FormatTableFormatV6))
+		require.Equal(t, fmv, d.FormatMajorVersion())
+		require.NoError(t, d.Close())
+	}
+}
+
+func TestRollbackFormatMajorVersionSupported(t *testing.T) {
+	// Rolling back a single version only succeeds for the versions that only
+	// raise the maximum table format.
+	for v := FormatMinSupported + 1; v <= internalFormatNewest; v++ {
+		t.Run(v.String(), func(t *testing.T) {
+			d, err := Open("", &Options{FS: vfs.NewMem(), FormatMajorVersion: v, Logger: testLogger{t}})
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+			err = d.RollbackFormatMajorVersion(v - 1)
+			switch v {
+			case FormatColumnarBlocks, FormatTableFormatV6:
+				require.NoError(t, err)
+				require.Equal(t, v-1, d.FormatMajorVersion())
+			default:
+				require.True(t, errors.Is(err, ErrFormatMajorVersionRollbackUnsupported), "%v", err)
+				require.Equal(t, fmt.Sprintf("pebble: format major version %s cannot be rolled back", v), err.Error())
+				require.Equal(t, v, d.FormatMajorVersion())
+			}
+		})
+	}
+
+	// Versions outside of [FormatMinSupported, current] are refused with a
+	// different error.
+	d, err := Open("", &Options{FS: vfs.NewMem(), FormatMajorVersion: FormatTableFormatV6})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	for _, v := range []FormatMajorVersion{FormatMinSupported - 1, FormatTableFormatV6 + 1} {
+		err := d.RollbackFormatMajorVersion(v)
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrFormatMajorVersionRollbackUnsupported))
+	}
+	require.Equal(t, FormatTableFormatV6, d.FormatMajorVersion())
+}
+
 func testBasicDB(d *DB) error {
 	key := []byte("a")
 	value := []byte("b")
@@ -225,6 +387,12 @@ func TestFormatMajorVersions_TableFormat(t *testing.T) {
 		FormatColumnarBlocks:             {sstable.TableFormatPebblev1, sstable.TableFormatPebblev5},
 		FormatWALSyncChunks:              {sstable.TableFormatPebblev1, sstable.TableFormatPebblev5},
 		FormatTableFormatV6:              {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatBlobFileFormatV2:           {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatContentPrefix:              {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatInlineTables:               {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatNamedSnapshots:             {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatSuffixReplacements:         {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
+		FormatWALBatchCompression:        {sstable.TableFormatPebblev1, sstable.TableFormatPebblev6},
 	}
 
 	// Valid versions.
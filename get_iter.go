@@ -19,6 +19,7 @@ import (
 // internalIterator, but specialized for Get operations so that it loads data
 // lazily.
 type getIter struct {
+	ctx      context.Context
 	comparer *Comparer
 	newIters tableNewIters
 	snapshot base.SeqNum
@@ -32,6 +33,13 @@ type getIter struct {
 	l0       []manifest.LevelSlice
 	version  *version
 	iterKV   *base.InternalKV
+	// access, if non-nil, records the sstables consulted by this Get as
+	// accessed. It is set for a sample of Gets.
+	access *tableAccessTracker
+	// sampleReads is set for a sample of Gets, whose consulted sstables are
+	// recorded in readSample for read triggered compactions.
+	sampleReads bool
+	readSample  readSample
 	// tombstoned and tombstonedSeqNum track whether the key has been deleted by
 	// a range delete tombstone. The first visible (at getIter.snapshot) range
 	// deletion encounterd transitions tombstoned to true. The tombstonedSeqNum
@@ -160,7 +168,9 @@ func (g *getIter) SetBounds(lower, upper []byte) {
 	panic("pebble: SetBounds unimplemented")
 }
 
-func (g *getIter) SetContext(_ context.Context) {}
+func (g *getIter) SetContext(ctx context.Context) {
+	g.ctx = ctx
+}
 
 // DebugTree is part of the InternalIterator interface.
 func (g *getIter) DebugTree(tp treeprinter.Node) {
@@ -270,8 +280,14 @@ func (g *getIter) getSSTableIterators(
 		return emptyIter, nil, nil
 	}
 	// m may possibly contain point (or range deletion) keys relevant to g.key.
+	if g.access != nil {
+		g.access.record(m, level.Level())
+	}
+	if g.sampleReads {
+		g.readSample.add(m, level.Level())
+	}
 	g.iterOpts.layer = level
-	iters, err := g.newIters(context.Background(), m, &g.iterOpts, internalIterOpts{}, iterPointKeys|iterRangeDeletions)
+	iters, err := g.newIters(g.ctx, m, &g.iterOpts, internalIterOpts{}, iterPointKeys|iterRangeDeletions)
 	if err != nil {
 		return emptyIter, nil, err
 	}
@@ -447,6 +447,7 @@ func TestGetIter(t *testing.T) {
 			}
 
 			get := &buf.get
+			get.ctx = context.Background()
 			get.comparer = testkeys.Comparer
 			get.newIters = newIter
 			get.key = ikey.UserKey
@@ -0,0 +1,110 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "github.com/cockroachdb/pebble/internal/hotkeys"
+
+// defaultHotKeysSampleRate is the default of HotKeysOptions.SampleRate.
+const defaultHotKeysSampleRate = 100
+
+// HotKeysOptions configures the detection of the most frequently read and
+// written keys, reported by DB.HotKeys. The detection samples the keys looked
+// up by Get, the keys iterators are positioned at by seeks, and the keys of
+// the batches applied to the DB. Their frequencies are estimated with a
+// count-min sketch, and the most frequent ones are tracked in a top-K heap;
+// the frequencies are periodically halved so that they reflect the recent
+// accesses.
+type HotKeysOptions struct {
+	// TopK is the number of keys that are tracked. Hot key detection is
+	// disabled if TopK is not positive.
+	TopK int
+	// SampleRate is the rate at which the accesses are sampled: one in
+	// SampleRate accesses is recorded. It defaults to 100.
+	SampleRate int
+	// KeepHotTablesLocal, if set, prevents remote tiering (see
+	// RemoteTieringMinAge) from moving tables that contain hot keys onto shared
+	// storage.
+	KeepHotTablesLocal bool
+}
+
+// HotKey is a frequently accessed key reported by DB.HotKeys.
+type HotKey struct {
+	Key []byte
+	// Reads is the estimated number of recent reads of the key (through Get or
+	// iterator seeks).
+	Reads uint64
+	// Writes is the estimated number of recent writes of the key.
+	Writes uint64
+}
+
+// HotKeys returns the most frequently read and written keys, in decreasing
+// order of their estimated number of accesses. It returns nil if hot key
+// detection is disabled; see Options.Experimental.HotKeys.
+func (d *DB) HotKeys() []HotKey {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.hotKeys == nil {
+		return nil
+	}
+	top := d.hotKeys.Top()
+	keys := make([]HotKey, len(top))
+	for i := range top {
+		keys[i] = HotKey{Key: top[i].Key, Reads: top[i].Reads, Writes: top[i].Writes}
+	}
+	return keys
+}
+
+// recordHotKeyWrites records the writes of the keys of the batch for hot key
+// detection. The batch is only read if one of its writes is sampled. Range
+// deletions and range keys are not recorded (but count towards the sampling).
+func (d *DB) recordHotKeyWrites(b *Batch) {
+	next := d.hotKeys.SampleN(int(b.Count()))
+	if next < 0 {
+		return
+	}
+	rate := d.hotKeys.SampleRate()
+	i := 0
+	for r := b.Reader(); ; {
+		kind, ukey, _, ok, err := r.Next()
+		if !ok || err != nil {
+			return
+		}
+		if kind == InternalKeyKindLogData {
+			// LogData records aren't counted by Batch.Count.
+			continue
+		}
+		if i == next {
+			switch kind {
+			case InternalKeyKindRangeDelete, InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset,
+				InternalKeyKindRangeKeyDelete:
+			default:
+				d.hotKeys.RecordSampled(hotkeys.Write, ukey)
+			}
+			next += rate
+		}
+		i++
+	}
+}
+
+// containsHotKey returns true if the table contains one of the given hot keys,
+// as far as its bounds tell.
+func (d *DB) containsHotKey(f *tableMetadata, hotKeys []hotkeys.Key) bool {
+	bounds := f.UserKeyBounds()
+	for _, k := range hotKeys {
+		if bounds.ContainsUserKey(d.cmp, k.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordHotKeyRead records a read of the key for hot key detection, if it's
+// enabled.
+func recordHotKeyRead(t *hotkeys.Tracker, key []byte) {
+	if t != nil {
+		t.Record(hotkeys.Read, key)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotKeys(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	require.Nil(t, d.HotKeys())
+	require.NoError(t, d.Close())
+
+	opts := &Options{FS: vfs.NewMem()}
+	opts.Experimental.HotKeys = HotKeysOptions{TopK: 2, SampleRate: 1}
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("cold%03d", i)), nil, nil))
+		require.NoError(t, d.Set([]byte("written"), nil, nil))
+	}
+	for i := 0; i < 50; i++ {
+		_, closer, err := d.Get([]byte("read"))
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Nil(t, closer)
+
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		iter.SeekGE([]byte("read"))
+		require.NoError(t, iter.Close())
+	}
+
+	// Keys with the same frequencies are ordered by key.
+	require.Equal(t, []HotKey{
+		{Key: []byte("read"), Reads: 100},
+		{Key: []byte("written"), Writes: 100},
+	}, d.HotKeys())
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// idempotencyKeyLogDataPrefix prefixes the LogData record through which the
+// idempotency key of a batch is persisted in the WAL.
+const idempotencyKeyLogDataPrefix = "\x00pebble.idempotency-key\x00"
+
+// defaultIdempotencyWindow is the default value of Options.IdempotencyWindow.
+const defaultIdempotencyWindow = 10000
+
+// SetIdempotencyKey sets the idempotency key of the batch. If a batch with the
+// same idempotency key was recently applied to the DB, applying this batch is a
+// no-op: the batch is not written, and Batch.SeqNum returns the sequence number
+// of the original batch. This allows writers to retry applying a batch (e.g.
+// after an RPC timeout) without risking applying it twice. If the original
+// batch is still being applied, Apply waits for it to complete. If the
+// WriteOptions request a sync, Apply syncs the WAL before returning so that the
+// original batch is durable.
+//
+// The DB remembers the idempotency keys of the last Options.IdempotencyWindow
+// batches applied. The key is persisted in the WAL as a LogData record, so that
+// the keys of batches replayed from the WAL when the DB is opened are
+// remembered too. WAL readers observe the record. The keys of batches that were
+// flushed before the DB was closed are forgotten.
+//
+// SetIdempotencyKey must be called at most once, before the batch is
+// committed. It is safe to modify the contents of the key after
+// SetIdempotencyKey returns.
+func (b *Batch) SetIdempotencyKey(key []byte) error {
+	if len(key) == 0 {
+		return errors.New("pebble: empty idempotency key")
+	}
+	if b.idempotencyKey != nil {
+		return errors.New("pebble: batch idempotency key already set")
+	}
+	data := make([]byte, 0, len(idempotencyKeyLogDataPrefix)+len(key))
+	data = append(data, idempotencyKeyLogDataPrefix...)
+	data = append(data, key...)
+	if err := b.LogData(data, nil); err != nil {
+		return err
+	}
+	b.idempotencyKey = slices.Clone(key)
+	return nil
+}
+
+// IdempotencyKey returns the idempotency key of the batch set through
+// SetIdempotencyKey, or nil if the batch has no idempotency key.
+func (b *Batch) IdempotencyKey() []byte {
+	return b.idempotencyKey
+}
+
+// idempotencyWindow remembers the sequence numbers of the most recently
+// applied batches with an idempotency key.
+type idempotencyWindow struct {
+	size int
+
+	mu sync.Mutex
+	// keys maps idempotency keys to the batches applying them.
+	keys map[string]*idempotencyEntry
+	// order is a ring buffer of the keys of applied batches, from oldest to
+	// newest starting at index start.
+	order []string
+	start int
+}
+
+type idempotencyEntry struct {
+	// seqNum is the sequence number of the batch. It is set once applied is
+	// true.
+	seqNum  base.SeqNum
+	applied bool
+	// done is closed once the batch has been applied, or failed to be.
+	done chan struct{}
+}
+
+func (w *idempotencyWindow) init(size int) {
+	w.size = size
+	w.keys = make(map[string]*idempotencyEntry)
+}
+
+// begin is called before applying a batch with the given idempotency key. If a
+// batch with the same key was applied, it returns dup=true and the sequence
+// number of that batch. Otherwise, it returns an entry that must be passed to
+// finish once the batch has been applied, or failed to be. If a batch with the
+// same key is being applied concurrently, begin waits for it to complete.
+func (w *idempotencyWindow) begin(key []byte) (e *idempotencyEntry, seqNum base.SeqNum, dup bool) {
+	for {
+		w.mu.Lock()
+		existing, ok := w.keys[string(key)]
+		if !ok {
+			e = &idempotencyEntry{done: make(chan struct{})}
+			w.keys[string(key)] = e
+			w.mu.Unlock()
+			return e, 0, false
+		}
+		if existing.applied {
+			w.mu.Unlock()
+			return nil, existing.seqNum, true
+		}
+		w.mu.Unlock()
+		<-existing.done
+	}
+}
+
+// finish completes an entry returned by begin. If applied is false, the key is
+// forgotten, allowing the batch to be retried.
+func (w *idempotencyWindow) finish(key []byte, e *idempotencyEntry, seqNum base.SeqNum, applied bool) {
+	w.mu.Lock()
+	if applied {
+		e.seqNum = seqNum
+		e.applied = true
+		w.appendLocked(string(key))
+	} else {
+		delete(w.keys, string(key))
+	}
+	w.mu.Unlock()
+	close(e.done)
+}
+
+// record remembers the key of a batch replayed from the WAL.
+func (w *idempotencyWindow) record(key []byte, seqNum base.SeqNum) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if e, ok := w.keys[string(key)]; ok {
+		e.seqNum = seqNum
+		return
+	}
+	done := make(chan struct{})
+	close(done)
+	w.keys[string(key)] = &idempotencyEntry{seqNum: seqNum, applied: true, done: done}
+	w.appendLocked(string(key))
+}
+
+func (w *idempotencyWindow) appendLocked(key string) {
+	if w.size <= 0 {
+		delete(w.keys, key)
+		return
+	}
+	if len(w.order) < w.size {
+		w.order = append(w.order, key)
+		return
+	}
+	// Forget the oldest key.
+	delete(w.keys, w.order[w.start])
+	w.order[w.start] = key
+	w.start = (w.start + 1) % len(w.order)
+}
+
+// recordBatch remembers the idempotency key, if any, of a batch replayed from
+// the WAL.
+func (w *idempotencyWindow) recordBatch(b *Batch, seqNum base.SeqNum) error {
+	if w.size <= 0 {
+		// Keys aren't remembered; don't bother scanning the batch.
+		return nil
+	}
+	for r := b.Reader(); ; {
+		kind, data, _, ok, err := r.Next()
+		if !ok {
+			return err
+		}
+		if kind == InternalKeyKindLogData && bytes.HasPrefix(data, []byte(idempotencyKeyLogDataPrefix)) {
+			w.record(data[len(idempotencyKeyLogDataPrefix):], seqNum)
+			return nil
+		}
+	}
+}
+
+// applyDuplicate completes the application of a batch whose idempotency key
+// matches that of a batch which was already applied with the given sequence
+// number.
+func (d *DB) applyDuplicate(b *Batch, seqNum base.SeqNum, sync bool) error {
+	if sync {
+		// The original batch may not have been synced. Sync the WAL to ensure
+		// it's durable.
+		if err := d.LogData(nil, Sync); err != nil {
+			return err
+		}
+	}
+	b.setSeqNum(seqNum)
+	b.applied.Store(true)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchIdempotencyKey(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{FS: mem, IdempotencyWindow: 2}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	apply := func(id, value string) *Batch {
+		b := d.NewBatch()
+		require.NoError(t, b.Set([]byte("k"), []byte(value), nil))
+		require.NoError(t, b.SetIdempotencyKey([]byte(id)))
+		require.NoError(t, d.Apply(b, Sync))
+		return b
+	}
+	requireValue := func(expected string) {
+		t.Helper()
+		v, closer, err := d.Get([]byte("k"))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(v))
+		require.NoError(t, closer.Close())
+	}
+
+	b := d.NewBatch()
+	require.Error(t, b.SetIdempotencyKey(nil))
+	require.NoError(t, b.SetIdempotencyKey([]byte("a")))
+	require.Error(t, b.SetIdempotencyKey([]byte("a")))
+	require.NoError(t, b.Close())
+
+	b1 := apply("a", "1")
+	seqNum := b1.SeqNum()
+	require.NoError(t, b1.Close())
+
+	// Applying a batch with the same key is a no-op returning the original
+	// sequence number.
+	b2 := apply("a", "2")
+	require.Equal(t, seqNum, b2.SeqNum())
+	require.NoError(t, b2.Close())
+	requireValue("1")
+
+	// The keys are remembered across restarts.
+	require.NoError(t, d.Close())
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	b3 := apply("a", "3")
+	require.Equal(t, seqNum, b3.SeqNum())
+	require.NoError(t, b3.Close())
+	requireValue("1")
+
+	// Only the keys of the last IdempotencyWindow batches are remembered.
+	require.NoError(t, apply("b", "4").Close())
+	require.NoError(t, apply("c", "5").Close())
+	requireValue("5")
+	require.NoError(t, apply("a", "6").Close())
+	requireValue("6")
+	require.NoError(t, apply("c", "7").Close())
+	requireValue("6")
+
+	// A negative window disables remembering the keys, including those of
+	// the batches replayed from the WAL.
+	require.NoError(t, d.Close())
+	opts.IdempotencyWindow = -1
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	require.NoError(t, apply("c", "8").Close())
+	requireValue("8")
+	require.NoError(t, apply("c", "9").Close())
+	requireValue("9")
+}
@@ -5,12 +5,15 @@
 package pebble
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"io"
 	"slices"
 	"sort"
 	"time"
 
+	"github.com/cockroachdb/crlib/crtime"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
@@ -89,12 +92,14 @@ func (k *KeyRange) OverlapsKeyRange(cmp Compare, span KeyRange) bool {
 	return cmp(k.Start, span.End) < 0 && cmp(k.End, span.Start) > 0
 }
 
-func ingestValidateKey(opts *Options, key *InternalKey) error {
+// ingestValidateKey validates a key of an ingested table. Unless allowSeqNums
+// is set, the key must have a zero sequence number.
+func ingestValidateKey(opts *Options, key *InternalKey, allowSeqNums bool) error {
 	if key.Kind() == InternalKeyKindInvalid {
 		return base.CorruptionErrorf("pebble: external sstable has corrupted key: %s",
 			key.Pretty(opts.Comparer.FormatKey))
 	}
-	if key.SeqNum() != 0 {
+	if key.SeqNum() != 0 && !allowSeqNums {
 		return base.CorruptionErrorf("pebble: external sstable has non-zero seqnum: %s",
 			key.Pretty(opts.Comparer.FormatKey))
 	}
@@ -201,6 +206,13 @@ func ingestLoad1External(
 	if n := opts.Comparer.Split(e.EndKey); n != len(e.EndKey) {
 		return nil, errors.Newf("pebble: external file bounds end key %q has suffix", e.EndKey)
 	}
+	if policy := opts.Experimental.IngestKeyTypePolicy; policy != nil && e.HasRangeKey {
+		bounds := base.UserKeyBoundsEndExclusiveIf(e.StartKey, e.EndKey, !e.EndKeyIsInclusive)
+		if !policy.rangeKeysAllowed(bounds) {
+			return nil, errors.Wrapf(ErrIngestKeyTypeNotAllowed, "external file with range keys %s",
+				bounds.Format(opts.Comparer.FormatKey))
+		}
+	}
 
 	// Don't load table stats. Doing a round trip to shared storage, one SST
 	// at a time is not worth it as it slows down ingestion.
@@ -241,6 +253,24 @@ func ingestLoad1External(
 	}
 
 	meta.SyntheticPrefixAndSuffix = sstable.MakeSyntheticPrefixAndSuffix(e.SyntheticPrefix, e.SyntheticSuffix)
+	if len(e.ContentPrefix) > 0 {
+		meta.ContentPrefix = slices.Clone(e.ContentPrefix)
+	}
+	if len(e.SuffixReplacements) > 0 {
+		if e.HasRangeKey {
+			return nil, errors.New("pebble: suffix replacements are not supported for files with range keys")
+		}
+		if len(e.SyntheticSuffix) > 0 {
+			return nil, errors.New("pebble: cannot use both a synthetic suffix and suffix replacements")
+		}
+		if err := validateSuffixReplacements(opts.Comparer, e.SuffixReplacements); err != nil {
+			return nil, err
+		}
+		meta.SuffixReplacements = make(sstable.SuffixReplacements, len(e.SuffixReplacements))
+		for i, r := range e.SuffixReplacements {
+			meta.SuffixReplacements[i] = SuffixReplacement{From: slices.Clone(r.From), To: slices.Clone(r.To)}
+		}
+	}
 
 	return meta, nil
 }
@@ -299,6 +329,10 @@ func (r *rangeKeyIngestValidator) Validate(nextFileSmallestKey *keyspan.Span) er
 // prevLastRangeKey is the last range key from the previous file. It is used to
 // ensure that the range keys defragment cleanly across files. These checks
 // are disabled if disableRangeKeyChecks is true.
+//
+// If allowSeqNums is set, the keys of the table may have non-zero sequence
+// numbers, which are overridden by the ingestion sequence number. See
+// DB.AdoptFiles.
 func ingestLoad1(
 	ctx context.Context,
 	opts *Options,
@@ -307,6 +341,7 @@ func ingestLoad1(
 	cacheHandle *cache.Handle,
 	fileNum base.FileNum,
 	rangeKeyValidator rangeKeyIngestValidator,
+	allowSeqNums bool,
 ) (meta *tableMetadata, lastRangeKey keyspan.Span, err error) {
 	o := opts.MakeReaderOptions()
 	o.CacheOpts = sstableinternal.CacheOptions{
@@ -341,6 +376,11 @@ func ingestLoad1(
 		return nil, keyspan.Span{}, errors.Newf(
 			"pebble: ingesting tables with blob references is not supported")
 	}
+	if policy := opts.Experimental.IngestKeyTypePolicy; policy != nil {
+		if err := ingestValidateKeyTypes(ctx, opts, policy, r); err != nil {
+			return nil, keyspan.Span{}, err
+		}
+	}
 
 	meta = &tableMetadata{}
 	meta.FileNum = fileNum
@@ -357,7 +397,7 @@ func ingestLoad1(
 	// disallowing removal of an open file. Under MemFS, if we don't populate
 	// meta.Stats here, the file will be loaded into the file cache for
 	// calculating stats before we can remove the original link.
-	maybeSetStatsFromProperties(meta.PhysicalMeta(), &r.Properties)
+	maybeSetStatsFromProperties(meta.PhysicalMeta(), &r.Properties, opts.UserPropertyAggregators)
 
 	{
 		iter, err := r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */)
@@ -367,7 +407,7 @@ func ingestLoad1(
 		defer iter.Close()
 		var smallest InternalKey
 		if kv := iter.First(); kv != nil {
-			if err := ingestValidateKey(opts, &kv.K); err != nil {
+			if err := ingestValidateKey(opts, &kv.K, allowSeqNums); err != nil {
 				return nil, keyspan.Span{}, err
 			}
 			smallest = kv.K.Clone()
@@ -376,7 +416,7 @@ func ingestLoad1(
 			return nil, keyspan.Span{}, err
 		}
 		if kv := iter.Last(); kv != nil {
-			if err := ingestValidateKey(opts, &kv.K); err != nil {
+			if err := ingestValidateKey(opts, &kv.K, allowSeqNums); err != nil {
 				return nil, keyspan.Span{}, err
 			}
 			meta.ExtendPointKeyBounds(opts.Comparer.Compare, smallest, kv.K.Clone())
@@ -397,7 +437,7 @@ func ingestLoad1(
 			return nil, keyspan.Span{}, err
 		} else if s != nil {
 			key := s.SmallestKey()
-			if err := ingestValidateKey(opts, &key); err != nil {
+			if err := ingestValidateKey(opts, &key, allowSeqNums); err != nil {
 				return nil, keyspan.Span{}, err
 			}
 			smallest = key.Clone()
@@ -406,7 +446,7 @@ func ingestLoad1(
 			return nil, keyspan.Span{}, err
 		} else if s != nil {
 			k := s.SmallestKey()
-			if err := ingestValidateKey(opts, &k); err != nil {
+			if err := ingestValidateKey(opts, &k, allowSeqNums); err != nil {
 				return nil, keyspan.Span{}, err
 			}
 			largest := s.LargestKey().Clone()
@@ -427,7 +467,7 @@ func ingestLoad1(
 				return nil, keyspan.Span{}, err
 			} else if s != nil {
 				key := s.SmallestKey()
-				if err := ingestValidateKey(opts, &key); err != nil {
+				if err := ingestValidateKey(opts, &key, allowSeqNums); err != nil {
 					return nil, keyspan.Span{}, err
 				}
 				smallest = key.Clone()
@@ -442,7 +482,7 @@ func ingestLoad1(
 				return nil, keyspan.Span{}, err
 			} else if s != nil {
 				k := s.SmallestKey()
-				if err := ingestValidateKey(opts, &k); err != nil {
+				if err := ingestValidateKey(opts, &k, allowSeqNums); err != nil {
 					return nil, keyspan.Span{}, err
 				}
 				// As range keys are fragmented, the end key of the last range key in
@@ -478,20 +518,47 @@ type ingestLoadResult struct {
 	external []ingestExternalMeta
 
 	externalFilesHaveLevel bool
+	// splitOverlappingLocal is set if some of the local sstables overlap each
+	// other, and are to be split when applied. See
+	// Options.Experimental.IngestSplitOverlappingInputs.
+	splitOverlappingLocal bool
+	// targetLevel, if positive, is the level the local sstables must be
+	// ingested into (see IngestOptions.TargetLevel).
+	targetLevel int
 }
 
 type ingestLocalMeta struct {
 	*tableMetadata
-	path string
+	// input is the index of the sstable in the paths or streams passed to the
+	// ingestion.
+	input int
+	path  string
+	// streamed is set if the sstable was read from a stream and written
+	// directly to an object of the provider (see DB.IngestStreams); path is
+	// unset.
+	streamed bool
+	// overlap, if valid, is the span of the sstable that overlaps other local
+	// sstables of the ingestion. See ingestFindLocalOverlaps.
+	overlap KeyRange
+	// overlapsInputs is set for the part of a split sstable that overlaps other
+	// local sstables of the ingestion, which is ingested into L0. See
+	// DB.ingestSplitOverlappingLocal.
+	overlapsInputs bool
 }
 
 type ingestSharedMeta struct {
 	*tableMetadata
+	// input is the index of the sstable in the shared sstables passed to the
+	// ingestion.
+	input  int
 	shared SharedSSTMeta
 }
 
 type ingestExternalMeta struct {
 	*tableMetadata
+	// input is the index of the sstable in the external files passed to the
+	// ingestion.
+	input    int
 	external ExternalFile
 	// usedExistingBacking is true if the external file is reusing a backing
 	// that existed before this ingestion. In this case, we called
@@ -513,6 +580,7 @@ func ingestLoad(
 	external []ExternalFile,
 	cacheHandle *cache.Handle,
 	pending []base.FileNum,
+	allowSeqNums bool,
 ) (ingestLoadResult, error) {
 	localFileNums := pending[:len(paths)]
 	sharedFileNums := pending[len(paths) : len(paths)+len(shared)]
@@ -544,13 +612,14 @@ func ingestLoad(
 		if !shouldDisableRangeKeyChecks {
 			rangeKeyValidator = validateSuffixedBoundaries(opts.Comparer, lastRangeKey)
 		}
-		m, lastRangeKey, err = ingestLoad1(ctx, opts, fmv, readable, cacheHandle, localFileNums[i], rangeKeyValidator)
+		m, lastRangeKey, err = ingestLoad1(ctx, opts, fmv, readable, cacheHandle, localFileNums[i], rangeKeyValidator, allowSeqNums)
 		if err != nil {
 			return ingestLoadResult{}, err
 		}
 		if m != nil {
 			result.local = append(result.local, ingestLocalMeta{
 				tableMetadata: m,
+				input:         i,
 				path:          paths[i],
 			})
 		}
@@ -577,6 +646,7 @@ func ingestLoad(
 		}
 		result.shared = append(result.shared, ingestSharedMeta{
 			tableMetadata: m,
+			input:         i,
 			shared:        shared[i],
 		})
 	}
@@ -588,6 +658,7 @@ func ingestLoad(
 		}
 		result.external = append(result.external, ingestExternalMeta{
 			tableMetadata: m,
+			input:         i,
 			external:      external[i],
 		})
 		if external[i].Level > 0 {
@@ -637,6 +708,15 @@ func ingestSortAndVerify(cmp Compare, lr ingestLoadResult, exciseSpan KeyRange)
 		}
 		return nil
 	}
+	if lr.splitOverlappingLocal {
+		// The local sstables are assigned sequence numbers in the order they
+		// were passed to the ingestion, so that the later ones take precedence
+		// where they overlap.
+		slices.SortFunc(lr.local, func(a, b ingestLocalMeta) int {
+			return a.input - b.input
+		})
+		return nil
+	}
 	if len(lr.local) <= 1 {
 		return nil
 	}
@@ -679,6 +759,55 @@ func ingestSortAndVerify(cmp Compare, lr ingestLoadResult, exciseSpan KeyRange)
 	return nil
 }
 
+// ingestFindLocalOverlaps sorts the local sstables by their smallest key and
+// sets the overlap span of those that overlap other local sstables, returning
+// true if any does. The overlap span of an sstable covers its intersections
+// with all the other sstables. Its bounds are aligned on key prefixes, so that
+// all the versions of a key fall on the same side of them.
+func ingestFindLocalOverlaps(comparer *base.Comparer, local []ingestLocalMeta) bool {
+	cmp := comparer.Compare
+	slices.SortFunc(local, func(a, b ingestLocalMeta) int {
+		return cmp(a.Smallest.UserKey, b.Smallest.UserKey)
+	})
+	bounds := make([]base.UserKeyBounds, len(local))
+	for i := range local {
+		bounds[i] = local[i].UserKeyBounds()
+	}
+	overlaps := make([]base.UserKeyBounds, len(local))
+	extend := func(i int, intersection base.UserKeyBounds) {
+		o := &overlaps[i]
+		if o.Start == nil || cmp(intersection.Start, o.Start) < 0 {
+			o.Start = intersection.Start
+		}
+		if o.End.Key == nil || o.End.CompareUpperBounds(cmp, intersection.End) < 0 {
+			o.End = intersection.End
+		}
+	}
+	found := false
+	for i := range local {
+		// The sstables are sorted by their start keys, so the sstables that
+		// overlap the i-th one and follow it are contiguous.
+		for j := i + 1; j < len(local) && bounds[j].Overlaps(cmp, &bounds[i]); j++ {
+			intersection := base.UserKeyBounds{Start: bounds[j].Start, End: bounds[i].End}
+			if bounds[j].End.CompareUpperBounds(cmp, bounds[i].End) < 0 {
+				intersection.End = bounds[j].End
+			}
+			extend(i, intersection)
+			extend(j, intersection)
+			found = true
+		}
+	}
+	for i := range local {
+		if o := overlaps[i]; o.Start != nil {
+			local[i].overlap = KeyRange{
+				Start: slices.Clone(o.Start[:comparer.Split(o.Start)]),
+				End:   comparer.ImmediateSuccessor(nil, o.End.Key[:comparer.Split(o.End.Key)]),
+			}
+		}
+	}
+	return found
+}
+
 func ingestCleanup(objProvider objstorage.Provider, meta []ingestLocalMeta) error {
 	var firstErr error
 	for i := range meta {
@@ -699,6 +828,10 @@ func ingestLinkLocal(
 	localMetas []ingestLocalMeta,
 ) error {
 	for i := range localMetas {
+		if localMetas[i].streamed {
+			// The object was created by ingestWriteStreams.
+			continue
+		}
 		objMeta, err := objProvider.LinkOrCopyFromLocal(
 			ctx, opts.FS, localMetas[i].path, base.FileTypeTable, localMetas[i].FileBacking.DiskFileNum,
 			objstorage.CreateOptions{PreferSharedStorage: true},
@@ -721,6 +854,109 @@ func ingestLinkLocal(
 	return nil
 }
 
+// ingestStreamBufferSize is the size of the chunks in which ingestWriteStreams
+// copies the streams to objects.
+const ingestStreamBufferSize = 256 << 10
+
+// ingestWriteStreams writes the sstables read from the streams to new objects
+// of the provider, with the given file numbers, and loads their metadata. The
+// objects of empty sstables are removed. If an error is returned, all the
+// objects written are removed.
+func (d *DB) ingestWriteStreams(
+	ctx context.Context, jobID JobID, streams []io.Reader, fileNums []base.FileNum,
+) ([]ingestLocalMeta, error) {
+	metas := make([]ingestLocalMeta, 0, len(streams))
+	fail := func(fileNum base.DiskFileNum, err error) ([]ingestLocalMeta, error) {
+		if err2 := d.objProvider.Remove(base.FileTypeTable, fileNum); err2 != nil && !d.objProvider.IsNotExistError(err2) {
+			d.opts.Logger.Errorf("ingest cleanup failed: %v", err2)
+		}
+		if err2 := ingestCleanup(d.objProvider, metas); err2 != nil {
+			d.opts.Logger.Errorf("ingest cleanup failed: %v", err2)
+		}
+		return nil, err
+	}
+	// See ingestLoad.
+	shouldDisableRangeKeyChecks := d.opts.Experimental.CreateOnShared != remote.CreateOnSharedNone
+	var lastRangeKey keyspan.Span
+	buf := make([]byte, ingestStreamBufferSize)
+	for i := range streams {
+		fileNum := base.PhysicalTableDiskFileNum(fileNums[i])
+		objMeta, err := d.ingestWriteStream(ctx, streams[i], fileNum, buf)
+		if err != nil {
+			return fail(fileNum, err)
+		}
+		readable, err := d.objProvider.OpenForReading(ctx, base.FileTypeTable, fileNum, objstorage.OpenOptions{MustExist: true})
+		if err != nil {
+			return fail(fileNum, err)
+		}
+		rangeKeyValidator := disableRangeKeyChecks()
+		if !shouldDisableRangeKeyChecks {
+			rangeKeyValidator = validateSuffixedBoundaries(d.opts.Comparer, lastRangeKey)
+		}
+		var m *tableMetadata
+		m, lastRangeKey, err = ingestLoad1(ctx, d.opts, d.FormatMajorVersion(), readable, d.cacheHandle, fileNums[i], rangeKeyValidator, false /* allowSeqNums */)
+		if err != nil {
+			return fail(fileNum, err)
+		}
+		if m == nil {
+			if err := d.objProvider.Remove(base.FileTypeTable, fileNum); err != nil {
+				return fail(fileNum, err)
+			}
+			continue
+		}
+		metas = append(metas, ingestLocalMeta{tableMetadata: m, input: i, streamed: true})
+		d.opts.EventListener.TableCreated(TableCreateInfo{
+			JobID:   int(jobID),
+			Reason:  "ingesting",
+			Path:    d.objProvider.Path(objMeta),
+			FileNum: fileNum,
+		})
+	}
+	if !shouldDisableRangeKeyChecks {
+		rangeKeyValidator := validateSuffixedBoundaries(d.opts.Comparer, lastRangeKey)
+		if err := rangeKeyValidator.Validate(nil /* nextFileSmallestKey */); err != nil {
+			if err2 := ingestCleanup(d.objProvider, metas); err2 != nil {
+				d.opts.Logger.Errorf("ingest cleanup failed: %v", err2)
+			}
+			return nil, err
+		}
+	}
+	return metas, nil
+}
+
+// ingestWriteStream copies the stream to a new object, using buf to read it.
+func (d *DB) ingestWriteStream(
+	ctx context.Context, r io.Reader, fileNum base.DiskFileNum, buf []byte,
+) (objstorage.ObjectMetadata, error) {
+	w, objMeta, err := d.objProvider.Create(ctx, base.FileTypeTable, fileNum, objstorage.CreateOptions{
+		PreferSharedStorage: true,
+	})
+	if err != nil {
+		return objstorage.ObjectMetadata{}, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			w.Abort()
+			return objstorage.ObjectMetadata{}, err
+		}
+		n, err := r.Read(buf)
+		if n > 0 {
+			// Write may modify buf, which is overwritten by the next Read.
+			if err := w.Write(buf[:n]); err != nil {
+				w.Abort()
+				return objstorage.ObjectMetadata{}, err
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			w.Abort()
+			return objstorage.ObjectMetadata{}, err
+		}
+	}
+	return objMeta, w.Finish()
+}
+
 // ingestAttachRemote attaches remote objects to the storage provider.
 //
 // For external objects, we reuse existing FileBackings from the current version
@@ -1077,6 +1313,50 @@ func ingestTargetLevel(
 	return targetLevel, splitFile, nil
 }
 
+// ingestCheckTargetLevel checks that a file being ingested can be placed in
+// the given level, which was requested by the caller (see
+// IngestOptions.TargetLevel), using the same criteria as ingestTargetLevel. If
+// the file has a boundary overlap with a file of the level, and suggestSplit
+// is true, that file is returned as the splitFile.
+func ingestCheckTargetLevel(
+	cmp base.Compare,
+	lsmOverlap overlap.WithLSM,
+	baseLevel int,
+	compactions map[*compaction]struct{},
+	meta *tableMetadata,
+	level int,
+	suggestSplit bool,
+) (splitFile *tableMetadata, err error) {
+	if level < baseLevel {
+		return nil, errors.Wrapf(ErrIngestTargetLevelUnsafe,
+			"L%d is above the base level L%d", errors.Safe(level), errors.Safe(baseLevel))
+	}
+	for l := 0; l <= level; l++ {
+		if lsmOverlap[l].Result == overlap.Data {
+			return nil, errors.Wrapf(ErrIngestTargetLevelUnsafe,
+				"%s overlaps data in L%d", meta.FileNum, errors.Safe(l))
+		}
+	}
+	if lsmOverlap[level].Result == overlap.OnlyBoundary {
+		if !suggestSplit || lsmOverlap[level].SplitFile == nil {
+			return nil, errors.Wrapf(ErrIngestTargetLevelUnsafe,
+				"%s overlaps the bounds of tables in L%d", meta.FileNum, errors.Safe(level))
+		}
+		splitFile = lsmOverlap[level].SplitFile
+	}
+	for c := range compactions {
+		if c.outputLevel == nil || level != c.outputLevel.level {
+			continue
+		}
+		if cmp(meta.Smallest.UserKey, c.largest.UserKey) <= 0 &&
+			cmp(meta.Largest.UserKey, c.smallest.UserKey) >= 0 {
+			return nil, errors.Wrapf(ErrIngestTargetLevelUnsafe,
+				"%s overlaps a compaction into L%d", meta.FileNum, errors.Safe(level))
+		}
+	}
+	return splitFile, nil
+}
+
 // Ingest ingests a set of sstables into the DB. Ingestion of the files is
 // atomic and semantically equivalent to creating a single batch containing all
 // of the mutations in the sstables. Ingestion may require the memtable to be
@@ -1144,7 +1424,7 @@ func (d *DB) Ingest(ctx context.Context, paths []string) error {
 	if d.opts.ReadOnly {
 		return ErrReadOnly
 	}
-	_, err := d.ingest(ctx, paths, nil /* shared */, KeyRange{}, nil /* external */)
+	_, err := d.ingest(ctx, paths, nil /* streams */, nil /* shared */, KeyRange{}, nil /* external */, false /* allowSeqNums */, IngestOptions{})
 	return err
 }
 
@@ -1162,6 +1442,57 @@ type IngestOperationStats struct {
 	// MemtableOverlappingFiles is the count of ingested sstables
 	// that overlapped keys in the memtables.
 	MemtableOverlappingFiles int
+	// BytesAsFlushable is the number of bytes in the sstables that were
+	// ingested as a flushable, i.e. queued behind the memtables they overlapped
+	// and added to the LSM when flushed.
+	BytesAsFlushable uint64
+	// BytesDirect is the number of bytes in the sstables that were added to
+	// the LSM directly.
+	BytesDirect uint64
+	// FlushWaitDuration is the time spent waiting for the flush of the
+	// memtables that overlapped the ingested sstables, when they couldn't be
+	// ingested as a flushable.
+	FlushWaitDuration time.Duration
+	// Tables describes where each of the ingested sstables was placed, and
+	// why. The local sstables (passed as paths or streams) are listed first,
+	// followed by the shared sstables and the external sstables, each in the
+	// order they were passed to the ingestion. Empty sstables, which aren't
+	// ingested, are omitted. An sstable that was split because it overlapped
+	// other sstables of the ingestion (see
+	// Options.Experimental.IngestSplitOverlappingInputs) has an entry for each
+	// of the tables it was split into.
+	Tables []IngestedTableStats
+}
+
+// IngestedTableStats describes the placement of an ingested sstable in the
+// LSM.
+type IngestedTableStats struct {
+	// TableInfo describes the table the sstable was ingested as, including its
+	// FileNum.
+	TableInfo
+	// Input is the index of the sstable in the paths, streams, shared sstables
+	// or external files passed to the ingestion.
+	Input int
+	// SeqNum is the sequence number assigned to the keys of the sstable.
+	SeqNum SeqNum
+	// Level is the level the sstable was ingested into, or -1 if it was ingested
+	// as a flushable (in which case its level is determined when it's flushed).
+	Level int
+	// AsFlushable is true if the sstable was ingested as a flushable, i.e.
+	// queued behind the memtables it overlapped and added to the LSM when
+	// they're flushed.
+	AsFlushable bool
+	// OverlapsMemtable is true if the sstable's bounds overlapped a memtable
+	// (or another flushable), forcing the ingestion to either be ingested as a
+	// flushable or wait for the memtable's flush.
+	OverlapsMemtable bool
+	// DataOverlapLevel is the highest level (i.e. with the lowest number) that
+	// contains data within the sstable's bounds, which prevented the sstable
+	// from being ingested below that level. It is -1 if no data overlap was
+	// encountered, or if the sstable's level wasn't determined by overlap
+	// checks (e.g. for shared and external sstables, and sstables ingested as
+	// a flushable).
+	DataOverlapLevel int
 }
 
 // ExternalFile are external sstables that can be referenced through
@@ -1207,6 +1538,18 @@ type ExternalFile struct {
 	// SyntheticPrefix must be a prefix of both Bounds.Start and Bounds.End.
 	SyntheticPrefix []byte
 
+	// ContentPrefix, if set, is stripped from all keys in the file during
+	// iteration, before SyntheticPrefix (which may be empty) is prepended. This
+	// allows the keys of the file to be remapped, for example from one tenant's
+	// keyspace to another's, without rewriting the backing file.
+	//
+	// ContentPrefix must be a prefix of every key in the backing file,
+	// including the end keys of range deletions and range keys (the entire
+	// sst, not just the part restricted to Bounds). Both ContentPrefix and
+	// SyntheticPrefix must be contained in the prefix of the keys, as returned
+	// by the Comparer's Split.
+	ContentPrefix []byte
+
 	// SyntheticSuffix will replace the suffix of every key in the file during
 	// iteration. Note that the file itself is not modified, rather, every key
 	// returned by an iterator will have the synthetic suffix.
@@ -1217,6 +1560,22 @@ type ExternalFile struct {
 	//  - the backing sst must not contain multiple keys with the same prefix.
 	SyntheticSuffix []byte
 
+	// SuffixReplacements, if set, re-stamp the keys of the file with different
+	// suffixes during iteration: the suffix of every key is replaced according
+	// to the replacement whose From is the key's suffix. This allows a file
+	// holding several versions of its keys to be logically re-stamped with
+	// several target suffixes without rewriting it.
+	//
+	// SuffixReplacements can only be used under the following conditions:
+	//  - the replacements are sorted by From, in the order of the Comparer's
+	//    ComparePointSuffixes, and the To suffixes are sorted in the same
+	//    order, so that the replacement preserves the ordering of the keys.
+	//  - every non-empty suffix of the point keys in the backing sst (the
+	//    entire sst, not just the part restricted to Bounds) is the From of a
+	//    replacement; keys without a suffix are left as is.
+	//  - the file has no range keys, and no SyntheticSuffix.
+	SuffixReplacements []SuffixReplacement
+
 	// Level denotes the level at which this file was present at read time
 	// if the external file was returned by a scan of an existing Pebble
 	// instance. If Level is 0, this field is ignored.
@@ -1232,7 +1591,71 @@ func (d *DB) IngestWithStats(ctx context.Context, paths []string) (IngestOperati
 	if d.opts.ReadOnly {
 		return IngestOperationStats{}, ErrReadOnly
 	}
-	return d.ingest(ctx, paths, nil, KeyRange{}, nil)
+	return d.ingest(ctx, paths, nil, nil, KeyRange{}, nil, false, IngestOptions{})
+}
+
+// IngestOptions configures an ingestion (see DB.IngestWithOptions).
+type IngestOptions struct {
+	// TargetLevel, if positive, is the level the sstables are ingested into,
+	// instead of the level determined automatically (see ingestTargetLevel).
+	// This allows a caller that knows the ingested data is cold to place it
+	// directly in L6, where the automatic placement may land it higher, e.g.
+	// because of a compaction in progress into L6.
+	//
+	// The placement must preserve the invariants of the LSM: no level up to and
+	// including TargetLevel may contain data within the bounds of an sstable
+	// (which would then shadow newer data), the sstable must not overlap the
+	// tables of TargetLevel (unless ingest-time splitting is enabled and can
+	// make room for it) or the outputs of the compactions in progress into
+	// TargetLevel, and TargetLevel must not be above the base level. An
+	// ingestion that overlaps the memtables first waits for their flush, whose
+	// output then overlaps the sstables in L0. If any sstable can't be placed
+	// in TargetLevel, the ingestion fails with an error wrapping
+	// ErrIngestTargetLevelUnsafe and has no effect, and may be retried without
+	// a TargetLevel.
+	TargetLevel int
+}
+
+// ErrIngestTargetLevelUnsafe is returned by DB.IngestWithOptions when an
+// sstable can't be ingested into IngestOptions.TargetLevel.
+var ErrIngestTargetLevelUnsafe = errors.New("pebble: sstable can't be ingested into the target level")
+
+// IngestWithOptions does the same as IngestWithStats, with the provided
+// options.
+func (d *DB) IngestWithOptions(
+	ctx context.Context, paths []string, opts IngestOptions,
+) (IngestOperationStats, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return IngestOperationStats{}, ErrReadOnly
+	}
+	if opts.TargetLevel < 0 || opts.TargetLevel >= numLevels {
+		return IngestOperationStats{}, errors.Newf("pebble: invalid ingest target level %d", opts.TargetLevel)
+	}
+	return d.ingest(ctx, paths, nil, nil, KeyRange{}, nil, false, opts)
+}
+
+// IngestStreams does the same as IngestWithStats, but the sstables are read
+// from streams rather than files: each stream is written directly to a new
+// object of the DB, on shared storage if Options.Experimental.CreateOnShared is
+// set, instead of being spilled to a local file to be linked. This allows
+// ingesting sstables that the caller is generating or receiving over the
+// network, without a temporary copy.
+//
+// The streams are read until io.EOF, in order. If the ingestion fails, the
+// objects written are removed.
+func (d *DB) IngestStreams(
+	ctx context.Context, streams []io.Reader,
+) (IngestOperationStats, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return IngestOperationStats{}, ErrReadOnly
+	}
+	return d.ingest(ctx, nil, streams, nil, KeyRange{}, nil, false, IngestOptions{})
 }
 
 // IngestExternalFiles does the same as IngestWithStats, and additionally
@@ -1252,7 +1675,7 @@ func (d *DB) IngestExternalFiles(
 	if d.opts.Experimental.RemoteStorage == nil {
 		return IngestOperationStats{}, errors.New("pebble: cannot ingest external files without shared storage configured")
 	}
-	return d.ingest(ctx, nil, nil, KeyRange{}, external)
+	return d.ingest(ctx, nil, nil, nil, KeyRange{}, external, false, IngestOptions{})
 }
 
 // IngestAndExcise does the same as IngestWithStats, and additionally accepts a
@@ -1291,7 +1714,7 @@ func (d *DB) IngestAndExcise(
 			v, FormatMinForSharedObjects,
 		)
 	}
-	return d.ingest(ctx, paths, shared, exciseSpan, external)
+	return d.ingest(ctx, paths, nil, shared, exciseSpan, external, false, IngestOptions{})
 }
 
 // Both DB.mu and commitPipeline.mu must be held while this is called.
@@ -1348,7 +1771,7 @@ func (d *DB) newIngestedFlushableEntry(
 		}
 	}
 
-	entry.flushForced = true
+	entry.forceFlush(FlushReasonIngestAsFlushable)
 	entry.releaseMemAccounting = func() {}
 	return entry, nil
 }
@@ -1429,17 +1852,32 @@ func (d *DB) handleIngestAsFlushable(
 	return nil
 }
 
-// See comment at Ingest() for details on how this works.
+// See comment at Ingest() for details on how this works. If allowSeqNums is
+// set, the keys of the local tables may have non-zero sequence numbers (see
+// ingestLoad1).
 func (d *DB) ingest(
 	ctx context.Context,
 	paths []string,
+	streams []io.Reader,
 	shared []SharedSSTMeta,
 	exciseSpan KeyRange,
 	external []ExternalFile,
+	allowSeqNums bool,
+	opts IngestOptions,
 ) (IngestOperationStats, error) {
 	if len(shared) > 0 && d.opts.Experimental.RemoteStorage == nil {
 		panic("cannot ingest shared sstables with nil SharedStorage")
 	}
+	if err := d.writesPausedErr(); err != nil {
+		return IngestOperationStats{}, err
+	}
+	// Ingesting local tables consumes disk space, like writes. Excises, which
+	// may reclaim disk space, are still accepted.
+	if len(paths)+len(streams) > 0 && d.diskSpaceLowForWrites() {
+		return IngestOperationStats{}, ErrDiskSpaceLow
+	}
+	fmv := d.beginIngestFormatVers()
+	defer d.endIngestFormatVers(fmv)
 	if (exciseSpan.Valid() || len(shared) > 0 || len(external) > 0) && d.FormatMajorVersion() < FormatVirtualSSTables {
 		return IngestOperationStats{}, errors.New("pebble: format major version too old for excise, shared or external sstable ingestion")
 	}
@@ -1453,13 +1891,27 @@ func (d *DB) ingest(
 			}
 		}
 	}
+	if len(external) > 0 && d.FormatMajorVersion() < FormatContentPrefix {
+		for i := range external {
+			if len(external[i].ContentPrefix) > 0 {
+				return IngestOperationStats{}, errors.New("pebble: format major version too old for content prefix ingestion")
+			}
+		}
+	}
+	if len(external) > 0 && d.FormatMajorVersion() < FormatSuffixReplacements {
+		for i := range external {
+			if len(external[i].SuffixReplacements) > 0 {
+				return IngestOperationStats{}, errors.New("pebble: format major version too old for suffix replacement ingestion")
+			}
+		}
+	}
 	// Allocate file numbers for all of the files being ingested and mark them as
 	// pending in order to prevent them from being deleted. Note that this causes
 	// the file number ordering to be out of alignment with sequence number
 	// ordering. The sorting of L0 tables by sequence number avoids relying on
 	// that (busted) invariant.
-	pendingOutputs := make([]base.FileNum, len(paths)+len(shared)+len(external))
-	for i := 0; i < len(paths)+len(shared)+len(external); i++ {
+	pendingOutputs := make([]base.FileNum, len(paths)+len(shared)+len(external)+len(streams))
+	for i := range pendingOutputs {
 		pendingOutputs[i] = d.mu.versions.getNextFileNum()
 	}
 
@@ -1467,18 +1919,41 @@ func (d *DB) ingest(
 
 	// Load the metadata for all the files being ingested. This step detects
 	// and elides empty sstables.
-	loadResult, err := ingestLoad(ctx, d.opts, d.FormatMajorVersion(), paths, shared, external, d.cacheHandle, pendingOutputs)
+	loadResult, err := ingestLoad(ctx, d.opts, fmv, paths, shared, external, d.cacheHandle, pendingOutputs, allowSeqNums)
 	if err != nil {
 		return IngestOperationStats{}, err
 	}
+	if len(streams) > 0 {
+		if len(paths) > 0 {
+			return IngestOperationStats{}, base.AssertionFailedf("pebble: cannot ingest both paths and streams")
+		}
+		streamed, err := d.ingestWriteStreams(ctx, jobID, streams, pendingOutputs[len(pendingOutputs)-len(streams):])
+		if err != nil {
+			return IngestOperationStats{}, err
+		}
+		loadResult.local = streamed
+	}
 
 	if loadResult.fileCount() == 0 && !exciseSpan.Valid() {
 		// All of the sstables to be ingested were empty. Nothing to do.
 		return IngestOperationStats{}, nil
 	}
 
-	// Verify the sstables do not overlap.
+	loadResult.targetLevel = opts.TargetLevel
+	if len(loadResult.shared) == 0 && len(loadResult.external) == 0 && loadResult.targetLevel == 0 &&
+		d.opts.Experimental.IngestSplitOverlappingInputs != nil &&
+		d.opts.Experimental.IngestSplitOverlappingInputs() && d.FormatMajorVersion() >= FormatVirtualSSTables {
+		loadResult.splitOverlappingLocal = ingestFindLocalOverlaps(d.opts.Comparer, loadResult.local)
+	}
+
+	// Verify the sstables do not overlap, unless they're to be split.
 	if err := ingestSortAndVerify(d.cmp, loadResult, exciseSpan); err != nil {
+		if len(streams) > 0 {
+			// The streamed sstables were already written to the provider.
+			if err2 := ingestCleanup(d.objProvider, loadResult.local); err2 != nil {
+				d.opts.Logger.Errorf("ingest cleanup failed: %v", err2)
+			}
+		}
 		return IngestOperationStats{}, err
 	}
 
@@ -1508,6 +1983,14 @@ func (d *DB) ingest(
 	// overlap some table in the flushable queue. It's used to approximate
 	// ingest-into-L0 stats when using flushable ingests.
 	metaFlushableOverlaps := make(map[base.FileNum]bool, loadResult.fileCount())
+	// dataOverlapLevels records, for the ingested sstables whose level was
+	// determined by checking for overlap with the LSM, the highest level with
+	// which their data overlapped.
+	dataOverlapLevels := make(map[base.FileNum]int)
+	// splitTables maps the local sstables that were split because they
+	// overlapped other local sstables to the tables they were split into.
+	splitTables := make(map[base.FileNum][]*tableMetadata)
+	var flushWaitDuration time.Duration
 	var mem *flushableEntry
 	var mut *memTable
 	// asFlushable indicates whether the sstable was ingested as a flushable.
@@ -1607,6 +2090,7 @@ func (d *DB) ingest(
 		canIngestFlushable := d.FormatMajorVersion() >= FormatFlushableIngest &&
 			(len(d.mu.mem.queue) < d.opts.MemTableStopWritesThreshold) &&
 			!d.opts.Experimental.DisableIngestAsFlushable() && !hasRemoteFiles &&
+			!loadResult.splitOverlappingLocal && loadResult.targetLevel == 0 &&
 			(!exciseSpan.Valid() || d.FormatMajorVersion() >= FormatFlushableIngestExcises)
 
 		if !canIngestFlushable {
@@ -1617,7 +2101,7 @@ func (d *DB) ingest(
 			// we cannot use flushable ingests and need
 			// to wait synchronously.
 			if mem.flushable == d.mu.mem.mutable {
-				err = d.makeRoomForWrite(nil)
+				err = d.makeRoomForWrite(nil, FlushReasonIngest)
 			}
 			// New writes with higher sequence numbers may be concurrently
 			// committed. We must ensure they don't flush before this ingest
@@ -1628,7 +2112,7 @@ func (d *DB) ingest(
 			// guaranteed that the flush won't edit the LSM before this ingest.
 			mut = d.mu.mem.mutable
 			mut.writerRef()
-			mem.flushForced = true
+			mem.forceFlush(FlushReasonIngest)
 			d.maybeScheduleFlush()
 			return
 		}
@@ -1644,6 +2128,11 @@ func (d *DB) ingest(
 
 	var ve *versionEdit
 	apply := func(seqNum base.SeqNum) {
+		if d.scanCache != nil {
+			// The ingested sstables and the excise are assigned the sequence
+			// numbers from seqNum on.
+			d.scanCache.invalidate(nil /* bounds */, seqNum+base.SeqNum(loadResult.fileCount()))
+		}
 		if err != nil || asFlushable {
 			// An error occurred during prepare.
 			if mut != nil {
@@ -1683,12 +2172,14 @@ func (d *DB) ingest(
 		// If we overlapped with a memtable in prepare wait for the flush to
 		// finish.
 		if mem != nil {
+			waitStart := crtime.NowMono()
 			<-mem.flushed
+			flushWaitDuration = waitStart.Elapsed()
 		}
 
 		// Assign the sstables to the correct level in the LSM and apply the
 		// version edit.
-		ve, err = d.ingestApply(ctx, jobID, loadResult, mut, exciseSpan, seqNum)
+		ve, err = d.ingestApply(ctx, jobID, loadResult, mut, exciseSpan, seqNum, dataOverlapLevels, splitTables)
 	}
 
 	// Only one ingest can occur at a time because if not, one would block waiting
@@ -1712,6 +2203,9 @@ func (d *DB) ingest(
 		// Since we either created a hard link to the ingesting files, or copied
 		// them over, it is safe to remove the originals paths.
 		for i := range loadResult.local {
+			if loadResult.local[i].streamed {
+				continue
+			}
 			path := loadResult.local[i].path
 			if err2 := d.opts.FS.Remove(path); err2 != nil {
 				d.opts.Logger.Errorf("ingest failed to remove original file: %s", err2)
@@ -1722,7 +2216,7 @@ func (d *DB) ingest(
 	// TODO(jackson): Refactor this so that the case where there are no files
 	// but a valid excise span is not so exceptional.
 
-	var stats IngestOperationStats
+	stats := IngestOperationStats{FlushWaitDuration: flushWaitDuration}
 	if loadResult.fileCount() > 0 {
 		info := TableIngestInfo{
 			JobID:     int(jobID),
@@ -1736,6 +2230,9 @@ func (d *DB) ingest(
 		} else {
 			info.GlobalSeqNum = loadResult.external[0].SmallestSeqNum
 		}
+		// levels maps the tables added by the ingestion to their level. It
+		// includes the tables produced by excises and ingest-time splits.
+		levels := make(map[base.FileNum]int)
 		if ve != nil {
 			info.Tables = make([]struct {
 				TableInfo
@@ -1746,12 +2243,14 @@ func (d *DB) ingest(
 				info.Tables[i].Level = e.Level
 				info.Tables[i].TableInfo = e.Meta.TableInfo()
 				stats.Bytes += e.Meta.Size
+				stats.BytesDirect += e.Meta.Size
 				if e.Level == 0 {
 					stats.ApproxIngestedIntoL0Bytes += e.Meta.Size
 				}
 				if metaFlushableOverlaps[e.Meta.FileNum] {
 					stats.MemtableOverlappingFiles++
 				}
+				levels[e.Meta.FileNum] = e.Level
 			}
 		} else if asFlushable {
 			// NB: If asFlushable == true, there are no shared sstables.
@@ -1763,6 +2262,8 @@ func (d *DB) ingest(
 				info.Tables[i].Level = -1
 				info.Tables[i].TableInfo = f.TableInfo()
 				stats.Bytes += f.Size
+				stats.BytesAsFlushable += f.Size
+				levels[f.FileNum] = -1
 				// We don't have exact stats on which files will be ingested into
 				// L0, because actual ingestion into the LSM has been deferred until
 				// flush time. Instead, we infer based on memtable overlap.
@@ -1777,6 +2278,48 @@ func (d *DB) ingest(
 				}
 			}
 		}
+		addTables := func(n int, get func(i int) (*tableMetadata, int)) {
+			start := len(stats.Tables)
+			for i := 0; i < n; i++ {
+				m, input := get(i)
+				tables, ok := splitTables[m.FileNum]
+				if !ok {
+					tables = []*tableMetadata{m}
+				}
+				for _, t := range tables {
+					level, ok := levels[t.FileNum]
+					if !ok {
+						continue
+					}
+					dataOverlapLevel, ok := dataOverlapLevels[t.FileNum]
+					if !ok {
+						dataOverlapLevel = -1
+					}
+					stats.Tables = append(stats.Tables, IngestedTableStats{
+						TableInfo:        t.TableInfo(),
+						Input:            input,
+						SeqNum:           t.SmallestSeqNum,
+						Level:            level,
+						AsFlushable:      asFlushable,
+						OverlapsMemtable: metaFlushableOverlaps[m.FileNum],
+						DataOverlapLevel: dataOverlapLevel,
+					})
+				}
+			}
+			slices.SortStableFunc(stats.Tables[start:], func(a, b IngestedTableStats) int {
+				return cmp.Compare(a.Input, b.Input)
+			})
+		}
+		addTables(len(loadResult.local), func(i int) (*tableMetadata, int) {
+			return loadResult.local[i].tableMetadata, loadResult.local[i].input
+		})
+		addTables(len(loadResult.shared), func(i int) (*tableMetadata, int) {
+			return loadResult.shared[i].tableMetadata, loadResult.shared[i].input
+		})
+		addTables(len(loadResult.external), func(i int) (*tableMetadata, int) {
+			return loadResult.external[i].tableMetadata, loadResult.external[i].input
+		})
+		info.Stats = stats
 		d.opts.EventListener.TableIngested(info)
 	}
 
@@ -1902,6 +2445,86 @@ func (d *DB) ingestSplit(
 	return nil
 }
 
+// ingestSplitOverlappingLocal returns the local sstables with each of those
+// that overlap other local sstables replaced by up to three tables: the parts
+// of the sstable before and after its overlap span, which don't overlap any
+// other sstable of the ingestion and are ingested at their usual target level,
+// and the part within its overlap span, which is ingested into L0. The parts
+// are virtual sstables backed by the ingested sstable, with tight bounds; they
+// are recorded in splitTables.
+//
+// DB.mu and the manifest lock must be held.
+func (d *DB) ingestSplitOverlappingLocal(
+	ctx context.Context,
+	local []ingestLocalMeta,
+	ve *versionEdit,
+	splitTables map[base.FileNum][]*tableMetadata,
+) ([]ingestLocalMeta, error) {
+	result := make([]ingestLocalMeta, 0, len(local))
+	for _, lm := range local {
+		if !lm.overlap.Valid() {
+			result = append(result, lm)
+			continue
+		}
+		m := lm.tableMetadata
+		// The virtual sstables are carved out of m by excising the rest of it.
+		// The changes excise records in its version edit don't apply to m,
+		// which isn't part of the LSM yet.
+		scratch := &versionEdit{DeletedTables: make(map[deletedFileEntry]*tableMetadata)}
+		carve := func(t *tableMetadata, exciseSpan base.UserKeyBounds) (*tableMetadata, error) {
+			added, err := d.excise(ctx, exciseSpan, t, scratch, 0)
+			if err != nil || len(added) == 0 {
+				return nil, err
+			}
+			return added[0].Meta, nil
+		}
+
+		var left, right *tableMetadata
+		middle := m
+		var err error
+		if d.cmp(m.Smallest.UserKey, lm.overlap.Start) < 0 {
+			left, err = carve(m, base.UserKeyBoundsInclusive(lm.overlap.Start, m.Largest.UserKey))
+			if err == nil {
+				middle, err = carve(m, base.UserKeyBoundsEndExclusive(m.Smallest.UserKey, lm.overlap.Start))
+			}
+		}
+		if err == nil && middle != nil && d.cmp(lm.overlap.End, middle.Largest.UserKey) <= 0 {
+			if d.cmp(middle.Smallest.UserKey, lm.overlap.End) >= 0 {
+				// None of m's keys fall within the overlap span.
+				right, middle = middle, nil
+			} else {
+				right, err = carve(middle, base.UserKeyBoundsEndExclusive(middle.Smallest.UserKey, lm.overlap.End))
+				if err == nil {
+					middle, err = carve(middle, base.UserKeyBoundsInclusive(lm.overlap.End, middle.Largest.UserKey))
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var tables []*tableMetadata
+		for _, t := range []*tableMetadata{left, middle, right} {
+			if t == nil {
+				continue
+			}
+			tables = append(tables, t)
+			result = append(result, ingestLocalMeta{
+				tableMetadata:  t,
+				input:          lm.input,
+				path:           lm.path,
+				streamed:       lm.streamed,
+				overlapsInputs: t == middle,
+			})
+		}
+		if middle != m {
+			ve.CreatedBackingTables = append(ve.CreatedBackingTables, m.FileBacking)
+		}
+		splitTables[m.FileNum] = tables
+	}
+	return result, nil
+}
+
 func (d *DB) ingestApply(
 	ctx context.Context,
 	jobID JobID,
@@ -1909,14 +2532,17 @@ func (d *DB) ingestApply(
 	mut *memTable,
 	exciseSpan KeyRange,
 	exciseSeqNum base.SeqNum,
+	dataOverlapLevels map[base.FileNum]int,
+	splitTables map[base.FileNum][]*tableMetadata,
 ) (*versionEdit, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	ve := &versionEdit{
-		NewTables: make([]newTableEntry, lr.fileCount()),
-	}
-	if exciseSpan.Valid() || (d.opts.Experimental.IngestSplit != nil && d.opts.Experimental.IngestSplit()) {
+	ve := &versionEdit{}
+	// Ingest-time splitting is enabled during bulk loads.
+	ingestSplit := d.mu.compact.bulkLoad ||
+		(d.opts.Experimental.IngestSplit != nil && d.opts.Experimental.IngestSplit())
+	if exciseSpan.Valid() || ingestSplit {
 		ve.DeletedTables = map[manifest.DeletedTableEntry]*manifest.TableMetadata{}
 	}
 	metrics := make(map[int]*LevelMetrics)
@@ -1929,6 +2555,16 @@ func (d *DB) ingestApply(
 	// returns must unlock the manifest.
 	d.mu.versions.logLock()
 
+	if lr.splitOverlappingLocal {
+		var err error
+		lr.local, err = d.ingestSplitOverlappingLocal(ctx, lr.local, ve, splitTables)
+		if err != nil {
+			d.mu.versions.logUnlock()
+			return nil, err
+		}
+	}
+	ve.NewTables = make([]newTableEntry, lr.fileCount())
+
 	if mut != nil {
 		// Unref the mutable memtable to allows its flush to proceed. Now that we've
 		// acquired the manifest lock, we can be certain that if the mutable
@@ -1951,8 +2587,7 @@ func (d *DB) ingestApply(
 		},
 		v: current,
 	}
-	shouldIngestSplit := d.opts.Experimental.IngestSplit != nil &&
-		d.opts.Experimental.IngestSplit() && d.FormatMajorVersion() >= FormatVirtualSSTables
+	shouldIngestSplit := ingestSplit && d.FormatMajorVersion() >= FormatVirtualSSTables
 	baseLevel := d.mu.versions.picker.getBaseLevel()
 	// filesToSplit is a list where each element is a pair consisting of a file
 	// being ingested and a file being split to make room for an ingestion into
@@ -1970,6 +2605,11 @@ func (d *DB) ingestApply(
 		if i < len(lr.local) {
 			// local file.
 			m = lr.local[i].tableMetadata
+			if lr.local[i].overlapsInputs {
+				// The sstable overlaps other sstables of the ingestion, which
+				// have different sequence numbers.
+				specifiedLevel = 0
+			}
 		} else if (i - len(lr.local)) < len(lr.shared) {
 			// shared file.
 			isShared = true
@@ -2022,9 +2662,22 @@ func (d *DB) ingestApply(
 					return overlapChecker.DetermineLSMOverlap(ctx, m.UserKeyBounds())
 				}()
 				if err == nil {
-					f.Level, splitTable, err = ingestTargetLevel(
-						ctx, d.cmp, lsmOverlap, baseLevel, d.mu.compact.inProgress, m, shouldIngestSplit,
-					)
+					for level := range lsmOverlap {
+						if lsmOverlap[level].Result == overlap.Data {
+							dataOverlapLevels[m.FileNum] = level
+							break
+						}
+					}
+					if lr.targetLevel > 0 {
+						f.Level = lr.targetLevel
+						splitTable, err = ingestCheckTargetLevel(
+							d.cmp, lsmOverlap, baseLevel, d.mu.compact.inProgress, m, f.Level, shouldIngestSplit,
+						)
+					} else {
+						f.Level, splitTable, err = ingestTargetLevel(
+							ctx, d.cmp, lsmOverlap, baseLevel, d.mu.compact.inProgress, m, shouldIngestSplit,
+						)
+					}
 				}
 			}
 
@@ -2108,6 +2761,9 @@ func (d *DB) ingestApply(
 				if err != nil {
 					return nil, err
 				}
+				if lr.fileCount() == 0 {
+					markExciseRemnants(newFiles)
+				}
 
 				if _, ok := ve.DeletedTables[deletedFileEntry{
 					Level:   level,
@@ -0,0 +1,222 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// ErrIngestKeyTypeNotAllowed is returned by ingestion when an sstable contains
+// a range deletion or range key outside the spans permitted by the configured
+// IngestKeyTypePolicy. The error can be checked with
+// errors.Is(err, ErrIngestKeyTypeNotAllowed).
+var ErrIngestKeyTypeNotAllowed = errors.New("pebble: ingested key type not allowed")
+
+// IngestKeyTypePolicy is an allowlist of the spans of the keyspace within
+// which ingested sstables may contain range deletions and range keys. When
+// configured through Options.Experimental.IngestKeyTypePolicy, ingestion of
+// an sstable containing a range deletion or range key that is not contained
+// within an allowed span fails with ErrIngestKeyTypeNotAllowed. This prevents
+// the accidental ingestion of key types into spans of the keyspace that do not
+// support them.
+//
+// An IngestKeyTypePolicy is safe for concurrent use. Spans may be allowed and
+// disallowed while the DB is open; the policy in effect when an sstable is
+// loaded for ingestion is the one enforced.
+type IngestKeyTypePolicy struct {
+	cmp base.Compare
+	mu  struct {
+		sync.RWMutex
+		rangeDels spanSet
+		rangeKeys spanSet
+	}
+}
+
+// NewIngestKeyTypePolicy constructs a new IngestKeyTypePolicy that orders keys
+// using the provided comparer. Initially, range deletions and range keys are
+// not allowed anywhere.
+func NewIngestKeyTypePolicy(cmp *base.Comparer) *IngestKeyTypePolicy {
+	return &IngestKeyTypePolicy{cmp: cmp.Compare}
+}
+
+// AllowRangeDels permits ingested sstables to contain range deletions within
+// [start, end).
+func (p *IngestKeyTypePolicy) AllowRangeDels(start, end []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mu.rangeDels.add(p.cmp, start, end)
+}
+
+// DisallowRangeDels forbids ingested sstables from containing range deletions
+// overlapping [start, end).
+func (p *IngestKeyTypePolicy) DisallowRangeDels(start, end []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mu.rangeDels.remove(p.cmp, start, end)
+}
+
+// AllowRangeKeys permits ingested sstables to contain range keys within
+// [start, end).
+func (p *IngestKeyTypePolicy) AllowRangeKeys(start, end []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mu.rangeKeys.add(p.cmp, start, end)
+}
+
+// DisallowRangeKeys forbids ingested sstables from containing range keys
+// overlapping [start, end).
+func (p *IngestKeyTypePolicy) DisallowRangeKeys(start, end []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mu.rangeKeys.remove(p.cmp, start, end)
+}
+
+// rangeDelsAllowed returns true if range deletions are permitted within the
+// provided bounds.
+func (p *IngestKeyTypePolicy) rangeDelsAllowed(bounds base.UserKeyBounds) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.mu.rangeDels.contains(p.cmp, bounds)
+}
+
+// rangeKeysAllowed returns true if range keys are permitted within the
+// provided bounds.
+func (p *IngestKeyTypePolicy) rangeKeysAllowed(bounds base.UserKeyBounds) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.mu.rangeKeys.contains(p.cmp, bounds)
+}
+
+// spanSet is a set of spans of the keyspace, represented as a sorted slice of
+// non-overlapping, non-adjacent key ranges.
+type spanSet []KeyRange
+
+// add adds [start, end) to the set, merging it with any overlapping or
+// adjacent spans.
+func (s *spanSet) add(cmp base.Compare, start, end []byte) {
+	if cmp(start, end) >= 0 {
+		return
+	}
+	// Find the spans that overlap or abut [start, end): spans [i, j).
+	i, _ := slices.BinarySearchFunc(*s, start, func(kr KeyRange, k []byte) int {
+		return cmp(kr.End, k)
+	})
+	j := i
+	for j < len(*s) && cmp((*s)[j].Start, end) <= 0 {
+		j++
+	}
+	merged := KeyRange{Start: slices.Clone(start), End: slices.Clone(end)}
+	if i < j {
+		if cmp((*s)[i].Start, start) < 0 {
+			merged.Start = (*s)[i].Start
+		}
+		if cmp((*s)[j-1].End, end) > 0 {
+			merged.End = (*s)[j-1].End
+		}
+	}
+	*s = slices.Replace(*s, i, j, merged)
+}
+
+// remove removes [start, end) from the set, truncating any spans that
+// partially overlap it.
+func (s *spanSet) remove(cmp base.Compare, start, end []byte) {
+	if cmp(start, end) >= 0 {
+		return
+	}
+	// Find the spans that overlap [start, end): spans [i, j).
+	i, _ := slices.BinarySearchFunc(*s, start, func(kr KeyRange, k []byte) int {
+		if cmp(kr.End, k) <= 0 {
+			return -1
+		}
+		return +1
+	})
+	j := i
+	for j < len(*s) && cmp((*s)[j].Start, end) < 0 {
+		j++
+	}
+	if i == j {
+		return
+	}
+	var remaining []KeyRange
+	if cmp((*s)[i].Start, start) < 0 {
+		remaining = append(remaining, KeyRange{Start: (*s)[i].Start, End: slices.Clone(start)})
+	}
+	if cmp((*s)[j-1].End, end) > 0 {
+		remaining = append(remaining, KeyRange{Start: slices.Clone(end), End: (*s)[j-1].End})
+	}
+	*s = slices.Replace(*s, i, j, remaining...)
+}
+
+// contains returns true if the provided bounds are entirely contained within
+// a span of the set.
+func (s spanSet) contains(cmp base.Compare, bounds base.UserKeyBounds) bool {
+	// Find the last span that starts at or before bounds.Start.
+	i, found := slices.BinarySearchFunc(s, bounds.Start, func(kr KeyRange, k []byte) int {
+		return cmp(kr.Start, k)
+	})
+	if !found {
+		if i == 0 {
+			return false
+		}
+		i--
+	}
+	kb := s[i].UserKeyBounds()
+	return kb.ContainsBounds(cmp, &bounds)
+}
+
+// ingestValidateKeyTypes validates that every range deletion and range key
+// within the sstable read by r is permitted by the provided policy.
+func ingestValidateKeyTypes(
+	ctx context.Context, opts *Options, policy *IngestKeyTypePolicy, r *sstable.Reader,
+) error {
+	rangeDelIter, err := r.NewRawRangeDelIter(ctx, sstable.NoFragmentTransforms, block.NoReadEnv)
+	if err != nil {
+		return err
+	}
+	if rangeDelIter != nil {
+		defer rangeDelIter.Close()
+		if err := ingestValidateSpans(opts, rangeDelIter, "range deletion", policy.rangeDelsAllowed); err != nil {
+			return err
+		}
+	}
+	rangeKeyIter, err := r.NewRawRangeKeyIter(ctx, sstable.NoFragmentTransforms, block.NoReadEnv)
+	if err != nil {
+		return err
+	}
+	if rangeKeyIter != nil {
+		defer rangeKeyIter.Close()
+		if err := ingestValidateSpans(opts, rangeKeyIter, "range key", policy.rangeKeysAllowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestValidateSpans validates that every span surfaced by iter is contained
+// within bounds permitted by allowed.
+func ingestValidateSpans(
+	opts *Options,
+	iter keyspan.FragmentIterator,
+	keyType string,
+	allowed func(base.UserKeyBounds) bool,
+) error {
+	s, err := iter.First()
+	for ; s != nil; s, err = iter.Next() {
+		bounds := s.Bounds()
+		if !allowed(bounds) {
+			return errors.Wrapf(ErrIngestKeyTypeNotAllowed, "%s %s",
+				errors.Safe(keyType), bounds.Format(opts.Comparer.FormatKey))
+		}
+	}
+	return err
+}
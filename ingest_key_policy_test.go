@@ -0,0 +1,132 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanSet(t *testing.T) {
+	cmp := base.DefaultComparer.Compare
+	var s spanSet
+	str := func() string {
+		var parts []string
+		for _, kr := range s {
+			parts = append(parts, fmt.Sprintf("[%s,%s)", kr.Start, kr.End))
+		}
+		return strings.Join(parts, " ")
+	}
+	contains := func(start, end string) bool {
+		return s.contains(cmp, base.UserKeyBoundsEndExclusive([]byte(start), []byte(end)))
+	}
+
+	s.add(cmp, []byte("c"), []byte("e"))
+	s.add(cmp, []byte("g"), []byte("i"))
+	require.Equal(t, "[c,e) [g,i)", str())
+	require.True(t, contains("c", "e"))
+	require.True(t, contains("d", "e"))
+	require.False(t, contains("b", "d"))
+	require.False(t, contains("d", "h"))
+
+	// Adding an abutting span merges it.
+	s.add(cmp, []byte("e"), []byte("f"))
+	require.Equal(t, "[c,f) [g,i)", str())
+	// Adding a span overlapping several spans merges them all.
+	s.add(cmp, []byte("a"), []byte("h"))
+	require.Equal(t, "[a,i)", str())
+	require.True(t, contains("b", "h"))
+	// Adding a contained span is a no-op.
+	s.add(cmp, []byte("b"), []byte("c"))
+	require.Equal(t, "[a,i)", str())
+	s.add(cmp, []byte("x"), []byte("z"))
+	require.Equal(t, "[a,i) [x,z)", str())
+
+	// Removing a span in the middle splits a span.
+	s.remove(cmp, []byte("c"), []byte("e"))
+	require.Equal(t, "[a,c) [e,i) [x,z)", str())
+	require.False(t, contains("b", "f"))
+	// Removing a span overlapping several spans truncates them.
+	s.remove(cmp, []byte("b"), []byte("f"))
+	require.Equal(t, "[a,b) [f,i) [x,z)", str())
+	s.remove(cmp, []byte("h"), []byte("y"))
+	require.Equal(t, "[a,b) [f,h) [y,z)", str())
+	// Removing a span that overlaps nothing is a no-op.
+	s.remove(cmp, []byte("b"), []byte("f"))
+	require.Equal(t, "[a,b) [f,h) [y,z)", str())
+	s.remove(cmp, []byte("a"), []byte("z"))
+	require.Equal(t, "", str())
+	require.False(t, contains("a", "b"))
+}
+
+func TestIngestKeyTypePolicy(t *testing.T) {
+	mem := vfs.NewMem()
+	policy := NewIngestKeyTypePolicy(base.DefaultComparer)
+	policy.AllowRangeDels([]byte("a"), []byte("m"))
+	policy.AllowRangeKeys([]byte("x"), []byte("z"))
+	opts := &Options{FS: mem}
+	opts.Experimental.IngestKeyTypePolicy = policy
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	var fileNum int
+	ingest := func(write func(w *sstable.Writer) error) error {
+		fileNum++
+		path := fmt.Sprintf("ext%d", fileNum)
+		f, err := mem.Create(path, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+			TableFormat: d.TableFormat(),
+		})
+		require.NoError(t, write(w))
+		require.NoError(t, w.Close())
+		return d.Ingest(context.Background(), []string{path})
+	}
+	rangeDel := func(start, end string) func(w *sstable.Writer) error {
+		return func(w *sstable.Writer) error {
+			return w.DeleteRange([]byte(start), []byte(end))
+		}
+	}
+	rangeKeySet := func(start, end string) func(w *sstable.Writer) error {
+		return func(w *sstable.Writer) error {
+			return w.RangeKeySet([]byte(start), []byte(end), nil, []byte("val"))
+		}
+	}
+
+	// Point keys are always allowed.
+	require.NoError(t, ingest(func(w *sstable.Writer) error {
+		return w.Set([]byte("n"), []byte("val"))
+	}))
+	// Range deletions and range keys within the allowed spans are allowed.
+	require.NoError(t, ingest(rangeDel("b", "c")))
+	require.NoError(t, ingest(rangeKeySet("x", "y")))
+
+	// Range deletions and range keys outside the allowed spans are rejected.
+	err = ingest(rangeDel("l", "n"))
+	require.True(t, errors.Is(err, ErrIngestKeyTypeNotAllowed), "%+v", err)
+	err = ingest(rangeDel("x", "y"))
+	require.True(t, errors.Is(err, ErrIngestKeyTypeNotAllowed), "%+v", err)
+	err = ingest(rangeKeySet("w", "y"))
+	require.True(t, errors.Is(err, ErrIngestKeyTypeNotAllowed), "%+v", err)
+	err = ingest(rangeKeySet("b", "c"))
+	require.True(t, errors.Is(err, ErrIngestKeyTypeNotAllowed), "%+v", err)
+
+	// Changes to the policy take effect for subsequent ingestions.
+	policy.DisallowRangeDels([]byte("a"), []byte("c"))
+	err = ingest(rangeDel("b", "c"))
+	require.True(t, errors.Is(err, ErrIngestKeyTypeNotAllowed), "%+v", err)
+	policy.AllowRangeKeys([]byte("w"), []byte("x"))
+	require.NoError(t, ingest(rangeKeySet("w", "y")))
+}
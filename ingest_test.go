@@ -21,6 +21,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/cockroachdb/datadriven"
@@ -148,7 +149,7 @@ func TestIngestLoad(t *testing.T) {
 				FS:         mem,
 			}
 			opts.WithFSDefaults()
-			lr, err := ingestLoad(context.Background(), opts, dbVersion, []string{"ext"}, nil, nil, nil, []base.FileNum{1})
+			lr, err := ingestLoad(context.Background(), opts, dbVersion, []string{"ext"}, nil, nil, nil, []base.FileNum{1}, false /* allowSeqNums */)
 			if err != nil {
 				return err.Error()
 			}
@@ -190,7 +191,8 @@ func TestIngestLoadRand(t *testing.T) {
 			tableMetadata: &tableMetadata{
 				FileNum: pending[i],
 			},
-			path: paths[i],
+			input: i,
+			path:  paths[i],
 		}
 		expected[i].tableMetadata.Stats.CompressionType = block.SnappyCompression
 		expected[i].StatsMarkValid()
@@ -241,7 +243,7 @@ func TestIngestLoadRand(t *testing.T) {
 	}
 	opts.WithFSDefaults()
 	opts.EnsureDefaults()
-	lr, err := ingestLoad(context.Background(), opts, version, paths, nil, nil, nil, pending)
+	lr, err := ingestLoad(context.Background(), opts, version, paths, nil, nil, nil, pending, false /* allowSeqNums */)
 	require.NoError(t, err)
 
 	for _, m := range lr.local {
@@ -262,7 +264,7 @@ func TestIngestLoadInvalid(t *testing.T) {
 		FS:       mem,
 	}
 	opts.WithFSDefaults()
-	if _, err := ingestLoad(context.Background(), opts, internalFormatNewest, []string{"invalid"}, nil, nil, nil, []base.FileNum{1}); err == nil {
+	if _, err := ingestLoad(context.Background(), opts, internalFormatNewest, []string{"invalid"}, nil, nil, nil, []base.FileNum{1}, false /* allowSeqNums */); err == nil {
 		t.Fatalf("expected error, but found success")
 	}
 }
@@ -2884,7 +2886,7 @@ func TestIngestStats(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	ingest := func(expectedLevel int, keys ...string) {
+	ingest := func(expectedLevel, expectedDataOverlapLevel int, keys ...string) {
 		t.Helper()
 		f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
 		require.NoError(t, err)
@@ -2902,14 +2904,318 @@ func TestIngestStats(t *testing.T) {
 			require.EqualValues(t, 0, stats.ApproxIngestedIntoL0Bytes)
 		}
 		require.Less(t, uint64(0), stats.Bytes)
+		require.Equal(t, stats.Bytes, stats.BytesDirect)
+		require.Zero(t, stats.BytesAsFlushable)
+		require.Len(t, stats.Tables, 1)
+		require.Equal(t, expectedLevel, stats.Tables[0].Level)
+		require.Equal(t, expectedDataOverlapLevel, stats.Tables[0].DataOverlapLevel)
+		require.False(t, stats.Tables[0].OverlapsMemtable)
 	}
-	ingest(6, "a")
-	ingest(0, "a")
-	ingest(6, "b", "g")
-	ingest(0, "c")
+	ingest(6, -1, "a")
+	ingest(0, 6, "a")
+	ingest(6, -1, "b", "g")
+	// "c" only overlaps the bounds of the table containing "b" and "g".
+	ingest(0, -1, "c")
 	require.NoError(t, d.Close())
 }
 
+// TestIngestStatsTables checks that the stats of an ingestion describe each
+// of the ingested sstables, in the order they were passed.
+func TestIngestStatsTables(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("d"), nil, nil))
+	require.NoError(t, d.Flush())
+	var paths []string
+	for i, keys := range [][]string{{"c", "e"}, {"a"}, {}, {"g"}} {
+		path := fmt.Sprint("ext", i)
+		f, err := mem.Create(path, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{})
+		for _, k := range keys {
+			require.NoError(t, w.Set([]byte(k), nil))
+		}
+		require.NoError(t, w.Close())
+		paths = append(paths, path)
+	}
+	seqNum := d.mu.versions.visibleSeqNum.Load()
+	stats, err := d.IngestWithStats(context.Background(), paths)
+	require.NoError(t, err)
+
+	// The empty sstable isn't ingested. The sequence numbers are assigned in
+	// key order.
+	require.Len(t, stats.Tables, 3)
+	fileNums := make(map[base.FileNum]bool)
+	for i, expected := range []struct {
+		input  int
+		seqNum base.SeqNum
+		level  int
+	}{
+		{input: 0, seqNum: seqNum + 1, level: 0},
+		{input: 1, seqNum: seqNum, level: 6},
+		{input: 3, seqNum: seqNum + 2, level: 6},
+	} {
+		tbl := stats.Tables[i]
+		require.Equal(t, expected.input, tbl.Input)
+		require.Equal(t, expected.seqNum, tbl.SeqNum)
+		require.Equal(t, expected.seqNum, tbl.LargestSeqNum)
+		require.Equal(t, expected.level, tbl.Level)
+		require.False(t, tbl.AsFlushable)
+		fileNums[tbl.FileNum] = true
+	}
+	require.Len(t, fileNums, 3)
+	require.Equal(t, "c", string(stats.Tables[0].Smallest.UserKey))
+}
+
+func TestIngestSplitOverlappingInputs(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{FS: mem, FormatMajorVersion: FormatNewest}
+	var splitOverlapping bool
+	opts.Experimental.IngestSplitOverlappingInputs = func() bool { return splitOverlapping }
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	var paths []string
+	write := func(f func(w *sstable.Writer)) {
+		path := fmt.Sprint("ext", len(paths))
+		file, err := mem.Create(path, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(file), d.opts.MakeWriterOptions(0, d.TableFormat()))
+		f(w)
+		require.NoError(t, w.Close())
+		paths = append(paths, path)
+	}
+	set := func(value string, keys ...string) func(w *sstable.Writer) {
+		return func(w *sstable.Writer) {
+			for _, k := range keys {
+				require.NoError(t, w.Set([]byte(k), []byte(value)))
+			}
+		}
+	}
+	write(set("0", "a", "c", "e", "g"))
+	write(set("1", "d", "e", "f"))
+	write(set("2", "x"))
+	write(func(w *sstable.Writer) {
+		require.NoError(t, w.DeleteRange([]byte("b"), []byte("cc")))
+		require.NoError(t, w.Set([]byte("f"), []byte("3")))
+	})
+	contents := func() string {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		defer iter.Close()
+		var s strings.Builder
+		for valid := iter.First(); valid; valid = iter.Next() {
+			fmt.Fprintf(&s, "%s=%s ", iter.Key(), iter.Value())
+		}
+		require.NoError(t, iter.Error())
+		return s.String()
+	}
+
+	_, err = d.IngestWithStats(context.Background(), paths)
+	require.ErrorContains(t, err, "overlapping ranges")
+
+	splitOverlapping = true
+	stats, err := d.IngestWithStats(context.Background(), paths)
+	require.NoError(t, err)
+	// The later sstables take precedence.
+	const expected = "a=0 d=1 e=1 f=3 g=0 x=2 "
+	require.Equal(t, expected, contents())
+
+	// The first sstable is split into [a], which doesn't overlap the other
+	// sstables, [c-e] which is ingested into L0, and [g].
+	type table struct {
+		input       int
+		level       int
+		start, end  string
+		overlapping bool
+	}
+	var tables []table
+	for _, tbl := range stats.Tables {
+		tables = append(tables, table{
+			input: tbl.Input,
+			level: tbl.Level,
+			start: string(tbl.Smallest.UserKey),
+			end:   string(tbl.Largest.UserKey),
+		})
+		require.False(t, tbl.AsFlushable)
+	}
+	require.Equal(t, []table{
+		{input: 0, level: 6, start: "a", end: "a"},
+		{input: 0, level: 0, start: "c", end: "e"},
+		{input: 0, level: 6, start: "g", end: "g"},
+		{input: 1, level: 0, start: "d", end: "f"},
+		{input: 2, level: 6, start: "x", end: "x"},
+		{input: 3, level: 0, start: "b", end: "f"},
+	}, tables)
+	require.Less(t, stats.Tables[0].SeqNum, stats.Tables[3].SeqNum)
+	require.Less(t, stats.Tables[3].SeqNum, stats.Tables[5].SeqNum)
+
+	require.NoError(t, d.CheckLevels(nil))
+	require.NoError(t, d.Close())
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	require.Equal(t, expected, contents())
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false))
+	require.Equal(t, expected, contents())
+}
+
+func TestIngestStreams(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	table := func(keys ...string) io.Reader {
+		f := &objstorage.MemObj{}
+		w := sstable.NewWriter(f, d.opts.MakeWriterOptions(0, d.TableFormat()))
+		for _, k := range keys {
+			require.NoError(t, w.Set([]byte(k), []byte(k)))
+		}
+		require.NoError(t, w.Close())
+		return bytes.NewReader(f.Data())
+	}
+	objects := func() int { return len(d.objProvider.List()) }
+
+	// Empty sstables are skipped.
+	stats, err := d.IngestStreams(context.Background(), []io.Reader{table("c", "d"), table(), table("a", "b")})
+	require.NoError(t, err)
+	require.Len(t, stats.Tables, 2)
+	require.Equal(t, 2, objects())
+	for _, k := range []string{"a", "b", "c", "d"} {
+		v, closer, err := d.Get([]byte(k))
+		require.NoError(t, err)
+		require.Equal(t, k, string(v))
+		require.NoError(t, closer.Close())
+	}
+
+	// The objects written are removed if the ingestion fails, whether on
+	// overlapping sstables or on an error reading a stream.
+	_, err = d.IngestStreams(context.Background(), []io.Reader{table("e", "g"), table("f")})
+	require.Error(t, err)
+	require.Equal(t, 2, objects())
+	failing := io.MultiReader(table("x"), iotest.ErrReader(errors.New("connection reset")))
+	_, err = d.IngestStreams(context.Background(), []io.Reader{table("e"), failing})
+	require.ErrorContains(t, err, "connection reset")
+	require.Equal(t, 2, objects())
+}
+
+func TestIngestWithOptionsTargetLevel(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{
+		FS:                          mem,
+		FormatMajorVersion:          FormatNewest,
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	table := func(name, value string, keys ...string) string {
+		f, err := mem.Create(name, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), d.opts.MakeWriterOptions(0, d.TableFormat()))
+		for _, k := range keys {
+			require.NoError(t, w.Set([]byte(k), []byte(value)))
+		}
+		require.NoError(t, w.Close())
+		return name
+	}
+	ingest := func(targetLevel int, paths ...string) (levels []int, _ error) {
+		stats, err := d.IngestWithOptions(context.Background(), paths, IngestOptions{TargetLevel: targetLevel})
+		for _, tbl := range stats.Tables {
+			levels = append(levels, tbl.Level)
+		}
+		return levels, err
+	}
+	get := func(key string) string {
+		v, closer, err := d.Get([]byte(key))
+		require.NoError(t, err)
+		defer closer.Close()
+		return string(v)
+	}
+
+	levels, err := ingest(6, table("ext1", "1", "a", "b"), table("ext2", "1", "d"))
+	require.NoError(t, err)
+	require.Equal(t, []int{6, 6}, levels)
+
+	// The data in L6 overlaps the sstable, which can only be ingested above it.
+	// The failed ingestion has no effect and can be retried without a target
+	// level.
+	_, err = ingest(6, table("ext3", "2", "a"))
+	require.True(t, errors.Is(err, ErrIngestTargetLevelUnsafe), "%v", err)
+	require.Equal(t, "1", get("a"))
+	levels, err = ingest(0, "ext3")
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, levels)
+	require.Equal(t, "2", get("a"))
+
+	// The levels above the base level (L6) are kept empty.
+	_, err = ingest(5, table("ext4", "2", "e"))
+	require.True(t, errors.Is(err, ErrIngestTargetLevelUnsafe), "%v", err)
+	require.ErrorContains(t, err, "above the base level")
+
+	// The memtable is flushed into L0 before the ingestion, and is in the way.
+	require.NoError(t, d.Set([]byte("g"), []byte("1"), nil))
+	_, err = ingest(6, table("ext5", "2", "g"))
+	require.True(t, errors.Is(err, ErrIngestTargetLevelUnsafe), "%v", err)
+	require.Equal(t, "1", get("g"))
+
+	_, err = ingest(numLevels, table("ext6", "2", "h"))
+	require.ErrorContains(t, err, "invalid ingest target level")
+}
+
+func TestIngestStatsMemtableOverlap(t *testing.T) {
+	for _, disableFlushable := range []bool{false, true} {
+		t.Run(fmt.Sprintf("disableFlushable=%t", disableFlushable), func(t *testing.T) {
+			mem := vfs.NewMem()
+			var ingestInfo TableIngestInfo
+			opts := &Options{
+				FS: mem,
+				EventListener: &EventListener{
+					TableIngested: func(info TableIngestInfo) { ingestInfo = info },
+				},
+				FormatMajorVersion: FormatNewest,
+			}
+			opts.Experimental.DisableIngestAsFlushable = func() bool { return disableFlushable }
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+
+			require.NoError(t, d.Set([]byte("a"), nil, nil))
+			f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
+			require.NoError(t, err)
+			w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+				TableFormat: d.TableFormat(),
+			})
+			require.NoError(t, w.Set([]byte("a"), nil))
+			require.NoError(t, w.Close())
+			stats, err := d.IngestWithStats(context.Background(), []string{"ext"})
+			require.NoError(t, err)
+			require.Equal(t, stats, ingestInfo.Stats)
+
+			require.Len(t, stats.Tables, 1)
+			require.True(t, stats.Tables[0].OverlapsMemtable)
+			require.Equal(t, !disableFlushable, stats.Tables[0].AsFlushable)
+			if disableFlushable {
+				// The ingestion waited for the memtable to be flushed, and the
+				// sstable was ingested above the flushed data.
+				require.Greater(t, stats.FlushWaitDuration, time.Duration(0))
+				require.Equal(t, stats.Bytes, stats.BytesDirect)
+				require.Equal(t, 0, stats.Tables[0].Level)
+				require.Equal(t, 0, stats.Tables[0].DataOverlapLevel)
+			} else {
+				require.Zero(t, stats.FlushWaitDuration)
+				require.Equal(t, stats.Bytes, stats.BytesAsFlushable)
+				require.Equal(t, -1, stats.Tables[0].Level)
+				require.Equal(t, -1, stats.Tables[0].DataOverlapLevel)
+			}
+		})
+	}
+}
+
 func TestIngestFlushQueuedLargeBatch(t *testing.T) {
 	// Verify that ingestion forces a flush of a queued large batch.
 
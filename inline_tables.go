@@ -0,0 +1,332 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/objstorage"
+)
+
+// inlineTableProvider wraps an objstorage.Provider and serves the sstables that
+// are stored inline in the MANIFEST (see Options.Experimental.InlineTableMaxSize)
+// from memory. All other objects are served by the wrapped provider.
+//
+// An inline table is first written to a regular file by a flush. Once the
+// version edit carrying its contents is committed to the MANIFEST, the file is
+// removed; if the DB crashes before that, the leftover file is removed when the
+// DB is reopened.
+type inlineTableProvider struct {
+	objstorage.Provider
+
+	mu struct {
+		sync.RWMutex
+		tables map[base.DiskFileNum][]byte
+		// size is the total size of the tables.
+		size int64
+	}
+}
+
+// inlineTablesMaxTotalSize bounds the total size of the inline tables. The
+// contents of all live inline tables are written to every MANIFEST snapshot
+// when the MANIFEST is rotated, so flushes stop inlining tables once the live
+// inline tables reach this size, until compactions rewrite them.
+const inlineTablesMaxTotalSize = 4 << 20
+
+var _ objstorage.Provider = (*inlineTableProvider)(nil)
+
+func newInlineTableProvider(provider objstorage.Provider) *inlineTableProvider {
+	p := &inlineTableProvider{Provider: provider}
+	p.mu.tables = make(map[base.DiskFileNum][]byte)
+	return p
+}
+
+// add registers the contents of an inline table.
+func (p *inlineTableProvider) add(fileNum base.DiskFileNum, data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mu.size += int64(len(data) - len(p.mu.tables[fileNum]))
+	p.mu.tables[fileNum] = data
+}
+
+// get returns the contents of an inline table, or false if the table isn't
+// inline.
+func (p *inlineTableProvider) get(fileNum base.DiskFileNum) ([]byte, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	data, ok := p.mu.tables[fileNum]
+	return data, ok
+}
+
+// isInline returns true if the given table is stored inline.
+func (p *inlineTableProvider) isInline(fileType base.FileType, fileNum base.DiskFileNum) bool {
+	if fileType != base.FileTypeTable {
+		return false
+	}
+	_, ok := p.get(fileNum)
+	return ok
+}
+
+// drop unregisters inline tables, without removing their local copies.
+func (p *inlineTableProvider) drop(fileNums ...base.DiskFileNum) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, fileNum := range fileNums {
+		p.mu.size -= int64(len(p.mu.tables[fileNum]))
+		delete(p.mu.tables, fileNum)
+	}
+}
+
+// count returns the number of inline tables.
+func (p *inlineTableProvider) count() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.mu.tables)
+}
+
+// size returns the total size of the inline tables.
+func (p *inlineTableProvider) size() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.mu.size
+}
+
+// removeLocalCopies removes the files that hold copies of inline tables, either
+// because they were just inlined by a flush or because a previous process
+// crashed before removing them.
+func (p *inlineTableProvider) removeLocalCopies(fileNums ...base.DiskFileNum) error {
+	for _, fileNum := range fileNums {
+		if _, err := p.Provider.Lookup(base.FileTypeTable, fileNum); err != nil {
+			continue
+		}
+		if err := p.Provider.Remove(base.FileTypeTable, fileNum); err != nil && !p.Provider.IsNotExistError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeAllLocalCopies removes the files that hold copies of any of the inline
+// tables.
+func (p *inlineTableProvider) removeAllLocalCopies() error {
+	p.mu.RLock()
+	fileNums := make([]base.DiskFileNum, 0, len(p.mu.tables))
+	for fileNum := range p.mu.tables {
+		fileNums = append(fileNums, fileNum)
+	}
+	p.mu.RUnlock()
+	return p.removeLocalCopies(fileNums...)
+}
+
+// OpenForReading is part of the objstorage.Provider interface.
+func (p *inlineTableProvider) OpenForReading(
+	ctx context.Context,
+	fileType base.FileType,
+	fileNum base.DiskFileNum,
+	opts objstorage.OpenOptions,
+) (objstorage.Readable, error) {
+	if fileType == base.FileTypeTable {
+		if data, ok := p.get(fileNum); ok {
+			return &inlineReadable{data: data}, nil
+		}
+	}
+	return p.Provider.OpenForReading(ctx, fileType, fileNum, opts)
+}
+
+// Remove is part of the objstorage.Provider interface.
+func (p *inlineTableProvider) Remove(fileType base.FileType, fileNum base.DiskFileNum) error {
+	if !p.isInline(fileType, fileNum) {
+		return p.Provider.Remove(fileType, fileNum)
+	}
+	p.drop(fileNum)
+	// The table may still have a local copy if it was never committed.
+	return p.removeLocalCopies(fileNum)
+}
+
+// Lookup is part of the objstorage.Provider interface.
+func (p *inlineTableProvider) Lookup(
+	fileType base.FileType, fileNum base.DiskFileNum,
+) (objstorage.ObjectMetadata, error) {
+	if p.isInline(fileType, fileNum) {
+		return objstorage.ObjectMetadata{DiskFileNum: fileNum, FileType: fileType}, nil
+	}
+	return p.Provider.Lookup(fileType, fileNum)
+}
+
+// Path is part of the objstorage.Provider interface.
+func (p *inlineTableProvider) Path(meta objstorage.ObjectMetadata) string {
+	if p.isInline(meta.FileType, meta.DiskFileNum) {
+		return fmt.Sprintf("%s (inline)", base.MakeFilename(meta.FileType, meta.DiskFileNum))
+	}
+	return p.Provider.Path(meta)
+}
+
+// Size is part of the objstorage.Provider interface.
+func (p *inlineTableProvider) Size(meta objstorage.ObjectMetadata) (int64, error) {
+	if meta.FileType == base.FileTypeTable {
+		if data, ok := p.get(meta.DiskFileNum); ok {
+			return int64(len(data)), nil
+		}
+	}
+	return p.Provider.Size(meta)
+}
+
+// List is part of the objstorage.Provider interface.
+func (p *inlineTableProvider) List() []objstorage.ObjectMetadata {
+	objs := p.Provider.List()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.mu.tables) == 0 {
+		return objs
+	}
+	objs = slices.DeleteFunc(objs, func(meta objstorage.ObjectMetadata) bool {
+		_, ok := p.mu.tables[meta.DiskFileNum]
+		return ok && meta.FileType == base.FileTypeTable
+	})
+	for fileNum := range p.mu.tables {
+		objs = append(objs, objstorage.ObjectMetadata{DiskFileNum: fileNum, FileType: base.FileTypeTable})
+	}
+	return objs
+}
+
+// liveInlineTables returns the contents of the inline tables that back the
+// tables of the current version or the given virtual backings.
+func (vs *versionSet) liveInlineTables(virtualBackings []*fileBacking) []manifest.InlineTable {
+	if vs.inlineTables == nil || vs.inlineTables.count() == 0 {
+		return nil
+	}
+	var tables []manifest.InlineTable
+	add := func(backing *fileBacking) {
+		if data, ok := vs.inlineTables.get(backing.DiskFileNum); ok {
+			tables = append(tables, manifest.InlineTable{FileNum: backing.DiskFileNum, Data: data})
+		}
+	}
+	for _, lm := range vs.currentVersion().Levels {
+		for f := range lm.All() {
+			if !f.Virtual {
+				add(f.FileBacking)
+			}
+		}
+	}
+	for _, backing := range virtualBackings {
+		add(backing)
+	}
+	return tables
+}
+
+// inlineReadable is an objstorage.Readable for an inline table.
+type inlineReadable struct {
+	data []byte
+}
+
+var _ objstorage.Readable = (*inlineReadable)(nil)
+
+// ReadAt is part of the objstorage.Readable interface.
+func (r *inlineReadable) ReadAt(ctx context.Context, p []byte, off int64) error {
+	if off < 0 || off+int64(len(p)) > int64(len(r.data)) {
+		return errors.Wrapf(base.ErrCorruption, "pebble: read [%d, %d) beyond the end of an inline table of %d bytes",
+			off, off+int64(len(p)), len(r.data))
+	}
+	copy(p, r.data[off:])
+	return nil
+}
+
+// Close is part of the objstorage.Readable interface.
+func (r *inlineReadable) Close() error {
+	return nil
+}
+
+// Size is part of the objstorage.Readable interface.
+func (r *inlineReadable) Size() int64 {
+	return int64(len(r.data))
+}
+
+// NewReadHandle is part of the objstorage.Readable interface.
+func (r *inlineReadable) NewReadHandle(
+	readBeforeSize objstorage.ReadBeforeSize,
+) objstorage.ReadHandle {
+	rh := objstorage.MakeNoopReadHandle(r)
+	return &rh
+}
+
+// inlineFlushedTables moves the contents of the small tables written by a
+// flush into the version edit, so that they're stored inline in the MANIFEST.
+// The local copies of the tables in ve.InlineTables must be removed once the
+// version edit is committed.
+//
+// d.mu must not be held when calling this.
+func (d *DB) inlineFlushedTables(ve *manifest.VersionEdit) error {
+	maxSize := d.opts.Experimental.InlineTableMaxSize
+	if maxSize <= 0 || d.FormatMajorVersion() < FormatInlineTables {
+		return nil
+	}
+	totalSize := d.inlineTables.size()
+	var inline []manifest.InlineTable
+	for _, nf := range ve.NewTables {
+		m := nf.Meta
+		if m.Virtual || m.Size > uint64(maxSize) || totalSize+int64(m.Size) > inlineTablesMaxTotalSize {
+			continue
+		}
+		fileNum := m.FileBacking.DiskFileNum
+		meta, err := d.objProvider.Lookup(base.FileTypeTable, fileNum)
+		if err != nil {
+			return err
+		}
+		if meta.IsRemote() {
+			continue
+		}
+		data, err := d.readObject(meta)
+		if err != nil {
+			return err
+		}
+		inline = append(inline, manifest.InlineTable{FileNum: fileNum, Data: data})
+		totalSize += int64(len(data))
+	}
+	// Register the tables only once all of them have been read, so that an
+	// error doesn't leave some of them registered.
+	for _, t := range inline {
+		d.inlineTables.add(t.FileNum, t.Data)
+	}
+	ve.InlineTables = inline
+	return nil
+}
+
+// finishInliningTables is called once the version edit carrying the contents
+// of inline tables is applied. If it was committed, the local copies of the
+// tables are removed; otherwise the tables stay in their local files.
+//
+// d.mu must be held when calling this. The local copies are small, so they're
+// removed without releasing it.
+func (d *DB) finishInliningTables(tables []manifest.InlineTable, committed bool) {
+	fileNums := make([]base.DiskFileNum, len(tables))
+	for i := range tables {
+		fileNums[i] = tables[i].FileNum
+	}
+	if !committed {
+		d.inlineTables.drop(fileNums...)
+		return
+	}
+	if err := d.inlineTables.removeLocalCopies(fileNums...); err != nil {
+		// The copies are removed when the DB is reopened.
+		d.opts.Logger.Errorf("failed to remove the local copies of inline tables: %v", err)
+	}
+}
+
+// readObject reads the full contents of a local object.
+func (d *DB) readObject(meta objstorage.ObjectMetadata) ([]byte, error) {
+	r, err := d.objProvider.OpenForReading(context.TODO(), meta.FileType, meta.DiskFileNum, objstorage.OpenOptions{})
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, r.Size())
+	err = r.ReadAt(context.TODO(), data, 0)
+	return data, errors.CombineErrors(err, r.Close())
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineTables(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                          mem,
+		Logger:                      testLogger{t},
+		FormatMajorVersion:          FormatInlineTables,
+		DisableAutomaticCompactions: true,
+	}
+	opts.Experimental.InlineTableMaxSize = 16 << 10
+	// Rotate the MANIFEST on every version edit, so that the inline tables are
+	// carried over by MANIFEST snapshots.
+	opts.MaxManifestFileSize = 1
+	d, err := Open("", opts)
+	require.NoError(t, err)
+
+	countTableFiles := func(fs vfs.FS, dir string) int {
+		t.Helper()
+		ls, err := fs.List(dir)
+		require.NoError(t, err)
+		var n int
+		for _, name := range ls {
+			if strings.HasSuffix(name, ".sst") {
+				n++
+			}
+		}
+		return n
+	}
+	requireValues := func(d *DB, from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			k := fmt.Sprintf("k%03d", i)
+			v, closer, err := d.Get([]byte(k))
+			require.NoError(t, err, k)
+			require.Equal(t, k, string(v))
+			require.NoError(t, closer.Close())
+		}
+	}
+
+	// Tiny flushes are stored inline.
+	for i := 0; i < 3; i++ {
+		for j := i * 10; j < (i+1)*10; j++ {
+			k := fmt.Sprintf("k%03d", j)
+			require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.Equal(t, int64(3), d.Metrics().Levels[0].NumFiles)
+	require.Equal(t, 3, d.inlineTables.count())
+	require.Equal(t, 0, countTableFiles(mem, ""))
+	requireValues(d, 0, 30)
+
+	// Checkpoints carry the inline tables in their MANIFEST.
+	require.NoError(t, d.Checkpoint("checkpoint"))
+	require.Equal(t, 0, countTableFiles(mem, "checkpoint"))
+
+	// The inline tables survive a reopen, even without the option.
+	require.NoError(t, d.Close())
+	d, err = Open("", &Options{FS: mem, Logger: testLogger{t}, DisableAutomaticCompactions: true})
+	require.NoError(t, err)
+	require.Equal(t, 3, d.inlineTables.count())
+	requireValues(d, 0, 30)
+	// Compactions rewrite the inline tables into regular sstables. The inline
+	// tables are dropped once no read state references them anymore: the
+	// table stats job loading the stats of the reopened tables concurrently
+	// holds one until it finishes, just like the read state below.
+	rs := d.loadReadState()
+	require.NoError(t, d.Compact([]byte("k"), []byte("l"), false /* parallelize */))
+	d.cleanupManager.Wait()
+	require.Equal(t, 3, d.inlineTables.count())
+	rs.unref()
+	d.mu.Lock()
+	d.waitTableStats()
+	d.mu.Unlock()
+	d.cleanupManager.Wait()
+	require.Equal(t, 0, d.inlineTables.count())
+	require.Equal(t, 1, countTableFiles(mem, ""))
+	requireValues(d, 0, 30)
+	require.NoError(t, d.Close())
+
+	d, err = Open("checkpoint", &Options{FS: mem, Logger: testLogger{t}})
+	require.NoError(t, err)
+	requireValues(d, 0, 30)
+	require.NoError(t, d.Close())
+}
+
+func TestInlineTablesFormatMajorVersion(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                          mem,
+		Logger:                      testLogger{t},
+		FormatMajorVersion:          FormatInlineTables - 1,
+		DisableAutomaticCompactions: true,
+	}
+	opts.Experimental.InlineTableMaxSize = 16 << 10
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Below FormatInlineTables, flushed tables are written to regular files.
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	require.NoError(t, d.Flush())
+	require.Equal(t, 0, d.inlineTables.count())
+
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatInlineTables))
+	require.NoError(t, d.Set([]byte("b"), []byte("b"), nil))
+	require.NoError(t, d.Flush())
+	require.Equal(t, 1, d.inlineTables.count())
+}
@@ -581,6 +581,246 @@ func CheckComparer(c *Comparer, prefixes [][]byte, suffixes [][]byte) error {
 		}
 	}
 
-	// TODO(radu): check more methods.
-	return nil
+	// Check the remaining methods on all the keys.
+	keys := make([][]byte, 0, len(prefixes)*len(suffixes))
+	for _, p := range prefixes {
+		for _, s := range suffixes {
+			keys = append(keys, slices.Concat(p, s))
+		}
+	}
+	return CheckComparerKeys(c, keys)
+}
+
+// maxComparerViolations is the maximum number of violations reported by
+// CheckComparerKeys.
+const maxComparerViolations = 20
+
+// CheckComparerKeys verifies that a comparer implementation satisfies the
+// invariants documented on the Comparer's functions (Compare, Equal, Split,
+// ComparePointSuffixes, CompareRangeSuffixes, AbbreviatedKey, Separator,
+// Successor and ImmediateSuccessor) on the given sample of valid keys and all
+// pairs thereof. It is recommended that the sample includes keys with and
+// without suffixes, keys that share a prefix, and keys whose prefixes are
+// prefixes of one another.
+//
+// Unlike CheckComparer, which stops at the first error, CheckComparerKeys
+// returns an error joining all the violations found (up to a limit), each
+// describing the keys involved. A faulty comparer can silently corrupt a
+// database, so custom comparers should be verified before being used.
+func CheckComparerKeys(c *Comparer, sampleKeys [][]byte) error {
+	if c.AbbreviatedKey == nil || c.Separator == nil || c.Successor == nil || c.Name == "" {
+		return errors.Errorf("invalid Comparer: mandatory field not set")
+	}
+	cc := comparerChecker{c: c.EnsureDefaults()}
+	cc.comparePointSuffixes = cc.c.ComparePointSuffixes
+	if cc.comparePointSuffixes == nil {
+		cc.comparePointSuffixes = bytes.Compare
+	}
+	for _, k := range sampleKeys {
+		cc.checkKey(k, sampleKeys)
+	}
+	for i, a := range sampleKeys {
+		for _, b := range sampleKeys[i+1:] {
+			cc.checkPair(a, b)
+			cc.checkPair(b, a)
+		}
+	}
+	cc.checkTransitivity(sampleKeys)
+	if len(cc.violations) == 0 {
+		return nil
+	}
+	if cc.truncated > 0 {
+		cc.violations = append(cc.violations, errors.Errorf("%d more violations", cc.truncated))
+	}
+	return errors.Join(cc.violations...)
+}
+
+type comparerChecker struct {
+	c                    *Comparer
+	comparePointSuffixes ComparePointSuffixes
+	violations           []error
+	truncated            int
+}
+
+func (cc *comparerChecker) failf(format string, args ...interface{}) {
+	if len(cc.violations) >= maxComparerViolations {
+		cc.truncated++
+		return
+	}
+	cc.violations = append(cc.violations, errors.Errorf("%s: "+format, append([]interface{}{cc.c.Name}, args...)...))
+}
+
+// validate reports a violation if k, returned by the named function, is not a
+// valid key.
+func (cc *comparerChecker) validate(fn string, k []byte) {
+	if err := cc.c.ValidateKey.Validate(k); err != nil {
+		cc.failf("%s returned invalid key %s: %v", fn, cc.c.FormatKey(k), err)
+	}
+}
+
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return +1
+	default:
+		return 0
+	}
+}
+
+// checkKey verifies the invariants that involve a single key of the sample.
+func (cc *comparerChecker) checkKey(k []byte, sampleKeys [][]byte) {
+	c := cc.c
+	if err := c.ValidateKey.Validate(k); err != nil {
+		cc.failf("sample key %s is invalid: %v", c.FormatKey(k), err)
+		return
+	}
+	if cmp := c.Compare(k, k); cmp != 0 {
+		cc.failf("Compare(%s, %s)=%d, expected 0", c.FormatKey(k), c.FormatKey(k), cmp)
+	}
+	if !c.Equal(k, k) {
+		cc.failf("Equal(%s, %s)=false, expected true", c.FormatKey(k), c.FormatKey(k))
+	}
+	if len(k) > 0 {
+		succ := c.Successor(nil, k)
+		cc.validate("Successor", succ)
+		if cmp := c.Compare(k, succ); cmp > 0 {
+			cc.failf("Successor(%s)=%s sorts before the key", c.FormatKey(k), c.FormatKey(succ))
+		}
+	}
+
+	n := c.Split(k)
+	if n < 0 || n > len(k) {
+		cc.failf("Split(%s)=%d, out of bounds", c.FormatKey(k), n)
+		return
+	}
+	prefix, suffix := k[:n], k[n:]
+	if pn := c.Split(prefix); pn != n {
+		cc.failf("Split(%s)=%d on the prefix of %s, expected %d", c.FormatKey(prefix), pn, c.FormatKey(k), n)
+	}
+	// Removing leading bytes from a prefix must yield a valid prefix.
+	for j := 1; j < n; j++ {
+		if jn := c.Split(k[j:]); jn != n-j {
+			cc.failf("Split(%s)=%d, expected %d after removing %d leading bytes from %s",
+				c.FormatKey(k[j:]), jn, n-j, j, c.FormatKey(k))
+		}
+	}
+	if len(suffix) > 0 {
+		// A key consisting of just a prefix sorts before all other keys with
+		// that prefix, and the empty suffix sorts before all other suffixes.
+		if cmp := c.Compare(prefix, k); cmp >= 0 {
+			cc.failf("Compare(%s, %s)=%d, expected the prefix to sort first", c.FormatKey(prefix), c.FormatKey(k), cmp)
+		}
+		if cmp := cc.comparePointSuffixes(nil, suffix); cmp >= 0 {
+			cc.failf("ComparePointSuffixes(\"\", %q)=%d, expected < 0", suffix, cmp)
+		}
+		if cmp := c.CompareRangeSuffixes(nil, suffix); cmp >= 0 {
+			cc.failf("CompareRangeSuffixes(\"\", %q)=%d, expected < 0", suffix, cmp)
+		}
+	}
+
+	if c.ImmediateSuccessor != nil {
+		succ := c.ImmediateSuccessor(nil, prefix)
+		cc.validate("ImmediateSuccessor", succ)
+		if sn := c.Split(succ); sn != len(succ) {
+			cc.failf("ImmediateSuccessor(%s)=%s is not a prefix", c.FormatKey(prefix), c.FormatKey(succ))
+		}
+		if cmp := c.Compare(prefix, succ); cmp >= 0 {
+			cc.failf("ImmediateSuccessor(%s)=%s does not sort after the prefix", c.FormatKey(prefix), c.FormatKey(succ))
+			return
+		}
+		for _, other := range sampleKeys {
+			on := c.Split(other)
+			if on < 0 || on > len(other) {
+				continue
+			}
+			p := other[:on]
+			if c.Compare(prefix, p) < 0 && c.Compare(p, succ) < 0 {
+				cc.failf("ImmediateSuccessor(%s)=%s, but prefix %s sorts between them",
+					c.FormatKey(prefix), c.FormatKey(succ), c.FormatKey(p))
+			}
+		}
+	}
+}
+
+// checkPair verifies the invariants that involve two distinct keys of the
+// sample.
+func (cc *comparerChecker) checkPair(a, b []byte) {
+	c := cc.c
+	if c.ValidateKey.Validate(a) != nil || c.ValidateKey.Validate(b) != nil {
+		// Already reported by checkKey.
+		return
+	}
+	result := sign(c.Compare(a, b))
+	if rev := sign(c.Compare(b, a)); rev != -result {
+		cc.failf("Compare(%s, %s)=%d but Compare(%s, %s)=%d",
+			c.FormatKey(a), c.FormatKey(b), result, c.FormatKey(b), c.FormatKey(a), rev)
+	}
+	if eq := c.Equal(a, b); eq != (result == 0) {
+		cc.failf("Equal(%s, %s)=%t doesn't agree with Compare", c.FormatKey(a), c.FormatKey(b), eq)
+	}
+
+	an, bn := c.Split(a), c.Split(b)
+	if an >= 0 && an <= len(a) && bn >= 0 && bn <= len(b) {
+		ap, as := a[:an], a[an:]
+		bp, bs := b[:bn], b[bn:]
+		if prefixCmp := bytes.Compare(ap, bp); prefixCmp != 0 {
+			if result != prefixCmp {
+				cc.failf("Compare(%s, %s)=%d, but the prefixes compare %d",
+					c.FormatKey(a), c.FormatKey(b), result, prefixCmp)
+			}
+		} else {
+			if suffixCmp := sign(cc.comparePointSuffixes(as, bs)); result != suffixCmp {
+				cc.failf("Compare(%s, %s)=%d but ComparePointSuffixes(%q, %q)=%d",
+					c.FormatKey(a), c.FormatKey(b), result, as, bs, suffixCmp)
+			}
+			// CompareRangeSuffixes is used to order range keys and mask point
+			// keys; it may be stricter than ComparePointSuffixes but must
+			// otherwise agree with it.
+			rangeCmp := sign(c.CompareRangeSuffixes(as, bs))
+			if rev := sign(c.CompareRangeSuffixes(bs, as)); rev != -rangeCmp {
+				cc.failf("CompareRangeSuffixes(%q, %q)=%d but CompareRangeSuffixes(%q, %q)=%d",
+					as, bs, rangeCmp, bs, as, rev)
+			}
+			if result != 0 && rangeCmp != result {
+				cc.failf("Compare(%s, %s)=%d but CompareRangeSuffixes(%q, %q)=%d",
+					c.FormatKey(a), c.FormatKey(b), result, as, bs, rangeCmp)
+			}
+		}
+	}
+
+	if ak, bk := c.AbbreviatedKey(a), c.AbbreviatedKey(b); (ak < bk && result >= 0) || (ak > bk && result <= 0) {
+		cc.failf("AbbreviatedKey(%s)=%#x and AbbreviatedKey(%s)=%#x don't agree with Compare=%d",
+			c.FormatKey(a), ak, c.FormatKey(b), bk, result)
+	}
+
+	if result < 0 && len(a) > 0 && len(b) > 0 {
+		sep := c.Separator(nil, a, b)
+		cc.validate("Separator", sep)
+		if c.Compare(a, sep) > 0 || c.Compare(sep, b) >= 0 {
+			cc.failf("Separator(%s, %s)=%s is not within [a, b)", c.FormatKey(a), c.FormatKey(b), c.FormatKey(sep))
+		}
+	}
+}
+
+// checkTransitivity verifies that Compare defines a total order on the sample:
+// once sorted, every key must sort before all the keys that follow it.
+func (cc *comparerChecker) checkTransitivity(sampleKeys [][]byte) {
+	c := cc.c
+	sorted := make([][]byte, 0, len(sampleKeys))
+	for _, k := range sampleKeys {
+		if c.ValidateKey.Validate(k) == nil {
+			sorted = append(sorted, k)
+		}
+	}
+	slices.SortFunc(sorted, c.Compare)
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			if c.Compare(sorted[i], sorted[j]) > 0 {
+				cc.failf("Compare is not transitive: %s sorts before %s but Compare(%s, %s) > 0",
+					c.FormatKey(sorted[i]), c.FormatKey(sorted[j]), c.FormatKey(sorted[i]), c.FormatKey(sorted[j]))
+			}
+		}
+	}
 }
@@ -5,9 +5,11 @@
 package base
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand/v2"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -71,6 +73,51 @@ func TestDefaultComparer(t *testing.T) {
 	}
 }
 
+func TestCheckComparerKeys(t *testing.T) {
+	keys := [][]byte{[]byte("a"), []byte("ab"), []byte("abc"), []byte("b"), []byte("black"), []byte("blue")}
+	if err := CheckComparerKeys(DefaultComparer, keys); err != nil {
+		t.Error(err)
+	}
+
+	// A Separator that returns the upper bound.
+	badSeparator := *DefaultComparer
+	badSeparator.Separator = func(dst, a, b []byte) []byte { return append(dst, b...) }
+	err := CheckComparerKeys(&badSeparator, keys)
+	if err == nil || !strings.Contains(err.Error(), "Separator(a, ab)=ab is not within [a, b)") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A Compare that reverses the order, which disagrees with Split,
+	// AbbreviatedKey and Separator.
+	reversed := *DefaultComparer
+	reversed.Compare = func(a, b []byte) int { return bytes.Compare(b, a) }
+	reversed.Equal = bytes.Equal
+	err = CheckComparerKeys(&reversed, keys)
+	if err == nil {
+		t.Fatal("expected violations")
+	}
+	for _, s := range []string{
+		"Compare(a, ab)=1, but the prefixes compare -1",
+		"AbbreviatedKey(a)=",
+		"more violations",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected %q in:\n%v", s, err)
+		}
+	}
+
+	// A Split that doesn't respect the prefix ordering.
+	badSplit := *DefaultComparer
+	badSplit.Split = func(k []byte) int { return min(len(k), 1) }
+	badSplit.ComparePointSuffixes = func(a, b []byte) int { return bytes.Compare(b, a) }
+	badSplit.Compare = nil
+	badSplit.Equal = nil
+	err = CheckComparerKeys(&badSplit, keys)
+	if err == nil || !strings.Contains(err.Error(), "expected the prefix to sort first") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestAbbreviatedKey(t *testing.T) {
 	rng := rand.New(rand.NewPCG(0, uint64(time.Now().UnixNano())))
 	randBytes := func(size int) []byte {
@@ -376,6 +376,9 @@ type InternalIteratorStats struct {
 	BlockBytes uint64
 	// Subset of BlockBytes that were in the block cache.
 	BlockBytesInCache uint64
+	// Subset of BlockBytes that were read from remote storage (i.e. not found
+	// in the block cache, and read from a remote object).
+	RemoteBlockBytes uint64
 	// BlockReadDuration accumulates the duration spent fetching blocks
 	// due to block cache misses.
 	// TODO(sumeer): this currently excludes the time spent in Reader creation,
@@ -417,12 +420,31 @@ type InternalIteratorStats struct {
 		// blocks) that were retrieved.
 		ValueBytesFetched uint64
 	}
+
+	// Stats related to the table filters (e.g. bloom filters) checked by
+	// SeekPrefixGE.
+	Filter struct {
+		// Consulted is the number of filter checks.
+		Consulted uint64
+		// Negatives is the subset of Consulted for which the filters excluded
+		// the prefix, avoiding a lookup in the table.
+		Negatives uint64
+		// SampledPositives is the number of sampled filter checks that passed,
+		// for which the outcome of the subsequent table lookup was checked.
+		SampledPositives uint64
+		// SampledFalsePositives is the subset of SampledPositives for which the
+		// table lookup did not find a key with the prefix. It can overcount if
+		// the table only contains keys with the prefix before the sought key,
+		// or beyond the iterator's upper bound.
+		SampledFalsePositives uint64
+	}
 }
 
 // Merge merges the stats in from into the given stats.
 func (s *InternalIteratorStats) Merge(from InternalIteratorStats) {
 	s.BlockBytes += from.BlockBytes
 	s.BlockBytesInCache += from.BlockBytesInCache
+	s.RemoteBlockBytes += from.RemoteBlockBytes
 	s.BlockReadDuration += from.BlockReadDuration
 	s.KeyBytes += from.KeyBytes
 	s.ValueBytes += from.ValueBytes
@@ -431,6 +453,10 @@ func (s *InternalIteratorStats) Merge(from InternalIteratorStats) {
 	s.SeparatedPointValue.Count += from.SeparatedPointValue.Count
 	s.SeparatedPointValue.ValueBytes += from.SeparatedPointValue.ValueBytes
 	s.SeparatedPointValue.ValueBytesFetched += from.SeparatedPointValue.ValueBytesFetched
+	s.Filter.Consulted += from.Filter.Consulted
+	s.Filter.Negatives += from.Filter.Negatives
+	s.Filter.SampledPositives += from.Filter.SampledPositives
+	s.Filter.SampledFalsePositives += from.Filter.SampledFalsePositives
 }
 
 func (s *InternalIteratorStats) String() string {
@@ -463,6 +489,13 @@ func (s *InternalIteratorStats) SafeFormat(p redact.SafePrinter, verb rune) {
 			humanize.Bytes.Uint64(s.SeparatedPointValue.ValueBytes),
 			humanize.Bytes.Uint64(s.SeparatedPointValue.ValueBytesFetched))
 	}
+	if s.Filter.Consulted != 0 {
+		p.Printf("; filter: %s consulted, %s negative, %s/%s sampled false positives",
+			humanize.Count.Uint64(s.Filter.Consulted),
+			humanize.Count.Uint64(s.Filter.Negatives),
+			humanize.Count.Uint64(s.Filter.SampledFalsePositives),
+			humanize.Count.Uint64(s.Filter.SampledPositives))
+	}
 }
 
 // IteratorDebug is an interface implemented by all internal iterators and
@@ -208,6 +208,18 @@ func (lv *LazyValue) Value(buf []byte) (val []byte, callerOwned bool, err error)
 	return lv.fetchValue(context.TODO(), buf)
 }
 
+// ValueWithContext is like Value, but uses the provided context if the value
+// needs to be fetched. If the context is canceled, the fetch may fail with the
+// context's error.
+func (lv *LazyValue) ValueWithContext(
+	ctx context.Context, buf []byte,
+) (val []byte, callerOwned bool, err error) {
+	if lv.Fetcher == nil {
+		return lv.ValueOrHandle, false, nil
+	}
+	return lv.fetchValue(ctx, buf)
+}
+
 // INVARIANT: lv.Fetcher != nil
 func (lv *LazyValue) fetchValue(
 	ctx context.Context, buf []byte,
@@ -4,7 +4,10 @@
 
 package base
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
 
 // ThroughputMetric is used to measure the byte throughput of some component
 // that performs work in a single-threaded manner. The throughput can be
@@ -89,6 +92,11 @@ func (gsm *GaugeSampleMetric) Subtract(x GaugeSampleMetric) {
 	gsm.count -= x.count
 }
 
+// Count returns the number of samples.
+func (gsm *GaugeSampleMetric) Count() int64 {
+	return gsm.count
+}
+
 // Mean returns the mean value.
 func (gsm *GaugeSampleMetric) Mean() float64 {
 	if gsm.count == 0 {
@@ -96,3 +104,25 @@ func (gsm *GaugeSampleMetric) Mean() float64 {
 	}
 	return float64(gsm.sampleSum) / float64(gsm.count)
 }
+
+// AtomicGaugeSampleMetric is a GaugeSampleMetric that may be sampled
+// concurrently.
+type AtomicGaugeSampleMetric struct {
+	sampleSum atomic.Int64
+	count     atomic.Int64
+}
+
+// AddSample adds the given sample.
+func (a *AtomicGaugeSampleMetric) AddSample(sample int64) {
+	a.sampleSum.Add(sample)
+	a.count.Add(1)
+}
+
+// Load returns the samples accumulated so far. Samples added concurrently may
+// be partially reflected.
+func (a *AtomicGaugeSampleMetric) Load() GaugeSampleMetric {
+	return GaugeSampleMetric{
+		sampleSum: a.sampleSum.Load(),
+		count:     a.count.Load(),
+	}
+}
@@ -5,6 +5,7 @@
 package base
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -77,3 +78,21 @@ func TestGaugeSampleMetricSubtract(t *testing.T) {
 	require.Equal(t, int64(2), g1.count)
 
 }
+
+func TestAtomicGaugeSampleMetric(t *testing.T) {
+	var a AtomicGaugeSampleMetric
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				a.AddSample(10)
+			}
+		}()
+	}
+	wg.Wait()
+	g := a.Load()
+	require.EqualValues(t, 400, g.Count())
+	require.EqualValues(t, 10, g.Mean())
+}
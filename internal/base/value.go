@@ -4,7 +4,11 @@
 
 package base
 
-import "github.com/cockroachdb/pebble/internal/invariants"
+import (
+	"context"
+
+	"github.com/cockroachdb/pebble/internal/invariants"
+)
 
 // An InternalValue represents a value. The value may be in-memory, immediately
 // accessible, or it may be stored out-of-band and need to be fetched when
@@ -79,6 +83,14 @@ func (v *InternalValue) Value(buf []byte) (val []byte, callerOwned bool, err err
 	return v.lazyValue.Value(buf)
 }
 
+// ValueWithContext is like Value, but uses the provided context if the value
+// needs to be fetched.
+func (v *InternalValue) ValueWithContext(
+	ctx context.Context, buf []byte,
+) (val []byte, callerOwned bool, err error) {
+	return v.lazyValue.ValueWithContext(ctx, buf)
+}
+
 // Clone creates a stable copy of the value, by appending bytes to buf.  The
 // fetcher parameter must be non-nil and may be over-written and used inside the
 // returned InternalValue -- this is needed to avoid an allocation.
@@ -74,7 +74,7 @@ type Metrics struct {
 // "tracing" produces a significant slowdown, while "invariants" does not.
 type Cache struct {
 	refs    atomic.Int64
-	maxSize int64
+	maxSize atomic.Int64
 	idAlloc atomic.Uint64
 	shards  []shard
 
@@ -125,10 +125,10 @@ func New(size int64) *Cache {
 
 func newCache(size int64, shards int) *Cache {
 	c := &Cache{
-		maxSize: size,
-		shards:  make([]shard, shards),
-		stack:   string(debug.Stack()),
+		shards: make([]shard, shards),
+		stack:  string(debug.Stack()),
 	}
+	c.maxSize.Store(size)
 	c.refs.Store(1)
 	c.trace("alloc", c.refs.Load())
 	for i := range c.shards {
@@ -222,7 +222,16 @@ func (c *Cache) Metrics() Metrics {
 
 // MaxSize returns the max size of the cache.
 func (c *Cache) MaxSize() int64 {
-	return c.maxSize
+	return c.maxSize.Load()
+}
+
+// SetMaxSize changes the max size of the cache. If the cache is shrunk, blocks
+// are evicted until the cache fits within the new size.
+func (c *Cache) SetMaxSize(size int64) {
+	c.maxSize.Store(size)
+	for i := range c.shards {
+		c.shards[i].setMaxSize(size / int64(len(c.shards)))
+	}
 }
 
 // Size returns the current space used by the cache.
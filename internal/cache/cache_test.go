@@ -206,6 +206,30 @@ func TestReserve(t *testing.T) {
 	require.EqualValues(t, 4, cache.Size())
 }
 
+func TestSetMaxSize(t *testing.T) {
+	cache := newCache(100, 1)
+	defer cache.Unref()
+	h := cache.NewHandle()
+	defer h.Close()
+
+	for i := 0; i < 50; i++ {
+		setTestValue(h, 0, uint64(i), "a", 1)
+	}
+	require.EqualValues(t, 50, cache.Size())
+
+	// Shrinking the cache evicts blocks.
+	cache.SetMaxSize(20)
+	require.EqualValues(t, 20, cache.MaxSize())
+	require.LessOrEqual(t, cache.Size(), int64(20))
+
+	// Growing the cache allows more blocks to be cached.
+	cache.SetMaxSize(200)
+	for i := 0; i < 100; i++ {
+		setTestValue(h, 0, uint64(i), "a", 1)
+	}
+	require.EqualValues(t, 100, cache.Size())
+}
+
 func TestReserveDoubleRelease(t *testing.T) {
 	cache := newCache(100, 1)
 	defer cache.Unref()
@@ -124,6 +124,12 @@ func (v *Value) acquire() {
 	v.ref.acquire()
 }
 
+// Acquire acquires an additional ref count on the buffer, which must be
+// released with Release.
+func (v *Value) Acquire() {
+	v.acquire()
+}
+
 // Release a ref count on the buffer. It is a no-op to call Release on a nil
 // Value.
 func (v *Value) Release() {
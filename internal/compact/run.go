@@ -51,6 +51,10 @@ type OutputTable struct {
 	BlobReferences manifest.BlobReferences
 	// BlobReferenceDepth is the depth of the blob references for the table.
 	BlobReferenceDepth manifest.BlobReferenceDepth
+	// AttributedBytes contains, for each of RunnerConfig.AttributedRanges, an
+	// estimate of the bytes of the table (and of the blob files written along
+	// with it) that fall within the range.
+	AttributedBytes []uint64
 }
 
 // OutputBlob contains metadata about a blob file that was created during a
@@ -113,13 +117,19 @@ type RunnerConfig struct {
 	// file. Implementations may implement heuristics that determine when to
 	// separate a value.
 	ValueSeparation ValueSeparation
+
+	// AttributedRanges are key ranges to which the bytes written to each output
+	// table are attributed (see OutputTable.AttributedBytes). The bytes are
+	// attributed in proportion to the sizes of the keys and values that fall
+	// within each range; the ranges may overlap.
+	AttributedRanges []base.UserKeyBounds
 }
 
 // ValueSeparation defines an interface for writing some values to separate blob
 // files.
 type ValueSeparation interface {
 	// EstimatedFileSize returns an estimate of the disk space consumed by the
-	// current, pending blob file if it were closed now. If no blob file has
+	// current, pending blob files if they were closed now. If no blob file has
 	// been created, it returns 0.
 	EstimatedFileSize() uint64
 	// EstimatedReferenceSize returns an estimate of the disk space consumed by
@@ -130,22 +140,21 @@ type ValueSeparation interface {
 	Add(tw sstable.RawWriter, kv *base.InternalKV, forceObsolete bool) error
 	// FinishOutput is called when a compaction is finishing an output sstable.
 	// It returns the table's blob references, which will be added to the
-	// table's TableMetadata, and stats and metadata describing a newly
-	// constructed blob file if any.
+	// table's TableMetadata, and stats and metadata describing any newly
+	// constructed blob files.
 	FinishOutput() (ValueSeparationMetadata, error)
 }
 
 // ValueSeparationMetadata describes metadata about a table's blob references,
-// and optionally a newly constructed blob file.
+// and optionally newly constructed blob files.
 type ValueSeparationMetadata struct {
 	BlobReferences     manifest.BlobReferences
 	BlobReferenceSize  uint64
 	BlobReferenceDepth manifest.BlobReferenceDepth
 
-	// The below fields are only populated if a new blob file was created.
-	BlobFileStats    blob.FileWriterStats
-	BlobFileObject   objstorage.ObjectMetadata
-	BlobFileMetadata *manifest.BlobFileMetadata
+	// NewBlobFiles describes the blob files newly constructed for the table,
+	// if any.
+	NewBlobFiles []OutputBlob
 }
 
 // Runner is a helper for running the "data" part of a compaction (where we use
@@ -175,6 +184,11 @@ type Runner struct {
 	// Last range key span (or portion of it) that was not yet written to a table.
 	lastRangeKeySpan keyspan.Span
 	stats            Stats
+	// rawBytes is the size of the keys and values written to the current output
+	// table, and rawAttributedBytes the subsets of it that fall within each of
+	// cfg.AttributedRanges.
+	rawBytes           uint64
+	rawAttributedBytes []uint64
 }
 
 // NewRunner creates a new Runner.
@@ -187,6 +201,9 @@ func NewRunner(cfg RunnerConfig, iter *Iter) *Runner {
 	if r.cfg.ValueSeparation == nil {
 		r.cfg.ValueSeparation = NeverSeparateValues{}
 	}
+	if len(r.cfg.AttributedRanges) > 0 {
+		r.rawAttributedBytes = make([]uint64, len(r.cfg.AttributedRanges))
+	}
 	r.kv = r.iter.First()
 	return r
 }
@@ -220,13 +237,7 @@ func (r *Runner) WriteTable(objMeta objstorage.ObjectMetadata, tw sstable.RawWri
 	} else {
 		r.tables[len(r.tables)-1].BlobReferences = valSepMeta.BlobReferences
 		r.tables[len(r.tables)-1].BlobReferenceDepth = valSepMeta.BlobReferenceDepth
-		if valSepMeta.BlobFileObject.DiskFileNum != 0 {
-			r.blobs = append(r.blobs, OutputBlob{
-				Stats:    valSepMeta.BlobFileStats,
-				ObjMeta:  valSepMeta.BlobFileObject,
-				Metadata: valSepMeta.BlobFileMetadata,
-			})
-		}
+		r.blobs = append(r.blobs, valSepMeta.NewBlobFiles...)
 	}
 
 	err = errors.CombineErrors(err, tw.Close())
@@ -247,7 +258,30 @@ func (r *Runner) WriteTable(objMeta objstorage.ObjectMetadata, tw sstable.RawWri
 	r.tables[len(r.tables)-1].WriterMeta = *writerMeta
 	r.stats.CumulativeWrittenSize += writerMeta.Size
 	r.stats.CumulativeBlobReferenceSize += valSepMeta.BlobReferenceSize
-	r.stats.CumulativeBlobFileSize += valSepMeta.BlobFileStats.FileLen
+	outputSize := writerMeta.Size
+	for i := range valSepMeta.NewBlobFiles {
+		r.stats.CumulativeBlobFileSize += valSepMeta.NewBlobFiles[i].Stats.FileLen
+		outputSize += valSepMeta.NewBlobFiles[i].Stats.FileLen
+	}
+	r.tables[len(r.tables)-1].AttributedBytes = r.attributeOutput(outputSize)
+}
+
+// attributeOutput returns the estimates of the bytes of an output of the given
+// size that fall within each of cfg.AttributedRanges, and resets the raw byte
+// counts for the next output table.
+func (r *Runner) attributeOutput(outputSize uint64) []uint64 {
+	if len(r.rawAttributedBytes) == 0 {
+		return nil
+	}
+	attributed := make([]uint64, len(r.rawAttributedBytes))
+	if r.rawBytes > 0 {
+		for i, raw := range r.rawAttributedBytes {
+			attributed[i] = uint64(float64(outputSize) * float64(raw) / float64(r.rawBytes))
+		}
+	}
+	r.rawBytes = 0
+	clear(r.rawAttributedBytes)
+	return attributed
 }
 
 func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ error) {
@@ -315,6 +349,15 @@ func (r *Runner) writeKeysToTable(tw sstable.RawWriter) (splitKey []byte, _ erro
 		if err := r.cfg.ValueSeparation.Add(tw, kv, r.iter.ForceObsoleteDueToRangeDel()); err != nil {
 			return nil, err
 		}
+		if r.rawAttributedBytes != nil {
+			n := uint64(len(kv.K.UserKey) + valueLen)
+			r.rawBytes += n
+			for i := range r.cfg.AttributedRanges {
+				if r.cfg.AttributedRanges[i].ContainsUserKey(r.cmp, kv.K.UserKey) {
+					r.rawAttributedBytes[i] += n
+				}
+			}
+		}
 		if r.iter.SnapshotPinned() {
 			// The kv pair we just added to the sstable was only surfaced by
 			// the compaction iterator because an open snapshot prevented
@@ -380,7 +423,7 @@ func (r *Runner) TableSplitLimit(startKey []byte) []byte {
 	// much data at level N+1. We want to avoid such large overlaps because they
 	// translate into large compactions. The current heuristic stops output of a
 	// table if the addition of another key would cause the table to overlap more
-	// than 10x the target file size at level N. See
+	// than 10x (by default) the target file size at level N. See
 	// compaction.maxGrandparentOverlapBytes.
 	iter := r.cfg.Grandparents.Iter()
 	var overlappedBytes uint64
@@ -0,0 +1,265 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+// Package hotkeys implements the approximate tracking of the most frequently
+// read and written keys, using a count-min sketch of the key frequencies and a
+// top-K heap of the most frequent keys.
+package hotkeys
+
+import (
+	"bytes"
+	"cmp"
+	"container/heap"
+	"hash/maphash"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Op is the kind of access to a key.
+type Op int8
+
+const (
+	// Read is a point lookup or an iterator seek.
+	Read Op = iota
+	// Write is a write to the key.
+	Write
+	numOps
+)
+
+const (
+	// sketchDepth and sketchWidth are the dimensions of the count-min sketches.
+	// With these dimensions, the frequency of a key is overestimated by at most
+	// e/sketchWidth (~0.07%) of the total number of samples, with probability
+	// 1-e^-sketchDepth (~98%).
+	sketchDepth = 4
+	sketchWidth = 4096
+	// agingPeriod is the number of samples after which all the frequencies are
+	// halved, so that they reflect the recent accesses.
+	agingPeriod = 1 << 18
+)
+
+// Key is a key tracked by a Tracker, with its estimated frequencies.
+type Key struct {
+	Key []byte
+	// Reads and Writes are the estimated numbers of reads and writes of the
+	// key, scaled by the sample rate.
+	Reads, Writes uint64
+}
+
+// Tracker samples the reads and writes of keys, and tracks the most frequent
+// ones. It is safe for concurrent use.
+type Tracker struct {
+	sampleRate uint64
+	counter    atomic.Uint64
+
+	mu struct {
+		sync.Mutex
+		sketches [numOps]sketch
+		samples  uint64
+		top      topK
+	}
+}
+
+// New returns a Tracker that tracks the k most frequent keys, sampling one in
+// sampleRate accesses.
+func New(k int, sampleRate int) *Tracker {
+	t := &Tracker{sampleRate: uint64(max(sampleRate, 1))}
+	seeds := [sketchDepth]maphash.Seed{}
+	for i := range seeds {
+		seeds[i] = maphash.MakeSeed()
+	}
+	for i := range t.mu.sketches {
+		t.mu.sketches[i].seeds = &seeds
+	}
+	t.mu.top.k = k
+	t.mu.top.index = make(map[string]int, k)
+	return t
+}
+
+// Record records an access of the given kind to the key, if it's sampled. The
+// key is copied if it's retained.
+func (t *Tracker) Record(op Op, key []byte) {
+	if t.counter.Add(1)%t.sampleRate != 0 {
+		return
+	}
+	t.RecordSampled(op, key)
+}
+
+// SampleN accounts for n accesses at once, for callers that would otherwise
+// call Record for each of them. It returns the index (in [0, n)) of the first
+// sampled access, or -1 if none of them is sampled. The following sampled
+// accesses are every SampleRate accesses after the first one. The sampled
+// accesses must be recorded with RecordSampled.
+func (t *Tracker) SampleN(n int) int {
+	if n <= 0 {
+		return -1
+	}
+	// The accesses are numbered from start+1 to start+n; access i is sampled
+	// if i is a multiple of the sample rate.
+	start := t.counter.Add(uint64(n)) - uint64(n)
+	first := t.sampleRate - start%t.sampleRate - 1
+	if first >= uint64(n) {
+		return -1
+	}
+	return int(first)
+}
+
+// SampleRate returns the rate at which the accesses are sampled.
+func (t *Tracker) SampleRate() int {
+	return int(t.sampleRate)
+}
+
+// RecordSampled records an access of the given kind to the key, which was
+// sampled through SampleN. The key is copied if it's retained.
+func (t *Tracker) RecordSampled(op Op, key []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mu.samples++
+	if t.mu.samples%agingPeriod == 0 {
+		for i := range t.mu.sketches {
+			t.mu.sketches[i].halve()
+		}
+		t.mu.top.halve()
+	}
+	var h [sketchDepth]uint64
+	t.mu.sketches[op].hash(key, &h)
+	t.mu.sketches[op].add(&h)
+	var freq uint32
+	for i := range t.mu.sketches {
+		freq += t.mu.sketches[i].estimate(&h)
+	}
+	t.mu.top.update(key, freq)
+}
+
+// Top returns the tracked keys, in decreasing order of their estimated
+// frequencies.
+func (t *Tracker) Top() []Key {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]Key, 0, len(t.mu.top.entries))
+	var h [sketchDepth]uint64
+	for _, e := range t.mu.top.entries {
+		t.mu.sketches[Read].hash([]byte(e.key), &h)
+		keys = append(keys, Key{
+			Key:    []byte(e.key),
+			Reads:  uint64(t.mu.sketches[Read].estimate(&h)) * t.sampleRate,
+			Writes: uint64(t.mu.sketches[Write].estimate(&h)) * t.sampleRate,
+		})
+	}
+	slices.SortFunc(keys, func(a, b Key) int {
+		if c := cmp.Compare(b.Reads+b.Writes, a.Reads+a.Writes); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Key, b.Key)
+	})
+	return keys
+}
+
+// sketch is a count-min sketch.
+type sketch struct {
+	// seeds are shared by the sketches of a Tracker, so that a key hashes to
+	// the same counters in all of them.
+	seeds    *[sketchDepth]maphash.Seed
+	counters [sketchDepth][sketchWidth]uint32
+}
+
+func (s *sketch) hash(key []byte, h *[sketchDepth]uint64) {
+	for i := range h {
+		h[i] = maphash.Bytes(s.seeds[i], key) % sketchWidth
+	}
+}
+
+func (s *sketch) add(h *[sketchDepth]uint64) {
+	for i := range h {
+		if c := &s.counters[i][h[i]]; *c < ^uint32(0) {
+			*c++
+		}
+	}
+}
+
+func (s *sketch) estimate(h *[sketchDepth]uint64) uint32 {
+	est := ^uint32(0)
+	for i := range h {
+		est = min(est, s.counters[i][h[i]])
+	}
+	return est
+}
+
+func (s *sketch) halve() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] /= 2
+		}
+	}
+}
+
+// topK is a min-heap of the k keys with the highest frequencies, with an index
+// of the keys' positions in the heap.
+type topK struct {
+	k       int
+	entries []topKEntry
+	index   map[string]int
+}
+
+type topKEntry struct {
+	key  string
+	freq uint32
+}
+
+// update records the new frequency of the key, adding it to the heap if it's
+// higher than the lowest frequency in the heap.
+func (t *topK) update(key []byte, freq uint32) {
+	if i, ok := t.index[string(key)]; ok {
+		t.entries[i].freq = freq
+		heap.Fix(t, i)
+		return
+	}
+	if len(t.entries) < t.k {
+		heap.Push(t, topKEntry{key: string(key), freq: freq})
+		return
+	}
+	if t.k == 0 || freq <= t.entries[0].freq {
+		return
+	}
+	delete(t.index, t.entries[0].key)
+	t.entries[0] = topKEntry{key: string(key), freq: freq}
+	t.index[t.entries[0].key] = 0
+	heap.Fix(t, 0)
+}
+
+func (t *topK) halve() {
+	// Halving all the frequencies preserves the heap order.
+	for i := range t.entries {
+		t.entries[i].freq /= 2
+	}
+}
+
+// Len implements heap.Interface.
+func (t *topK) Len() int { return len(t.entries) }
+
+// Less implements heap.Interface.
+func (t *topK) Less(i, j int) bool { return t.entries[i].freq < t.entries[j].freq }
+
+// Swap implements heap.Interface.
+func (t *topK) Swap(i, j int) {
+	t.entries[i], t.entries[j] = t.entries[j], t.entries[i]
+	t.index[t.entries[i].key] = i
+	t.index[t.entries[j].key] = j
+}
+
+// Push implements heap.Interface.
+func (t *topK) Push(x any) {
+	e := x.(topKEntry)
+	t.index[e.key] = len(t.entries)
+	t.entries = append(t.entries, e)
+}
+
+// Pop implements heap.Interface.
+func (t *topK) Pop() any {
+	e := t.entries[len(t.entries)-1]
+	t.entries = t.entries[:len(t.entries)-1]
+	delete(t.index, e.key)
+	return e
+}
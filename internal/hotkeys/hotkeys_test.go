@@ -0,0 +1,83 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package hotkeys
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	tr := New(3, 1)
+	rng := rand.New(rand.NewPCG(0, 0))
+	for i := 0; i < 10000; i++ {
+		// Every tenth access is to one of three hot keys; the other accesses
+		// are spread over many cold keys.
+		switch i % 30 {
+		case 0:
+			tr.Record(Read, []byte("hot-read"))
+		case 10:
+			tr.Record(Write, []byte("hot-write"))
+		case 20:
+			tr.Record(Read, []byte("hot-both"))
+			tr.Record(Write, []byte("hot-both"))
+		default:
+			tr.Record(Op(rng.IntN(2)), []byte(fmt.Sprintf("cold-%d", rng.IntN(100000))))
+		}
+	}
+
+	top := tr.Top()
+	require.Len(t, top, 3)
+	require.Equal(t, "hot-both", string(top[0].Key))
+	require.InDelta(t, 334, top[0].Reads, 10)
+	require.InDelta(t, 334, top[0].Writes, 10)
+	require.ElementsMatch(t, []string{"hot-read", "hot-write"}, []string{string(top[1].Key), string(top[2].Key)})
+	for _, k := range top[1:] {
+		if string(k.Key) == "hot-read" {
+			require.InDelta(t, 334, k.Reads, 10)
+			require.InDelta(t, 0, k.Writes, 10)
+		} else {
+			require.InDelta(t, 0, k.Reads, 10)
+			require.InDelta(t, 334, k.Writes, 10)
+		}
+	}
+}
+
+func TestTrackerSampling(t *testing.T) {
+	tr := New(1, 10)
+	for i := 0; i < 1000; i++ {
+		tr.Record(Read, []byte("a"))
+	}
+	top := tr.Top()
+	require.Len(t, top, 1)
+	require.Equal(t, "a", string(top[0].Key))
+	// The estimates are scaled by the sample rate.
+	require.Equal(t, uint64(1000), top[0].Reads)
+}
+
+func TestTrackerSampleN(t *testing.T) {
+	tr := New(1, 10)
+	// Accesses 1 to 25: accesses 10 and 20 are sampled.
+	require.Equal(t, 9, tr.SampleN(25))
+	// Accesses 26 to 29.
+	require.Equal(t, -1, tr.SampleN(4))
+	// Accesses 30 to 30.
+	require.Equal(t, 0, tr.SampleN(1))
+	require.Equal(t, -1, tr.SampleN(0))
+	// SampleN and Record share the sampling.
+	for i := 0; i < 9; i++ {
+		tr.Record(Read, []byte("a"))
+	}
+	require.Empty(t, tr.Top())
+	require.Equal(t, 0, tr.SampleN(10))
+	tr.RecordSampled(Write, []byte("b"))
+	top := tr.Top()
+	require.Len(t, top, 1)
+	require.Equal(t, "b", string(top[0].Key))
+	require.Equal(t, uint64(10), top[0].Writes)
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -87,6 +88,48 @@ type TableStats struct {
 	// This statistic is used to determine eligibility for a tombstone density
 	// compaction.
 	TombstoneDenseBlocksRatio float64
+	// UserProperties holds the table-level properties of the block property
+	// collectors the table was written with that are aggregated by
+	// Options.UserPropertyAggregators, without the collectors' short IDs. For
+	// a virtual table, these are the properties of its backing table. It must
+	// not be modified.
+	UserProperties map[string]string
+}
+
+// TableAccessStats records sampled reads of a table. Reads are sampled by
+// iterators (alongside read-triggered compaction sampling) and by Get. The
+// statistics describe how "hot" a table is, and can inform tiering and caching
+// policies. They are safe for concurrent use.
+//
+// This state is in-memory only. It is not persisted to the manifest and is
+// reset when the DB is reopened.
+type TableAccessStats struct {
+	// lastAccess is the time of the most recent sampled read, in nanoseconds
+	// since the epoch. Zero if the table has not been read since it was added
+	// to the LSM (or since the DB was opened).
+	lastAccess atomic.Int64
+	// count is the number of sampled reads.
+	count atomic.Uint64
+}
+
+// Record records a sampled read of the table at the given time.
+func (s *TableAccessStats) Record(now time.Time) {
+	s.count.Add(1)
+	s.lastAccess.Store(now.UnixNano())
+}
+
+// LastAccess returns the time of the most recent sampled read, or the zero
+// time if no read has been sampled.
+func (s *TableAccessStats) LastAccess() time.Time {
+	if n := s.lastAccess.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
+}
+
+// Count returns the number of sampled reads of the table.
+func (s *TableAccessStats) Count() uint64 {
+	return s.count.Load()
 }
 
 // boundType represents the type of key (point or range) present as the smallest
@@ -174,6 +217,9 @@ type TableMetadata struct {
 	// that returns a user key (eg. Next, Prev, SeekGE, SeekLT, etc).
 	AllowedSeeks atomic.Int64
 
+	// Access records sampled reads of the table. See TableAccessStats.
+	Access TableAccessStats
+
 	// statsValid indicates if stats have been loaded for the table. The
 	// TableStats structure is populated only if valid is true.
 	statsValid atomic.Bool
@@ -318,6 +364,15 @@ type TableMetadata struct {
 	// SyntheticPrefix is used to prepend a prefix to all keys and/or override all
 	// suffixes in a table; used for some virtual tables.
 	SyntheticPrefixAndSuffix sstable.SyntheticPrefixAndSuffix
+	// ContentPrefix, if set, is a prefix of every key physically stored in the
+	// backing table that is replaced by the synthetic prefix (which may be
+	// empty) at read time; used for some virtual tables. See
+	// sstable.PrefixReplacement.
+	ContentPrefix []byte
+	// SuffixReplacements, if set, replace the suffixes of the keys of the
+	// table at read time; used for some virtual tables. See
+	// sstable.SuffixReplacement.
+	SuffixReplacements sstable.SuffixReplacements
 }
 
 // Ref increments the table's ref count. If this is the table's first reference,
@@ -397,22 +452,47 @@ func (m *TableMetadata) SyntheticSeqNum() sstable.SyntheticSeqNum {
 	return sstable.NoSyntheticSeqNum
 }
 
+// PrefixReplacement returns the replacement of the table's content prefix with
+// its synthetic prefix. The returned replacement is unset if the table has no
+// content prefix.
+func (m *TableMetadata) PrefixReplacement() sstable.PrefixReplacement {
+	if len(m.ContentPrefix) == 0 {
+		return sstable.PrefixReplacement{}
+	}
+	return sstable.PrefixReplacement{
+		ContentPrefix:   m.ContentPrefix,
+		SyntheticPrefix: m.SyntheticPrefixAndSuffix.Prefix(),
+	}
+}
+
 // IterTransforms returns an sstable.IterTransforms populated according to the
 // file.
+//
+// If the table has a content prefix, the synthetic prefix is omitted from the
+// transforms: replacing the content prefix is the responsibility of the
+// caller (see PrefixReplacement).
 func (m *TableMetadata) IterTransforms() sstable.IterTransforms {
 	return sstable.IterTransforms{
 		SyntheticSeqNum:          m.SyntheticSeqNum(),
-		SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+		SyntheticPrefixAndSuffix: m.blockPrefixAndSuffix(),
 	}
 }
 
 // FragmentIterTransforms returns an sstable.FragmentIterTransforms populated
-// according to the file.
+// according to the file. As with IterTransforms, the synthetic prefix is
+// omitted if the table has a content prefix.
 func (m *TableMetadata) FragmentIterTransforms() sstable.FragmentIterTransforms {
 	return sstable.FragmentIterTransforms{
 		SyntheticSeqNum:          m.SyntheticSeqNum(),
-		SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+		SyntheticPrefixAndSuffix: m.blockPrefixAndSuffix(),
+	}
+}
+
+func (m *TableMetadata) blockPrefixAndSuffix() sstable.SyntheticPrefixAndSuffix {
+	if len(m.ContentPrefix) > 0 {
+		return m.SyntheticPrefixAndSuffix.RemovePrefix()
 	}
+	return m.SyntheticPrefixAndSuffix
 }
 
 // PhysicalTableMeta is used by functions which want a guarantee that their input
@@ -462,10 +542,30 @@ type VirtualTableMeta struct {
 
 // VirtualReaderParams fills in the parameters necessary to create a virtual
 // sstable reader.
-func (m VirtualTableMeta) VirtualReaderParams(isShared bool) sstable.VirtualReaderParams {
+//
+// If the table has a content prefix or suffix replacements, the bounds are
+// expressed in terms of the keys physically stored in the backing table.
+func (m VirtualTableMeta) VirtualReaderParams(
+	isShared bool, cmp *base.Comparer,
+) sstable.VirtualReaderParams {
+	lower, upper := m.Smallest, m.Largest
+	if len(m.SuffixReplacements) > 0 {
+		lower.UserKey, _ = m.SuffixReplacements.InvertTo(cmp, nil, lower.UserKey)
+		var exact bool
+		upper.UserKey, exact = m.SuffixReplacements.InvertTo(cmp, nil, upper.UserKey)
+		if !exact && !upper.IsExclusiveSentinel() {
+			// The inverted key is the successor of the bound's prefix, which
+			// the table's keys sort before.
+			upper = base.MakeRangeDeleteSentinelKey(upper.UserKey)
+		}
+	}
+	if pr := m.PrefixReplacement(); pr.IsSet() {
+		lower.UserKey = pr.Invert(lower.UserKey)
+		upper.UserKey = pr.Invert(upper.UserKey)
+	}
 	return sstable.VirtualReaderParams{
-		Lower:            m.Smallest,
-		Upper:            m.Largest,
+		Lower:            lower,
+		Upper:            upper,
 		FileNum:          m.FileNum,
 		IsSharedIngested: isShared && m.SyntheticSeqNum() != 0,
 		Size:             m.Size,
@@ -1064,6 +1164,17 @@ func (m *TableMetadata) Validate(cmp Compare, formatKey base.FormatKey) error {
 			return base.CorruptionErrorf("non-virtual file with synthetic suffix")
 		}
 	}
+	if len(m.ContentPrefix) > 0 && !m.Virtual {
+		return base.CorruptionErrorf("non-virtual file with content prefix")
+	}
+	if len(m.SuffixReplacements) > 0 {
+		if !m.Virtual {
+			return base.CorruptionErrorf("non-virtual file with suffix replacements")
+		}
+		if m.SyntheticPrefixAndSuffix.HasSuffix() {
+			return base.CorruptionErrorf("virtual file with both a synthetic suffix and suffix replacements")
+		}
+	}
 
 	return nil
 }
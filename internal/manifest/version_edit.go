@@ -5,7 +5,6 @@
 package manifest
 
 import (
-	"bufio"
 	"bytes"
 	stdcmp "cmp"
 	"encoding/binary"
@@ -27,9 +26,12 @@ import (
 
 var errCorruptManifest = base.CorruptionErrorf("pebble: corrupt manifest")
 
+// byteReader is the reader of a version edit. Len returns the number of
+// unread bytes of the edit, which bounds the counts decoded from it.
 type byteReader interface {
 	io.ByteReader
 	io.Reader
+	Len() int
 }
 
 // Tags for the versionEdit disk format.
@@ -46,20 +48,31 @@ const (
 	tagPrevLogNumber  = 9
 
 	// RocksDB tags.
-	tagNewFile2         = 100
-	tagNewFile3         = 102
-	tagNewFile4         = 103
-	tagColumnFamily     = 200
-	tagColumnFamilyAdd  = 201
-	tagColumnFamilyDrop = 202
-	tagMaxColumnFamily  = 203
+	tagMinLogNumberToKeep = 10
+	tagNewFile2           = 100
+	tagNewFile3           = 102
+	tagNewFile4           = 103
+	tagColumnFamily       = 200
+	tagColumnFamilyAdd    = 201
+	tagColumnFamilyDrop   = 202
+	tagMaxColumnFamily    = 203
+	tagInAtomicGroup      = 300
+	tagBlobFileAddition   = 400
+	tagBlobFileGarbage    = 401
+	// The RocksDB tags that have the tagSafeIgnoreMask bit set (e.g. the DB
+	// ID, and the WAL additions and deletions) are safe to ignore, and their
+	// format is a single bytes field.
+	tagSafeIgnoreMask = 1 << 13
 
 	// Pebble tags.
-	tagNewFile5            = 104 // Range keys.
-	tagCreatedBackingTable = 105
-	tagRemovedBackingTable = 106
-	tagNewBlobFile         = 107
-	tagDeletedBlobFile     = 108
+	tagNewFile5             = 104 // Range keys.
+	tagCreatedBackingTable  = 105
+	tagRemovedBackingTable  = 106
+	tagNewBlobFile          = 107
+	tagDeletedBlobFile      = 108
+	tagInlineTable          = 109
+	tagNamedSnapshot        = 110
+	tagDeletedNamedSnapshot = 111
 
 	// The custom tags sub-format used by tagNewFile4 and above. All tags less
 	// than customTagNonSafeIgnoreMask are safe to ignore and their format must be
@@ -73,6 +86,8 @@ const (
 	customTagSyntheticPrefix   = 67
 	customTagSyntheticSuffix   = 68
 	customTagBlobReferences    = 69
+	customTagContentPrefix     = 70
+	customTagSuffixReplacement = 71
 )
 
 // DeletedTableEntry holds the state for a sstable deletion from a level. The
@@ -92,6 +107,20 @@ type NewTableEntry struct {
 	BackingFileNum base.DiskFileNum
 }
 
+// InlineTable holds the contents of a physical sstable that is stored inline
+// in the MANIFEST instead of in its own file.
+type InlineTable struct {
+	FileNum base.DiskFileNum
+	Data    []byte
+}
+
+// NamedSnapshot is a snapshot recorded in the MANIFEST under a name, so that it
+// can be re-acquired after the DB is reopened.
+type NamedSnapshot struct {
+	Name   string
+	SeqNum base.SeqNum
+}
+
 // VersionEdit holds the state for an edit to a Version along with other
 // on-disk state (log numbers, next file number, and the last sequence number).
 type VersionEdit struct {
@@ -163,6 +192,18 @@ type VersionEdit struct {
 	// version edit. These blob files must not be referenced by any sstable in
 	// the resulting Version.
 	DeletedBlobFiles []base.DiskFileNum
+	// InlineTables holds the contents of the sstables stored inline in the
+	// MANIFEST. A table's contents are recorded in the version edit that adds
+	// the table, and in every MANIFEST snapshot while the table's backing is
+	// live; they are not recorded again when the table moves between levels.
+	InlineTables []InlineTable
+	// NamedSnapshots holds the named snapshots created in the version edit. Like
+	// inline tables, the named snapshots are recorded again in every MANIFEST
+	// snapshot until they are released.
+	NamedSnapshots []NamedSnapshot
+	// DeletedNamedSnapshots holds the names of the named snapshots released in
+	// the version edit.
+	DeletedNamedSnapshots []string
 }
 
 // Decode decodes an edit from the specified reader.
@@ -173,7 +214,11 @@ type VersionEdit struct {
 func (v *VersionEdit) Decode(r io.Reader) error {
 	br, ok := r.(byteReader)
 	if !ok {
-		br = bufio.NewReader(r)
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		br = bytes.NewReader(data)
 	}
 	d := versionEditDecoder{br}
 	for {
@@ -357,6 +402,8 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			}{}
 			var syntheticPrefix sstable.SyntheticPrefix
 			var syntheticSuffix sstable.SyntheticSuffix
+			var contentPrefix []byte
+			var suffixReplacements sstable.SuffixReplacements
 			var blobReferences BlobReferences
 			var blobReferenceDepth BlobReferenceDepth
 			if tag == tagNewFile4 || tag == tagNewFile5 {
@@ -411,6 +458,28 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 							return err
 						}
 
+					case customTagContentPrefix:
+						if contentPrefix, err = d.readBytes(); err != nil {
+							return err
+						}
+
+					case customTagSuffixReplacement:
+						// Each replacement is encoded as two byte strings, of at
+						// least one byte each.
+						n, err := d.readCount(2)
+						if err != nil {
+							return err
+						}
+						suffixReplacements = make(sstable.SuffixReplacements, n)
+						for i := range suffixReplacements {
+							if suffixReplacements[i].From, err = d.readBytes(); err != nil {
+								return err
+							}
+							if suffixReplacements[i].To, err = d.readBytes(); err != nil {
+								return err
+							}
+						}
+
 					case customTagBlobReferences:
 						// The first varint encodes the 'blob reference depth'
 						// of the table.
@@ -419,12 +488,13 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 							return err
 						}
 						blobReferenceDepth = BlobReferenceDepth(v)
-						n, err := d.readUvarint()
+						// Each reference is encoded as two varints.
+						n, err := d.readCount(2)
 						if err != nil {
 							return err
 						}
 						blobReferences = make([]BlobReference, n)
-						for i := 0; i < int(n); i++ {
+						for i := 0; i < n; i++ {
 							fileNum, err := d.readUvarint()
 							if err != nil {
 								return err
@@ -462,6 +532,8 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 				MarkedForCompaction:      markedForCompaction,
 				Virtual:                  virtualState.virtual,
 				SyntheticPrefixAndSuffix: sstable.MakeSyntheticPrefixAndSuffix(syntheticPrefix, syntheticSuffix),
+				ContentPrefix:            contentPrefix,
+				SuffixReplacements:       suffixReplacements,
 			}
 			if tag != tagNewFile5 { // no range keys present
 				m.SmallestPointKey = base.DecodeInternalKey(smallestPointKey)
@@ -537,6 +609,41 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			}
 			v.DeletedBlobFiles = append(v.DeletedBlobFiles, base.DiskFileNum(fileNum))
 
+		case tagInlineTable:
+			fileNum, err := d.readFileNum()
+			if err != nil {
+				return err
+			}
+			data, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v.InlineTables = append(v.InlineTables, InlineTable{
+				FileNum: base.DiskFileNum(fileNum),
+				Data:    data,
+			})
+
+		case tagNamedSnapshot:
+			name, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			seqNum, err := d.readUvarint()
+			if err != nil {
+				return err
+			}
+			v.NamedSnapshots = append(v.NamedSnapshots, NamedSnapshot{
+				Name:   string(name),
+				SeqNum: base.SeqNum(seqNum),
+			})
+
+		case tagDeletedNamedSnapshot:
+			name, err := d.readBytes()
+			if err != nil {
+				return err
+			}
+			v.DeletedNamedSnapshots = append(v.DeletedNamedSnapshots, string(name))
+
 		case tagPrevLogNumber:
 			n, err := d.readUvarint()
 			if err != nil {
@@ -544,10 +651,38 @@ func (v *VersionEdit) Decode(r io.Reader) error {
 			}
 			v.ObsoletePrevLogNum = n
 
-		case tagColumnFamily, tagColumnFamilyAdd, tagColumnFamilyDrop, tagMaxColumnFamily:
+		case tagMinLogNumberToKeep, tagInAtomicGroup, tagMaxColumnFamily:
+			// These RocksDB fields are only relevant to DBs with multiple column
+			// families or two-phase commits, which aren't supported: the records
+			// of such DBs have other tags, which are rejected.
+			if _, err := d.readUvarint(); err != nil {
+				return err
+			}
+
+		case tagColumnFamily:
+			// RocksDB doesn't write the ID of the default column family, but
+			// allow it to be explicit.
+			id, err := d.readUvarint()
+			if err != nil {
+				return err
+			}
+			if id != 0 {
+				return base.CorruptionErrorf("column families are not supported")
+			}
+
+		case tagColumnFamilyAdd, tagColumnFamilyDrop:
 			return base.CorruptionErrorf("column families are not supported")
 
+		case tagBlobFileAddition, tagBlobFileGarbage:
+			return base.CorruptionErrorf("RocksDB blob files are not supported")
+
 		default:
+			if tag&tagSafeIgnoreMask != 0 {
+				if _, err := d.readBytes(); err != nil {
+					return err
+				}
+				break
+			}
 			return errCorruptManifest
 		}
 	}
@@ -606,6 +741,15 @@ func (v *VersionEdit) string(verbose bool, fmtKey base.FormatKey) string {
 	for _, df := range v.DeletedBlobFiles {
 		fmt.Fprintf(&buf, "  del-blob-file: %s\n", df)
 	}
+	for _, t := range v.InlineTables {
+		fmt.Fprintf(&buf, "  inline-table:  %s (%d bytes)\n", t.FileNum, len(t.Data))
+	}
+	for _, s := range v.NamedSnapshots {
+		fmt.Fprintf(&buf, "  add-snapshot:  %q #%d\n", s.Name, s.SeqNum)
+	}
+	for _, name := range v.DeletedNamedSnapshots {
+		fmt.Fprintf(&buf, "  del-snapshot:  %q\n", name)
+	}
 	return buf.String()
 }
 
@@ -801,6 +945,18 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 				e.writeUvarint(customTagSyntheticSuffix)
 				e.writeBytes(x.Meta.SyntheticPrefixAndSuffix.Suffix())
 			}
+			if len(x.Meta.ContentPrefix) > 0 {
+				e.writeUvarint(customTagContentPrefix)
+				e.writeBytes(x.Meta.ContentPrefix)
+			}
+			if len(x.Meta.SuffixReplacements) > 0 {
+				e.writeUvarint(customTagSuffixReplacement)
+				e.writeUvarint(uint64(len(x.Meta.SuffixReplacements)))
+				for _, r := range x.Meta.SuffixReplacements {
+					e.writeBytes(r.From)
+					e.writeBytes(r.To)
+				}
+			}
 			if len(x.Meta.BlobReferences) > 0 {
 				e.writeUvarint(customTagBlobReferences)
 				e.writeUvarint(uint64(x.Meta.BlobReferenceDepth))
@@ -824,6 +980,20 @@ func (v *VersionEdit) Encode(w io.Writer) error {
 		e.writeUvarint(tagDeletedBlobFile)
 		e.writeUvarint(uint64(x))
 	}
+	for _, x := range v.InlineTables {
+		e.writeUvarint(tagInlineTable)
+		e.writeUvarint(uint64(x.FileNum))
+		e.writeBytes(x.Data)
+	}
+	for _, x := range v.NamedSnapshots {
+		e.writeUvarint(tagNamedSnapshot)
+		e.writeString(x.Name)
+		e.writeUvarint(uint64(x.SeqNum))
+	}
+	for _, x := range v.DeletedNamedSnapshots {
+		e.writeUvarint(tagDeletedNamedSnapshot)
+		e.writeString(x)
+	}
 	_, err := w.Write(e.Bytes())
 	return err
 }
@@ -834,7 +1004,7 @@ type versionEditDecoder struct {
 }
 
 func (d versionEditDecoder) readBytes() ([]byte, error) {
-	n, err := d.readUvarint()
+	n, err := d.readCount(1)
 	if err != nil {
 		return nil, err
 	}
@@ -849,6 +1019,21 @@ func (d versionEditDecoder) readBytes() ([]byte, error) {
 	return s, nil
 }
 
+// readCount reads the count of the elements of a field, each encoded in at
+// least minSize bytes, and checks that they fit in the unread bytes of the
+// edit. This prevents a corrupt count from forcing a huge allocation.
+func (d versionEditDecoder) readCount(minSize int) (int, error) {
+	n, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(d.Len()/minSize) {
+		return 0, base.CorruptionErrorf("pebble: corrupt manifest: count %d exceeds the %d remaining bytes",
+			errors.Safe(n), errors.Safe(d.Len()))
+	}
+	return int(n), nil
+}
+
 func (d versionEditDecoder) readLevel() (int, error) {
 	u, err := d.readUvarint()
 	if err != nil {
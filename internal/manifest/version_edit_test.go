@@ -58,6 +58,7 @@ func TestVERoundTripAndAccumulate(t *testing.T) {
 		LargestSeqNum:            11,
 		LargestSeqNumAbsolute:    11,
 		SyntheticPrefixAndSuffix: sstable.MakeSyntheticPrefixAndSuffix([]byte("after"), []byte("foo")),
+		ContentPrefix:            []byte("before"),
 	}).ExtendPointKeyBounds(
 		cmp,
 		base.MakeInternalKey([]byte("a"), 0, base.InternalKeyKindSet),
@@ -78,6 +79,10 @@ func TestVERoundTripAndAccumulate(t *testing.T) {
 		LargestSeqNumAbsolute: 11,
 		Virtual:               true,
 		FileBacking:           m1.FileBacking,
+		SuffixReplacements: sstable.SuffixReplacements{
+			{From: []byte("@2"), To: []byte("@7")},
+			{From: []byte("@1"), To: []byte("@5")},
+		},
 	}).ExtendPointKeyBounds(
 		cmp,
 		base.MakeInternalKey([]byte("a"), 0, base.InternalKeyKindSet),
@@ -252,6 +257,14 @@ func TestVersionEditRoundTrip(t *testing.T) {
 					Meta:  m4,
 				},
 			},
+			InlineTables: []InlineTable{
+				{FileNum: 805, Data: []byte("foo")},
+				{FileNum: 806, Data: []byte("bar")},
+			},
+			NamedSnapshots: []NamedSnapshot{
+				{Name: "backup", SeqNum: 42},
+			},
+			DeletedNamedSnapshots: []string{"export"},
 		},
 	}
 	for _, tc := range testCases {
@@ -0,0 +1,256 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/tokenbucket"
+)
+
+// IOPriority is the priority with which an operation draws from an
+// IORateLimiter.
+type IOPriority int8
+
+const (
+	// IOPriorityLow is the priority of compaction I/O.
+	IOPriorityLow IOPriority = iota
+	// IOPriorityHigh is the priority of flush I/O. While operations of high
+	// priority are waiting for the limiter, operations of low priority are
+	// not admitted, so that flushes can make progress when memtables back up,
+	// at the expense of compactions.
+	IOPriorityHigh
+
+	numIOPriorities
+)
+
+// String implements fmt.Stringer.
+func (p IOPriority) String() string {
+	switch p {
+	case IOPriorityLow:
+		return "low"
+	case IOPriorityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("IOPriority(%d)", int8(p))
+	}
+}
+
+type ioPriorityContextKey struct{}
+
+// WithReadIOPriority returns a context that carries the given IOPriority for
+// reads. Reads performed on behalf of operations that are passed the returned
+// context (e.g. through NewIterWithContext, GetWithContext or ScanInternal)
+// honor the priority: if the DB is configured with an IORateLimiter, reads
+// with IOPriorityLow (e.g. scrubs and backups) draw from the limiter, behind
+// flushes. Reads with IOPriorityHigh, which is the default for operations
+// whose context doesn't carry a priority, are not limited.
+//
+// The priority only applies to reads. The priority of writes is determined by
+// the operation that performs them: flushes write with IOPriorityHigh and
+// compactions with IOPriorityLow. WAL writes are never limited, since all
+// committed batches share the WAL and a low priority batch would hold up the
+// batches queued behind it.
+func WithReadIOPriority(ctx context.Context, priority IOPriority) context.Context {
+	return context.WithValue(ctx, ioPriorityContextKey{}, priority)
+}
+
+// ReadIOPriorityFromContext returns the read IOPriority carried by the
+// context (see WithReadIOPriority), or IOPriorityHigh if it carries none.
+func ReadIOPriorityFromContext(ctx context.Context) IOPriority {
+	if p, ok := ctx.Value(ioPriorityContextKey{}).(IOPriority); ok {
+		return p
+	}
+	return IOPriorityHigh
+}
+
+// IORate describes the rate and burst of an IORateLimiter.
+type IORate struct {
+	// BytesPerSecond is the rate at which the limiter admits bytes. A
+	// non-positive value disables limiting.
+	BytesPerSecond int64
+	// Burst is the maximum number of bytes admitted at once.
+	Burst int64
+}
+
+// ioRateLimiterYieldInterval is the interval at which an operation that is
+// yielding to higher priority operations checks the limiter again.
+const ioRateLimiterYieldInterval = time.Millisecond
+
+// An IORateLimiter limits the rate at which flushes and compactions write
+// data, to protect the latency of foreground operations on a shared disk. It
+// implements a token bucket that is refilled at a configured number of bytes
+// per second, up to a burst size. The rate and burst can be adjusted at
+// runtime, and a limiter can be shared between DB instances (see
+// Options.IORateLimiter), capping their combined background I/O.
+//
+// Flushes draw from the limiter with IOPriorityHigh and compactions with
+// IOPriorityLow.
+//
+// IORateLimiter is safe for concurrent use.
+type IORateLimiter struct {
+	mu struct {
+		sync.Mutex
+		tb             tokenbucket.TokenBucket
+		bytesPerSecond int64
+		burst          int64
+		// waiting is the number of operations of each priority waiting for
+		// tokens.
+		waiting [numIOPriorities]int
+		// waitDuration is the cumulative time operations spent waiting.
+		waitDuration time.Duration
+	}
+	nowFn   func() time.Time
+	sleepFn func(time.Duration)
+}
+
+// NewIORateLimiter returns a new IORateLimiter that allows bytesPerSecond
+// bytes per second, with bursts of up to burst bytes. A non-positive
+// bytesPerSecond disables limiting.
+func NewIORateLimiter(bytesPerSecond, burst int64) *IORateLimiter {
+	return newIORateLimiterWithCustomTime(bytesPerSecond, burst, time.Now, time.Sleep)
+}
+
+func newIORateLimiterWithCustomTime(
+	bytesPerSecond, burst int64, nowFn func() time.Time, sleepFn func(time.Duration),
+) *IORateLimiter {
+	l := &IORateLimiter{nowFn: nowFn, sleepFn: sleepFn}
+	l.mu.bytesPerSecond = bytesPerSecond
+	l.mu.burst = burst
+	l.mu.tb.InitWithNowFn(tokenbucket.TokensPerSecond(bytesPerSecond), tokenbucket.Tokens(burst), nowFn)
+	return l
+}
+
+// SetRate adjusts the rate and burst of the limiter. A non-positive
+// bytesPerSecond disables limiting.
+func (l *IORateLimiter) SetRate(bytesPerSecond, burst int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mu.bytesPerSecond = bytesPerSecond
+	l.mu.burst = burst
+	l.mu.tb.UpdateConfig(tokenbucket.TokensPerSecond(bytesPerSecond), tokenbucket.Tokens(burst))
+}
+
+// Rate returns the current rate and burst of the limiter.
+func (l *IORateLimiter) Rate() (bytesPerSecond, burst int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.mu.bytesPerSecond, l.mu.burst
+}
+
+// WaitDuration returns the cumulative time operations have spent waiting for
+// the limiter.
+func (l *IORateLimiter) WaitDuration() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.mu.waitDuration
+}
+
+// Wait blocks until n bytes of I/O are admitted at the given priority. If n is
+// larger than the burst, the limiter goes into debt, delaying future
+// operations.
+func (l *IORateLimiter) Wait(priority IOPriority, n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var start time.Time
+	for {
+		if l.mu.bytesPerSecond <= 0 {
+			break
+		}
+		var d time.Duration
+		if l.higherPriorityWaitingLocked(priority) {
+			d = ioRateLimiterYieldInterval
+		} else {
+			var ok bool
+			if ok, d = l.mu.tb.TryToFulfill(tokenbucket.Tokens(n)); ok {
+				break
+			}
+		}
+		if start.IsZero() {
+			start = l.nowFn()
+			l.mu.waiting[priority]++
+			defer func() { l.mu.waiting[priority]-- }()
+		}
+		l.mu.Unlock()
+		l.sleepFn(d)
+		l.mu.Lock()
+	}
+	if !start.IsZero() {
+		l.mu.waitDuration += l.nowFn().Sub(start)
+	}
+}
+
+func (l *IORateLimiter) higherPriorityWaitingLocked(priority IOPriority) bool {
+	for p := priority + 1; p < numIOPriorities; p++ {
+		if l.mu.waiting[p] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitedWritable is an objstorage.Writable wrapper that draws from an
+// IORateLimiter before every write.
+type rateLimitedWritable struct {
+	objstorage.Writable
+
+	limiter  *IORateLimiter
+	priority IOPriority
+}
+
+// Write is part of the objstorage.Writable interface.
+func (w *rateLimitedWritable) Write(p []byte) error {
+	w.limiter.Wait(w.priority, int64(len(p)))
+	return w.Writable.Write(p)
+}
+
+// rateLimitedReadable is an objstorage.Readable wrapper that draws from an
+// IORateLimiter before reads of low priority (see WithReadIOPriority), including
+// reads through the ReadHandles it creates.
+type rateLimitedReadable struct {
+	objstorage.Readable
+
+	limiter *IORateLimiter
+}
+
+// ReadAt is part of the objstorage.Readable interface.
+func (r *rateLimitedReadable) ReadAt(ctx context.Context, p []byte, off int64) error {
+	waitForRead(ctx, r.limiter, len(p))
+	return r.Readable.ReadAt(ctx, p, off)
+}
+
+// NewReadHandle is part of the objstorage.Readable interface.
+func (r *rateLimitedReadable) NewReadHandle(
+	readBeforeSize objstorage.ReadBeforeSize,
+) objstorage.ReadHandle {
+	return &rateLimitedReadHandle{
+		ReadHandle: r.Readable.NewReadHandle(readBeforeSize),
+		limiter:    r.limiter,
+	}
+}
+
+type rateLimitedReadHandle struct {
+	objstorage.ReadHandle
+
+	limiter *IORateLimiter
+}
+
+// ReadAt is part of the objstorage.ReadHandle interface.
+func (rh *rateLimitedReadHandle) ReadAt(ctx context.Context, p []byte, off int64) error {
+	waitForRead(ctx, rh.limiter, len(p))
+	return rh.ReadHandle.ReadAt(ctx, p, off)
+}
+
+// waitForRead waits for the limiter before a read of n bytes, if the read has
+// low priority.
+func waitForRead(ctx context.Context, limiter *IORateLimiter, n int) {
+	if p := ReadIOPriorityFromContext(ctx); p == IOPriorityLow {
+		limiter.Wait(p, int64(n))
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/cache"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIORateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	var slept []time.Duration
+	var onSleep func()
+	l := newIORateLimiterWithCustomTime(1000, 100,
+		func() time.Time { return now },
+		func(d time.Duration) {
+			slept = append(slept, d)
+			now = now.Add(d)
+			if onSleep != nil {
+				onSleep()
+			}
+		})
+
+	// The bucket starts full.
+	l.Wait(IOPriorityLow, 100)
+	require.Empty(t, slept)
+	require.Zero(t, l.WaitDuration())
+
+	// Once exhausted, operations wait for the bucket to refill.
+	l.Wait(IOPriorityLow, 50)
+	require.Equal(t, []time.Duration{50 * time.Millisecond}, slept)
+	require.Equal(t, 50*time.Millisecond, l.WaitDuration())
+	slept = nil
+
+	// Operations larger than the burst put the limiter into debt.
+	l.Wait(IOPriorityHigh, 300)
+	require.Equal(t, []time.Duration{100 * time.Millisecond}, slept)
+	slept = nil
+	l.Wait(IOPriorityHigh, 100)
+	require.Equal(t, []time.Duration{300 * time.Millisecond}, slept)
+	slept = nil
+
+	// Low priority operations yield to waiting high priority operations.
+	now = now.Add(time.Second)
+	l.mu.waiting[IOPriorityHigh]++
+	yields := 0
+	onSleep = func() {
+		if yields++; yields == 3 {
+			l.mu.waiting[IOPriorityHigh]--
+		}
+	}
+	l.Wait(IOPriorityLow, 10)
+	require.Equal(t, 3, yields)
+	require.Equal(t, []time.Duration{
+		ioRateLimiterYieldInterval, ioRateLimiterYieldInterval, ioRateLimiterYieldInterval,
+	}, slept)
+	require.Equal(t, [numIOPriorities]int{}, l.mu.waiting)
+	onSleep = nil
+	slept = nil
+
+	// The rate can be adjusted, and a non-positive rate disables limiting.
+	l.SetRate(0, 100)
+	for i := 0; i < 10; i++ {
+		l.Wait(IOPriorityLow, 1000)
+	}
+	require.Empty(t, slept)
+	l.SetRate(2000, 200)
+	bytesPerSecond, burst := l.Rate()
+	require.Equal(t, int64(2000), bytesPerSecond)
+	require.Equal(t, int64(200), burst)
+}
+
+func TestIORateLimiterFlushAndCompaction(t *testing.T) {
+	var sleeps atomic.Int64
+	limiter := newIORateLimiterWithCustomTime(1<<20, 1, time.Now, func(d time.Duration) {
+		sleeps.Add(1)
+		time.Sleep(d)
+	})
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		IORateLimiter:               limiter,
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	write := func() {
+		for i := 0; i < 100; i++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", i)), make([]byte, 100), nil))
+		}
+	}
+	// Put the limiter into debt before each operation, so that the operation
+	// has to wait for it.
+	drain := func() {
+		limiter.Wait(IOPriorityLow, 1<<14)
+		sleeps.Store(0)
+	}
+
+	// Flush twice, so that the compaction below rewrites the overlapping
+	// tables rather than moving them.
+	for i := 0; i < 2; i++ {
+		write()
+		drain()
+		require.NoError(t, d.Flush())
+		require.Greater(t, sleeps.Load(), int64(0))
+	}
+
+	drain()
+	require.NoError(t, d.Compact([]byte("k"), []byte("l"), true /* parallelize */))
+	require.Greater(t, sleeps.Load(), int64(0))
+	require.Greater(t, limiter.WaitDuration(), time.Duration(0))
+}
+
+func TestIORateLimiterReadPriority(t *testing.T) {
+	var sleeps atomic.Int64
+	limiter := newIORateLimiterWithCustomTime(1<<20, 1, time.Now, func(d time.Duration) {
+		sleeps.Add(1)
+		time.Sleep(d)
+	})
+	// Use an empty block cache, so that all reads go to the files.
+	c := cache.New(0)
+	defer c.Unref()
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		Cache:                       c,
+		IORateLimiter:               limiter,
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", i)), make([]byte, 100), nil))
+	}
+	require.NoError(t, d.Flush())
+
+	scan := func(ctx context.Context) int64 {
+		limiter.Wait(IOPriorityLow, 1<<14)
+		sleeps.Store(0)
+		iter, err := d.NewIterWithContext(ctx, nil)
+		require.NoError(t, err)
+		for valid := iter.First(); valid; valid = iter.Next() {
+		}
+		require.NoError(t, iter.Close())
+		v, closer, err := d.GetWithContext(ctx, []byte("k050"))
+		require.NoError(t, err)
+		require.Len(t, v, 100)
+		require.NoError(t, closer.Close())
+		return sleeps.Load()
+	}
+	// Foreground reads are not limited.
+	require.Zero(t, scan(context.Background()))
+	require.Zero(t, scan(WithReadIOPriority(context.Background(), IOPriorityHigh)))
+	// Low priority reads wait for the limiter.
+	require.Greater(t, scan(WithReadIOPriority(context.Background(), IOPriorityLow)), int64(0))
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/bytealloc"
+	"github.com/cockroachdb/pebble/internal/hotkeys"
 	"github.com/cockroachdb/pebble/internal/humanize"
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/internal/keyspan"
@@ -22,6 +23,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/internal/rangekeystack"
 	"github.com/cockroachdb/pebble/internal/treeprinter"
+	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/redact"
 )
 
@@ -85,6 +87,9 @@ const (
 // https://github.com/cockroachdb/pebble/issues/29#issuecomment-494477985
 const readBytesPeriod uint64 = 1 << 16
 
+// The default value of Options.Experimental.ReadCompactionMinOverlappingLevels.
+const defaultReadCompactionMinOverlappingLevels = 2
+
 var errReversePrefixIteration = errors.New("pebble: unsupported reverse prefix iteration")
 
 // IteratorMetrics holds per-iterator metrics. These do not change over the
@@ -203,6 +208,18 @@ type Iterator struct {
 	// Either readState or version is set, but not both.
 	readState *readState
 	version   *version
+	// tracker is set if the iterator is tracked by the DB's iterTracker, which
+	// must be notified when the iterator is closed.
+	tracker *iterTracker
+	// hotKeys is set if the iterator reads the DB state and hot key detection
+	// is enabled; the keys of seeks are recorded as reads.
+	hotKeys *hotkeys.Tracker
+	// externalValues is set if an external value store is configured; values
+	// that are handles are resolved through it.
+	externalValues *externalValues
+	// family is set once the iterator is cloned, or if it is a clone; it is
+	// shared by the iterator and its clones.
+	family *iterFamily
 	// rangeKey holds iteration state specific to iteration over range keys.
 	// The range key field may be nil if the Iterator has never been configured
 	// to iterate over range keys. Its non-nilness cannot be used to determine
@@ -223,7 +240,10 @@ type Iterator struct {
 	fetcher  base.LazyFetcher
 	// For use in LazyValue.Value.
 	lazyValueBuf []byte
-	valueCloser  io.Closer
+	// For use in fetching the values referenced by handles from an external
+	// value store.
+	externalValueBuf []byte
+	valueCloser      io.Closer
 	// boundsBuf holds two buffers used to store the lower and upper bounds.
 	// Whenever the Iterator's bounds change, the new bounds are copied into
 	// boundsBuf[boundsBufIdx]. The two bounds share a slice to reduce
@@ -827,69 +847,104 @@ func (i *Iterator) maybeSampleRead() {
 	i.readSampling.bytesUntilReadSampling -= bytesRead
 }
 
+// maybeSampleSeek is called when a seek is returning. In addition to the
+// sampling of bytes read (see maybeSampleRead), it samples a fraction of seeks
+// (see Options.Experimental.ReadCompactionSeekSamplingPeriod).
+func (i *Iterator) maybeSampleSeek() {
+	i.maybeSampleRead()
+	if i.iterValidityState == IterValid && i.readState != nil && i.readState.db.sampleSeek() {
+		i.sampleRead()
+	}
+}
+
 func (i *Iterator) sampleRead() {
-	var topFile *manifest.TableMetadata
-	topLevel, numOverlappingLevels := numLevels, 0
+	var sample readSample
 	mi := i.merging
 	if mi == nil {
 		return
 	}
-	if len(mi.levels) > 1 {
-		mi.ForEachLevelIter(func(li *levelIter) (done bool) {
-			if li.layer.IsFlushableIngests() {
-				return false
+	access := &i.readState.db.tableAccess
+	mi.ForEachLevelIter(func(li *levelIter) (done bool) {
+		if li.layer.IsFlushableIngests() {
+			return false
+		}
+		l := li.layer.Level()
+		if f := li.iterFile; f != nil {
+			var containsKey bool
+			if i.pos == iterPosNext || i.pos == iterPosCurForward ||
+				i.pos == iterPosCurForwardPaused {
+				containsKey = i.cmp(f.SmallestPointKey.UserKey, i.key) <= 0
+			} else if i.pos == iterPosPrev || i.pos == iterPosCurReverse ||
+				i.pos == iterPosCurReversePaused {
+				containsKey = i.cmp(f.LargestPointKey.UserKey, i.key) >= 0
 			}
-			l := li.layer.Level()
-			if f := li.iterFile; f != nil {
-				var containsKey bool
-				if i.pos == iterPosNext || i.pos == iterPosCurForward ||
-					i.pos == iterPosCurForwardPaused {
-					containsKey = i.cmp(f.SmallestPointKey.UserKey, i.key) <= 0
-				} else if i.pos == iterPosPrev || i.pos == iterPosCurReverse ||
-					i.pos == iterPosCurReversePaused {
-					containsKey = i.cmp(f.LargestPointKey.UserKey, i.key) >= 0
-				}
-				// Do nothing if the current key is not contained in f's
-				// bounds. We could seek the LevelIterator at this level
-				// to find the right file, but the performance impacts of
-				// doing that are significant enough to negate the benefits
-				// of read sampling in the first place. See the discussion
-				// at:
-				// https://github.com/cockroachdb/pebble/pull/1041#issuecomment-763226492
-				if containsKey {
-					numOverlappingLevels++
-					if numOverlappingLevels >= 2 {
-						// Terminate the loop early if at least 2 overlapping levels are found.
-						return true
-					}
-					topLevel = l
-					topFile = f
-				}
+			// Do nothing if the current key is not contained in f's
+			// bounds. We could seek the LevelIterator at this level
+			// to find the right file, but the performance impacts of
+			// doing that are significant enough to negate the benefits
+			// of read sampling in the first place. See the discussion
+			// at:
+			// https://github.com/cockroachdb/pebble/pull/1041#issuecomment-763226492
+			if containsKey {
+				// Every file containing the key was (potentially) read to
+				// produce it, so record an access of each of them.
+				access.record(f, l)
+				sample.add(f, l)
 			}
-			return false
-		})
+		}
+		return false
+	})
+	sample.finish(i.readState.db.opts, &i.readSampling.pendingCompactions, i.cmp)
+}
+
+// readSample accumulates the tables that contain the key returned by a
+// sampled read, in order to decide whether the read should count towards a
+// read triggered compaction.
+type readSample struct {
+	// topFile is the table in the highest level containing the key.
+	topFile  *manifest.TableMetadata
+	topLevel int
+	// numOverlappingLevels is the number of levels (counting each L0 sublevel
+	// separately) with a table containing the key.
+	numOverlappingLevels int
+}
+
+// add records that the table f, in the given level, contains the sampled key.
+// Tables must be added from the highest level to the lowest.
+func (s *readSample) add(f *manifest.TableMetadata, level int) {
+	s.numOverlappingLevels++
+	if s.topFile == nil {
+		s.topFile = f
+		s.topLevel = level
 	}
-	if topFile == nil || topLevel >= numLevels {
+}
+
+// finish counts the sample towards a read triggered compaction of topFile if
+// the key was contained in enough levels, and adds the compaction to pending
+// once topFile has been sampled enough times.
+func (s *readSample) finish(opts *Options, pending *readCompactionQueue, cmp Compare) {
+	if s.topFile == nil || s.topLevel >= numLevels {
 		return
 	}
-	if numOverlappingLevels >= 2 {
-		allowedSeeks := topFile.AllowedSeeks.Add(-1)
-		if allowedSeeks == 0 {
-
-			// Since the compaction queue can handle duplicates, we can keep
-			// adding to the queue even once allowedSeeks hits 0.
-			// In fact, we NEED to keep adding to the queue, because the queue
-			// is small and evicts older and possibly useful compactions.
-			topFile.AllowedSeeks.Add(topFile.InitAllowedSeeks)
-
-			read := readCompaction{
-				start:   topFile.SmallestPointKey.UserKey,
-				end:     topFile.LargestPointKey.UserKey,
-				level:   topLevel,
-				fileNum: topFile.FileNum,
-			}
-			i.readSampling.pendingCompactions.add(&read, i.cmp)
+	if s.numOverlappingLevels < opts.Experimental.ReadCompactionMinOverlappingLevels {
+		return
+	}
+	allowedSeeks := s.topFile.AllowedSeeks.Add(-1)
+	if allowedSeeks == 0 {
+
+		// Since the compaction queue can handle duplicates, we can keep
+		// adding to the queue even once allowedSeeks hits 0.
+		// In fact, we NEED to keep adding to the queue, because the queue
+		// is small and evicts older and possibly useful compactions.
+		s.topFile.AllowedSeeks.Add(s.topFile.InitAllowedSeeks)
+
+		read := readCompaction{
+			start:   s.topFile.SmallestPointKey.UserKey,
+			end:     s.topFile.LargestPointKey.UserKey,
+			level:   s.topLevel,
+			fileNum: s.topFile.FileNum,
 		}
+		pending.add(&read, cmp)
 	}
 }
 
@@ -1077,7 +1132,7 @@ func (i *Iterator) findPrevEntry(limit []byte) {
 				// memory for both simultaneously.
 				var value []byte
 				var callerOwned bool
-				value, callerOwned, i.err = i.value.Value(i.lazyValueBuf)
+				value, callerOwned, i.err = i.value.ValueWithContext(i.ctx, i.lazyValueBuf)
 				if callerOwned {
 					i.lazyValueBuf = value[:0]
 				}
@@ -1262,6 +1317,10 @@ func (i *Iterator) SeekGE(key []byte) bool {
 // guarantees it will surface any range keys with bounds overlapping the
 // keyspace [key, limit).
 func (i *Iterator) SeekGEWithLimit(key []byte, limit []byte) IterValidityState {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("SeekGE", key)()
+	}
+	recordHotKeyRead(i.hotKeys, key)
 	if i.rangeKey != nil {
 		// NB: Check Valid() before clearing requiresReposition.
 		i.rangeKey.prevPosHadRangeKey = i.rangeKey.hasRangeKey && i.Valid()
@@ -1352,7 +1411,7 @@ func (i *Iterator) SeekGEWithLimit(key []byte, limit []byte) IterValidityState {
 		}
 	}
 	i.findNextEntry(limit)
-	i.maybeSampleRead()
+	i.maybeSampleSeek()
 	if i.Error() == nil {
 		// Prepare state for a future noop optimization.
 		i.prefixOrFullSeekKey = append(i.prefixOrFullSeekKey[:0], key...)
@@ -1361,6 +1420,83 @@ func (i *Iterator) SeekGEWithLimit(key []byte, limit []byte) IterValidityState {
 	return i.iterValidityState
 }
 
+// SeekGEMulti positions the iterator at the first key/value pair whose key is
+// greater than or equal to each of the given keys in turn, and calls fn with
+// the index of the key after each positioning. While fn runs, the iterator is
+// positioned exactly as SeekGE(keys[idx]) would have positioned it, and fn may
+// read its state through Valid, Key, Value, RangeKeys, etc. fn must not
+// reposition the iterator. If fn returns an error, SeekGEMulti stops and
+// returns it.
+//
+// The keys must be sorted in increasing order. SeekGEMulti is much faster than
+// a sequence of SeekGE calls made by the caller for dense probe sets, such as
+// those of index joins: the iterator steps from one key to the next with Next
+// when the gap between them is small, and re-seeks otherwise, in which case
+// the seek only moves forward from the current position in each level (see
+// base.SeekGEFlags.TrySeekUsingNext), reusing the blocks that are already
+// loaded and keeping the block reads sequential. The number of Next calls
+// attempted before re-seeking adapts to the gaps observed so far.
+//
+// SeekGEMulti returns the iterator error, if any, in which case the iterator
+// is exhausted.
+func (i *Iterator) SeekGEMulti(keys [][]byte, fn func(idx int) error) error {
+	// Stepping with Next over the range keys surfaces the range key boundaries
+	// rather than the seek keys that a seek within a range key surfaces, so the
+	// iterator always re-seeks when range keys are configured.
+	stepping := !i.opts.rangeKeys()
+	maxSteps := seekGEMultiInitialSteps
+	for idx, key := range keys {
+		if idx > 0 && i.cmp(keys[idx-1], key) > 0 {
+			return errors.New("pebble: SeekGEMulti keys are not sorted")
+		}
+		if idx > 0 && stepping && i.lastPositioningOp == seekGELastPositioningOp &&
+			i.iterValidityState == IterValid && i.cmp(i.key, key) < 0 {
+			// The iterator is positioned at the first key greater than or equal to
+			// the previous key, and before the key. Try to reach the key with a few
+			// Next calls.
+			steps := 0
+			for steps < maxSteps && i.Next() && i.cmp(i.key, key) < 0 {
+				steps++
+			}
+			if i.err != nil {
+				return i.err
+			}
+			if i.iterValidityState != IterValid || i.cmp(i.key, key) >= 0 {
+				// The iterator is positioned at the first key greater than or equal
+				// to key, or exhausted if there is no such key. Record the position as
+				// a seek to key, which allows the next key to use the seek
+				// optimizations.
+				maxSteps = min(2*maxSteps, seekGEMultiMaxSteps)
+				i.prefixOrFullSeekKey = append(i.prefixOrFullSeekKey[:0], key...)
+				i.lastPositioningOp = seekGELastPositioningOp
+			} else {
+				// The gap is too large: re-seek. The iterator was only moved forward
+				// with Next since the seek to the previous key, so the seek may use
+				// the TrySeekUsingNext optimization.
+				maxSteps = max(maxSteps/2, 1)
+				i.lastPositioningOp = seekGELastPositioningOp
+				i.SeekGE(key)
+			}
+		} else {
+			i.SeekGE(key)
+		}
+		if i.err != nil {
+			return i.err
+		}
+		if err := fn(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seekGEMultiInitialSteps and seekGEMultiMaxSteps bound the number of Next
+// calls that SeekGEMulti attempts before re-seeking.
+const (
+	seekGEMultiInitialSteps = 4
+	seekGEMultiMaxSteps     = 32
+)
+
 // SeekPrefixGE moves the iterator to the first key/value pair whose key is
 // greater than or equal to the given key and which has the same "prefix" as
 // the given key. The prefix for a key is determined by the user-defined
@@ -1411,6 +1547,10 @@ func (i *Iterator) SeekGEWithLimit(key []byte, limit []byte) IterValidityState {
 // ImmediateSuccessor method. For example, a SeekPrefixGE("a@9") call with the
 // prefix "a" will truncate range key bounds to [a,ImmediateSuccessor(a)].
 func (i *Iterator) SeekPrefixGE(key []byte) bool {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("SeekPrefixGE", key)()
+	}
+	recordHotKeyRead(i.hotKeys, key)
 	if i.rangeKey != nil {
 		// NB: Check Valid() before clearing requiresReposition.
 		i.rangeKey.prevPosHadRangeKey = i.rangeKey.hasRangeKey && i.Valid()
@@ -1503,7 +1643,7 @@ func (i *Iterator) SeekPrefixGE(key []byte) bool {
 	i.iterKV = i.iter.SeekPrefixGE(i.prefixOrFullSeekKey, key, flags)
 	i.stats.ForwardSeekCount[InternalIterCall]++
 	i.findNextEntry(nil)
-	i.maybeSampleRead()
+	i.maybeSampleSeek()
 	if i.Error() == nil {
 		i.lastPositioningOp = seekPrefixGELastPositioningOp
 	}
@@ -1541,6 +1681,10 @@ func (i *Iterator) SeekLT(key []byte) bool {
 // guarantees it will surface any range keys with bounds overlapping the
 // keyspace up to limit.
 func (i *Iterator) SeekLTWithLimit(key []byte, limit []byte) IterValidityState {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("SeekLT", key)()
+	}
+	recordHotKeyRead(i.hotKeys, key)
 	if i.rangeKey != nil {
 		// NB: Check Valid() before clearing requiresReposition.
 		i.rangeKey.prevPosHadRangeKey = i.rangeKey.hasRangeKey && i.Valid()
@@ -1611,7 +1755,7 @@ func (i *Iterator) SeekLTWithLimit(key []byte, limit []byte) IterValidityState {
 		}
 	}
 	i.findPrevEntry(limit)
-	i.maybeSampleRead()
+	i.maybeSampleSeek()
 	if i.Error() == nil && i.batch == nil {
 		// Prepare state for a future noop optimization.
 		i.prefixOrFullSeekKey = append(i.prefixOrFullSeekKey[:0], key...)
@@ -1623,6 +1767,9 @@ func (i *Iterator) SeekLTWithLimit(key []byte, limit []byte) IterValidityState {
 // First moves the iterator the first key/value pair. Returns true if the
 // iterator is pointing at a valid entry and false otherwise.
 func (i *Iterator) First() bool {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("First", nil)()
+	}
 	if i.rangeKey != nil {
 		// NB: Check Valid() before clearing requiresReposition.
 		i.rangeKey.prevPosHadRangeKey = i.rangeKey.hasRangeKey && i.Valid()
@@ -1659,6 +1806,9 @@ func (i *Iterator) First() bool {
 // Last moves the iterator the last key/value pair. Returns true if the
 // iterator is pointing at a valid entry and false otherwise.
 func (i *Iterator) Last() bool {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("Last", nil)()
+	}
 	if i.rangeKey != nil {
 		// NB: Check Valid() before clearing requiresReposition.
 		i.rangeKey.prevPosHadRangeKey = i.rangeKey.hasRangeKey && i.Valid()
@@ -1723,6 +1873,9 @@ func (i *Iterator) NextWithLimit(limit []byte) IterValidityState {
 // upper-bound that is a versioned MVCC key (see the comment for
 // Comparer.Split). It returns an error in this case.
 func (i *Iterator) NextPrefix() bool {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("NextPrefix", nil)()
+	}
 	if i.nextPrefixNotPermittedByUpperBound {
 		i.lastPositioningOp = unknownLastPositionOp
 		i.requiresReposition = false
@@ -1890,6 +2043,9 @@ func (i *Iterator) internalNextPrefix(currKeyPrefixLen int) {
 }
 
 func (i *Iterator) nextWithLimit(limit []byte) IterValidityState {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("Next", nil)()
+	}
 	i.stats.ForwardStepCount[InterfaceCall]++
 	if i.hasPrefix {
 		if limit != nil {
@@ -2000,6 +2156,9 @@ func (i *Iterator) Prev() bool {
 // guarantees it will surface any range keys with bounds overlapping the
 // keyspace up to limit.
 func (i *Iterator) PrevWithLimit(limit []byte) IterValidityState {
+	if i.opts.DebugTrace != nil {
+		defer i.traceOp("Prev", nil)()
+	}
 	i.stats.ReverseStepCount[InterfaceCall]++
 	if i.err != nil {
 		return i.iterValidityState
@@ -2278,7 +2437,7 @@ func (i *Iterator) Value() []byte {
 // The caller should not modify the contents of the returned slice, and its
 // contents may change on the next call to Next.
 func (i *Iterator) ValueAndErr() ([]byte, error) {
-	val, callerOwned, err := i.value.Value(i.lazyValueBuf)
+	val, callerOwned, err := i.value.ValueWithContext(i.ctx, i.lazyValueBuf)
 	if err != nil {
 		i.err = err
 		i.iterValidityState = IterExhausted
@@ -2286,9 +2445,27 @@ func (i *Iterator) ValueAndErr() ([]byte, error) {
 	if callerOwned {
 		i.lazyValueBuf = val[:0]
 	}
+	if err == nil && i.externalValues != nil {
+		val, callerOwned, err = i.externalValues.resolve(i.ctx, val, i.externalValueBuf)
+		if err != nil {
+			i.err = err
+			i.iterValidityState = IterExhausted
+		}
+		if callerOwned {
+			i.externalValueBuf = val[:0]
+		}
+	}
 	return val, err
 }
 
+// ReadSeqNum returns the sequence number the iterator reads at: the iterator
+// observes the writes with lower sequence numbers, except those excluded by
+// IterOptions.OnlyReadGuaranteedDurable. With IterOptions.ReadAtDurableFrontier,
+// this is the durable frontier the iterator serves.
+func (i *Iterator) ReadSeqNum() SeqNum {
+	return i.seqNum
+}
+
 // LazyValue returns the LazyValue. Only for advanced use cases.
 // REQUIRES: i.Error()==nil and HasPointAndRange() returns true for hasPoint.
 func (i *Iterator) LazyValue() LazyValue {
@@ -2385,6 +2562,14 @@ func (i *Iterator) Close() error {
 	if i.version != nil {
 		i.version.Unref()
 	}
+	if i.tracker != nil {
+		i.tracker.release(i)
+		i.tracker = nil
+	}
+	if i.family != nil {
+		i.family.release(&i.stats)
+		i.family = nil
+	}
 	if i.externalIter != nil {
 		err = firstError(err, i.externalIter.Close())
 	}
@@ -2575,6 +2760,10 @@ func (i *Iterator) processBounds(lower, upper []byte) {
 //
 // If only lower and upper bounds need to be modified, prefer SetBounds.
 func (i *Iterator) SetOptions(o *IterOptions) {
+	// The sequence number of an iterator is chosen at creation.
+	if o.ReadAtDurableFrontier != i.opts.ReadAtDurableFrontier {
+		panic("pebble: ReadAtDurableFrontier cannot be changed by SetOptions")
+	}
 	if i.externalIter != nil {
 		if err := validateExternalIterOpts(o); err != nil {
 			panic(err)
@@ -2599,11 +2788,11 @@ func (i *Iterator) SetOptions(o *IterOptions) {
 	closeBoth := i.err != nil ||
 		o.OnlyReadGuaranteedDurable != i.opts.OnlyReadGuaranteedDurable
 
-	// If either options specify block property filters for an iterator stack,
-	// reconstruct it.
+	// If either options specify block property filters or a debug trace for an
+	// iterator stack, reconstruct it.
 	if i.pointIter != nil && (closeBoth || len(o.PointKeyFilters) > 0 || len(i.opts.PointKeyFilters) > 0 ||
 		o.RangeKeyMasking.Filter != nil || i.opts.RangeKeyMasking.Filter != nil || o.SkipPoint != nil ||
-		i.opts.SkipPoint != nil) {
+		i.opts.SkipPoint != nil || o.DebugTrace != nil || i.opts.DebugTrace != nil) {
 		i.err = firstError(i.err, i.pointIter.Close())
 		i.pointIter = nil
 	}
@@ -2829,6 +3018,9 @@ type CloneOptions struct {
 // extend the lifetime of the data backing the original Iterator since that
 // will cause an increase in memory and disk usage (use NewSnapshot for that
 // purpose).
+//
+// An Iterator and its clones form a family, sharing the index blocks loaded
+// by the iterators constructed after the first Clone (see FamilyStats).
 func (i *Iterator) Clone(opts CloneOptions) (*Iterator, error) {
 	return i.CloneWithContext(context.Background(), opts)
 }
@@ -2842,11 +3034,23 @@ func (i *Iterator) CloneWithContext(ctx context.Context, opts CloneOptions) (*It
 	if i.batchOnlyIter {
 		return nil, errors.Errorf("cannot Clone a batch-only Iterator")
 	}
+	if opts.IterOptions.ReadAtDurableFrontier != i.opts.ReadAtDurableFrontier {
+		return nil, errors.Errorf("cannot change ReadAtDurableFrontier when cloning an Iterator")
+	}
 	readState := i.readState
 	vers := i.version
 	if readState == nil && vers == nil {
 		return nil, errors.Errorf("cannot Clone a closed Iterator")
 	}
+	if i.tracker != nil {
+		if err := i.tracker.acquire(); err != nil {
+			return nil, err
+		}
+	}
+	if i.family == nil {
+		i.family = newIterFamily(i.fc.blockCacheHandle.Cache().MaxSize() / iterFamilyPinnedIndexFraction)
+	}
+	i.family.addClone()
 	// i is already holding a ref, so there is no race with unref here.
 	//
 	// TODO(bilal): If the underlying iterator was created on a snapshot, we could
@@ -2880,6 +3084,9 @@ func (i *Iterator) CloneWithContext(ctx context.Context, opts CloneOptions) (*It
 		newIters:            i.newIters,
 		newIterRangeKey:     i.newIterRangeKey,
 		seqNum:              i.seqNum,
+		hotKeys:             i.hotKeys,
+		externalValues:      i.externalValues,
+		family:              i.family,
 	}
 	dbi.processBounds(dbi.opts.LowerBound, dbi.opts.UpperBound)
 
@@ -2888,10 +3095,91 @@ func (i *Iterator) CloneWithContext(ctx context.Context, opts CloneOptions) (*It
 	if i.batch != nil && opts.RefreshBatchView {
 		dbi.batchSeqNum = (base.SeqNum(len(i.batch.data)) | base.SeqNumBatchBit)
 	}
+	if i.tracker != nil {
+		i.tracker.register(dbi)
+	}
 
 	return finishInitializingIter(ctx, buf), nil
 }
 
+// IteratorFamilyStats contains the stats of the family formed by an Iterator
+// and its clones (see Iterator.Clone).
+type IteratorFamilyStats struct {
+	// Iterators is the number of open iterators in the family, and Clones the
+	// number of clones created within the family.
+	Iterators int
+	Clones    int
+	// ClosedStats accumulates the stats of the iterators of the family that
+	// have been closed. The stats of an open iterator are returned by its
+	// Stats.
+	ClosedStats IteratorStats
+	// IndexBlocks holds the stats of the index blocks pinned by the family.
+	// They are shared by the iterators of the family, and stay in memory
+	// until all of them are closed, or until they're released to bound the
+	// memory pinned by the family (see iterFamilyPinnedIndexFraction).
+	IndexBlocks block.BlockPinsStats
+}
+
+// iterFamilyPinnedIndexFraction bounds the total size of the index blocks
+// pinned by an iterator family to this fraction of the block cache's
+// capacity, so that a long-lived family over many tables doesn't pin cache
+// memory without bound.
+const iterFamilyPinnedIndexFraction = 16
+
+// iterFamily is shared by an Iterator and its clones.
+type iterFamily struct {
+	indexBlocks block.BlockPins
+	mu          struct {
+		sync.Mutex
+		iterators   int
+		clones      int
+		closedStats IteratorStats
+	}
+}
+
+func newIterFamily(maxPinnedIndexSize int64) *iterFamily {
+	f := &iterFamily{}
+	f.indexBlocks.Init(maxPinnedIndexSize)
+	f.mu.iterators = 1
+	return f
+}
+
+func (f *iterFamily) addClone() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mu.iterators++
+	f.mu.clones++
+}
+
+// release is called when an iterator of the family is closed, with its stats.
+func (f *iterFamily) release(stats *IteratorStats) {
+	f.mu.Lock()
+	f.mu.closedStats.Merge(*stats)
+	f.mu.iterators--
+	last := f.mu.iterators == 0
+	f.mu.Unlock()
+	if last {
+		f.indexBlocks.Release()
+	}
+}
+
+// FamilyStats returns the stats of the family of the iterator. An iterator
+// that was never cloned, and isn't a clone, is alone in its family.
+func (i *Iterator) FamilyStats() IteratorFamilyStats {
+	if i.family == nil {
+		return IteratorFamilyStats{Iterators: 1}
+	}
+	f := i.family
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return IteratorFamilyStats{
+		Iterators:   f.mu.iterators,
+		Clones:      f.mu.clones,
+		ClosedStats: f.mu.closedStats,
+		IndexBlocks: f.indexBlocks.Stats(),
+	}
+}
+
 // Merge adds all of the argument's statistics to the receiver. It may be used
 // to accumulate stats across multiple iterators.
 func (stats *IteratorStats) Merge(o IteratorStats) {
@@ -22,6 +22,7 @@ import (
 
 	"github.com/cockroachdb/datadriven"
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/bloom"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/bytealloc"
 	"github.com/cockroachdb/pebble/internal/cache"
@@ -412,6 +413,21 @@ func TestReadSampling(t *testing.T) {
 			}
 			return fmt.Sprintf("%d", foundAllowedSeeks)
 
+		case "access-stats":
+			if d == nil {
+				return fmt.Sprintf("%s: db is not defined", td.Cmd)
+			}
+
+			var buf strings.Builder
+			d.mu.Lock()
+			for level, l := range d.mu.versions.currentVersion().Levels {
+				for f := range l.All() {
+					fmt.Fprintf(&buf, "L%d %s: sampled-reads=%d\n", level, f.FileNum, f.Access.Count())
+				}
+			}
+			d.mu.Unlock()
+			return buf.String()
+
 		case "iter":
 			if iter == nil || iter.iter == nil {
 				// TODO(peter): runDBDefineCmd doesn't properly update the visible
@@ -472,6 +488,70 @@ func TestReadSampling(t *testing.T) {
 	})
 }
 
+func TestReadCompactionSeekSampling(t *testing.T) {
+	for _, minLevels := range []int{2, 3} {
+		t.Run(fmt.Sprintf("min-levels=%d", minLevels), func(t *testing.T) {
+			opts := &Options{FS: vfs.NewMem(), DisableAutomaticCompactions: true}
+			opts.Experimental.ReadCompactionSeekSamplingPeriod = 1
+			opts.Experimental.ReadCompactionMinOverlappingLevels = minLevels
+			// Disable the sampling of bytes read.
+			opts.Experimental.ReadSamplingMultiplier = 1 << 30
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+
+			// Create a table in L6 and an overlapping table in L0.
+			for _, k := range []string{"a", "c", "e"} {
+				require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+			}
+			require.NoError(t, d.Compact([]byte("a"), []byte("f"), false /* parallelize */))
+			for _, k := range []string{"b", "d"} {
+				require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+			}
+			require.NoError(t, d.Flush())
+			m := d.Metrics()
+			require.Equal(t, int64(1), m.Levels[0].NumFiles)
+			require.Equal(t, int64(1), m.Levels[6].NumFiles)
+
+			readCompactions := func() int {
+				d.mu.Lock()
+				defer d.mu.Unlock()
+				return d.mu.compact.readCompactions.size
+			}
+			// Reading c consults the tables in both levels. Tables allow at least
+			// 100 sampled reads before they're compacted.
+			for i := 0; i < 100; i++ {
+				v, closer, err := d.Get([]byte("c"))
+				require.NoError(t, err)
+				require.Equal(t, "c", string(v))
+				require.NoError(t, closer.Close())
+			}
+			if minLevels == 2 {
+				require.Equal(t, 1, readCompactions())
+			} else {
+				require.Equal(t, 0, readCompactions())
+			}
+
+			d.mu.Lock()
+			d.mu.compact.readCompactions = readCompactionQueue{}
+			d.mu.Unlock()
+			iter, err := d.NewIter(nil)
+			require.NoError(t, err)
+			for i := 0; i < 100; i++ {
+				// Alternate between keys, since a seek to the key the iterator
+				// is already positioned at can return without seeking.
+				require.True(t, iter.SeekGE([]byte{"bc"[i%2]}))
+			}
+			require.NoError(t, iter.Close())
+			if minLevels == 2 {
+				require.Equal(t, 1, readCompactions())
+			} else {
+				require.Equal(t, 0, readCompactions())
+			}
+		})
+	}
+}
+
 func TestIteratorTableFilter(t *testing.T) {
 	var d *DB
 	defer func() {
@@ -1192,6 +1272,116 @@ func TestIteratorGuaranteedDurable(t *testing.T) {
 	})
 }
 
+func TestIteratorDebugTrace(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, d.Close())
+	}()
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	require.NoError(t, d.Set([]byte("c"), []byte("3"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("b"), []byte("2"), nil))
+
+	var buf bytes.Buffer
+	iter, err := d.NewIter(&IterOptions{DebugTrace: &buf})
+	require.NoError(t, err)
+	require.True(t, iter.SeekGE([]byte("b")))
+	require.True(t, iter.Next())
+	require.False(t, iter.Next())
+	require.NoError(t, iter.Close())
+
+	trace := buf.String()
+	for _, s := range []string{
+		"SeekGE(b)",
+		"memtable 0: SeekGE(b) = b#",
+		": load table ",
+		"=> valid b",
+		"=> valid c",
+		"=> exhausted",
+	} {
+		require.Contains(t, trace, s)
+	}
+
+	// Clearing the trace writer through SetOptions stops the trace.
+	iter, err = d.NewIter(&IterOptions{DebugTrace: &buf})
+	require.NoError(t, err)
+	iter.SetOptions(&IterOptions{})
+	buf.Reset()
+	require.True(t, iter.First())
+	require.NoError(t, iter.Close())
+	require.Empty(t, buf.String())
+}
+
+func TestIteratorReadAtDurableFrontier(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	keys := func(o *IterOptions) (string, SeqNum) {
+		iter, err := d.NewIter(o)
+		require.NoError(t, err)
+		var buf strings.Builder
+		for valid := iter.First(); valid; valid = iter.Next() {
+			buf.Write(iter.Key())
+		}
+		seqNum := iter.ReadSeqNum()
+		require.NoError(t, iter.Close())
+		return buf.String(), seqNum
+	}
+	frontierOpts := &IterOptions{ReadAtDurableFrontier: true}
+
+	require.NoError(t, d.Set([]byte("a"), nil, nil))
+	require.NoError(t, d.Flush())
+	frontier := d.DurableVisibleSeqNum()
+	require.NoError(t, d.Set([]byte("b"), nil, nil))
+	require.Equal(t, frontier, d.DurableVisibleSeqNum())
+
+	// An ingested sstable that doesn't overlap the memtable is added to the LSM
+	// with a sequence number above the frontier. It's visible to iterators that
+	// only read sstables, but not to iterators reading at the frontier.
+	f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), sstable.WriterOptions{
+		TableFormat: d.TableFormat(),
+	})
+	require.NoError(t, w.Set([]byte("c"), nil))
+	require.NoError(t, w.Close())
+	require.NoError(t, d.Ingest(context.Background(), []string{"ext"}))
+
+	ks, _ := keys(nil)
+	require.Equal(t, "abc", ks)
+	ks, _ = keys(&IterOptions{OnlyReadGuaranteedDurable: true})
+	require.Equal(t, "ac", ks)
+	ks, seqNum := keys(frontierOpts)
+	require.Equal(t, "a", ks)
+	require.Equal(t, frontier, seqNum)
+
+	// Flushing advances the frontier past both the memtable and the ingested
+	// sstable.
+	require.NoError(t, d.Flush())
+	require.Greater(t, d.DurableVisibleSeqNum(), frontier)
+	ks, seqNum = keys(frontierOpts)
+	require.Equal(t, "abc", ks)
+	require.Equal(t, d.DurableVisibleSeqNum(), seqNum)
+
+	// The option can't be changed once the iterator is created.
+	iter, err := d.NewIter(frontierOpts)
+	require.NoError(t, err)
+	_, err = iter.Clone(CloneOptions{IterOptions: &IterOptions{}})
+	require.Error(t, err)
+	require.True(t, iter.First())
+	require.Panics(t, func() { iter.SetOptions(&IterOptions{}) })
+	// The iterator is left untouched by the rejected options.
+	require.True(t, iter.Valid())
+	require.Equal(t, "a", string(iter.Key()))
+	require.NoError(t, iter.Close())
+	snap := d.NewSnapshot()
+	require.Panics(t, func() { _, _ = snap.NewIter(frontierOpts) })
+	require.NoError(t, snap.Close())
+}
+
 func TestIteratorBoundsLifetimes(t *testing.T) {
 	rng := rand.New(rand.NewPCG(0, uint64(time.Now().UnixNano())))
 	d := newPointTestkeysDatabase(t, testkeys.Alpha(2))
@@ -1350,6 +1540,10 @@ func TestIteratorStatsMerge(t *testing.T) {
 	s.InternalStats.SeparatedPointValue.Count = 1
 	s.InternalStats.SeparatedPointValue.ValueBytes = 5
 	s.InternalStats.SeparatedPointValue.ValueBytesFetched = 3
+	s.InternalStats.Filter.Consulted = 4
+	s.InternalStats.Filter.Negatives = 2
+	s.InternalStats.Filter.SampledPositives = 2
+	s.InternalStats.Filter.SampledFalsePositives = 1
 	s2 := IteratorStats{
 		ForwardSeekCount: [NumStatsKind]int{1, 2},
 		ReverseSeekCount: [NumStatsKind]int{3, 4},
@@ -1373,6 +1567,10 @@ func TestIteratorStatsMerge(t *testing.T) {
 	s2.InternalStats.SeparatedPointValue.Count = 2
 	s2.InternalStats.SeparatedPointValue.ValueBytes = 10
 	s2.InternalStats.SeparatedPointValue.ValueBytesFetched = 6
+	s2.InternalStats.Filter.Consulted = 8
+	s2.InternalStats.Filter.Negatives = 4
+	s2.InternalStats.Filter.SampledPositives = 4
+	s2.InternalStats.Filter.SampledFalsePositives = 2
 	s.Merge(s2)
 	expected := IteratorStats{
 		ForwardSeekCount: [NumStatsKind]int{2, 4},
@@ -1397,12 +1595,204 @@ func TestIteratorStatsMerge(t *testing.T) {
 	expected.InternalStats.SeparatedPointValue.Count = 3
 	expected.InternalStats.SeparatedPointValue.ValueBytes = 15
 	expected.InternalStats.SeparatedPointValue.ValueBytesFetched = 9
+	expected.InternalStats.Filter.Consulted = 12
+	expected.InternalStats.Filter.Negatives = 6
+	expected.InternalStats.Filter.SampledPositives = 6
+	expected.InternalStats.Filter.SampledFalsePositives = 3
 	require.Equal(t, expected, s)
 }
 
+// falsePositiveFilterPolicy wraps a FilterPolicy, and reports that the filters
+// may contain all the keys with the "fp" prefix.
+type falsePositiveFilterPolicy struct {
+	FilterPolicy
+}
+
+func (p falsePositiveFilterPolicy) MayContain(ftype FilterType, filter, key []byte) bool {
+	return bytes.HasPrefix(key, []byte("fp")) || p.FilterPolicy.MayContain(ftype, filter, key)
+}
+
+func TestIteratorFilterStats(t *testing.T) {
+	fp := falsePositiveFilterPolicy{bloom.FilterPolicy(10)}
+	d, err := Open("", &Options{
+		FS:     vfs.NewMem(),
+		Levels: []LevelOptions{{FilterPolicy: fp}},
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	// The sstable spans all the keys sought below, so that the filter is
+	// consulted for each of them.
+	for _, k := range []string{"a", "b", "c", "z"} {
+		require.NoError(t, d.Set([]byte(k), nil, nil))
+	}
+	require.NoError(t, d.Flush())
+
+	seekPrefixes := func(prefixes ...string) base.InternalIteratorStats {
+		iter, err := d.NewIter(nil)
+		require.NoError(t, err)
+		for _, p := range prefixes {
+			iter.SeekPrefixGE([]byte(p))
+		}
+		stats := iter.Stats().InternalStats
+		require.NoError(t, iter.Close())
+		return stats
+	}
+
+	// The first positive of an iterator is sampled.
+	stats := seekPrefixes("fpa")
+	require.Equal(t, uint64(1), stats.Filter.Consulted)
+	require.Equal(t, uint64(0), stats.Filter.Negatives)
+	require.Equal(t, uint64(1), stats.Filter.SampledPositives)
+	require.Equal(t, uint64(1), stats.Filter.SampledFalsePositives)
+
+	stats = seekPrefixes("a", "b", "d")
+	require.Equal(t, uint64(3), stats.Filter.Consulted)
+	require.Equal(t, uint64(1), stats.Filter.Negatives)
+	require.Equal(t, uint64(1), stats.Filter.SampledPositives)
+	require.Equal(t, uint64(0), stats.Filter.SampledFalsePositives)
+	require.Contains(t, stats.String(), "filter: 3 consulted, 1 negative, 0/1 sampled false positives")
+}
+
+func TestIteratorFamily(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, d.Set([]byte(k), nil, nil))
+		require.NoError(t, d.Flush())
+	}
+
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	require.Equal(t, IteratorFamilyStats{Iterators: 1}, iter.FamilyStats())
+	scan := func(it *Iterator) {
+		var n int
+		for valid := it.First(); valid; valid = it.Next() {
+			n++
+		}
+		require.Equal(t, 3, n)
+	}
+	clone1, err := iter.Clone(CloneOptions{})
+	require.NoError(t, err)
+	clone2, err := clone1.Clone(CloneOptions{})
+	require.NoError(t, err)
+
+	// The index blocks of the tables loaded by the first clone are served to
+	// the second one by the family.
+	scan(clone1)
+	scan(clone2)
+	stats := clone2.FamilyStats()
+	require.Equal(t, 3, stats.Iterators)
+	require.Equal(t, 2, stats.Clones)
+	require.Equal(t, 3, stats.IndexBlocks.Count)
+	require.Equal(t, int64(3), stats.IndexBlocks.Hits)
+	require.Equal(t, stats, iter.FamilyStats())
+
+	// Closed iterators contribute their stats to the family.
+	require.NoError(t, clone1.Close())
+	stats = iter.FamilyStats()
+	require.Equal(t, 2, stats.Iterators)
+	require.Equal(t, 3, stats.ClosedStats.ForwardStepCount[InterfaceCall])
+	require.NoError(t, clone2.Close())
+	stats = iter.FamilyStats()
+	require.Equal(t, 1, stats.Iterators)
+	require.Equal(t, 6, stats.ClosedStats.ForwardStepCount[InterfaceCall])
+	require.NoError(t, iter.Close())
+}
+
+// TestIteratorFamilyPinLimit tests that the index blocks pinned by an
+// iterator family are bounded by a fraction of the block cache's capacity,
+// and that the pins are released when the family is closed.
+func TestIteratorFamilyPinLimit(t *testing.T) {
+	const cacheSize = 64 << 10
+	c := NewCache(cacheSize)
+	defer c.Unref()
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		Cache:                       c,
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	const numTables = 100
+	for i := 0; i < numTables; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("key-%03d", i)), nil, nil))
+		require.NoError(t, d.Flush())
+	}
+
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	clone, err := iter.Clone(CloneOptions{})
+	require.NoError(t, err)
+	var n int
+	for valid := clone.First(); valid; valid = clone.Next() {
+		n++
+	}
+	require.Equal(t, numTables, n)
+
+	// The family can't pin the index blocks of all the tables: the oldest
+	// pins are released.
+	stats := clone.FamilyStats().IndexBlocks
+	require.LessOrEqual(t, stats.Size, int64(cacheSize/iterFamilyPinnedIndexFraction))
+	require.Less(t, stats.Count, numTables)
+	require.Greater(t, stats.Released, int64(0))
+	require.Equal(t, numTables, stats.Count+int(stats.Released))
+
+	// Closing the last iterator of the family releases the remaining pins.
+	f := iter.family
+	require.NoError(t, clone.Close())
+	require.NotZero(t, f.indexBlocks.Stats().Count)
+	require.NoError(t, iter.Close())
+	require.Zero(t, f.indexBlocks.Stats().Count)
+	require.Zero(t, f.indexBlocks.Stats().Size)
+}
+
 // TestSetOptionsEquivalence tests equivalence between SetOptions to mutate an
 // iterator and constructing a new iterator with NewIter. The long-lived
 // iterator and the new iterator should surface identical iterator states.
+func TestIteratorContextCancellation(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("", &Options{FS: mem})
+	require.NoError(t, err)
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, d.Set([]byte(k), []byte(k), nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Close())
+
+	// Reopen the DB with an empty block cache, so that reads need to load
+	// blocks.
+	cache := NewCache(1 << 20)
+	defer cache.Unref()
+	d, err = Open("", &Options{FS: mem, Cache: cache})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = d.GetWithContext(ctx, []byte("a"))
+	require.ErrorIs(t, err, context.Canceled)
+
+	iter, err := d.NewIterWithContext(ctx, nil)
+	require.NoError(t, err)
+	require.False(t, iter.First())
+	require.ErrorIs(t, iter.Error(), context.Canceled)
+
+	// Replacing the context allows the iterator to be used again.
+	iter.SetContext(context.Background())
+	require.True(t, iter.First())
+	require.Equal(t, "a", string(iter.Key()))
+	require.NoError(t, iter.Close())
+
+	v, closer, err := d.GetWithContext(context.Background(), []byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, "b", string(v))
+	require.NoError(t, closer.Close())
+}
+
 func TestSetOptionsEquivalence(t *testing.T) {
 	seed := uint64(time.Now().UnixNano())
 	// Call a helper function with the seed so that the seed appears within
@@ -1544,6 +1934,7 @@ func iterOptionsString(o *IterOptions) string {
 	if o.OnlyReadGuaranteedDurable {
 		fmt.Fprintf(&buf, ", only-durable")
 	}
+
 	if o.UseL6Filters {
 		fmt.Fprintf(&buf, ", use-L6-filters")
 	}
@@ -2284,6 +2675,50 @@ func TestIteratorSeekPrefixGERandomized(t *testing.T) {
 	}
 }
 
+func TestIteratorSeekGEMultiRandomized(t *testing.T) {
+	seed := uint64(time.Now().UnixNano())
+	t.Logf("seed: %d", seed)
+	rng := rand.New(rand.NewPCG(seed, seed))
+	ks := testkeys.Alpha(2)
+	d := newTestkeysDatabase(t, ks, rng)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Probe with increasingly sparse sets of keys, so that SeekGEMulti both
+	// steps and re-seeks, and compare with a sequence of SeekGE calls.
+	for _, maxGap := range []int64{2, 10, 100, 1000} {
+		var keys [][]byte
+		for j := rng.Int64N(maxGap); j < ks.Count(); j += rng.Int64N(maxGap) {
+			keys = append(keys, testkeys.Key(ks, j))
+		}
+		lower, upper := testkeys.Key(ks, rng.Int64N(ks.Count())), testkeys.Key(ks, rng.Int64N(ks.Count()))
+		if d.cmp(lower, upper) > 0 {
+			lower, upper = upper, lower
+		}
+		for _, opts := range []*IterOptions{nil, {LowerBound: lower, UpperBound: upper}} {
+			multiIter, err := d.NewIter(opts)
+			require.NoError(t, err)
+			iter, err := d.NewIter(opts)
+			require.NoError(t, err)
+			require.NoError(t, multiIter.SeekGEMulti(keys, func(idx int) error {
+				valid := iter.SeekGE(keys[idx])
+				require.Equal(t, valid, multiIter.Valid(), "SeekGE(%q)", keys[idx])
+				if valid {
+					require.Equal(t, string(iter.Key()), string(multiIter.Key()), "SeekGE(%q)", keys[idx])
+					require.Equal(t, string(iter.Value()), string(multiIter.Value()), "SeekGE(%q)", keys[idx])
+				}
+				return nil
+			}))
+			if maxGap == 2 && opts == nil {
+				// The dense probe set steps with Next instead of seeking.
+				require.Less(t, multiIter.Stats().ForwardSeekCount[InternalIterCall],
+					iter.Stats().ForwardSeekCount[InternalIterCall])
+			}
+			require.NoError(t, iter.Close())
+			require.NoError(t, multiIter.Close())
+		}
+	}
+}
+
 // BenchmarkIterator_RangeKeyMasking benchmarks a scan through a keyspace with
 // 10,000 random suffixed point keys, and three range keys covering most of the
 // keyspace. It varies the suffix of the range keys in subbenchmarks to exercise
@@ -0,0 +1,171 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/humanize"
+	"github.com/cockroachdb/pebble/internal/treeprinter"
+)
+
+// This file implements the trace of the positioning operations of an Iterator
+// configured with IterOptions.DebugTrace. The trace of a positioning operation
+// of the Iterator looks like:
+//
+//	SeekGE(b)
+//	  memtable 0: SeekGE(b) = <nil>
+//	  L6: load table 000005 [a#1,SET-z#3,SET]
+//	  L6: SeekGE(b) = b#2,SET
+//	  => valid b (block bytes: 4.0KB loaded, 3.2KB cached)
+
+// iterTracef writes a line of an iterator trace to w.
+func iterTracef(w io.Writer, format string, args ...any) {
+	_, _ = fmt.Fprintf(w, format+"\n", args...)
+}
+
+// formatTraceKV formats the result of a positioning operation of an internal
+// iterator.
+func formatTraceKV(kv *base.InternalKV, formatKey base.FormatKey) string {
+	if kv == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(kv.K.Pretty(formatKey))
+}
+
+// traceOp writes the trace line of a positioning operation of the Iterator,
+// and returns a function that writes the result of the operation, to be
+// called when the operation returns.
+func (i *Iterator) traceOp(op string, key []byte) func() {
+	if key != nil {
+		iterTracef(i.opts.DebugTrace, "%s(%s)", op, i.comparer.FormatKey(key))
+	} else {
+		iterTracef(i.opts.DebugTrace, "%s()", op)
+	}
+	before := i.stats.InternalStats
+	return func() {
+		var res string
+		switch i.iterValidityState {
+		case IterValid:
+			res = fmt.Sprintf("valid %s", i.comparer.FormatKey(i.Key()))
+		case IterAtLimit:
+			res = "at limit"
+		default:
+			res = "exhausted"
+			if err := i.Error(); err != nil {
+				res = fmt.Sprintf("error: %v", err)
+			}
+		}
+		after := i.stats.InternalStats
+		iterTracef(i.opts.DebugTrace, "  => %s (block bytes: %s loaded, %s cached)", res,
+			humanize.Bytes.Uint64(after.BlockBytes-before.BlockBytes),
+			humanize.Bytes.Uint64(after.BlockBytesInCache-before.BlockBytesInCache))
+	}
+}
+
+// maybeWrapForTrace wraps the iterator of one level of the Iterator's merging
+// iterator so that its positioning operations are traced, if the Iterator is
+// configured with IterOptions.DebugTrace. The name of the level is only built
+// if the iterator is traced.
+func (i *Iterator) maybeWrapForTrace(iter internalIterator, name func() string) internalIterator {
+	if i.opts.DebugTrace == nil {
+		return iter
+	}
+	return &tracingIter{iter: iter, w: i.opts.DebugTrace, name: name(), formatKey: i.comparer.FormatKey}
+}
+
+// tracingIter wraps an internal iterator, tracing its positioning operations.
+type tracingIter struct {
+	iter      internalIterator
+	w         io.Writer
+	name      string
+	formatKey base.FormatKey
+}
+
+var _ internalIterator = (*tracingIter)(nil)
+
+func (t *tracingIter) trace(op string, key []byte, kv *base.InternalKV) *base.InternalKV {
+	if key != nil {
+		iterTracef(t.w, "  %s: %s(%s) = %s", t.name, op, t.formatKey(key), formatTraceKV(kv, t.formatKey))
+	} else {
+		iterTracef(t.w, "  %s: %s() = %s", t.name, op, formatTraceKV(kv, t.formatKey))
+	}
+	return kv
+}
+
+// SeekGE implements internalIterator.
+func (t *tracingIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	return t.trace("SeekGE", key, t.iter.SeekGE(key, flags))
+}
+
+// SeekPrefixGE implements internalIterator.
+func (t *tracingIter) SeekPrefixGE(prefix, key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	return t.trace("SeekPrefixGE", key, t.iter.SeekPrefixGE(prefix, key, flags))
+}
+
+// SeekLT implements internalIterator.
+func (t *tracingIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	return t.trace("SeekLT", key, t.iter.SeekLT(key, flags))
+}
+
+// First implements internalIterator.
+func (t *tracingIter) First() *base.InternalKV {
+	return t.trace("First", nil, t.iter.First())
+}
+
+// Last implements internalIterator.
+func (t *tracingIter) Last() *base.InternalKV {
+	return t.trace("Last", nil, t.iter.Last())
+}
+
+// Next implements internalIterator.
+func (t *tracingIter) Next() *base.InternalKV {
+	return t.trace("Next", nil, t.iter.Next())
+}
+
+// NextPrefix implements internalIterator.
+func (t *tracingIter) NextPrefix(succKey []byte) *base.InternalKV {
+	return t.trace("NextPrefix", succKey, t.iter.NextPrefix(succKey))
+}
+
+// Prev implements internalIterator.
+func (t *tracingIter) Prev() *base.InternalKV {
+	return t.trace("Prev", nil, t.iter.Prev())
+}
+
+// Error implements internalIterator.
+func (t *tracingIter) Error() error {
+	return t.iter.Error()
+}
+
+// Close implements internalIterator.
+func (t *tracingIter) Close() error {
+	return t.iter.Close()
+}
+
+// SetBounds implements internalIterator.
+func (t *tracingIter) SetBounds(lower, upper []byte) {
+	iterTracef(t.w, "  %s: SetBounds(%s, %s)", t.name, t.formatKey(lower), t.formatKey(upper))
+	t.iter.SetBounds(lower, upper)
+}
+
+// SetContext implements internalIterator.
+func (t *tracingIter) SetContext(ctx context.Context) {
+	t.iter.SetContext(ctx)
+}
+
+// String implements fmt.Stringer.
+func (t *tracingIter) String() string {
+	return fmt.Sprintf("trace(%s)", t.iter.String())
+}
+
+// DebugTree implements base.IteratorDebug.
+func (t *tracingIter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p) %s", t, t, t.name)
+	t.iter.DebugTree(n)
+}
@@ -0,0 +1,215 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// ErrTooManyIterators is returned when creating an iterator would exceed
+// Options.MaxOpenIterators.
+var ErrTooManyIterators = errors.New("pebble: too many open iterators")
+
+// iterTracker tracks the open iterators that read the DB state, i.e. those
+// created through DB.NewIter, Snapshot.NewIter, Batch.NewIter and the like,
+// and Iterator.Clone. Each such iterator pins a readState (or a version) and
+// with it memtables and sstables that may already have been flushed or
+// compacted. It enforces Options.MaxOpenIterators and, if
+// Options.Experimental.IteratorLeakThreshold is set, detects iterators that
+// have been open for longer than the threshold.
+type iterTracker struct {
+	maxOpen       int
+	leakThreshold time.Duration
+	captureStacks bool
+	logger        Logger
+	timeNow       func() time.Time
+
+	// count is the number of open iterators.
+	count atomic.Int64
+
+	mu struct {
+		sync.Mutex
+		// iters contains the open iterators. It is only populated if leak
+		// detection is enabled.
+		iters map[*Iterator]*trackedIter
+		// lastCheck is the last time the iterators were checked for leaks.
+		lastCheck time.Time
+	}
+}
+
+// trackedIter contains the state tracked for an open iterator when leak
+// detection is enabled.
+type trackedIter struct {
+	createdAt time.Time
+	// stack is the stack trace of the iterator's creation, if
+	// Options.Experimental.IteratorLeakStacks is set.
+	stack []byte
+	// Either readState or version is set, but not both.
+	readState *readState
+	version   *version
+	// reported is set once the iterator has been reported as leaked.
+	reported bool
+}
+
+func (t *iterTracker) init(opts *Options, timeNow func() time.Time) {
+	t.maxOpen = opts.MaxOpenIterators
+	t.leakThreshold = opts.Experimental.IteratorLeakThreshold
+	t.captureStacks = opts.Experimental.IteratorLeakStacks
+	t.logger = opts.Logger
+	t.timeNow = timeNow
+	if t.leakThreshold > 0 {
+		t.mu.iters = make(map[*Iterator]*trackedIter)
+	}
+}
+
+// acquire reserves a slot for a new iterator, returning ErrTooManyIterators if
+// Options.MaxOpenIterators iterators are already open. On success, the
+// iterator must be registered after it has been constructed.
+func (t *iterTracker) acquire() error {
+	if n := t.count.Add(1); t.maxOpen > 0 && n > int64(t.maxOpen) {
+		t.count.Add(-1)
+		return ErrTooManyIterators
+	}
+	return nil
+}
+
+// register starts tracking an iterator for which a slot was acquired. The
+// iterator's readState or version must be set.
+func (t *iterTracker) register(i *Iterator) {
+	i.tracker = t
+	if t.leakThreshold <= 0 {
+		return
+	}
+	ti := &trackedIter{
+		createdAt: t.timeNow(),
+		readState: i.readState,
+		version:   i.version,
+	}
+	if t.captureStacks {
+		ti.stack = debug.Stack()
+	}
+	t.mu.Lock()
+	t.mu.iters[i] = ti
+	check := ti.createdAt.Sub(t.mu.lastCheck) >= t.leakThreshold
+	t.mu.Unlock()
+	if check {
+		t.checkLeaks()
+	}
+}
+
+// release stops tracking a closed iterator, freeing its slot.
+func (t *iterTracker) release(i *Iterator) {
+	if t.leakThreshold > 0 {
+		t.mu.Lock()
+		delete(t.mu.iters, i)
+		t.mu.Unlock()
+	}
+	t.count.Add(-1)
+}
+
+// checkLeaks reports, through the logger, the iterators that have been open for
+// longer than the leak threshold. Each iterator is reported once.
+func (t *iterTracker) checkLeaks() {
+	if t.leakThreshold <= 0 {
+		return
+	}
+	now := t.timeNow()
+	type leak struct {
+		age   time.Duration
+		stack []byte
+	}
+	var leaks []leak
+	t.mu.Lock()
+	t.mu.lastCheck = now
+	for _, ti := range t.mu.iters {
+		if age := now.Sub(ti.createdAt); !ti.reported && age >= t.leakThreshold {
+			ti.reported = true
+			leaks = append(leaks, leak{age: age, stack: ti.stack})
+		}
+	}
+	t.mu.Unlock()
+	for _, l := range leaks {
+		if l.stack != nil {
+			t.logger.Errorf("pebble: iterator open for %s, possibly leaked; created at:\n%s", l.age, l.stack)
+		} else {
+			t.logger.Errorf("pebble: iterator open for %s, possibly leaked", l.age)
+		}
+	}
+}
+
+// staleIteratorMetricsLocked populates the iterator metrics. Stale iterators
+// are those that have been open for longer than the leak threshold and whose
+// view of the DB is no longer current.
+//
+// d.mu must be held when calling this.
+func (d *DB) staleIteratorMetricsLocked(m *Metrics) {
+	t := &d.iters
+	m.Iterators.Open = t.count.Load()
+	if t.leakThreshold <= 0 {
+		return
+	}
+	now := t.timeNow()
+	current := d.mu.versions.currentVersion()
+	liveMem := make(map[*flushableEntry]struct{}, len(d.mu.mem.queue))
+	for _, mem := range d.mu.mem.queue {
+		liveMem[mem] = struct{}{}
+	}
+	pinnedMem := make(map[*flushableEntry]struct{})
+	pinnedVersions := make(map[*version]struct{})
+
+	t.mu.Lock()
+	for _, ti := range t.mu.iters {
+		if now.Sub(ti.createdAt) < t.leakThreshold {
+			continue
+		}
+		v := ti.version
+		if ti.readState != nil {
+			v = ti.readState.current
+		}
+		stale := v != current
+		if ti.readState != nil {
+			for _, mem := range ti.readState.memtables {
+				if _, ok := liveMem[mem]; !ok {
+					stale = true
+					pinnedMem[mem] = struct{}{}
+				}
+			}
+		}
+		if stale {
+			m.Iterators.Stale++
+			if v != current {
+				pinnedVersions[v] = struct{}{}
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	for mem := range pinnedMem {
+		m.Iterators.StalePinnedMemTableBytes += mem.totalBytes()
+	}
+	// The sstables pinned by stale iterators are the zombie tables in the
+	// versions they read from.
+	counted := make(map[base.DiskFileNum]struct{})
+	for v := range pinnedVersions {
+		for level := range v.Levels {
+			for f := range v.Levels[level].All() {
+				fileNum := f.FileBacking.DiskFileNum
+				if _, ok := counted[fileNum]; ok {
+					continue
+				}
+				if info, ok := d.mu.versions.zombieTables[fileNum]; ok {
+					counted[fileNum] = struct{}{}
+					m.Iterators.StalePinnedTableBytes += info.FileSize
+				}
+			}
+		}
+	}
+}
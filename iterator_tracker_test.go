@@ -0,0 +1,108 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxOpenIterators(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:               vfs.NewMem(),
+		MaxOpenIterators: 2,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	iter1, err := d.NewIter(nil)
+	require.NoError(t, err)
+	iter2, err := iter1.Clone(CloneOptions{})
+	require.NoError(t, err)
+	_, err = d.NewIter(nil)
+	require.ErrorIs(t, err, ErrTooManyIterators)
+	_, err = iter1.Clone(CloneOptions{})
+	require.ErrorIs(t, err, ErrTooManyIterators)
+	require.EqualValues(t, 2, d.Metrics().Iterators.Open)
+
+	// Batch-only iterators are not limited.
+	b := d.NewIndexedBatch()
+	batchIter, err := b.NewBatchOnlyIter(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, batchIter.Close())
+
+	require.NoError(t, iter2.Close())
+	iter3, err := d.NewIter(nil)
+	require.NoError(t, err)
+	require.NoError(t, iter1.Close())
+	require.NoError(t, iter3.Close())
+	require.NoError(t, b.Close())
+	require.Zero(t, d.Metrics().Iterators.Open)
+}
+
+func TestIteratorLeakDetection(t *testing.T) {
+	logger := &base.InMemLogger{}
+	opts := &Options{
+		FS:                          vfs.NewMem(),
+		Logger:                      logger,
+		DisableAutomaticCompactions: true,
+	}
+	opts.Experimental.IteratorLeakThreshold = time.Minute
+	opts.Experimental.IteratorLeakStacks = true
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	var now atomic.Int64
+	d.timeNow = func() time.Time { return time.Unix(0, now.Load()) }
+
+	require.NoError(t, d.Set([]byte("a"), []byte("v"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("a"), []byte("v2"), nil))
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	logger.Reset()
+
+	// The iterator isn't reported before the threshold.
+	now.Add(int64(30 * time.Second))
+	m := d.Metrics()
+	require.EqualValues(t, 1, m.Iterators.Open)
+	require.Zero(t, m.Iterators.Stale)
+	require.Empty(t, logger.String())
+
+	// Once the threshold has passed, the iterator is reported, with its
+	// creation stack, but it's not stale until its view of the DB is outdated.
+	now.Add(int64(time.Minute))
+	m = d.Metrics()
+	require.Zero(t, m.Iterators.Stale)
+	require.Contains(t, logger.String(), "possibly leaked")
+	require.Contains(t, logger.String(), "TestIteratorLeakDetection")
+
+	// The iterator pins the flushed memtable and, after a compaction, the
+	// compacted sstable.
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact([]byte("a"), []byte("b"), false /* parallelize */))
+	m = d.Metrics()
+	require.EqualValues(t, 1, m.Iterators.Stale)
+	require.NotZero(t, m.Iterators.StalePinnedMemTableBytes)
+	require.NotZero(t, m.Iterators.StalePinnedTableBytes)
+
+	// The iterator is only reported once.
+	logger.Reset()
+	now.Add(int64(time.Hour))
+	d.Metrics()
+	require.Empty(t, logger.String())
+
+	require.NoError(t, iter.Close())
+	m = d.Metrics()
+	require.Zero(t, m.Iterators.Open)
+	require.Zero(t, m.Iterators.Stale)
+	require.Zero(t, m.Iterators.StalePinnedMemTableBytes)
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// KeyRangeStats describes the write and space amplification of a key range
+// registered through DB.RegisterKeyRangeStats (e.g. the keys of a tenant).
+//
+// The bytes written by flushes and compactions are attributed to the range in
+// proportion to the sizes of the keys and values that fall within it. Bytes
+// written by ingestions, and by compactions that copy or move tables without
+// rewriting them, are not attributed.
+type KeyRangeStats struct {
+	// BytesFlushed is the number of bytes, within the range, written by flushes
+	// since the range was registered.
+	BytesFlushed uint64
+	// BytesCompacted is the number of bytes, within the range, written by
+	// compactions since the range was registered.
+	BytesCompacted uint64
+	// LiveBytes is an estimate of the size of the live tables that overlap the
+	// range (see DB.EstimateDiskUsage).
+	LiveBytes uint64
+	// BottommostBytes is an estimate of the size of the tables in the bottommost
+	// level that overlap the range, which approximates the size of the range's
+	// data without amplification.
+	BottommostBytes uint64
+}
+
+// WriteAmp returns the write amplification of the range since it was
+// registered: the bytes flushed and compacted, divided by the bytes flushed.
+// It returns 0 if nothing was flushed.
+func (s *KeyRangeStats) WriteAmp() float64 {
+	if s.BytesFlushed == 0 {
+		return 0
+	}
+	return float64(s.BytesFlushed+s.BytesCompacted) / float64(s.BytesFlushed)
+}
+
+// SpaceAmp returns the space amplification of the range: LiveBytes divided by
+// BottommostBytes. It returns 0 if the range has no data in the bottommost
+// level.
+func (s *KeyRangeStats) SpaceAmp() float64 {
+	if s.BottommostBytes == 0 {
+		return 0
+	}
+	return float64(s.LiveBytes) / float64(s.BottommostBytes)
+}
+
+// keyRangeStatsEntry is a key range registered through
+// DB.RegisterKeyRangeStats.
+type keyRangeStatsEntry struct {
+	name   string
+	bounds base.UserKeyBounds
+	// bytesFlushed and bytesCompacted are protected by DB.mu.
+	bytesFlushed   uint64
+	bytesCompacted uint64
+}
+
+// RegisterKeyRangeStats starts attributing the bytes written by flushes and
+// compactions within the given key range to name, so that they're reported by
+// KeyRangeStats. The range is [r.Start, r.End). It returns an error if a range
+// is already registered under the same name.
+//
+// Registering a range adds a small cost, proportional to the number of
+// registered ranges, to every key written by flushes and compactions. Flushes
+// and compactions that are running when the range is registered don't
+// attribute their bytes to it.
+func (d *DB) RegisterKeyRangeStats(name string, r KeyRange) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if !r.Valid() || d.cmp(r.Start, r.End) >= 0 {
+		return errors.Errorf("pebble: invalid key range [%q, %q)", r.Start, r.End)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range d.mu.keyRangeStats {
+		if e.name == name {
+			return errors.Errorf("pebble: key range %q is already registered", name)
+		}
+	}
+	d.mu.keyRangeStats = append(d.mu.keyRangeStats, &keyRangeStatsEntry{
+		name:   name,
+		bounds: r.UserKeyBounds(),
+	})
+	return nil
+}
+
+// UnregisterKeyRangeStats stops attributing bytes to the key range registered
+// under name, if any.
+func (d *DB) UnregisterKeyRangeStats(name string) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, e := range d.mu.keyRangeStats {
+		if e.name == name {
+			// The slice is shared with the compactions in progress; don't modify
+			// it in place.
+			d.mu.keyRangeStats = append(d.mu.keyRangeStats[:i:i], d.mu.keyRangeStats[i+1:]...)
+			return
+		}
+	}
+}
+
+// KeyRangeStats returns the stats of the key ranges registered through
+// RegisterKeyRangeStats, by name.
+func (d *DB) KeyRangeStats() map[string]KeyRangeStats {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	entries := d.mu.keyRangeStats
+	stats := make(map[string]KeyRangeStats, len(entries))
+	for _, e := range entries {
+		stats[e.name] = KeyRangeStats{
+			BytesFlushed:   e.bytesFlushed,
+			BytesCompacted: e.bytesCompacted,
+		}
+	}
+	d.mu.Unlock()
+
+	readState := d.loadReadState()
+	defer readState.unref()
+	v := readState.current
+	for _, e := range entries {
+		s := stats[e.name]
+		s.LiveBytes = *d.mu.annotators.totalSize.VersionRangeAnnotation(v, e.bounds)
+		s.BottommostBytes = *d.mu.annotators.totalSize.LevelRangeAnnotation(v.Levels[numLevels-1], e.bounds)
+		stats[e.name] = s
+	}
+	return stats
+}
+
+// attributedRanges returns the bounds of the given key ranges, to be passed
+// to compact.RunnerConfig.AttributedRanges.
+func attributedRanges(entries []*keyRangeStatsEntry) []base.UserKeyBounds {
+	if len(entries) == 0 {
+		return nil
+	}
+	bounds := make([]base.UserKeyBounds, len(entries))
+	for i, e := range entries {
+		bounds[i] = e.bounds
+	}
+	return bounds
+}
+
+// recordKeyRangeBytesLocked attributes the bytes written by a completed flush
+// or compaction to the key ranges registered when it started.
+//
+// d.mu must be held when calling this.
+func (d *DB) recordKeyRangeBytesLocked(c *compaction) {
+	for i, e := range c.keyRanges {
+		if i >= len(c.keyRangeBytes) {
+			break
+		}
+		if c.flushing != nil {
+			e.bytesFlushed += c.keyRangeBytes[i]
+		} else {
+			e.bytesCompacted += c.keyRangeBytes[i]
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/testutils"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRangeStats(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.RegisterKeyRangeStats("a", KeyRange{Start: []byte("a"), End: []byte("b")}))
+	require.NoError(t, d.RegisterKeyRangeStats("b", KeyRange{Start: []byte("b"), End: []byte("c")}))
+	require.Error(t, d.RegisterKeyRangeStats("a", KeyRange{Start: []byte("x"), End: []byte("y")}))
+	require.Error(t, d.RegisterKeyRangeStats("c", KeyRange{Start: []byte("y"), End: []byte("x")}))
+
+	// Write three times as many bytes to "a" as to "b".
+	rng := rand.New(rand.NewPCG(0, 0))
+	for i := 0; i < 300; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("a%04d", i)), testutils.RandBytes(rng, 100), nil))
+		if i%3 == 0 {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("b%04d", i)), testutils.RandBytes(rng, 100), nil))
+		}
+	}
+	require.NoError(t, d.Flush())
+
+	stats := d.KeyRangeStats()
+	require.Len(t, stats, 2)
+	a, b := stats["a"], stats["b"]
+	require.Greater(t, b.BytesFlushed, uint64(0))
+	require.InDelta(t, 3, float64(a.BytesFlushed)/float64(b.BytesFlushed), 0.2)
+	require.Zero(t, a.BytesCompacted)
+	require.Equal(t, float64(1), a.WriteAmp())
+	require.Greater(t, a.LiveBytes, uint64(0))
+	require.Zero(t, a.BottommostBytes)
+
+	// Overwrite some of the keys, so that the compaction of the two flushed
+	// tables rewrites them.
+	require.NoError(t, d.Set([]byte("a0000"), []byte("x"), nil))
+	require.NoError(t, d.Set([]byte("b0000"), []byte("x"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact([]byte("a"), []byte("c"), true /* parallelize */))
+	stats = d.KeyRangeStats()
+	a, b = stats["a"], stats["b"]
+	require.Greater(t, a.BytesCompacted, uint64(0))
+	require.Greater(t, b.BytesCompacted, uint64(0))
+	require.Greater(t, a.WriteAmp(), float64(1))
+	require.Greater(t, a.BottommostBytes, uint64(0))
+	require.Equal(t, float64(1), a.SpaceAmp())
+
+	d.UnregisterKeyRangeStats("a")
+	stats = d.KeyRangeStats()
+	require.Len(t, stats, 1)
+	require.Contains(t, stats, "b")
+}
@@ -7,6 +7,7 @@ package pebble
 import (
 	"context"
 	"fmt"
+	"io"
 	"runtime/debug"
 
 	"github.com/cockroachdb/errors"
@@ -33,8 +34,11 @@ type levelIter struct {
 	// short-lived (since they pin sstables), (b) plumbing a context into every
 	// method is very painful, (c) they do not (yet) respect context
 	// cancellation and are only used for tracing.
-	ctx      context.Context
-	logger   Logger
+	ctx    context.Context
+	logger Logger
+	// trace, if set, is the writer of the trace of the iterator (see
+	// IterOptions.DebugTrace).
+	trace    io.Writer
 	comparer *Comparer
 	cmp      Compare
 	split    Split
@@ -149,6 +153,7 @@ func (l *levelIter) init(
 	l.err = nil
 	l.layer = layer
 	l.logger = opts.getLogger()
+	l.trace = opts.DebugTrace
 	l.prefix = nil
 	l.lower = opts.LowerBound
 	l.upper = opts.UpperBound
@@ -573,6 +578,14 @@ func (l *levelIter) loadFile(file *tableMetadata, dir int) loadFileReturnIndicat
 			return noFileLoaded
 		}
 		l.iter = iters.Point()
+		if l.trace != nil {
+			if iters.point == nil {
+				iterTracef(l.trace, "  %s: table %s skipped by filters", l.layer, file.FileNum)
+			} else {
+				iterTracef(l.trace, "  %s: load table %s [%s-%s]", l.layer, file.FileNum,
+					file.SmallestPointKey.Pretty(l.comparer.FormatKey), file.LargestPointKey.Pretty(l.comparer.FormatKey))
+			}
+		}
 		if l.rangeDelIterSetter != nil && iters.rangeDeletion != nil {
 			// If this file has range deletions, interleave the bounds of the
 			// range deletions among the point keys. When used with a
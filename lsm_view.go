@@ -185,10 +185,18 @@ func (b *lsmViewBuilder) tableDetails(
 		// Note: we are abusing the key formatter by passing just the prefix.
 		outf("synthetic prefix: %s", b.fmtKey(m.SyntheticPrefixAndSuffix.Prefix()))
 	}
+	if len(m.ContentPrefix) > 0 {
+		// Note: we are abusing the key formatter by passing just the prefix.
+		outf("content prefix: %s", b.fmtKey(m.ContentPrefix))
+	}
 	if m.SyntheticPrefixAndSuffix.HasSuffix() {
 		// Note: we are abusing the key formatter by passing just the suffix.
 		outf("synthetic suffix: %s", b.fmtKey(m.SyntheticPrefixAndSuffix.Suffix()))
 	}
+	for _, r := range m.SuffixReplacements {
+		// Note: we are abusing the key formatter by passing just the suffixes.
+		outf("suffix replacement: %s -> %s", b.fmtKey(r.From), b.fmtKey(r.To))
+	}
 	var iters iterSet
 	if b.scanTables {
 		var err error
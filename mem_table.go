@@ -87,6 +87,9 @@ type memTable struct {
 	// guaranteed to be less than or equal to any seqnum stored in the memtable.
 	logSeqNum                    base.SeqNum
 	releaseAccountingReservation func()
+	// valueCompressor is non-nil if the point values of the memtable are
+	// compressed. See Options.Experimental.MemTableValueCompression.
+	valueCompressor *memTableValueCompressor
 }
 
 func (m *memTable) free() {
@@ -139,6 +142,7 @@ func (m *memTable) init(opts memTableOptions) {
 		arenaBuf:                     opts.arenaBuf,
 		logSeqNum:                    opts.logSeqNum,
 		releaseAccountingReservation: opts.releaseAccountingReservation,
+		valueCompressor:              makeMemTableValueCompressor(opts.Experimental.MemTableValueCompression),
 	}
 	m.writerRefs.Store(1)
 	m.tombstones = keySpanCache{
@@ -195,10 +199,15 @@ func (m *memTable) readyForFlush() bool {
 // writerUnref() after the batch has been applied.
 func (m *memTable) prepare(batch *Batch) error {
 	avail := m.availBytes()
-	if batch.memTableSize > uint64(avail) {
+	size := batch.memTableSize
+	if m.valueCompressor != nil {
+		// Encoding a value may increase its size.
+		size += batch.count * memTableValueOverhead
+	}
+	if size > uint64(avail) {
 		return arenaskl.ErrArenaFull
 	}
-	m.reserved += uint32(batch.memTableSize)
+	m.reserved += uint32(size)
 
 	m.writerRef()
 	return nil
@@ -212,6 +221,7 @@ func (m *memTable) apply(batch *Batch, seqNum base.SeqNum) error {
 
 	var ins arenaskl.Inserter
 	var tombstoneCount, rangeKeyCount uint32
+	var valueBuf []byte
 	startSeqNum := seqNum
 	for r := batch.Reader(); ; seqNum++ {
 		kind, ukey, value, ok, err := r.Next()
@@ -236,6 +246,9 @@ func (m *memTable) apply(batch *Batch, seqNum base.SeqNum) error {
 		case InternalKeyKindIngestSST, InternalKeyKindExcise:
 			panic("pebble: cannot apply ingested sstable or excise kind keys to memtable")
 		default:
+			if m.valueCompressor != nil {
+				value, valueBuf = m.valueCompressor.encode(valueBuf, value)
+			}
 			err = ins.Add(&m.skl, ikey, value)
 		}
 		if err != nil {
@@ -259,11 +272,17 @@ func (m *memTable) apply(batch *Batch, seqNum base.SeqNum) error {
 // unpositioned (Iterator.Valid() will return false). The iterator can be
 // positioned via a call to SeekGE, SeekLT, First or Last.
 func (m *memTable) newIter(o *IterOptions) internalIterator {
+	if m.valueCompressor != nil {
+		return &compressedMemTableIter{iter: m.skl.NewIter(o.GetLowerBound(), o.GetUpperBound()), c: m.valueCompressor}
+	}
 	return m.skl.NewIter(o.GetLowerBound(), o.GetUpperBound())
 }
 
 // newFlushIter is part of the flushable interface.
 func (m *memTable) newFlushIter(o *IterOptions) internalIterator {
+	if m.valueCompressor != nil {
+		return &compressedMemTableIter{iter: m.skl.NewFlushIter(), c: m.valueCompressor}
+	}
 	return m.skl.NewFlushIter()
 }
 
@@ -0,0 +1,231 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/treeprinter"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// MemTableValueCompressionOptions configures the compression of large values
+// stored in memtables (see Options.Experimental.MemTableValueCompression).
+//
+// Compressing values trades CPU for memory: more data fits in a memtable of a
+// given size, so bursts of writes of large values don't immediately fill the
+// memtables, forcing flushes and possibly write stalls. Values are compressed
+// when they're applied to the memtable, and decompressed whenever they're read
+// from it (including when the memtable is flushed).
+type MemTableValueCompressionOptions struct {
+	// MinValueSize is the size of the smallest value that is compressed. Value
+	// compression is disabled if MinValueSize is not positive. Values smaller
+	// than MinValueSize are stored as is; larger values are stored with a
+	// 1-byte header if they're not compressed.
+	MinValueSize int
+	// Compression is the compression algorithm. DefaultCompression selects
+	// snappy. Value compression is disabled if Compression is NoCompression.
+	Compression Compression
+}
+
+// memTableValueCompressor encodes the point values of a memtable that
+// compresses its values.
+//
+// Values smaller than minValueSize are stored as is. Larger values are encoded
+// with a length of at least minValueSize, and prefixed by a compression
+// indicator, so that the encoded values are told apart by their lengths. If
+// the indicator is block.NoCompressionIndicator, the rest of the encoded value
+// is the value itself. Otherwise, the indicator is followed by the
+// uvarint-encoded lengths of the value and of the compressed value, the
+// compressed value, and padding up to minValueSize bytes.
+type memTableValueCompressor struct {
+	minValueSize int
+	compressor   block.Compressor
+}
+
+// memTableValueOverhead is the maximum number of bytes by which encoding a
+// value for a memtable that compresses its values increases its size.
+const memTableValueOverhead = 1
+
+func makeMemTableValueCompressor(opts MemTableValueCompressionOptions) *memTableValueCompressor {
+	if opts.MinValueSize <= 0 || opts.Compression == NoCompression {
+		return nil
+	}
+	return &memTableValueCompressor{
+		minValueSize: opts.MinValueSize,
+		compressor:   block.GetCompressor(resolveDefaultCompression(opts.Compression)),
+	}
+}
+
+// encode returns the encoding of v. If the encoding differs from v, it's
+// appended to buf[:0], which is returned for reuse. Like block compression,
+// the compressed value is only used if compression reduces the value's size by
+// at least 12.5%.
+func (c *memTableValueCompressor) encode(buf, v []byte) (encoded, _ []byte) {
+	if len(v) < c.minValueSize {
+		return v, buf
+	}
+	var hdr [1 + 2*binary.MaxVarintLen64]byte
+	algo, compressed := c.compressor.Compress(nil, v)
+	hdr[0] = byte(algo)
+	n := 1 + binary.PutUvarint(hdr[1:], uint64(len(v)))
+	n += binary.PutUvarint(hdr[n:], uint64(len(compressed)))
+	if encodedLen := max(n+len(compressed), c.minValueSize); encodedLen < len(v)-len(v)/8 {
+		buf = append(buf[:0], hdr[:n]...)
+		buf = append(buf, compressed...)
+		// Pad the encoded value so that it's not mistaken for a small value.
+		for len(buf) < encodedLen {
+			buf = append(buf, 0)
+		}
+		return buf, buf
+	}
+	buf = append(buf[:0], byte(block.NoCompressionIndicator))
+	buf = append(buf, v...)
+	return buf, buf
+}
+
+// decode decodes a value encoded by encode. If the value is compressed, it's
+// decompressed into a newly allocated slice, so that the returned value is
+// stable like the values stored in the memtable.
+func (c *memTableValueCompressor) decode(v []byte) ([]byte, error) {
+	if len(v) < c.minValueSize {
+		return v, nil
+	}
+	algo := block.CompressionIndicator(v[0])
+	if algo == block.NoCompressionIndicator {
+		return v[1:], nil
+	}
+	valueLen, n1 := binary.Uvarint(v[1:])
+	if n1 <= 0 {
+		return nil, base.CorruptionErrorf("pebble: invalid compressed memtable value length")
+	}
+	compressedLen, n2 := binary.Uvarint(v[1+n1:])
+	if n2 <= 0 || compressedLen > uint64(len(v)-1-n1-n2) {
+		return nil, base.CorruptionErrorf("pebble: invalid compressed memtable value length")
+	}
+	compressed := v[1+n1+n2:][:compressedLen]
+	buf := make([]byte, valueLen)
+	if err := block.DecompressInto(algo, compressed, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// compressedMemTableIter wraps a point iterator of a memtable that compresses
+// its values, decoding the values.
+type compressedMemTableIter struct {
+	iter internalIterator
+	c    *memTableValueCompressor
+	kv   base.InternalKV
+	err  error
+}
+
+var _ internalIterator = (*compressedMemTableIter)(nil)
+
+func (i *compressedMemTableIter) decode(kv *base.InternalKV) *base.InternalKV {
+	if kv == nil {
+		return nil
+	}
+	v, err := i.c.decode(kv.InPlaceValue())
+	if err != nil {
+		i.err = err
+		return nil
+	}
+	i.kv = base.InternalKV{K: kv.K, V: base.MakeInPlaceValue(v)}
+	return &i.kv
+}
+
+// SeekGE implements internalIterator.
+func (i *compressedMemTableIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	i.err = nil
+	return i.decode(i.iter.SeekGE(key, flags))
+}
+
+// SeekPrefixGE implements internalIterator.
+func (i *compressedMemTableIter) SeekPrefixGE(
+	prefix, key []byte, flags base.SeekGEFlags,
+) *base.InternalKV {
+	i.err = nil
+	return i.decode(i.iter.SeekPrefixGE(prefix, key, flags))
+}
+
+// SeekLT implements internalIterator.
+func (i *compressedMemTableIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	i.err = nil
+	return i.decode(i.iter.SeekLT(key, flags))
+}
+
+// First implements internalIterator.
+func (i *compressedMemTableIter) First() *base.InternalKV {
+	i.err = nil
+	return i.decode(i.iter.First())
+}
+
+// Last implements internalIterator.
+func (i *compressedMemTableIter) Last() *base.InternalKV {
+	i.err = nil
+	return i.decode(i.iter.Last())
+}
+
+// Next implements internalIterator.
+func (i *compressedMemTableIter) Next() *base.InternalKV {
+	if i.err != nil {
+		return nil
+	}
+	return i.decode(i.iter.Next())
+}
+
+// NextPrefix implements internalIterator.
+func (i *compressedMemTableIter) NextPrefix(succKey []byte) *base.InternalKV {
+	if i.err != nil {
+		return nil
+	}
+	return i.decode(i.iter.NextPrefix(succKey))
+}
+
+// Prev implements internalIterator.
+func (i *compressedMemTableIter) Prev() *base.InternalKV {
+	if i.err != nil {
+		return nil
+	}
+	return i.decode(i.iter.Prev())
+}
+
+// Error implements internalIterator.
+func (i *compressedMemTableIter) Error() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.iter.Error()
+}
+
+// Close implements internalIterator.
+func (i *compressedMemTableIter) Close() error {
+	return i.iter.Close()
+}
+
+// SetBounds implements internalIterator.
+func (i *compressedMemTableIter) SetBounds(lower, upper []byte) {
+	i.iter.SetBounds(lower, upper)
+}
+
+// SetContext implements internalIterator.
+func (i *compressedMemTableIter) SetContext(ctx context.Context) {
+	i.iter.SetContext(ctx)
+}
+
+// String implements fmt.Stringer.
+func (i *compressedMemTableIter) String() string {
+	return fmt.Sprintf("compressed(%s)", i.iter.String())
+}
+
+// DebugTree implements base.IteratorDebug.
+func (i *compressedMemTableIter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p)", i, i)
+	i.iter.DebugTree(n)
+}
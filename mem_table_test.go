@@ -21,6 +21,8 @@ import (
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/itertest"
 	"github.com/cockroachdb/pebble/internal/rangekey"
+	"github.com/cockroachdb/pebble/internal/testutils"
+	"github.com/cockroachdb/pebble/vfs"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 )
@@ -380,6 +382,95 @@ func TestMemTableReserved(t *testing.T) {
 	require.Equal(t, int(m.reserved), int(b.memTableSize)+int(prevReserved))
 }
 
+func TestMemTableValueCompression(t *testing.T) {
+	applyBatch := func(m *memTable, b *Batch) {
+		require.NoError(t, m.prepare(b))
+		require.NoError(t, m.apply(b, 1))
+		m.writerUnref()
+	}
+	large := bytes.Repeat([]byte("compressible"), 1000)
+	b := newBatch(nil)
+	require.NoError(t, b.Set([]byte("a"), large, nil))
+	require.NoError(t, b.Set([]byte("b"), []byte("small"), nil))
+	require.NoError(t, b.Delete([]byte("c"), nil))
+	require.NoError(t, b.Merge([]byte("d"), large, nil))
+
+	uncompressed := newMemTable(memTableOptions{})
+	applyBatch(uncompressed, b)
+
+	opts := &Options{}
+	opts.Experimental.MemTableValueCompression = MemTableValueCompressionOptions{MinValueSize: 100}
+	m := newMemTable(memTableOptions{Options: opts})
+	applyBatch(m, b)
+	require.Less(t, m.inuseBytes(), uncompressed.inuseBytes()/2)
+
+	expected := []string{
+		fmt.Sprintf("a#1,SET:%s", large),
+		"b#2,SET:small",
+		"c#3,DEL:",
+		fmt.Sprintf("d#4,MERGE:%s", large),
+	}
+	for _, iter := range []internalIterator{m.newIter(nil), m.newFlushIter(nil)} {
+		var got []string
+		for kv := iter.First(); kv != nil; kv = iter.Next() {
+			got = append(got, fmt.Sprintf("%s:%s", kv.K, kv.InPlaceValue()))
+		}
+		require.NoError(t, iter.Close())
+		require.Equal(t, expected, got)
+	}
+	iter := m.newIter(nil)
+	kv := iter.SeekLT([]byte("b"), base.SeekLTFlagsNone)
+	require.Equal(t, large, kv.InPlaceValue())
+	require.NoError(t, iter.Close())
+
+	// Values are decompressed by reads and flushes.
+	opts.FS = vfs.NewMem()
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.Set([]byte("a"), large, nil))
+	for _, flush := range []bool{false, true} {
+		if flush {
+			require.NoError(t, d.Flush())
+		}
+		v, closer, err := d.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, large, v)
+		require.NoError(t, closer.Close())
+	}
+}
+
+func TestMemTableValueCompressorEncoding(t *testing.T) {
+	// NoCompression disables value compression.
+	require.Nil(t, makeMemTableValueCompressor(MemTableValueCompressionOptions{
+		MinValueSize: 100, Compression: NoCompression,
+	}))
+
+	c := makeMemTableValueCompressor(MemTableValueCompressionOptions{MinValueSize: 100})
+	for _, v := range [][]byte{
+		nil,
+		[]byte("small"),
+		bytes.Repeat([]byte("a"), 99),
+		// Compressed to fewer than MinValueSize bytes, and padded.
+		bytes.Repeat([]byte("a"), 200),
+		bytes.Repeat([]byte("compressible"), 1000),
+		// Incompressible.
+		testutils.RandBytes(rand.New(rand.NewPCG(0, 0)), 200),
+	} {
+		encoded, _ := c.encode(nil, v)
+		if len(v) < 100 {
+			// Small values are stored as is.
+			require.Equal(t, v, encoded)
+		} else {
+			require.GreaterOrEqual(t, len(encoded), 100)
+			require.LessOrEqual(t, len(encoded), len(v)+memTableValueOverhead)
+		}
+		decoded, err := c.decode(encoded)
+		require.NoError(t, err)
+		require.Equal(t, string(v), string(decoded))
+	}
+}
+
 func TestMemTable(t *testing.T) {
 	var m *memTable
 	var buf bytes.Buffer
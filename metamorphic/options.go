@@ -677,6 +677,14 @@ func RandomOptions(
 	opts.Experimental.LevelMultiplier = 5 << rng.IntN(7)        // 5 - 320
 	opts.TargetByteDeletionRate = 1 << uint(20+rng.IntN(10))    // 1MB - 1GB
 	opts.Experimental.ValidateOnIngest = rng.IntN(2) != 0
+	opts.Experimental.MaxGrandparentOverlapFactor = 1 + rng.IntN(20)
+	opts.Experimental.AdaptiveGrandparentOverlap = rng.IntN(2) == 0
+	if rng.IntN(4) == 0 {
+		opts.Experimental.MemTableValueCompression.MinValueSize = 1 << rng.IntN(8) // 1B - 128B
+	}
+	if rng.IntN(2) == 0 {
+		opts.Experimental.MaxSubcompactions = 2 + rng.IntN(3) // 2-4
+	}
 	opts.L0CompactionThreshold = 1 + rng.IntN(100)     // 1 - 100
 	opts.L0CompactionFileThreshold = 1 << rng.IntN(11) // 1 - 1024
 	opts.L0StopWritesThreshold = 50 + rng.IntN(100)    // 50 - 150
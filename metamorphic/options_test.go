@@ -65,8 +65,10 @@ func TestOptionsRoundtrip(t *testing.T) {
 		"Cache:",
 		"Cache.",
 		"FS:",
+		"TempFS:",
 		"KeySchemas[",
 		"FileCache:",
+		"IORateLimiter:",
 		"Experimental.CompactionScheduler",
 		// Function pointers
 		"BlockPropertyCollectors:",
@@ -7,16 +7,19 @@ package pebble
 import (
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
 	"github.com/cockroachdb/pebble/internal/humanize"
 	"github.com/cockroachdb/pebble/internal/manual"
+	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider/sharedcache"
 	"github.com/cockroachdb/pebble/record"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/wal"
 	"github.com/cockroachdb/redact"
 	"github.com/prometheus/client_golang/prometheus"
@@ -37,6 +40,107 @@ type ThroughputMetric = base.ThroughputMetric
 // file system.
 type SecondaryCacheMetrics = sharedcache.Metrics
 
+// RemoteStorageBreakerState is the state of the circuit breaker around reads
+// from the remote storage of a locator.
+type RemoteStorageBreakerState = objstorage.RemoteBreakerState
+
+// FlushReasonMetrics holds cumulative statistics about the flushes initiated
+// for a FlushReason.
+type FlushReasonMetrics struct {
+	// Count is the number of flushes.
+	Count int64
+	// Bytes is the number of bytes written to sstables by the flushes, or
+	// ingested for flushes of ingested sstables.
+	Bytes uint64
+	// Duration is the time spent writing and syncing the flushed keys.
+	Duration time.Duration
+}
+
+// CommitPipelineMetrics holds cumulative statistics about the batches committed
+// through the commit pipeline.
+type CommitPipelineMetrics struct {
+	// Count is the number of committed batches.
+	Count int64
+	// BatchSize samples the size of committed batches, and SyncBatchSize the
+	// size of committed batches that requested a WAL sync.
+	BatchSize     base.GaugeSampleMetric
+	SyncBatchSize base.GaugeSampleMetric
+	// BatchCommitStats holds the time spent in each stage of the commit
+	// pipeline, summed across all committed batches. For batches committed
+	// through DB.ApplyNoSyncWait, the wait for the WAL sync is accounted for in
+	// Batch.SyncWait.
+	BatchCommitStats
+}
+
+// commitPipelineCounters accumulates CommitPipelineMetrics. Batches are
+// committed concurrently, so the counters are updated atomically rather than
+// under a mutex that would serialize committers.
+type commitPipelineCounters struct {
+	count                       atomic.Int64
+	batchSize                   base.AtomicGaugeSampleMetric
+	syncBatchSize               base.AtomicGaugeSampleMetric
+	totalDuration               atomic.Int64
+	semaphoreWaitDuration       atomic.Int64
+	walQueueWaitDuration        atomic.Int64
+	memTableWriteStallDuration  atomic.Int64
+	l0ReadAmpWriteStallDuration atomic.Int64
+	writeControllerWaitDuration atomic.Int64
+	priorityWaitDuration        atomic.Int64
+	walRotationDuration         atomic.Int64
+	commitWaitDuration          atomic.Int64
+}
+
+// addBatch records a committed batch of the provided size.
+func (c *commitPipelineCounters) addBatch(size int64, sync bool, s *BatchCommitStats) {
+	c.count.Add(1)
+	c.batchSize.AddSample(size)
+	if sync {
+		c.syncBatchSize.AddSample(size)
+	}
+	addDuration(&c.totalDuration, s.TotalDuration)
+	addDuration(&c.semaphoreWaitDuration, s.SemaphoreWaitDuration)
+	addDuration(&c.walQueueWaitDuration, s.WALQueueWaitDuration)
+	addDuration(&c.memTableWriteStallDuration, s.MemTableWriteStallDuration)
+	addDuration(&c.l0ReadAmpWriteStallDuration, s.L0ReadAmpWriteStallDuration)
+	addDuration(&c.writeControllerWaitDuration, s.WriteControllerWaitDuration)
+	addDuration(&c.priorityWaitDuration, s.PriorityWaitDuration)
+	addDuration(&c.walRotationDuration, s.WALRotationDuration)
+	addDuration(&c.commitWaitDuration, s.CommitWaitDuration)
+}
+
+// addSyncWait records the time a batch committed through
+// DB.ApplyNoSyncWait waited in Batch.SyncWait.
+func (c *commitPipelineCounters) addSyncWait(d time.Duration) {
+	addDuration(&c.commitWaitDuration, d)
+	addDuration(&c.totalDuration, d)
+}
+
+func addDuration(v *atomic.Int64, d time.Duration) {
+	if d != 0 {
+		v.Add(int64(d))
+	}
+}
+
+// load returns the metrics accumulated so far.
+func (c *commitPipelineCounters) load() CommitPipelineMetrics {
+	return CommitPipelineMetrics{
+		Count:         c.count.Load(),
+		BatchSize:     c.batchSize.Load(),
+		SyncBatchSize: c.syncBatchSize.Load(),
+		BatchCommitStats: BatchCommitStats{
+			TotalDuration:               time.Duration(c.totalDuration.Load()),
+			SemaphoreWaitDuration:       time.Duration(c.semaphoreWaitDuration.Load()),
+			WALQueueWaitDuration:        time.Duration(c.walQueueWaitDuration.Load()),
+			MemTableWriteStallDuration:  time.Duration(c.memTableWriteStallDuration.Load()),
+			L0ReadAmpWriteStallDuration: time.Duration(c.l0ReadAmpWriteStallDuration.Load()),
+			WriteControllerWaitDuration: time.Duration(c.writeControllerWaitDuration.Load()),
+			PriorityWaitDuration:        time.Duration(c.priorityWaitDuration.Load()),
+			WALRotationDuration:         time.Duration(c.walRotationDuration.Load()),
+			CommitWaitDuration:          time.Duration(c.commitWaitDuration.Load()),
+		},
+	}
+}
+
 // LevelMetrics holds per-level metrics such as the number of files and total
 // size of the files, and compaction related metrics.
 type LevelMetrics struct {
@@ -112,6 +216,11 @@ type LevelMetrics struct {
 		// LevelMetrics.format, but are available to sophisticated clients.
 		BytesWrittenDataBlocks  uint64
 		BytesWrittenValueBlocks uint64
+		// SampledReads is the cumulative number of sampled reads of sstables
+		// in this level, recorded by iterator read sampling and by a fraction
+		// of Gets. Not printed by LevelMetrics.format. Per-table access
+		// statistics are available through DB.SSTables.
+		SampledReads uint64
 	}
 }
 
@@ -137,6 +246,7 @@ func (m *LevelMetrics) Add(u *LevelMetrics) {
 	m.Additional.BytesWrittenDataBlocks += u.Additional.BytesWrittenDataBlocks
 	m.Additional.BytesWrittenValueBlocks += u.Additional.BytesWrittenValueBlocks
 	m.Additional.ValueBlocksSize += u.Additional.ValueBlocksSize
+	m.Additional.SampledReads += u.Additional.SampledReads
 }
 
 // WriteAmp computes the write amplification for compactions at this
@@ -152,6 +262,44 @@ var categoryCompaction = block.RegisterCategory("pebble-compaction", block.NonLa
 var categoryIngest = block.RegisterCategory("pebble-ingest", block.LatencySensitiveQoSLevel)
 var categoryGet = block.RegisterCategory("pebble-get", block.LatencySensitiveQoSLevel)
 
+// CompactionLevels identifies the start and output levels of compactions.
+// A level is -1 if not applicable (e.g. both levels of delete-only
+// compactions).
+type CompactionLevels struct {
+	StartLevel  int
+	OutputLevel int
+}
+
+// CompactionUsage describes the resources used by compactions.
+type CompactionUsage struct {
+	// Count is the number of compactions.
+	Count int64
+	// CPUTime is the CPU time consumed by the goroutines running the
+	// compactions (including the subcompactions). It is only measured on
+	// Linux, and is zero elsewhere. To measure it, each compaction goroutine is
+	// locked to its OS thread while it runs.
+	CPUTime time.Duration
+	// BytesReadLocal, BytesReadRemote and BytesReadCache are the bytes of the
+	// input blocks read from local storage, read from remote storage, and
+	// served from the block cache.
+	BytesReadLocal  uint64
+	BytesReadRemote uint64
+	BytesReadCache  uint64
+	// BytesWritten is the number of bytes of the tables and blob files
+	// written.
+	BytesWritten uint64
+}
+
+// Add adds the usage of other compactions.
+func (u *CompactionUsage) Add(o CompactionUsage) {
+	u.Count += o.Count
+	u.CPUTime += o.CPUTime
+	u.BytesReadLocal += o.BytesReadLocal
+	u.BytesReadRemote += o.BytesReadRemote
+	u.BytesReadCache += o.BytesReadCache
+	u.BytesWritten += o.BytesWritten
+}
+
 // Metrics holds metrics for various subsystems of the DB such as the Cache,
 // Compactions, WAL, and per-Level metrics.
 //
@@ -172,6 +320,8 @@ type Metrics struct {
 		ReadCount             int64
 		TombstoneDensityCount int64
 		RewriteCount          int64
+		TieringCount          int64
+		SuggestedCount        int64
 		MultiLevelCount       int64
 		CounterLevelCount     int64
 		// An estimate of the number of bytes that need to be compacted for the LSM
@@ -183,6 +333,11 @@ type Metrics struct {
 		InProgressBytes int64
 		// Number of compactions that are in-progress.
 		NumInProgress int64
+		// MaxConcurrency is the current upper bound on the number of concurrent
+		// compactions, after autotuning (see
+		// Options.Experimental.CompactionConcurrencyAutotuning) and
+		// DB.SetOptions.
+		MaxConcurrency int
 		// Number of compactions that were cancelled.
 		CancelledCount int64
 		// CancelledBytes the number of bytes written by compactions that were
@@ -192,9 +347,29 @@ type Metrics struct {
 		// compaction. Such files are compacted in a rewrite compaction
 		// when no other compactions are picked.
 		MarkedFiles int
+		// DeletionHints is the number of pending deletion hints: key ranges
+		// deleted by range deletions or range key deletions that may contain
+		// entire tables, which delete-only compactions can drop.
+		DeletionHints int
+		// DeleteOnlyEligibleFiles and DeleteOnlyEligibleSize are the count and
+		// total size of the tables that a delete-only compaction could drop
+		// now, per the pending deletion hints. See DB.CompactDeletedRanges.
+		DeleteOnlyEligibleFiles int64
+		DeleteOnlyEligibleSize  uint64
 		// Duration records the cumulative duration of all compactions since the
 		// database was opened.
 		Duration time.Duration
+		// Paused is true if automatic compactions are paused through
+		// DB.DisableAutomaticCompactions, or during a bulk load (see
+		// DB.BeginBulkLoad).
+		Paused bool
+		// UsageByLevels and UsageByReason aggregate the resources used by the
+		// completed compactions (including the failed and cancelled ones), by
+		// the compactions' start and output levels and by the compactions'
+		// reasons (e.g. "default" for the compactions picked by score,
+		// "elision-only", "move" or "read"). Flushes are not included.
+		UsageByLevels map[CompactionLevels]CompactionUsage
+		UsageByReason map[string]CompactionUsage
 	}
 
 	Ingest struct {
@@ -218,12 +393,19 @@ type Metrics struct {
 		// AsIngestBytes is a monotonically increasing counter of the bytes flushed
 		// for flushables that originated as ingestion operations.
 		AsIngestBytes uint64
+		// ByReason partitions the successful flushes by the reason they were
+		// initiated, indexed by FlushReason.
+		ByReason [NumFlushReasons]FlushReasonMetrics
 	}
 
 	Filter FilterMetrics
 
 	Levels [numLevels]LevelMetrics
 
+	// Windowed contains the write and read amplification over a recent window
+	// of time.
+	Windowed WindowedAmpMetrics
+
 	MemTable struct {
 		// The number of bytes allocated by memtables and large (flushable)
 		// batches.
@@ -305,11 +487,47 @@ type Metrics struct {
 
 	FileCache CacheMetrics
 
+	TableStats struct {
+		// PendingCount is the number of new tables whose stats have yet to be
+		// loaded.
+		PendingCount int
+		// BacklogCount is the number of tables that existed at Open whose stats
+		// have yet to be loaded, as of the last scan of the LSM for such tables.
+		// It's only meaningful while InitialLoadCompleted is false.
+		BacklogCount int
+		// LoadedCount is the cumulative count of the tables whose stats were
+		// loaded.
+		LoadedCount int64
+		// InitialLoadCompleted is true once the stats of all the tables that
+		// existed at Open have been loaded.
+		InitialLoadCompleted bool
+	}
+
 	// Count of the number of open sstable iterators.
 	TableIters int64
+
+	Iterators struct {
+		// The number of open iterators reading the DB state. See
+		// Options.MaxOpenIterators.
+		Open int64
+		// The number of stale iterators: iterators that have been open for
+		// longer than Options.Experimental.IteratorLeakThreshold and whose
+		// view of the DB is no longer current. Stale iterators are only tracked
+		// if the threshold is set.
+		Stale int64
+		// The size of the memtables that have been flushed but are still pinned
+		// by stale iterators.
+		StalePinnedMemTableBytes uint64
+		// The size of the zombie sstables pinned by stale iterators.
+		StalePinnedTableBytes uint64
+	}
 	// Uptime is the total time since this DB was opened.
 	Uptime time.Duration
 
+	// UserProperties holds the aggregates computed by each of
+	// Options.UserPropertyAggregators, keyed by the aggregator's name.
+	UserProperties map[string]UserPropertyAggregate
+
 	WAL struct {
 		// Number of live WAL files.
 		Files int64
@@ -334,15 +552,44 @@ type Metrics struct {
 		Failover wal.FailoverStats
 	}
 
+	TempFiles struct {
+		// Number of live temporary files created by subsystems that spill data
+		// to disk.
+		Count int64
+		// Size of the live temporary files.
+		Size uint64
+	}
+
 	LogWriter struct {
 		FsyncLatency prometheus.Histogram
 		record.LogWriterMetrics
 	}
 
+	CommitPipeline CommitPipelineMetrics
+
 	CategoryStats []block.CategoryStatsAggregate
 
 	SecondaryCacheMetrics SecondaryCacheMetrics
 
+	// ScanCache contains the metrics of the scan cache. It's empty unless
+	// Options.Experimental.ScanCache is configured.
+	ScanCache ScanCacheMetrics
+
+	// ExternalValues contains the metrics of the reads of values stored in an
+	// external value store. It's empty unless
+	// Options.Experimental.ExternalValues is configured.
+	ExternalValues ExternalValueMetrics
+
+	// RemoteStorageBreakers is the state of the circuit breakers around reads
+	// from remote storage, per locator read from. It's empty unless
+	// Options.Experimental.RemoteReadCircuitBreaker is configured.
+	RemoteStorageBreakers []RemoteStorageBreakerState
+
+	// IO contains the histograms of the I/O operations performed on the DB's
+	// files, per file type. It's only set if Options.FS is (or wraps) a
+	// vfs.InstrumentedFS.
+	IO *vfs.IOMetrics
+
 	private struct {
 		optionsFileSize  uint64
 		manifestFileSize uint64
@@ -581,9 +828,8 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 		humanize.Bytes.Uint64(m.WAL.BytesIn),
 		humanize.Bytes.Uint64(m.WAL.BytesWritten),
 		redact.Safe(percent(int64(m.WAL.BytesWritten)-int64(m.WAL.BytesIn), int64(m.WAL.BytesIn))))
-	failoverStats := m.WAL.Failover
-	failoverStats.FailoverWriteAndSyncLatency = nil
-	if failoverStats == (wal.FailoverStats{}) {
+	if f := &m.WAL.Failover; f.DirSwitchCount == 0 && f.PrimaryWriteDuration == 0 &&
+		f.SecondaryWriteDuration == 0 && f.ActiveDirIndex == 0 && !f.Forced {
 		w.Printf("\n")
 	} else {
 		w.Printf(" failover: (switches: %d, primary: %s, secondary: %s)\n", m.WAL.Failover.DirSwitchCount,
@@ -668,6 +914,11 @@ func (m *Metrics) SafeFormat(w redact.SafePrinter, _ rune) {
 		redact.Safe(m.Snapshots.EarliestSeqNum))
 
 	w.Printf("Table iters: %d\n", redact.Safe(m.TableIters))
+	if m.TempFiles.Count > 0 {
+		w.Printf("Temp files: %d (%s)\n",
+			redact.Safe(m.TempFiles.Count),
+			humanize.Bytes.Uint64(m.TempFiles.Size))
+	}
 	w.Printf("Filter utility: %.1f%%\n", redact.Safe(hitRate(m.Filter.Hits, m.Filter.Misses)))
 	w.Printf("Ingestions: %d  as flushable: %d (%s in %d tables)\n",
 		redact.Safe(m.Ingest.Count),
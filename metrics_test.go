@@ -6,6 +6,7 @@ package pebble
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"math/rand/v2"
 	"runtime"
@@ -20,11 +21,14 @@ import (
 	"github.com/cockroachdb/pebble/internal/humanize"
 	"github.com/cockroachdb/pebble/internal/manual"
 	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
 	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/vfs/errorfs"
 	"github.com/cockroachdb/redact"
+	io_prometheus_client "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -448,6 +452,21 @@ func TestMetricsWAmpDisableWAL(t *testing.T) {
 	require.NoError(t, d.Close())
 }
 
+func TestMetricsCommitPipeline(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, d.Set([]byte(strconv.Itoa(i)), []byte("v"), &WriteOptions{Sync: i%2 == 0}))
+	}
+	m := d.Metrics().CommitPipeline
+	require.EqualValues(t, 10, m.Count)
+	require.EqualValues(t, 10, m.BatchSize.Count())
+	require.EqualValues(t, 5, m.SyncBatchSize.Count())
+	require.Greater(t, m.TotalDuration, time.Duration(0))
+}
+
 // TestMetricsWALBytesWrittenMonotonicity tests that the
 // Metrics.WAL.BytesWritten metric is always nondecreasing.
 // It's a regression test for issue #3505.
@@ -511,3 +530,172 @@ func TestMetricsWALBytesWrittenMonotonicity(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestAmpWindow(t *testing.T) {
+	var w ampWindow
+	var m Metrics
+	var walBytes uint64
+	now := time.Unix(0, 0)
+	require.Zero(t, w.metrics())
+
+	// Writes at a steady rate, with each flush compacted once into L6, and a
+	// read amp of 3 (two sublevels and L6).
+	step := func(minutes int, compacted bool) {
+		for i := 0; i < minutes; i++ {
+			now = now.Add(time.Minute)
+			walBytes += 100
+			m.Levels[0].BytesIn += 100
+			m.Levels[0].BytesFlushed += 100
+			w.recordCompaction(compactionKindFlush, 100)
+			if compacted {
+				m.Levels[6].BytesIn += 100
+				m.Levels[6].BytesCompacted += 100
+				w.recordCompaction(compactionKindDefault, 100)
+			}
+			w.update(now, &m, walBytes)
+		}
+	}
+	m.Levels[0].Sublevels = 2
+	m.Levels[6].Sublevels = 1
+	w.update(now, &m, walBytes)
+	step(20, true)
+
+	wm := w.metrics()
+	require.Equal(t, ampWindowBuckets*ampWindowBucketDuration, wm.Window)
+	require.EqualValues(t, 1000, wm.BytesIn)
+	require.InDelta(t, 3.0, wm.WriteAmp, 1e-9)
+	require.InDelta(t, 3.0, wm.ReadAmp, 1e-9)
+	require.InDelta(t, 1.0, wm.Levels[0].WriteAmp, 1e-9)
+	require.InDelta(t, 1.0, wm.Levels[6].WriteAmp, 1e-9)
+	require.Equal(t, map[string]float64{"flush": 1, "default": 1}, wm.WriteAmpByKind)
+
+	// Compactions stop and the read amp rises. The windowed metrics reflect the
+	// new behavior once the window has passed, unlike the cumulative ones.
+	m.Levels[0].Sublevels = 5
+	w.update(now, &m, walBytes)
+	step(10, false)
+	wm = w.metrics()
+	require.InDelta(t, 2.0, wm.WriteAmp, 1e-9)
+	require.InDelta(t, 6.0, wm.ReadAmp, 1e-9)
+	require.Equal(t, map[string]float64{"flush": 1}, wm.WriteAmpByKind)
+
+	// After a long idle period, the window only covers the last snapshot
+	// before the window's start.
+	now = now.Add(time.Hour)
+	w.update(now, &m, walBytes)
+	wm = w.metrics()
+	require.Zero(t, wm.BytesIn)
+	require.Zero(t, wm.WriteAmp)
+	require.InDelta(t, 6.0, wm.ReadAmp, 1e-9)
+}
+
+func TestMetricsWindowed(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 100; j++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", j)), []byte("v"), nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact([]byte("k"), []byte("l"), false /* parallelize */))
+
+	m := d.Metrics()
+	require.NotZero(t, m.Windowed.BytesIn)
+	require.Greater(t, m.Windowed.WriteAmp, 1.0)
+	require.Contains(t, m.Windowed.WriteAmpByKind, "flush")
+	require.Contains(t, m.Windowed.WriteAmpByKind, "default")
+}
+
+func TestMetricsIO(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	require.Nil(t, d.Metrics().IO)
+	require.NoError(t, d.Close())
+
+	fs := vfs.WithInstrumentation(vfs.NewMem())
+	// The instrumented FS is found even if it's wrapped.
+	healthFS, closer := vfs.WithDiskHealthChecks(fs, time.Minute, nil, func(vfs.DiskSlowInfo) {})
+	defer closer.Close()
+	d, err = Open("", &Options{FS: healthFS})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.Set([]byte("a"), []byte("b"), Sync))
+	require.NoError(t, d.Flush())
+
+	m := d.Metrics()
+	require.NotNil(t, m.IO)
+	for _, typ := range []vfs.IOFileType{vfs.IOFileTypeWAL, vfs.IOFileTypeTable, vfs.IOFileTypeManifest} {
+		var metric io_prometheus_client.Metric
+		require.NoError(t, m.IO[typ].WriteLatency.Write(&metric))
+		require.NotZero(t, metric.Histogram.GetSampleCount(), typ)
+	}
+}
+
+func TestMetricsFlushReasons(t *testing.T) {
+	mem := vfs.NewMem()
+	var reasons []string
+	opts := &Options{
+		FS:                 mem,
+		FormatMajorVersion: FormatNewest,
+		MemTableSize:       1 << 20,
+		EventListener: &EventListener{
+			FlushEnd: func(info FlushInfo) { reasons = append(reasons, info.Reason) },
+		},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	byReason := func() [NumFlushReasons]FlushReasonMetrics {
+		return d.Metrics().Flush.ByReason
+	}
+
+	value := bytes.Repeat([]byte("v"), 1<<10)
+	for i := 0; i < 4<<10; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("k%05d", i)), value, nil))
+	}
+	require.Eventually(t, func() bool {
+		return byReason()[FlushReasonMemTableFull].Count > 0
+	}, 10*time.Second, time.Millisecond)
+	require.NoError(t, d.Flush())
+	m := byReason()
+	require.EqualValues(t, 1, m[FlushReasonManual].Count)
+	require.Greater(t, m[FlushReasonManual].Bytes, uint64(0))
+	require.Greater(t, m[FlushReasonMemTableFull].Bytes, uint64(0))
+	require.Greater(t, m[FlushReasonMemTableFull].Duration, time.Duration(0))
+
+	// An ingestion overlapping the memtable is ingested as a flushable.
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), d.opts.MakeWriterOptions(0, d.TableFormat()))
+	require.NoError(t, w.Set([]byte("a"), []byte("2")))
+	require.NoError(t, w.Close())
+	require.NoError(t, d.Ingest(context.Background(), []string{"ext"}))
+	require.Eventually(t, func() bool {
+		return byReason()[FlushReasonIngestAsFlushable].Count == 1
+	}, 10*time.Second, time.Millisecond)
+
+	// An eventually file-only snapshot forces a flush of the memtables it
+	// overlaps.
+	require.NoError(t, d.Set([]byte("b"), []byte("1"), nil))
+	es := d.NewEventuallyFileOnlySnapshot([]KeyRange{{Start: []byte("a"), End: []byte("c")}})
+	require.NoError(t, es.WaitForFileOnlySnapshot(context.Background(), time.Millisecond))
+	require.NoError(t, es.Close())
+	require.EqualValues(t, 1, byReason()[FlushReasonFileOnlySnapshot].Count)
+	require.Contains(t, reasons, "memtable-full")
+	require.Contains(t, reasons, "ingest-as-flushable")
+	require.Contains(t, reasons, "file-only-snapshot")
+
+	// The memtables replayed from the WAL are flushed when reopening the DB.
+	require.NoError(t, d.Set([]byte("c"), []byte("1"), nil))
+	require.NoError(t, d.Close())
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.Contains(t, reasons, "wal-replay")
+}
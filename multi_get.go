@@ -0,0 +1,156 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// MultiGetOptions configures Snapshot.MultiGet.
+type MultiGetOptions struct {
+	// Concurrency is the maximum number of goroutines looking up keys in
+	// parallel. If zero, it defaults to GOMAXPROCS.
+	Concurrency int
+}
+
+// MultiGetResult is the result of looking up a single key with
+// Snapshot.MultiGet.
+type MultiGetResult struct {
+	// Value is the value of the key. It remains valid until the Closer returned
+	// by MultiGet is closed.
+	Value []byte
+	// Err is ErrNotFound if the key was not found, or the error encountered
+	// while looking up the key.
+	Err error
+}
+
+// MultiGet looks up the given keys in the Snapshot, returning one result per
+// key in the order of keys. All keys are read from the same pinned view of the
+// LSM. The keys are sorted and grouped by the sstable of the bottommost
+// non-empty level that contains them, which holds most of the data. The groups
+// are looked up in parallel, and the keys of a group are read by the same
+// goroutine in key order, so that the blocks of an sstable are read once and
+// shared by the lookups of its keys.
+//
+// The caller should not modify the contents of the returned values, but it is
+// safe to modify the contents of the keys after MultiGet returns. The returned
+// values remain valid until the returned Closer is closed, which the caller
+// MUST do or a memory leak will occur.
+func (s *Snapshot) MultiGet(keys [][]byte, opts *MultiGetOptions) ([]MultiGetResult, io.Closer) {
+	if s.db == nil {
+		panic(ErrClosed)
+	}
+	var concurrency int
+	if opts != nil {
+		concurrency = opts.Concurrency
+	}
+	return s.db.multiGet(context.Background(), keys, s.seqNum, concurrency)
+}
+
+func (d *DB) multiGet(
+	ctx context.Context, keys [][]byte, seqNum base.SeqNum, concurrency int,
+) ([]MultiGetResult, io.Closer) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	results := make([]MultiGetResult, len(keys))
+	closers := make(multiGetCloser, len(keys))
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return d.cmp(keys[a], keys[b])
+	})
+
+	// Every lookup takes its own reference on the readState, which is
+	// released when the lookup's iterator is closed.
+	readState := d.loadReadState()
+	defer readState.unref()
+	lookup := func(order []int) {
+		for _, i := range order {
+			readState.ref()
+			results[i].Value, closers[i], results[i].Err = d.getWithReadState(
+				ctx, keys[i], nil /* batch */, seqNum, readState)
+		}
+	}
+
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	concurrency = min(concurrency, len(keys))
+	if concurrency <= 1 {
+		lookup(order)
+		return results, closers
+	}
+	var wg sync.WaitGroup
+	for _, order := range multiGetPartition(d.cmp, readState.current, keys, order, concurrency) {
+		wg.Add(1)
+		go func(order []int) {
+			defer wg.Done()
+			lookup(order)
+		}(order)
+	}
+	wg.Wait()
+	return results, closers
+}
+
+// multiGetPartition divides the sorted keys into at most concurrency
+// contiguous partitions of roughly equal size. Keys that fall into the same
+// sstable of the bottommost non-empty level of v are kept in the same
+// partition, so a partition may be larger than its share if an sstable holds
+// many of the keys.
+func multiGetPartition(
+	cmp base.Compare, v *manifest.Version, keys [][]byte, order []int, concurrency int,
+) [][]int {
+	// Find the table of each key, or nil if the key falls between tables.
+	tables := make([]*manifest.TableMetadata, len(order))
+	for level := numLevels - 1; level > 0; level-- {
+		if v.Levels[level].Empty() {
+			continue
+		}
+		iter := v.Levels[level].Iter()
+		for j, i := range order {
+			if f := iter.SeekGE(cmp, keys[i]); f != nil && cmp(f.Smallest.UserKey, keys[i]) <= 0 {
+				tables[j] = f
+			}
+		}
+		break
+	}
+
+	target := (len(order) + concurrency - 1) / concurrency
+	var partitions [][]int
+	start := 0
+	for j := 1; j <= len(order); j++ {
+		if j < len(order) && (j-start < target || (tables[j] != nil && tables[j] == tables[j-1])) {
+			continue
+		}
+		partitions = append(partitions, order[start:j])
+		start = j
+	}
+	return partitions
+}
+
+// multiGetCloser closes the iterators backing the values returned by a
+// MultiGet.
+type multiGetCloser []io.Closer
+
+// Close implements io.Closer.
+func (c multiGetCloser) Close() error {
+	var err error
+	for _, closer := range c {
+		if closer != nil {
+			err = firstError(err, closer.Close())
+		}
+	}
+	return err
+}
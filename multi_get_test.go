@@ -0,0 +1,102 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotMultiGet(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := func(i int) []byte { return []byte(fmt.Sprintf("k%04d", i)) }
+	for i := 0; i < 1000; i += 2 {
+		require.NoError(t, d.Set(key(i), key(i), nil))
+		if i%200 == 0 {
+			require.NoError(t, d.Flush())
+		}
+	}
+	snap := d.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+	// Writes after the snapshot are not visible.
+	require.NoError(t, d.Set(key(1), key(1), nil))
+	require.NoError(t, d.Delete(key(2), nil))
+
+	var keys [][]byte
+	for i := 999; i >= 0; i -= 3 {
+		keys = append(keys, key(i))
+	}
+	for _, concurrency := range []int{0, 1, 4, 1000} {
+		results, closer := snap.MultiGet(keys, &MultiGetOptions{Concurrency: concurrency})
+		require.Len(t, results, len(keys))
+		for i, r := range results {
+			var n int
+			_, err := fmt.Sscanf(string(keys[i]), "k%04d", &n)
+			require.NoError(t, err)
+			if n%2 == 0 {
+				require.NoError(t, r.Err)
+				require.Equal(t, keys[i], r.Value)
+			} else {
+				require.ErrorIs(t, r.Err, ErrNotFound)
+			}
+		}
+		require.NoError(t, closer.Close())
+	}
+}
+
+func TestMultiGetPartition(t *testing.T) {
+	opts := &Options{FS: vfs.NewMem()}
+	opts.EnsureDefaults()
+	for i := range opts.Levels {
+		opts.Levels[i].TargetFileSize = 1 << 10
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	key := func(i int) []byte { return []byte(fmt.Sprintf("k%04d", i)) }
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, d.Set(key(i), bytes.Repeat([]byte("v"), 100), nil))
+	}
+	require.NoError(t, d.Compact(key(0), key(1000), false /* parallelize */))
+
+	var keys [][]byte
+	var order []int
+	for i := 0; i < 1000; i += 7 {
+		order = append(order, len(keys))
+		keys = append(keys, key(i))
+	}
+	readState := d.loadReadState()
+	defer readState.unref()
+	v := readState.current
+	require.Greater(t, v.Levels[numLevels-1].Len(), 4)
+
+	tableOf := func(k []byte) *manifest.TableMetadata {
+		iter := v.Levels[numLevels-1].Iter()
+		return iter.SeekGE(d.cmp, k)
+	}
+	for _, concurrency := range []int{1, 3, 8, len(keys)} {
+		partitions := multiGetPartition(d.cmp, v, keys, order, concurrency)
+		require.LessOrEqual(t, len(partitions), concurrency)
+		var n int
+		for i, p := range partitions {
+			n += len(p)
+			// No table is split across partitions.
+			if i > 0 {
+				prev := partitions[i-1]
+				require.NotEqual(t, tableOf(keys[prev[len(prev)-1]]), tableOf(keys[p[0]]))
+			}
+		}
+		require.Equal(t, len(keys), n)
+	}
+}
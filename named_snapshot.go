@@ -0,0 +1,219 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+)
+
+// ErrNamedSnapshotNotFound is returned by OpenNamedSnapshot and
+// ReleaseNamedSnapshot when there is no named snapshot with the given name.
+var ErrNamedSnapshotNotFound = errors.New("pebble: named snapshot not found")
+
+// NewNamedSnapshot returns a snapshot of the current DB state, like
+// NewSnapshot, and records it in the MANIFEST under the given name. Unlike the
+// returned Snapshot, which must be closed like any other, the named snapshot
+// survives the close of the DB: the DB retains the state as of the snapshot
+// until ReleaseNamedSnapshot is called, including across restarts, and
+// OpenNamedSnapshot re-acquires it. This allows long-running read jobs to
+// resume after a restart without a checkpoint of the DB.
+//
+// The writes visible in the snapshot are made durable before it's recorded.
+// An error is returned if a named snapshot with the same name exists, or if
+// the DB's format major version is below FormatNamedSnapshots.
+func (d *DB) NewNamedSnapshot(name string) (*Snapshot, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	if v := d.FormatMajorVersion(); v < FormatNamedSnapshots {
+		return nil, errors.Newf(
+			"pebble: named snapshots require at least format major version %d (current: %d)",
+			FormatNamedSnapshots, v,
+		)
+	}
+	if d.opts.Preset == CachePreset {
+		return nil, errors.New("pebble: snapshots are not supported with CachePreset")
+	}
+
+	// Retain the state as of the snapshot before making it durable, so that
+	// compactions can't drop it in the meantime.
+	d.mu.Lock()
+	named := &Snapshot{db: d, seqNum: d.mu.versions.visibleSeqNum.Load(), named: true}
+	d.trackSnapshot(named)
+	d.mu.snapshots.pushBack(named)
+	d.mu.Unlock()
+
+	// The writes visible in the snapshot must survive a crash once the snapshot
+	// is recorded: sync the WAL, or flush the memtables if the WAL is disabled.
+	var err error
+	if d.opts.DisableWAL {
+		err = d.Flush()
+	} else {
+		err = d.LogData(nil, Sync)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil {
+		err = d.logNamedSnapshotLocked(name, named)
+	}
+	if err != nil {
+		_ = named.closeLocked()
+		return nil, err
+	}
+	s := &Snapshot{db: d, seqNum: named.seqNum}
+	d.trackSnapshot(s)
+	d.mu.snapshots.insert(s)
+	return s, nil
+}
+
+// logNamedSnapshotLocked records the named snapshot in the MANIFEST.
+//
+// d.mu must be held when calling this.
+func (d *DB) logNamedSnapshotLocked(name string, named *Snapshot) error {
+	jobID := d.newJobIDLocked()
+	d.mu.versions.logLock()
+	if _, ok := d.mu.versions.namedSnapshots[name]; ok {
+		d.mu.versions.logUnlock()
+		return errors.Errorf("pebble: named snapshot %q already exists", name)
+	}
+	ve := &manifest.VersionEdit{
+		NamedSnapshots: []manifest.NamedSnapshot{{Name: name, SeqNum: named.seqNum}},
+	}
+	if err := d.mu.versions.logAndApply(jobID, ve, map[int]*LevelMetrics{}, false, /* forceRotation */
+		func() []compactionInfo { return d.getInProgressCompactionInfoLocked(nil) }); err != nil {
+		return err
+	}
+	if d.mu.namedSnapshots == nil {
+		d.mu.namedSnapshots = make(map[string]*Snapshot)
+	}
+	d.mu.namedSnapshots[name] = named
+	return nil
+}
+
+// OpenNamedSnapshot returns a snapshot of the DB as of the named snapshot
+// created by NewNamedSnapshot, which may have been created before the DB was
+// reopened. The returned Snapshot must be closed; it retains the state even if
+// the named snapshot is released. ErrNamedSnapshotNotFound is returned if
+// there is no named snapshot with the given name.
+func (d *DB) OpenNamedSnapshot(name string) (*Snapshot, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	named, ok := d.mu.namedSnapshots[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrNamedSnapshotNotFound, "%q", name)
+	}
+	s := &Snapshot{db: d, seqNum: named.seqNum}
+	d.trackSnapshot(s)
+	d.mu.snapshots.insert(s)
+	return s, nil
+}
+
+// ReleaseNamedSnapshot removes the named snapshot from the MANIFEST, allowing
+// compactions to drop the state it retained (unless it's retained by open
+// Snapshots). ErrNamedSnapshotNotFound is returned if there is no named
+// snapshot with the given name.
+func (d *DB) ReleaseNamedSnapshot(name string) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	jobID := d.newJobIDLocked()
+	d.mu.versions.logLock()
+	if _, ok := d.mu.versions.namedSnapshots[name]; !ok {
+		d.mu.versions.logUnlock()
+		return errors.Wrapf(ErrNamedSnapshotNotFound, "%q", name)
+	}
+	ve := &manifest.VersionEdit{DeletedNamedSnapshots: []string{name}}
+	if err := d.mu.versions.logAndApply(jobID, ve, map[int]*LevelMetrics{}, false, /* forceRotation */
+		func() []compactionInfo { return d.getInProgressCompactionInfoLocked(nil) }); err != nil {
+		return err
+	}
+	named := d.mu.namedSnapshots[name]
+	delete(d.mu.namedSnapshots, name)
+	return named.closeLocked()
+}
+
+// NamedSnapshots returns the names of the named snapshots, in sorted order.
+func (d *DB) NamedSnapshots() []string {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.mu.namedSnapshots))
+	for name := range d.mu.namedSnapshots {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// openNamedSnapshotsLocked retains the state as of the named snapshots recorded
+// in the MANIFEST. It's called when the DB is opened, before the WAL is
+// replayed, so that the flushes of the replayed memtables retain the state.
+//
+// d.mu must be held when calling this.
+func (d *DB) openNamedSnapshotsLocked() {
+	for name, seqNum := range d.mu.versions.namedSnapshots {
+		named := &Snapshot{db: d, seqNum: seqNum, named: true}
+		d.trackSnapshot(named)
+		d.mu.snapshots.insert(named)
+		if d.mu.namedSnapshots == nil {
+			d.mu.namedSnapshots = make(map[string]*Snapshot)
+		}
+		d.mu.namedSnapshots[name] = named
+	}
+}
+
+// closeNamedSnapshotsLocked releases the state retained by the named snapshots
+// when the DB is closed. The named snapshots remain recorded in the MANIFEST.
+//
+// d.mu must be held when calling this.
+func (d *DB) closeNamedSnapshotsLocked() {
+	for name, named := range d.mu.namedSnapshots {
+		// closeLocked only fails if the DB is closed.
+		_ = named.closeLocked()
+		delete(d.mu.namedSnapshots, name)
+	}
+}
+
+// applyNamedSnapshots applies the named snapshots created and released by the
+// version edit.
+func (vs *versionSet) applyNamedSnapshots(ve *manifest.VersionEdit) {
+	for _, name := range ve.DeletedNamedSnapshots {
+		delete(vs.namedSnapshots, name)
+	}
+	for _, s := range ve.NamedSnapshots {
+		if vs.namedSnapshots == nil {
+			vs.namedSnapshots = make(map[string]base.SeqNum)
+		}
+		vs.namedSnapshots[s.Name] = s.SeqNum
+	}
+}
+
+// liveNamedSnapshots returns the named snapshots that haven't been released,
+// to be carried over to a new MANIFEST.
+func (vs *versionSet) liveNamedSnapshots() []manifest.NamedSnapshot {
+	var snapshots []manifest.NamedSnapshot
+	for name, seqNum := range vs.namedSnapshots {
+		snapshots = append(snapshots, manifest.NamedSnapshot{Name: name, SeqNum: seqNum})
+	}
+	return snapshots
+}
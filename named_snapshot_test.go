@@ -0,0 +1,85 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedSnapshots(t *testing.T) {
+	mem := vfs.NewMem()
+	open := func() *DB {
+		d, err := Open("", &Options{FS: mem, FormatMajorVersion: FormatNamedSnapshots})
+		require.NoError(t, err)
+		return d
+	}
+	get := func(r Reader, key string) string {
+		v, closer, err := r.Get([]byte(key))
+		if err == ErrNotFound {
+			return "<not found>"
+		}
+		require.NoError(t, err)
+		defer closer.Close()
+		return string(v)
+	}
+
+	d := open()
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	s, err := d.NewNamedSnapshot("backup")
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	_, err = d.NewNamedSnapshot("backup")
+	require.Error(t, err)
+	require.Equal(t, []string{"backup"}, d.NamedSnapshots())
+
+	// Overwrite the key and compact it. The named snapshot retains the old
+	// value although no Snapshot is open.
+	require.NoError(t, d.Set([]byte("a"), []byte("2"), nil))
+	require.NoError(t, d.Delete([]byte("b"), nil))
+	require.NoError(t, d.Compact([]byte("a"), []byte("c"), false /* parallelize */))
+	require.NoError(t, d.Close())
+
+	// The named snapshot survives a restart.
+	d = open()
+	require.Equal(t, []string{"backup"}, d.NamedSnapshots())
+	require.NoError(t, d.Compact([]byte("a"), []byte("c"), false /* parallelize */))
+	s, err = d.OpenNamedSnapshot("backup")
+	require.NoError(t, err)
+	require.Equal(t, "1", get(s, "a"))
+	require.Equal(t, "2", get(d, "a"))
+
+	// Releasing the named snapshot doesn't affect the open Snapshot.
+	require.NoError(t, d.ReleaseNamedSnapshot("backup"))
+	require.ErrorIs(t, d.ReleaseNamedSnapshot("backup"), ErrNamedSnapshotNotFound)
+	_, err = d.OpenNamedSnapshot("backup")
+	require.ErrorIs(t, err, ErrNamedSnapshotNotFound)
+	require.NoError(t, d.Compact([]byte("a"), []byte("c"), false /* parallelize */))
+	require.Equal(t, "1", get(s, "a"))
+	require.NoError(t, s.Close())
+	require.NoError(t, d.Close())
+
+	d = open()
+	require.Empty(t, d.NamedSnapshots())
+	require.NoError(t, d.Close())
+}
+
+func TestNamedSnapshotsFormatMajorVersion(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem(), FormatMajorVersion: FormatNamedSnapshots - 1})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	_, err = d.NewNamedSnapshot("backup")
+	require.Error(t, err)
+	require.Empty(t, d.NamedSnapshots())
+
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatNamedSnapshots))
+	s, err := d.NewNamedSnapshot("backup")
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	require.Equal(t, []string{"backup"}, d.NamedSnapshots())
+}
@@ -235,6 +235,12 @@ type CreateOptions struct {
 	// WriteCategory is used for the object when it is created on local storage
 	// to collect aggregated write metrics for each write source.
 	WriteCategory vfs.DiskWriteCategory
+
+	// ForCompaction is true if the object is written by a compaction (other
+	// than a flush). The provider may avoid caching the data of such objects in
+	// the OS page cache once written (see
+	// objstorageprovider.Settings.Local.DropCompactionWritesFromPageCache).
+	ForCompaction bool
 }
 
 // Provider is a singleton object used to access and manage objects.
@@ -340,9 +346,39 @@ type Provider interface {
 	// path should be able to resolve references to the specified files.
 	CheckpointState(fs vfs.FS, dir string, fileType base.FileType, fileNums []base.DiskFileNum) error
 
+	// SetBytesPerSync changes the BytesPerSync setting used for local objects
+	// created from now on. Objects that are already being written are not
+	// affected.
+	SetBytesPerSync(bytesPerSync int)
+
 	// Metrics returns metrics about objstorage. Currently, it only returns metrics
 	// about the shared cache.
 	Metrics() sharedcache.Metrics
+
+	// RemoteBreakers returns the state of the circuit breakers around reads
+	// from remote storage, one per locator that was read from. It's empty if
+	// the circuit breakers are disabled.
+	RemoteBreakers() []RemoteBreakerState
+}
+
+// ErrRemoteUnavailable is returned by reads from remote storage while the
+// circuit breaker of its locator is tripped, after repeated read failures.
+// Reads served by the secondary cache still succeed.
+var ErrRemoteUnavailable = errors.New("pebble: remote storage temporarily unavailable")
+
+// RemoteBreakerState is the state of the circuit breaker around reads from the
+// remote storage of a locator.
+type RemoteBreakerState struct {
+	Locator remote.Locator
+	// Tripped is set while reads fail fast with ErrRemoteUnavailable.
+	Tripped bool
+	// ConsecutiveFailures is the number of reads that failed since the last
+	// successful read.
+	ConsecutiveFailures int
+	// Trips is the number of times the breaker tripped.
+	Trips int64
+	// LastError is the error of the last failed read.
+	LastError error
 }
 
 // RemoteObjectBacking encodes the metadata necessary to incorporate a shared
@@ -29,6 +29,10 @@ import (
 type provider struct {
 	st Settings
 
+	// bytesPerSync is initialized from st.BytesPerSync and can be changed
+	// through SetBytesPerSync.
+	bytesPerSync atomic.Int64
+
 	fsDir vfs.File
 
 	tracer *objiotracing.Tracer
@@ -93,6 +97,10 @@ type Settings struct {
 	// out a large chunk of dirty filesystem buffers.
 	BytesPerSync int
 
+	// ReadaheadPolicy decides the readahead issued for sequential reads of
+	// both local and remote objects. If nil, DefaultReadaheadPolicy is used.
+	ReadaheadPolicy ReadaheadPolicy
+
 	// Local contains fields that are only relevant for files stored on the local
 	// filesystem.
 	Local struct {
@@ -101,6 +109,17 @@ type Settings struct {
 		// ReadaheadConfig is used to retrieve the current readahead mode; it is
 		// consulted whenever a read handle is initialized.
 		ReadaheadConfig *ReadaheadConfig
+
+		// DropCompactionReadsFromPageCache, if true, advises the OS that data
+		// read through read handles set up for compaction may be dropped from
+		// its page cache once read (see vfs.AdviseDontNeed).
+		DropCompactionReadsFromPageCache bool
+
+		// DropCompactionWritesFromPageCache, if true, advises the OS that the
+		// data of objects created with CreateOptions.ForCompaction may be
+		// dropped from its page cache once written and synced (see
+		// vfs.AdviseDontNeed).
+		DropCompactionWritesFromPageCache bool
 	}
 
 	// Fields here are set only if the provider is to support remote objects
@@ -135,6 +154,19 @@ type Settings struct {
 		// 2*runtime.GOMAXPROCS is used as the shard count.
 		CacheShardCount int
 
+		// CachePersistence configures whether the contents of the cache are
+		// reused across restarts.
+		CachePersistence sharedcache.PersistenceOptions
+
+		// CircuitBreaker configures the circuit breakers around reads from
+		// remote storage. They are disabled if CircuitBreaker.FailureThreshold
+		// is 0.
+		CircuitBreaker CircuitBreakerOptions
+
+		// OnCircuitBreakerChange, if set, is called when a circuit breaker trips
+		// or recovers.
+		OnCircuitBreakerChange func(objstorage.RemoteBreakerState)
+
 		// TODO(radu): allow the cache to live on another FS/location (e.g. to use
 		// instance-local SSD).
 	}
@@ -248,6 +280,7 @@ func open(settings Settings) (p *provider, _ error) {
 		st:    settings,
 		fsDir: fsDir,
 	}
+	p.bytesPerSync.Store(int64(settings.BytesPerSync))
 	p.mu.knownObjects = make(map[base.DiskFileNum]objstorage.ObjectMetadata)
 	p.mu.protectedObjects = make(map[base.DiskFileNum]int)
 
@@ -338,7 +371,8 @@ func (p *provider) Create(
 		} else {
 			category = vfs.WriteCategoryUnspecified
 		}
-		w, meta, err = p.vfsCreate(ctx, fileType, fileNum, category)
+		dropAfterSync := opts.ForCompaction && p.st.Local.DropCompactionWritesFromPageCache
+		w, meta, err = p.vfsCreate(ctx, fileType, fileNum, category, dropAfterSync)
 	}
 	if err != nil {
 		err = errors.Wrapf(err, "creating object %s", fileNum)
@@ -430,7 +464,7 @@ func (p *provider) LinkOrCopyFromLocal(
 		// vfs.NewSyncingFile.
 		fs := vfs.NewSyncingFS(p.st.FS, vfs.SyncingFileOptions{
 			NoSyncOnClose: p.st.NoSyncOnClose,
-			BytesPerSync:  p.st.BytesPerSync,
+			BytesPerSync:  int(p.bytesPerSync.Load()),
 		})
 		dstPath := p.vfsPath(dstFileType, dstFileNum)
 		if err := vfs.LinkOrCopy(fs, srcFilePath, dstPath); err != nil {
@@ -532,6 +566,11 @@ func (p *provider) List() []objstorage.ObjectMetadata {
 	return res
 }
 
+// SetBytesPerSync is part of the objstorage.Provider interface.
+func (p *provider) SetBytesPerSync(bytesPerSync int) {
+	p.bytesPerSync.Store(int64(bytesPerSync))
+}
+
 // Metrics is part of the objstorage.Provider interface.
 func (p *provider) Metrics() sharedcache.Metrics {
 	if p.remote.cache != nil {
@@ -721,3 +721,75 @@ func TestParallelSync(t *testing.T) {
 		})
 	}
 }
+
+func TestDropCompactionReadsFromPageCache(t *testing.T) {
+	dir := t.TempDir()
+	for _, drop := range []bool{false, true} {
+		t.Run(fmt.Sprintf("drop=%t", drop), func(t *testing.T) {
+			st := DefaultSettings(vfs.Default, dir)
+			st.Local.ReadaheadConfig = NewReadaheadConfig()
+			st.Local.DropCompactionReadsFromPageCache = drop
+			p, err := Open(st)
+			require.NoError(t, err)
+			defer p.Close()
+
+			ctx := context.Background()
+			w, _, err := p.Create(ctx, base.FileTypeTable, 1, objstorage.CreateOptions{})
+			require.NoError(t, err)
+			require.NoError(t, w.Write([]byte("hello world")))
+			require.NoError(t, w.Finish())
+
+			r, err := p.OpenForReading(ctx, base.FileTypeTable, 1, objstorage.OpenOptions{})
+			require.NoError(t, err)
+			defer r.Close()
+			for _, forCompaction := range []bool{false, true} {
+				rh := r.NewReadHandle(objstorage.NoReadBefore)
+				if forCompaction {
+					rh.SetupForCompaction()
+				}
+				// Only handles set up for compaction drop data from the page cache.
+				require.Equal(t, drop && forCompaction, rh.(*vfsReadHandle).dropAfterRead)
+				buf := make([]byte, 5)
+				require.NoError(t, rh.ReadAt(ctx, buf, 6))
+				require.Equal(t, "world", string(buf))
+				require.NoError(t, rh.Close())
+			}
+			require.NoError(t, p.Remove(base.FileTypeTable, 1))
+		})
+	}
+}
+
+func TestDropCompactionWritesFromPageCache(t *testing.T) {
+	dir := t.TempDir()
+	for _, drop := range []bool{false, true} {
+		t.Run(fmt.Sprintf("drop=%t", drop), func(t *testing.T) {
+			st := DefaultSettings(vfs.Default, dir)
+			st.Local.DropCompactionWritesFromPageCache = drop
+			p, err := Open(st)
+			require.NoError(t, err)
+			defer p.Close()
+
+			ctx := context.Background()
+			for i, forCompaction := range []bool{false, true} {
+				fileNum := base.DiskFileNum(i + 1)
+				w, _, err := p.Create(ctx, base.FileTypeTable, fileNum, objstorage.CreateOptions{
+					ForCompaction: forCompaction,
+				})
+				require.NoError(t, err)
+				// Only objects written by compactions are dropped from the page
+				// cache.
+				require.Equal(t, drop && forCompaction, w.(*fileBufferedWritable).dropAfterSync)
+				require.NoError(t, w.Write([]byte("hello world")))
+				require.NoError(t, w.Finish())
+
+				r, err := p.OpenForReading(ctx, base.FileTypeTable, fileNum, objstorage.OpenOptions{})
+				require.NoError(t, err)
+				buf := make([]byte, 5)
+				require.NoError(t, r.ReadAt(ctx, buf, 6))
+				require.Equal(t, "world", string(buf))
+				require.NoError(t, r.Close())
+				require.NoError(t, p.Remove(base.FileTypeTable, fileNum))
+			}
+		})
+	}
+}
@@ -4,7 +4,11 @@
 
 package objstorageprovider
 
-import "github.com/cockroachdb/pebble/internal/invariants"
+import (
+	"fmt"
+
+	"github.com/cockroachdb/pebble/internal/invariants"
+)
 
 const (
 	// Constants for dynamic readahead of data blocks. Note that the size values
@@ -16,15 +20,107 @@ const (
 	initialReadaheadSize = 64 << 10 /* 64KB */
 )
 
+// ReadaheadPolicy decides the readahead issued by the read handles of objects
+// when they detect sequential reads. A read handle tracks runs of sequential
+// reads (reads at or just past the data it has read or read ahead so far), and
+// consults the policy on every read of a run that isn't served by a previous
+// readahead; a read far from the previous ones ends the run.
+//
+// Readahead of local files is subject to the modes of the ReadaheadConfig:
+// the policy is not consulted if the mode is NoReadahead, and its Sequential
+// decisions are only followed in FadviseSequential mode. Compactions reading
+// remote objects don't consult the policy, and use a larger fixed readahead.
+//
+// Implementations must be safe for concurrent use.
+type ReadaheadPolicy interface {
+	// Readahead returns the readahead to issue for a read that continues a run
+	// of sequential reads.
+	Readahead(info ReadaheadInfo) ReadaheadDecision
+}
+
+// ReadaheadInfo holds the inputs of a ReadaheadPolicy.
+type ReadaheadInfo struct {
+	// SequentialReads is the number of reads of the current run of sequential
+	// reads, including the current read. Reads served from the block cache
+	// count towards the run.
+	SequentialReads int64
+	// PrevSize is the size of the last readahead of the run, or zero if there
+	// was none.
+	PrevSize int64
+	// MaxSize is the maximum readahead size for the object; ReadaheadDecision
+	// sizes are capped to it.
+	MaxSize int64
+	// Level is the LSM level of the table being read, or -1 if it isn't known
+	// (e.g. for blob files, or for reads outside of the LSM).
+	Level int
+	// Temperature is the temperature of the object being read.
+	Temperature FileTemperature
+}
+
+// ReadaheadDecision holds the outputs of a ReadaheadPolicy.
+type ReadaheadDecision struct {
+	// Size is the number of bytes, starting at the offset of the read, that
+	// should be read ahead; zero disables readahead for the read.
+	Size int64
+	// Sequential, if true, switches the read handle to OS-level sequential
+	// readahead (fadvise(FADV_SEQUENTIAL) on Linux) for the rest of its
+	// lifetime, after which the policy is no longer consulted. It only applies
+	// to local files, with the FadviseSequential readahead mode.
+	Sequential bool
+}
+
+// FileTemperature describes the storage of an object, as an input to the
+// ReadaheadPolicy.
+type FileTemperature uint8
+
+const (
+	// FileTemperatureHot is the temperature of objects stored locally.
+	FileTemperatureHot FileTemperature = iota
+	// FileTemperatureCold is the temperature of objects stored on remote
+	// storage, e.g. tables moved there by remote tiering, for which reads have
+	// a higher latency and cost.
+	FileTemperatureCold
+)
+
+// String implements fmt.Stringer.
+func (t FileTemperature) String() string {
+	switch t {
+	case FileTemperatureHot:
+		return "hot"
+	case FileTemperatureCold:
+		return "cold"
+	default:
+		return fmt.Sprintf("FileTemperature(%d)", t)
+	}
+}
+
+// DefaultReadaheadPolicy is the ReadaheadPolicy used when none is specified.
+// It starts reading ahead on the third sequential read, reading ahead 64KB and
+// doubling the size with every readahead up to the maximum size, at which
+// point it switches to OS-level sequential readahead.
+var DefaultReadaheadPolicy ReadaheadPolicy = defaultReadaheadPolicy{}
+
+type defaultReadaheadPolicy struct{}
+
+// Readahead implements ReadaheadPolicy.
+func (defaultReadaheadPolicy) Readahead(info ReadaheadInfo) ReadaheadDecision {
+	if info.SequentialReads <= minFileReadsForReadahead {
+		return ReadaheadDecision{}
+	}
+	size := int64(initialReadaheadSize)
+	if info.PrevSize > 0 {
+		size = 2 * info.PrevSize
+	}
+	size = min(size, info.MaxSize)
+	return ReadaheadDecision{Size: size, Sequential: size >= info.MaxSize}
+}
+
 // readaheadState contains state variables related to readahead. Updated on
 // file reads.
 type readaheadState struct {
 	// Number of sequential reads.
 	numReads         int64
 	maxReadaheadSize int64
-	// Size issued to the next call to Prefetch. Starts at or above
-	// initialReadaheadSize and grows exponentially until maxReadaheadSize.
-	size int64
 	// prevSize is the size used in the last Prefetch call.
 	prevSize int64
 	// The byte offset up to which the OS has been asked to read ahead / cached.
@@ -32,12 +128,22 @@ type readaheadState struct {
 	// operation. Reads after this limit can benefit from a new call to
 	// Prefetch.
 	limit int64
+
+	policy       ReadaheadPolicy
+	temperature  FileTemperature
+	levelPlusOne uint8
 }
 
-func makeReadaheadState(maxReadaheadSize int64) readaheadState {
+func makeReadaheadState(
+	maxReadaheadSize int64, policy ReadaheadPolicy, temperature FileTemperature,
+) readaheadState {
+	if policy == nil {
+		policy = DefaultReadaheadPolicy
+	}
 	return readaheadState{
-		size:             initialReadaheadSize,
 		maxReadaheadSize: maxReadaheadSize,
+		policy:           policy,
+		temperature:      temperature,
 	}
 }
 
@@ -47,159 +153,125 @@ func (rs *readaheadState) recordCacheHit(offset, blockLength int64) {
 
 // maybeReadahead updates state and determines whether to issue a readahead /
 // prefetch call for a block read at offset for blockLength bytes.
-// Returns a size value (greater than 0) that should be prefetched if readahead
-// would be beneficial.
-func (rs *readaheadState) maybeReadahead(offset, blockLength int64) int64 {
+// Returns the decision of the ReadaheadPolicy, whose size is greater than 0 if
+// readahead would be beneficial.
+func (rs *readaheadState) maybeReadahead(offset, blockLength int64) ReadaheadDecision {
 	return rs.maybeReadaheadOrCacheHit(offset, blockLength, true)
 }
 
 // The return value should be ignored if !readahead.
 func (rs *readaheadState) maybeReadaheadOrCacheHit(
 	offset, blockLength int64, readahead bool,
-) int64 {
+) ReadaheadDecision {
 	if invariants.Enabled && rs.maxReadaheadSize == 0 {
 		panic("readaheadState not initialized")
 	}
 	currentReadEnd := offset + blockLength
-	if rs.numReads >= minFileReadsForReadahead {
-		// The minimum threshold of sequential reads to justify reading ahead
-		// has been reached.
-		// There are two intervals: the interval being read:
-		// [offset, currentReadEnd]
-		// as well as the interval where a read would benefit from read ahead:
-		// [rs.limit, rs.limit + rs.size]
-		// We increase the latter interval to
-		// [rs.limit, rs.limit + rs.maxReadaheadSize] to account for cases where
-		// readahead may not be beneficial with a small readahead size, but over
-		// time the readahead size would increase exponentially to make it
-		// beneficial.
-		if currentReadEnd >= rs.limit && offset <= rs.limit+rs.maxReadaheadSize {
-			// We are doing a read in the interval ahead of
-			// the last readahead range. In the diagrams below, ++++ is the last
-			// readahead range, ==== is the range represented by
-			// [rs.limit, rs.limit + rs.maxReadaheadSize], and ---- is the range
-			// being read.
-			//
-			//               rs.limit           rs.limit + rs.maxReadaheadSize
-			//         ++++++++++|===========================|
-			//
-			//              |-------------|
-			//            offset       currentReadEnd
-			//
-			// This case is also possible, as are all cases with an overlap
-			// between [rs.limit, rs.limit + rs.maxReadaheadSize] and [offset,
-			// currentReadEnd]:
-			//
-			//               rs.limit           rs.limit + rs.maxReadaheadSize
-			//         ++++++++++|===========================|
-			//
-			//                                            |-------------|
-			//                                         offset       currentReadEnd
-			//
-			//
-			rs.numReads++
-			if readahead {
-				rs.limit = offset + rs.size
-				rs.prevSize = rs.size
-				// Increase rs.size for the next read.
-				rs.size *= 2
-				if rs.size > rs.maxReadaheadSize {
-					rs.size = rs.maxReadaheadSize
-				}
-			} else {
-				// This is a read that would have resulted in a readahead, had it
-				// not been a cache hit.
-				rs.limit = currentReadEnd
-			}
-			return rs.prevSize
-		}
-		if currentReadEnd < rs.limit-rs.prevSize || offset > rs.limit+rs.maxReadaheadSize {
-			// We read too far away from rs.limit to benefit from readahead in
-			// any scenario. Reset all variables.
-			// The case where we read too far ahead:
-			//
-			// (rs.limit - rs.prevSize)    (rs.limit)   (rs.limit + rs.maxReadaheadSize)
-			//                    |+++++++++++++|=============|
-			//
-			//                                                  |-------------|
-			//                                             offset       currentReadEnd
-			//
-			// Or too far behind:
-			//
-			// (rs.limit - rs.prevSize)    (rs.limit)   (rs.limit + rs.maxReadaheadSize)
-			//                    |+++++++++++++|=============|
-			//
-			//    |-------------|
-			// offset       currentReadEnd
-			//
-			rs.numReads = 1
-			rs.limit = currentReadEnd
-			rs.size = initialReadaheadSize
-			rs.prevSize = 0
-
-			return 0
-		}
-		// The previous if-block predicates were all false. This mechanically implies:
+	// There are two intervals: the interval being read:
+	// [offset, currentReadEnd]
+	// as well as the interval where a read would benefit from read ahead:
+	// [rs.limit, rs.limit + size]
+	// We increase the latter interval to
+	// [rs.limit, rs.limit + rs.maxReadaheadSize] to account for cases where
+	// readahead may not be beneficial with a small readahead size, but over
+	// time the readahead size would increase to make it beneficial.
+	if currentReadEnd >= rs.limit && offset <= rs.limit+rs.maxReadaheadSize {
+		// We are doing a read in the interval ahead of
+		// the last readahead range. In the diagrams below, ++++ is the last
+		// readahead range, ==== is the range represented by
+		// [rs.limit, rs.limit + rs.maxReadaheadSize], and ---- is the range
+		// being read.
 		//
-		// INVARIANT:
-		//  !(currentReadEnd >= rs.limit && offset <= rs.limit+rs.maxReadaheadSize) &&
-		//  !(currentReadEnd < rs.limit-rs.prevSize || offset > rs.limit+rs.maxReadaheadSize)
-		// Which mechanically simplifies to:
-		//  currentReadEnd < rs.limit  && currentReadEnd >= rs.limit-rs.prevSize &&
-		//  offset <= rs.limit+rs.maxReadaheadSize
+		//               rs.limit           rs.limit + rs.maxReadaheadSize
+		//         ++++++++++|===========================|
 		//
-		// So reads in the range [rs.limit - rs.prevSize, rs.limit] end up
-		// here. This is a read that is potentially benefitting from a past
-		// readahead, but there's no reason to issue a readahead call at the
-		// moment.
+		//              |-------------|
+		//            offset       currentReadEnd
 		//
-		// (rs.limit - rs.prevSize)            (rs.limit + rs.maxReadaheadSize)
-		//                    |+++++++++++++|===============|
-		//                             (rs.limit)
+		// This case is also possible, as are all cases with an overlap
+		// between [rs.limit, rs.limit + rs.maxReadaheadSize] and [offset,
+		// currentReadEnd]:
 		//
-		//                        |-------|
-		//                     offset    currentReadEnd
+		//               rs.limit           rs.limit + rs.maxReadaheadSize
+		//         ++++++++++|===========================|
 		//
+		//                                            |-------------|
+		//                                         offset       currentReadEnd
+		//
+		// The blocks are being read sequentially, so the policy decides whether
+		// the run is long enough to justify reading ahead.
 		rs.numReads++
-		return 0
+		// If the read is a cache hit or no readahead is issued, we still advance
+		// rs.limit. If we left it behind, it is possible that the next read is
+		// sequential but has offset > rs.limit+rs.maxReadaheadSize, and we would
+		// incorrectly think that readahead is not beneficial.
+		rs.limit = currentReadEnd
+		if !readahead {
+			// This is a read that could have resulted in a readahead, had it not
+			// been a cache hit.
+			return ReadaheadDecision{}
+		}
+		decision := rs.policy.Readahead(ReadaheadInfo{
+			SequentialReads: rs.numReads,
+			PrevSize:        rs.prevSize,
+			MaxSize:         rs.maxReadaheadSize,
+			Level:           int(rs.levelPlusOne) - 1,
+			Temperature:     rs.temperature,
+		})
+		decision.Size = min(decision.Size, rs.maxReadaheadSize)
+		if decision.Size > 0 {
+			rs.limit = offset + decision.Size
+			rs.prevSize = decision.Size
+		}
+		return decision
 	}
-	// Not yet at the numReads threshold to justify readahead. But we want to
-	// capture whether readahead will be beneficial in the future.
-	if currentReadEnd >= rs.limit && offset <= rs.limit+rs.maxReadaheadSize {
-		// Blocks are being read sequentially and would benefit from readahead
-		// down the line.
+	if currentReadEnd < rs.limit-rs.prevSize || offset > rs.limit+rs.maxReadaheadSize {
+		// We read too far away from rs.limit to benefit from readahead in
+		// any scenario. This indicates a random read, where readahead is not
+		// desirable. Reset all variables.
+		// The case where we read too far ahead:
 		//
-		//                       (rs.limit)   (rs.limit + rs.maxReadaheadSize)
-		//                         |=============|
+		// (rs.limit - rs.prevSize)    (rs.limit)   (rs.limit + rs.maxReadaheadSize)
+		//                    |+++++++++++++|=============|
 		//
-		//                    |-------|
-		//                offset    currentReadEnd
+		//                                                  |-------------|
+		//                                             offset       currentReadEnd
 		//
-		rs.numReads++
-		// It is possible to fall here when rs.limit has not been initialized. If
-		// we don't initialize, rs.limit, it is possible that the first read
-		// offset was at rs.limit+rs.maxReadaheadSize-delta and the enclosing
-		// if-block predicate was true, and the next read is sequential but has
-		// offset > rs.limit+rs.maxReadaheadSize (if we left rs.limit at 0), and
-		// the enclosing if-block predicate will be false and we will incorrectly
-		// think that readahead is not beneficial. The same issue arises if
-		// rs.limit has been initialized and currentReadEnd is advancing past
-		// rs.limit.
+		// Or too far behind:
+		//
+		// (rs.limit - rs.prevSize)    (rs.limit)   (rs.limit + rs.maxReadaheadSize)
+		//                    |+++++++++++++|=============|
+		//
+		//    |-------------|
+		// offset       currentReadEnd
+		//
+		rs.numReads = 1
 		rs.limit = currentReadEnd
-		return 0
+		rs.prevSize = 0
+		return ReadaheadDecision{}
 	}
-	// We read too far ahead of the last read, or before it. This indicates
-	// a random read, where readahead is not desirable. Reset all variables.
+	// The previous if-block predicates were all false. This mechanically implies:
+	//
+	// INVARIANT:
+	//  !(currentReadEnd >= rs.limit && offset <= rs.limit+rs.maxReadaheadSize) &&
+	//  !(currentReadEnd < rs.limit-rs.prevSize || offset > rs.limit+rs.maxReadaheadSize)
+	// Which mechanically simplifies to:
+	//  currentReadEnd < rs.limit  && currentReadEnd >= rs.limit-rs.prevSize &&
+	//  offset <= rs.limit+rs.maxReadaheadSize
+	//
+	// So reads in the range [rs.limit - rs.prevSize, rs.limit] end up
+	// here. This is a read that is potentially benefitting from a past
+	// readahead, but there's no reason to issue a readahead call at the
+	// moment. Note that this is only possible once a readahead was issued,
+	// since rs.prevSize is zero otherwise.
 	//
-	// (rs.limit - rs.maxReadaheadSize)  (rs.limit)   (rs.limit + rs.maxReadaheadSize)
-	//                     |+++++++++++++|=============|
+	// (rs.limit - rs.prevSize)            (rs.limit + rs.maxReadaheadSize)
+	//                    |+++++++++++++|===============|
+	//                             (rs.limit)
 	//
-	//                                                    |-------|
-	//                                                offset    currentReadEnd
+	//                        |-------|
+	//                     offset    currentReadEnd
 	//
-	rs.numReads = 1
-	rs.limit = currentReadEnd
-	rs.size = initialReadaheadSize
-	rs.prevSize = 0
-	return 0
+	rs.numReads++
+	return ReadaheadDecision{}
 }
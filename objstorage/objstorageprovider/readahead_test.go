@@ -15,12 +15,12 @@ import (
 )
 
 func TestMaybeReadahead(t *testing.T) {
-	rs := makeReadaheadState(256 * 1024)
+	rs := makeReadaheadState(256*1024, nil /* policy */, FileTemperatureHot)
 	datadriven.RunTest(t, "testdata/readahead", func(t *testing.T, d *datadriven.TestData) string {
 		cacheHit := false
 		switch d.Cmd {
 		case "reset":
-			rs = makeReadaheadState(rs.maxReadaheadSize)
+			rs = makeReadaheadState(rs.maxReadaheadSize, nil /* policy */, FileTemperatureHot)
 			return ""
 
 		case "cache-read":
@@ -40,13 +40,12 @@ func TestMaybeReadahead(t *testing.T) {
 			if cacheHit {
 				rs.recordCacheHit(offset, size)
 			} else {
-				raSize = rs.maybeReadahead(offset, size)
+				raSize = rs.maybeReadahead(offset, size).Size
 			}
 
 			var buf strings.Builder
 			fmt.Fprintf(&buf, "readahead:  %d\n", raSize)
 			fmt.Fprintf(&buf, "numReads:   %d\n", rs.numReads)
-			fmt.Fprintf(&buf, "size:       %d\n", rs.size)
 			fmt.Fprintf(&buf, "prevSize:   %d\n", rs.prevSize)
 			fmt.Fprintf(&buf, "limit:      %d", rs.limit)
 			return buf.String()
@@ -55,3 +54,47 @@ func TestMaybeReadahead(t *testing.T) {
 		}
 	})
 }
+
+type fixedReadaheadPolicy struct {
+	infos []ReadaheadInfo
+}
+
+func (p *fixedReadaheadPolicy) Readahead(info ReadaheadInfo) ReadaheadDecision {
+	p.infos = append(p.infos, info)
+	if info.SequentialReads < 2 {
+		return ReadaheadDecision{}
+	}
+	return ReadaheadDecision{Size: 1 << 20, Sequential: info.Level >= 5}
+}
+
+func TestReadaheadPolicy(t *testing.T) {
+	p := &fixedReadaheadPolicy{}
+	rs := makeReadaheadState(256<<10, p, FileTemperatureCold)
+	rs.levelPlusOne = 6
+
+	require.Equal(t, ReadaheadDecision{}, rs.maybeReadahead(0, 4096))
+	// The policy decides to read ahead on the second read, and its size is
+	// capped to the maximum.
+	require.Equal(t, ReadaheadDecision{Size: 256 << 10, Sequential: true}, rs.maybeReadahead(4096, 4096))
+	require.Equal(t, []ReadaheadInfo{
+		{SequentialReads: 1, MaxSize: 256 << 10, Level: 5, Temperature: FileTemperatureCold},
+		{SequentialReads: 2, MaxSize: 256 << 10, Level: 5, Temperature: FileTemperatureCold},
+	}, p.infos)
+
+	// A read served by the readahead doesn't consult the policy.
+	p.infos = nil
+	require.Equal(t, ReadaheadDecision{}, rs.maybeReadahead(8192, 4096))
+	require.Empty(t, p.infos)
+
+	// A read that continues the run consults the policy with the previous size.
+	require.Equal(t, int64(256<<10), rs.maybeReadahead(4096+256<<10, 4096).Size)
+	require.Equal(t, []ReadaheadInfo{
+		{SequentialReads: 4, PrevSize: 256 << 10, MaxSize: 256 << 10, Level: 5, Temperature: FileTemperatureCold},
+	}, p.infos)
+
+	// A random read resets the run.
+	p.infos = nil
+	require.Equal(t, ReadaheadDecision{}, rs.maybeReadahead(100<<20, 4096))
+	require.Empty(t, p.infos)
+	require.Equal(t, int64(1), rs.numReads)
+}
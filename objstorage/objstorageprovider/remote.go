@@ -53,6 +53,10 @@ type remoteLockedState struct {
 
 	storageObjects map[remote.Locator]remote.Storage
 
+	// breakers are the circuit breakers around reads, per locator. They're
+	// created on the first read from a locator.
+	breakers map[remote.Locator]*remoteBreaker
+
 	externalObjects map[remote.ObjectKey][]base.DiskFileNum
 }
 
@@ -123,7 +127,8 @@ func (p *provider) remoteInit() error {
 		}
 
 		p.remote.cache, err = sharedcache.Open(
-			p.st.FS, p.st.Logger, p.st.FSDirName, blockSize, shardingBlockSize, p.st.Remote.CacheSizeBytes, numShards)
+			p.st.FS, p.st.Logger, p.st.FSDirName, blockSize, shardingBlockSize, p.st.Remote.CacheSizeBytes, numShards,
+			p.st.Remote.CachePersistence)
 		if err != nil {
 			return errors.Wrapf(err, "pebble: could not open remote object cache")
 		}
@@ -339,8 +344,21 @@ func (p *provider) remoteOpenForReading(
 			return nil, errors.Wrapf(err, "checking marker object %q", errors.Safe(refName))
 		}
 	}
+	p.mu.Lock()
+	breaker := p.remoteBreakerLocked(meta.Remote.Locator)
+	p.mu.Unlock()
+	var probe bool
+	if breaker != nil {
+		var err error
+		if probe, err = breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
 	objName := remoteObjectName(meta)
 	reader, size, err := meta.Remote.Storage.ReadObject(ctx, objName)
+	if breaker != nil {
+		breaker.done(ctx, probe, err, meta.Remote.Storage.IsNotExistError)
+	}
 	if err != nil {
 		if opts.MustExist && meta.Remote.Storage.IsNotExistError(err) {
 			// TODO(radu): maybe list references for the object.
@@ -348,6 +366,16 @@ func (p *provider) remoteOpenForReading(
 		}
 		return nil, err
 	}
+	if breaker != nil {
+		// Reads served by the secondary cache don't go through the breaker, so
+		// that the cached parts of the object remain readable while the
+		// storage is unavailable.
+		reader = &breakerObjectReader{
+			ObjectReader:  reader,
+			b:             breaker,
+			errIsNotExist: meta.Remote.Storage.IsNotExistError,
+		}
+	}
 	return p.newRemoteReadable(reader, size, meta.DiskFileNum, meta.Remote.Storage.IsNotExistError), nil
 }
 
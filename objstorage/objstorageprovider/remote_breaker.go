@@ -0,0 +1,190 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package objstorageprovider
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+)
+
+// CircuitBreakerOptions configures the circuit breakers around reads from
+// remote storage.
+//
+// There is a circuit breaker per locator. It trips after FailureThreshold
+// consecutive failed reads; while it's tripped, reads that aren't served by
+// the secondary cache fail immediately with objstorage.ErrRemoteUnavailable
+// instead of waiting on the remote storage. Once Cooldown elapses, a single
+// read is let through to probe the storage: the breaker recovers if it
+// succeeds, and stays tripped for another Cooldown otherwise.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed reads that trip a
+	// breaker. The breakers are disabled if it's 0.
+	FailureThreshold int
+	// Cooldown is how long a tripped breaker fails reads before probing the
+	// storage again.
+	Cooldown time.Duration
+}
+
+// remoteBreaker is the circuit breaker around the reads from the remote
+// storage of a locator.
+type remoteBreaker struct {
+	locator  remote.Locator
+	opts     CircuitBreakerOptions
+	onChange func(objstorage.RemoteBreakerState)
+
+	mu struct {
+		sync.Mutex
+		failures  int
+		tripped   bool
+		trippedAt time.Time
+		// probing is set while the read probing a tripped breaker is in flight.
+		probing bool
+		trips   int64
+		lastErr error
+	}
+}
+
+// remoteBreakerLocked returns the circuit breaker of the locator, or nil if
+// the circuit breakers are disabled. p.mu must be held.
+func (p *provider) remoteBreakerLocked(locator remote.Locator) *remoteBreaker {
+	if p.st.Remote.CircuitBreaker.FailureThreshold <= 0 {
+		return nil
+	}
+	if b, ok := p.mu.remote.breakers[locator]; ok {
+		return b
+	}
+	if p.mu.remote.breakers == nil {
+		p.mu.remote.breakers = make(map[remote.Locator]*remoteBreaker)
+	}
+	b := &remoteBreaker{
+		locator:  locator,
+		opts:     p.st.Remote.CircuitBreaker,
+		onChange: p.st.Remote.OnCircuitBreakerChange,
+	}
+	p.mu.remote.breakers[locator] = b
+	return b
+}
+
+// RemoteBreakers is part of the objstorage.Provider interface.
+func (p *provider) RemoteBreakers() []objstorage.RemoteBreakerState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	states := make([]objstorage.RemoteBreakerState, 0, len(p.mu.remote.breakers))
+	for _, b := range p.mu.remote.breakers {
+		states = append(states, b.state())
+	}
+	slices.SortFunc(states, func(a, b objstorage.RemoteBreakerState) int {
+		return cmp.Compare(a.Locator, b.Locator)
+	})
+	return states
+}
+
+func (b *remoteBreaker) state() objstorage.RemoteBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+func (b *remoteBreaker) stateLocked() objstorage.RemoteBreakerState {
+	return objstorage.RemoteBreakerState{
+		Locator:             b.locator,
+		Tripped:             b.mu.tripped,
+		ConsecutiveFailures: b.mu.failures,
+		Trips:               b.mu.trips,
+		LastError:           b.mu.lastErr,
+	}
+}
+
+// allow returns an error wrapping objstorage.ErrRemoteUnavailable if the
+// breaker is tripped. Otherwise the read can proceed, and its outcome must be
+// passed to done; probe is set if the read probes a tripped breaker.
+func (b *remoteBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.mu.tripped {
+		return false, nil
+	}
+	if b.mu.probing || time.Since(b.mu.trippedAt) < b.opts.Cooldown {
+		return false, errors.Wrapf(objstorage.ErrRemoteUnavailable,
+			"locator %q after %d failed reads: %v",
+			errors.Safe(b.locator), errors.Safe(b.mu.failures), b.mu.lastErr)
+	}
+	b.mu.probing = true
+	return true, nil
+}
+
+// done records the outcome of a read allowed by allow. Reads interrupted by
+// their context don't reflect on the storage and aren't counted, and neither
+// are the results of reads issued before the breaker tripped.
+func (b *remoteBreaker) done(ctx context.Context, probe bool, err error, errIsNotExist func(error) bool) {
+	// A missing object is a successful round trip to the storage.
+	failed := err != nil && !errIsNotExist(err)
+	if failed && ctx.Err() != nil {
+		if probe {
+			b.mu.Lock()
+			b.mu.probing = false
+			b.mu.Unlock()
+		}
+		return
+	}
+
+	b.mu.Lock()
+	if b.mu.tripped && !probe {
+		b.mu.Unlock()
+		return
+	}
+	b.mu.probing = false
+	changed := false
+	if failed {
+		b.mu.failures++
+		b.mu.lastErr = err
+		if b.mu.tripped {
+			// The probe failed; wait for another cooldown.
+			b.mu.trippedAt = time.Now()
+		} else if b.mu.failures >= b.opts.FailureThreshold {
+			b.mu.tripped = true
+			b.mu.trippedAt = time.Now()
+			b.mu.trips++
+			changed = true
+		}
+	} else {
+		changed = b.mu.tripped
+		b.mu.tripped = false
+		b.mu.failures = 0
+	}
+	state := b.stateLocked()
+	b.mu.Unlock()
+	if changed && b.onChange != nil {
+		b.onChange(state)
+	}
+}
+
+// breakerObjectReader wraps the remote.ObjectReader of an object, guarding its
+// reads with the breaker of the object's locator.
+type breakerObjectReader struct {
+	remote.ObjectReader
+	b             *remoteBreaker
+	errIsNotExist func(error) bool
+}
+
+var _ remote.ObjectReader = (*breakerObjectReader)(nil)
+
+// ReadAt is part of the remote.ObjectReader interface.
+func (r *breakerObjectReader) ReadAt(ctx context.Context, p []byte, offset int64) error {
+	probe, err := r.b.allow()
+	if err != nil {
+		return err
+	}
+	err = r.ObjectReader.ReadAt(ctx, p, offset)
+	r.b.done(ctx, probe, err, r.errIsNotExist)
+	return err
+}
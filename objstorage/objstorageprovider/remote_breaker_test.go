@@ -0,0 +1,123 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package objstorageprovider
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStorage wraps a remote.Storage, failing the reads of its objects while
+// fail is set.
+type flakyStorage struct {
+	remote.Storage
+	fail  atomic.Bool
+	reads atomic.Int64
+}
+
+func (s *flakyStorage) ReadObject(
+	ctx context.Context, objName string,
+) (remote.ObjectReader, int64, error) {
+	r, size, err := s.Storage.ReadObject(ctx, objName)
+	return &flakyObjectReader{ObjectReader: r, s: s}, size, err
+}
+
+type flakyObjectReader struct {
+	remote.ObjectReader
+	s *flakyStorage
+}
+
+func (r *flakyObjectReader) ReadAt(ctx context.Context, p []byte, offset int64) error {
+	r.s.reads.Add(1)
+	if r.s.fail.Load() {
+		return errors.New("injected timeout")
+	}
+	return r.ObjectReader.ReadAt(ctx, p, offset)
+}
+
+func TestRemoteCircuitBreaker(t *testing.T) {
+	storage := &flakyStorage{Storage: remote.NewInMem()}
+	st := DefaultSettings(vfs.NewMem(), "")
+	st.Remote.StorageFactory = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": storage,
+	})
+	st.Remote.CreateOnShared = remote.CreateOnSharedAll
+	st.Remote.CircuitBreaker = CircuitBreakerOptions{
+		FailureThreshold: 2,
+		Cooldown:         50 * time.Millisecond,
+	}
+	var changes []objstorage.RemoteBreakerState
+	st.Remote.OnCircuitBreakerChange = func(s objstorage.RemoteBreakerState) {
+		changes = append(changes, s)
+	}
+	p, err := Open(st)
+	require.NoError(t, err)
+	defer p.Close()
+	require.NoError(t, p.SetCreatorID(1))
+
+	ctx := context.Background()
+	w, _, err := p.Create(ctx, base.FileTypeTable, 1, objstorage.CreateOptions{PreferSharedStorage: true})
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]byte("foobar")))
+	require.NoError(t, w.Finish())
+	r, err := p.OpenForReading(ctx, base.FileTypeTable, 1, objstorage.OpenOptions{})
+	require.NoError(t, err)
+	defer r.Close()
+	buf := make([]byte, 3)
+	require.NoError(t, r.ReadAt(ctx, buf, 0))
+	require.Zero(t, p.RemoteBreakers()[0].ConsecutiveFailures)
+
+	// Reads interrupted by their context aren't failures of the storage.
+	storage.fail.Store(true)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Error(t, r.ReadAt(canceled, buf, 0))
+
+	// The breaker trips after two failed reads.
+	require.Error(t, r.ReadAt(ctx, buf, 0))
+	require.False(t, p.RemoteBreakers()[0].Tripped)
+	require.Error(t, r.ReadAt(ctx, buf, 0))
+	require.Len(t, changes, 1)
+	require.True(t, changes[0].Tripped)
+	require.Equal(t, 2, changes[0].ConsecutiveFailures)
+
+	// While it's tripped, reads fail without reaching the storage.
+	reads := storage.reads.Load()
+	require.ErrorIs(t, r.ReadAt(ctx, buf, 0), objstorage.ErrRemoteUnavailable)
+	_, err = p.OpenForReading(ctx, base.FileTypeTable, 1, objstorage.OpenOptions{})
+	require.ErrorIs(t, err, objstorage.ErrRemoteUnavailable)
+	require.Equal(t, reads, storage.reads.Load())
+	state := p.RemoteBreakers()[0]
+	require.True(t, state.Tripped)
+	require.Equal(t, int64(1), state.Trips)
+	require.Error(t, state.LastError)
+
+	// After the cooldown, a failed probe keeps the breaker tripped.
+	time.Sleep(st.Remote.CircuitBreaker.Cooldown)
+	require.NotErrorIs(t, r.ReadAt(ctx, buf, 0), objstorage.ErrRemoteUnavailable)
+	require.ErrorIs(t, r.ReadAt(ctx, buf, 0), objstorage.ErrRemoteUnavailable)
+	require.Len(t, changes, 1)
+
+	// A successful probe recovers it.
+	storage.fail.Store(false)
+	time.Sleep(st.Remote.CircuitBreaker.Cooldown)
+	require.NoError(t, r.ReadAt(ctx, buf, 0))
+	require.Equal(t, "foo", string(buf))
+	require.Len(t, changes, 2)
+	require.False(t, changes[1].Tripped)
+	state = p.RemoteBreakers()[0]
+	require.False(t, state.Tripped)
+	require.Zero(t, state.ConsecutiveFailures)
+	require.Equal(t, int64(1), state.Trips)
+}
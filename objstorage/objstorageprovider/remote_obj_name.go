@@ -15,6 +15,9 @@ import (
 //
 // For sstables, the format is: <hash>-<creator-id>-<file-num>.sst
 // For example: 1a3f-2-000001.sst
+//
+// For blob files, the format is: <hash>-<creator-id>-<file-num>.blob
+// For example: 1a3f-2-000001.blob
 func remoteObjectName(meta objstorage.ObjectMetadata) string {
 	if meta.Remote.CustomObjectName != "" {
 		return meta.Remote.CustomObjectName
@@ -25,6 +28,11 @@ func remoteObjectName(meta objstorage.ObjectMetadata) string {
 			"%04x-%d-%06d.sst",
 			objHash(meta), meta.Remote.CreatorID, meta.Remote.CreatorFileNum,
 		)
+	case base.FileTypeBlob:
+		return fmt.Sprintf(
+			"%04x-%d-%06d.blob",
+			objHash(meta), meta.Remote.CreatorID, meta.Remote.CreatorFileNum,
+		)
 	}
 	panic("unknown FileType")
 }
@@ -51,6 +59,11 @@ func sharedObjectRefName(
 			"%04x-%d-%06d.sst.ref.%d.%06d",
 			objHash(meta), meta.Remote.CreatorID, meta.Remote.CreatorFileNum, refCreatorID, refFileNum,
 		)
+	case base.FileTypeBlob:
+		return fmt.Sprintf(
+			"%04x-%d-%06d.blob.ref.%d.%06d",
+			objHash(meta), meta.Remote.CreatorID, meta.Remote.CreatorFileNum, refCreatorID, refFileNum,
+		)
 	}
 	panic("unknown FileType")
 }
@@ -65,6 +78,11 @@ func sharedObjectRefPrefix(meta objstorage.ObjectMetadata) string {
 			"%04x-%d-%06d.sst.ref.",
 			objHash(meta), meta.Remote.CreatorID, meta.Remote.CreatorFileNum,
 		)
+	case base.FileTypeBlob:
+		return fmt.Sprintf(
+			"%04x-%d-%06d.blob.ref.",
+			objHash(meta), meta.Remote.CreatorID, meta.Remote.CreatorFileNum,
+		)
 	}
 	panic("unknown FileType")
 }
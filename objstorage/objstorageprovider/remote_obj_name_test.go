@@ -18,6 +18,7 @@ func TestSharedObjectNames(t *testing.T) {
 	t.Run("crosscheck", func(t *testing.T) {
 		supportedFileTypes := []base.FileType{
 			base.FileTypeTable,
+			base.FileTypeBlob,
 		}
 		for it := 0; it < 100; it++ {
 			var meta objstorage.ObjectMetadata
@@ -40,6 +40,8 @@ type remoteReadable struct {
 	fileNum       base.DiskFileNum
 	cache         *sharedcache.Cache
 	errIsNotExist func(error) bool
+	// readaheadPolicy is nil if the DefaultReadaheadPolicy is used.
+	readaheadPolicy ReadaheadPolicy
 }
 
 var _ objstorage.Readable = (*remoteReadable)(nil)
@@ -51,11 +53,12 @@ func (p *provider) newRemoteReadable(
 	errIsNotExist func(error) bool,
 ) *remoteReadable {
 	return &remoteReadable{
-		objReader:     objReader,
-		size:          size,
-		fileNum:       fileNum,
-		cache:         p.remote.cache,
-		errIsNotExist: errIsNotExist,
+		objReader:       objReader,
+		size:            size,
+		fileNum:         fileNum,
+		cache:           p.remote.cache,
+		errIsNotExist:   errIsNotExist,
+		readaheadPolicy: p.st.ReadaheadPolicy,
 	}
 }
 
@@ -69,6 +72,10 @@ func (r *remoteReadable) ReadAt(ctx context.Context, p []byte, offset int64) err
 func (r *remoteReadable) readInternal(
 	ctx context.Context, p []byte, offset int64, forCompaction bool,
 ) error {
+	// Remote reads can be slow; don't issue one if the caller already gave up.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	var err error
 	if r.cache != nil {
 		flags := sharedcache.ReadFlags{
@@ -106,7 +113,7 @@ func (r *remoteReadable) NewReadHandle(
 ) objstorage.ReadHandle {
 	rh := remoteReadHandlePool.Get().(*remoteReadHandle)
 	*rh = remoteReadHandle{readable: r, readBeforeSize: readBeforeSize, buffered: rh.buffered}
-	rh.readAheadState = makeReadaheadState(remoteMaxReadaheadSize)
+	rh.readAheadState = makeReadaheadState(remoteMaxReadaheadSize, r.readaheadPolicy, FileTemperatureCold)
 	return rh
 }
 
@@ -240,7 +247,7 @@ func (r *remoteReadHandle) maybeReadahead(offset int64, len int) int {
 	if r.forCompaction {
 		return remoteReadaheadSizeForCompaction
 	}
-	return int(r.readAheadState.maybeReadahead(offset, int64(len)))
+	return int(r.readAheadState.maybeReadahead(offset, int64(len)).Size)
 }
 
 func (r *remoteReadHandle) readToBuffer(ctx context.Context, offset int64, length int) error {
@@ -146,3 +146,18 @@ func TestErrorWhenObjectDisappears(t *testing.T) {
 	err = readable.ReadAt(ctx, make([]byte, 1), 0)
 	require.True(t, base.IsCorruptionError(err))
 }
+
+func TestRemoteReadableCanceledContext(t *testing.T) {
+	var or testObjectReader
+	or.init(100)
+	rr := &remoteReadable{objReader: &or, size: 100}
+	defer func() { require.NoError(t, rr.Close()) }()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := make([]byte, 10)
+	require.ErrorIs(t, rr.ReadAt(ctx, p, 0), context.Canceled)
+	// The canceled read must not reach the object reader.
+	require.Equal(t, "", or.b.String())
+	require.NoError(t, rr.ReadAt(context.Background(), p, 0))
+	require.Equal(t, or.buf[:10], p)
+}
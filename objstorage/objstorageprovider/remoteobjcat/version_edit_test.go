@@ -59,7 +59,7 @@ func TestVersionEditRoundTrip(t *testing.T) {
 				},
 				{
 					FileNum:          base.DiskFileNum(3),
-					FileType:         base.FileTypeTable,
+					FileType:         base.FileTypeBlob,
 					CreatorID:        32,
 					CreatorFileNum:   base.DiskFileNum(323),
 					CleanupMethod:    objstorage.SharedRefTracking,
@@ -0,0 +1,260 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sharedcache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/crc"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// PersistenceOptions configures whether the contents of the cache survive a
+// restart of the process.
+//
+// The cache data itself always lives on disk; what is lost on restart is the
+// in-memory index mapping logical blocks of remote objects to cache blocks.
+// When persistence is enabled, each shard writes a small index file on Close
+// recording the cached blocks (in LRU order). When the cache is next opened,
+// the index is loaded so that reads are served from the cache immediately,
+// avoiding a storm of reads against remote storage after a restart.
+//
+// An index is only written on a clean Close, and any index file is removed
+// when the cache is opened. An unclean shutdown therefore results in an empty
+// cache, never a stale one.
+type PersistenceOptions struct {
+	// PersistOnClose causes each shard to write its index when the cache is
+	// closed. It also causes checksums of cache blocks to be computed when they
+	// are written, so that they can be validated on open.
+	PersistOnClose bool
+	// WarmOnOpen causes the index written by a previous PersistOnClose to be
+	// loaded when the cache is opened. If there is no index, or it is invalid or
+	// was written with a different cache geometry (block size, sharding block
+	// size or shard count), the cache starts empty.
+	WarmOnOpen bool
+	// ValidateOnOpen causes every block listed in a loaded index to be read and
+	// its checksum verified before the block is added to the cache. Blocks that
+	// fail validation are discarded. This increases the time it takes to open
+	// the cache proportionally to the amount of cached data.
+	ValidateOnOpen bool
+}
+
+const (
+	indexMagic   = 0x5c1d_ca4e
+	indexVersion = 1
+
+	// indexHeaderLen is the length of the index header: magic (4 bytes),
+	// version (4), block size (4), shard count (4), sharding block size (8) and
+	// entry count (8).
+	indexHeaderLen = 32
+	// indexEntryLen is the length of an index entry: file number (8 bytes),
+	// logical block index (8), cache block index (8) and block checksum (4).
+	indexEntryLen = 28
+	// indexFooterLen is the length of the checksum of the index contents.
+	indexFooterLen = 4
+)
+
+// indexEntry describes a cache block that is populated with the data of a
+// logical block of a remote object.
+type indexEntry struct {
+	logical  logicalBlockID
+	index    cacheBlockIndex
+	checksum uint32
+}
+
+func shardIndexPath(fs vfs.FS, fsDir string, shardIdx int) string {
+	return fs.PathJoin(fsDir, fmt.Sprintf("SHARED-CACHE-%03d.index", shardIdx))
+}
+
+// encodeIndex encodes the given entries, along with the geometry of the cache
+// that they pertain to.
+func (s *shard) encodeIndex(entries []indexEntry) []byte {
+	buf := make([]byte, indexHeaderLen+len(entries)*indexEntryLen+indexFooterLen)
+	binary.LittleEndian.PutUint32(buf[0:], indexMagic)
+	binary.LittleEndian.PutUint32(buf[4:], indexVersion)
+	binary.LittleEndian.PutUint32(buf[8:], uint32(s.bm.BlockSize()))
+	binary.LittleEndian.PutUint32(buf[12:], uint32(len(s.cache.shards)))
+	binary.LittleEndian.PutUint64(buf[16:], uint64(s.shardingBlockSize))
+	binary.LittleEndian.PutUint64(buf[24:], uint64(len(entries)))
+	b := buf[indexHeaderLen:]
+	for _, e := range entries {
+		binary.LittleEndian.PutUint64(b[0:], uint64(e.logical.filenum))
+		binary.LittleEndian.PutUint64(b[8:], uint64(e.logical.cacheBlockIdx))
+		binary.LittleEndian.PutUint64(b[16:], uint64(e.index))
+		binary.LittleEndian.PutUint32(b[24:], e.checksum)
+		b = b[indexEntryLen:]
+	}
+	binary.LittleEndian.PutUint32(b, crc.New(buf[:len(buf)-indexFooterLen]).Value())
+	return buf
+}
+
+// decodeIndex decodes an index produced by encodeIndex. An error is returned
+// if the index is corrupt or was written by a cache with a different
+// geometry.
+func (s *shard) decodeIndex(buf []byte, numShards int) ([]indexEntry, error) {
+	if len(buf) < indexHeaderLen+indexFooterLen {
+		return nil, errors.Newf("index too short (%d bytes)", len(buf))
+	}
+	contents, footer := buf[:len(buf)-indexFooterLen], buf[len(buf)-indexFooterLen:]
+	if expected, actual := binary.LittleEndian.Uint32(footer), crc.New(contents).Value(); expected != actual {
+		return nil, errors.Newf("index checksum mismatch (expected %x, computed %x)", expected, actual)
+	}
+	if magic := binary.LittleEndian.Uint32(contents[0:]); magic != indexMagic {
+		return nil, errors.Newf("invalid index magic %x", magic)
+	}
+	if version := binary.LittleEndian.Uint32(contents[4:]); version != indexVersion {
+		return nil, errors.Newf("unsupported index version %d", version)
+	}
+	if blockSize := binary.LittleEndian.Uint32(contents[8:]); int(blockSize) != s.bm.BlockSize() {
+		return nil, errors.Newf("block size changed from %d to %d", blockSize, s.bm.BlockSize())
+	}
+	if n := binary.LittleEndian.Uint32(contents[12:]); int(n) != numShards {
+		return nil, errors.Newf("shard count changed from %d to %d", n, numShards)
+	}
+	if sbs := binary.LittleEndian.Uint64(contents[16:]); int64(sbs) != s.shardingBlockSize {
+		return nil, errors.Newf("sharding block size changed from %d to %d", sbs, s.shardingBlockSize)
+	}
+	n := binary.LittleEndian.Uint64(contents[24:])
+	b := contents[indexHeaderLen:]
+	if uint64(len(b)) != n*indexEntryLen {
+		return nil, errors.Newf("index has %d bytes of entries, expected %d entries", len(b), n)
+	}
+	entries := make([]indexEntry, 0, n)
+	for ; len(b) > 0; b = b[indexEntryLen:] {
+		entries = append(entries, indexEntry{
+			logical: logicalBlockID{
+				filenum:       base.DiskFileNum(binary.LittleEndian.Uint64(b[0:])),
+				cacheBlockIdx: cacheBlockIndex(binary.LittleEndian.Uint64(b[8:])),
+			},
+			index:    cacheBlockIndex(binary.LittleEndian.Uint64(b[16:])),
+			checksum: binary.LittleEndian.Uint32(b[24:]),
+		})
+	}
+	return entries, nil
+}
+
+// loadIndex reads and removes the index file for the shard, if any. If
+// warming is enabled, it returns the entries of the index that can be used to
+// populate the shard, in LRU order (most recently used first).
+//
+// Errors are not returned: a missing or invalid index results in an empty
+// shard.
+func (s *shard) loadIndex(numShards int, opts PersistenceOptions) []indexEntry {
+	fs, path := s.fs, s.indexPath
+	f, err := fs.Open(path)
+	if err != nil {
+		if !oserror.IsNotExist(err) {
+			s.cache.logger.Errorf("opening secondary cache index %s failed: %v", path, err)
+		}
+		return nil
+	}
+	buf, err := io.ReadAll(f)
+	_ = f.Close()
+	// The index describes the cache contents as of the last clean Close; it
+	// becomes stale as soon as the cache is written to. Remove it so that it
+	// can't be loaded after an unclean shutdown.
+	if rmErr := fs.Remove(path); rmErr != nil {
+		s.cache.logger.Errorf("removing secondary cache index %s failed: %v", path, rmErr)
+		return nil
+	}
+	if !opts.WarmOnOpen {
+		return nil
+	}
+	if err != nil {
+		s.cache.logger.Errorf("reading secondary cache index %s failed: %v", path, err)
+		return nil
+	}
+	entries, err := s.decodeIndex(buf, numShards)
+	if err != nil {
+		s.cache.logger.Infof("discarding secondary cache index %s: %v", path, err)
+		return nil
+	}
+
+	used := make([]bool, s.sizeInBlocks)
+	seen := make(map[logicalBlockID]struct{}, len(entries))
+	var blockBuf []byte
+	if opts.ValidateOnOpen {
+		blockBuf = make([]byte, s.bm.BlockSize())
+	}
+	valid := entries[:0]
+	var discarded int
+	for _, e := range entries {
+		if e.index < 0 || int64(e.index) >= s.sizeInBlocks || used[e.index] {
+			// The cache may have been resized.
+			discarded++
+			continue
+		}
+		if _, ok := seen[e.logical]; ok {
+			discarded++
+			continue
+		}
+		if opts.ValidateOnOpen {
+			if _, err := s.file.ReadAt(blockBuf, s.bm.BlockOffset(e.index)); err != nil || crc.New(blockBuf).Value() != e.checksum {
+				discarded++
+				continue
+			}
+		}
+		used[e.index] = true
+		seen[e.logical] = struct{}{}
+		valid = append(valid, e)
+	}
+	if discarded > 0 {
+		s.cache.logger.Infof("secondary cache index %s: discarded %d of %d blocks", path, discarded, len(entries))
+	}
+	return valid
+}
+
+// persistIndex writes the index file for the shard. It must only be called
+// once there are no in-flight writes to the shard.
+func (s *shard) persistIndex() error {
+	fs, path := s.fs, s.indexPath
+	s.mu.Lock()
+	entries := make([]indexEntry, 0, len(s.mu.where))
+	if s.mu.lruHead != invalidBlockIndex {
+		for b := s.mu.lruHead; ; {
+			if state := &s.mu.blocks[b]; state.lock != writeLockTaken {
+				entries = append(entries, indexEntry{
+					logical:  state.logical,
+					index:    b,
+					checksum: state.checksum,
+				})
+			}
+			b = s.lruNext(b)
+			if b == s.mu.lruHead {
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	// The cached data must be durable before the index referencing it.
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	f, err := fs.Create(tmpPath, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(s.encodeIndex(entries)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// We don't sync the directory: if the rename is lost, the cache starts
+	// empty on the next open.
+	return fs.Rename(tmpPath, path)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/crc"
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/vfs"
@@ -37,6 +38,7 @@ type Cache struct {
 
 	bm                blockMath
 	shardingBlockSize int64
+	persistence       PersistenceOptions
 
 	logger  base.Logger
 	metrics internalMetrics
@@ -115,7 +117,8 @@ const (
 )
 
 // Open opens a cache. If there is no existing cache at fsDir, a new one
-// is created.
+// is created. See PersistenceOptions for when existing cache contents are
+// reused.
 func Open(
 	fs vfs.FS,
 	logger base.Logger,
@@ -129,6 +132,7 @@ func Open(
 	shardingBlockSize int64,
 	sizeBytes int64,
 	numShards int,
+	persistence PersistenceOptions,
 ) (*Cache, error) {
 	if minSize := shardingBlockSize * int64(numShards); sizeBytes < minSize {
 		// Up the size so that we have one block per shard. In practice, this should
@@ -140,6 +144,7 @@ func Open(
 		logger:            logger,
 		bm:                makeBlockMath(blockSize),
 		shardingBlockSize: shardingBlockSize,
+		persistence:       persistence,
 	}
 	c.shards = make([]shard, numShards)
 	blocksPerShard := sizeBytes / int64(numShards) / int64(blockSize)
@@ -169,6 +174,12 @@ func (c *Cache) Close() error {
 
 	var retErr error
 	for i := range c.shards {
+		if c.persistence.PersistOnClose {
+			if err := c.shards[i].persistIndex(); err != nil {
+				// The cache will start empty on the next open.
+				c.logger.Errorf("persisting secondary cache index failed: %v", err)
+			}
+		}
 		if err := c.shards[i].close(); err != nil && retErr == nil {
 			retErr = err
 		}
@@ -375,7 +386,9 @@ func (c *Cache) getShard(fileNum base.DiskFileNum, ofs int64) *shard {
 
 type shard struct {
 	cache             *Cache
+	fs                vfs.FS
 	file              vfs.File
+	indexPath         string
 	sizeInBlocks      int64
 	bm                blockMath
 	shardingBlockSize int64
@@ -395,6 +408,9 @@ type shard struct {
 type cacheBlockState struct {
 	lock    lockState
 	logical logicalBlockID
+	// checksum is the checksum of the block contents. It is only computed when
+	// PersistenceOptions.PersistOnClose is set.
+	checksum uint32
 
 	// next is the next block in the LRU or free list (or invalidBlockIndex if it
 	// is the last block in the free list).
@@ -439,7 +455,9 @@ func (s *shard) init(
 ) error {
 	*s = shard{
 		cache:        cache,
+		fs:           fs,
 		sizeInBlocks: sizeInBlocks,
+		indexPath:    shardIndexPath(fs, fsDir, shardIdx),
 	}
 	if blockSize < 1024 || shardingBlockSize%int64(blockSize) != 0 {
 		return errors.Newf("invalid block size %d (must divide %d)", blockSize, shardingBlockSize)
@@ -457,15 +475,28 @@ func (s *shard) init(
 	}
 	s.file = file
 
-	// TODO(josh): Right now, the secondary cache is not persistent. All existing
-	// cache contents will be over-written, since all metadata is only stored in
-	// memory.
-	s.mu.where = make(whereMap)
+	// Unless the shard is warmed from an index written by a previous Close, all
+	// existing cache contents will be over-written.
+	entries := s.loadIndex(len(cache.shards), cache.persistence)
+	s.mu.where = make(whereMap, len(entries))
 	s.mu.blocks = make([]cacheBlockState, sizeInBlocks)
 	s.mu.lruHead = invalidBlockIndex
 	s.mu.freeHead = invalidBlockIndex
+	used := make([]bool, sizeInBlocks)
+	// Entries are ordered from most to least recently used.
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		s.mu.where[e.logical] = e.index
+		s.mu.blocks[e.index].logical = e.logical
+		s.mu.blocks[e.index].checksum = e.checksum
+		s.lruInsertFront(e.index)
+		used[e.index] = true
+	}
+	cache.metrics.count.Add(int64(len(entries)))
 	for i := range s.mu.blocks {
-		s.freePush(cacheBlockIndex(i))
+		if !used[i] {
+			s.freePush(cacheBlockIndex(i))
+		}
 	}
 
 	return nil
@@ -704,6 +735,11 @@ func (s *shard) set(fileNum base.DiskFileNum, p []byte, ofs int64) error {
 			writeSize = len(p[n:])
 		}
 
+		var checksum uint32
+		if s.cache.persistence.PersistOnClose {
+			checksum = crc.New(p[n : n+writeSize]).Value()
+		}
+
 		start := time.Now()
 		_, err := s.file.WriteAt(p[n:n+writeSize], writeAt)
 		s.cache.metrics.diskWriteLatency.Observe(float64(time.Since(start)))
@@ -716,7 +752,7 @@ func (s *shard) set(fileNum base.DiskFileNum, p []byte, ofs int64) error {
 			s.mu.Unlock()
 			return err
 		}
-		s.dropWriteLock(cacheBlockIdx)
+		s.dropWriteLock(cacheBlockIdx, checksum)
 		n += writeSize
 	}
 }
@@ -732,12 +768,13 @@ func (s *shard) dropReadLock(cacheBlockInd cacheBlockIndex) {
 }
 
 // Doesn't inline currently. This might be okay, but something to keep in mind.
-func (s *shard) dropWriteLock(cacheBlockInd cacheBlockIndex) {
+func (s *shard) dropWriteLock(cacheBlockInd cacheBlockIndex, checksum uint32) {
 	s.mu.Lock()
 	if invariants.Enabled && s.mu.blocks[cacheBlockInd].lock != writeLockTaken {
 		panic(fmt.Sprintf("unexpected lock state %v in dropWriteLock", s.mu.blocks[cacheBlockInd].lock))
 	}
 	s.mu.blocks[cacheBlockInd].lock = unlocked
+	s.mu.blocks[cacheBlockInd].checksum = checksum
 	s.mu.Unlock()
 }
 
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/datadriven"
+	"github.com/cockroachdb/errors/oserror"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/buildtags"
 	"github.com/cockroachdb/pebble/objstorage"
@@ -55,6 +56,7 @@ func TestSharedCache(t *testing.T) {
 				}
 				cache, err = sharedcache.Open(
 					fs, base.DefaultLogger, "", blockSize, int64(shardingBlockSize), int64(size), numShards,
+					sharedcache.PersistenceOptions{},
 				)
 				require.NoError(t, err)
 				return fmt.Sprintf("initialized with block-size=%d size=%d num-shards=%d", blockSize, size, numShards)
@@ -143,7 +145,7 @@ func TestSharedCacheRandomized(t *testing.T) {
 					numShards := rng.IntN(maxShards) + 1
 					cacheSize := shardingBlockSize * int64(numShards) // minimum allowed cache size
 
-					cache, err := sharedcache.Open(fs, base.DefaultLogger, "", blockSize, shardingBlockSize, cacheSize, numShards)
+					cache, err := sharedcache.Open(fs, base.DefaultLogger, "", blockSize, shardingBlockSize, cacheSize, numShards, sharedcache.PersistenceOptions{})
 					require.NoError(t, err)
 					defer cache.Close()
 
@@ -262,3 +264,91 @@ func tryParseBytesArg(t testing.TB, d *datadriven.TestData, argName string) (val
 
 	return res * factor, true
 }
+
+func TestSharedCachePersistence(t *testing.T) {
+	ctx := context.Background()
+	fs := vfs.NewMem()
+	provider, err := objstorageprovider.Open(objstorageprovider.DefaultSettings(fs, ""))
+	require.NoError(t, err)
+	defer provider.Close()
+
+	const blockSize = 32 << 10
+	const shardingBlockSize = 1 << 20
+	const size = 4 * blockSize
+	objData := make([]byte, size)
+	wrote := make([]byte, size)
+	for i := range objData {
+		objData[i] = byte(i)
+		wrote[i] = byte(i)
+	}
+	writable, _, err := provider.Create(ctx, base.FileTypeTable, base.DiskFileNum(1), objstorage.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, writable.Write(wrote))
+	require.NoError(t, writable.Finish())
+	readable, err := provider.OpenForReading(ctx, base.FileTypeTable, base.DiskFileNum(1), objstorage.OpenOptions{})
+	require.NoError(t, err)
+	defer readable.Close()
+
+	persist := sharedcache.PersistenceOptions{
+		PersistOnClose: true,
+		WarmOnOpen:     true,
+		ValidateOnOpen: true,
+	}
+	open := func(numShards int, opts sharedcache.PersistenceOptions) *sharedcache.Cache {
+		cache, err := sharedcache.Open(
+			fs, base.DefaultLogger, "", blockSize, shardingBlockSize, shardingBlockSize*int64(numShards), numShards, opts)
+		require.NoError(t, err)
+		return cache
+	}
+	read := func(cache *sharedcache.Cache) {
+		got := make([]byte, size)
+		require.NoError(t, cache.ReadAt(ctx, base.DiskFileNum(1), got, 0, readable, readable.Size(), sharedcache.ReadFlags{}))
+		require.Equal(t, objData, got)
+		cache.WaitForWritesToComplete()
+	}
+
+	// Populate the cache and close it, persisting the index.
+	cache := open(1, persist)
+	read(cache)
+	require.Equal(t, int64(4), cache.Metrics().Count)
+	require.NoError(t, cache.Close())
+	_, err = fs.Stat("SHARED-CACHE-000.index")
+	require.NoError(t, err)
+
+	// Reopening the cache warms it from the index, which is then removed.
+	cache = open(1, persist)
+	require.Equal(t, int64(4), cache.Metrics().Count)
+	_, err = fs.Stat("SHARED-CACHE-000.index")
+	require.True(t, oserror.IsNotExist(err))
+	read(cache)
+	require.Equal(t, int64(1), cache.Metrics().ReadsWithFullHit)
+	require.NoError(t, cache.Close())
+
+	// Corrupt a cached block; validation discards it. The free list hands out
+	// the last cache block first, so the first logical block is cached there.
+	f, err := fs.OpenReadWrite("SHARED-CACHE-000", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("corruption"), shardingBlockSize-blockSize)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	cache = open(1, persist)
+	require.Equal(t, int64(3), cache.Metrics().Count)
+	read(cache)
+	require.Equal(t, int64(1), cache.Metrics().ReadsWithNoHit)
+	require.NoError(t, cache.Close())
+
+	// Without WarmOnOpen, the index is ignored (and removed).
+	cache = open(1, sharedcache.PersistenceOptions{})
+	require.Zero(t, cache.Metrics().Count)
+	require.NoError(t, cache.Close())
+	_, err = fs.Stat("SHARED-CACHE-000.index")
+	require.True(t, oserror.IsNotExist(err))
+
+	// An index written with a different shard count is discarded.
+	cache = open(1, persist)
+	read(cache)
+	require.NoError(t, cache.Close())
+	cache = open(2, persist)
+	require.Zero(t, cache.Metrics().Count)
+	require.NoError(t, cache.Close())
+}
@@ -32,7 +32,13 @@ func (p *provider) vfsOpenForReading(
 		}
 		return nil, err
 	}
-	return newFileReadable(file, p.st.FS, p.st.Local.ReadaheadConfig, filename)
+	r, err := newFileReadable(file, p.st.FS, p.st.Local.ReadaheadConfig, filename)
+	if err != nil {
+		return nil, err
+	}
+	r.readaheadPolicy = p.st.ReadaheadPolicy
+	r.dropCompactionReads = p.st.Local.DropCompactionReadsFromPageCache
+	return r, nil
 }
 
 func (p *provider) vfsCreate(
@@ -40,6 +46,7 @@ func (p *provider) vfsCreate(
 	fileType base.FileType,
 	fileNum base.DiskFileNum,
 	category vfs.DiskWriteCategory,
+	dropAfterSync bool,
 ) (objstorage.Writable, objstorage.ObjectMetadata, error) {
 	filename := p.vfsPath(fileType, fileNum)
 	file, err := p.st.FS.Create(filename, category)
@@ -48,13 +55,15 @@ func (p *provider) vfsCreate(
 	}
 	file = vfs.NewSyncingFile(file, vfs.SyncingFileOptions{
 		NoSyncOnClose: p.st.NoSyncOnClose,
-		BytesPerSync:  p.st.BytesPerSync,
+		BytesPerSync:  int(p.bytesPerSync.Load()),
 	})
 	meta := objstorage.ObjectMetadata{
 		DiskFileNum: fileNum,
 		FileType:    fileType,
 	}
-	return newFileBufferedWritable(file), meta, nil
+	w := newFileBufferedWritable(file)
+	w.dropAfterSync = dropAfterSync
+	return w, meta, nil
 }
 
 func (p *provider) vfsRemove(fileType base.FileType, fileNum base.DiskFileNum) error {
@@ -26,6 +26,10 @@ type fileReadable struct {
 	size int64
 
 	readaheadConfig *ReadaheadConfig
+	readaheadPolicy ReadaheadPolicy
+	// dropCompactionReads is true if data read through handles set up for
+	// compaction should be dropped from the OS page cache once read.
+	dropCompactionReads bool
 
 	// The following fields are used to possibly open the file again using the
 	// sequential reads option (see vfsReadHandle).
@@ -99,6 +103,10 @@ type vfsReadHandle struct {
 	// OS-level readahead. Once this is non-nil, the other variables in
 	// readaheadState don't matter much as we defer to OS-level readahead.
 	sequentialFile vfs.File
+
+	// dropAfterRead is true if data read through the handle should be dropped
+	// from the OS page cache once read.
+	dropAfterRead bool
 }
 
 var _ objstorage.ReadHandle = (*vfsReadHandle)(nil)
@@ -121,7 +129,7 @@ var readHandlePool = sync.Pool{
 func (rh *vfsReadHandle) init(r *fileReadable) {
 	*rh = vfsReadHandle{
 		r:             r,
-		rs:            makeReadaheadState(fileMaxReadaheadSize),
+		rs:            makeReadaheadState(fileMaxReadaheadSize, r.readaheadPolicy, FileTemperatureHot),
 		readaheadMode: r.readaheadConfig.Speculative(),
 	}
 }
@@ -145,28 +153,36 @@ func (rh *vfsReadHandle) ReadAt(_ context.Context, p []byte, offset int64) error
 		if invariants.Enabled && err == nil && n != len(p) {
 			panic("short read")
 		}
+		if rh.dropAfterRead {
+			_ = vfs.AdviseDontNeed(rh.sequentialFile, offset, int64(n))
+		}
 		return err
 	}
 	if rh.readaheadMode != NoReadahead {
-		if readaheadSize := rh.rs.maybeReadahead(offset, int64(len(p))); readaheadSize > 0 {
-			if rh.readaheadMode == FadviseSequential && readaheadSize >= fileMaxReadaheadSize {
-				// We've reached the maximum readahead size. Beyond this point, rely on
-				// OS-level readahead.
-				rh.switchToOSReadahead()
-			} else {
-				_ = rh.r.file.Prefetch(offset, readaheadSize)
-			}
+		d := rh.rs.maybeReadahead(offset, int64(len(p)))
+		if rh.readaheadMode == FadviseSequential && d.Sequential {
+			// Beyond this point, rely on OS-level readahead. By default, this
+			// happens once we've reached the maximum readahead size.
+			rh.switchToOSReadahead()
+		} else if d.Size > 0 {
+			_ = rh.r.file.Prefetch(offset, d.Size)
 		}
 	}
 	n, err := rh.r.file.ReadAt(p, offset)
 	if invariants.Enabled && err == nil && n != len(p) {
 		panic("short read")
 	}
+	if rh.dropAfterRead {
+		_ = vfs.AdviseDontNeed(rh.r.file, offset, int64(n))
+	}
 	return err
 }
 
 // SetupForCompaction is part of the objstorage.ReadHandle interface.
 func (rh *vfsReadHandle) SetupForCompaction() {
+	// Compactions read their inputs once; avoid polluting the OS page cache
+	// with them if configured.
+	rh.dropAfterRead = rh.r.dropCompactionReads
 	rh.readaheadMode = rh.r.readaheadConfig.Informed()
 	if rh.readaheadMode == FadviseSequential {
 		rh.switchToOSReadahead()
@@ -198,6 +214,20 @@ func (rh *vfsReadHandle) RecordCacheHit(_ context.Context, offset, size int64) {
 	rh.rs.recordCacheHit(offset, size)
 }
 
+// SetReadaheadLevel informs the ReadHandle of the LSM level of the table it
+// reads, plus one (zero if unknown), which is provided to the ReadaheadPolicy.
+// It is a no-op for ReadHandles that don't perform readahead.
+func SetReadaheadLevel(rh objstorage.ReadHandle, levelPlusOne uint8) {
+	switch rh := rh.(type) {
+	case *vfsReadHandle:
+		rh.rs.levelPlusOne = levelPlusOne
+	case *PreallocatedReadHandle:
+		rh.rs.levelPlusOne = levelPlusOne
+	case *remoteReadHandle:
+		rh.readAheadState.levelPlusOne = levelPlusOne
+	}
+}
+
 // TestingCheckMaxReadahead returns true if the ReadHandle has switched to
 // OS-level read-ahead.
 func TestingCheckMaxReadahead(rh objstorage.ReadHandle) bool {
@@ -20,6 +20,9 @@ func NewFileWritable(file vfs.File) objstorage.Writable {
 type fileBufferedWritable struct {
 	file vfs.File
 	bw   *bufio.Writer
+	// dropAfterSync is true if the data of the file should be dropped from the
+	// OS page cache once it is synced.
+	dropAfterSync bool
 }
 
 var _ objstorage.Writable = (*fileBufferedWritable)(nil)
@@ -53,6 +56,10 @@ func (w *fileBufferedWritable) Finish() error {
 	if err == nil {
 		err = w.file.Sync()
 	}
+	if err == nil && w.dropAfterSync {
+		// The data is durable, so its pages are clean and can be dropped.
+		_ = vfs.AdviseDontNeed(w.file, 0, 0)
+	}
 	err = firstError(err, w.file.Close())
 	w.bw = nil
 	w.file = nil
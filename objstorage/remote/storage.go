@@ -48,6 +48,10 @@ const (
 	CreateOnSharedLower
 	// CreateOnSharedAll denotes the creation of all sstables on shared storage.
 	CreateOnSharedAll
+	// CreateOnSharedTiered denotes the creation of all sstables on local
+	// storage. Shared storage is available, but sstables are only moved onto it
+	// by remote tiering (see Options.Experimental.RemoteTieringMinAge).
+	CreateOnSharedTiered
 )
 
 // ShouldCreateShared returns whether new table files at the specified level
@@ -56,7 +60,7 @@ func ShouldCreateShared(strategy CreateOnSharedStrategy, level int) bool {
 	switch strategy {
 	case CreateOnSharedAll:
 		return true
-	case CreateOnSharedNone:
+	case CreateOnSharedNone, CreateOnSharedTiered:
 		return false
 	case CreateOnSharedLower:
 		return level >= SharedLevelsStart
@@ -48,6 +48,10 @@ type cleanupManager struct {
 		completedJobs          int
 		completedJobsCond      sync.Cond
 		jobsQueueWarningIssued bool
+		// queue contains the jobs that were enqueued but not yet completed.
+		queue []*cleanupJob
+		// running is the job in queue that is being processed, if any.
+		running *cleanupJob
 	}
 }
 
@@ -74,6 +78,7 @@ type obsoleteFile struct {
 type cleanupJob struct {
 	jobID         JobID
 	obsoleteFiles []obsoleteFile
+	enqueuedAt    time.Time
 }
 
 // openCleanupManager creates a cleanupManager and starts its background goroutine.
@@ -123,6 +128,7 @@ func (cm *cleanupManager) EnqueueJob(jobID JobID, obsoleteFiles []obsoleteFile)
 	job := &cleanupJob{
 		jobID:         jobID,
 		obsoleteFiles: obsoleteFiles,
+		enqueuedAt:    time.Now(),
 	}
 
 	// Report deleted bytes to the pacer, which can use this data to potentially
@@ -141,6 +147,7 @@ func (cm *cleanupManager) EnqueueJob(jobID JobID, obsoleteFiles []obsoleteFile)
 
 	cm.mu.Lock()
 	cm.mu.totalJobs++
+	cm.mu.queue = append(cm.mu.queue, job)
 	cm.maybeLogLocked()
 	cm.mu.Unlock()
 
@@ -170,6 +177,9 @@ func (cm *cleanupManager) mainLoop() {
 	// Use a token bucket with 1 token / second refill rate and 1 token burst.
 	tb.Init(1.0, 1.0)
 	for job := range cm.jobsCh {
+		cm.mu.Lock()
+		cm.mu.running = job
+		cm.mu.Unlock()
 		for _, of := range job.obsoleteFiles {
 			switch of.fileType {
 			case base.FileTypeTable:
@@ -190,6 +200,8 @@ func (cm *cleanupManager) mainLoop() {
 		}
 		cm.mu.Lock()
 		cm.mu.completedJobs++
+		cm.mu.queue = slices.DeleteFunc(cm.mu.queue, func(j *cleanupJob) bool { return j == job })
+		cm.mu.running = nil
 		cm.mu.completedJobsCond.Broadcast()
 		cm.maybeLogLocked()
 		cm.mu.Unlock()
@@ -485,9 +497,11 @@ type fileInfo = base.FileInfo
 // d.mu must be held when calling this. The function will release and re-aquire the mutex.
 //
 // Does nothing if file deletions are disabled (see disableFileDeletions). A
-// cleanup job will be scheduled when file deletions are re-enabled.
+// cleanup job will be scheduled when file deletions are re-enabled. Also
+// does nothing in read-only mode: the WALs that were replayed by Open are
+// still needed, since their contents weren't flushed.
 func (d *DB) deleteObsoleteFiles(jobID JobID) {
-	if d.mu.disableFileDeletions > 0 {
+	if d.mu.disableFileDeletions > 0 || d.opts.ReadOnly {
 		return
 	}
 	_, noRecycle := d.opts.Cleaner.(base.NeedsFileContents)
@@ -24,6 +24,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
 	"github.com/cockroachdb/pebble/internal/constants"
+	"github.com/cockroachdb/pebble/internal/hotkeys"
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/manifest"
@@ -87,6 +88,10 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	if err := opts.Validate(); err != nil {
 		return nil, err
 	}
+	// Route the events through eventListeners, so that listeners can be
+	// attached to the open DB (see DB.AddEventListener).
+	eventListeners := newDynamicEventListener(*opts.EventListener)
+	opts.EventListener = eventListeners.eventListener()
 	if opts.LoggerAndTracer == nil {
 		opts.LoggerAndTracer = &base.LoggerWithNoopTracer{Logger: opts.Logger}
 	} else {
@@ -156,6 +161,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	}()
 
 	noFormatVersionMarker := formatVersion == FormatDefault
+	errorIfNotPristine := opts.ErrorIfNotPristine
 	if noFormatVersionMarker {
 		// We will initialize the store at the minimum possible format, then upgrade
 		// the format to the desired one. This helps test the format upgrade code.
@@ -199,6 +205,25 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		}
 	}()
 
+	// A RocksDB DB has neither a format version marker nor a manifest marker:
+	// its current manifest is named by the CURRENT file. It can be opened in
+	// read-only mode, for example to migrate its data to a Pebble DB.
+	var fromRocksDB bool
+	if !manifestExists && noFormatVersionMarker {
+		manifestFileNum, fromRocksDB, err = findRocksDBManifest(opts.FS, dirname, ls)
+		if err != nil {
+			return nil, errors.Wrapf(err, "pebble: database %q", dirname)
+		}
+		if fromRocksDB {
+			if !opts.ReadOnly {
+				return nil, errors.Newf(
+					"pebble: database %q was written by RocksDB and can only be opened in read-only mode", dirname)
+			}
+			manifestExists = true
+			opts.ErrorIfNotPristine = errorIfNotPristine
+		}
+	}
+
 	// Atomic markers may leave behind obsolete files if there's a crash
 	// mid-update. Clean these up if we're not in read-only mode.
 	if !opts.ReadOnly {
@@ -219,6 +244,8 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		cacheHandle:         opts.Cache.NewHandle(),
 		dirname:             dirname,
 		opts:                opts,
+		eventListeners:      eventListeners,
+		fromRocksDB:         fromRocksDB,
 		cmp:                 opts.Comparer.Compare,
 		equal:               opts.Comparer.Equal,
 		merge:               opts.Merger.Merge,
@@ -232,6 +259,26 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	}
 	d.mu.versions = &versionSet{}
 	d.diskAvailBytes.Store(math.MaxUint64)
+	d.writeController = opts.Experimental.WriteController
+	if d.writeController == nil {
+		d.writeController = defaultWriteController{d: d}
+	}
+	d.writeAdmission.cond.L = &d.writeAdmission.Mutex
+	if opts.Experimental.CompactionConcurrencyAutotuning.enabled() && !opts.ReadOnly {
+		d.compactionAutotuner = newCompactionAutotuner(opts.Experimental.CompactionConcurrencyAutotuning)
+	}
+	// Allow DB.SetOptions and autotuning to override the compaction
+	// concurrency.
+	maxConcurrentCompactions := opts.MaxConcurrentCompactions
+	opts.MaxConcurrentCompactions = func() int {
+		if n := d.maxConcurrentCompactions.Load(); n > 0 {
+			return int(n)
+		}
+		if d.compactionAutotuner != nil {
+			return d.compactionAutotuner.concurrency()
+		}
+		return maxConcurrentCompactions()
+	}
 
 	defer func() {
 		// If an error or panic occurs during open, attempt to release the manually
@@ -291,6 +338,18 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	d.mu.formatVers.marker = formatVersionMarker
 
 	d.timeNow = time.Now
+	d.tableAccess.timeNow = func() time.Time { return d.timeNow() }
+	d.iters.init(opts, func() time.Time { return d.timeNow() })
+	if h := opts.Experimental.HotKeys; h.TopK > 0 {
+		d.hotKeys = hotkeys.New(h.TopK, h.SampleRate)
+	}
+	if c := opts.Experimental.ScanCache; c.Size > 0 {
+		d.scanCache = newScanCache(d.cmp, c, d.mu.versions.visibleSeqNum.Load)
+	}
+	if v := opts.Experimental.ExternalValues; v.Store != nil {
+		d.externalValues = newExternalValues(v)
+	}
+	d.idempotency.init(opts.IdempotencyWindow)
 	d.openedAt = d.timeNow()
 
 	d.mu.Lock()
@@ -308,15 +367,32 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		BytesPerSync:        opts.BytesPerSync,
 	}
 	providerSettings.Local.ReadaheadConfig = opts.Local.ReadaheadConfig
+	providerSettings.ReadaheadPolicy = opts.Experimental.ReadaheadPolicy
+	providerSettings.Local.DropCompactionReadsFromPageCache = opts.Local.DropCompactionReadsFromPageCache
+	providerSettings.Local.DropCompactionWritesFromPageCache = opts.Local.DropCompactionWritesFromPageCache
 	providerSettings.Remote.StorageFactory = opts.Experimental.RemoteStorage
 	providerSettings.Remote.CreateOnShared = opts.Experimental.CreateOnShared
 	providerSettings.Remote.CreateOnSharedLocator = opts.Experimental.CreateOnSharedLocator
 	providerSettings.Remote.CacheSizeBytes = opts.Experimental.SecondaryCacheSizeBytes
+	providerSettings.Remote.CachePersistence = opts.Experimental.SecondaryCachePersistence
+	providerSettings.Remote.CircuitBreaker = opts.Experimental.RemoteReadCircuitBreaker
+	providerSettings.Remote.OnCircuitBreakerChange = func(s objstorage.RemoteBreakerState) {
+		opts.EventListener.RemoteStorageBreaker(RemoteStorageBreakerInfo{
+			Locator:             s.Locator,
+			Tripped:             s.Tripped,
+			ConsecutiveFailures: s.ConsecutiveFailures,
+			Err:                 s.LastError,
+		})
+	}
 
-	d.objProvider, err = objstorageprovider.Open(providerSettings)
+	provider, err := objstorageprovider.Open(providerSettings)
 	if err != nil {
 		return nil, err
 	}
+	// The tables stored inline in the MANIFEST must be served even if
+	// Options.Experimental.InlineTableMaxSize is no longer set.
+	d.inlineTables = newInlineTableProvider(provider)
+	d.objProvider = d.inlineTables
 
 	if !manifestExists {
 		// DB does not exist.
@@ -338,6 +414,13 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			dirname, d.objProvider, opts, manifestFileNum, manifestMarker, d.FormatMajorVersion, &d.mu.Mutex); err != nil {
 			return nil, err
 		}
+		// Remove the local copies of inline tables left behind by a crash
+		// between the commit of a flush and the removal of its local copies.
+		if !opts.ReadOnly {
+			if err := d.inlineTables.removeAllLocalCopies(); err != nil {
+				return nil, err
+			}
+		}
 		if opts.ErrorIfNotPristine {
 			liveFileNums := make(map[base.DiskFileNum]struct{})
 			d.mu.versions.addLiveFileNums(liveFileNums)
@@ -368,6 +451,9 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		BytesPerSync:         opts.WALBytesPerSync,
 		PreallocateSize:      d.walPreallocateSize,
 		MinSyncInterval:      opts.WALMinSyncInterval,
+		SyncInterval:         opts.WALSyncInterval,
+		MaxGroupCommitSize:   opts.WALMaxGroupCommitSize,
+		OnSynced:             d.durability.onSynced,
 		FsyncLatency:         d.mu.log.metrics.fsyncLatency,
 		QueueSemChan:         d.commit.logSyncQSem,
 		Logger:               opts.Logger,
@@ -376,10 +462,17 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 	}
 	if opts.WALFailover != nil {
 		walOpts.Secondary = opts.WALFailover.Secondary
+		walOpts.AdditionalSecondaries = opts.WALFailover.AdditionalSecondaries
 		walOpts.FailoverOptions = opts.WALFailover.FailoverOptions
 		walOpts.FailoverWriteAndSyncLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
 			Buckets: FsyncLatencyBuckets,
 		})
+		walOpts.DirFsyncLatencies = make([]prometheus.Histogram, len(walOpts.Dirs()))
+		for i := range walOpts.DirFsyncLatencies {
+			walOpts.DirFsyncLatencies[i] = prometheus.NewHistogram(prometheus.HistogramOpts{
+				Buckets: FsyncLatencyBuckets,
+			})
+		}
 	}
 	walDirs := append(walOpts.Dirs(), opts.WALRecoveryDirs...)
 	wals, err := wal.Scan(walDirs...)
@@ -413,6 +506,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		defer opts.FileCache.Unref()
 	}
 	d.fileCache = opts.FileCache.newHandle(d.cacheHandle, d.objProvider, d.opts.LoggerAndTracer, d.opts.MakeReaderOptions(), d.reportCorruption)
+	d.fileCache.ioRateLimiter = d.opts.IORateLimiter
 	d.newIters = d.fileCache.newIters
 	d.tableNewRangeKeyIter = tableNewRangeKeyIter(d.newIters)
 
@@ -433,6 +527,7 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		}
 		return meta.IsRemote() && meta.Remote.CleanupMethod == objstorage.SharedNoCleanup
 	})
+	d.mu.annotators.userProperties = makeUserPropertyAnnotators(d.opts.UserPropertyAggregators)
 
 	var previousOptionsFileNum base.DiskFileNum
 	var previousOptionsFilename string
@@ -467,6 +562,17 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			}
 		}
 	}
+	tempDirname := opts.TempDir
+	if tempDirname == "" {
+		tempDirname = opts.FS.PathJoin(dirname, defaultTempDirname)
+	}
+	d.tempFiles.init(opts.TempFS, tempDirname)
+	if !d.opts.ReadOnly {
+		if err := d.tempFiles.removeLeftovers(); err != nil {
+			return nil, err
+		}
+	}
+
 	if n := len(wals); n > 0 {
 		// Don't reuse any obsolete file numbers to avoid modifying an
 		// ingested sstable's original external file.
@@ -490,6 +596,22 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		if err := opts.CheckCompatibility(previousOptions); err != nil {
 			return nil, err
 		}
+		if d.opts.ReadOnly {
+			// The OPTIONS file isn't rewritten in read-only mode: checkpoints
+			// copy the most recent one.
+			d.optionsFileNum = previousOptionsFileNum
+		}
+	}
+
+	// Load the samples of the visible sequence number and the named snapshots
+	// before replaying the WAL, so that the flushes of the replayed memtables
+	// retain the history.
+	if d.opts.Experimental.SeqNumTime.SampleInterval > 0 {
+		d.loadSeqNumTimesLocked()
+	}
+	d.openNamedSnapshotsLocked()
+	if d.id, err = d.loadOrCreateID(); err != nil {
+		return nil, err
 	}
 
 	// Replay any newer log files than the ones named in the manifest.
@@ -520,6 +642,9 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 			d.mu.versions.logSeqNum.Store(maxSeqNum)
 		}
 	}
+	if d.opts.ReadOnly {
+		d.mu.log.readOnlyWALs = replayWALs
+	}
 	if d.mu.mem.mutable == nil {
 		// Recreate the mutable memtable if replayWAL got rid of it.
 		var entry *flushableEntry
@@ -527,6 +652,12 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 		d.mu.mem.queue = append(d.mu.mem.queue, entry)
 	}
 	d.mu.versions.visibleSeqNum.Store(d.mu.versions.logSeqNum.Load())
+	d.durability.init(d.mu.versions.logSeqNum.Load())
+	// Samples of sequence numbers that weren't recovered, because the WAL
+	// wasn't synced, don't describe a state of the DB.
+	d.dropSeqNumTimesLocked(func(s seqNumTimeSample) bool {
+		return s.seqNum > d.mu.versions.visibleSeqNum.Load()
+	})
 
 	// Register with the CompactionScheduler before calling
 	// d.maybeScheduleFlush, since completion of the flush can trigger
@@ -626,6 +757,22 @@ func Open(dirname string, opts *Options) (db *DB, err error) {
 
 	d.maybeScheduleFlush()
 	d.maybeScheduleCompaction()
+	if d.opts.Experimental.RemoteTieringMinAge > 0 && !d.opts.ReadOnly {
+		d.compactionSchedulers.Add(1)
+		go d.remoteTieringLoop()
+	}
+	if d.compactionAutotuner != nil {
+		d.compactionSchedulers.Add(1)
+		go d.compactionAutotuneLoop()
+	}
+	if d.opts.Preset == CachePreset && d.opts.CacheMode.TTL > 0 && !d.opts.ReadOnly {
+		d.compactionSchedulers.Add(1)
+		go d.cacheEvictionLoop()
+	}
+	if d.opts.Experimental.SeqNumTime.SampleInterval > 0 && !d.opts.ReadOnly {
+		d.compactionSchedulers.Add(1)
+		go d.seqNumTimeLoop()
+	}
 
 	// Note: this is a no-op if invariants are disabled or race is enabled.
 	//
@@ -682,12 +829,14 @@ func prepareAndOpenDirs(
 			f.Close()
 		}
 		if opts.WALFailover != nil {
-			secondary := opts.WALFailover.Secondary
-			f, err := mkdirAllAndSyncParents(secondary.FS, secondary.Dirname)
-			if err != nil {
-				return "", nil, err
+			secondaries := append([]wal.Dir{opts.WALFailover.Secondary}, opts.WALFailover.AdditionalSecondaries...)
+			for _, secondary := range secondaries {
+				f, err := mkdirAllAndSyncParents(secondary.FS, secondary.Dirname)
+				if err != nil {
+					return "", nil, err
+				}
+				f.Close()
 			}
-			f.Close()
 		}
 	}
 
@@ -823,9 +972,11 @@ func (d *DB) replayIngestedFlushable(
 		if err != nil {
 			return nil, errors.Wrap(err, "pebble: error when opening flushable ingest files")
 		}
-		// NB: ingestLoad1 will close readable.
+		// NB: ingestLoad1 will close readable. The tables were validated when
+		// they were ingested, and adopted tables may have non-zero sequence
+		// numbers (see DB.AdoptFiles).
 		meta[i], lastRangeKey, err = ingestLoad1(context.TODO(), d.opts, d.FormatMajorVersion(),
-			readable, d.cacheHandle, base.PhysicalTableFileNum(n), disableRangeKeyChecks())
+			readable, d.cacheHandle, base.PhysicalTableFileNum(n), disableRangeKeyChecks(), true /* allowSeqNums */)
 		if err != nil {
 			return nil, errors.Wrap(err, "pebble: error when loading flushable ingest files")
 		}
@@ -895,7 +1046,9 @@ func (d *DB) replayWAL(
 		if d.mu.mem.mutable == mem {
 			d.mu.mem.mutable = nil
 		}
-		entry.flushForced = !d.opts.ReadOnly
+		if !d.opts.ReadOnly {
+			entry.forceFlush(FlushReasonWALReplay)
+		}
 		var logSize uint64
 		mergedOffset := offset.Physical + offset.PreviousFilesBytes
 		if mergedOffset >= lastFlushOffset {
@@ -982,11 +1135,22 @@ func (d *DB) replayWAL(
 		// which is used below.
 		b = Batch{}
 		b.db = d
-		b.SetRepr(buf.Bytes())
+		if d.fromRocksDB {
+			if err := checkRocksDBBatch(buf.Bytes(), base.DiskFileNum(ll.Num)); err != nil {
+				return nil, 0, err
+			}
+		}
+		if err := b.SetRepr(buf.Bytes()); err != nil {
+			return nil, 0, base.CorruptionErrorf("pebble: corrupt wal %s (offset %s): %v",
+				errors.Safe(base.DiskFileNum(ll.Num)), offset, err)
+		}
 		seqNum := b.SeqNum()
 		maxSeqNum = seqNum + base.SeqNum(b.Count())
 		keysReplayed += int64(b.Count())
 		batchesReplayed++
+		if err := d.idempotency.recordBatch(&b, seqNum); err != nil {
+			return nil, 0, err
+		}
 		{
 			br := b.Reader()
 			if kind, _, _, ok, err := br.Next(); err != nil {
@@ -1060,6 +1224,30 @@ func (d *DB) replayWAL(
 	return flushableIngests, maxSeqNum, err
 }
 
+// checkRocksDBBatch returns an error if the batch, read from the WAL of a
+// RocksDB DB, contains records that Pebble doesn't support. RocksDB assigns
+// some of the kinds that Pebble uses for its own records (e.g. ingestions and
+// range keys) to other records (e.g. column families, transactions and wide
+// columns), so these records can't be replayed.
+func checkRocksDBBatch(repr []byte, logNum base.DiskFileNum) error {
+	for r := batchrepr.Read(repr); ; {
+		kind, _, _, ok, err := r.Next()
+		if err != nil {
+			return errors.Wrapf(err, "pebble: RocksDB WAL %s", logNum)
+		}
+		if !ok {
+			return nil
+		}
+		switch kind {
+		case InternalKeyKindDelete, InternalKeyKindSet, InternalKeyKindMerge, InternalKeyKindLogData,
+			InternalKeyKindSingleDelete, InternalKeyKindRangeDelete:
+		default:
+			return errors.Newf("pebble: RocksDB WAL %s contains a record of unsupported kind %d; "+
+				"flush the RocksDB memtables before opening the DB", logNum, errors.Safe(int(kind)))
+		}
+	}
+}
+
 func readOptionsFile(opts *Options, path string) (string, error) {
 	f, err := opts.FS.Open(path)
 	if err != nil {
@@ -1309,3 +1497,13 @@ func (l walEventListenerAdaptor) LogCreated(ci wal.CreateInfo) {
 	}
 	l.l.WALCreated(wci)
 }
+
+func (l walEventListenerAdaptor) DirSwitched(dsi wal.DirSwitchInfo) {
+	l.l.WALFailover(WALFailoverInfo{
+		FromDirIndex: dsi.From,
+		ToDirIndex:   dsi.To,
+		FromDir:      dsi.FromDirname,
+		ToDir:        dsi.ToDirname,
+		Reason:       dsi.Reason,
+	})
+}
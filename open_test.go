@@ -31,12 +31,14 @@ import (
 	"github.com/cockroachdb/datadriven"
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/metamorphic"
+	"github.com/cockroachdb/pebble/batchrepr"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
 	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/record"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/vfs/atomicfs"
@@ -329,10 +331,11 @@ func TestNewDBFilenames(t *testing.T) {
 	versions := map[FormatMajorVersion][]string{
 		internalFormatNewest: {
 			"000002.log",
+			"DBID",
 			"LOCK",
 			"MANIFEST-000001",
 			"OPTIONS-000003",
-			"marker.format-version.000008.021",
+			"marker.format-version.000014.027",
 			"marker.manifest.000001.MANIFEST-000001",
 		},
 	}
@@ -671,6 +674,145 @@ func TestOpenReadOnly(t *testing.T) {
 	}
 }
 
+// TestOpenRocksDB opens a directory laid out like the directory of a RocksDB
+// DB: the manifest is named by the CURRENT file, the manifest and the OPTIONS
+// file have the RocksDB records and sections, and there are no markers.
+func TestOpenRocksDB(t *testing.T) {
+	mem := vfs.NewMem()
+	writeFile := func(name string, data []byte) {
+		f, err := mem.Create(name, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		_, err = f.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+	writeRecords := func(name string, records ...[]byte) {
+		f, err := mem.Create(name, vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		w := record.NewWriter(f)
+		for _, r := range records {
+			rw, err := w.Next()
+			require.NoError(t, err)
+			_, err = rw.Write(r)
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+		require.NoError(t, f.Close())
+	}
+
+	// 000004.sst holds a@1 and b@2.
+	sstFile, err := mem.Create("000004.sst", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewRawWriter(objstorageprovider.NewFileWritable(sstFile), sstable.WriterOptions{
+		TableFormat: sstable.TableFormatRocksDBv2,
+	})
+	smallest := base.MakeInternalKey([]byte("a"), 1, InternalKeyKindSet)
+	largest := base.MakeInternalKey([]byte("b"), 2, InternalKeyKindSet)
+	require.NoError(t, w.Add(smallest, []byte("a1"), false /* forceObsolete */))
+	require.NoError(t, w.Add(largest, []byte("b2"), false /* forceObsolete */))
+	require.NoError(t, w.Close())
+	sstInfo, err := mem.Stat("000004.sst")
+	require.NoError(t, err)
+
+	// The manifest records are encoded as RocksDB encodes them.
+	appendBytes := func(buf, b []byte) []byte {
+		return append(binary.AppendUvarint(buf, uint64(len(b))), b...)
+	}
+	appendKey := func(buf []byte, k InternalKey) []byte {
+		enc := make([]byte, k.Size())
+		k.Encode(enc)
+		return appendBytes(buf, enc)
+	}
+	var edit1, edit2 []byte
+	edit1 = appendBytes(binary.AppendUvarint(edit1, 1<<13+1), []byte("rocksdb-db-id")) // DB ID
+	edit1 = appendBytes(binary.AppendUvarint(edit1, 1), []byte(DefaultComparer.Name))  // comparator
+	edit2 = binary.AppendUvarint(edit2, 103)                                           // new file 4
+	edit2 = binary.AppendUvarint(edit2, 6)                                             // level
+	edit2 = binary.AppendUvarint(edit2, 4)                                             // file number
+	edit2 = binary.AppendUvarint(edit2, uint64(sstInfo.Size()))
+	edit2 = appendKey(edit2, smallest)
+	edit2 = appendKey(edit2, largest)
+	edit2 = binary.AppendUvarint(edit2, 1)                            // smallest sequence number
+	edit2 = binary.AppendUvarint(edit2, 2)                            // largest sequence number
+	edit2 = appendBytes(binary.AppendUvarint(edit2, 13), []byte{1})   // epoch number
+	edit2 = binary.AppendUvarint(edit2, 1)                            // terminate
+	edit2 = binary.AppendUvarint(binary.AppendUvarint(edit2, 10), 6)  // min log number to keep
+	edit2 = binary.AppendUvarint(binary.AppendUvarint(edit2, 2), 6)   // log number
+	edit2 = binary.AppendUvarint(binary.AppendUvarint(edit2, 3), 8)   // next file number
+	edit2 = binary.AppendUvarint(binary.AppendUvarint(edit2, 4), 2)   // last sequence
+	edit2 = binary.AppendUvarint(binary.AppendUvarint(edit2, 203), 0) // max column family
+	writeRecords("MANIFEST-000005", edit1, edit2)
+	writeFile("CURRENT", []byte("MANIFEST-000005\n"))
+	writeFile("IDENTITY", []byte("rocksdb-db-id\n"))
+	writeFile("OPTIONS-000007", []byte(`# This is a RocksDB option file.
+[Version]
+  rocksdb_version=8.11.3
+  options_file_version=1.1
+[DBOptions]
+  max_open_files=-1
+[CFOptions "default"]
+  comparator=leveldb.BytewiseComparator
+  merge_operator=nullptr
+`))
+
+	// The WAL 000006.log sets c@3 and deletes a@4.
+	b := newBatch(nil)
+	require.NoError(t, b.Set([]byte("c"), []byte("c3"), nil))
+	require.NoError(t, b.Delete([]byte("a"), nil))
+	b.setSeqNum(3)
+	writeRecords("000006.log", b.Repr())
+
+	files, err := mem.List("")
+	require.NoError(t, err)
+	sort.Strings(files)
+
+	// The directory can't be opened in read-write mode.
+	_, err = Open("", &Options{FS: mem, Logger: testLogger{t}})
+	require.ErrorContains(t, err, "was written by RocksDB")
+
+	d, err := Open("", &Options{FS: mem, Logger: testLogger{t}, ReadOnly: true})
+	require.NoError(t, err)
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	var kvs []string
+	for valid := iter.First(); valid; valid = iter.Next() {
+		kvs = append(kvs, fmt.Sprintf("%s:%s", iter.Key(), iter.Value()))
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, []string{"b:b2", "c:c3"}, kvs)
+
+	// The data can be migrated to a Pebble DB with a checkpoint.
+	require.NoError(t, d.Checkpoint("checkpoint"))
+	require.NoError(t, d.Close())
+	d, err = Open("checkpoint", &Options{FS: mem, Logger: testLogger{t}})
+	require.NoError(t, err)
+	v, closer, err := d.Get([]byte("c"))
+	require.NoError(t, err)
+	require.Equal(t, "c3", string(v))
+	require.NoError(t, closer.Close())
+	require.NoError(t, d.Close())
+
+	// The RocksDB directory wasn't modified, other than by the creation of the
+	// lock file.
+	files = append(files, "LOCK", "checkpoint")
+	sort.Strings(files)
+	newFiles, err := mem.List("")
+	require.NoError(t, err)
+	sort.Strings(newFiles)
+	require.Equal(t, files, newFiles)
+
+	// The records of the WAL that RocksDB assigns to kinds that Pebble uses
+	// for its own records are rejected. 0x16 is a wide-column entity.
+	repr := slices.Clone(b.Repr()[:batchrepr.HeaderLen])
+	binary.LittleEndian.PutUint32(repr[8:], 1)
+	repr = append(repr, 0x16)
+	repr = appendBytes(repr, []byte("d"))
+	repr = appendBytes(repr, []byte("entity"))
+	writeRecords("000006.log", repr)
+	_, err = Open("", &Options{FS: mem, Logger: testLogger{t}, ReadOnly: true})
+	require.ErrorContains(t, err, "contains a record of unsupported kind 22")
+}
+
 func TestOpenWALReplay(t *testing.T) {
 	largeValue := []byte(strings.Repeat("a", 100<<10))
 	hugeValue := []byte(strings.Repeat("b", 10<<20))
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,7 +24,9 @@ import (
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider/sharedcache"
 	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/rangekey"
 	"github.com/cockroachdb/pebble/sstable"
@@ -35,8 +38,12 @@ import (
 )
 
 const (
-	cacheDefaultSize       = 8 << 20 // 8 MB
-	defaultLevelMultiplier = 10
+	cacheDefaultSize                   = 8 << 20 // 8 MB
+	defaultLevelMultiplier             = 10
+	defaultMaxGrandparentOverlapFactor = 10
+	// defaultRemoteReadCircuitBreakerCooldown is the default of
+	// RemoteReadCircuitBreakerOptions.Cooldown.
+	defaultRemoteReadCircuitBreakerCooldown = 10 * time.Second
 )
 
 // Compression exports the base.Compression type.
@@ -79,6 +86,9 @@ type ShortAttributeExtractor = base.ShortAttributeExtractor
 // UserKeyPrefixBound exports the sstable.UserKeyPrefixBound type.
 type UserKeyPrefixBound = sstable.UserKeyPrefixBound
 
+// SuffixReplacement exports the sstable.SuffixReplacement type.
+type SuffixReplacement = sstable.SuffixReplacement
+
 // IterKeyType configures which types of keys an iterator should surface.
 type IterKeyType int8
 
@@ -182,6 +192,19 @@ type IterOptions struct {
 	// weight than creating an iterator, so we have opted to support this
 	// iterator option.
 	OnlyReadGuaranteedDurable bool
+	// ReadAtDurableFrontier is an advanced option that is only supported by the
+	// Reader implemented by DB. When set to true, the iterator reads at the
+	// durable frontier of the DB at the time the iterator is created (see
+	// DB.DurableVisibleSeqNum): it observes exactly the writes with sequence
+	// numbers below the frontier, all of which are in flushed sstables. Unlike
+	// OnlyReadGuaranteedDurable, which may also observe newer ingested sstables,
+	// the visible state is fully described by the frontier, which is returned
+	// by Iterator.ReadSeqNum. This allows replicas serving reads of durable
+	// state to report the exact point they serve.
+	//
+	// ReadAtDurableFrontier can't be changed by Iterator.SetOptions or
+	// Iterator.Clone.
+	ReadAtDurableFrontier bool
 	// UseL6Filters allows the caller to opt into reading filter blocks for L6
 	// sstables. Helpful if a lot of SeekPrefixGEs are expected in quick
 	// succession, that are also likely to not yield a single key. Filter blocks in
@@ -196,6 +219,15 @@ type IterOptions struct {
 
 	DebugRangeKeyStack bool
 
+	// DebugTrace, if set, receives a trace of every positioning operation of
+	// the iterator across the iterator stack: the operations on each level of
+	// the LSM, the tables loaded or skipped by block property filters, the
+	// block bytes loaded and whether the iterator switched to combined
+	// iteration of point and range keys. It's intended for producing bug
+	// reports of surprising iterator results, and has a significant
+	// performance cost.
+	DebugTrace io.Writer
+
 	// Internal options.
 
 	logger Logger
@@ -277,6 +309,11 @@ type scanInternalOptions struct {
 	// are exposed. If false, only one internal key per user key is exposed.
 	includeObsoleteKeys bool
 
+	// disableHideObsoletePoints disables the hiding of the points that
+	// sstables mark as obsolete, so that the keys that are only visible to
+	// older snapshots are exposed too.
+	disableHideObsoletePoints bool
+
 	// rateLimitFunc is used to limit the amount of bytes read per second.
 	rateLimitFunc func(key *InternalKey, value LazyValue) error
 }
@@ -439,6 +476,17 @@ type LevelOptions struct {
 
 	// The target file size for the level.
 	TargetFileSize int64
+
+	// DisableValueBlocks disables writing values to value blocks in the
+	// sstables written to the level, even when the table format supports them.
+	// Older versions of a key are then stored inline, avoiding the extra
+	// indirection when writing and reading them. This is useful for levels
+	// whose tables are short-lived, such as L0, where the data is about to be
+	// recompacted. It has no effect on table formats that don't support value
+	// blocks.
+	//
+	// The default value is false.
+	DisableValueBlocks bool
 }
 
 // EnsureDefaults ensures that the default values for all of the options have
@@ -502,6 +550,27 @@ type Options struct {
 		// consulted whenever a read handle is initialized.
 		ReadaheadConfig *ReadaheadConfig
 
+		// DropCompactionReadsFromPageCache, if true, advises the OS that
+		// compaction inputs may be dropped from its page cache once they have
+		// been read, using fadvise(POSIX_FADV_DONTNEED) on Linux. Compactions
+		// read their inputs once, so caching them evicts data that is more
+		// likely to be reused. This is useful on hosts that rely on the OS page
+		// cache in addition to the block cache.
+		DropCompactionReadsFromPageCache bool
+
+		// DropCompactionWritesFromPageCache, if true, advises the OS that the
+		// tables written by compactions (but not by flushes) may be dropped from
+		// its page cache once they have been written and synced, using
+		// fadvise(POSIX_FADV_DONTNEED) on Linux. Like
+		// DropCompactionReadsFromPageCache, this prevents compactions from
+		// evicting hot foreground data from the OS page cache.
+		DropCompactionWritesFromPageCache bool
+
+		// DropCheckpointReadsFromPageCache, if true, advises the OS that files
+		// copied by DB.Checkpoint may be dropped from its page cache once they
+		// have been copied, using fadvise(POSIX_FADV_DONTNEED) on Linux.
+		DropCheckpointReadsFromPageCache bool
+
 		// TODO(radu): move BytesPerSync, LoadBlockSema, Cleaner here.
 	}
 
@@ -569,11 +638,66 @@ type Options struct {
 		// concurrency slots as determined by the two options is chosen.
 		CompactionDebtConcurrency uint64
 
+		// CompactionConcurrencyAutotuning, if its MaxConcurrency is set, enables
+		// the automatic tuning of the compaction concurrency within the
+		// configured bounds, in place of MaxConcurrentCompactions. See
+		// CompactionConcurrencyAutotuning.
+		CompactionConcurrencyAutotuning CompactionConcurrencyAutotuning
+
+		// MemTableValueCompression, if its MinValueSize is positive, enables
+		// the compression of the values of at least MinValueSize bytes stored
+		// in memtables. See MemTableValueCompressionOptions.
+		MemTableValueCompression MemTableValueCompressionOptions
+
+		// MaxSubcompactions is the maximum number of subcompactions that a
+		// manual compaction or a compaction out of L0 can be split into. The
+		// subcompactions cover disjoint key ranges of the compaction, delimited
+		// by the boundaries of the input tables, and run concurrently. Their
+		// output tables are installed atomically, in a single version edit. A
+		// compaction is only split if each subcompaction would write at least
+		// one output table of the target file size. Values <= 1 disable
+		// subcompactions.
+		//
+		// Every subcompaction beyond the first one requires the permission of
+		// the CompactionScheduler, like a compaction, so subcompactions count
+		// against MaxConcurrentCompactions. A compaction is split into fewer
+		// subcompactions if the scheduler doesn't permit more.
+		MaxSubcompactions int
+
+		// HotKeys configures the detection of the most frequently read and
+		// written keys, reported by DB.HotKeys. It is disabled by default. See
+		// HotKeysOptions.
+		HotKeys HotKeysOptions
+
+		// ScanCache configures the cache of the results of DB.Scan. It is
+		// disabled by default. See ScanCacheOptions.
+		ScanCache ScanCacheOptions
+
+		// ExternalValues configures the reads of values stored in an
+		// application-managed value store, referenced by handles stored in
+		// Pebble. It is disabled by default. See ExternalValueStore.
+		ExternalValues ExternalValueOptions
+
+		// SeqNumTime configures the sampling of the visible sequence number over
+		// time, for time-travel reads through DB.SeqNumForTime and
+		// DB.NewSnapshotAt. It is disabled by default. See SeqNumTimeOptions.
+		SeqNumTime SeqNumTimeOptions
+
 		// IngestSplit, if it returns true, allows for ingest-time splitting of
 		// existing sstables into two virtual sstables to allow ingestion sstables to
 		// slot into a lower level than they otherwise would have.
 		IngestSplit func() bool
 
+		// IngestSplitOverlappingInputs, if it returns true, allows the local
+		// sstables of an ingestion (but not its shared or external sstables) to
+		// overlap each other, instead of failing the ingestion. Each sstable that
+		// overlaps others is split into virtual sstables: the parts that don't
+		// overlap any other sstable are ingested like non-overlapping sstables,
+		// and the part that does is ingested into L0. Where the sstables overlap,
+		// those passed later to the ingestion take precedence. Such ingestions
+		// are never ingested as flushables. Requires FormatVirtualSSTables.
+		IngestSplitOverlappingInputs func() bool
+
 		// ReadCompactionRate controls the frequency of read triggered
 		// compactions by adjusting `AllowedSeeks` in manifest.TableMetadata:
 		//
@@ -604,6 +728,23 @@ type Options struct {
 		// gets multiplied with a constant of 1 << 16 to yield 1 << 20 (1MB).
 		ReadSamplingMultiplier int64
 
+		// ReadCompactionSeekSamplingPeriod, if positive, additionally samples
+		// on average one in every ReadCompactionSeekSamplingPeriod seeks
+		// (SeekGE, SeekPrefixGE and SeekLT) and Gets for read triggered
+		// compactions, regardless of the number of bytes they read. Sampling
+		// reads by bytes alone rarely samples point reads, which return little
+		// data, so read-mostly key ranges served by point reads are otherwise
+		// rarely compacted. The default is 0, which only samples reads by
+		// bytes. Has no effect if ReadSamplingMultiplier is negative.
+		ReadCompactionSeekSamplingPeriod int64
+
+		// ReadCompactionMinOverlappingLevels is the minimum number of levels
+		// (counting each L0 sublevel separately) with a table containing the key
+		// returned by a sampled read for the sample to count towards a read
+		// triggered compaction of the table in the highest of those levels. The
+		// default is 2.
+		ReadCompactionMinOverlappingLevels int
+
 		// NumDeletionsThreshold defines the minimum number of point tombstones
 		// that must be present in a single data block for that block to be
 		// considered tombstone-dense for the purposes of triggering a
@@ -624,6 +765,47 @@ type Options struct {
 		// sstable writers. The default value is 0.5.
 		DeletionSizeRatioThreshold float32
 
+		// WriteController, if set, replaces Pebble's built-in write stall
+		// heuristics (based on MemTableStopWritesThreshold and
+		// L0StopWritesThreshold), deciding when writes are stalled or delayed
+		// based on the health of the LSM. See WriteController.
+		WriteController WriteController
+
+		// ValueFilters, if true and the lowest level has a FilterPolicy, writes
+		// a value filter (see sstable.WriterOptions.ValueFilter) into sstables
+		// written to the lowest level. Point tombstones in the lowest level
+		// can't delete anything beneath them, so prefix seeks (including Get)
+		// can use the value filter to skip tables that contain only point
+		// tombstones for the sought prefix. Value filters are consulted
+		// regardless of IterOptions.UseL6Filters, since they're only written
+		// when this option is enabled.
+		ValueFilters bool
+
+		// InlineTableMaxSize, if positive, is the maximum size of a flushed
+		// sstable that is stored inline in the MANIFEST instead of in its own
+		// file. Workloads with frequent tiny flushes otherwise produce a large
+		// number of tiny files, each of which costs a file descriptor and an
+		// open in the file cache. The contents of inline tables are kept in
+		// memory and are written to the MANIFEST whenever it's rotated, so this
+		// should be kept small (e.g. 16 KB); the total size of the inline tables
+		// is also capped at 4 MB. Compactions rewrite inline tables into regular
+		// sstables.
+		//
+		// Tables are only stored inline once the DB's format major version is
+		// at least FormatInlineTables.
+		InlineTableMaxSize int64
+
+		// WALBatchCompressionThreshold, if positive, is the minimum size of a
+		// batch that is compressed before being appended to the WAL. Bulky but
+		// compressible batches otherwise cost their full size in WAL bytes
+		// written and synced. The batches are transparently decompressed when
+		// the WAL is replayed. A batch is only written compressed if
+		// compression reduces its size by at least 12.5%.
+		//
+		// Batches are only compressed once the DB's format major version is at
+		// least FormatWALBatchCompression.
+		WALBatchCompressionThreshold int
+
 		// TombstoneDenseCompactionThreshold is the minimum percent of data
 		// blocks in a table that must be tombstone-dense for that table to be
 		// eligible for a tombstone density compaction. It should be defined as a
@@ -646,16 +828,68 @@ type Options struct {
 		// limited by runtime.GOMAXPROCS.
 		FileCacheShards int
 
+		// TableStatsConcurrency is the maximum number of goroutines that load
+		// table stats concurrently (see DisableTableStats). Raising it shortens
+		// the time after Open before the stats of all the tables, and so the
+		// deletion estimates, are available. The default value is 4.
+		TableStatsConcurrency int
+
 		// ValidateOnIngest schedules validation of sstables after they have
 		// been ingested.
 		//
 		// By default, this value is false.
 		ValidateOnIngest bool
 
+		// IngestKeyTypePolicy, if non-nil, restricts the spans of the keyspace
+		// within which ingested sstables may contain range deletions and range
+		// keys. Ingesting an sstable containing a range deletion or range key
+		// outside the spans allowed by the policy fails with
+		// ErrIngestKeyTypeNotAllowed. External files are validated using their
+		// declared bounds if they contain range keys; range deletions within
+		// external and shared files are not validated.
+		//
+		// By default, this value is nil and all key types may be ingested
+		// anywhere.
+		IngestKeyTypePolicy *IngestKeyTypePolicy
+
 		// LevelMultiplier configures the size multiplier used to determine the
 		// desired size of each level of the LSM. Defaults to 10.
 		LevelMultiplier int
 
+		// MaxGrandparentOverlapFactor bounds the overlap of each table written
+		// by a compaction with the level below the compaction's output level
+		// (the grandparent level), as a multiple of the output level's
+		// TargetFileSize. A compaction stops building an output table before
+		// its overlap with the grandparent level exceeds the bound, which limits
+		// the size of the compaction that will later move the table down.
+		// Lower values reduce the size of those compactions at the cost of
+		// producing smaller tables. Defaults to 10.
+		MaxGrandparentOverlapFactor int
+
+		// AdaptiveGrandparentOverlap, if true, tunes the grandparent overlap
+		// bound (see MaxGrandparentOverlapFactor) of each compaction based on
+		// the overlap observed between the compaction's inputs and the
+		// grandparent level. When the grandparent level holds more data across
+		// the key range of the compaction than LevelMultiplier times the
+		// inputs, as happens with skewed key distributions, the bound is
+		// lowered proportionally (but not below twice the output level's
+		// TargetFileSize), so that the output tables in dense key ranges don't
+		// result in large compactions later on.
+		AdaptiveGrandparentOverlap bool
+
+		// IteratorLeakThreshold, if positive, enables the detection of
+		// iterators that remain open for longer than the threshold, pinning
+		// old memtables and sstables. Such iterators are reported through the
+		// Logger (once per iterator), and the memtables and sstables pinned by
+		// them are reported in Metrics.Iterators.
+		IteratorLeakThreshold time.Duration
+
+		// IteratorLeakStacks, if true, captures the stack trace of the creation
+		// of every iterator and snapshot when IteratorLeakThreshold is set, and
+		// includes it when reporting leaked iterators (and in the report of
+		// DB.CloseReport). Capturing stack traces is expensive.
+		IteratorLeakStacks bool
+
 		// MultiLevelCompactionHeuristic determines whether to add an additional
 		// level to a conventional two level compaction. If nil, a multilevel
 		// compaction will never get triggered.
@@ -703,6 +937,28 @@ type Options struct {
 		// in value blocks.
 		RequiredInPlaceValueBound UserKeyPrefixBound
 
+		// BlobCompression specifies the compression algorithm used for values
+		// written to blob files. If DefaultCompression (the zero value), the
+		// compression configured for the level of the accompanying sstables is
+		// used.
+		BlobCompression Compression
+
+		// IsColdBlobValue, if set, classifies the values separated into blob
+		// files by compactions: the values for which it returns true are
+		// expected to be read rarely. Compactions that write new blob files
+		// write cold values to separate blob files, which are created on shared
+		// storage whenever it's configured (see CreateOnShared), so that the
+		// blob files holding frequently read values stay local.
+		IsColdBlobValue func(userKey, value []byte) bool
+
+		// BlobCompressionGranularity specifies whether blob file values are
+		// compressed a block at a time (the default) or individually. Compressing
+		// values individually allows a fetch of a single value to decompress only
+		// that value, at the cost of a worse compression ratio. Values are only
+		// compressed individually at format major versions of at least
+		// FormatBlobFileFormatV2.
+		BlobCompressionGranularity blob.CompressionGranularity
+
 		// DisableIngestAsFlushable disables lazy ingestion of sstables through
 		// a WAL write and memtable rotation. Only effectual if the format
 		// major version is at least `FormatFlushableIngest`.
@@ -724,10 +980,49 @@ type Options struct {
 		CreateOnShared        remote.CreateOnSharedStrategy
 		CreateOnSharedLocator remote.Locator
 
+		// RemoteTieringMinAge, if positive, enables remote tiering: physical
+		// sstables in the bottommost level that are stored locally and were
+		// created at least RemoteTieringMinAge ago are moved onto shared storage
+		// in the background, through copy compactions that rewrite the manifest
+		// to point at the shared objects. Tables that have been read within the
+		// last RemoteTieringMinAge (as observed by read sampling; see
+		// ReadSamplingMultiplier) are kept local. Tiered data can be moved back
+		// onto local storage using Download with DownloadSpan.IncludeSharedTables.
+		//
+		// Requires CreateOnShared to be set; CreateOnSharedTiered creates all
+		// new sstables locally, so that only tiering moves data onto shared
+		// storage.
+		RemoteTieringMinAge time.Duration
+
 		// CacheSizeBytesBytes is the size of the on-disk block cache for objects
 		// on shared storage in bytes. If it is 0, no cache is used.
 		SecondaryCacheSizeBytes int64
 
+		// SecondaryCachePersistence configures whether the contents of the
+		// secondary cache are reused across restarts, so that a restart does not
+		// trigger a storm of reads against shared storage. The zero value
+		// disables persistence.
+		SecondaryCachePersistence SecondaryCachePersistenceOptions
+
+		// RemoteReadCircuitBreaker configures circuit breakers around reads from
+		// remote storage: after repeated read failures from a locator, reads of
+		// its objects that miss the secondary cache fail immediately with
+		// ErrRemoteUnavailable for a cooldown period, instead of each waiting on
+		// the storage's timeouts. Breakers that trip or recover are reported
+		// through EventListener.RemoteStorageBreaker and their state through
+		// Metrics.RemoteStorageBreakers. The zero value disables the breakers.
+		RemoteReadCircuitBreaker RemoteReadCircuitBreakerOptions
+
+		// ReadaheadPolicy decides the readahead issued when iterators read
+		// tables sequentially, given the length of the run of sequential reads,
+		// the level of the table and whether it is stored locally or remotely.
+		// It allows embedders to tune the IO of scans for their storage
+		// hardware. Readahead of local tables remains subject to the modes of
+		// Local.ReadaheadConfig.
+		//
+		// If nil, DefaultReadaheadPolicy is used.
+		ReadaheadPolicy ReadaheadPolicy
+
 		// EnableDeleteOnlyCompactionExcises enables delete-only compactions to also
 		// apply delete-only compaction hints on sstables that partially overlap
 		// with it. This application happens through an excise, similar to
@@ -864,6 +1159,29 @@ type Options struct {
 	// The default value is 1000.
 	MaxOpenFiles int
 
+	// IdempotencyWindow is the number of idempotency keys of recently applied
+	// batches remembered by the DB. Applying a batch whose idempotency key is
+	// remembered is a no-op. See Batch.SetIdempotencyKey.
+	//
+	// The keys are remembered in memory, and recovered when the DB is opened
+	// from the batches replayed from the WAL. The keys of batches whose
+	// memtables were flushed before the DB was closed are not recovered, so a
+	// batch applied before a flush and a restart may be applied again.
+	//
+	// The default value is 10000. A negative value disables remembering the
+	// keys of applied batches.
+	IdempotencyWindow int
+
+	// MaxOpenIterators is the maximum number of iterators reading the DB state
+	// (e.g. created through DB.NewIter, Snapshot.NewIter, Batch.NewIter or
+	// Iterator.Clone) that may be open at once. Each open iterator pins the
+	// memtables and sstables it reads from, preventing their memory and disk
+	// space from being reclaimed. Creating an iterator beyond the limit fails
+	// with ErrTooManyIterators. Batch-only iterators are not limited.
+	//
+	// The default value is 0, meaning no limit.
+	MaxOpenIterators int
+
 	// The size of a MemTable in steady state. The actual MemTable size starts at
 	// min(256KB, MemTableSize) and doubles for each subsequent MemTable up to
 	// MemTableSize. This reduces the memory pressure caused by MemTables for
@@ -944,8 +1262,18 @@ type Options struct {
 	// DisableAutomaticCompactions dictates whether automatic compactions are
 	// scheduled or not. The default is false (enabled). This option is only used
 	// externally when running a manual compaction, and internally for tests.
+	// See DB.DisableAutomaticCompactions for pausing automatic compactions at
+	// runtime.
 	DisableAutomaticCompactions bool
 
+	// Preset selects a set of defaults, and the code paths tailored to a
+	// particular use of the DB. See CachePreset for using the DB as a
+	// persistent cache. The default is DefaultPreset.
+	Preset Preset
+
+	// CacheMode configures the eviction of tables when Preset is CachePreset.
+	CacheMode CacheModeOptions
+
 	// DisableConsistencyCheck disables the consistency check that is performed on
 	// open. Should only be used when a database cannot be opened normally (e.g.
 	// some of the tables don't exist / aren't accessible).
@@ -975,6 +1303,14 @@ type Options struct {
 	// to the DB will return an error, background compactions are disabled, and
 	// the flush that normally occurs after replaying the WAL at startup is
 	// disabled.
+	//
+	// In read-only mode, the directory of a RocksDB DB can be opened, for
+	// example to copy its data to a Pebble DB with a Checkpoint or an
+	// iterator: the DB must use the default column family only, without
+	// BlobDB or user-defined timestamps, its sstables must use format_version
+	// 2 and its WAL must only contain the point operations and range
+	// deletions of uncompressed batches. Flushing the memtables of the RocksDB
+	// DB before closing it avoids most of these limitations in the WAL.
 	ReadOnly bool
 
 	// FileCache is an initialized FileCache which should be set as an
@@ -985,11 +1321,23 @@ type Options struct {
 	// and pebble will panic otherwise.
 	FileCache *FileCache
 
+	// IORateLimiter, if non-nil, limits the rate at which flushes and
+	// compactions write data. Flushes take priority over compactions. The
+	// limiter can be adjusted at runtime and shared between DB instances. See
+	// IORateLimiter.
+	IORateLimiter *IORateLimiter
+
 	// BlockPropertyCollectors is a list of BlockPropertyCollector creation
 	// functions. A new BlockPropertyCollector is created for each sstable
 	// built and lives for the lifetime of writing that table.
 	BlockPropertyCollectors []func() BlockPropertyCollector
 
+	// UserPropertyAggregators is a list of aggregators that roll up the
+	// table-level properties of some of the BlockPropertyCollectors across
+	// tables. The aggregates are exposed per level and DB-wide through
+	// Metrics.UserProperties.
+	UserPropertyAggregators []UserPropertyAggregator
+
 	// WALBytesPerSync sets the number of bytes to write to a WAL before calling
 	// Sync on it in the background. Just like with BytesPerSync above, this
 	// helps smooth out disk write latencies, and avoids cases where the OS
@@ -1006,6 +1354,18 @@ type Options struct {
 	// (i.e. the directory passed to pebble.Open).
 	WALDir string
 
+	// TempDir specifies the directory in which subsystems that spill data to
+	// disk create temporary files. Temporary files are accounted for in
+	// Metrics.TempFiles and are removed when the DB is opened, so the directory
+	// must not be shared with another DB. If empty (the default), temporary
+	// files are stored in a "tmp" subdirectory of the directory passed to
+	// pebble.Open.
+	TempDir string
+
+	// TempFS is the filesystem on which temporary files are created. If nil
+	// (the default), FS is used.
+	TempFS vfs.FS
+
 	// WALFailover may be set to configure Pebble to monitor writes to its
 	// write-ahead log and failover to writing write-ahead log entries to a
 	// secondary location (eg, a separate physical disk). WALFailover may be
@@ -1036,6 +1396,27 @@ type Options struct {
 	// changing options dynamically?
 	WALMinSyncInterval func() time.Duration
 
+	// WALMaxGroupCommitSize bounds the size of a group commit when WAL syncs are
+	// delayed by WALMinSyncInterval: once this many bytes have been written to
+	// the WAL since the last sync, the next sync is issued without waiting for
+	// the rest of WALMinSyncInterval. WALMinSyncInterval thus bounds the latency
+	// of a group commit and WALMaxGroupCommitSize its size. The number of
+	// commits coalesced into each sync is reported in
+	// Metrics.LogWriter.SyncGroupSize. This option is supplied as a closure in
+	// order to allow the value to be changed dynamically. The default value is
+	// 0, i.e. no limit.
+	WALMaxGroupCommitSize func() int
+
+	// WALSyncInterval, if positive, enables periodic durability: instead of
+	// syncing the WAL for every commit that requests it, the WAL is synced at
+	// most once per interval (e.g. every 5ms), within an interval of data being
+	// written to it. Commits with WriteOptions.Sync wait for the next periodic
+	// sync, and commits without it are also durable within the interval, which
+	// thus bounds the window of data loss on a crash. WALMinSyncInterval and
+	// WALMaxGroupCommitSize are ignored when periodic durability is enabled.
+	// The default value is 0, i.e. the WAL is synced when requested.
+	WALSyncInterval time.Duration
+
 	// The controls below manage deletion pacing, which slows down
 	// deletions when compactions finish or when readers close and
 	// obsolete files must be cleaned up. Rapid deletion of many
@@ -1057,6 +1438,23 @@ type Options struct {
 	// Default is 16GB.
 	FreeSpaceThresholdBytes uint64
 
+	// MinFreeSpaceForWrites, if non-zero, is the amount of available disk space
+	// (as reported by FS.GetDiskUsage) below which the DB refuses new writes
+	// with ErrDiskSpaceLow. Ingestions of local tables are refused as well.
+	// Batches that only contain deletions are still accepted, as are excises,
+	// reads, flushes and compactions, so that the space can be reclaimed. The
+	// default is 0 (disabled).
+	MinFreeSpaceForWrites uint64
+
+	// BackgroundErrorRecovery, if set, enables the automatic recovery from
+	// errors encountered by flushes and compactions. Retryable errors cause
+	// background work to be retried with exponential backoff. Once retries are
+	// exhausted, or a non-retryable error is encountered, the DB stops its
+	// background work and refuses writes with ErrWritesPaused until
+	// DB.Resume is called. If nil (the default), failed flushes and compactions
+	// are rescheduled immediately and writes are never paused.
+	BackgroundErrorRecovery *BackgroundErrorRecoveryOptions
+
 	// FreeSpaceTimeframe sets the duration (in seconds) within which Pebble attempts
 	// to restore the free disk space back to FreeSpaceThreshold. A lower value means
 	// more aggressive deletions. Default is 10s.
@@ -1124,15 +1522,87 @@ type WALFailoverOptions struct {
 	// Secondary indicates the secondary directory and VFS to use in the event a
 	// write to the primary WAL stalls.
 	Secondary wal.Dir
+	// AdditionalSecondaries is an ordered list of further directories to fail
+	// over to, in the event writes to the Secondary stall as well. Each
+	// directory's health is monitored independently: writes move on to the
+	// next directory in the list when the current one is unhealthy, and fail
+	// back to the first preceding directory that is healthy again.
+	AdditionalSecondaries []wal.Dir
 	// FailoverOptions provides configuration of the thresholds and intervals
 	// involved in WAL failover. If any of its fields are left unspecified,
 	// reasonable defaults will be used.
 	wal.FailoverOptions
 }
 
+// BackgroundErrorRecoveryOptions configures the automatic recovery from errors
+// encountered by flushes and compactions. See Options.BackgroundErrorRecovery.
+type BackgroundErrorRecoveryOptions struct {
+	// IsRetryable returns whether a background error is transient, in which
+	// case the failed work is retried after a backoff. The default treats
+	// out-of-disk-space errors as retryable.
+	IsRetryable func(error) bool
+	// InitialBackoff is the delay before background work is retried after the
+	// first failure. The delay doubles with every consecutive failure. The
+	// default is 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff bounds the delay between retries. The default is 30s.
+	MaxBackoff time.Duration
+	// MaxRetries is the number of consecutive failures of background work
+	// after which the DB stops retrying and pauses writes. The default is 10.
+	// A negative value retries indefinitely.
+	MaxRetries int
+}
+
+// EnsureDefaults ensures that the default values for all of the options have
+// been initialized.
+func (o *BackgroundErrorRecoveryOptions) EnsureDefaults() {
+	if o.IsRetryable == nil {
+		o.IsRetryable = vfs.IsNoSpaceError
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 10
+	}
+}
+
 // ReadaheadConfig controls the use of read-ahead.
 type ReadaheadConfig = objstorageprovider.ReadaheadConfig
 
+// ReadaheadPolicy decides the readahead issued for sequential reads; see
+// Options.Experimental.ReadaheadPolicy.
+type ReadaheadPolicy = objstorageprovider.ReadaheadPolicy
+
+// ReadaheadInfo exports the objstorageprovider.ReadaheadInfo type.
+type ReadaheadInfo = objstorageprovider.ReadaheadInfo
+
+// ReadaheadDecision exports the objstorageprovider.ReadaheadDecision type.
+type ReadaheadDecision = objstorageprovider.ReadaheadDecision
+
+// FileTemperature exports the objstorageprovider.FileTemperature type.
+type FileTemperature = objstorageprovider.FileTemperature
+
+// DefaultReadaheadPolicy is the ReadaheadPolicy used when
+// Options.Experimental.ReadaheadPolicy is nil.
+var DefaultReadaheadPolicy = objstorageprovider.DefaultReadaheadPolicy
+
+// SecondaryCachePersistenceOptions configures the persistence of the secondary
+// cache across restarts.
+type SecondaryCachePersistenceOptions = sharedcache.PersistenceOptions
+
+// RemoteReadCircuitBreakerOptions configures the circuit breakers around reads
+// from remote storage.
+type RemoteReadCircuitBreakerOptions = objstorageprovider.CircuitBreakerOptions
+
+// ErrRemoteUnavailable is returned by reads from remote storage while the
+// circuit breaker of its locator is tripped. See
+// Options.Experimental.RemoteReadCircuitBreaker.
+var ErrRemoteUnavailable = objstorage.ErrRemoteUnavailable
+
 // JemallocSizeClasses exports sstable.JemallocSizeClasses.
 var JemallocSizeClasses = sstable.JemallocSizeClasses
 
@@ -1183,11 +1653,15 @@ func (o *Options) EnsureDefaults() {
 	if o.Experimental.CompactionDebtConcurrency <= 0 {
 		o.Experimental.CompactionDebtConcurrency = 1 << 30 // 1 GB
 	}
+	o.Experimental.CompactionConcurrencyAutotuning.ensureDefaults()
 	if o.KeySchema == "" && len(o.KeySchemas) == 0 {
 		ks := colblk.DefaultKeySchema(o.Comparer, 16 /* bundleSize */)
 		o.KeySchema = ks.Name
 		o.KeySchemas = sstable.MakeKeySchemas(&ks)
 	}
+	if o.Preset == CachePreset {
+		o.ensureCachePresetDefaults()
+	}
 	if o.L0CompactionThreshold <= 0 {
 		o.L0CompactionThreshold = 4
 	}
@@ -1252,6 +1726,9 @@ func (o *Options) EnsureDefaults() {
 	if o.MaxOpenFiles == 0 {
 		o.MaxOpenFiles = 1000
 	}
+	if o.IdempotencyWindow == 0 {
+		o.IdempotencyWindow = defaultIdempotencyWindow
+	}
 	if o.MemTableSize <= 0 {
 		o.MemTableSize = 4 << 20 // 4 MB
 	}
@@ -1281,21 +1758,33 @@ func (o *Options) EnsureDefaults() {
 	if o.FS == nil {
 		o.WithFSDefaults()
 	}
+	if o.TempFS == nil {
+		o.TempFS = o.FS
+	}
 	if o.FlushSplitBytes <= 0 {
 		o.FlushSplitBytes = 2 * o.Levels[0].TargetFileSize
 	}
+	if o.BackgroundErrorRecovery != nil {
+		o.BackgroundErrorRecovery.EnsureDefaults()
+	}
 	if o.WALFailover != nil {
 		o.WALFailover.FailoverOptions.EnsureDefaults()
 	}
 	if o.Experimental.LevelMultiplier <= 0 {
 		o.Experimental.LevelMultiplier = defaultLevelMultiplier
 	}
+	if o.Experimental.MaxGrandparentOverlapFactor <= 0 {
+		o.Experimental.MaxGrandparentOverlapFactor = defaultMaxGrandparentOverlapFactor
+	}
 	if o.Experimental.ReadCompactionRate == 0 {
 		o.Experimental.ReadCompactionRate = 16000
 	}
 	if o.Experimental.ReadSamplingMultiplier == 0 {
 		o.Experimental.ReadSamplingMultiplier = 1 << 4
 	}
+	if o.Experimental.ReadCompactionMinOverlappingLevels <= 0 {
+		o.Experimental.ReadCompactionMinOverlappingLevels = defaultReadCompactionMinOverlappingLevels
+	}
 	if o.Experimental.NumDeletionsThreshold == 0 {
 		o.Experimental.NumDeletionsThreshold = sstable.DefaultNumDeletionsThreshold
 	}
@@ -1305,9 +1794,24 @@ func (o *Options) EnsureDefaults() {
 	if o.Experimental.TombstoneDenseCompactionThreshold == 0 {
 		o.Experimental.TombstoneDenseCompactionThreshold = 0.10
 	}
+	if o.Experimental.HotKeys.TopK > 0 && o.Experimental.HotKeys.SampleRate <= 0 {
+		o.Experimental.HotKeys.SampleRate = defaultHotKeysSampleRate
+	}
+	if c := &o.Experimental.ScanCache; c.Size > 0 && c.MaxResultSize <= 0 {
+		c.MaxResultSize = c.Size / defaultScanCacheMaxResultSizeRatio
+	}
+	if s := &o.Experimental.SeqNumTime; s.SampleInterval > 0 && s.Retention <= 0 {
+		s.Retention = defaultSeqNumTimeRetentionSamples * s.SampleInterval
+	}
+	if b := &o.Experimental.RemoteReadCircuitBreaker; b.FailureThreshold > 0 && b.Cooldown <= 0 {
+		b.Cooldown = defaultRemoteReadCircuitBreakerCooldown
+	}
 	if o.Experimental.FileCacheShards <= 0 {
 		o.Experimental.FileCacheShards = runtime.GOMAXPROCS(0)
 	}
+	if o.Experimental.TableStatsConcurrency <= 0 {
+		o.Experimental.TableStatsConcurrency = defaultTableStatsConcurrency
+	}
 	if o.Experimental.MultiLevelCompactionHeuristic == nil {
 		o.Experimental.MultiLevelCompactionHeuristic = WriteAmpHeuristic{}
 	}
@@ -1400,10 +1904,19 @@ func (o *Options) String() string {
 	fmt.Fprintf(&buf, "  pebble_version=0.1\n")
 	fmt.Fprintf(&buf, "\n")
 	fmt.Fprintf(&buf, "[Options]\n")
+	if o.Experimental.AdaptiveGrandparentOverlap {
+		fmt.Fprintf(&buf, "  adaptive_grandparent_overlap=%t\n", true)
+	}
 	fmt.Fprintf(&buf, "  bytes_per_sync=%d\n", o.BytesPerSync)
 	fmt.Fprintf(&buf, "  cache_size=%d\n", cacheSize)
 	fmt.Fprintf(&buf, "  cleaner=%s\n", o.Cleaner)
 	fmt.Fprintf(&buf, "  compaction_debt_concurrency=%d\n", o.Experimental.CompactionDebtConcurrency)
+	if a := o.Experimental.CompactionConcurrencyAutotuning; a.enabled() {
+		fmt.Fprintf(&buf, "  compaction_concurrency_autotuning_min=%d\n", a.MinConcurrency)
+		fmt.Fprintf(&buf, "  compaction_concurrency_autotuning_max=%d\n", a.MaxConcurrency)
+		fmt.Fprintf(&buf, "  compaction_concurrency_autotuning_max_disk_sync_latency=%s\n", a.MaxDiskSyncLatency)
+		fmt.Fprintf(&buf, "  compaction_concurrency_autotuning_min_cpu_idle_fraction=%f\n", a.MinCPUIdleFraction)
+	}
 	fmt.Fprintf(&buf, "  comparer=%s\n", o.Comparer.Name)
 	fmt.Fprintf(&buf, "  disable_wal=%t\n", o.DisableWAL)
 	if o.Experimental.DisableIngestAsFlushable != nil && o.Experimental.DisableIngestAsFlushable() {
@@ -1416,6 +1929,14 @@ func (o *Options) String() string {
 	fmt.Fprintf(&buf, "  flush_delay_range_key=%s\n", o.FlushDelayRangeKey)
 	fmt.Fprintf(&buf, "  flush_split_bytes=%d\n", o.FlushSplitBytes)
 	fmt.Fprintf(&buf, "  format_major_version=%d\n", o.FormatMajorVersion)
+	if h := o.Experimental.HotKeys; h.TopK > 0 {
+		fmt.Fprintf(&buf, "  hot_keys_top_k=%d\n", h.TopK)
+		fmt.Fprintf(&buf, "  hot_keys_sample_rate=%d\n", h.SampleRate)
+		fmt.Fprintf(&buf, "  hot_keys_keep_hot_tables_local=%t\n", h.KeepHotTablesLocal)
+	}
+	if o.IdempotencyWindow != 0 && o.IdempotencyWindow != defaultIdempotencyWindow {
+		fmt.Fprintf(&buf, "  idempotency_window=%d\n", o.IdempotencyWindow)
+	}
 	fmt.Fprintf(&buf, "  key_schema=%s\n", o.KeySchema)
 	fmt.Fprintf(&buf, "  l0_compaction_concurrency=%d\n", o.Experimental.L0CompactionConcurrency)
 	fmt.Fprintf(&buf, "  l0_compaction_file_threshold=%d\n", o.L0CompactionFileThreshold)
@@ -1426,34 +1947,105 @@ func (o *Options) String() string {
 		fmt.Fprintf(&buf, "  level_multiplier=%d\n", o.Experimental.LevelMultiplier)
 	}
 	fmt.Fprintf(&buf, "  max_concurrent_compactions=%d\n", o.MaxConcurrentCompactions())
+	if f := o.Experimental.MaxGrandparentOverlapFactor; f != 0 && f != defaultMaxGrandparentOverlapFactor {
+		fmt.Fprintf(&buf, "  max_grandparent_overlap_factor=%d\n", f)
+	}
 	fmt.Fprintf(&buf, "  max_concurrent_downloads=%d\n", o.MaxConcurrentDownloads())
 	fmt.Fprintf(&buf, "  max_manifest_file_size=%d\n", o.MaxManifestFileSize)
 	fmt.Fprintf(&buf, "  max_open_files=%d\n", o.MaxOpenFiles)
+	if o.MaxOpenIterators != 0 {
+		fmt.Fprintf(&buf, "  max_open_iterators=%d\n", o.MaxOpenIterators)
+	}
+	if o.Experimental.MaxSubcompactions > 1 {
+		fmt.Fprintf(&buf, "  max_subcompactions=%d\n", o.Experimental.MaxSubcompactions)
+	}
 	fmt.Fprintf(&buf, "  mem_table_size=%d\n", o.MemTableSize)
 	fmt.Fprintf(&buf, "  mem_table_stop_writes_threshold=%d\n", o.MemTableStopWritesThreshold)
+	if c := o.Experimental.MemTableValueCompression; c.MinValueSize > 0 {
+		fmt.Fprintf(&buf, "  memtable_value_compression_min_size=%d\n", c.MinValueSize)
+		fmt.Fprintf(&buf, "  memtable_value_compression=%s\n", c.Compression)
+	}
 	fmt.Fprintf(&buf, "  min_deletion_rate=%d\n", o.TargetByteDeletionRate)
 	fmt.Fprintf(&buf, "  free_space_threshold_bytes=%d\n", o.FreeSpaceThresholdBytes)
 	fmt.Fprintf(&buf, "  free_space_timeframe=%s\n", o.FreeSpaceTimeframe.String())
+	if o.MinFreeSpaceForWrites != 0 {
+		fmt.Fprintf(&buf, "  min_free_space_for_writes=%d\n", o.MinFreeSpaceForWrites)
+	}
+	if o.Preset != DefaultPreset {
+		fmt.Fprintf(&buf, "  preset=%s\n", o.Preset)
+		fmt.Fprintf(&buf, "  cache_mode_max_size=%d\n", o.CacheMode.MaxSize)
+		fmt.Fprintf(&buf, "  cache_mode_ttl=%s\n", o.CacheMode.TTL)
+	}
 	fmt.Fprintf(&buf, "  obsolete_bytes_max_ratio=%f\n", o.ObsoleteBytesMaxRatio)
 	fmt.Fprintf(&buf, "  obsolete_bytes_timeframe=%s\n", o.ObsoleteBytesTimeframe.String())
 	fmt.Fprintf(&buf, "  merger=%s\n", o.Merger.Name)
+	if o.Experimental.BlobCompression != DefaultCompression {
+		fmt.Fprintf(&buf, "  blob_compression=%s\n", o.Experimental.BlobCompression)
+	}
+	if o.Experimental.BlobCompressionGranularity != blob.CompressBlocks {
+		fmt.Fprintf(&buf, "  blob_compression_granularity=%s\n", o.Experimental.BlobCompressionGranularity)
+	}
 	if o.Experimental.MultiLevelCompactionHeuristic != nil {
 		fmt.Fprintf(&buf, "  multilevel_compaction_heuristic=%s\n", o.Experimental.MultiLevelCompactionHeuristic.String())
 	}
 	fmt.Fprintf(&buf, "  read_compaction_rate=%d\n", o.Experimental.ReadCompactionRate)
 	fmt.Fprintf(&buf, "  read_sampling_multiplier=%d\n", o.Experimental.ReadSamplingMultiplier)
+	if o.Experimental.ReadCompactionSeekSamplingPeriod > 0 {
+		fmt.Fprintf(&buf, "  read_compaction_seek_sampling_period=%d\n", o.Experimental.ReadCompactionSeekSamplingPeriod)
+	}
+	if n := o.Experimental.ReadCompactionMinOverlappingLevels; n > 0 && n != defaultReadCompactionMinOverlappingLevels {
+		fmt.Fprintf(&buf, "  read_compaction_min_overlapping_levels=%d\n", o.Experimental.ReadCompactionMinOverlappingLevels)
+	}
 	fmt.Fprintf(&buf, "  num_deletions_threshold=%d\n", o.Experimental.NumDeletionsThreshold)
 	fmt.Fprintf(&buf, "  deletion_size_ratio_threshold=%f\n", o.Experimental.DeletionSizeRatioThreshold)
 	fmt.Fprintf(&buf, "  tombstone_dense_compaction_threshold=%f\n", o.Experimental.TombstoneDenseCompactionThreshold)
+	if o.Experimental.ValueFilters {
+		fmt.Fprintf(&buf, "  value_filters=%t\n", o.Experimental.ValueFilters)
+	}
+	if o.Experimental.InlineTableMaxSize > 0 {
+		fmt.Fprintf(&buf, "  inline_table_max_size=%d\n", o.Experimental.InlineTableMaxSize)
+	}
+	if o.Experimental.WALBatchCompressionThreshold > 0 {
+		fmt.Fprintf(&buf, "  wal_batch_compression_threshold=%d\n", o.Experimental.WALBatchCompressionThreshold)
+	}
 	// We no longer care about strict_wal_tail, but set it to true in case an
 	// older version reads the options.
 	fmt.Fprintf(&buf, "  strict_wal_tail=%t\n", true)
 	fmt.Fprintf(&buf, "  table_cache_shards=%d\n", o.Experimental.FileCacheShards)
+	if o.Experimental.TableStatsConcurrency != defaultTableStatsConcurrency {
+		fmt.Fprintf(&buf, "  table_stats_concurrency=%d\n", o.Experimental.TableStatsConcurrency)
+	}
 	fmt.Fprintf(&buf, "  validate_on_ingest=%t\n", o.Experimental.ValidateOnIngest)
+	if o.TempDir != "" {
+		fmt.Fprintf(&buf, "  temp_dir=%s\n", o.TempDir)
+	}
 	fmt.Fprintf(&buf, "  wal_dir=%s\n", o.WALDir)
 	fmt.Fprintf(&buf, "  wal_bytes_per_sync=%d\n", o.WALBytesPerSync)
+	if o.WALSyncInterval != 0 {
+		fmt.Fprintf(&buf, "  wal_sync_interval=%s\n", o.WALSyncInterval)
+	}
 	fmt.Fprintf(&buf, "  secondary_cache_size_bytes=%d\n", o.Experimental.SecondaryCacheSizeBytes)
+	if p := o.Experimental.SecondaryCachePersistence; p != (SecondaryCachePersistenceOptions{}) {
+		fmt.Fprintf(&buf, "  secondary_cache_persist_on_close=%t\n", p.PersistOnClose)
+		fmt.Fprintf(&buf, "  secondary_cache_warm_on_open=%t\n", p.WarmOnOpen)
+		fmt.Fprintf(&buf, "  secondary_cache_validate_on_open=%t\n", p.ValidateOnOpen)
+	}
+	if b := o.Experimental.RemoteReadCircuitBreaker; b.FailureThreshold > 0 {
+		fmt.Fprintf(&buf, "  remote_read_breaker_failure_threshold=%d\n", b.FailureThreshold)
+		fmt.Fprintf(&buf, "  remote_read_breaker_cooldown=%s\n", b.Cooldown)
+	}
+	if s := o.Experimental.SeqNumTime; s.SampleInterval > 0 {
+		fmt.Fprintf(&buf, "  seqnum_time_sample_interval=%s\n", s.SampleInterval)
+		fmt.Fprintf(&buf, "  seqnum_time_retention=%s\n", s.Retention)
+	}
 	fmt.Fprintf(&buf, "  create_on_shared=%d\n", o.Experimental.CreateOnShared)
+	if o.Experimental.RemoteTieringMinAge > 0 {
+		fmt.Fprintf(&buf, "  remote_tiering_min_age=%s\n", o.Experimental.RemoteTieringMinAge)
+	}
+	if c := o.Experimental.ScanCache; c.Size > 0 {
+		fmt.Fprintf(&buf, "  scan_cache_size=%d\n", c.Size)
+		fmt.Fprintf(&buf, "  scan_cache_max_result_size=%d\n", c.MaxResultSize)
+	}
 
 	// Private options.
 	//
@@ -1476,6 +2068,9 @@ func (o *Options) String() string {
 		fmt.Fprintf(&buf, "\n")
 		fmt.Fprintf(&buf, "[WAL Failover]\n")
 		fmt.Fprintf(&buf, "  secondary_dir=%s\n", o.WALFailover.Secondary.Dirname)
+		for _, dir := range o.WALFailover.AdditionalSecondaries {
+			fmt.Fprintf(&buf, "  additional_secondary_dir=%s\n", dir.Dirname)
+		}
 		fmt.Fprintf(&buf, "  primary_dir_probe_interval=%s\n", o.WALFailover.FailoverOptions.PrimaryDirProbeInterval)
 		fmt.Fprintf(&buf, "  healthy_probe_latency_threshold=%s\n", o.WALFailover.FailoverOptions.HealthyProbeLatencyThreshold)
 		fmt.Fprintf(&buf, "  healthy_interval=%s\n", o.WALFailover.FailoverOptions.HealthyInterval)
@@ -1496,6 +2091,9 @@ func (o *Options) String() string {
 		fmt.Fprintf(&buf, "  filter_type=%s\n", l.FilterType)
 		fmt.Fprintf(&buf, "  index_block_size=%d\n", l.IndexBlockSize)
 		fmt.Fprintf(&buf, "  target_file_size=%d\n", l.TargetFileSize)
+		if l.DisableValueBlocks {
+			fmt.Fprintf(&buf, "  disable_value_blocks=%t\n", l.DisableValueBlocks)
+		}
 	}
 
 	return buf.String()
@@ -1634,6 +2232,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 		case section == "Options":
 			var err error
 			switch key {
+			case "adaptive_grandparent_overlap":
+				o.Experimental.AdaptiveGrandparentOverlap, err = strconv.ParseBool(value)
 			case "bytes_per_sync":
 				o.BytesPerSync, err = strconv.Atoi(value)
 			case "cache_size":
@@ -1657,6 +2257,14 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				}
 			case "compaction_debt_concurrency":
 				o.Experimental.CompactionDebtConcurrency, err = strconv.ParseUint(value, 10, 64)
+			case "compaction_concurrency_autotuning_min":
+				o.Experimental.CompactionConcurrencyAutotuning.MinConcurrency, err = strconv.Atoi(value)
+			case "compaction_concurrency_autotuning_max":
+				o.Experimental.CompactionConcurrencyAutotuning.MaxConcurrency, err = strconv.Atoi(value)
+			case "compaction_concurrency_autotuning_max_disk_sync_latency":
+				o.Experimental.CompactionConcurrencyAutotuning.MaxDiskSyncLatency, err = time.ParseDuration(value)
+			case "compaction_concurrency_autotuning_min_cpu_idle_fraction":
+				o.Experimental.CompactionConcurrencyAutotuning.MinCPUIdleFraction, err = strconv.ParseFloat(value, 64)
 			case "delete_range_flush_delay":
 				// NB: This is a deprecated serialization of the
 				// `flush_delay_delete_range`.
@@ -1699,6 +2307,14 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				if err == nil {
 					o.FormatMajorVersion = FormatMajorVersion(v)
 				}
+			case "hot_keys_top_k":
+				o.Experimental.HotKeys.TopK, err = strconv.Atoi(value)
+			case "hot_keys_sample_rate":
+				o.Experimental.HotKeys.SampleRate, err = strconv.Atoi(value)
+			case "hot_keys_keep_hot_tables_local":
+				o.Experimental.HotKeys.KeepHotTablesLocal, err = strconv.ParseBool(value)
+			case "idempotency_window":
+				o.IdempotencyWindow, err = strconv.Atoi(value)
 			case "key_schema":
 				o.KeySchema = value
 				if o.KeySchemas == nil {
@@ -1763,10 +2379,24 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.MaxManifestFileSize, err = strconv.ParseInt(value, 10, 64)
 			case "max_open_files":
 				o.MaxOpenFiles, err = strconv.Atoi(value)
+			case "max_grandparent_overlap_factor":
+				o.Experimental.MaxGrandparentOverlapFactor, err = strconv.Atoi(value)
+			case "max_open_iterators":
+				o.MaxOpenIterators, err = strconv.Atoi(value)
+			case "max_subcompactions":
+				o.Experimental.MaxSubcompactions, err = strconv.Atoi(value)
 			case "mem_table_size":
 				o.MemTableSize, err = strconv.ParseUint(value, 10, 64)
 			case "mem_table_stop_writes_threshold":
 				o.MemTableStopWritesThreshold, err = strconv.Atoi(value)
+			case "memtable_value_compression_min_size":
+				o.Experimental.MemTableValueCompression.MinValueSize, err = strconv.Atoi(value)
+			case "memtable_value_compression":
+				c := block.CompressionFromString(value)
+				if c == DefaultCompression && value != "Default" {
+					return errors.Errorf("pebble: unknown compression: %q", errors.Safe(value))
+				}
+				o.Experimental.MemTableValueCompression.Compression = c
 			case "min_compaction_rate":
 				// Do nothing; option existed in older versions of pebble, and
 				// may be meaningful again eventually.
@@ -1776,6 +2406,14 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				o.FreeSpaceThresholdBytes, err = strconv.ParseUint(value, 10, 64)
 			case "free_space_timeframe":
 				o.FreeSpaceTimeframe, err = time.ParseDuration(value)
+			case "min_free_space_for_writes":
+				o.MinFreeSpaceForWrites, err = strconv.ParseUint(value, 10, 64)
+			case "preset":
+				o.Preset, err = parsePreset(value)
+			case "cache_mode_max_size":
+				o.CacheMode.MaxSize, err = strconv.ParseUint(value, 10, 64)
+			case "cache_mode_ttl":
+				o.CacheMode.TTL, err = time.ParseDuration(value)
 			case "obsolete_bytes_max_ratio":
 				o.ObsoleteBytesMaxRatio, err = strconv.ParseFloat(value, 64)
 			case "obsolete_bytes_timeframe":
@@ -1828,10 +2466,26 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 						o.Merger, err = hooks.NewMerger(value)
 					}
 				}
+			case "blob_compression":
+				c := block.CompressionFromString(value)
+				if c == DefaultCompression && value != "Default" {
+					return errors.Errorf("pebble: unknown compression: %q", errors.Safe(value))
+				}
+				o.Experimental.BlobCompression = c
+			case "blob_compression_granularity":
+				g, ok := blob.CompressionGranularityFromString(value)
+				if !ok {
+					return errors.Errorf("pebble: unknown blob compression granularity: %q", errors.Safe(value))
+				}
+				o.Experimental.BlobCompressionGranularity = g
 			case "read_compaction_rate":
 				o.Experimental.ReadCompactionRate, err = strconv.ParseInt(value, 10, 64)
 			case "read_sampling_multiplier":
 				o.Experimental.ReadSamplingMultiplier, err = strconv.ParseInt(value, 10, 64)
+			case "read_compaction_seek_sampling_period":
+				o.Experimental.ReadCompactionSeekSamplingPeriod, err = strconv.ParseInt(value, 10, 64)
+			case "read_compaction_min_overlapping_levels":
+				o.Experimental.ReadCompactionMinOverlappingLevels, err = strconv.Atoi(value)
 			case "num_deletions_threshold":
 				o.Experimental.NumDeletionsThreshold, err = strconv.Atoi(value)
 			case "deletion_size_ratio_threshold":
@@ -1840,8 +2494,16 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				err = parseErr
 			case "tombstone_dense_compaction_threshold":
 				o.Experimental.TombstoneDenseCompactionThreshold, err = strconv.ParseFloat(value, 64)
+			case "value_filters":
+				o.Experimental.ValueFilters, err = strconv.ParseBool(value)
+			case "inline_table_max_size":
+				o.Experimental.InlineTableMaxSize, err = strconv.ParseInt(value, 10, 64)
+			case "wal_batch_compression_threshold":
+				o.Experimental.WALBatchCompressionThreshold, err = strconv.Atoi(value)
 			case "table_cache_shards":
 				o.Experimental.FileCacheShards, err = strconv.Atoi(value)
+			case "table_stats_concurrency":
+				o.Experimental.TableStatsConcurrency, err = strconv.Atoi(value)
 			case "table_format":
 				switch value {
 				case "leveldb":
@@ -1853,20 +2515,44 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				// No longer implemented; ignore.
 			case "validate_on_ingest":
 				o.Experimental.ValidateOnIngest, err = strconv.ParseBool(value)
+			case "temp_dir":
+				o.TempDir = value
 			case "wal_dir":
 				o.WALDir = value
 			case "wal_bytes_per_sync":
 				o.WALBytesPerSync, err = strconv.Atoi(value)
+			case "wal_sync_interval":
+				o.WALSyncInterval, err = time.ParseDuration(value)
 			case "max_writer_concurrency":
 				// No longer implemented; ignore.
 			case "force_writer_parallelism":
 				// No longer implemented; ignore.
 			case "secondary_cache_size_bytes":
 				o.Experimental.SecondaryCacheSizeBytes, err = strconv.ParseInt(value, 10, 64)
+			case "secondary_cache_persist_on_close":
+				o.Experimental.SecondaryCachePersistence.PersistOnClose, err = strconv.ParseBool(value)
+			case "secondary_cache_warm_on_open":
+				o.Experimental.SecondaryCachePersistence.WarmOnOpen, err = strconv.ParseBool(value)
+			case "secondary_cache_validate_on_open":
+				o.Experimental.SecondaryCachePersistence.ValidateOnOpen, err = strconv.ParseBool(value)
+			case "remote_read_breaker_failure_threshold":
+				o.Experimental.RemoteReadCircuitBreaker.FailureThreshold, err = strconv.Atoi(value)
+			case "remote_read_breaker_cooldown":
+				o.Experimental.RemoteReadCircuitBreaker.Cooldown, err = time.ParseDuration(value)
+			case "seqnum_time_sample_interval":
+				o.Experimental.SeqNumTime.SampleInterval, err = time.ParseDuration(value)
+			case "seqnum_time_retention":
+				o.Experimental.SeqNumTime.Retention, err = time.ParseDuration(value)
 			case "create_on_shared":
 				var createOnSharedInt int64
 				createOnSharedInt, err = strconv.ParseInt(value, 10, 64)
 				o.Experimental.CreateOnShared = remote.CreateOnSharedStrategy(createOnSharedInt)
+			case "remote_tiering_min_age":
+				o.Experimental.RemoteTieringMinAge, err = time.ParseDuration(value)
+			case "scan_cache_size":
+				o.Experimental.ScanCache.Size, err = strconv.ParseInt(value, 10, 64)
+			case "scan_cache_max_result_size":
+				o.Experimental.ScanCache.MaxResultSize, err = strconv.ParseInt(value, 10, 64)
 			default:
 				if hooks != nil && hooks.SkipUnknown != nil && hooks.SkipUnknown(section+"."+key, value) {
 					return nil
@@ -1884,6 +2570,9 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 			switch key {
 			case "secondary_dir":
 				o.WALFailover.Secondary = wal.Dir{Dirname: value, FS: vfs.Default}
+			case "additional_secondary_dir":
+				o.WALFailover.AdditionalSecondaries = append(o.WALFailover.AdditionalSecondaries,
+					wal.Dir{Dirname: value, FS: vfs.Default})
 			case "primary_dir_probe_interval":
 				o.WALFailover.PrimaryDirProbeInterval, err = time.ParseDuration(value)
 			case "healthy_probe_latency_threshold":
@@ -1963,6 +2652,8 @@ func (o *Options) Parse(s string, hooks *ParseHooks) error {
 				l.IndexBlockSize, err = strconv.Atoi(value)
 			case "target_file_size":
 				l.TargetFileSize, err = strconv.ParseInt(value, 10, 64)
+			case "disable_value_blocks":
+				l.DisableValueBlocks, err = strconv.ParseBool(value)
 			default:
 				if hooks != nil && hooks.SkipUnknown != nil && hooks.SkipUnknown(section+"."+key, value) {
 					return nil
@@ -2014,12 +2705,15 @@ func (o *Options) CheckCompatibility(previousOptions string) error {
 				return errors.Errorf("pebble: merger name from file %q != merger name from options %q",
 					errors.Safe(value), errors.Safe(o.Merger.Name))
 			}
-		case "Options.wal_dir", "WAL Failover.secondary_dir":
+		case "Options.wal_dir", "WAL Failover.secondary_dir", "WAL Failover.additional_secondary_dir":
 			switch {
 			case o.WALDir == value:
 				return nil
 			case o.WALFailover != nil && o.WALFailover.Secondary.Dirname == value:
 				return nil
+			case o.WALFailover != nil && slices.ContainsFunc(o.WALFailover.AdditionalSecondaries,
+				func(d wal.Dir) bool { return d.Dirname == value }):
+				return nil
 			default:
 				for _, d := range o.WALRecoveryDirs {
 					if d.Dirname == value {
@@ -2066,6 +2760,20 @@ func (o *Options) Validate() error {
 		fmt.Fprintf(&buf, "FormatMajorVersion (%d) when CreateOnShared is set must be at least %d\n",
 			o.FormatMajorVersion, FormatMinForSharedObjects)
 	}
+	if a := o.Experimental.CompactionConcurrencyAutotuning; a.enabled() && a.MinConcurrency > a.MaxConcurrency {
+		fmt.Fprintf(&buf, "CompactionConcurrencyAutotuning.MinConcurrency (%d) must be <= MaxConcurrency (%d)\n",
+			a.MinConcurrency, a.MaxConcurrency)
+	}
+	if o.Preset == CachePreset && o.CacheMode.MaxSize == 0 && o.CacheMode.TTL <= 0 {
+		fmt.Fprintf(&buf, "CachePreset requires CacheMode.MaxSize or CacheMode.TTL to be set\n")
+	}
+	if o.Preset == CachePreset && o.Experimental.SeqNumTime.SampleInterval > 0 {
+		fmt.Fprintf(&buf, "CachePreset does not support SeqNumTime, which requires snapshots\n")
+	}
+	if o.Experimental.RemoteTieringMinAge > 0 && o.Experimental.CreateOnShared == remote.CreateOnSharedNone {
+		fmt.Fprintf(&buf, "RemoteTieringMinAge (%s) requires CreateOnShared to be set\n",
+			o.Experimental.RemoteTieringMinAge)
+	}
 	if len(o.KeySchemas) > 0 {
 		if o.KeySchema == "" {
 			fmt.Fprintf(&buf, "KeySchemas is set but KeySchema is not\n")
@@ -2121,7 +2829,9 @@ func (o *Options) MakeWriterOptions(level int, format sstable.TableFormat) sstab
 	writerOpts.Compression = resolveDefaultCompression(levelOpts.Compression())
 	writerOpts.FilterPolicy = levelOpts.FilterPolicy
 	writerOpts.FilterType = levelOpts.FilterType
+	writerOpts.ValueFilter = o.Experimental.ValueFilters && level == numLevels-1
 	writerOpts.IndexBlockSize = levelOpts.IndexBlockSize
+	writerOpts.DisableValueBlocks = levelOpts.DisableValueBlocks
 	writerOpts.KeySchema = o.KeySchemas[o.KeySchema]
 	writerOpts.AllocatorSizeClasses = o.AllocatorSizeClasses
 	writerOpts.NumDeletionsThreshold = o.Experimental.NumDeletionsThreshold
@@ -2130,12 +2840,22 @@ func (o *Options) MakeWriterOptions(level int, format sstable.TableFormat) sstab
 }
 
 // MakeBlobWriterOptions constructs blob.FileWriterOptions from the corresponding
-// options in the receiver.
-func (o *Options) MakeBlobWriterOptions(level int) blob.FileWriterOptions {
+// options in the receiver. Values are only compressed individually if the
+// provided blob file format supports it.
+func (o *Options) MakeBlobWriterOptions(level int, format blob.FileFormat) blob.FileWriterOptions {
 	lo := o.Level(level)
+	compression := lo.Compression()
+	if o.Experimental.BlobCompression != DefaultCompression {
+		compression = o.Experimental.BlobCompression
+	}
+	granularity := o.Experimental.BlobCompressionGranularity
+	if format < blob.FileFormatV2 {
+		granularity = blob.CompressBlocks
+	}
 	return blob.FileWriterOptions{
-		Compression:  resolveDefaultCompression(lo.Compression()),
-		ChecksumType: block.ChecksumTypeCRC32c,
+		Compression:            resolveDefaultCompression(compression),
+		CompressionGranularity: granularity,
+		ChecksumType:           block.ChecksumTypeCRC32c,
 		FlushGovernor: block.MakeFlushGovernor(
 			lo.BlockSize,
 			lo.BlockSizeThreshold,
@@ -14,6 +14,8 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/pebble/vfs"
 	"github.com/cockroachdb/pebble/wal"
 	"github.com/stretchr/testify/require"
@@ -72,6 +74,15 @@ func TestLevelOptions(t *testing.T) {
 	}
 }
 
+func TestLevelOptionsDisableValueBlocks(t *testing.T) {
+	opts := &Options{Levels: make([]LevelOptions, 2)}
+	opts.Levels[0].DisableValueBlocks = true
+	opts.EnsureDefaults()
+	require.True(t, opts.MakeWriterOptions(0, sstable.TableFormatPebblev4).DisableValueBlocks)
+	require.False(t, opts.MakeWriterOptions(1, sstable.TableFormatPebblev4).DisableValueBlocks)
+	require.False(t, opts.MakeWriterOptions(6, sstable.TableFormatPebblev4).DisableValueBlocks)
+}
+
 func TestDefaultOptionsString(t *testing.T) {
 	n := runtime.GOMAXPROCS(8)
 	defer runtime.GOMAXPROCS(n)
@@ -225,6 +236,22 @@ func TestOptionsCheckCompatibility(t *testing.T) {
 
 [WAL Failover]
   secondary_dir=failover-wal-dir
+`))
+	// The same applies to additional secondary dirs.
+	require.Equal(t, ErrMissingWALRecoveryDir{Dir: "failover-wal-dir-2"}, opts.CheckCompatibility(`
+[Options]
+
+[WAL Failover]
+  secondary_dir=failover-wal-dir
+  additional_secondary_dir=failover-wal-dir-2
+`))
+	opts.WALFailover.AdditionalSecondaries = []wal.Dir{{Dirname: "failover-wal-dir-2"}}
+	require.NoError(t, opts.CheckCompatibility(`
+[Options]
+
+[WAL Failover]
+  secondary_dir=failover-wal-dir
+  additional_secondary_dir=failover-wal-dir-2
 `))
 }
 
@@ -280,17 +307,22 @@ func TestOptionsParse(t *testing.T) {
 			opts.Comparer = c.comparer
 			opts.Merger = c.merger
 			opts.WALDir = "wal"
+			opts.TempDir = "scratch"
 			opts.Levels = make([]LevelOptions, 3)
 			opts.Levels[0].BlockSize = 1024
 			opts.Levels[1].BlockSize = 2048
 			opts.Levels[2].BlockSize = 4096
+			opts.Levels[0].DisableValueBlocks = true
 			opts.Experimental.CompactionDebtConcurrency = 100
 			opts.FlushDelayDeleteRange = 10 * time.Second
 			opts.FlushDelayRangeKey = 11 * time.Second
 			opts.Experimental.LevelMultiplier = 5
+			opts.Experimental.MaxGrandparentOverlapFactor = 4
+			opts.Experimental.AdaptiveGrandparentOverlap = true
 			opts.TargetByteDeletionRate = 200
 			opts.WALFailover = &WALFailoverOptions{
-				Secondary: wal.Dir{Dirname: "wal_secondary", FS: vfs.Default},
+				Secondary:             wal.Dir{Dirname: "wal_secondary", FS: vfs.Default},
+				AdditionalSecondaries: []wal.Dir{{Dirname: "wal_tertiary", FS: vfs.Default}},
 			}
 			opts.Experimental.ReadCompactionRate = 300
 			opts.Experimental.ReadSamplingMultiplier = 400
@@ -299,6 +331,34 @@ func TestOptionsParse(t *testing.T) {
 			opts.Experimental.TombstoneDenseCompactionThreshold = 0.2
 			opts.Experimental.FileCacheShards = 500
 			opts.Experimental.SecondaryCacheSizeBytes = 1024
+			opts.Experimental.RemoteTieringMinAge = 36 * time.Hour
+			opts.Experimental.CompactionConcurrencyAutotuning = CompactionConcurrencyAutotuning{
+				MinConcurrency:     2,
+				MaxConcurrency:     8,
+				MaxDiskSyncLatency: 20 * time.Millisecond,
+				MinCPUIdleFraction: 0.25,
+			}
+			opts.Experimental.SecondaryCachePersistence = SecondaryCachePersistenceOptions{
+				PersistOnClose: true,
+				WarmOnOpen:     true,
+			}
+			opts.Experimental.BlobCompression = ZstdCompression
+			opts.Experimental.MemTableValueCompression = MemTableValueCompressionOptions{
+				MinValueSize: 1024,
+				Compression:  SnappyCompression,
+			}
+			opts.Experimental.BlobCompressionGranularity = blob.CompressValues
+			opts.Experimental.MaxSubcompactions = 4
+			opts.Experimental.TableStatsConcurrency = 2
+			opts.Experimental.SeqNumTime = SeqNumTimeOptions{SampleInterval: time.Second, Retention: time.Minute}
+			opts.Experimental.RemoteReadCircuitBreaker = RemoteReadCircuitBreakerOptions{
+				FailureThreshold: 5,
+				Cooldown:         30 * time.Second,
+			}
+			opts.Experimental.HotKeys = HotKeysOptions{TopK: 16, SampleRate: 10, KeepHotTablesLocal: true}
+			opts.Experimental.ScanCache = ScanCacheOptions{Size: 1 << 20, MaxResultSize: 1 << 10}
+			opts.Preset = CachePreset
+			opts.CacheMode = CacheModeOptions{MaxSize: 64 << 20, TTL: 2 * time.Hour}
 			opts.EnsureDefaults()
 			str := opts.String()
 
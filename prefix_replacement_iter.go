@@ -0,0 +1,413 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/treeprinter"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// cmpSyntheticPrefix returns 0 if key has the given synthetic prefix, and
+// otherwise -1 or +1 depending on whether the key sorts before or after all
+// keys with the prefix.
+func cmpSyntheticPrefix(key, syntheticPrefix []byte) int {
+	if bytes.HasPrefix(key, syntheticPrefix) {
+		return 0
+	}
+	return bytes.Compare(key, syntheticPrefix)
+}
+
+// invertBounds maps iterator bounds expressed in terms of keys with the
+// synthetic prefix to bounds over the keys physically stored in the table,
+// appending the inverted keys to buf. If the bounds exclude all the keys in
+// the table, empty is true.
+func invertBounds(
+	pr sstable.PrefixReplacement, buf, lower, upper []byte,
+) (newBuf, innerLower, innerUpper []byte, empty bool) {
+	buf = buf[:0]
+	if lower != nil {
+		switch cmpSyntheticPrefix(lower, pr.SyntheticPrefix) {
+		case -1:
+			// The bound is before all the keys; leave the lower bound unset.
+		case +1:
+			empty = true
+		default:
+			buf = pr.InvertTo(buf, lower)
+		}
+	}
+	n := len(buf)
+	if upper != nil {
+		switch cmpSyntheticPrefix(upper, pr.SyntheticPrefix) {
+		case -1:
+			empty = true
+		case +1:
+			// The bound is after all the keys; leave the upper bound unset.
+		default:
+			buf = pr.InvertTo(buf, upper)
+			innerUpper = buf[n:]
+		}
+	}
+	if n > 0 {
+		innerLower = buf[:n:n]
+	}
+	return buf, innerLower, innerUpper, empty
+}
+
+// prefixReplacingIter wraps an iterator over a table with a content prefix,
+// replacing the content prefix of every key with the table's synthetic prefix
+// (see sstable.PrefixReplacement). Seek keys and bounds are mapped to the keys
+// physically stored in the table.
+type prefixReplacingIter struct {
+	iter internalIterator
+	pr   sstable.PrefixReplacement
+	err  error
+
+	kv      base.InternalKV
+	keyBuf  []byte
+	seekBuf []byte
+	// prefixBuf holds the inverted prefix passed to SeekPrefixGE.
+	prefixBuf []byte
+	// boundsBuf holds the inverted bounds of the wrapped iterator. The bounds
+	// are double-buffered since the wrapped iterator may compare its previous
+	// bounds to the new ones during SetBounds.
+	boundsBuf [2][]byte
+	boundsIdx int
+	// empty is true if the current bounds exclude all the keys in the table.
+	// The wrapped iterator's bounds are not updated in this case.
+	empty bool
+	// unpositioned is true if the last positioning operation did not position
+	// the wrapped iterator, in which case it must not be asked to seek using
+	// next.
+	unpositioned bool
+}
+
+var _ internalIterator = (*prefixReplacingIter)(nil)
+
+// newPrefixReplacingIter returns a prefixReplacingIter with its bounds
+// initialized. The inner bounds are returned so that they can be used to
+// construct the wrapped iterator, which must be set with init.
+func newPrefixReplacingIter(
+	pr sstable.PrefixReplacement, lower, upper []byte,
+) (_ *prefixReplacingIter, innerLower, innerUpper []byte) {
+	i := &prefixReplacingIter{pr: pr}
+	i.boundsBuf[0], innerLower, innerUpper, i.empty = invertBounds(pr, nil, lower, upper)
+	if i.empty {
+		innerLower, innerUpper = nil, nil
+	}
+	return i, innerLower, innerUpper
+}
+
+func (i *prefixReplacingIter) init(iter internalIterator) {
+	i.iter = iter
+}
+
+func (i *prefixReplacingIter) surface(kv *base.InternalKV) *base.InternalKV {
+	i.unpositioned = false
+	if kv == nil {
+		return nil
+	}
+	rest, ok := bytes.CutPrefix(kv.K.UserKey, i.pr.ContentPrefix)
+	if !ok {
+		i.err = base.CorruptionErrorf("pebble: key %q does not have content prefix %q",
+			kv.K.UserKey, i.pr.ContentPrefix)
+		return nil
+	}
+	i.keyBuf = append(append(i.keyBuf[:0], i.pr.SyntheticPrefix...), rest...)
+	i.kv = *kv
+	i.kv.K.UserKey = i.keyBuf
+	return &i.kv
+}
+
+// exhaustForward positions the wrapped iterator after its last key, so that a
+// subsequent Prev returns the last key.
+func (i *prefixReplacingIter) exhaustForward() *base.InternalKV {
+	if kv := i.iter.Last(); kv != nil {
+		i.iter.Next()
+	}
+	i.unpositioned = false
+	return nil
+}
+
+// exhaustBackward positions the wrapped iterator before its first key, so that
+// a subsequent Next returns the first key.
+func (i *prefixReplacingIter) exhaustBackward() *base.InternalKV {
+	if kv := i.iter.First(); kv != nil {
+		i.iter.Prev()
+	}
+	i.unpositioned = false
+	return nil
+}
+
+func (i *prefixReplacingIter) seekGEFlags(flags base.SeekGEFlags) base.SeekGEFlags {
+	if i.unpositioned {
+		flags = flags.DisableTrySeekUsingNext()
+	}
+	return flags
+}
+
+// SeekGE implements base.InternalIterator.
+func (i *prefixReplacingIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	i.err = nil
+	if i.empty {
+		i.unpositioned = true
+		return nil
+	}
+	switch cmpSyntheticPrefix(key, i.pr.SyntheticPrefix) {
+	case -1:
+		return i.surface(i.iter.First())
+	case +1:
+		return i.exhaustForward()
+	}
+	i.seekBuf = i.pr.InvertTo(i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekGE(i.seekBuf, i.seekGEFlags(flags)))
+}
+
+// SeekPrefixGE implements base.InternalIterator.
+func (i *prefixReplacingIter) SeekPrefixGE(
+	prefix, key []byte, flags base.SeekGEFlags,
+) *base.InternalKV {
+	i.err = nil
+	// The synthetic prefix is contained in the prefix of every key, so a
+	// prefix without it cannot match any keys.
+	if i.empty || !bytes.HasPrefix(prefix, i.pr.SyntheticPrefix) {
+		i.unpositioned = true
+		return nil
+	}
+	i.prefixBuf = i.pr.InvertTo(i.prefixBuf[:0], prefix)
+	i.seekBuf = i.pr.InvertTo(i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekPrefixGE(i.prefixBuf, i.seekBuf, i.seekGEFlags(flags)))
+}
+
+// SeekLT implements base.InternalIterator.
+func (i *prefixReplacingIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	i.err = nil
+	if i.empty {
+		i.unpositioned = true
+		return nil
+	}
+	switch cmpSyntheticPrefix(key, i.pr.SyntheticPrefix) {
+	case -1:
+		return i.exhaustBackward()
+	case +1:
+		return i.surface(i.iter.Last())
+	}
+	i.seekBuf = i.pr.InvertTo(i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekLT(i.seekBuf, flags))
+}
+
+// First implements base.InternalIterator.
+func (i *prefixReplacingIter) First() *base.InternalKV {
+	i.err = nil
+	if i.empty {
+		i.unpositioned = true
+		return nil
+	}
+	return i.surface(i.iter.First())
+}
+
+// Last implements base.InternalIterator.
+func (i *prefixReplacingIter) Last() *base.InternalKV {
+	i.err = nil
+	if i.empty {
+		i.unpositioned = true
+		return nil
+	}
+	return i.surface(i.iter.Last())
+}
+
+// Next implements base.InternalIterator.
+func (i *prefixReplacingIter) Next() *base.InternalKV {
+	if i.empty {
+		return nil
+	}
+	return i.surface(i.iter.Next())
+}
+
+// NextPrefix implements base.InternalIterator.
+func (i *prefixReplacingIter) NextPrefix(succKey []byte) *base.InternalKV {
+	if i.empty {
+		return nil
+	}
+	if !bytes.HasPrefix(succKey, i.pr.SyntheticPrefix) {
+		// succKey follows the current key, which has the synthetic prefix, so it
+		// must be after all the keys.
+		return i.exhaustForward()
+	}
+	i.seekBuf = i.pr.InvertTo(i.seekBuf[:0], succKey)
+	return i.surface(i.iter.NextPrefix(i.seekBuf))
+}
+
+// Prev implements base.InternalIterator.
+func (i *prefixReplacingIter) Prev() *base.InternalKV {
+	if i.empty {
+		return nil
+	}
+	return i.surface(i.iter.Prev())
+}
+
+// Error implements base.InternalIterator.
+func (i *prefixReplacingIter) Error() error {
+	return errors.CombineErrors(i.err, i.iter.Error())
+}
+
+// Close implements base.InternalIterator.
+func (i *prefixReplacingIter) Close() error {
+	return i.iter.Close()
+}
+
+// SetBounds implements base.InternalIterator.
+func (i *prefixReplacingIter) SetBounds(lower, upper []byte) {
+	idx := i.boundsIdx ^ 1
+	var innerLower, innerUpper []byte
+	i.boundsBuf[idx], innerLower, innerUpper, i.empty = invertBounds(i.pr, i.boundsBuf[idx], lower, upper)
+	if i.empty {
+		return
+	}
+	i.boundsIdx = idx
+	i.iter.SetBounds(innerLower, innerUpper)
+}
+
+// SetContext implements base.InternalIterator.
+func (i *prefixReplacingIter) SetContext(ctx context.Context) {
+	i.iter.SetContext(ctx)
+}
+
+// DebugTree is part of the InternalIterator interface.
+func (i *prefixReplacingIter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p)", i, i)
+	if i.iter != nil {
+		i.iter.DebugTree(n)
+	}
+}
+
+func (i *prefixReplacingIter) String() string {
+	return i.iter.String()
+}
+
+// prefixReplacingSpanIter is the keyspan.FragmentIterator analog of
+// prefixReplacingIter, used for the range deletions and range keys of a table
+// with a content prefix.
+type prefixReplacingSpanIter struct {
+	iter keyspan.FragmentIterator
+	pr   sstable.PrefixReplacement
+
+	span     keyspan.Span
+	startBuf []byte
+	endBuf   []byte
+	seekBuf  []byte
+}
+
+var _ keyspan.FragmentIterator = (*prefixReplacingSpanIter)(nil)
+
+func newPrefixReplacingSpanIter(
+	iter keyspan.FragmentIterator, pr sstable.PrefixReplacement,
+) keyspan.FragmentIterator {
+	if iter == nil {
+		return nil
+	}
+	return &prefixReplacingSpanIter{iter: iter, pr: pr}
+}
+
+func (i *prefixReplacingSpanIter) surface(s *keyspan.Span, err error) (*keyspan.Span, error) {
+	if s == nil || err != nil {
+		return s, err
+	}
+	start, ok1 := bytes.CutPrefix(s.Start, i.pr.ContentPrefix)
+	end, ok2 := bytes.CutPrefix(s.End, i.pr.ContentPrefix)
+	if !ok1 || !ok2 {
+		return nil, base.CorruptionErrorf("pebble: span %s does not have content prefix %q",
+			s, i.pr.ContentPrefix)
+	}
+	i.startBuf = append(append(i.startBuf[:0], i.pr.SyntheticPrefix...), start...)
+	i.endBuf = append(append(i.endBuf[:0], i.pr.SyntheticPrefix...), end...)
+	i.span = *s
+	i.span.Start, i.span.End = i.startBuf, i.endBuf
+	return &i.span, nil
+}
+
+// SeekGE implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) SeekGE(key []byte) (*keyspan.Span, error) {
+	switch cmpSyntheticPrefix(key, i.pr.SyntheticPrefix) {
+	case -1:
+		return i.surface(i.iter.First())
+	case +1:
+		// Position the iterator after the last span, so that a subsequent Prev
+		// returns the last span.
+		if s, err := i.iter.Last(); s == nil || err != nil {
+			return nil, err
+		}
+		_, err := i.iter.Next()
+		return nil, err
+	}
+	i.seekBuf = i.pr.InvertTo(i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekGE(i.seekBuf))
+}
+
+// SeekLT implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) SeekLT(key []byte) (*keyspan.Span, error) {
+	switch cmpSyntheticPrefix(key, i.pr.SyntheticPrefix) {
+	case -1:
+		// Position the iterator before the first span, so that a subsequent
+		// Next returns the first span.
+		if s, err := i.iter.First(); s == nil || err != nil {
+			return nil, err
+		}
+		_, err := i.iter.Prev()
+		return nil, err
+	case +1:
+		return i.surface(i.iter.Last())
+	}
+	i.seekBuf = i.pr.InvertTo(i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekLT(i.seekBuf))
+}
+
+// First implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) First() (*keyspan.Span, error) {
+	return i.surface(i.iter.First())
+}
+
+// Last implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) Last() (*keyspan.Span, error) {
+	return i.surface(i.iter.Last())
+}
+
+// Next implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) Next() (*keyspan.Span, error) {
+	return i.surface(i.iter.Next())
+}
+
+// Prev implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) Prev() (*keyspan.Span, error) {
+	return i.surface(i.iter.Prev())
+}
+
+// Close implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) Close() {
+	i.iter.Close()
+}
+
+// WrapChildren implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) WrapChildren(wrap keyspan.WrapFn) {
+	i.iter = wrap(i.iter)
+}
+
+// SetContext implements keyspan.FragmentIterator.
+func (i *prefixReplacingSpanIter) SetContext(ctx context.Context) {
+	i.iter.SetContext(ctx)
+}
+
+// DebugTree is part of the FragmentIterator interface.
+func (i *prefixReplacingSpanIter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p)", i, i)
+	if i.iter != nil {
+		i.iter.DebugTree(n)
+	}
+}
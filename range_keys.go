@@ -553,6 +553,10 @@ func (i *lazyCombinedIter) initCombinedIteration(
 		}
 	}
 
+	if w := i.parent.opts.DebugTrace; w != nil {
+		iterTracef(w, "  switching to combined iteration at %s", i.parent.comparer.FormatKey(seekKey))
+	}
+
 	// An operation on the point iterator observed a file containing range keys,
 	// so we must switch to combined interleaving iteration. First, construct
 	// the range key iterator stack. It must not exist, otherwise we'd already
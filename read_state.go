@@ -91,6 +91,14 @@ func (d *DB) updateReadStateLocked(checker func(*DB) error) {
 		mem.readerRef()
 	}
 
+	// The current version may have changed.
+	d.mu.compact.deleteOnlyEligible.valid = false
+
+	h := d.lsmHealthLocked()
+	d.lsmHealth.Store(&h)
+	d.updateLowPriorityStallLocked(h)
+	d.updateAmpWindowLocked()
+
 	d.readState.Lock()
 	old := d.readState.val
 	d.readState.val = s
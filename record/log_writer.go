@@ -187,6 +187,9 @@ type pendingSyncs interface {
 	setBlocked()
 	clearBlocked()
 	empty() bool
+	// queued returns true if syncs have been requested, regardless of whether
+	// syncing is blocked.
+	queued() bool
 	snapshotForPop() pendingSyncsSnapshot
 	pop(snap pendingSyncsSnapshot, err error) error
 }
@@ -206,6 +209,8 @@ type PendingSync interface {
 type pendingSyncsWithSyncQueue struct {
 	syncQueue
 	syncQueueLen    *base.GaugeSampleMetric
+	syncGroupSize   *base.GaugeSampleMetric
+	onSynced        func(numSyncs int)
 	snapshotBacking syncQueueSnapshot
 	// See the comment for LogWriterConfig.QueueSemChan.
 	queueSemChan chan struct{}
@@ -218,6 +223,11 @@ func (q *pendingSyncsWithSyncQueue) push(ps PendingSync) {
 	q.syncQueue.push(ps2.wg, ps2.err)
 }
 
+func (q *pendingSyncsWithSyncQueue) queued() bool {
+	_, _, realLength := q.syncQueue.load()
+	return realLength > 0
+}
+
 func (q *pendingSyncsWithSyncQueue) snapshotForPop() pendingSyncsSnapshot {
 	head, tail, realLength := q.syncQueue.load()
 	q.snapshotBacking = syncQueueSnapshot{
@@ -225,12 +235,22 @@ func (q *pendingSyncsWithSyncQueue) snapshotForPop() pendingSyncsSnapshot {
 		tail: tail,
 	}
 	q.syncQueueLen.AddSample(int64(realLength))
+	if n := head - tail; n > 0 {
+		// The snapshot is synced together.
+		q.syncGroupSize.AddSample(int64(n))
+	}
 	return &q.snapshotBacking
 }
 
 func (q *pendingSyncsWithSyncQueue) pop(snap pendingSyncsSnapshot, err error) error {
 	s := snap.(*syncQueueSnapshot)
-	return q.syncQueue.pop(s.head, s.tail, err, q.queueSemChan)
+	if popErr := q.syncQueue.pop(s.head, s.tail, err, q.queueSemChan); popErr != nil {
+		return popErr
+	}
+	if n := s.head - s.tail; n > 0 && err == nil && q.onSynced != nil {
+		q.onSynced(int(n))
+	}
+	return nil
 }
 
 // The implementation of pendingSyncsSnapshot in standalone mode.
@@ -292,6 +312,10 @@ func (si *pendingSyncsWithHighestSyncIndex) empty() bool {
 	return si.load() == NoSyncIndex
 }
 
+func (si *pendingSyncsWithHighestSyncIndex) queued() bool {
+	return si.index.Load() != NoSyncIndex
+}
+
 func (si *pendingSyncsWithHighestSyncIndex) snapshotForPop() pendingSyncsSnapshot {
 	si.snapshotBacking = PendingSyncIndex{Index: si.load()}
 	return &si.snapshotBacking
@@ -455,6 +479,16 @@ type LogWriter struct {
 		err error
 		// minSyncInterval is the minimum duration between syncs.
 		minSyncInterval durationFunc
+		// maxGroupCommitSize is the number of bytes written since the last
+		// sync after which a sync is no longer delayed by minSyncInterval.
+		maxGroupCommitSize func() int
+		// syncInterval, if positive, is the interval at which syncs are
+		// performed periodically, instead of when requested. See
+		// LogWriterConfig.WALSyncInterval.
+		syncInterval time.Duration
+		// periodicSyncDue is set by the periodic sync timer when a periodic
+		// sync is due.
+		periodicSyncDue bool
 		fsyncLatency    prometheus.Histogram
 		pending         []*block
 		// Pushing and popping from pendingSyncs does not require flusher mutex to
@@ -489,7 +523,18 @@ type LogWriter struct {
 // LogWriterConfig is a struct used for configuring new LogWriters
 type LogWriterConfig struct {
 	WALMinSyncInterval durationFunc
-	WALFsyncLatency    prometheus.Histogram
+	// WALSyncInterval, if positive, enables periodic syncing: instead of
+	// syncing when requested, the LogWriter syncs at most once per interval,
+	// within an interval of data being written. Sync requests wait for the
+	// next periodic sync, and data written without requesting a sync is also
+	// synced within the interval. WALMinSyncInterval and WALMaxGroupCommitSize
+	// are ignored when periodic syncing is enabled.
+	WALSyncInterval time.Duration
+	// WALMaxGroupCommitSize, if it returns a positive value, bounds the number
+	// of bytes written since the last sync for which a sync is delayed by
+	// WALMinSyncInterval.
+	WALMaxGroupCommitSize func() int
+	WALFsyncLatency       prometheus.Histogram
 	// QueueSemChan is an optional channel to pop from when popping from
 	// LogWriter.flusher.syncQueue. It functions as a semaphore that prevents
 	// the syncQueue from overflowing (which will cause a panic). All production
@@ -511,6 +556,12 @@ type LogWriterConfig struct {
 
 	// WriteWALSyncOffsets represents whether to write the WAL sync chunk format.
 	WriteWALSyncOffsets bool
+
+	// OnSynced, if non-nil, is invoked after sync requests are successfully
+	// synced, with the number of sync requests, which are always synced in the
+	// order in which they were queued. It is invoked from the flush loop and
+	// must not block. It is not used when ExternalSyncQueueCallback is set.
+	OnSynced func(numSyncs int)
 }
 
 // ExternalSyncQueueCallback is to be run when a PendingSync has been
@@ -564,8 +615,10 @@ func NewLogWriter(
 		r.flusher.pendingSyncs = &r.pendingSyncsBackingIndex
 	} else {
 		r.pendingSyncsBackingQ = pendingSyncsWithSyncQueue{
-			syncQueueLen: &m.SyncQueueLen,
-			queueSemChan: logWriterConfig.QueueSemChan,
+			syncQueueLen:  &m.SyncQueueLen,
+			syncGroupSize: &m.SyncGroupSize,
+			onSynced:      logWriterConfig.OnSynced,
+			queueSemChan:  logWriterConfig.QueueSemChan,
 		}
 		r.flusher.pendingSyncs = &r.pendingSyncsBackingQ
 	}
@@ -579,6 +632,8 @@ func NewLogWriter(
 
 	f := &r.flusher
 	f.minSyncInterval = logWriterConfig.WALMinSyncInterval
+	f.maxGroupCommitSize = logWriterConfig.WALMaxGroupCommitSize
+	f.syncInterval = logWriterConfig.WALSyncInterval
 	f.fsyncLatency = logWriterConfig.WALFsyncLatency
 
 	go func() {
@@ -609,6 +664,15 @@ func (w *LogWriter) flushLoop(context.Context) {
 	// but not necessarily synced. This is used to update logWriter's
 	// syncedOffset after a sync.
 	var writtenOffset uint64 = 0
+	// unsyncedBytes is the number of bytes written since the last sync.
+	var unsyncedBytes int64
+	// syncTimerArmed is true when periodic syncing is enabled and the
+	// periodic sync timer has been started but hasn't fired yet.
+	var syncTimerArmed bool
+	if f.syncInterval > 0 {
+		// Sync requests are blocked until the next periodic sync.
+		f.pendingSyncs.setBlocked()
+	}
 
 	// The flush loop performs flushing of full and partial data blocks to the
 	// underlying writer (LogWriter.w), syncing of the writer, and notification
@@ -641,6 +705,13 @@ func (w *LogWriter) flushLoop(context.Context) {
 	//   requested, any previously queued flush work will be synced. This
 	//   motivates reading the syncing work (f.syncQ.load()) before picking up
 	//   the flush work (w.block.written.Load()).
+	//
+	// - When periodic syncing is enabled (flusher.syncInterval > 0), syncing is
+	//   always blocked, except when the periodic sync timer fires. The timer is
+	//   started whenever data has been written but not synced or syncs have
+	//   been requested, and upon firing it unblocks syncing and sets
+	//   flusher.periodicSyncDue, which forces a sync even if there are no sync
+	//   requests.
 
 	// The list of full blocks that need to be written. This is copied from
 	// f.pending on every loop iteration, though the number of elements is
@@ -656,7 +727,12 @@ func (w *LogWriter) flushLoop(context.Context) {
 			// the current block can be added to the pending blocks list after we release
 			// the flusher lock, but it won't be part of pending.
 			written := w.block.written.Load()
-			if len(f.pending) > 0 || written > w.block.flushed || !f.pendingSyncs.empty() {
+			if len(f.pending) > 0 || written > w.block.flushed || !f.pendingSyncs.empty() || f.periodicSyncDue {
+				break
+			}
+			if f.syncInterval > 0 && !syncTimerArmed && f.pendingSyncs.queued() {
+				// A sync was requested after the last periodic sync, for data that
+				// was already synced by it. Start the periodic sync timer.
 				break
 			}
 			if f.close {
@@ -705,6 +781,11 @@ func (w *LogWriter) flushLoop(context.Context) {
 		data := w.block.buf[w.block.flushed:written]
 		w.block.flushed = written
 
+		// A periodic sync syncs any data written since the last sync, whether
+		// or not it was requested.
+		forceSync := f.periodicSyncDue && (unsyncedBytes > 0 || len(data) > 0 || len(pending) > 0)
+		f.periodicSyncDue = false
+
 		fErr := f.err
 		f.Unlock()
 		// If flusher has an error, we propagate it to waiters. Note in spite of
@@ -721,7 +802,7 @@ func (w *LogWriter) flushLoop(context.Context) {
 			continue
 		}
 		writtenOffset += uint64(len(data))
-		synced, syncLatency, bytesWritten, err := w.flushPending(data, pending, snap)
+		synced, syncLatency, bytesWritten, err := w.flushPending(data, pending, snap, forceSync)
 		f.Lock()
 		if synced && f.fsyncLatency != nil {
 			w.syncedOffset.Store(writtenOffset)
@@ -737,7 +818,41 @@ func (w *LogWriter) flushLoop(context.Context) {
 			continue
 		}
 
-		if synced && f.minSyncInterval != nil {
+		if synced {
+			f.metrics.SyncBytes.AddSample(unsyncedBytes + bytesWritten)
+			unsyncedBytes = 0
+		} else {
+			unsyncedBytes += bytesWritten
+			if f.syncInterval <= 0 && f.maxGroupCommitSize != nil {
+				if max := f.maxGroupCommitSize(); max > 0 && unsyncedBytes >= int64(max) {
+					// Enough data has accumulated since the last sync. Don't wait for
+					// the min-sync-interval to expire before syncing again.
+					f.pendingSyncs.clearBlocked()
+				}
+			}
+		}
+		if f.syncInterval > 0 {
+			if !f.close {
+				f.pendingSyncs.setBlocked()
+			}
+			if (unsyncedBytes > 0 || f.pendingSyncs.queued()) && !syncTimerArmed {
+				// Data has been written but not synced, or syncs have been
+				// requested. Sync within the interval.
+				syncTimerArmed = true
+				if syncTimer == nil {
+					syncTimer = w.afterFunc(f.syncInterval, func() {
+						f.Lock()
+						syncTimerArmed = false
+						f.periodicSyncDue = true
+						f.Unlock()
+						f.pendingSyncs.clearBlocked()
+						f.ready.Signal()
+					})
+				} else {
+					syncTimer.Reset(f.syncInterval)
+				}
+			}
+		} else if synced && f.minSyncInterval != nil {
 			// A sync was performed. Make sure we've waited for the min sync
 			// interval before syncing again.
 			if min := f.minSyncInterval(); min > 0 {
@@ -762,7 +877,7 @@ func (w *LogWriter) flushLoop(context.Context) {
 }
 
 func (w *LogWriter) flushPending(
-	data []byte, pending []*block, snap pendingSyncsSnapshot,
+	data []byte, pending []*block, snap pendingSyncsSnapshot, forceSync bool,
 ) (synced bool, syncLatency time.Duration, bytesWritten int64, err error) {
 	defer func() {
 		// Translate panics into errors. The errors will cause flushLoop to shut
@@ -785,16 +900,18 @@ func (w *LogWriter) flushPending(
 		_, err = w.w.Write(data)
 	}
 
-	synced = !snap.empty()
+	synced = !snap.empty() || forceSync
 	if synced {
 		if err == nil && w.s != nil {
 			syncLatency, err = w.syncWithLatency()
 		} else {
 			synced = false
 		}
-		f := &w.flusher
-		if popErr := f.pendingSyncs.pop(snap, err); popErr != nil {
-			return synced, syncLatency, bytesWritten, firstError(err, popErr)
+		if !snap.empty() {
+			f := &w.flusher
+			if popErr := f.pendingSyncs.pop(snap, err); popErr != nil {
+				return synced, syncLatency, bytesWritten, firstError(err, popErr)
+			}
 		}
 	}
 
@@ -1090,6 +1207,13 @@ type LogWriterMetrics struct {
 	WriteThroughput  base.ThroughputMetric
 	PendingBufferLen base.GaugeSampleMetric
 	SyncQueueLen     base.GaugeSampleMetric
+	// SyncGroupSize samples the number of sync requests coalesced into each
+	// sync. It is only populated when the LogWriter is not used for WAL
+	// failover.
+	SyncGroupSize base.GaugeSampleMetric
+	// SyncBytes samples the number of bytes written between consecutive syncs.
+	// Its count is the number of syncs.
+	SyncBytes base.GaugeSampleMetric
 }
 
 // Merge merges metrics from x. Requires that x is non-nil.
@@ -1097,5 +1221,7 @@ func (m *LogWriterMetrics) Merge(x *LogWriterMetrics) error {
 	m.WriteThroughput.Merge(x.WriteThroughput)
 	m.PendingBufferLen.Merge(x.PendingBufferLen)
 	m.SyncQueueLen.Merge(x.SyncQueueLen)
+	m.SyncGroupSize.Merge(x.SyncGroupSize)
+	m.SyncBytes.Merge(x.SyncBytes)
 	return nil
 }
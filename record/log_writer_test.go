@@ -371,6 +371,124 @@ func TestMinSyncIntervalClose(t *testing.T) {
 	wg.Wait()
 }
 
+func TestMaxGroupCommitSize(t *testing.T) {
+	const minSyncInterval = 100 * time.Millisecond
+
+	f := &syncFile{}
+	w := NewLogWriter(f, 0, LogWriterConfig{
+		WALMinSyncInterval: func() time.Duration {
+			return minSyncInterval
+		},
+		WALMaxGroupCommitSize: func() int {
+			return 64 << 10
+		},
+		WALFsyncLatency: prometheus.NewHistogram(prometheus.HistogramOpts{}),
+	})
+
+	var timer fakeTimer
+	w.afterFunc = func(d time.Duration, f func()) syncTimer {
+		timer.f = f
+		timer.Reset(d)
+		return &timer
+	}
+
+	syncRecord := func(n int) *sync.WaitGroup {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		_, err := w.SyncRecord(bytes.Repeat([]byte{'a'}, n), wg, new(error))
+		require.NoError(t, err)
+		return wg
+	}
+
+	// Sync one record which will cause the sync timer to kick in.
+	syncRecord(1).Wait()
+
+	// Write records totalling more than the max group commit size. The records
+	// that push the unsynced bytes over the limit are synced without the timer
+	// firing.
+	var wgs []*sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wgs = append(wgs, syncRecord(10000))
+	}
+	wgs[6].Wait()
+
+	require.NoError(t, w.Close())
+	for _, wg := range wgs {
+		wg.Wait()
+	}
+	m := w.Metrics()
+	require.GreaterOrEqual(t, m.SyncBytes.Count(), int64(2))
+	require.GreaterOrEqual(t, m.SyncGroupSize.Count(), int64(2))
+	require.Greater(t, m.SyncGroupSize.Mean(), 1.0)
+}
+
+// notifyingTimer is a fakeTimer that notifies whenever it is started.
+type notifyingTimer struct {
+	fakeTimer
+	started chan struct{}
+}
+
+func (t *notifyingTimer) Reset(d time.Duration) bool {
+	t.started <- struct{}{}
+	return false
+}
+
+func TestPeriodicSync(t *testing.T) {
+	const syncInterval = 5 * time.Millisecond
+
+	f := &syncFile{}
+	w := NewLogWriter(f, 0, LogWriterConfig{
+		WALSyncInterval: syncInterval,
+		WALFsyncLatency: prometheus.NewHistogram(prometheus.HistogramOpts{}),
+	})
+
+	timer := notifyingTimer{started: make(chan struct{}, 1)}
+	w.afterFunc = func(d time.Duration, f func()) syncTimer {
+		require.Equal(t, syncInterval, d)
+		timer.f = f
+		timer.Reset(d)
+		return &timer
+	}
+	waitForWrite := func(pos int64) {
+		require.NoError(t, try(time.Millisecond, 5*time.Second, func() error {
+			if v := f.writePos.Load(); v <= pos {
+				return errors.Errorf("expected writePos > %d, but found %d", pos, v)
+			}
+			return nil
+		}))
+	}
+
+	// A record written without requesting a sync starts the timer, and is
+	// synced once it fires.
+	_, err := w.WriteRecord(bytes.Repeat([]byte{'a'}, 100))
+	require.NoError(t, err)
+	<-timer.started
+	waitForWrite(0)
+	require.Zero(t, f.syncPos.Load())
+	timer.f()
+	require.NoError(t, try(time.Millisecond, 5*time.Second, func() error {
+		if w, s := f.writePos.Load(), f.syncPos.Load(); w != s {
+			return errors.Errorf("expected syncPos %d, but found %d", w, s)
+		}
+		return nil
+	}))
+
+	// A record requesting a sync waits for the timer.
+	syncPos := f.syncPos.Load()
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	_, err = w.SyncRecord(bytes.Repeat([]byte{'a'}, 100), wg, new(error))
+	require.NoError(t, err)
+	<-timer.started
+	waitForWrite(syncPos)
+	require.Equal(t, syncPos, f.syncPos.Load())
+	timer.f()
+	wg.Wait()
+	require.Equal(t, f.writePos.Load(), f.syncPos.Load())
+
+	require.NoError(t, w.Close())
+}
+
 type syncFileWithWait struct {
 	f       syncFile
 	writeWG sync.WaitGroup
@@ -0,0 +1,158 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/hotkeys"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/objstorage"
+)
+
+// remoteTieringMaxCheckInterval is the maximum interval at which the remote
+// tiering loop attempts to schedule compactions. Tiering compactions are also
+// picked whenever any other compaction is scheduled.
+const remoteTieringMaxCheckInterval = time.Minute
+
+// remoteTieringLoop periodically attempts to schedule compactions so that
+// bottommost tables are tiered as they age, even in the absence of other
+// activity that schedules compactions. It exits when the DB is closed.
+func (d *DB) remoteTieringLoop() {
+	defer d.compactionSchedulers.Done()
+
+	ticker := time.NewTicker(min(d.opts.Experimental.RemoteTieringMinAge, remoteTieringMaxCheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.closedCh:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			d.maybeScheduleCompaction()
+			d.mu.Unlock()
+		}
+	}
+}
+
+// pickTieringCompaction picks a compaction that moves the oldest local
+// physical table in the bottommost level that is at least
+// Options.Experimental.RemoteTieringMinAge old, and that has not been read
+// (according to read sampling) for at least as long, onto shared storage. It
+// returns nil if remote tiering is disabled or there is no such table.
+//
+// REQUIRES: d.mu and d.mu.versions.logLock are held.
+func (d *DB) pickTieringCompaction(env compactionEnv) *pickedCompaction {
+	minAge := d.opts.Experimental.RemoteTieringMinAge
+	if minAge <= 0 {
+		return nil
+	}
+	now := d.timeNow()
+	if now.Before(d.mu.compact.tieringNextScan) {
+		return nil
+	}
+
+	vers := d.mu.versions.currentVersion()
+	cutoff := now.Add(-minAge)
+	nextScan := now.Add(minAge)
+	var candidate *tableMetadata
+	var compacting bool
+	// The hot keys are loaded once, when the first eligible table is found.
+	keepHotLocal := d.hotKeys != nil && d.opts.Experimental.HotKeys.KeepHotTablesLocal
+	var hotKeys []hotkeys.Key
+	for f := range vers.Levels[numLevels-1].All() {
+		// Virtual tables can't be copied byte-for-byte; they are tiered once
+		// their data is rewritten into physical tables by compactions.
+		if f.Virtual || !objstorage.IsLocalTable(d.objProvider, f.FileBacking.DiskFileNum) {
+			continue
+		}
+		// A table is cold once both its creation and its most recent sampled
+		// read are at least minAge in the past.
+		coldSince := time.Unix(f.CreationTime, 0)
+		if lastAccess := f.Access.LastAccess(); lastAccess.After(coldSince) {
+			coldSince = lastAccess
+		}
+		if coldSince.After(cutoff) {
+			// Keep track of when the table becomes eligible, so we know when to
+			// scan again.
+			if eligibleAt := coldSince.Add(minAge); eligibleAt.Before(nextScan) {
+				nextScan = eligibleAt
+			}
+			continue
+		}
+		if f.IsCompacting() {
+			compacting = true
+			continue
+		}
+		if keepHotLocal {
+			if hotKeys == nil {
+				hotKeys = d.hotKeys.Top()
+				keepHotLocal = len(hotKeys) > 0
+			}
+			if keepHotLocal && d.containsHotKey(f, hotKeys) {
+				// The table's hot keys may cool down; scan again later.
+				if eligibleAt := now.Add(remoteTieringMaxCheckInterval); eligibleAt.Before(nextScan) {
+					nextScan = eligibleAt
+				}
+				continue
+			}
+		}
+		if candidate == nil || f.CreationTime < candidate.CreationTime {
+			candidate = f
+		}
+	}
+	if candidate == nil {
+		// If an eligible table is being compacted, its compaction may be
+		// cancelled. Scan again next time.
+		if !compacting {
+			d.mu.compact.tieringNextScan = nextScan
+		}
+		return nil
+	}
+
+	pc := newPickedCompaction(d.opts, vers, d.mu.versions.l0Organizer,
+		numLevels-1, numLevels-1, d.mu.versions.picker.getBaseLevel())
+	pc.kind = compactionKindTiering
+	pc.startLevel.files = manifest.NewLevelSliceKeySorted(d.cmp, []*tableMetadata{candidate})
+	if !pc.setupInputs(d.opts, env.diskAvailBytes, pc.startLevel) {
+		return nil
+	}
+	// Fail-safe to protect against compacting the same sstable concurrently.
+	if inputRangeAlreadyCompacting(env, pc) {
+		return nil
+	}
+	return pc
+}
+
+// copyRemoteTableToLocal copies the physical table inputMeta, which must be
+// stored on shared storage, into a new local object for newMeta.
+func (d *DB) copyRemoteTableToLocal(
+	ctx context.Context, inputMeta *tableMetadata, newMeta *tableMetadata,
+) error {
+	src, err := d.objProvider.OpenForReading(
+		ctx, base.FileTypeTable, inputMeta.FileBacking.DiskFileNum, objstorage.OpenOptions{},
+	)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	rh := src.NewReadHandle(objstorage.NoReadBefore)
+	rh.SetupForCompaction()
+	defer rh.Close()
+
+	w, _, err := d.objProvider.Create(
+		ctx, base.FileTypeTable, newMeta.FileBacking.DiskFileNum, objstorage.CreateOptions{},
+	)
+	if err != nil {
+		return err
+	}
+	if err := objstorage.Copy(ctx, rh, w, 0, uint64(src.Size())); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Finish()
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteTiering(t *testing.T) {
+	var opts Options
+	opts.FS = vfs.NewMem()
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedTiered
+	opts.Experimental.RemoteTieringMinAge = time.Hour
+	opts.Logger = testLogger{t}
+
+	d, err := Open("", &opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.SetCreatorID(1))
+
+	var offset atomic.Int64
+	d.mu.Lock()
+	d.timeNow = func() time.Time {
+		return time.Now().Add(time.Duration(offset.Load()))
+	}
+	d.mu.Unlock()
+
+	key := func(i int) []byte {
+		return []byte(fmt.Sprintf("k%02d", i))
+	}
+	const numKeys = 10
+	for i := 0; i < numKeys; i++ {
+		require.NoError(t, d.Set(key(i), key(i), nil))
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.Compact(key(0), key(numKeys), false))
+
+	// bottommostTables returns the number of local and remote tables in L6.
+	bottommostTables := func() (local, remote int) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for f := range d.mu.versions.currentVersion().Levels[numLevels-1].All() {
+			if objstorage.IsLocalTable(d.objProvider, f.FileBacking.DiskFileNum) {
+				local++
+			} else {
+				remote++
+			}
+		}
+		return local, remote
+	}
+	scheduleCompactions := func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.maybeScheduleCompaction()
+	}
+	verifyData := func() {
+		for i := 0; i < numKeys; i++ {
+			v, closer, err := d.Get(key(i))
+			require.NoError(t, err)
+			require.Equal(t, key(i), v)
+			require.NoError(t, closer.Close())
+		}
+	}
+
+	// Young tables are not tiered.
+	scheduleCompactions()
+	local, remoteTables := bottommostTables()
+	require.Greater(t, local, 0)
+	require.Zero(t, remoteTables)
+	require.Zero(t, d.Metrics().Compact.TieringCount)
+
+	// Tables that are old enough but were recently read are not tiered.
+	offset.Store(int64(2 * time.Hour))
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	iter.readSampling.forceReadSampling = true
+	for valid := iter.First(); valid; valid = iter.Next() {
+	}
+	require.NoError(t, iter.Close())
+	scheduleCompactions()
+	local, remoteTables = bottommostTables()
+	require.Greater(t, local, 0)
+	require.Zero(t, remoteTables)
+	require.Greater(t, d.Metrics().Levels[numLevels-1].Additional.SampledReads, uint64(0))
+
+	// Once the tables have not been read for long enough, they are moved onto
+	// shared storage.
+	offset.Store(int64(4 * time.Hour))
+	scheduleCompactions()
+	require.Eventually(t, func() bool {
+		local, _ := bottommostTables()
+		return local == 0
+	}, 10*time.Second, time.Millisecond)
+	_, remoteTables = bottommostTables()
+	require.Greater(t, remoteTables, 0)
+	require.Equal(t, int64(remoteTables), d.Metrics().Compact.TieringCount)
+	verifyData()
+
+	// Download moves the tables back onto local storage. The downloaded tables
+	// are considered new, so they are not immediately tiered again.
+	require.NoError(t, d.Download(context.Background(), []DownloadSpan{{
+		StartKey:               key(0),
+		EndKey:                 key(numKeys),
+		ViaBackingFileDownload: true,
+		IncludeSharedTables:    true,
+	}}))
+	scheduleCompactions()
+	local, remoteTables = bottommostTables()
+	require.Greater(t, local, 0)
+	require.Zero(t, remoteTables)
+	verifyData()
+}
+
+func TestRemoteTieringKeepHotTablesLocal(t *testing.T) {
+	var opts Options
+	opts.FS = vfs.NewMem()
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedTiered
+	opts.Experimental.RemoteTieringMinAge = time.Hour
+	opts.Experimental.HotKeys = HotKeysOptions{TopK: 1, SampleRate: 1, KeepHotTablesLocal: true}
+	opts.Logger = testLogger{t}
+
+	d, err := Open("", &opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.SetCreatorID(1))
+
+	require.NoError(t, d.Set([]byte("a"), []byte("a"), nil))
+	require.NoError(t, d.Set([]byte("b"), []byte("b"), nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact([]byte("a"), []byte("c"), false))
+
+	// The table is old enough to be tiered, but it contains a hot key. The
+	// tables are scanned again once the hot key may have cooled down.
+	d.mu.Lock()
+	now := time.Now().Add(2 * time.Hour)
+	d.timeNow = func() time.Time { return now }
+	d.mu.compact.tieringNextScan = time.Time{}
+	d.maybeScheduleCompaction()
+	for d.mu.compact.compactingCount > 0 {
+		d.mu.compact.cond.Wait()
+	}
+	require.Equal(t, now.Add(remoteTieringMaxCheckInterval), d.mu.compact.tieringNextScan)
+	d.mu.Unlock()
+	require.Zero(t, d.Metrics().Compact.TieringCount)
+	require.Equal(t, "a", string(d.HotKeys()[0].Key))
+}
@@ -0,0 +1,641 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/batchrepr"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/record"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/vfs/atomicfs"
+	"github.com/cockroachdb/pebble/wal"
+)
+
+// RepairQuarantineDir is the name of the directory, within the database
+// directory, into which Repair moves the files it doesn't use.
+const RepairQuarantineDir = "quarantine"
+
+// RepairReport describes the outcome of Repair.
+type RepairReport struct {
+	// Manifest is the path of the MANIFEST written by Repair.
+	Manifest string
+	// RecoveredManifest is the name of the previous MANIFEST whose LSM was
+	// recovered, or empty if none of the previous MANIFESTs could be read.
+	RecoveredManifest string
+	// Tables holds the file numbers of the tables recovered into the new
+	// MANIFEST.
+	Tables []base.DiskFileNum
+	// WALs holds the numbers of the WALs that are replayed when the database
+	// is next opened.
+	WALs []wal.NumWAL
+	// Quarantined holds the tables that couldn't be read. They were moved to
+	// RepairQuarantineDir and their keys are lost.
+	Quarantined []RepairLostTable
+	// Unplaced holds the readable tables that the recovered MANIFEST doesn't
+	// reference and that couldn't be placed in the LSM: their sequence numbers
+	// interleave with those of a recovered table whose keys they overlap, or
+	// with those of a WAL that is replayed. Such tables are usually the
+	// outputs of compactions or flushes that weren't recorded, whose keys are
+	// also in the recovered tables or in the replayed WALs. They were moved to
+	// RepairQuarantineDir.
+	Unplaced []base.DiskFileNum
+	// ObsoleteManifests holds the names of the previous MANIFESTs, which were
+	// moved to RepairQuarantineDir.
+	ObsoleteManifests []string
+}
+
+// RepairLostTable describes a table that Repair couldn't read.
+type RepairLostTable struct {
+	// FileNum is the table's file number.
+	FileNum base.DiskFileNum
+	// Err is the corruption encountered when reading the table.
+	Err error
+	// Lost is the range of the point keys of the table, whose keys are lost.
+	// Start or End is nil if that bound couldn't be read.
+	Lost KeyRange
+}
+
+// Repair reconstructs a MANIFEST for the database in dirname from the files
+// that survive in the directory, for a database that can't be opened because
+// its MANIFEST is corrupted or missing. The database must not be open.
+//
+// Repair replays the newest MANIFEST in the directory whose first edit can be
+// read, up to its first edit that can't be, and every table in the directory
+// is read in full. The readable tables that are live in the replayed MANIFEST
+// keep their levels. The tables it removed from the LSM are left to be deleted
+// as obsolete once the database is opened. The other readable tables (all of
+// them, if no MANIFEST can be read) are placed in L0, in the order of their
+// sequence numbers, if their sequence numbers are above those of every table
+// already placed that overlaps their keys; otherwise, they're moved to
+// RepairQuarantineDir (see RepairReport.Unplaced), since L0 can't order them
+// without changing which keys the range deletions shadow. The tables that are
+// corrupted are moved to RepairQuarantineDir, as are the previous MANIFESTs.
+//
+// The WALs in the directories of the database (including Options.WALDir,
+// Options.WALFailover and Options.WALRecoveryDirs) are left in place. The
+// WALs that precede the replayed MANIFEST's minimum unflushed WAL, and the
+// WALs whose batches are all in the tables placed in L0, are deleted as
+// obsolete once the database is opened; the others are replayed, as they
+// would be after a crash.
+//
+// The edits of the replayed MANIFEST after its first unreadable one are lost,
+// as are the tables stored inline in the MANIFEST (see
+// Options.Experimental.InlineTableMaxSize) and remote tables. If no MANIFEST
+// can be read, the keys of the tables backing virtual tables are all
+// recovered, which can resurrect keys that were deleted by an excise, and the
+// keys of ingested tables are recovered with the sequence number zero.
+// Databases with blob files aren't supported.
+func Repair(dirname string, opts *Options) (*RepairReport, error) {
+	opts = opts.Clone()
+	opts.EnsureDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	fs := opts.FS
+	lock, err := LockDirectory(dirname, fs)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Close()
+
+	ls, err := fs.List(dirname)
+	if err != nil {
+		return nil, err
+	}
+	fmv, fmvMarker, err := lookupFormatMajorVersion(fs, dirname, ls)
+	if err != nil {
+		return nil, err
+	}
+	if err := fmvMarker.Close(); err != nil {
+		return nil, err
+	}
+	if fmv == FormatDefault {
+		return nil, errors.Newf("pebble: no database in %q", dirname)
+	}
+
+	walDirs := []wal.Dir{{FS: fs, Dirname: dirname}}
+	if opts.WALDir != "" && opts.WALDir != dirname {
+		walDirs = append(walDirs, wal.Dir{FS: fs, Dirname: opts.WALDir})
+	}
+	if opts.WALFailover != nil {
+		walDirs = append(walDirs, opts.WALFailover.Secondary)
+		walDirs = append(walDirs, opts.WALFailover.AdditionalSecondaries...)
+	}
+	walDirs = append(walDirs, opts.WALRecoveryDirs...)
+	walDirs = slices.DeleteFunc(walDirs, func(d wal.Dir) bool {
+		_, err := d.FS.Stat(d.Dirname)
+		return oserror.IsNotExist(err)
+	})
+	wals, err := wal.Scan(walDirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RepairReport{}
+	var nextFileNum base.DiskFileNum
+	var tables, manifests []base.DiskFileNum
+	for _, filename := range ls {
+		ft, fileNum, ok := base.ParseFilename(fs, filename)
+		if !ok {
+			continue
+		}
+		nextFileNum = max(nextFileNum, fileNum+1)
+		switch ft {
+		case base.FileTypeTable:
+			tables = append(tables, fileNum)
+		case base.FileTypeManifest:
+			report.ObsoleteManifests = append(report.ObsoleteManifests, filename)
+			manifests = append(manifests, fileNum)
+		case base.FileTypeBlob:
+			return nil, errors.Newf("pebble: repairing a database with blob files is not supported")
+		}
+	}
+	slices.Sort(tables)
+	slices.Sort(manifests)
+	for _, w := range wals {
+		nextFileNum = max(nextFileNum, base.DiskFileNum(w.Num)+1)
+	}
+	ve := &versionEdit{
+		ComparerName:       opts.Comparer.Name,
+		MinUnflushedLogNum: nextFileNum,
+	}
+	if len(wals) > 0 {
+		ve.MinUnflushedLogNum = base.DiskFileNum(wals[0].Num)
+	}
+
+	// Recover the LSM recorded in the newest readable MANIFEST.
+	var rm *repairManifest
+	for i := len(manifests) - 1; i >= 0 && rm == nil; i-- {
+		path := base.MakeFilepath(fs, dirname, base.FileTypeManifest, manifests[i])
+		if rm, err = repairReadManifest(fs, path, opts.Comparer.Name); err != nil {
+			return nil, err
+		}
+		if rm != nil {
+			report.RecoveredManifest = fs.PathBase(path)
+			nextFileNum = max(nextFileNum, rm.nextFileNum)
+			ve.MinUnflushedLogNum = max(ve.MinUnflushedLogNum, rm.minUnflushedLogNum)
+			ve.LastSeqNum = rm.lastSeqNum
+		}
+	}
+
+	// Read every table, keeping the levels of the tables that are live in the
+	// recovered MANIFEST.
+	ctx := context.Background()
+	var quarantined []string
+	var unknown []*tableMetadata
+	for _, fileNum := range tables {
+		path := base.MakeFilepath(fs, dirname, base.FileTypeTable, fileNum)
+		meta, err := repairLoadTable(ctx, opts, path, fileNum)
+		if err != nil {
+			if !base.IsCorruptionError(err) {
+				return nil, errors.Wrapf(err, "pebble: reading table %s", fileNum)
+			}
+			report.Quarantined = append(report.Quarantined, RepairLostTable{
+				FileNum: fileNum,
+				Err:     err,
+				Lost:    repairLostRange(ctx, opts, path),
+			})
+			quarantined = append(quarantined, fs.PathBase(path))
+			continue
+		}
+		if rm != nil {
+			if entries, ok := rm.tables[fileNum]; ok {
+				report.Tables = append(report.Tables, fileNum)
+				ve.NewTables = append(ve.NewTables, entries...)
+				if backing, ok := rm.backings[fileNum]; ok {
+					ve.CreatedBackingTables = append(ve.CreatedBackingTables, backing)
+				}
+				continue
+			}
+			if _, ok := rm.obsolete[fileNum]; ok {
+				continue
+			}
+		}
+		if meta == nil {
+			// The table is empty. It's deleted as an obsolete table once the
+			// database is opened.
+			continue
+		}
+		unknown = append(unknown, meta)
+	}
+
+	// Read the range of the sequence numbers of the WALs that may be replayed.
+	var walSeqNums []repairWALSeqNums
+	for _, w := range wals {
+		if base.DiskFileNum(w.Num) >= ve.MinUnflushedLogNum {
+			walSeqNums = append(walSeqNums, repairReadWALSeqNums(w))
+		}
+	}
+
+	// Place the other tables in L0, above every table whose keys they overlap.
+	// A table whose sequence numbers interleave with those of such a table, or
+	// with those of a WAL that is replayed, can't be ordered in L0.
+	slices.SortFunc(unknown, func(a, b *tableMetadata) int {
+		if c := cmp.Compare(a.SmallestSeqNum, b.SmallestSeqNum); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.LargestSeqNum, b.LargestSeqNum)
+	})
+	var flushedSeqNum base.SeqNum
+	for _, meta := range unknown {
+		if !repairCanPlaceInL0(opts.Comparer.Compare, meta, ve.NewTables, walSeqNums) {
+			fileNum := meta.FileBacking.DiskFileNum
+			report.Unplaced = append(report.Unplaced, fileNum)
+			quarantined = append(quarantined, fs.PathBase(base.MakeFilepath(fs, dirname, base.FileTypeTable, fileNum)))
+			continue
+		}
+		report.Tables = append(report.Tables, meta.FileBacking.DiskFileNum)
+		ve.NewTables = append(ve.NewTables, newTableEntry{Level: 0, Meta: meta})
+		flushedSeqNum = max(flushedSeqNum, meta.LargestSeqNum)
+	}
+	slices.Sort(report.Tables)
+	for _, nte := range ve.NewTables {
+		ve.LastSeqNum = max(ve.LastSeqNum, nte.Meta.LargestSeqNum)
+	}
+
+	// The WALs whose batches are all in the tables placed in L0 were flushed:
+	// replaying them would apply their batches twice.
+	for _, w := range walSeqNums {
+		if !w.readable || w.largest > flushedSeqNum {
+			break
+		}
+		ve.MinUnflushedLogNum = base.DiskFileNum(w.num) + 1
+	}
+	for _, w := range wals {
+		if base.DiskFileNum(w.Num) >= ve.MinUnflushedLogNum {
+			report.WALs = append(report.WALs, w.Num)
+		}
+	}
+
+	// Write the new MANIFEST and point the marker to it.
+	manifestNum := nextFileNum
+	ve.NextFileNum = uint64(manifestNum + 1)
+	report.Manifest = base.MakeFilepath(fs, dirname, base.FileTypeManifest, manifestNum)
+	if err := repairWriteManifest(fs, report.Manifest, ve); err != nil {
+		return nil, err
+	}
+	marker, _, err := atomicfs.LocateMarkerInListing(fs, dirname, manifestMarkerName, ls)
+	if err != nil {
+		return nil, err
+	}
+	if err := marker.Move(fs.PathBase(report.Manifest)); err != nil {
+		return nil, errors.CombineErrors(err, marker.Close())
+	}
+	if err := errors.CombineErrors(marker.RemoveObsolete(), marker.Close()); err != nil {
+		return nil, err
+	}
+
+	// Move the unused files out of the way.
+	quarantined = append(quarantined, report.ObsoleteManifests...)
+	if len(quarantined) > 0 {
+		quarantineDir := fs.PathJoin(dirname, RepairQuarantineDir)
+		if err := fs.MkdirAll(quarantineDir, 0755); err != nil {
+			return nil, err
+		}
+		for _, name := range quarantined {
+			if err := fs.Rename(fs.PathJoin(dirname, name), fs.PathJoin(quarantineDir, name)); err != nil {
+				return nil, err
+			}
+		}
+		if err := repairSyncDir(fs, quarantineDir); err != nil {
+			return nil, err
+		}
+		if err := repairSyncDir(fs, dirname); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// repairManifest holds the LSM recorded in a MANIFEST.
+type repairManifest struct {
+	// tables holds the live tables, by the file that holds them: a physical
+	// table, or the backing of virtual tables.
+	tables map[base.DiskFileNum][]newTableEntry
+	// backings holds the backings of the live virtual tables.
+	backings map[base.DiskFileNum]*fileBacking
+	// obsolete holds the files of the tables that were removed from the LSM.
+	obsolete           map[base.DiskFileNum]struct{}
+	minUnflushedLogNum base.DiskFileNum
+	nextFileNum        base.DiskFileNum
+	lastSeqNum         base.SeqNum
+}
+
+// repairReadManifest replays the edits of the MANIFEST at path, up to the
+// first one that can't be read. It returns nil if the first edit can't be
+// read.
+func repairReadManifest(fs vfs.FS, path string, comparerName string) (*repairManifest, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type liveTable struct {
+		newTableEntry
+		fileNum base.DiskFileNum
+	}
+	live := make(map[base.FileNum]liveTable)
+	backings := make(map[base.DiskFileNum]*fileBacking)
+	added := make(map[base.DiskFileNum]struct{})
+	rm := &repairManifest{}
+	rr := record.NewReader(f, 0 /* logNum */)
+	var edits int
+	for ; ; edits++ {
+		r, err := rr.Next()
+		if err != nil {
+			break
+		}
+		var ve versionEdit
+		if err := ve.Decode(r); err != nil {
+			break
+		}
+		if ve.ComparerName != "" && ve.ComparerName != comparerName {
+			return nil, errors.Errorf("pebble: manifest file %q: "+
+				"comparer name from file %q != comparer name from Options %q",
+				errors.Safe(fs.PathBase(path)), errors.Safe(ve.ComparerName), errors.Safe(comparerName))
+		}
+		for dte := range ve.DeletedTables {
+			delete(live, dte.FileNum)
+		}
+		for _, b := range ve.CreatedBackingTables {
+			backings[b.DiskFileNum] = b
+		}
+		for _, nte := range ve.NewTables {
+			t := liveTable{newTableEntry: nte, fileNum: base.PhysicalTableDiskFileNum(nte.Meta.FileNum)}
+			if nte.Meta.Virtual {
+				t.fileNum = nte.BackingFileNum
+			}
+			live[nte.Meta.FileNum] = t
+			added[t.fileNum] = struct{}{}
+		}
+		if ve.MinUnflushedLogNum != 0 {
+			rm.minUnflushedLogNum = ve.MinUnflushedLogNum
+		}
+		if ve.NextFileNum != 0 {
+			rm.nextFileNum = base.DiskFileNum(ve.NextFileNum)
+		}
+		rm.lastSeqNum = max(rm.lastSeqNum, ve.LastSeqNum)
+	}
+	if edits == 0 {
+		return nil, nil
+	}
+
+	rm.tables = make(map[base.DiskFileNum][]newTableEntry)
+	rm.backings = make(map[base.DiskFileNum]*fileBacking)
+	for _, fileNum := range slices.Sorted(maps.Keys(live)) {
+		t := live[fileNum]
+		if t.Meta.Virtual {
+			backing, ok := backings[t.fileNum]
+			if !ok {
+				continue
+			}
+			t.Meta.FileBacking = backing
+			rm.backings[t.fileNum] = backing
+		}
+		rm.tables[t.fileNum] = append(rm.tables[t.fileNum], newTableEntry{Level: t.Level, Meta: t.Meta})
+	}
+	rm.obsolete = make(map[base.DiskFileNum]struct{})
+	for fileNum := range added {
+		if _, ok := rm.tables[fileNum]; !ok {
+			rm.obsolete[fileNum] = struct{}{}
+		}
+	}
+	return rm, nil
+}
+
+// repairWALSeqNums holds the range of the sequence numbers of the batches in a
+// WAL.
+type repairWALSeqNums struct {
+	num               wal.NumWAL
+	smallest, largest base.SeqNum
+	// readable is false if the WAL couldn't be read up to its end, in which
+	// case the range is incomplete.
+	readable bool
+}
+
+// repairReadWALSeqNums reads the headers of the batches in the WAL. The WAL
+// may end with a torn write, as it would after a crash.
+func repairReadWALSeqNums(ll wal.LogicalLog) repairWALSeqNums {
+	w := repairWALSeqNums{num: ll.Num}
+	rr := ll.OpenForRead()
+	defer rr.Close()
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		r, _, err := rr.NextRecord()
+		if err == nil {
+			_, err = io.Copy(&buf, r)
+		}
+		if err != nil {
+			w.readable = errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+			return w
+		}
+		h, ok := batchrepr.ReadHeader(buf.Bytes())
+		if !ok {
+			return w
+		}
+		if h.Count == 0 {
+			continue
+		}
+		if w.largest == 0 {
+			w.smallest = h.SeqNum
+		}
+		w.largest = max(w.largest, h.SeqNum+base.SeqNum(h.Count)-1)
+	}
+}
+
+// repairCanPlaceInL0 returns true if the table can be placed in L0, above the
+// tables already placed: its sequence numbers must be above those of every
+// placed table whose keys it overlaps, and it must not hold part of the
+// batches of a WAL that may be replayed.
+func repairCanPlaceInL0(
+	cmp base.Compare, meta *tableMetadata, placed []newTableEntry, wals []repairWALSeqNums,
+) bool {
+	bounds := meta.UserKeyBounds()
+	for _, nte := range placed {
+		if nte.Meta.Overlaps(cmp, &bounds) && nte.Meta.LargestSeqNum >= meta.SmallestSeqNum {
+			return false
+		}
+	}
+	for _, w := range wals {
+		if w.largest != 0 && w.smallest <= meta.LargestSeqNum && meta.LargestSeqNum < w.largest {
+			return false
+		}
+	}
+	return true
+}
+
+// repairLoadTable reads the table at path in full, and returns its metadata,
+// or nil if the table is empty.
+func repairLoadTable(
+	ctx context.Context, opts *Options, path string, fileNum base.DiskFileNum,
+) (*tableMetadata, error) {
+	r, size, err := repairOpenTable(ctx, opts, path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if err := r.ValidateBlockChecksums(); err != nil {
+		return nil, err
+	}
+	if r.Properties.NumValuesInBlobFiles > 0 {
+		return nil, base.CorruptionErrorf("pebble: table %s references blob files", fileNum)
+	}
+
+	meta := &tableMetadata{
+		FileNum:        base.PhysicalTableFileNum(fileNum),
+		Size:           uint64(size),
+		CreationTime:   time.Now().Unix(),
+		SmallestSeqNum: base.SeqNumMax,
+	}
+	addSeqNum := func(seqNum base.SeqNum) {
+		meta.SmallestSeqNum = min(meta.SmallestSeqNum, seqNum)
+		meta.LargestSeqNum = max(meta.LargestSeqNum, seqNum)
+	}
+	cmp := opts.Comparer.Compare
+
+	iter, err := r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */)
+	if err != nil {
+		return nil, err
+	}
+	var smallest, largest InternalKey
+	var valBuf []byte
+	for kv := iter.First(); kv != nil; kv = iter.Next() {
+		if smallest.UserKey == nil {
+			smallest = kv.K.Clone()
+		}
+		largest.CopyFrom(kv.K)
+		addSeqNum(kv.SeqNum())
+		// Read the value to verify its checksum, if it's stored out of line.
+		if _, _, err := kv.Value(valBuf[:0]); err != nil {
+			return nil, errors.CombineErrors(err, iter.Close())
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if smallest.UserKey != nil {
+		meta.ExtendPointKeyBounds(cmp, smallest, largest)
+	}
+
+	spanIters := func(fn func(*keyspan.Span)) func(keyspan.FragmentIterator, error) error {
+		return func(iter keyspan.FragmentIterator, err error) error {
+			if err != nil || iter == nil {
+				return err
+			}
+			defer iter.Close()
+			s, err := iter.First()
+			for ; s != nil; s, err = iter.Next() {
+				for i := range s.Keys {
+					addSeqNum(s.Keys[i].SeqNum())
+				}
+				fn(s)
+			}
+			return err
+		}
+	}
+	if err := spanIters(func(s *keyspan.Span) {
+		meta.ExtendPointKeyBounds(cmp, s.SmallestKey().Clone(), s.LargestKey().Clone())
+	})(r.NewRawRangeDelIter(ctx, sstable.NoFragmentTransforms, block.NoReadEnv)); err != nil {
+		return nil, err
+	}
+	if err := spanIters(func(s *keyspan.Span) {
+		meta.ExtendRangeKeyBounds(cmp, s.SmallestKey().Clone(), s.LargestKey().Clone())
+	})(r.NewRawRangeKeyIter(ctx, sstable.NoFragmentTransforms, block.NoReadEnv)); err != nil {
+		return nil, err
+	}
+
+	if !meta.HasPointKeys && !meta.HasRangeKeys {
+		return nil, nil
+	}
+	meta.LargestSeqNumAbsolute = meta.LargestSeqNum
+	meta.InitPhysicalBacking()
+	return meta, nil
+}
+
+// repairLostRange returns the range of the point keys of a corrupted table, as
+// far as it can be read.
+func repairLostRange(ctx context.Context, opts *Options, path string) KeyRange {
+	r, _, err := repairOpenTable(ctx, opts, path)
+	if err != nil {
+		return KeyRange{}
+	}
+	defer r.Close()
+	iter, err := r.NewIter(sstable.NoTransforms, nil /* lower */, nil /* upper */)
+	if err != nil {
+		return KeyRange{}
+	}
+	defer iter.Close()
+	var lost KeyRange
+	if kv := iter.First(); kv != nil {
+		lost.Start = slices.Clone(kv.K.UserKey)
+	}
+	if kv := iter.Last(); kv != nil {
+		lost.End = opts.Comparer.ImmediateSuccessor(nil, kv.K.UserKey)
+	}
+	return lost
+}
+
+func repairOpenTable(
+	ctx context.Context, opts *Options, path string,
+) (_ *sstable.Reader, size int64, _ error) {
+	f, err := opts.FS.Open(path, vfs.RandomReadsOption)
+	if err != nil {
+		return nil, 0, err
+	}
+	readable, err := sstable.NewSimpleReadable(f)
+	if err != nil {
+		return nil, 0, errors.CombineErrors(err, f.Close())
+	}
+	size = readable.Size()
+	r, err := sstable.NewReader(ctx, readable, opts.MakeReaderOptions())
+	return r, size, err
+}
+
+func repairSyncDir(fs vfs.FS, dirname string) error {
+	dir, err := fs.OpenDir(dirname)
+	if err != nil {
+		return err
+	}
+	return errors.CombineErrors(dir.Sync(), dir.Close())
+}
+
+func repairWriteManifest(fs vfs.FS, path string, ve *versionEdit) (err error) {
+	f, err := fs.Create(path, "pebble-manifest")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.CombineErrors(err, fs.Remove(path))
+		}
+	}()
+	w := record.NewWriter(f)
+	rw, err := w.Next()
+	if err == nil {
+		err = ve.Encode(rw)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	return errors.CombineErrors(err, f.Close())
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/datadriven"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/cockroachdb/pebble/wal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		FS:                          mem,
+		DisableAutomaticCompactions: true,
+		Logger:                      testLogger{t},
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, d.Set([]byte(k), []byte(k+"-value"), nil))
+		require.NoError(t, d.Flush())
+	}
+	require.NoError(t, d.DeleteRange([]byte("a"), []byte("b"), nil))
+	require.NoError(t, d.Flush())
+	// The last write is only in the WAL.
+	require.NoError(t, d.Set([]byte("d"), []byte("d-value"), nil))
+	var tables []base.DiskFileNum
+	levels, err := d.SSTables()
+	require.NoError(t, err)
+	for _, level := range levels {
+		for _, table := range level {
+			tables = append(tables, base.PhysicalTableDiskFileNum(table.FileNum))
+		}
+	}
+	require.Len(t, tables, 4)
+	require.NoError(t, d.Close())
+
+	// Corrupt the table holding "b" and the MANIFEST.
+	repairRewriteFile(t, mem, base.MakeFilepath(mem, "", base.FileTypeTable, tables[1]), func(data []byte) {
+		data[0] ^= 0xff
+	})
+	for _, name := range repairListFiles(t, mem, base.FileTypeManifest) {
+		repairRewriteFile(t, mem, name, repairCorruptAll)
+	}
+	_, err = Open("", opts)
+	require.Error(t, err)
+
+	report, err := Repair("", opts)
+	require.NoError(t, err)
+	require.Equal(t, []base.DiskFileNum{tables[0], tables[2], tables[3]}, report.Tables)
+	require.Len(t, report.Quarantined, 1)
+	require.Equal(t, tables[1], report.Quarantined[0].FileNum)
+	require.True(t, base.IsCorruptionError(report.Quarantined[0].Err))
+	require.NotEmpty(t, report.WALs)
+	require.NotEmpty(t, report.ObsoleteManifests)
+	quarantined, err := mem.List(RepairQuarantineDir)
+	require.NoError(t, err)
+	require.Len(t, quarantined, 1+len(report.ObsoleteManifests))
+
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	// "a" is deleted by the recovered range deletion, and "b" is lost with the
+	// quarantined table.
+	require.Equal(t, "", repairGet(t, d, "a"))
+	require.Equal(t, "", repairGet(t, d, "b"))
+	require.Equal(t, "c-value", repairGet(t, d, "c"))
+	require.Equal(t, "d-value", repairGet(t, d, "d"))
+	require.NoError(t, d.Set([]byte("e"), []byte("e-value"), nil))
+	require.Equal(t, "e-value", repairGet(t, d, "e"))
+}
+
+func TestRepairLevels(t *testing.T) {
+	// b in L5 is newer than the range deletion in L6, but older than z: the
+	// range deletion would shadow b if both tables were ordered in L0 by their
+	// largest sequence numbers.
+	define := &datadriven.TestData{Cmd: "define", Input: `
+L5
+  b.SET.12:b-value
+L6
+  a.SET.8:a-value
+  a.RANGEDEL.10:c
+  z.SET.14:z-value
+`}
+	testCases := []struct {
+		name string
+		// corruptAll corrupts every MANIFEST, rather than only the newest one.
+		corruptAll bool
+	}{
+		{name: "previous-manifest"},
+		{name: "no-manifest", corruptAll: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mem := vfs.NewMem()
+			opts := &Options{
+				FS:                          mem,
+				DisableAutomaticCompactions: true,
+				Logger:                      testLogger{t},
+				// Rotate the MANIFEST on every edit, so that the previous
+				// MANIFEST holds the LSM before the flush below.
+				MaxManifestFileSize: 1,
+			}
+			d, err := runDBDefineCmdReuseFS(define, opts)
+			require.NoError(t, err)
+			require.NoError(t, d.Set([]byte("c"), []byte("c-value"), nil))
+			require.NoError(t, d.Flush())
+			require.NoError(t, d.Set([]byte("d"), []byte("d-value"), nil))
+			levels := repairTableLevels(t, d)
+			require.Len(t, levels, 3)
+			require.NoError(t, d.Close())
+
+			manifests := repairListFiles(t, mem, base.FileTypeManifest)
+			require.Len(t, manifests, 2)
+			corrupt := manifests[1:]
+			if tc.corruptAll {
+				corrupt = manifests
+			}
+			for _, name := range corrupt {
+				repairRewriteFile(t, mem, name, repairCorruptAll)
+			}
+
+			report, err := Repair("", opts)
+			require.NoError(t, err)
+			require.Empty(t, report.Quarantined)
+			d, err = Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+			require.Equal(t, "", repairGet(t, d, "a"))
+			require.Equal(t, "c-value", repairGet(t, d, "c"))
+			require.Equal(t, "d-value", repairGet(t, d, "d"))
+			require.Equal(t, "z-value", repairGet(t, d, "z"))
+
+			if !tc.corruptAll {
+				// The tables keep their levels, and the table flushed after
+				// the previous MANIFEST is placed in L0.
+				require.Equal(t, manifests[0], report.RecoveredManifest)
+				require.Empty(t, report.Unplaced)
+				recovered := repairTableLevels(t, d)
+				for fileNum, level := range levels {
+					require.Equal(t, level, recovered[fileNum])
+				}
+				require.Equal(t, "b-value", repairGet(t, d, "b"))
+				return
+			}
+			// Without a MANIFEST, the L5 table can't be ordered in L0 with
+			// the L6 table. It's moved to the quarantine directory rather than
+			// shadowed by the range deletion.
+			require.Equal(t, "", report.RecoveredManifest)
+			var l5 base.DiskFileNum
+			for fileNum, level := range levels {
+				if level == 5 {
+					l5 = fileNum
+				}
+			}
+			require.Equal(t, []base.DiskFileNum{l5}, report.Unplaced)
+			quarantined, err := mem.List(RepairQuarantineDir)
+			require.NoError(t, err)
+			require.Contains(t, quarantined, mem.PathBase(base.MakeFilepath(mem, "", base.FileTypeTable, l5)))
+			require.Equal(t, "", repairGet(t, d, "b"))
+		})
+	}
+}
+
+func TestRepairFlushedWAL(t *testing.T) {
+	for _, corruptManifest := range []bool{false, true} {
+		t.Run(fmt.Sprintf("corrupt-manifest=%t", corruptManifest), func(t *testing.T) {
+			mem := vfs.NewMem()
+			opts := &Options{FS: mem, Logger: testLogger{t}}
+			d, err := Open("", opts)
+			require.NoError(t, err)
+			require.NoError(t, d.Merge([]byte("m"), []byte("x"), nil))
+			// Save the WAL holding the first operand, which becomes obsolete
+			// once its memtable is flushed.
+			ls, err := mem.List("")
+			require.NoError(t, err)
+			var walName string
+			var walNum wal.NumWAL
+			for _, name := range ls {
+				if num, _, ok := wal.ParseLogFilename(name); ok {
+					walName, walNum = name, num
+				}
+			}
+			require.NotEmpty(t, walName)
+			require.NoError(t, vfs.Copy(mem, walName, "saved-wal"))
+			require.NoError(t, d.Flush())
+			require.NoError(t, d.Merge([]byte("m"), []byte("y"), nil))
+			require.NoError(t, d.Close())
+			// Restore the flushed WAL, as if it hadn't been deleted yet.
+			require.NoError(t, vfs.Copy(mem, "saved-wal", walName))
+			require.NoError(t, mem.Remove("saved-wal"))
+			if corruptManifest {
+				for _, name := range repairListFiles(t, mem, base.FileTypeManifest) {
+					repairRewriteFile(t, mem, name, repairCorruptAll)
+				}
+			}
+
+			report, err := Repair("", opts)
+			require.NoError(t, err)
+			require.Len(t, report.Tables, 1)
+			require.Len(t, report.WALs, 1)
+			require.NotEqual(t, walNum, report.WALs[0])
+			d, err = Open("", opts)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, d.Close()) }()
+			// The flushed operand isn't applied twice.
+			require.Equal(t, "xy", repairGet(t, d, "m"))
+		})
+	}
+}
+
+func repairGet(t *testing.T, d *DB, k string) string {
+	v, closer, err := d.Get([]byte(k))
+	if err == ErrNotFound {
+		return ""
+	}
+	require.NoError(t, err)
+	defer closer.Close()
+	return string(v)
+}
+
+// repairTableLevels returns the level of each table of the database.
+func repairTableLevels(t *testing.T, d *DB) map[base.DiskFileNum]int {
+	levels := make(map[base.DiskFileNum]int)
+	sstables, err := d.SSTables()
+	require.NoError(t, err)
+	for level, tables := range sstables {
+		for _, table := range tables {
+			levels[base.PhysicalTableDiskFileNum(table.FileNum)] = level
+		}
+	}
+	return levels
+}
+
+// repairListFiles returns the names of the files of the given type, in
+// increasing order of their file numbers.
+func repairListFiles(t *testing.T, fs vfs.FS, fileType base.FileType) []string {
+	ls, err := fs.List("")
+	require.NoError(t, err)
+	slices.Sort(ls)
+	var names []string
+	for _, name := range ls {
+		if ft, _, ok := base.ParseFilename(fs, name); ok && ft == fileType {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func repairRewriteFile(t *testing.T, fs vfs.FS, path string, fn func([]byte)) {
+	f, err := fs.Open(path)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	fn(data)
+	f, err = fs.Create(path, vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func repairCorruptAll(data []byte) {
+	for i := range data {
+		data[i] = 0xff
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// defaultScanCacheMaxResultSizeRatio is the default of
+// ScanCacheOptions.MaxResultSize, as a fraction of ScanCacheOptions.Size.
+const defaultScanCacheMaxResultSizeRatio = 16
+
+// scanKVOverhead approximates the memory used by a ScanKV besides its key and
+// value.
+const scanKVOverhead = 48
+
+// ScanCacheOptions configures the scan cache, which memoizes the results of
+// DB.Scan for read-mostly workloads that repeat the same small scans at a high
+// frequency. A cached result is dropped when a batch, ingestion or excise that
+// overlaps its key range is applied, so that it's never stale.
+//
+// Every write checks the cached results for overlap, so the scan cache is
+// meant for a modest number of distinct scans.
+type ScanCacheOptions struct {
+	// Size is the maximum total size of the cached results, in bytes. The
+	// least recently used results are evicted to stay under it. The scan cache
+	// is disabled if Size is not positive.
+	Size int64
+	// MaxResultSize is the size above which a result isn't cached. It
+	// defaults to Size/16.
+	MaxResultSize int64
+}
+
+// ScanKV is a key and its value, returned by DB.Scan.
+type ScanKV struct {
+	Key, Value []byte
+}
+
+// Scan returns the point keys in [lower, upper) and their values, in order.
+// If limit is positive, at most limit keys are returned.
+//
+// If the scan cache is enabled (see Options.Experimental.ScanCache), identical
+// scans (with the same bounds and limit) are served from the cache until a
+// write overlapping [lower, upper) is applied. The returned keys and values
+// may be shared with other callers and must not be modified.
+func (d *DB) Scan(ctx context.Context, lower, upper []byte, limit int) ([]ScanKV, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if lower == nil || upper == nil {
+		return nil, errors.New("pebble: scan requires lower and upper bounds")
+	}
+	c := d.scanCache
+	if c == nil {
+		return d.scan(ctx, lower, upper, limit)
+	}
+	key := scanCacheKey{lower: string(lower), upper: string(upper), limit: limit}
+	if kvs, ok := c.get(key); ok {
+		return kvs, nil
+	}
+	s := c.beginScan()
+	kvs, err := d.scan(ctx, lower, upper, limit)
+	c.endScan(s, key, kvs, err == nil)
+	return kvs, err
+}
+
+func (d *DB) scan(ctx context.Context, lower, upper []byte, limit int) ([]ScanKV, error) {
+	iter, err := d.NewIterWithContext(ctx, &IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return nil, err
+	}
+	var kvs []ScanKV
+	for valid := iter.First(); valid && (limit <= 0 || len(kvs) < limit); valid = iter.Next() {
+		value, err := iter.ValueAndErr()
+		if err != nil {
+			return nil, errors.CombineErrors(err, iter.Close())
+		}
+		kvs = append(kvs, ScanKV{
+			Key:   append([]byte(nil), iter.Key()...),
+			Value: append([]byte(nil), value...),
+		})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// ScanCacheMetrics holds the metrics of the scan cache.
+type ScanCacheMetrics struct {
+	// Count and Size are the number and size in bytes of the cached results.
+	Count int64
+	Size  int64
+	// Hits and Misses count the scans served and not served by the cache.
+	Hits   int64
+	Misses int64
+	// Invalidations is the number of cached results dropped because a write
+	// overlapped them.
+	Invalidations int64
+}
+
+type scanCacheKey struct {
+	lower, upper string
+	limit        int
+}
+
+type scanCacheEntry struct {
+	key    scanCacheKey
+	bounds base.UserKeyBounds
+	kvs    []ScanKV
+	size   int64
+	// prev and next link the entries of scanCache.mu.lru.
+	prev, next *scanCacheEntry
+}
+
+// scanCacheScan is a scan whose result is to be inserted into the cache.
+type scanCacheScan struct {
+	// seqNum is the visible sequence number when the scan started: the scan
+	// observes all the writes with lower sequence numbers.
+	seqNum     base.SeqNum
+	generation uint64
+}
+
+// scanCacheInvalidation records a write with sequence numbers up to seqNum
+// that overlaps bounds, or the whole keyspace if all is set.
+type scanCacheInvalidation struct {
+	bounds base.UserKeyBounds
+	all    bool
+	seqNum base.SeqNum
+}
+
+// scanCache memoizes the results of DB.Scan. See ScanCacheOptions.
+//
+// The writes are applied to the memtable (or the LSM, for ingestions) before
+// their sequence numbers become visible, at which point the cached results
+// they overlap are dropped. A scan that started before then may not observe
+// the write; the invalidations are kept until no such scan is in flight, and
+// the results of the scans they overlap aren't cached.
+type scanCache struct {
+	cmp           Compare
+	opts          ScanCacheOptions
+	visibleSeqNum func() base.SeqNum
+
+	mu struct {
+		sync.Mutex
+		entries map[scanCacheKey]*scanCacheEntry
+		// lru is the sentinel of the list of entries, most recently used
+		// first.
+		lru  scanCacheEntry
+		size int64
+		// generation is incremented when the cache is cleared by a change of
+		// the LSM that isn't ordered by sequence numbers.
+		generation    uint64
+		scans         []*scanCacheScan
+		invalidations []scanCacheInvalidation
+		metrics       ScanCacheMetrics
+	}
+}
+
+func newScanCache(
+	cmp Compare, opts ScanCacheOptions, visibleSeqNum func() base.SeqNum,
+) *scanCache {
+	c := &scanCache{cmp: cmp, opts: opts, visibleSeqNum: visibleSeqNum}
+	c.mu.entries = make(map[scanCacheKey]*scanCacheEntry)
+	c.mu.lru.prev, c.mu.lru.next = &c.mu.lru, &c.mu.lru
+	return c
+}
+
+func (c *scanCache) get(key scanCacheKey) ([]ScanKV, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.mu.entries[key]
+	if !ok {
+		c.mu.metrics.Misses++
+		return nil, false
+	}
+	c.mu.metrics.Hits++
+	c.unlinkLocked(e)
+	c.pushFrontLocked(e)
+	return e.kvs, true
+}
+
+func (c *scanCache) beginScan() *scanCacheScan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &scanCacheScan{seqNum: c.visibleSeqNum(), generation: c.mu.generation}
+	c.mu.scans = append(c.mu.scans, s)
+	return s
+}
+
+// endScan inserts the result of the scan, unless it failed or a write it
+// may not have observed overlaps it.
+func (c *scanCache) endScan(s *scanCacheScan, key scanCacheKey, kvs []ScanKV, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.mu.scans {
+		if c.mu.scans[i] == s {
+			c.mu.scans[i] = c.mu.scans[len(c.mu.scans)-1]
+			c.mu.scans = c.mu.scans[:len(c.mu.scans)-1]
+			break
+		}
+	}
+	defer c.pruneLocked()
+	if !ok || s.generation != c.mu.generation {
+		return
+	}
+	e := &scanCacheEntry{
+		key:    key,
+		bounds: base.UserKeyBoundsEndExclusive([]byte(key.lower), []byte(key.upper)),
+		kvs:    kvs,
+		size:   int64(len(key.lower) + len(key.upper)),
+	}
+	for i := range kvs {
+		e.size += int64(len(kvs[i].Key)+len(kvs[i].Value)) + scanKVOverhead
+	}
+	if e.size > c.opts.MaxResultSize {
+		return
+	}
+	for i := range c.mu.invalidations {
+		inv := &c.mu.invalidations[i]
+		if inv.seqNum >= s.seqNum && (inv.all || inv.bounds.Overlaps(c.cmp, &e.bounds)) {
+			return
+		}
+	}
+	if old, ok := c.mu.entries[key]; ok {
+		c.removeLocked(old)
+	}
+	c.mu.entries[key] = e
+	c.pushFrontLocked(e)
+	c.mu.size += e.size
+	for c.mu.size > c.opts.Size {
+		c.removeLocked(c.mu.lru.prev)
+	}
+}
+
+// invalidateBatch drops the results overlapping the keys of the batch, which
+// has been assigned its sequence numbers.
+func (c *scanCache) invalidateBatch(b *Batch) {
+	var bounds base.UserKeyBounds
+	for r := b.Reader(); ; {
+		kind, ukey, value, ok, err := r.Next()
+		if !ok || err != nil {
+			if err != nil {
+				// Be conservative with a batch that can't be read; it fails to
+				// apply anyway.
+				c.invalidate(nil, b.SeqNum()+base.SeqNum(b.Count()))
+				return
+			}
+			break
+		}
+		var kb base.UserKeyBounds
+		switch kind {
+		case InternalKeyKindLogData:
+			continue
+		case InternalKeyKindRangeDelete, InternalKeyKindRangeKeySet, InternalKeyKindRangeKeyUnset,
+			InternalKeyKindRangeKeyDelete:
+			kb = base.UserKeyBoundsEndExclusive(ukey, value)
+		default:
+			kb = base.UserKeyBoundsInclusive(ukey, ukey)
+		}
+		if bounds.Start == nil || c.cmp(kb.Start, bounds.Start) < 0 {
+			bounds.Start = kb.Start
+		}
+		if bounds.End.Key == nil || bounds.End.CompareUpperBounds(c.cmp, kb.End) < 0 {
+			bounds.End = kb.End
+		}
+	}
+	if bounds.Start != nil {
+		c.invalidate(&bounds, b.SeqNum()+base.SeqNum(b.Count())-1)
+	}
+}
+
+// invalidate drops the results overlapping the bounds, or all the results if
+// bounds is nil, for a write with sequence numbers up to seqNum.
+func (c *scanCache) invalidate(bounds *base.UserKeyBounds, seqNum base.SeqNum) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.mu.entries {
+		if bounds == nil || bounds.Overlaps(c.cmp, &e.bounds) {
+			c.removeLocked(e)
+			c.mu.metrics.Invalidations++
+		}
+	}
+	inv := scanCacheInvalidation{all: bounds == nil, seqNum: seqNum}
+	if bounds != nil {
+		inv.bounds = base.UserKeyBounds{
+			Start: append([]byte(nil), bounds.Start...),
+			End: base.UserKeyBoundary{
+				Key:  append([]byte(nil), bounds.End.Key...),
+				Kind: bounds.End.Kind,
+			},
+		}
+	}
+	c.mu.invalidations = append(c.mu.invalidations, inv)
+	c.pruneLocked()
+}
+
+// clear drops all the results, after a change of the LSM that isn't ordered by
+// sequence numbers and took effect immediately.
+func (c *scanCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.mu.entries {
+		c.removeLocked(e)
+		c.mu.metrics.Invalidations++
+	}
+	c.mu.generation++
+}
+
+// pruneLocked drops the invalidations that no scan in flight or started from
+// now on can miss.
+func (c *scanCache) pruneLocked() {
+	minSeqNum := c.visibleSeqNum()
+	for _, s := range c.mu.scans {
+		minSeqNum = min(minSeqNum, s.seqNum)
+	}
+	n := 0
+	for _, inv := range c.mu.invalidations {
+		if inv.seqNum >= minSeqNum {
+			c.mu.invalidations[n] = inv
+			n++
+		}
+	}
+	clear(c.mu.invalidations[n:])
+	c.mu.invalidations = c.mu.invalidations[:n]
+}
+
+func (c *scanCache) metrics() ScanCacheMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.mu.metrics
+	m.Count = int64(len(c.mu.entries))
+	m.Size = c.mu.size
+	return m
+}
+
+func (c *scanCache) pushFrontLocked(e *scanCacheEntry) {
+	e.prev, e.next = &c.mu.lru, c.mu.lru.next
+	e.prev.next, e.next.prev = e, e
+}
+
+func (c *scanCache) unlinkLocked(e *scanCacheEntry) {
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+func (c *scanCache) removeLocked(e *scanCacheEntry) {
+	c.unlinkLocked(e)
+	delete(c.mu.entries, e.key)
+	c.mu.size -= e.size
+}
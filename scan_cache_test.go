@@ -0,0 +1,116 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanCache(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{FS: mem}
+	opts.Experimental.ScanCache.Size = 1 << 20
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, d.Set([]byte(k), []byte(k+"1"), nil))
+	}
+	scan := func(lower, upper string, limit int) string {
+		kvs, err := d.Scan(context.Background(), []byte(lower), []byte(upper), limit)
+		require.NoError(t, err)
+		var parts []string
+		for _, kv := range kvs {
+			parts = append(parts, fmt.Sprintf("%s=%s", kv.Key, kv.Value))
+		}
+		return strings.Join(parts, " ")
+	}
+	metrics := func() ScanCacheMetrics { return d.Metrics().ScanCache }
+
+	require.Equal(t, "a=a1 b=b1 c=c1 d=d1", scan("a", "z", 0))
+	require.Equal(t, "a=a1 b=b1 c=c1 d=d1", scan("a", "z", 0))
+	require.Equal(t, "a=a1 b=b1", scan("a", "z", 2))
+	require.Equal(t, "c=c1", scan("c", "d", 0))
+	m := metrics()
+	require.Equal(t, int64(1), m.Hits)
+	require.Equal(t, int64(3), m.Misses)
+	require.Equal(t, int64(3), m.Count)
+
+	// A write invalidates the results it overlaps.
+	require.NoError(t, d.Set([]byte("b"), []byte("b2"), nil))
+	require.Equal(t, int64(2), metrics().Invalidations)
+	require.Equal(t, "c=c1", scan("c", "d", 0))
+	require.Equal(t, "a=a1 b=b2 c=c1 d=d1", scan("a", "z", 0))
+	require.NoError(t, d.Set([]byte("z"), []byte("z1"), nil))
+	require.NoError(t, d.DeleteRange([]byte("c"), []byte("ca"), nil))
+	require.Equal(t, int64(4), metrics().Invalidations)
+	require.Equal(t, "a=a1 b=b2 d=d1", scan("a", "z", 0))
+	require.Equal(t, "", scan("c", "d", 0))
+
+	// An ingestion invalidates all the results.
+	f, err := mem.Create("ext", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := sstable.NewWriter(objstorageprovider.NewFileWritable(f), d.opts.MakeWriterOptions(0, d.TableFormat()))
+	require.NoError(t, w.Set([]byte("x"), []byte("x1")))
+	require.NoError(t, w.Close())
+	require.NoError(t, d.Ingest(context.Background(), []string{"ext"}))
+	require.Zero(t, metrics().Count)
+	require.Equal(t, "a=a1 b=b2 d=d1 x=x1", scan("a", "z", 0))
+}
+
+// TestScanCacheConcurrentWrite checks that the result of a scan isn't cached
+// if an overlapping write it may not have observed was applied while it was in
+// flight.
+func TestScanCacheConcurrentWrite(t *testing.T) {
+	visible := base.SeqNum(10)
+	c := newScanCache(base.DefaultComparer.Compare, ScanCacheOptions{Size: 1 << 10, MaxResultSize: 1 << 10},
+		func() base.SeqNum { return visible })
+	key := func(lower, upper string) scanCacheKey { return scanCacheKey{lower: lower, upper: upper} }
+	kvs := []ScanKV{{Key: []byte("b"), Value: []byte("1")}}
+
+	// The write with sequence number 10 is applied, but isn't visible yet.
+	s1 := c.beginScan()
+	bounds := base.UserKeyBoundsInclusive([]byte("b"), []byte("b"))
+	c.invalidate(&bounds, 10)
+	s2 := c.beginScan()
+	c.endScan(s1, key("a", "c"), kvs, true)
+	c.endScan(s2, key("a", "c"), kvs, true)
+	_, ok := c.get(key("a", "c"))
+	require.False(t, ok)
+
+	// Scans that don't overlap the write, or that started once it became
+	// visible, are cached.
+	s3 := c.beginScan()
+	c.endScan(s3, key("c", "d"), nil, true)
+	_, ok = c.get(key("c", "d"))
+	require.True(t, ok)
+	visible = 11
+	s4 := c.beginScan()
+	c.endScan(s4, key("a", "c"), kvs, true)
+	_, ok = c.get(key("a", "c"))
+	require.True(t, ok)
+	require.Empty(t, c.mu.invalidations)
+
+	// Results are evicted when the cache is full.
+	for i := 0; i < 20; i++ {
+		s := c.beginScan()
+		c.endScan(s, key(fmt.Sprintf("k%02d", i), "l"), kvs, true)
+	}
+	require.LessOrEqual(t, c.metrics().Size, int64(1<<10))
+	_, ok = c.get(key("a", "c"))
+	require.False(t, ok)
+	_, ok = c.get(key("k19", "l"))
+	require.True(t, ok)
+}
@@ -470,9 +470,16 @@ func (d *DB) truncateExternalFile(
 		HasPointKey:     file.HasPointKeys,
 		HasRangeKey:     file.HasRangeKeys,
 		Size:            file.Size,
+		ContentPrefix:   slices.Clone(file.ContentPrefix),
 		SyntheticPrefix: slices.Clone(file.SyntheticPrefixAndSuffix.Prefix()),
 		SyntheticSuffix: slices.Clone(file.SyntheticPrefixAndSuffix.Suffix()),
 	}
+	for _, r := range file.SuffixReplacements {
+		sst.SuffixReplacements = append(sst.SuffixReplacements, SuffixReplacement{
+			From: slices.Clone(r.From),
+			To:   slices.Clone(r.To),
+		})
+	}
 
 	needsLowerTruncate := cmp(lower, file.Smallest.UserKey) > 0
 	if needsLowerTruncate {
@@ -896,7 +903,9 @@ func (i *scanInternalIterator) constructPointIter(
 	mlevels = mlevels[:numMergingLevels]
 	levels = levels[:numLevelIters]
 	rangeDelLevels = rangeDelLevels[:numLevelIters]
-	i.opts.IterOptions.snapshotForHideObsoletePoints = i.seqNum
+	if !i.opts.disableHideObsoletePoints {
+		i.opts.IterOptions.snapshotForHideObsoletePoints = i.seqNum
+	}
 	i.opts.IterOptions.Category = category
 	addLevelIterForFiles := func(files manifest.LevelIterator, level manifest.Layer) {
 		li := &levels[levelsIndex]
@@ -0,0 +1,138 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+
+	"github.com/cockroachdb/pebble/rangekey"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// ScanInternalKeysOptions configures a scan of the internal keys of a key
+// range by ScanInternalKeys, for building tools such as incremental
+// replication or backup diffing that need to observe every write, rather than
+// the user-visible state.
+//
+// The scan exposes all the internal keys visible at the sequence number of the
+// scan (the visible sequence number of the DB, or the sequence number of the
+// snapshot): keys shadowed by newer keys, point tombstones, merge operands,
+// range deletions and range keys, with their sequence numbers. Keys newer than
+// the sequence number of the scan are never exposed. Keys deleted by range
+// deletions are exposed alongside the range deletions; it's up to the caller
+// to apply them.
+//
+// The exposed keys are independent of the format major version of the DB: the
+// kinds that only differ by the format in which they're persisted are
+// normalized (InternalKeyKindSetWithDelete is exposed as InternalKeyKindSet and
+// InternalKeyKindDeleteSized as InternalKeyKindDelete), and values are
+// retrieved wherever they're stored. Note that compactions may drop keys that
+// are not protected by a snapshot and zero the sequence numbers of the keys in
+// the bottommost level; scanning through a Snapshot or
+// EventuallyFileOnlySnapshot provides a stable view.
+type ScanInternalKeysOptions struct {
+	// LowerBound and UpperBound bound the scan to the user keys in [LowerBound,
+	// UpperBound). Range deletions and range keys are truncated to the bounds. A
+	// nil bound leaves the corresponding side of the scan unbounded.
+	LowerBound, UpperBound []byte
+	// Category is the category of the scan's reads, for block cache and IO
+	// statistics.
+	Category block.Category
+
+	// VisitPointKey is called for each point key, in increasing user key order
+	// and, within a user key, in decreasing sequence number order. The key and
+	// value are only valid for the duration of the call.
+	VisitPointKey func(key InternalKey, value []byte) error
+	// VisitRangeDel is called for each fragment of the range deletions, with
+	// the sequence numbers of the range deletions covering it in decreasing
+	// order. The arguments are only valid for the duration of the call.
+	VisitRangeDel func(start, end []byte, seqNums []SeqNum) error
+	// VisitRangeKey is called for each fragment of the range keys, with the
+	// range keys covering it in decreasing sequence number order. The arguments
+	// are only valid for the duration of the call.
+	VisitRangeKey func(start, end []byte, keys []rangekey.Key) error
+}
+
+// ScanInternalKeys scans the internal keys of a key range at the visible
+// sequence number of the DB; see ScanInternalKeysOptions.
+func (d *DB) ScanInternalKeys(ctx context.Context, opts *ScanInternalKeysOptions) error {
+	return d.scanInternalKeys(ctx, snapshotIterOpts{}, opts)
+}
+
+func (d *DB) scanInternalKeys(
+	ctx context.Context, sOpts snapshotIterOpts, opts *ScanInternalKeysOptions,
+) error {
+	scanInternalOpts := &scanInternalOptions{
+		category:            opts.Category,
+		includeObsoleteKeys: true,
+		// Keys shadowed within the same sstable are exposed, even though they
+		// are only visible to older snapshots.
+		disableHideObsoletePoints: true,
+		IterOptions: IterOptions{
+			KeyTypes:   IterKeyTypePointsAndRanges,
+			LowerBound: opts.LowerBound,
+			UpperBound: opts.UpperBound,
+		},
+	}
+	iter, err := d.newInternalIter(ctx, sOpts, scanInternalOpts)
+	if err != nil {
+		return err
+	}
+	defer iter.close()
+
+	var valueBuf []byte
+	var seqNums []SeqNum
+	for valid := iter.seekGE(opts.LowerBound); valid && iter.error() == nil; valid = iter.next() {
+		key := *iter.unsafeKey()
+		switch key.Kind() {
+		case InternalKeyKindRangeKeyDelete, InternalKeyKindRangeKeyUnset, InternalKeyKindRangeKeySet:
+			if opts.VisitRangeKey != nil {
+				span := iter.unsafeSpan()
+				if err := opts.VisitRangeKey(span.Start, span.End, span.Keys); err != nil {
+					return err
+				}
+			}
+		case InternalKeyKindRangeDelete:
+			if opts.VisitRangeDel != nil {
+				span := iter.unsafeRangeDel()
+				seqNums = seqNums[:0]
+				for i := range span.Keys {
+					seqNums = append(seqNums, span.Keys[i].SeqNum())
+				}
+				if err := opts.VisitRangeDel(span.Start, span.End, seqNums); err != nil {
+					return err
+				}
+			}
+		default:
+			if opts.VisitPointKey == nil {
+				continue
+			}
+			var value []byte
+			switch key.Kind() {
+			case InternalKeyKindSetWithDelete:
+				key.SetKind(InternalKeyKindSet)
+			case InternalKeyKindDeleteSized:
+				// The value of a sized deletion is the size of the deleted
+				// value, which is only a hint for compactions.
+				key.SetKind(InternalKeyKindDelete)
+			}
+			if key.Kind() != InternalKeyKindDelete {
+				lv := iter.lazyValue()
+				v, callerOwned, err := lv.Value(valueBuf)
+				if err != nil {
+					return err
+				}
+				if callerOwned {
+					valueBuf = v[:0]
+				}
+				value = v
+			}
+			if err := opts.VisitPointKey(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return iter.error()
+}
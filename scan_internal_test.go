@@ -5,6 +5,7 @@
 package pebble
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
@@ -24,6 +25,7 @@ import (
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
 	"github.com/cockroachdb/pebble/objstorage/remote"
+	"github.com/cockroachdb/pebble/rangekey"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
@@ -401,6 +403,8 @@ func TestScanInternal(t *testing.T) {
 			commit := td.HasArg("commit")
 			ingest := td.HasArg("ingest")
 			ingestExternal := td.HasArg("ingest-external")
+			var contentPrefix string
+			td.MaybeScanArgs(t, "content-prefix", &contentPrefix)
 			b := d.NewIndexedBatch()
 			require.NoError(t, runBatchDefineCmd(td, b))
 
@@ -486,6 +490,12 @@ func TestScanInternal(t *testing.T) {
 					EndKeyIsInclusive: true,
 					HasPointKey:       true,
 				}
+				if contentPrefix != "" {
+					// The keys are surfaced without the content prefix.
+					ef.ContentPrefix = []byte(contentPrefix)
+					ef.StartKey = bytes.TrimPrefix(ef.StartKey, ef.ContentPrefix)
+					ef.EndKey = bytes.TrimPrefix(ef.EndKey, ef.ContentPrefix)
+				}
 				_, err = d.IngestExternalFiles(context.Background(), []ExternalFile{ef})
 				require.NoError(t, err)
 			} else if name != "" {
@@ -558,6 +568,9 @@ func TestScanInternal(t *testing.T) {
 							hex.EncodeToString(sst.StartKey),
 							hex.EncodeToString(sst.EndKey),
 							sst.HasPointKey, sst.HasRangeKey)
+						if len(sst.ContentPrefix) > 0 {
+							fmt.Fprintf(&b, "  content prefix: %s\n", sst.ContentPrefix)
+						}
 						return nil
 					}
 				}
@@ -638,3 +651,94 @@ func TestPointCollapsingIter(t *testing.T) {
 		}
 	})
 }
+
+func TestScanInternalKeys(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                 vfs.NewMem(),
+		Comparer:           testkeys.Comparer,
+		Merger:             base.DefaultMerger,
+		FormatMajorVersion: FormatNewest,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// The snapshots prevent the flush from dropping the shadowed keys and
+	// combining the merge operands.
+	var snaps []*Snapshot
+	defer func() {
+		for _, s := range snaps {
+			require.NoError(t, s.Close())
+		}
+	}()
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	snaps = append(snaps, d.NewSnapshot())
+	require.NoError(t, d.Set([]byte("a"), []byte("2"), nil))
+	require.NoError(t, d.Merge([]byte("b"), []byte("x"), nil))
+	snaps = append(snaps, d.NewSnapshot())
+	require.NoError(t, d.Merge([]byte("b"), []byte("y"), nil))
+	require.NoError(t, d.DeleteSized([]byte("c"), 10, nil))
+	require.NoError(t, d.Set([]byte("e"), []byte("3"), nil))
+	snaps = append(snaps, d.NewSnapshot())
+	require.NoError(t, d.DeleteRange([]byte("d"), []byte("f"), nil))
+	require.NoError(t, d.RangeKeySet([]byte("g"), []byte("h"), []byte("@5"), []byte("v"), nil))
+	require.NoError(t, d.Flush())
+	snap := d.NewSnapshot()
+	defer func() { require.NoError(t, snap.Close()) }()
+	require.NoError(t, d.Set([]byte("a"), []byte("4"), nil))
+	require.NoError(t, d.DeleteRange([]byte("d"), []byte("f"), nil))
+
+	scan := func(s interface {
+		ScanInternalKeys(context.Context, *ScanInternalKeysOptions) error
+	}, lower, upper []byte) string {
+		var buf strings.Builder
+		var prev InternalKey
+		require.NoError(t, s.ScanInternalKeys(context.Background(), &ScanInternalKeysOptions{
+			LowerBound: lower,
+			UpperBound: upper,
+			VisitPointKey: func(key InternalKey, value []byte) error {
+				// Keys of the same user key are in decreasing sequence number order.
+				if d.cmp(prev.UserKey, key.UserKey) == 0 {
+					require.Greater(t, prev.SeqNum(), key.SeqNum())
+				}
+				prev = key.Clone()
+				fmt.Fprintf(&buf, "%s.%s=%s\n", key.UserKey, key.Kind(), value)
+				return nil
+			},
+			VisitRangeDel: func(start, end []byte, seqNums []SeqNum) error {
+				fmt.Fprintf(&buf, "[%s, %s).RANGEDEL x%d\n", start, end, len(seqNums))
+				return nil
+			},
+			VisitRangeKey: func(start, end []byte, keys []rangekey.Key) error {
+				for _, k := range keys {
+					require.NotZero(t, k.SeqNum())
+					fmt.Fprintf(&buf, "[%s, %s).%s %s=%s\n", start, end, k.Kind(), k.Suffix, k.Value)
+				}
+				return nil
+			},
+		}))
+		return buf.String()
+	}
+
+	require.Equal(t, `a.SET=4
+a.SET=2
+a.SET=1
+b.MERGE=y
+b.MERGE=x
+c.DEL=
+[d, f).RANGEDEL x2
+e.SET=3
+[g, h).RANGEKEYSET @5=v
+`, scan(d, nil, nil))
+	require.Equal(t, `a.SET=2
+a.SET=1
+b.MERGE=y
+b.MERGE=x
+c.DEL=
+[d, f).RANGEDEL x1
+e.SET=3
+[g, h).RANGEKEYSET @5=v
+`, scan(snap, nil, nil))
+	require.Equal(t, `c.DEL=
+[d, e).RANGEDEL x2
+`, scan(d, []byte("c"), []byte("e")))
+}
@@ -0,0 +1,328 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/crc"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// defaultSeqNumTimeRetentionSamples is the default of
+// SeqNumTimeOptions.Retention, as a multiple of the sample interval.
+const defaultSeqNumTimeRetentionSamples = 60
+
+// ErrHistoryUnavailable is returned by SeqNumForTime and NewSnapshotAt when the
+// requested state of the DB is not retained.
+var ErrHistoryUnavailable = errors.New("pebble: the requested history is not retained")
+
+// SeqNumTimeOptions configures the sampling of the visible sequence number of
+// the DB over time, which maps wall-clock times to sequence numbers for
+// time-travel reads ("read as of 5 minutes ago"); see DB.SeqNumForTime and
+// DB.NewSnapshotAt.
+//
+// Every SampleInterval, the visible sequence number is recorded along with the
+// current time. The DB retains the state as of each sample for Retention,
+// like an open snapshot would: compactions don't drop the keys that were
+// visible at the sample. This increases the write and space amplification in
+// proportion to the number of retained samples and the rate of overwrites.
+//
+// The samples are persisted in a file of the DB's directory, so that the
+// history survives a restart. Samples of sequence numbers that were lost in a
+// crash (because the WAL was not synced) are discarded.
+type SeqNumTimeOptions struct {
+	// SampleInterval is the interval at which the visible sequence number is
+	// sampled. The sampling is disabled if SampleInterval is not positive.
+	SampleInterval time.Duration
+	// Retention is how long the samples, and the states of the DB as of them,
+	// are retained. It defaults to 60 times SampleInterval.
+	Retention time.Duration
+}
+
+// seqNumTimeSample records the visible sequence number of the DB at a point in
+// time.
+type seqNumTimeSample struct {
+	seqNum base.SeqNum
+	time   time.Time
+	// snap retains the state of the DB as of seqNum.
+	snap *Snapshot
+}
+
+// SeqNumForTime returns the sequence number at which the DB can be read as of
+// the given time: the visible sequence number of the latest sample taken at or
+// before t. The state of the DB at the sequence number reflects all the writes
+// committed before the sample, which trails t by at most
+// SeqNumTimeOptions.SampleInterval. Use NewSnapshotAt to read the DB at the
+// sequence number.
+//
+// ErrHistoryUnavailable is returned if t precedes the retained samples or
+// sampling is disabled; see Options.Experimental.SeqNumTime.
+func (d *DB) SeqNumForTime(t time.Time) (base.SeqNum, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	samples := d.mu.seqNumTimes
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].time.After(t) })
+	if i == 0 {
+		return 0, errors.Wrapf(ErrHistoryUnavailable, "no sample at or before %s", t)
+	}
+	return samples[i-1].seqNum, nil
+}
+
+// NewSnapshotAt returns a snapshot of the DB as of the given sequence number,
+// which must be the current visible sequence number or one returned by
+// SeqNumForTime whose sample is still retained. ErrHistoryUnavailable is
+// returned otherwise. The snapshot retains the state of the DB until it's
+// closed, regardless of SeqNumTimeOptions.Retention.
+func (d *DB) NewSnapshotAt(seqNum base.SeqNum) (*Snapshot, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if seqNum != d.mu.versions.visibleSeqNum.Load() {
+		retained := false
+		for _, s := range d.mu.seqNumTimes {
+			retained = retained || s.seqNum == seqNum
+		}
+		if !retained {
+			return nil, errors.Wrapf(ErrHistoryUnavailable, "sequence number %s", seqNum)
+		}
+	}
+	s := &Snapshot{db: d, seqNum: seqNum}
+	d.trackSnapshot(s)
+	d.mu.snapshots.insert(s)
+	return s, nil
+}
+
+// seqNumTimeLoop periodically samples the visible sequence number, until the
+// DB is closed.
+func (d *DB) seqNumTimeLoop() {
+	defer d.compactionSchedulers.Done()
+
+	ticker := time.NewTicker(d.opts.Experimental.SeqNumTime.SampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.closedCh:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			if d.closed.Load() != nil {
+				d.mu.Unlock()
+				return
+			}
+			d.sampleSeqNumTimeLocked()
+			buf, gen := d.encodeSeqNumTimesLocked()
+			d.mu.Unlock()
+			if err := d.persistSeqNumTimes(buf, gen); err != nil {
+				d.opts.Logger.Errorf("failed to persist the sequence number samples: %v", err)
+			}
+		}
+	}
+}
+
+// sampleSeqNumTimeLocked records a sample of the visible sequence number, and
+// drops the samples older than SeqNumTimeOptions.Retention.
+//
+// d.mu must be held when calling this.
+func (d *DB) sampleSeqNumTimeLocked() {
+	now := d.timeNow()
+	d.addSeqNumTimeLocked(seqNumTimeSample{seqNum: d.mu.versions.visibleSeqNum.Load(), time: now})
+	d.dropSeqNumTimesLocked(func(s seqNumTimeSample) bool {
+		return now.Sub(s.time) > d.opts.Experimental.SeqNumTime.Retention
+	})
+}
+
+// dropSeqNumTimesLocked drops the samples for which the given function returns
+// true, releasing the state they retain.
+//
+// d.mu must be held when calling this.
+func (d *DB) dropSeqNumTimesLocked(drop func(seqNumTimeSample) bool) {
+	keep := d.mu.seqNumTimes[:0]
+	for _, s := range d.mu.seqNumTimes {
+		if drop(s) {
+			// closeLocked only fails if the DB is closed.
+			_ = s.snap.closeLocked()
+			continue
+		}
+		keep = append(keep, s)
+	}
+	clear(d.mu.seqNumTimes[len(keep):])
+	d.mu.seqNumTimes = keep
+}
+
+// closeSeqNumTimesLocked persists the samples and releases the state they
+// retain, when the DB is closed.
+//
+// d.mu must be held when calling this.
+func (d *DB) closeSeqNumTimesLocked() error {
+	if d.opts.Experimental.SeqNumTime.SampleInterval <= 0 {
+		return nil
+	}
+	var err error
+	if !d.opts.ReadOnly {
+		// The DB is closing, so the samples file is written without releasing
+		// d.mu.
+		err = d.persistSeqNumTimes(d.encodeSeqNumTimesLocked())
+	}
+	d.dropSeqNumTimesLocked(func(seqNumTimeSample) bool { return true })
+	return err
+}
+
+const (
+	seqNumTimesFilename = "SEQNUM-TIMES"
+	seqNumTimesMagic    = 0x5e9_7173
+	seqNumTimesVersion  = 1
+	// seqNumTimesHeaderLen is the length of the header of the samples file:
+	// magic (4 bytes), version (4) and sample count (8).
+	seqNumTimesHeaderLen = 16
+	// seqNumTimesEntryLen is the length of a sample: sequence number (8 bytes)
+	// and time in nanoseconds since the Unix epoch (8).
+	seqNumTimesEntryLen = 16
+	// seqNumTimesFooterLen is the length of the checksum of the file contents.
+	seqNumTimesFooterLen = 4
+)
+
+// encodeSeqNumTimesLocked encodes the samples to be written to the samples
+// file by persistSeqNumTimes, returning the generation of the encoding.
+//
+// d.mu must be held when calling this.
+func (d *DB) encodeSeqNumTimesLocked() ([]byte, uint64) {
+	samples := d.mu.seqNumTimes
+	buf := make([]byte, seqNumTimesHeaderLen+len(samples)*seqNumTimesEntryLen+seqNumTimesFooterLen)
+	binary.LittleEndian.PutUint32(buf[0:], seqNumTimesMagic)
+	binary.LittleEndian.PutUint32(buf[4:], seqNumTimesVersion)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(len(samples)))
+	b := buf[seqNumTimesHeaderLen:]
+	for _, s := range samples {
+		binary.LittleEndian.PutUint64(b[0:], uint64(s.seqNum))
+		binary.LittleEndian.PutUint64(b[8:], uint64(s.time.UnixNano()))
+		b = b[seqNumTimesEntryLen:]
+	}
+	binary.LittleEndian.PutUint32(b, crc.New(buf[:len(buf)-seqNumTimesFooterLen]).Value())
+	d.mu.seqNumTimesGen++
+	return buf, d.mu.seqNumTimesGen
+}
+
+// persistSeqNumTimes writes the encoded samples to the samples file, replacing
+// it atomically. The write is skipped if samples of a later generation have
+// already been written.
+//
+// d.mu does not need to be held.
+func (d *DB) persistSeqNumTimes(buf []byte, gen uint64) error {
+	d.seqNumTimesFile.Lock()
+	defer d.seqNumTimesFile.Unlock()
+	if gen <= d.seqNumTimesFile.gen {
+		return nil
+	}
+	fs := d.opts.FS
+	path := fs.PathJoin(d.dirname, seqNumTimesFilename)
+	tmpPath := path + ".tmp"
+	f, err := fs.Create(tmpPath, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		return errors.CombineErrors(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return errors.CombineErrors(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := fs.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if err := d.dataDir.Sync(); err != nil {
+		return err
+	}
+	d.seqNumTimesFile.gen = gen
+	return nil
+}
+
+// loadSeqNumTimesLocked loads the samples persisted by a previous instance of
+// the DB, retaining the state as of the samples that are still within
+// SeqNumTimeOptions.Retention. It's called when the DB is opened, before the
+// WAL is replayed. A missing or corrupt samples file is ignored.
+//
+// d.mu must be held when calling this.
+func (d *DB) loadSeqNumTimesLocked() {
+	fs := d.opts.FS
+	f, err := fs.Open(fs.PathJoin(d.dirname, seqNumTimesFilename))
+	if err != nil {
+		if !oserror.IsNotExist(err) {
+			d.opts.Logger.Errorf("failed to load the sequence number samples: %v", err)
+		}
+		return
+	}
+	buf, err := io.ReadAll(f)
+	err = errors.CombineErrors(err, f.Close())
+	var samples []seqNumTimeSample
+	if err == nil {
+		samples, err = decodeSeqNumTimes(buf)
+	}
+	if err != nil {
+		d.opts.Logger.Errorf("failed to load the sequence number samples: %v", err)
+		return
+	}
+	now := d.timeNow()
+	for _, s := range samples {
+		if now.Sub(s.time) > d.opts.Experimental.SeqNumTime.Retention {
+			continue
+		}
+		d.addSeqNumTimeLocked(s)
+	}
+}
+
+// addSeqNumTimeLocked appends the sample, creating the snapshot that retains
+// the state of the DB as of the sample.
+//
+// d.mu must be held when calling this.
+func (d *DB) addSeqNumTimeLocked(s seqNumTimeSample) {
+	s.snap = &Snapshot{db: d, seqNum: s.seqNum, sample: true}
+	d.trackSnapshot(s.snap)
+	d.mu.snapshots.insert(s.snap)
+	d.mu.seqNumTimes = append(d.mu.seqNumTimes, s)
+}
+
+// decodeSeqNumTimes decodes the contents of a samples file.
+func decodeSeqNumTimes(buf []byte) ([]seqNumTimeSample, error) {
+	if len(buf) < seqNumTimesHeaderLen+seqNumTimesFooterLen {
+		return nil, errors.Newf("samples file too short (%d bytes)", len(buf))
+	}
+	contents, footer := buf[:len(buf)-seqNumTimesFooterLen], buf[len(buf)-seqNumTimesFooterLen:]
+	if expected, actual := binary.LittleEndian.Uint32(footer), crc.New(contents).Value(); expected != actual {
+		return nil, errors.Newf("samples file checksum mismatch (expected %x, computed %x)", expected, actual)
+	}
+	if magic := binary.LittleEndian.Uint32(contents[0:]); magic != seqNumTimesMagic {
+		return nil, errors.Newf("invalid samples file magic %x", magic)
+	}
+	if version := binary.LittleEndian.Uint32(contents[4:]); version != seqNumTimesVersion {
+		return nil, errors.Newf("unsupported samples file version %d", version)
+	}
+	n := binary.LittleEndian.Uint64(contents[8:])
+	b := contents[seqNumTimesHeaderLen:]
+	if uint64(len(b)) != n*seqNumTimesEntryLen {
+		return nil, errors.Newf("samples file has %d bytes of samples, expected %d samples", len(b), n)
+	}
+	samples := make([]seqNumTimeSample, n)
+	for i := range samples {
+		samples[i].seqNum = base.SeqNum(binary.LittleEndian.Uint64(b[0:]))
+		samples[i].time = time.Unix(0, int64(binary.LittleEndian.Uint64(b[8:])))
+		b = b[seqNumTimesEntryLen:]
+	}
+	return samples, nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeqNumTime(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{FS: mem}
+	// The samples are taken explicitly by the test.
+	opts.Experimental.SeqNumTime = SeqNumTimeOptions{SampleInterval: time.Hour, Retention: 10 * time.Minute}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+
+	now := time.Now()
+	d.timeNow = func() time.Time { return now }
+	sample := func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.sampleSeqNumTimeLocked()
+	}
+	requireValueAt := func(d *DB, tm time.Time, expected string) {
+		t.Helper()
+		seqNum, err := d.SeqNumForTime(tm)
+		require.NoError(t, err)
+		s, err := d.NewSnapshotAt(seqNum)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, s.Close()) }()
+		v, closer, err := s.Get([]byte("k"))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(v))
+		require.NoError(t, closer.Close())
+	}
+
+	t0 := now
+	require.NoError(t, d.Set([]byte("k"), []byte("v1"), nil))
+	sample()
+	now = now.Add(time.Minute)
+	t1 := now
+	require.NoError(t, d.Set([]byte("k"), []byte("v2"), nil))
+	sample()
+	require.NoError(t, d.Set([]byte("k"), []byte("v3"), nil))
+	// The compaction retains the versions of the key as of the samples.
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false /* parallelize */))
+
+	_, err = d.SeqNumForTime(t0.Add(-time.Second))
+	require.ErrorIs(t, err, ErrHistoryUnavailable)
+	requireValueAt(d, t0, "v1")
+	requireValueAt(d, t0.Add(30*time.Second), "v1")
+	requireValueAt(d, t1, "v2")
+	_, err = d.NewSnapshotAt(base.SeqNumStart)
+	require.ErrorIs(t, err, ErrHistoryUnavailable)
+
+	// The samples survive a restart.
+	report, err := d.CloseReport()
+	require.NoError(t, err)
+	require.Empty(t, report.Snapshots)
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	d.timeNow = func() time.Time { return now }
+	require.NoError(t, d.Compact([]byte("a"), []byte("z"), false /* parallelize */))
+	requireValueAt(d, t0, "v1")
+	requireValueAt(d, t1, "v2")
+
+	// Samples older than the retention are dropped.
+	now = t0.Add(10*time.Minute + time.Second)
+	sample()
+	_, err = d.SeqNumForTime(t0)
+	require.ErrorIs(t, err, ErrHistoryUnavailable)
+	requireValueAt(d, t1, "v2")
+	requireValueAt(d, now, "v3")
+	require.NoError(t, d.Close())
+}
@@ -0,0 +1,273 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// ErrSessionTokenUnreachable is returned by DB.WaitForToken when the DB can
+// never observe the writes covered by a session token, because it is
+// read-only and has not observed them already.
+var ErrSessionTokenUnreachable = errors.New("pebble: session token unreachable")
+
+// ErrSessionTokenMismatch is returned by DB.WaitForToken when a session token
+// was obtained from a different DB, or covers durable writes that the DB
+// doesn't contain.
+var ErrSessionTokenMismatch = errors.New("pebble: session token mismatch")
+
+// sessionTokenVersion is the version of the SessionToken encoding.
+const sessionTokenVersion = 2
+
+// Bounds on the interval at which DB.WaitForToken polls the visible sequence
+// number.
+const (
+	minSessionTokenPollInterval = 100 * time.Microsecond
+	maxSessionTokenPollInterval = 50 * time.Millisecond
+)
+
+// A SessionToken captures the frontier of writes visible in a DB at a point
+// in time. It provides read-your-writes consistency across processes: a
+// client that obtains a token after its writes are acknowledged can pass the
+// (encoded) token along with a subsequent request, and the process serving
+// that request calls DB.WaitForToken to ensure it observes those writes before
+// reading, even if the request is served by a different process or a reopened
+// store.
+//
+// Tokens are only meaningful for the DB they were obtained from, identified by
+// the ID persisted in its directory when it's created (see DB.ID). The same
+// DB reopened, or a copy of its directory, continues its history; a
+// checkpoint is a new DB. WaitForToken rejects tokens obtained from another
+// DB.
+//
+// A token also captures the durable frontier of the DB: the writes below it
+// were durable when the token was obtained, so a DB that doesn't contain them
+// has lost them, and WaitForToken rejects the token. A token does not make the
+// other writes it covers durable: writes committed without WriteOptions.Sync
+// may be lost in a crash, in which case a token covering them is satisfied
+// once the reopened DB has committed enough subsequent writes.
+//
+// The zero SessionToken covers no writes, and is accepted by any DB.
+type SessionToken struct {
+	// dbID is the ID of the DB the token was obtained from, or zero if the
+	// DB had no ID (see DB.ID).
+	dbID uint64
+	// durableSeqNum is the durable frontier at the time the token was
+	// obtained. All writes with sequence numbers less than durableSeqNum were
+	// durable.
+	durableSeqNum base.SeqNum
+	// seqNum is the visible sequence number at the time the token was
+	// obtained. All writes with sequence numbers less than seqNum are covered
+	// by the token.
+	seqNum base.SeqNum
+}
+
+// DBID returns the ID of the DB the token was obtained from.
+func (t SessionToken) DBID() uint64 {
+	return t.dbID
+}
+
+// DurableSeqNum returns the durable sequence number captured by the token.
+func (t SessionToken) DurableSeqNum() base.SeqNum {
+	return t.durableSeqNum
+}
+
+// SeqNum returns the visible sequence number captured by the token.
+func (t SessionToken) SeqNum() base.SeqNum {
+	return t.seqNum
+}
+
+// String implements fmt.Stringer.
+func (t SessionToken) String() string {
+	return fmt.Sprintf("session-token(%016x, %s, %s)", t.dbID, t.durableSeqNum, t.seqNum)
+}
+
+// Encode appends the encoding of the token to buf and returns the result. The
+// encoding can be decoded with DecodeSessionToken.
+func (t SessionToken) Encode(buf []byte) []byte {
+	buf = append(buf, sessionTokenVersion)
+	buf = binary.AppendUvarint(buf, t.dbID)
+	buf = binary.AppendUvarint(buf, uint64(t.durableSeqNum))
+	return binary.AppendUvarint(buf, uint64(t.seqNum))
+}
+
+// DecodeSessionToken decodes a token encoded by SessionToken.Encode.
+func DecodeSessionToken(buf []byte) (SessionToken, error) {
+	if len(buf) == 0 {
+		return SessionToken{}, base.CorruptionErrorf("pebble: empty session token")
+	}
+	if buf[0] != sessionTokenVersion {
+		return SessionToken{}, base.CorruptionErrorf("pebble: unknown session token version %d", errors.Safe(buf[0]))
+	}
+	var vals [3]uint64
+	buf = buf[1:]
+	for i := range vals {
+		var n int
+		vals[i], n = binary.Uvarint(buf)
+		if n <= 0 {
+			return SessionToken{}, base.CorruptionErrorf("pebble: malformed session token")
+		}
+		buf = buf[n:]
+	}
+	if len(buf) != 0 || vals[1] > vals[2] {
+		return SessionToken{}, base.CorruptionErrorf("pebble: malformed session token")
+	}
+	return SessionToken{
+		dbID:          vals[0],
+		durableSeqNum: base.SeqNum(vals[1]),
+		seqNum:        base.SeqNum(vals[2]),
+	}, nil
+}
+
+// SessionToken returns a token capturing all writes visible in the DB. In
+// particular, the token covers every write whose commit was acknowledged
+// before SessionToken was called. See SessionToken for details.
+func (d *DB) SessionToken() SessionToken {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	// Load the durable frontier before the visible sequence number, so that
+	// the former doesn't exceed the latter.
+	var durable base.SeqNum
+	if d.opts.DisableWAL {
+		durable = d.DurableVisibleSeqNum()
+	} else {
+		d.durability.mu.Lock()
+		durable = d.durability.durable
+		d.durability.mu.Unlock()
+	}
+	visible := d.mu.versions.visibleSeqNum.Load()
+	return SessionToken{
+		dbID:          d.id,
+		durableSeqNum: min(durable, visible),
+		seqNum:        visible,
+	}
+}
+
+// WaitForToken blocks until all writes covered by the given session token are
+// visible in the DB, so that subsequent reads observe them. It returns early
+// with an error if the context is canceled or the DB is closed. If the DB is
+// read-only and the writes are not already visible, WaitForToken returns
+// ErrSessionTokenUnreachable immediately.
+//
+// WaitForToken returns ErrSessionTokenMismatch if the token was obtained from
+// a different DB, or if the DB doesn't contain the writes that were durable
+// when the token was obtained.
+func (d *DB) WaitForToken(ctx context.Context, token SessionToken) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if token.dbID != 0 && d.id != 0 && token.dbID != d.id {
+		return errors.Wrapf(ErrSessionTokenMismatch, "DB ID %016x, token %s", d.id, token)
+	}
+	// The writes below the durable frontier of the token were durable, so they
+	// were recovered if the DB was reopened since. If they aren't visible, they
+	// were lost, and waiting for subsequent writes would wrongly satisfy the
+	// token.
+	if visible := d.mu.versions.visibleSeqNum.Load(); visible < token.durableSeqNum {
+		return errors.Wrapf(ErrSessionTokenMismatch, "visible seqnum %s, token %s", visible, token)
+	}
+	if d.mu.versions.visibleSeqNum.Load() >= token.seqNum {
+		return nil
+	}
+	if d.opts.ReadOnly {
+		return errors.Wrapf(ErrSessionTokenUnreachable, "visible seqnum %s, token %s",
+			d.mu.versions.visibleSeqNum.Load(), token)
+	}
+
+	// The visible sequence number is published by the commit pipeline, which
+	// doesn't notify waiters; poll it with exponential backoff.
+	interval := minSessionTokenPollInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.closedCh:
+			return ErrClosed
+		case <-timer.C:
+		}
+		if d.mu.versions.visibleSeqNum.Load() >= token.seqNum {
+			return nil
+		}
+		interval = min(2*interval, maxSessionTokenPollInterval)
+		timer.Reset(interval)
+	}
+}
+
+// dbIDFilename is the name of the file holding the ID of the DB.
+const dbIDFilename = "DBID"
+
+// ID returns the ID of the DB, a random number generated when the DB is first
+// opened and persisted in its directory. The ID identifies the history of the
+// DB: it's retained when the DB is reopened, but a checkpoint of the DB gets a
+// new ID. ID returns zero if the DB was opened in read-only mode and has no ID.
+func (d *DB) ID() uint64 {
+	return d.id
+}
+
+// loadOrCreateID returns the ID persisted in the DB directory, generating and
+// persisting a new ID if there's none and the DB isn't read-only.
+func (d *DB) loadOrCreateID() (uint64, error) {
+	fs := d.opts.FS
+	path := fs.PathJoin(d.dirname, dbIDFilename)
+	f, err := fs.Open(path)
+	if err == nil {
+		buf, err := io.ReadAll(f)
+		err = errors.CombineErrors(err, f.Close())
+		if err != nil {
+			return 0, err
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(string(buf)), 16, 64)
+		if err != nil || id == 0 {
+			return 0, base.CorruptionErrorf("pebble: malformed DB ID %q", buf)
+		}
+		return id, nil
+	}
+	if !oserror.IsNotExist(err) {
+		return 0, err
+	}
+	if d.opts.ReadOnly {
+		return 0, nil
+	}
+	var id uint64
+	for id == 0 {
+		id = rand.Uint64()
+	}
+	tmpPath := path + ".tmp"
+	f, err = fs.Create(tmpPath, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fmt.Fprintf(f, "%016x\n", id); err != nil {
+		return 0, errors.CombineErrors(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return 0, errors.CombineErrors(err, f.Close())
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := fs.Rename(tmpPath, path); err != nil {
+		return 0, err
+	}
+	if err := d.dataDir.Sync(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionTokenEncoding(t *testing.T) {
+	for _, token := range []SessionToken{
+		{},
+		{dbID: 1, durableSeqNum: 0, seqNum: 1},
+		{dbID: 0xdeadbeef, durableSeqNum: 900, seqNum: 1000},
+		{dbID: math.MaxUint64, durableSeqNum: base.SeqNumMax, seqNum: base.SeqNumMax},
+	} {
+		decoded, err := DecodeSessionToken(token.Encode(nil))
+		require.NoError(t, err)
+		require.Equal(t, token, decoded)
+	}
+
+	for _, buf := range [][]byte{
+		nil,
+		{sessionTokenVersion},
+		{sessionTokenVersion, 1, 2},
+		{sessionTokenVersion - 1, 1},
+		{sessionTokenVersion + 1, 1, 2, 3},
+		{sessionTokenVersion, 1, 2, 3, 4},
+		{sessionTokenVersion, 1, 2, 0x80},
+		// The durable sequence number exceeds the visible one.
+		{sessionTokenVersion, 1, 3, 2},
+	} {
+		_, err := DecodeSessionToken(buf)
+		require.True(t, base.IsCorruptionError(err), "%x: %v", buf, err)
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	write := func(d *DB, n int, opts *WriteOptions) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", i)), nil, opts))
+		}
+	}
+
+	fs := vfs.NewCrashableMem()
+	d, err := Open("", &Options{FS: fs})
+	require.NoError(t, err)
+	id := d.ID()
+	require.NotZero(t, id)
+	write(d, 10, Sync)
+	synced := d.SessionToken()
+	require.Equal(t, id, synced.DBID())
+	require.Equal(t, synced.SeqNum(), synced.DurableSeqNum())
+	write(d, 5, NoSync)
+	token := d.SessionToken()
+	require.Equal(t, synced.DurableSeqNum(), token.DurableSeqNum())
+	require.Greater(t, token.SeqNum(), token.DurableSeqNum())
+	require.NoError(t, d.WaitForToken(context.Background(), token))
+
+	// The token round trips through its encoding, and is satisfied by the same
+	// store once reopened.
+	token, err = DecodeSessionToken(token.Encode(nil))
+	require.NoError(t, err)
+	crashFS := fs.CrashClone(vfs.CrashCloneCfg{})
+	require.NoError(t, d.Close())
+	d, err = Open("", &Options{FS: fs})
+	require.NoError(t, err)
+	require.Equal(t, id, d.ID())
+	require.NoError(t, d.WaitForToken(context.Background(), token))
+	require.NoError(t, d.Close())
+
+	// A read-only store that hasn't observed the writes can never observe them.
+	d, err = Open("", &Options{FS: fs, ReadOnly: true})
+	require.NoError(t, err)
+	require.NoError(t, d.WaitForToken(context.Background(), token))
+	require.True(t, errors.Is(d.WaitForToken(context.Background(),
+		SessionToken{dbID: id, seqNum: token.seqNum + 1}), ErrSessionTokenUnreachable))
+	require.NoError(t, d.Close())
+
+	// A token obtained from another DB is rejected.
+	other, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	require.NotEqual(t, id, other.ID())
+	require.ErrorIs(t, other.WaitForToken(context.Background(), SessionToken{dbID: id}), ErrSessionTokenMismatch)
+	require.NoError(t, other.Close())
+
+	// The store recovered from a crash lost the writes that weren't synced. It
+	// rejects a token covering more durable writes than it contains, and waits
+	// until it has committed enough writes to satisfy the others.
+	d, err = Open("", &Options{FS: crashFS})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.Equal(t, id, d.ID())
+	require.Equal(t, synced.SeqNum(), d.SessionToken().SeqNum())
+	require.ErrorIs(t, d.WaitForToken(context.Background(), SessionToken{
+		dbID: id, durableSeqNum: token.seqNum, seqNum: token.seqNum,
+	}), ErrSessionTokenMismatch)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, d.WaitForToken(ctx, token), context.DeadlineExceeded)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- d.WaitForToken(context.Background(), token) }()
+	write(d, 2, Sync)
+	select {
+	case err := <-errCh:
+		t.Fatalf("WaitForToken returned before the token was reached: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	write(d, 3, Sync)
+	require.NoError(t, <-errCh)
+	require.GreaterOrEqual(t, d.SessionToken().SeqNum(), token.SeqNum())
+}
@@ -26,11 +26,25 @@ type Snapshot struct {
 	// Set if part of an EventuallyFileOnlySnapshot.
 	efos *EventuallyFileOnlySnapshot
 
+	// Set if the snapshot retains the state of the DB as of a sample of its
+	// visible sequence number; see SeqNumTimeOptions.
+	sample bool
+	// Set if the snapshot retains the state of the DB as of a named snapshot;
+	// see DB.NewNamedSnapshot.
+	named bool
+
 	// The list the snapshot is linked into.
 	list *snapshotList
 
 	// The next/prev link for the snapshotList doubly-linked list of snapshots.
 	prev, next *Snapshot
+
+	// createdAt is the time at which the snapshot was created (see
+	// DB.TombstoneHorizon). stack is the stack trace of its creation, for
+	// reporting it if it's leaked (see DB.CloseReport); it's only set if
+	// Options.Experimental.IteratorLeakThreshold and IteratorLeakStacks are set.
+	createdAt time.Time
+	stack     []byte
 }
 
 var _ Reader = (*Snapshot)(nil)
@@ -43,10 +57,16 @@ var _ Reader = (*Snapshot)(nil)
 // slice will remain valid until the returned Closer is closed. On success, the
 // caller MUST call closer.Close() or a memory leak will occur.
 func (s *Snapshot) Get(key []byte) ([]byte, io.Closer, error) {
+	return s.GetWithContext(context.Background(), key)
+}
+
+// GetWithContext is like Get, and additionally accepts a context. If the
+// context is canceled, reads stop issuing I/O and return the context's error.
+func (s *Snapshot) GetWithContext(ctx context.Context, key []byte) ([]byte, io.Closer, error) {
 	if s.db == nil {
 		panic(ErrClosed)
 	}
-	return s.db.getInternal(key, nil /* batch */, s)
+	return s.db.getInternal(ctx, key, nil /* batch */, s)
 }
 
 // NewIter returns an iterator that is unpositioned (Iterator.Valid() will
@@ -57,14 +77,14 @@ func (s *Snapshot) NewIter(o *IterOptions) (*Iterator, error) {
 }
 
 // NewIterWithContext is like NewIter, and additionally accepts a context for
-// tracing.
+// tracing and cancellation (see DB.NewIterWithContext).
 func (s *Snapshot) NewIterWithContext(ctx context.Context, o *IterOptions) (*Iterator, error) {
 	if s.db == nil {
 		panic(ErrClosed)
 	}
 	return s.db.newIter(ctx, nil /* batch */, newIterOpts{
 		snapshot: snapshotIterOpts{seqNum: s.seqNum},
-	}, o), nil
+	}, o)
 }
 
 // ScanInternal scans all internal keys within the specified bounds, truncating
@@ -109,6 +129,15 @@ func (s *Snapshot) ScanInternal(
 	return scanInternalImpl(ctx, lower, upper, iter, scanInternalOpts)
 }
 
+// ScanInternalKeys scans the internal keys of a key range at the sequence
+// number of the snapshot; see ScanInternalKeysOptions.
+func (s *Snapshot) ScanInternalKeys(ctx context.Context, opts *ScanInternalKeysOptions) error {
+	if s.db == nil {
+		panic(ErrClosed)
+	}
+	return s.db.scanInternalKeys(ctx, snapshotIterOpts{seqNum: s.seqNum}, opts)
+}
+
 // closeLocked is similar to Close(), except it requires that db.mu be held
 // by the caller.
 func (s *Snapshot) closeLocked() error {
@@ -142,6 +171,9 @@ func (s *Snapshot) Close() error {
 
 type snapshotList struct {
 	root Snapshot
+	// gen is incremented whenever a snapshot is added to or removed from the
+	// list.
+	gen uint64
 }
 
 func (l *snapshotList) init() {
@@ -192,6 +224,25 @@ func (l *snapshotList) pushBack(s *Snapshot) {
 	s.next = &l.root
 	s.next.prev = s
 	s.list = l
+	l.gen++
+}
+
+// insert inserts the snapshot into the list, keeping the list sorted by
+// sequence number.
+func (l *snapshotList) insert(s *Snapshot) {
+	at := l.root.prev
+	for at != &l.root && at.seqNum > s.seqNum {
+		at = at.prev
+	}
+	if s.list != nil || s.prev != nil || s.next != nil {
+		panic("pebble: snapshot list is inconsistent")
+	}
+	s.prev = at
+	s.next = at.next
+	s.prev.next = s
+	s.next.prev = s
+	s.list = l
+	l.gen++
 }
 
 func (l *snapshotList) remove(s *Snapshot) {
@@ -206,6 +257,7 @@ func (l *snapshotList) remove(s *Snapshot) {
 	s.next = nil // avoid memory leaks
 	s.prev = nil // avoid memory leaks
 	s.list = nil // avoid memory leaks
+	l.gen++
 }
 
 // EventuallyFileOnlySnapshot (aka EFOS) provides a read-only point-in-time view
@@ -251,9 +303,10 @@ type EventuallyFileOnlySnapshot struct {
 	protectedRanges []KeyRange
 
 	// The db the snapshot was created from.
-	db     *DB
-	seqNum base.SeqNum
-	closed chan struct{}
+	db        *DB
+	seqNum    base.SeqNum
+	createdAt time.Time
+	closed    chan struct{}
 }
 
 func (d *DB) makeEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFileOnlySnapshot {
@@ -272,9 +325,14 @@ func (d *DB) makeEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFil
 	es := &EventuallyFileOnlySnapshot{
 		db:              d,
 		seqNum:          seqNum,
+		createdAt:       d.timeNow(),
 		protectedRanges: keyRanges,
 		closed:          make(chan struct{}),
 	}
+	if d.mu.snapshots.efos == nil {
+		d.mu.snapshots.efos = make(map[*EventuallyFileOnlySnapshot]struct{})
+	}
+	d.mu.snapshots.efos[es] = struct{}{}
 	if isFileOnly {
 		es.mu.vers = d.mu.versions.currentVersion()
 		es.mu.vers.Ref()
@@ -285,6 +343,7 @@ func (d *DB) makeEventuallyFileOnlySnapshot(keyRanges []KeyRange) *EventuallyFil
 		}
 		s.efos = es
 		es.mu.snap = s
+		d.trackSnapshot(s)
 		d.mu.snapshots.pushBack(s)
 	}
 	return es
@@ -350,7 +409,8 @@ func (es *EventuallyFileOnlySnapshot) waitForFlush(ctx context.Context, dur time
 		// Check if the current mutable memtable contains keys less than seqNum.
 		// If so, rotate it.
 		if es.db.mu.mem.mutable.logSeqNum < es.seqNum && dur.Nanoseconds() > 0 {
-			es.db.maybeScheduleDelayedFlush(es.db.mu.mem.mutable, dur)
+			es.db.maybeScheduleDelayedFlush(
+				es.db.mu.mem.mutable, dur, FlushReasonFileOnlySnapshot)
 		} else {
 			// Find the last memtable that contains seqNums less than es.seqNum,
 			// and force a flush on it.
@@ -360,7 +420,7 @@ func (es *EventuallyFileOnlySnapshot) waitForFlush(ctx context.Context, dur time
 					mem = es.db.mu.mem.queue[i]
 				}
 			}
-			mem.flushForced = true
+			mem.forceFlush(FlushReasonFileOnlySnapshot)
 			es.db.maybeScheduleFlush()
 		}
 		es.db.mu.compact.cond.Wait()
@@ -403,6 +463,7 @@ func (es *EventuallyFileOnlySnapshot) Close() error {
 	close(es.closed)
 	es.db.mu.Lock()
 	defer es.db.mu.Unlock()
+	delete(es.db.mu.snapshots.efos, es)
 	es.mu.Lock()
 	defer es.mu.Unlock()
 
@@ -458,12 +519,11 @@ func (es *EventuallyFileOnlySnapshot) NewIterWithContext(
 	defer es.mu.Unlock()
 	if es.mu.vers != nil {
 		sOpts := snapshotIterOpts{seqNum: es.seqNum, vers: es.mu.vers}
-		return es.db.newIter(ctx, nil /* batch */, newIterOpts{snapshot: sOpts}, o), nil
+		return es.db.newIter(ctx, nil /* batch */, newIterOpts{snapshot: sOpts}, o)
 	}
 
 	sOpts := snapshotIterOpts{seqNum: es.seqNum}
-	iter := es.db.newIter(ctx, nil /* batch */, newIterOpts{snapshot: sOpts}, o)
-	return iter, nil
+	return es.db.newIter(ctx, nil /* batch */, newIterOpts{snapshot: sOpts}, o)
 }
 
 // ScanInternal scans all internal keys within the specified bounds, truncating
@@ -519,3 +579,18 @@ func (es *EventuallyFileOnlySnapshot) ScanInternal(
 
 	return scanInternalImpl(ctx, lower, upper, iter, opts)
 }
+
+// ScanInternalKeys scans the internal keys of a key range at the sequence
+// number of the snapshot; see ScanInternalKeysOptions.
+func (es *EventuallyFileOnlySnapshot) ScanInternalKeys(
+	ctx context.Context, opts *ScanInternalKeysOptions,
+) error {
+	if es.db == nil {
+		panic(ErrClosed)
+	}
+	sOpts := snapshotIterOpts{seqNum: es.seqNum}
+	es.mu.Lock()
+	sOpts.vers = es.mu.vers
+	es.mu.Unlock()
+	return es.db.scanInternalKeys(ctx, sOpts, opts)
+}
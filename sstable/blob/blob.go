@@ -32,6 +32,8 @@ func (f FileFormat) String() string {
 	switch f {
 	case FileFormatV1:
 		return "blobV1"
+	case FileFormatV2:
+		return "blobV2"
 	default:
 		return "unknown"
 	}
@@ -40,8 +42,55 @@ func (f FileFormat) String() string {
 const (
 	// FileFormatV1 is the first version of the blob file format.
 	FileFormatV1 FileFormat = 1
+	// FileFormatV2 is a version of the blob file format in which values are
+	// compressed individually rather than as blocks. Each value is prefixed
+	// with a byte-sized block.CompressionIndicator. A compressed value's
+	// indicator is followed by the uvarint-encoded length of the compressed
+	// value. Value blocks themselves are never compressed.
+	FileFormatV2 FileFormat = 2
 )
 
+// CompressionGranularity configures the unit of compression within a blob
+// file.
+type CompressionGranularity uint8
+
+const (
+	// CompressBlocks compresses each block of values as a unit. Retrieving any
+	// value requires decompressing the entire block containing it, but the
+	// decompressed block is cached in the block cache.
+	CompressBlocks CompressionGranularity = iota
+	// CompressValues compresses each value individually, so that retrieving a
+	// value only requires decompressing the value itself. Values that do not
+	// compress well are stored uncompressed. Blob files written with
+	// CompressValues use FileFormatV2.
+	CompressValues
+)
+
+// String implements the fmt.Stringer interface.
+func (g CompressionGranularity) String() string {
+	switch g {
+	case CompressBlocks:
+		return "block"
+	case CompressValues:
+		return "value"
+	default:
+		return "unknown"
+	}
+}
+
+// CompressionGranularityFromString returns the CompressionGranularity with the
+// provided name. Inverse of CompressionGranularity.String.
+func CompressionGranularityFromString(s string) (CompressionGranularity, bool) {
+	switch s {
+	case "block":
+		return CompressBlocks, true
+	case "value":
+		return CompressValues, true
+	default:
+		return 0, false
+	}
+}
+
 const (
 	fileFooterLength = 33
 	fileMagic        = "\xf0\x9f\xaa\xb3\xf0\x9f\xa6\x80" // 🪳🦀
@@ -49,9 +98,12 @@ const (
 
 // FileWriterOptions are used to configure the FileWriter.
 type FileWriterOptions struct {
-	Compression   block.Compression
-	ChecksumType  block.ChecksumType
-	FlushGovernor block.FlushGovernor
+	Compression block.Compression
+	// CompressionGranularity configures whether values are compressed as
+	// blocks or individually.
+	CompressionGranularity CompressionGranularity
+	ChecksumType           block.ChecksumType
+	FlushGovernor          block.FlushGovernor
 	// Only CPUMeasurer.MeasureCPUBlobFileSecondary is used.
 	CpuMeasurer base.CPUMeasurer
 }
@@ -95,9 +147,15 @@ func (s FileWriterStats) String() string {
 
 // A FileWriter writes a blob file.
 type FileWriter struct {
-	fileNum      base.DiskFileNum
-	w            objstorage.Writable
-	b            block.Buffer
+	fileNum base.DiskFileNum
+	w       objstorage.Writable
+	format  FileFormat
+	b       block.Buffer
+	// valueCompression is the compression algorithm applied to individual
+	// values. It's only used when format is FileFormatV2.
+	valueCompression block.Compression
+	compressBuf      []byte
+
 	stats        FileWriterStats
 	flushGov     block.FlushGovernor
 	blockOffsets []uint64
@@ -123,7 +181,15 @@ func NewFileWriter(fn base.DiskFileNum, w objstorage.Writable, opts FileWriterOp
 	fw := writerPool.Get().(*FileWriter)
 	fw.fileNum = fn
 	fw.w = w
+	fw.format = FileFormatV1
 	fw.b.Init(opts.Compression, opts.ChecksumType)
+	if opts.CompressionGranularity == CompressValues {
+		// Values are compressed individually, so there's no use in attempting
+		// to compress the blocks containing them.
+		fw.format = FileFormatV2
+		fw.valueCompression = opts.Compression
+		fw.b.SetCompression(block.NoCompression)
+	}
 	fw.flushGov = opts.FlushGovernor
 	fw.checksumType = opts.ChecksumType
 	fw.cpuMeasurer = opts.CpuMeasurer
@@ -140,6 +206,9 @@ var writerPool = sync.Pool{
 // AddValue adds the provided value to the blob file, returning a Handle
 // identifying the location of the value.
 func (w *FileWriter) AddValue(v []byte) Handle {
+	if w.format >= FileFormatV2 {
+		return w.addCompressedValue(v)
+	}
 	// Determine if we should first flush the block.
 	if sz := w.b.Size(); sz != 0 && w.flushGov.ShouldFlush(sz, sz+len(v)) {
 		w.flush()
@@ -155,6 +224,41 @@ func (w *FileWriter) AddValue(v []byte) Handle {
 	}
 }
 
+// addCompressedValue adds the provided value to a FileFormatV2 blob file,
+// compressing the value individually. Like block compression, the compressed
+// value is discarded in favor of the uncompressed value if compression doesn't
+// reduce the value's size by at least 12.5%.
+func (w *FileWriter) addCompressedValue(v []byte) Handle {
+	var prefix [1 + binary.MaxVarintLen64]byte
+	prefix[0] = byte(block.NoCompressionIndicator)
+	prefixLen := 1
+	payload := v
+	if w.valueCompression != block.NoCompression && len(v) > 0 {
+		var algo block.CompressionIndicator
+		algo, w.compressBuf = block.GetCompressor(w.valueCompression).Compress(w.compressBuf[:0], v)
+		if len(w.compressBuf) < len(v)-len(v)/8 {
+			prefix[0] = byte(algo)
+			prefixLen += binary.PutUvarint(prefix[1:], uint64(len(w.compressBuf)))
+			payload = w.compressBuf
+		}
+	}
+	// Determine if we should first flush the block.
+	encodedLen := prefixLen + len(payload)
+	if sz := w.b.Size(); sz != 0 && w.flushGov.ShouldFlush(sz, sz+encodedLen) {
+		w.flush()
+	}
+	w.stats.ValueCount++
+	w.stats.UncompressedValueBytes += uint64(len(v))
+	off := uint32(w.b.Append(prefix[:prefixLen]))
+	w.b.Append(payload)
+	return Handle{
+		FileNum:       w.fileNum,
+		BlockNum:      uint32(w.stats.BlockCount),
+		OffsetInBlock: off,
+		ValueLen:      uint32(len(v)),
+	}
+}
+
 // EstimatedSize returns an estimate of the disk space consumed by the blob file
 // if it were closed now.
 func (w *FileWriter) EstimatedSize() uint64 {
@@ -274,7 +378,7 @@ func (w *FileWriter) Close() (FileWriterStats, error) {
 
 	// Write the footer.
 	footer := fileFooter{
-		format:      FileFormatV1,
+		format:      w.format,
 		checksum:    w.checksumType,
 		indexHandle: vbih,
 	}
@@ -292,8 +396,10 @@ func (w *FileWriter) Close() (FileWriterStats, error) {
 	// Clean up w and return it to the pool.
 	w.b.Release()
 	blockOffsets := w.blockOffsets[:0]
+	compressBuf := w.compressBuf[:0]
 	*w = FileWriter{}
 	w.blockOffsets = blockOffsets
+	w.compressBuf = compressBuf
 	writerPool.Put(w)
 	return stats, nil
 }
@@ -343,7 +449,7 @@ func (f *fileFooter) decode(b []byte) error {
 
 	f.checksum = block.ChecksumType(b[23])
 	f.format = FileFormat(b[24])
-	if f.format != FileFormatV1 {
+	if f.format != FileFormatV1 && f.format != FileFormatV2 {
 		return base.CorruptionErrorf("invalid blob file format %x", f.format)
 	}
 	if string(b[25:]) != fileMagic {
@@ -451,6 +557,11 @@ func (r *FileReader) ValueIndexHandle() valblk.IndexHandle {
 	return r.footer.indexHandle
 }
 
+// Format returns the format of the blob file.
+func (r *FileReader) Format() FileFormat {
+	return r.footer.format
+}
+
 func noInitBlockMetadata(_ *block.Metadata, _ []byte) error { return nil }
 
 // lenLittleEndian returns the minimum number of bytes needed to encode v
@@ -67,10 +67,19 @@ func scanFileWriterOptions(t *testing.T, td *datadriven.TestData) FileWriterOpti
 	if cmdArg, ok := td.Arg("compression"); ok {
 		compression = block.CompressionFromString(cmdArg.SingleVal(t))
 	}
+	var granularity CompressionGranularity
+	if cmdArg, ok := td.Arg("compression-granularity"); ok {
+		var ok bool
+		granularity, ok = CompressionGranularityFromString(cmdArg.SingleVal(t))
+		if !ok {
+			t.Fatalf("unknown compression granularity %q", cmdArg.SingleVal(t))
+		}
+	}
 	return FileWriterOptions{
-		Compression:   compression,
-		ChecksumType:  block.ChecksumTypeCRC32c,
-		FlushGovernor: block.MakeFlushGovernor(targetBlockSize, blockSizeThreshold, 0, nil),
+		Compression:            compression,
+		CompressionGranularity: granularity,
+		ChecksumType:           block.ChecksumTypeCRC32c,
+		FlushGovernor:          block.MakeFlushGovernor(targetBlockSize, blockSizeThreshold, 0, nil),
 	}
 }
 
@@ -6,6 +6,7 @@ package blob
 
 import (
 	"context"
+	"encoding/binary"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -24,6 +25,10 @@ type ValueReader interface {
 	// ValueIndexHandle returns the handle for the file's value index block.
 	ValueIndexHandle() valblk.IndexHandle
 
+	// Format returns the format of the file, which determines how values are
+	// encoded within value blocks.
+	Format() FileFormat
+
 	// InitReadHandle initializes a ReadHandle for the file, using the provided
 	// preallocated read handle to avoid an allocation.
 	InitReadHandle(rh *objstorageprovider.PreallocatedReadHandle) objstorage.ReadHandle
@@ -85,11 +90,12 @@ func (r *ValueFetcher) Fetch(
 		BlockNum:      handleSuffix.BlockNum,
 		OffsetInBlock: handleSuffix.OffsetInBlock,
 	}
-	v, err := r.retrieve(ctx, vh)
-	return v, false, err
+	return r.retrieve(ctx, vh, buf)
 }
 
-func (r *ValueFetcher) retrieve(ctx context.Context, vh Handle) (val []byte, err error) {
+func (r *ValueFetcher) retrieve(
+	ctx context.Context, vh Handle, buf []byte,
+) (val []byte, callerOwned bool, err error) {
 	// Look for a cached reader for the file. Also, find the least-recently used
 	// reader. If we don't find a cached reader, we'll replace the
 	// least-recently used reader with the new one for the file indicated by
@@ -111,11 +117,11 @@ func (r *ValueFetcher) retrieve(ctx context.Context, vh Handle) (val []byte, err
 		// Release the previous reader, if any.
 		if cr.r != nil {
 			if err = cr.Close(); err != nil {
-				return nil, err
+				return nil, false, err
 			}
 		}
 		if cr.r, cr.closeFunc, err = r.readerProvider.GetValueReader(ctx, vh.FileNum); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		cr.fileNum = vh.FileNum
 		cr.rh = cr.r.InitReadHandle(&cr.preallocRH)
@@ -123,8 +129,7 @@ func (r *ValueFetcher) retrieve(ctx context.Context, vh Handle) (val []byte, err
 
 	r.fetchCount++
 	cr.lastFetchCount = r.fetchCount
-	val, err = cr.GetUnsafeValue(ctx, vh, r.env)
-	return val, err
+	return cr.GetUnsafeValue(ctx, vh, r.env, buf)
 }
 
 // Close closes the ValueFetcher and releases all cached readers. Once Close is
@@ -154,13 +159,16 @@ type cachedReader struct {
 	preallocRH         objstorageprovider.PreallocatedReadHandle
 }
 
-// GetUnsafeValue retrieves the value for the given handle. The value is
-// returned as a byte slice pointing directly into the block cache's data. The
-// value is only guaranteed to be stable until the next call to GetUnsafeValue
-// or until the cachedReader is closed.
+// GetUnsafeValue retrieves the value for the given handle. If the value is
+// stored uncompressed, it is returned as a byte slice pointing directly into
+// the block cache's data. The value is only guaranteed to be stable until the
+// next call to GetUnsafeValue or until the cachedReader is closed.
+//
+// If the value was compressed individually (see CompressValues), the value is
+// decompressed into buf, growing it if necessary, and callerOwned is true.
 func (cr *cachedReader) GetUnsafeValue(
-	ctx context.Context, vh Handle, env block.ReadEnv,
-) ([]byte, error) {
+	ctx context.Context, vh Handle, env block.ReadEnv, buf []byte,
+) (val []byte, callerOwned bool, err error) {
 	ctx = objiotracing.WithBlockType(ctx, objiotracing.ValueBlock)
 
 	if !cr.indexBlockBuf.Valid() {
@@ -168,7 +176,7 @@ func (cr *cachedReader) GetUnsafeValue(
 		var err error
 		cr.indexBlockBuf, err = cr.r.ReadValueIndexBlock(ctx, env, cr.rh)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	}
 
@@ -177,23 +185,61 @@ func (cr *cachedReader) GetUnsafeValue(
 		// file's index block.
 		h, err := valblk.DecodeBlockHandleFromIndex(cr.indexBlockBuf.BlockData(), vh.BlockNum, cr.r.ValueIndexHandle())
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		cr.currentBlockBuf.Release()
 		cr.currentBlockLoaded = false
 		cr.currentBlockBuf, err = cr.r.ReadValueBlock(ctx, env, cr.rh, h)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		cr.currentBlockNum = vh.BlockNum
 		cr.currentBlockLoaded = true
 	}
 	data := cr.currentBlockBuf.BlockData()
+	if cr.r.Format() >= FileFormatV2 {
+		return decodeCompressedValue(data, vh, buf)
+	}
 	if len(data) < int(vh.OffsetInBlock+vh.ValueLen) {
-		return nil, base.CorruptionErrorf("blob file %s: block %d: expected block length %d, got %d",
+		return nil, false, base.CorruptionErrorf("blob file %s: block %d: expected block length %d, got %d",
 			vh.FileNum, vh.BlockNum, vh.OffsetInBlock+vh.ValueLen, len(data))
 	}
-	return data[vh.OffsetInBlock : vh.OffsetInBlock+vh.ValueLen], nil
+	return data[vh.OffsetInBlock : vh.OffsetInBlock+vh.ValueLen], false, nil
+}
+
+// decodeCompressedValue decodes the value identified by vh from the provided
+// FileFormatV2 value block. If the value is compressed, it's decompressed into
+// buf and callerOwned is true.
+func decodeCompressedValue(
+	data []byte, vh Handle, buf []byte,
+) (val []byte, callerOwned bool, err error) {
+	if len(data) <= int(vh.OffsetInBlock) {
+		return nil, false, base.CorruptionErrorf("blob file %s: block %d: value offset %d beyond block length %d",
+			vh.FileNum, vh.BlockNum, vh.OffsetInBlock, len(data))
+	}
+	algo := block.CompressionIndicator(data[vh.OffsetInBlock])
+	data = data[vh.OffsetInBlock+1:]
+	if algo == block.NoCompressionIndicator {
+		if len(data) < int(vh.ValueLen) {
+			return nil, false, base.CorruptionErrorf("blob file %s: block %d: expected value length %d, got %d",
+				vh.FileNum, vh.BlockNum, vh.ValueLen, len(data))
+		}
+		return data[:vh.ValueLen], false, nil
+	}
+	compressedLen, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < compressedLen {
+		return nil, false, base.CorruptionErrorf("blob file %s: block %d: invalid compressed value length at offset %d",
+			vh.FileNum, vh.BlockNum, vh.OffsetInBlock)
+	}
+	compressed := data[n : n+int(compressedLen)]
+	if cap(buf) < int(vh.ValueLen) {
+		buf = make([]byte, vh.ValueLen)
+	}
+	buf = buf[:vh.ValueLen]
+	if err := block.DecompressInto(algo, compressed, buf); err != nil {
+		return nil, false, err
+	}
+	return buf, true, nil
 }
 
 // Close releases resources associated with the reader.
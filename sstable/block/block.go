@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
@@ -370,11 +371,24 @@ type ReadEnv struct {
 	// is managed by the fileCacheContainer.
 	Stats     *base.InternalIteratorStats
 	IterStats *CategoryStatsShard
+	// BytesRead, if set, is incremented by the length of every block that is
+	// read or served from the cache. Unlike Stats, it may be read concurrently;
+	// it is used to report the progress of compactions.
+	BytesRead *atomic.Int64
 
 	// BufferPool is not-nil if we read blocks into a buffer pool and not into the
 	// cache. This is used during compactions.
 	BufferPool *BufferPool
 
+	// IndexBlockPins, if set, pins the index blocks read from the cache so that
+	// they are shared with the other iterators using the same pins (e.g. the
+	// clones of a pebble.Iterator).
+	IndexBlockPins *BlockPins
+
+	// LevelPlusOne is the LSM level of the table being read plus one, or zero
+	// if unknown. It is provided to the readahead policy of the read handles.
+	LevelPlusOne uint8
+
 	// ReportCorruptionFn is called with ReportCorruptionArg and the error
 	// whenever an SSTable corruption is detected. The argument is used to avoid
 	// allocating a separate function for each object. It returns an error with
@@ -392,17 +406,27 @@ func (env *ReadEnv) BlockServedFromCache(blockLength uint64) {
 	if env.IterStats != nil {
 		env.IterStats.Accumulate(blockLength, blockLength, 0)
 	}
+	if env.BytesRead != nil {
+		env.BytesRead.Add(int64(blockLength))
+	}
 }
 
-// BlockRead updates the stats when a block had to be read.
-func (env *ReadEnv) BlockRead(blockLength uint64, readDuration time.Duration) {
+// BlockRead updates the stats when a block had to be read, from a remote
+// object if remote is true.
+func (env *ReadEnv) BlockRead(blockLength uint64, readDuration time.Duration, remote bool) {
 	if env.Stats != nil {
 		env.Stats.BlockBytes += blockLength
 		env.Stats.BlockReadDuration += readDuration
+		if remote {
+			env.Stats.RemoteBlockBytes += blockLength
+		}
 	}
 	if env.IterStats != nil {
 		env.IterStats.Accumulate(blockLength, 0, readDuration)
 	}
+	if env.BytesRead != nil {
+		env.BytesRead.Add(int64(blockLength))
+	}
 }
 
 // maybeReportCorruption calls the ReportCorruptionFn if the given error
@@ -433,6 +457,10 @@ type ReaderOptions struct {
 	LoadBlockSema *fifo.Semaphore
 	// LoggerAndTracer is an optional logger and tracer.
 	LoggerAndTracer base.LoggerAndTracer
+	// Remote is true if the blocks are read from a remote object. The bytes
+	// of the blocks read (and not found in the cache) are then accounted for
+	// in InternalIteratorStats.RemoteBlockBytes.
+	Remote bool
 }
 
 // Init initializes the Reader to read blocks from the provided Readable.
@@ -541,6 +569,10 @@ func (r *Reader) doRead(
 		}
 		defer sema.Release(1)
 	}
+	// Don't issue the read if the caller is no longer interested in the block.
+	if err := ctx.Err(); err != nil {
+		return Value{}, err
+	}
 
 	compressed := Alloc(int(bh.Length+TrailerLen), env.BufferPool)
 	readStopwatch := makeStopwatch()
@@ -566,7 +598,7 @@ func (r *Reader) doRead(
 		compressed.Release()
 		return Value{}, err
 	}
-	env.BlockRead(bh.Length, readDuration)
+	env.BlockRead(bh.Length, readDuration, r.opts.Remote)
 	if err = ValidateChecksum(r.checksumType, compressed.BlockData(), bh); err != nil {
 		compressed.Release()
 		err = errors.Wrapf(err, "pebble/table: table %s", r.opts.CacheOpts.FileNum)
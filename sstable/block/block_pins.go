@@ -0,0 +1,152 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package block
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/cache"
+	"github.com/cockroachdb/pebble/objstorage"
+)
+
+// BlockPins holds references to blocks read from the block cache, shared by a
+// set of iterators: a block pinned by one of the iterators is served to the
+// others without a cache lookup, and stays in memory until the pins are
+// released, regardless of the cache's evictions.
+//
+// The total size of the pinned blocks is bounded by the size passed to Init:
+// when pinning a block would exceed it, the blocks that were pinned first are
+// released. The blocks remain in the cache, subject to its evictions.
+//
+// BlockPins is safe for concurrent use.
+type BlockPins struct {
+	maxSize int64
+	mu      struct {
+		sync.Mutex
+		blocks map[blockPinKey]*cache.Value
+		// order holds the keys of the pinned blocks, in the order in which
+		// they were pinned.
+		order    []blockPinKey
+		size     int64
+		hits     int64
+		released int64
+	}
+}
+
+type blockPinKey struct {
+	cacheHandle *cache.Handle
+	fileNum     base.DiskFileNum
+	offset      uint64
+}
+
+// BlockPinsStats contains the stats of a BlockPins.
+type BlockPinsStats struct {
+	// Count and Size are the number and total size in bytes of the pinned
+	// blocks.
+	Count int
+	Size  int64
+	// Hits is the number of block reads served by the pins.
+	Hits int64
+	// Released is the number of blocks whose pin was released to respect the
+	// maximum size of the pins.
+	Released int64
+}
+
+// Init initializes the pins, with the maximum total size in bytes of the
+// pinned blocks.
+func (p *BlockPins) Init(maxSize int64) {
+	p.maxSize = maxSize
+}
+
+// Read reads the block referenced by the provided handle like Reader.Read,
+// serving it from the pins if it is pinned, and pinning it otherwise. Blocks
+// that aren't read through the block cache are never pinned.
+func (p *BlockPins) Read(
+	ctx context.Context,
+	env ReadEnv,
+	r *Reader,
+	readHandle objstorage.ReadHandle,
+	bh Handle,
+	initBlockMetadataFn func(*Metadata, []byte) error,
+) (BufferHandle, error) {
+	if r.opts.CacheOpts.CacheHandle == nil || env.BufferPool != nil {
+		return r.Read(ctx, env, readHandle, bh, initBlockMetadataFn)
+	}
+	key := blockPinKey{
+		cacheHandle: r.opts.CacheOpts.CacheHandle,
+		fileNum:     r.opts.CacheOpts.FileNum,
+		offset:      bh.Offset,
+	}
+	p.mu.Lock()
+	if cv, ok := p.mu.blocks[key]; ok {
+		cv.Acquire()
+		p.mu.hits++
+		p.mu.Unlock()
+		recordCacheHit(ctx, env, readHandle, bh)
+		return CacheBufferHandle(cv), nil
+	}
+	p.mu.Unlock()
+
+	h, err := r.Read(ctx, env, readHandle, bh, initBlockMetadataFn)
+	if err != nil || h.cv == nil {
+		return h, err
+	}
+	size := int64(len(h.cv.RawBuffer()))
+	if size > p.maxSize {
+		return h, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.mu.blocks[key]; !ok {
+		for p.mu.size+size > p.maxSize {
+			p.releaseOldestLocked()
+		}
+		if p.mu.blocks == nil {
+			p.mu.blocks = make(map[blockPinKey]*cache.Value)
+		}
+		h.cv.Acquire()
+		p.mu.blocks[key] = h.cv
+		p.mu.order = append(p.mu.order, key)
+		p.mu.size += size
+	}
+	return h, nil
+}
+
+// releaseOldestLocked releases the pin of the block that was pinned first.
+func (p *BlockPins) releaseOldestLocked() {
+	key := p.mu.order[0]
+	p.mu.order = p.mu.order[1:]
+	cv := p.mu.blocks[key]
+	delete(p.mu.blocks, key)
+	p.mu.size -= int64(len(cv.RawBuffer()))
+	p.mu.released++
+	cv.Release()
+}
+
+// Stats returns the current stats of the pins.
+func (p *BlockPins) Stats() BlockPinsStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return BlockPinsStats{
+		Count:    len(p.mu.blocks),
+		Size:     p.mu.size,
+		Hits:     p.mu.hits,
+		Released: p.mu.released,
+	}
+}
+
+// Release releases all the pinned blocks. The pins may be reused afterwards.
+func (p *BlockPins) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, cv := range p.mu.blocks {
+		cv.Release()
+	}
+	p.mu.blocks = nil
+	p.mu.order = nil
+	p.mu.size = 0
+}
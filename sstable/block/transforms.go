@@ -7,6 +7,8 @@ package block
 import (
 	"bytes"
 	"fmt"
+	"slices"
+	"sort"
 	"unsafe"
 
 	"github.com/cockroachdb/pebble/internal/base"
@@ -162,6 +164,115 @@ func (sp SyntheticPrefix) Invert(key []byte) []byte {
 	return res
 }
 
+// PrefixReplacement represents a read-time replacement of a ContentPrefix that
+// is common to every key physically stored in a file with a SyntheticPrefix.
+// Unlike SyntheticPrefix, which only prepends bytes to the stored keys,
+// PrefixReplacement allows leading bytes of the stored keys to be stripped or
+// rewritten; for example, keys written under one tenant's keyspace can be
+// surfaced under another's without rewriting the file.
+//
+// Every key in the file, including the end keys of range deletions and range
+// keys, must have the ContentPrefix as a prefix. Replacing a prefix common to
+// all keys preserves their relative ordering. Both prefixes must be contained
+// within the portion of the keys returned by the Comparer's Split.
+type PrefixReplacement struct {
+	ContentPrefix   []byte
+	SyntheticPrefix []byte
+}
+
+// IsSet returns true if the replacement strips a non-empty content prefix.
+func (pr PrefixReplacement) IsSet() bool {
+	return len(pr.ContentPrefix) > 0
+}
+
+// Apply replaces the content prefix of a key with the synthetic prefix.
+func (pr PrefixReplacement) Apply(key []byte) []byte {
+	return pr.ApplyTo(nil, key)
+}
+
+// ApplyTo is like Apply, but appends the result to dst.
+func (pr PrefixReplacement) ApplyTo(dst, key []byte) []byte {
+	rest, ok := bytes.CutPrefix(key, pr.ContentPrefix)
+	if !ok {
+		panic(fmt.Sprintf("unexpected content prefix: %s", key))
+	}
+	dst = append(dst, pr.SyntheticPrefix...)
+	return append(dst, rest...)
+}
+
+// Invert replaces the synthetic prefix of a key with the content prefix.
+func (pr PrefixReplacement) Invert(key []byte) []byte {
+	return pr.InvertTo(nil, key)
+}
+
+// InvertTo is like Invert, but appends the result to dst.
+func (pr PrefixReplacement) InvertTo(dst, key []byte) []byte {
+	rest, ok := bytes.CutPrefix(key, pr.SyntheticPrefix)
+	if !ok {
+		panic(fmt.Sprintf("unexpected prefix: %s", key))
+	}
+	dst = append(dst, pr.ContentPrefix...)
+	return append(dst, rest...)
+}
+
+// SuffixReplacement represents a read-time replacement of the suffix From of
+// the keys physically stored in a file with the suffix To. A list of suffix
+// replacements generalizes SyntheticSuffix, which replaces every suffix with a
+// single one: the keys of a file holding several suffixes can be re-stamped
+// with several target suffixes.
+//
+// A file's suffix replacements are sorted by From, in the order of the
+// Comparer's ComparePointSuffixes, and replacing the suffixes must preserve the
+// relative ordering of the keys: the To suffixes must be sorted in the same
+// order. Every non-empty suffix of a point key in the file must be the From of
+// a replacement; keys without a suffix are left as is.
+type SuffixReplacement struct {
+	From, To []byte
+}
+
+// SuffixReplacements is a file's list of suffix replacements, sorted by From.
+type SuffixReplacements []SuffixReplacement
+
+// ApplyTo replaces the suffix of key according to the replacements, appending
+// the result to dst. It returns false if the key has a suffix that isn't
+// replaced.
+func (rs SuffixReplacements) ApplyTo(cmp *base.Comparer, dst, key []byte) ([]byte, bool) {
+	n := cmp.Split(key)
+	if n == len(key) {
+		return append(dst, key...), true
+	}
+	i, found := slices.BinarySearchFunc(rs, key[n:], func(r SuffixReplacement, suffix []byte) int {
+		return cmp.ComparePointSuffixes(r.From, suffix)
+	})
+	if !found {
+		return dst, false
+	}
+	dst = append(dst, key[:n]...)
+	return append(dst, rs[i].To...), true
+}
+
+// InvertTo maps key to the smallest key physically stored in the file whose
+// replaced key is greater than or equal to key, appending the result to dst:
+// the keys stored in the file that sort before the result are exactly those
+// whose replaced keys sort before key. Since keys are compared by prefix
+// first, the result may be the immediate successor of the key's prefix when
+// the key's suffix sorts after all the replaced suffixes. It returns false in
+// that case.
+func (rs SuffixReplacements) InvertTo(cmp *base.Comparer, dst, key []byte) ([]byte, bool) {
+	n := cmp.Split(key)
+	if n == len(key) {
+		return append(dst, key...), true
+	}
+	i := sort.Search(len(rs), func(i int) bool {
+		return cmp.ComparePointSuffixes(rs[i].To, key[n:]) >= 0
+	})
+	if i == len(rs) {
+		return cmp.ImmediateSuccessor(dst, key[:n]), false
+	}
+	dst = append(dst, key[:n]...)
+	return append(dst, rs[i].From...), true
+}
+
 // SyntheticPrefixAndSuffix is a more compact way of representing both a
 // synthetic prefix and a synthetic suffix. See SyntheticPrefix and
 // SyntheticSuffix.
@@ -246,3 +357,16 @@ func (ps SyntheticPrefixAndSuffix) RemoveSuffix() SyntheticPrefixAndSuffix {
 		buf:       ps.buf,
 	}
 }
+
+// RemovePrefix returns a SyntheticPrefixAndSuffix that has the same suffix as
+// the receiver but no prefix.
+func (ps SyntheticPrefixAndSuffix) RemovePrefix() SyntheticPrefixAndSuffix {
+	if ps.suffixLen == 0 {
+		return SyntheticPrefixAndSuffix{}
+	}
+	return SyntheticPrefixAndSuffix{
+		prefixLen: 0,
+		suffixLen: ps.suffixLen,
+		buf:       unsafe.Pointer(uintptr(ps.buf) + uintptr(ps.prefixLen)),
+	}
+}
@@ -103,4 +103,31 @@ func TestSyntheticPrefixAndSuffix(t *testing.T) {
 	require.Equal(t, "suffix", string(ps.Suffix()))
 
 	require.True(t, ps.RemoveSuffix().IsUnset())
+
+	ps = MakeSyntheticPrefixAndSuffix([]byte("some-prefix"), []byte("suffix"))
+	ps = ps.RemovePrefix()
+	require.False(t, ps.IsUnset())
+	require.False(t, ps.HasPrefix())
+	require.Nil(t, ps.Prefix())
+	require.True(t, ps.HasSuffix())
+	require.Equal(t, "suffix", string(ps.Suffix()))
+
+	require.True(t, MakeSyntheticPrefixAndSuffix([]byte("some-prefix"), nil).RemovePrefix().IsUnset())
+}
+
+func TestPrefixReplacement(t *testing.T) {
+	require.False(t, PrefixReplacement{SyntheticPrefix: []byte("b/")}.IsSet())
+
+	pr := PrefixReplacement{ContentPrefix: []byte("tenant1/"), SyntheticPrefix: []byte("t2/")}
+	require.True(t, pr.IsSet())
+	require.Equal(t, "t2/foo", string(pr.Apply([]byte("tenant1/foo"))))
+	require.Equal(t, "tenant1/foo", string(pr.Invert([]byte("t2/foo"))))
+	require.Equal(t, "x:t2/", string(pr.ApplyTo([]byte("x:"), []byte("tenant1/"))))
+	require.Panics(t, func() { pr.Apply([]byte("tenant2/foo")) })
+	require.Panics(t, func() { pr.Invert([]byte("t3/foo")) })
+
+	// Stripping the content prefix without a synthetic prefix.
+	pr = PrefixReplacement{ContentPrefix: []byte("tenant1/")}
+	require.Equal(t, "foo", string(pr.Apply([]byte("tenant1/foo"))))
+	require.Equal(t, "tenant1/foo", string(pr.Invert([]byte("foo"))))
 }
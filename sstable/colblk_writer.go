@@ -61,7 +61,12 @@ type RawColumnWriter struct {
 	// filter accumulates the filter block. If populated, the filter ingests
 	// either the output of w.split (i.e. a prefix extractor) if w.split is not
 	// nil, or the full keys otherwise.
-	filterBlock  filterWriter
+	filterBlock filterWriter
+
+	// valueFilter accumulates the value filter block, if enabled. See
+	// WriterOptions.ValueFilter.
+	valueFilter *valueFilterWriter
+
 	prevPointKey struct {
 		trailer    base.InternalKeyTrailer
 		isObsolete bool
@@ -145,6 +150,9 @@ func newColumnarWriter(
 		switch o.FilterType {
 		case TableFilter:
 			w.filterBlock = newTableFilterWriter(o.FilterPolicy)
+			if o.ValueFilter {
+				w.valueFilter = newValueFilterWriter(o.FilterPolicy)
+			}
 		default:
 			panic(fmt.Sprintf("unknown filter type: %v", o.FilterType))
 		}
@@ -473,7 +481,11 @@ func (w *RawColumnWriter) add(
 	}
 	w.obsoleteCollector.AddPoint(eval.isObsolete)
 	if w.filterBlock != nil {
-		w.filterBlock.addKey(key.UserKey[:eval.kcmp.PrefixLen])
+		prefix := key.UserKey[:eval.kcmp.PrefixLen]
+		w.filterBlock.addKey(prefix)
+		if w.valueFilter != nil {
+			w.valueFilter.addPoint(key.Kind(), prefix)
+		}
 	}
 	w.meta.updateSeqNum(key.SeqNum())
 	if !w.meta.HasPointKeys {
@@ -947,6 +959,11 @@ func (w *RawColumnWriter) Close() (err error) {
 		w.props.FilterPolicyName = w.filterBlock.policyName()
 		w.props.FilterSize = bh.Length
 	}
+	if w.valueFilter != nil && w.valueFilter.shouldWrite() {
+		if _, err := w.layout.WriteFilterBlock(w.valueFilter); err != nil {
+			return err
+		}
+	}
 
 	// Write the range deletion block if non-empty.
 	if w.rangeDelBlock.KeyCount() > 0 {
@@ -1004,6 +1021,11 @@ func (w *RawColumnWriter) Close() (err error) {
 				// that the block property collector was used when writing.
 				userProps[w.blockPropCollectors[i].Name()] = prop
 			}
+			for name, prop := range w.opts.inheritedUserProperties {
+				if _, ok := userProps[name]; !ok {
+					userProps[name] = prop
+				}
+			}
 			if len(userProps) > 0 {
 				w.props.UserProperties = userProps
 			}
@@ -1036,6 +1058,9 @@ func (w *RawColumnWriter) Close() (err error) {
 func (w *RawColumnWriter) rewriteSuffixes(
 	r *Reader, sstBytes []byte, wo WriterOptions, from, to []byte, concurrency int,
 ) error {
+	// Keys that don't go through the writer's key path aren't reflected in
+	// the value filter, so it must not be written.
+	w.valueFilter = nil
 	for _, c := range w.blockPropCollectors {
 		if !c.SupportsSuffixReplacement() {
 			return errors.Errorf("block property collector %s does not support suffix replacement", c.Name())
@@ -1145,6 +1170,7 @@ func shouldFlushWithoutLatestKV(
 func (w *RawColumnWriter) copyDataBlocks(
 	ctx context.Context, blocks []indexEntry, rh objstorage.ReadHandle,
 ) error {
+	w.valueFilter = nil // See rewriteSuffixes.
 	const readSizeTarget = 256 << 10
 	readAndFlushBlocks := func(firstBlockIdx, lastBlockIdx int) error {
 		if firstBlockIdx > lastBlockIdx {
@@ -1202,6 +1228,7 @@ func (w *RawColumnWriter) copyDataBlocks(
 // by the sstable copier that can copy parts of an sstable to a new sstable,
 // using CopySpan().
 func (w *RawColumnWriter) addDataBlock(b, sep []byte, bhp block.HandleWithProperties) error {
+	w.valueFilter = nil // See rewriteSuffixes.
 	// Serialize the data block, compress it and send it to the write queue.
 	cb := compressedBlockPool.Get().(*compressedBlock)
 	cb.blockBuf.checksummer.Type = w.opts.Checksum
@@ -1221,6 +1248,7 @@ func (w *RawColumnWriter) addDataBlock(b, sep []byte, bhp block.HandleWithProper
 // by the sstable copier that can copy parts of an sstable to a new sstable,
 // using CopySpan().
 func (w *RawColumnWriter) copyFilter(filter []byte, filterName string) error {
+	w.valueFilter = nil // See rewriteSuffixes.
 	if w.filterBlock != nil && filterName != w.filterBlock.policyName() {
 		return errors.New("mismatched filters")
 	}
@@ -1245,3 +1273,8 @@ func (w *RawColumnWriter) copyProperties(props Properties) {
 	w.props.IndexSize = 0
 	w.props.IndexType = 0
 }
+
+// setError implements RawWriter.
+func (w *RawColumnWriter) setError(err error) {
+	w.err = firstError(w.err, err)
+}
@@ -0,0 +1,285 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/sstable/blob"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/sstable/colblk"
+	"github.com/cockroachdb/pebble/sstable/rowblk"
+	"github.com/cockroachdb/pebble/sstable/valblk"
+)
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// ResolveBlobValue, if set, is called to retrieve the values that are
+	// stored in blob files. The sstable only records the index of the blob
+	// file among the table's blob references (see blob.ReferenceID); mapping
+	// it to a blob file requires the table's metadata in the manifest. If
+	// ResolveBlobValue is nil, the values stored in blob files are only
+	// described by their handle.
+	ResolveBlobValue func(ctx context.Context, h blob.InlineHandle) ([]byte, error)
+}
+
+// ExportRecord is a JSON record written by Export.
+type ExportRecord struct {
+	// Type is one of "properties", "point", "range-del" and "range-key".
+	Type string `json:"type"`
+	// Format and Properties are set for the properties record. Properties
+	// holds the properties that are set, keyed by their name in the
+	// properties block, and the user properties.
+	Format     string         `json:"format,omitempty"`
+	Properties map[string]any `json:"properties,omitempty"`
+	// Key is the user key of a point key, and Start and End are the bounds of
+	// a range deletion or range key.
+	Key   []byte `json:"key,omitempty"`
+	Start []byte `json:"start,omitempty"`
+	End   []byte `json:"end,omitempty"`
+	// SeqNum and Kind are the sequence number and the kind of a key.
+	SeqNum uint64 `json:"seqnum"`
+	Kind   string `json:"kind,omitempty"`
+	// Suffix is the suffix of a range key.
+	Suffix []byte `json:"suffix,omitempty"`
+	// Value is the value of a point key or range key. It isn't set for the
+	// values stored in blob files, unless ExportOptions.ResolveBlobValue is
+	// set.
+	Value []byte `json:"value,omitempty"`
+	// Blob describes the handle of a value stored in a blob file.
+	Blob *ExportBlobHandle `json:"blob,omitempty"`
+}
+
+// ExportBlobHandle describes the handle of a value stored in a blob file.
+type ExportBlobHandle struct {
+	ReferenceID   uint32 `json:"ref"`
+	BlockNum      uint32 `json:"block"`
+	OffsetInBlock uint32 `json:"offset"`
+	ValueLen      uint32 `json:"len"`
+}
+
+// Export writes the contents of the sstable read by r to w in the JSON lines
+// format, for offline analysis and debugging: a properties record, followed
+// by a record for each point key, range deletion and range key, in key order.
+// Each record is an ExportRecord encoded as a JSON object; keys and values
+// are base64-encoded.
+//
+// Export reads the blocks of the table directly, so that it supports all the
+// table formats, including the values that are stored in value blocks or in
+// blob files (see ExportOptions.ResolveBlobValue). The range keys are
+// exported as one record per key of a fragment.
+func Export(ctx context.Context, r *Reader, w io.Writer, o ExportOptions) error {
+	bw := bufio.NewWriter(w)
+	e := exporter{
+		ctx: ctx,
+		r:   r,
+		o:   o,
+		enc: json.NewEncoder(bw),
+	}
+	if err := e.exportProperties(); err != nil {
+		return err
+	}
+	if err := e.exportPointKeys(); err != nil {
+		return err
+	}
+	rangeDelIter, err := r.NewRawRangeDelIter(ctx, NoFragmentTransforms, block.NoReadEnv)
+	if err != nil {
+		return err
+	}
+	if err := e.exportSpans(rangeDelIter); err != nil {
+		return err
+	}
+	rangeKeyIter, err := r.NewRawRangeKeyIter(ctx, NoFragmentTransforms, block.NoReadEnv)
+	if err != nil {
+		return err
+	}
+	if err := e.exportSpans(rangeKeyIter); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// exporter holds the state of an Export call.
+type exporter struct {
+	ctx context.Context
+	r   *Reader
+	o   ExportOptions
+	enc *json.Encoder
+	// values resolves the values of the point keys that aren't stored in
+	// place.
+	values   exportValueHandler
+	valueBuf []byte
+}
+
+func (e *exporter) exportProperties() error {
+	props := make(map[string]any)
+	visitProperties(e.r.Properties.Loaded, reflect.ValueOf(e.r.Properties), func(tag string, f reflect.Value) {
+		props[tag] = f.Interface()
+	})
+	for k, v := range e.r.Properties.UserProperties {
+		props[k] = v
+	}
+	return e.enc.Encode(&ExportRecord{
+		Type:       "properties",
+		Format:     e.r.tableFormat.String(),
+		Properties: props,
+	})
+}
+
+func (e *exporter) exportPointKeys() error {
+	layout, err := e.r.Layout()
+	if err != nil {
+		return err
+	}
+	e.values.vbReader = valblk.MakeReader(e, MakeTrivialReaderProvider(e.r), e.r.valueBIH, nil /* stats */)
+	defer e.values.vbReader.Close()
+	for _, bh := range layout.Data {
+		if err := e.exportDataBlock(bh.Handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *exporter) exportDataBlock(bh block.Handle) error {
+	h, err := e.r.readDataBlock(e.ctx, block.NoReadEnv, noReadHandle, bh)
+	if err != nil {
+		return err
+	}
+	defer h.Release()
+	if e.r.tableFormat.BlockColumnar() {
+		var decoder colblk.DataBlockDecoder
+		decoder.Init(e.r.keySchema, h.BlockData())
+		var iter colblk.DataBlockIter
+		iter.InitOnce(e.r.keySchema, e.r.Comparer, &e.values)
+		if err := iter.Init(&decoder, block.IterTransforms{}); err != nil {
+			return err
+		}
+		defer iter.Close()
+		return e.exportBlockKVs(&iter)
+	}
+	iter, err := rowblk.NewIter(e.r.Comparer.Compare, e.r.Comparer.ComparePointSuffixes, e.r.Comparer.Split, h.BlockData(), NoTransforms)
+	if err != nil {
+		return err
+	}
+	if e.r.tableFormat >= TableFormatPebblev3 {
+		iter.SetHasValuePrefix(true)
+		iter.SetGetLazyValuer(&e.values)
+	}
+	defer iter.Close()
+	return e.exportBlockKVs(iter)
+}
+
+func (e *exporter) exportBlockKVs(iter block.DataBlockIterator) error {
+	for kv := iter.First(); kv != nil; kv = iter.Next() {
+		rec := ExportRecord{
+			Type:   "point",
+			Key:    kv.K.UserKey,
+			SeqNum: uint64(kv.K.SeqNum()),
+			Kind:   kv.K.Kind().String(),
+		}
+		if e.values.isBlob {
+			e.values.isBlob = false
+			h := e.values.blobHandle
+			rec.Blob = &ExportBlobHandle{
+				ReferenceID:   uint32(h.ReferenceID),
+				BlockNum:      h.BlockNum,
+				OffsetInBlock: h.OffsetInBlock,
+				ValueLen:      h.ValueLen,
+			}
+			if e.o.ResolveBlobValue != nil {
+				v, err := e.o.ResolveBlobValue(e.ctx, h)
+				if err != nil {
+					return err
+				}
+				rec.Value = v
+			}
+		} else {
+			v, callerOwned, err := kv.Value(e.valueBuf)
+			if err != nil {
+				return err
+			}
+			if callerOwned {
+				e.valueBuf = v[:0]
+			}
+			rec.Value = v
+		}
+		if err := e.enc.Encode(&rec); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+func (e *exporter) exportSpans(iter keyspan.FragmentIterator) error {
+	if iter == nil {
+		return nil
+	}
+	defer iter.Close()
+	span, err := iter.First()
+	for ; span != nil; span, err = iter.Next() {
+		for _, k := range span.Keys {
+			rec := ExportRecord{
+				Type:   "range-key",
+				Start:  span.Start,
+				End:    span.End,
+				SeqNum: uint64(k.SeqNum()),
+				Kind:   k.Kind().String(),
+				Suffix: k.Suffix,
+				Value:  k.Value,
+			}
+			if k.Kind() == base.InternalKeyKindRangeDelete {
+				rec.Type = "range-del"
+			}
+			if err := e.enc.Encode(&rec); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// ReadValueBlock implements the valblk.IteratorBlockReader interface.
+func (e *exporter) ReadValueBlock(
+	bh block.Handle, stats *base.InternalIteratorStats,
+) (block.BufferHandle, error) {
+	env := block.NoReadEnv
+	env.Stats = stats
+	return e.r.readValueBlock(e.ctx, env, noReadHandle, bh)
+}
+
+// exportValueHandler is the block.GetInternalValueForPrefixAndValueHandler of
+// an Export call. It resolves the values that are stored in value blocks, and
+// records the handles of the values that are stored in blob files.
+type exportValueHandler struct {
+	vbReader valblk.Reader
+	// isBlob is set if the value of the current key is stored in a blob file,
+	// in which case blobHandle is its handle.
+	isBlob     bool
+	blobHandle blob.InlineHandle
+}
+
+var _ block.GetInternalValueForPrefixAndValueHandler = (*exportValueHandler)(nil)
+
+func (h *exportValueHandler) GetInternalValueForPrefixAndValueHandle(
+	handle []byte,
+) base.InternalValue {
+	if !block.ValuePrefix(handle[0]).IsBlobValueHandle() {
+		return h.vbReader.GetInternalValueForPrefixAndValueHandle(handle)
+	}
+	preface, rest := blob.DecodeInlineHandlePrefix(handle[1:])
+	h.isBlob = true
+	h.blobHandle = blob.InlineHandle{
+		InlineHandlePreface: preface,
+		HandleSuffix:        blob.DecodeHandleSuffix(rest),
+	}
+	return base.MakeInPlaceValue(nil)
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/blob"
+	"github.com/cockroachdb/pebble/sstable/colblk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	keySchema := colblk.DefaultKeySchema(testkeys.Comparer, 16)
+	readerOpts := ReaderOptions{
+		Comparer:   testkeys.Comparer,
+		KeySchemas: MakeKeySchemas(&keySchema),
+	}
+	blobHandle := blob.InlineHandle{
+		InlineHandlePreface: blob.InlineHandlePreface{ReferenceID: 1, ValueLen: 100},
+		HandleSuffix:        blob.HandleSuffix{BlockNum: 2, OffsetInBlock: 30},
+	}
+	export := func(t *testing.T, sst []byte, o ExportOptions) []ExportRecord {
+		r, err := NewMemReader(sst, readerOpts)
+		require.NoError(t, err)
+		defer r.Close()
+		var buf bytes.Buffer
+		require.NoError(t, Export(context.Background(), r, &buf, o))
+		var recs []ExportRecord
+		s := bufio.NewScanner(&buf)
+		for s.Scan() {
+			var rec ExportRecord
+			require.NoError(t, json.Unmarshal(s.Bytes(), &rec))
+			recs = append(recs, rec)
+		}
+		return recs
+	}
+	// describe returns the records other than the properties record as
+	// strings.
+	describe := func(recs []ExportRecord) []string {
+		var res []string
+		for _, rec := range recs[1:] {
+			s := fmt.Sprintf("%s %s%s-%s #%d,%s %s=%s", rec.Type, rec.Key, rec.Start, rec.End, rec.SeqNum, rec.Kind, rec.Suffix, rec.Value)
+			if rec.Blob != nil {
+				s += fmt.Sprintf(" blob:%+v", *rec.Blob)
+			}
+			res = append(res, s)
+		}
+		return res
+	}
+
+	for _, format := range []TableFormat{TableFormatPebblev2, TableFormatPebblev3, TableFormatPebblev5, TableFormatPebblev6} {
+		t.Run(format.String(), func(t *testing.T) {
+			f := &objstorage.MemObj{}
+			w := NewRawWriter(f, WriterOptions{
+				Comparer:    testkeys.Comparer,
+				KeySchema:   &keySchema,
+				TableFormat: format,
+			})
+			add := func(key string, seqNum base.SeqNum, kind base.InternalKeyKind, value string) {
+				ik := base.MakeInternalKey([]byte(key), seqNum, kind)
+				require.NoError(t, w.Add(ik, []byte(value), false /* forceObsolete */))
+			}
+			add("a@3", 3, InternalKeyKindSet, "a3")
+			// a@2 is stored in a value block, for the formats that have them.
+			add("a@2", 2, InternalKeyKindSet, "a2")
+			add("b", 4, InternalKeyKindDelete, "")
+			add("c", 5, InternalKeyKindMerge, "c5")
+			if format >= TableFormatPebblev6 {
+				ik := base.MakeInternalKey([]byte("d"), 6, InternalKeyKindSet)
+				require.NoError(t, w.AddWithBlobHandle(ik, blobHandle, 0, false /* forceObsolete */))
+			}
+			require.NoError(t, w.EncodeSpan(keyspan.Span{
+				Start: []byte("e"), End: []byte("f"),
+				Keys: []keyspan.Key{{Trailer: base.MakeTrailer(7, InternalKeyKindRangeDelete)}},
+			}))
+			require.NoError(t, w.EncodeSpan(keyspan.Span{
+				Start: []byte("g"), End: []byte("h"),
+				Keys: []keyspan.Key{
+					{Trailer: base.MakeTrailer(9, base.InternalKeyKindRangeKeySet), Suffix: []byte("@5"), Value: []byte("v")},
+					{Trailer: base.MakeTrailer(8, base.InternalKeyKindRangeKeyDelete)},
+				},
+			}))
+			require.NoError(t, w.Close())
+			sst := f.Data()
+
+			recs := export(t, sst, ExportOptions{})
+			require.Equal(t, "properties", recs[0].Type)
+			require.Equal(t, format.String(), recs[0].Format)
+			require.EqualValues(t, 1, recs[0].Properties["rocksdb.num.range-deletions"])
+			if format >= TableFormatPebblev3 {
+				require.EqualValues(t, 1, recs[0].Properties["pebble.num.values.in.value-blocks"])
+			}
+			expected := []string{
+				"point a@3- #3,SET =a3",
+				"point a@2- #2,SET =a2",
+				"point b- #4,DEL =",
+				"point c- #5,MERGE =c5",
+				"range-del e-f #7,RANGEDEL =",
+				"range-key g-h #9,RANGEKEYSET @5=v",
+				"range-key g-h #8,RANGEKEYDEL =",
+			}
+			if format < TableFormatPebblev6 {
+				require.Equal(t, expected, describe(recs))
+				return
+			}
+			require.EqualValues(t, 1, recs[0].Properties["pebble.num.values.in.blob-files"])
+			blobRecord := "point d- #6,SET = blob:{ReferenceID:1 BlockNum:2 OffsetInBlock:30 ValueLen:100}"
+			expected = append(expected[:4], append([]string{blobRecord}, expected[4:]...)...)
+			require.Equal(t, expected, describe(recs))
+
+			// The values stored in blob files are resolved by ResolveBlobValue.
+			recs = export(t, sst, ExportOptions{
+				ResolveBlobValue: func(_ context.Context, h blob.InlineHandle) ([]byte, error) {
+					require.Equal(t, blobHandle, h)
+					return []byte("d6"), nil
+				},
+			})
+			require.Equal(t, "point d- #6,SET =d6 blob:{ReferenceID:1 BlockNum:2 OffsetInBlock:30 ValueLen:100}", describe(recs)[4])
+		})
+	}
+}
@@ -4,7 +4,11 @@
 
 package sstable
 
-import "sync/atomic"
+import (
+	"sync/atomic"
+
+	"github.com/cockroachdb/pebble/internal/base"
+)
 
 // FilterMetrics holds metrics for the filter policy.
 type FilterMetrics struct {
@@ -100,3 +104,50 @@ func (f *tableFilterWriter) metaName() string {
 func (f *tableFilterWriter) policyName() string {
 	return f.policy.Name()
 }
+
+// valueFilterMetaPrefix is the prefix of the metaindex name of a table's value
+// filter block. The prefix is followed by the filter policy's name.
+const valueFilterMetaPrefix = "valuefilter."
+
+// valueFilterWriter accumulates a table's value filter: a table filter over the
+// prefixes of the point keys that carry a value (i.e., that aren't point
+// tombstones). A prefix that may be in the table filter but isn't in the value
+// filter has only point tombstones in the table, which allows point lookups
+// that don't need to observe tombstones to skip the table.
+//
+// The value filter is only written if the table contains point tombstones;
+// otherwise it would be equivalent to the table filter.
+type valueFilterWriter struct {
+	tableFilterWriter
+	// numTombstones is the number of point tombstones added.
+	numTombstones int
+}
+
+func newValueFilterWriter(policy FilterPolicy) *valueFilterWriter {
+	return &valueFilterWriter{
+		tableFilterWriter: tableFilterWriter{
+			policy: policy,
+			writer: policy.NewWriter(TableFilter),
+		},
+	}
+}
+
+// addPoint adds the point key with the given prefix to the value filter, unless
+// it is a point tombstone.
+func (f *valueFilterWriter) addPoint(kind base.InternalKeyKind, prefix []byte) {
+	switch kind {
+	case base.InternalKeyKindDelete, base.InternalKeyKindSingleDelete, base.InternalKeyKindDeleteSized:
+		f.numTombstones++
+	default:
+		f.addKey(prefix)
+	}
+}
+
+// shouldWrite returns true if the value filter should be written to the table.
+func (f *valueFilterWriter) shouldWrite() bool {
+	return f.numTombstones > 0
+}
+
+func (f *valueFilterWriter) metaName() string {
+	return valueFilterMetaPrefix + f.policy.Name()
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"container/heap"
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+)
+
+// MergePolicy determines which keys Merge keeps when several keys share the
+// same user key.
+type MergePolicy uint8
+
+const (
+	// MergeKeepNewest keeps, for each user key, only the key with the largest
+	// trailer (i.e. the largest sequence number). If several sources contain the
+	// user key with the same trailer (e.g. sstables built for ingestion, which
+	// use sequence number zero), the key from the source that comes last in
+	// srcs is kept.
+	MergeKeepNewest MergePolicy = iota
+	// MergeKeepLastSource keeps, for each user key, only the keys from the
+	// source that comes last in srcs among the sources that contain the user
+	// key, regardless of their sequence numbers.
+	MergeKeepLastSource
+	// MergeKeepAll keeps all the keys. The sources must not contain identical
+	// internal keys (i.e. the same user key with the same trailer).
+	MergeKeepAll
+)
+
+// String implements fmt.Stringer.
+func (p MergePolicy) String() string {
+	switch p {
+	case MergeKeepNewest:
+		return "keep-newest"
+	case MergeKeepLastSource:
+		return "keep-last-source"
+	case MergeKeepAll:
+		return "keep-all"
+	default:
+		return "unknown"
+	}
+}
+
+// MergeOptions configures Merge.
+type MergeOptions struct {
+	// ReaderOptions are used to open the sources.
+	ReaderOptions ReaderOptions
+	// WriterOptions are used to write the output. WriterOptions.Comparer is
+	// used to order the keys and must be the comparer the sources were written
+	// with.
+	WriterOptions WriterOptions
+	// Policy determines the keys that are kept when several keys share the same
+	// user key.
+	Policy MergePolicy
+}
+
+// Merge merge-sorts the point keys of the srcs sstables into a single sstable
+// written to dst, without requiring a DB. This is useful to consolidate the
+// sstables produced by an offline pipeline before ingesting them. Duplicate
+// user keys are resolved according to opts.Policy.
+//
+// Merging sstables that contain range deletions or range keys is not
+// supported.
+//
+// Merge takes ownership of srcs, which are closed when it returns, and
+// finishes or aborts dst in all cases, including on errors.
+func Merge(
+	ctx context.Context, dst objstorage.Writable, srcs []objstorage.Readable, opts MergeOptions,
+) (_ *WriterMetadata, err error) {
+	if opts.WriterOptions.Comparer == nil {
+		opts.WriterOptions.Comparer = base.DefaultComparer
+	}
+	if opts.ReaderOptions.Comparer == nil {
+		opts.ReaderOptions.Comparer = opts.WriterOptions.Comparer
+	}
+	// The merged keys don't carry obsolete bits.
+	opts.WriterOptions.IsStrictObsolete = false
+	w := NewRawWriter(dst, opts.WriterOptions)
+	defer func() {
+		if w != nil {
+			// Abort dst instead of finishing a truncated sstable.
+			w.setError(err)
+			_ = w.Close()
+		}
+	}()
+
+	m := mergeHeap{cmp: opts.WriterOptions.Comparer.Compare, policy: opts.Policy}
+	defer func() {
+		for _, s := range m.sources {
+			err = firstError(err, s.close())
+		}
+	}()
+	for i, src := range srcs {
+		r, err := NewReader(ctx, src, opts.ReaderOptions)
+		if err != nil {
+			// NewReader closes src on error.
+			for _, src := range srcs[i+1:] {
+				_ = src.Close()
+			}
+			return nil, err
+		}
+		s := &mergeSource{index: i, r: r}
+		m.sources = append(m.sources, s)
+		if r.Properties.NumRangeDeletions > 0 || r.Properties.NumRangeKeys() > 0 {
+			for _, src := range srcs[i+1:] {
+				_ = src.Close()
+			}
+			return nil, errors.New("pebble: merging sstables with range deletions or range keys is not supported")
+		}
+		if s.iter, err = r.NewIter(NoTransforms, nil /* lower */, nil /* upper */); err != nil {
+			for _, src := range srcs[i+1:] {
+				_ = src.Close()
+			}
+			return nil, err
+		}
+	}
+	for _, s := range m.sources {
+		s.kv = s.iter.First()
+		if err := s.iter.Error(); err != nil {
+			return nil, err
+		}
+		if s.kv != nil {
+			m.items = append(m.items, s)
+		}
+	}
+	heap.Init(&m)
+
+	var prevUserKey []byte
+	var prevSource int
+	var prevTrailer base.InternalKeyTrailer
+	first := true
+	for len(m.items) > 0 {
+		s := m.items[0]
+		kv := s.kv
+		sameUserKey := !first && m.cmp(kv.K.UserKey, prevUserKey) == 0
+		keep := true
+		switch {
+		case !sameUserKey:
+		case opts.Policy == MergeKeepNewest:
+			keep = false
+		case opts.Policy == MergeKeepLastSource:
+			keep = s.index == prevSource
+		case opts.Policy == MergeKeepAll && kv.K.Trailer == prevTrailer:
+			return nil, errors.Errorf("pebble: duplicate key %s in merged sstables",
+				kv.K.Pretty(opts.WriterOptions.Comparer.FormatKey))
+		}
+		if keep {
+			val, _, err := kv.Value(nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := w.Add(kv.K, val, false /* forceObsolete */); err != nil {
+				return nil, err
+			}
+			prevSource = s.index
+			prevTrailer = kv.K.Trailer
+		}
+		if !sameUserKey {
+			prevUserKey = append(prevUserKey[:0], kv.K.UserKey...)
+			first = false
+		}
+
+		s.kv = s.iter.Next()
+		if err := s.iter.Error(); err != nil {
+			return nil, err
+		}
+		if s.kv != nil {
+			heap.Fix(&m, 0)
+		} else {
+			heap.Pop(&m)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		w = nil
+		return nil, err
+	}
+	meta, err := w.Metadata()
+	w = nil
+	return meta, err
+}
+
+// mergeSource is one of the sstables being merged.
+type mergeSource struct {
+	index int
+	r     *Reader
+	iter  Iterator
+	kv    *base.InternalKV
+}
+
+func (s *mergeSource) close() error {
+	var err error
+	if s.iter != nil {
+		err = s.iter.Close()
+	}
+	return firstError(err, s.r.Close())
+}
+
+// mergeHeap is a min-heap of the positioned sources, ordered by the key they're
+// positioned at. For the same user key, the keys that the policy prefers come
+// first.
+type mergeHeap struct {
+	cmp     base.Compare
+	policy  MergePolicy
+	sources []*mergeSource
+	items   []*mergeSource
+}
+
+var _ heap.Interface = (*mergeHeap)(nil)
+
+func (m *mergeHeap) Len() int { return len(m.items) }
+
+func (m *mergeHeap) Less(i, j int) bool {
+	a, b := m.items[i], m.items[j]
+	if c := m.cmp(a.kv.K.UserKey, b.kv.K.UserKey); c != 0 {
+		return c < 0
+	}
+	if m.policy == MergeKeepLastSource && a.index != b.index {
+		return a.index > b.index
+	}
+	if a.kv.K.Trailer != b.kv.K.Trailer {
+		return a.kv.K.Trailer > b.kv.K.Trailer
+	}
+	return a.index > b.index
+}
+
+func (m *mergeHeap) Swap(i, j int) { m.items[i], m.items[j] = m.items[j], m.items[i] }
+
+func (m *mergeHeap) Push(x any) { m.items = append(m.items, x.(*mergeSource)) }
+
+func (m *mergeHeap) Pop() any {
+	n := len(m.items)
+	s := m.items[n-1]
+	m.items = m.items[:n-1]
+	return s
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	// buildTable builds an sstable out of the given internal keys, all with the
+	// given value.
+	buildTable := func(value string, keys ...string) objstorage.Readable {
+		f := &objstorage.MemObj{}
+		w := NewRawWriter(f, WriterOptions{TableFormat: TableFormatPebblev4})
+		for _, k := range keys {
+			ik := base.ParseInternalKey(k)
+			require.NoError(t, w.Add(ik, []byte(value), false /* forceObsolete */))
+		}
+		require.NoError(t, w.Close())
+		return f
+	}
+	// contents returns the keys and values of an sstable.
+	contents := func(f *objstorage.MemObj) string {
+		r, err := NewReader(context.Background(), f, ReaderOptions{})
+		require.NoError(t, err)
+		defer r.Close()
+		iter, err := r.NewIter(NoTransforms, nil, nil)
+		require.NoError(t, err)
+		defer iter.Close()
+		var buf strings.Builder
+		for kv := iter.First(); kv != nil; kv = iter.Next() {
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			fmt.Fprintf(&buf, "%s:%s\n", kv.K, v)
+		}
+		require.NoError(t, iter.Error())
+		return buf.String()
+	}
+	sources := func() []objstorage.Readable {
+		return []objstorage.Readable{
+			buildTable("s0", "a#5,SET", "c#3,SET", "c#2,DEL", "e#1,SET"),
+			buildTable("s1", "b#4,SET", "c#1,SET", "d#6,SET"),
+			buildTable("s2", "a#5,SET", "d#2,SET", "f#7,SET"),
+		}
+	}
+
+	testCases := []struct {
+		policy   MergePolicy
+		expected string
+	}{
+		{
+			policy: MergeKeepNewest,
+			expected: `a#5,SET:s2
+b#4,SET:s1
+c#3,SET:s0
+d#6,SET:s1
+e#1,SET:s0
+f#7,SET:s2
+`,
+		},
+		{
+			policy: MergeKeepLastSource,
+			expected: `a#5,SET:s2
+b#4,SET:s1
+c#1,SET:s1
+d#2,SET:s2
+e#1,SET:s0
+f#7,SET:s2
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			dst := &objstorage.MemObj{}
+			meta, err := Merge(context.Background(), dst, sources(), MergeOptions{
+				WriterOptions: WriterOptions{TableFormat: TableFormatPebblev4},
+				Policy:        tc.policy,
+			})
+			require.NoError(t, err)
+			require.Equal(t, uint64(6), meta.Properties.NumEntries)
+			require.Equal(t, tc.expected, contents(dst))
+		})
+	}
+
+	t.Run(MergeKeepAll.String(), func(t *testing.T) {
+		// The sources contain a#5 twice. The output is aborted rather than
+		// finished with the keys merged before the error.
+		aborted := &objstorage.MemObj{}
+		_, err := Merge(context.Background(), aborted, sources(), MergeOptions{
+			WriterOptions: WriterOptions{TableFormat: TableFormatPebblev4},
+			Policy:        MergeKeepAll,
+		})
+		require.ErrorContains(t, err, "duplicate key a#5,SET")
+		require.Empty(t, aborted.Data())
+
+		srcs := sources()[:2]
+		dst := &objstorage.MemObj{}
+		_, err = Merge(context.Background(), dst, srcs, MergeOptions{
+			WriterOptions: WriterOptions{TableFormat: TableFormatPebblev4},
+			Policy:        MergeKeepAll,
+		})
+		require.NoError(t, err)
+		require.Equal(t, `a#5,SET:s0
+b#4,SET:s1
+c#3,SET:s0
+c#2,DEL:s0
+c#1,SET:s1
+d#6,SET:s1
+e#1,SET:s0
+`, contents(dst))
+	})
+
+	t.Run("range-deletions", func(t *testing.T) {
+		f := &objstorage.MemObj{}
+		w := NewWriter(f, WriterOptions{TableFormat: TableFormatPebblev4})
+		require.NoError(t, w.DeleteRange([]byte("a"), []byte("b")))
+		require.NoError(t, w.Close())
+		_, err := Merge(context.Background(), &objstorage.MemObj{}, []objstorage.Readable{f}, MergeOptions{
+			WriterOptions: WriterOptions{TableFormat: TableFormatPebblev4},
+		})
+		require.ErrorContains(t, err, "not supported")
+	})
+}
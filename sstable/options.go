@@ -190,6 +190,14 @@ type WriterOptions struct {
 	// filters should be preferred except under constrained memory situations.
 	FilterType FilterType
 
+	// ValueFilter, if true and FilterPolicy is set, writes a value filter in
+	// addition to the table filter for tables that contain point tombstones.
+	// The value filter only includes the prefixes of point keys that carry a
+	// value, which allows point lookups to skip tables whose only entries for
+	// a prefix are point tombstones, when those tombstones can't shadow
+	// anything. See IterOptions.SkipTombstoneOnlyPrefixes.
+	ValueFilter bool
+
 	// IndexBlockSize is the target uncompressed size in bytes of each index
 	// block. When the index block size is larger than this target, two-level
 	// indexes are automatically enabled. Setting this option to a large value
@@ -242,13 +250,14 @@ type WriterOptions struct {
 
 	// DisableValueBlocks is only used for TableFormat >= TableFormatPebblev3,
 	// and if set to true, does not write any values to value blocks. This is
-	// only intended for cases where the in-memory buffering of all value blocks
-	// while writing a sstable is too expensive and likely to cause an OOM. It
-	// is never set to true by a Pebble DB, and can be set to true when some
-	// external code is directly generating huge sstables using Pebble's
-	// sstable.Writer (for example, CockroachDB backups can sometimes write
-	// 750MB sstables -- see
-	// https://github.com/cockroachdb/cockroach/issues/117113).
+	// intended for cases where the in-memory buffering of all value blocks
+	// while writing a sstable is too expensive and likely to cause an OOM, such
+	// as when some external code is directly generating huge sstables using
+	// Pebble's sstable.Writer (for example, CockroachDB backups can sometimes
+	// write 750MB sstables -- see
+	// https://github.com/cockroachdb/cockroach/issues/117113). A Pebble DB sets
+	// it for the levels configured with LevelOptions.DisableValueBlocks, whose
+	// sstables are short-lived.
 	DisableValueBlocks bool
 
 	// AllocatorSizeClasses provides a sorted list containing the supported size
@@ -270,6 +279,11 @@ type WriterOptions struct {
 	// disableObsoleteCollector is used to disable the obsolete key block property
 	// collector automatically added by sstable block writers.
 	disableObsoleteCollector bool
+
+	// inheritedUserProperties are user properties added to the table, unless
+	// a block property collector sets a property of the same name. It's used
+	// by Rewrite to carry over the user properties of the rewritten table.
+	inheritedUserProperties map[string]string
 }
 
 // UserKeyPrefixBound represents a [Lower,Upper) bound of user key prefixes.
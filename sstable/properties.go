@@ -210,12 +210,34 @@ func (p *Properties) NumRangeKeys() uint64 {
 }
 
 func writeProperties(loaded map[uintptr]struct{}, v reflect.Value, buf *bytes.Buffer) {
+	visitProperties(loaded, v, func(tag string, f reflect.Value) {
+		fmt.Fprintf(buf, "%s: ", tag)
+		switch f.Kind() {
+		case reflect.Bool:
+			fmt.Fprintf(buf, "%t\n", f.Bool())
+		case reflect.Uint32:
+			fmt.Fprintf(buf, "%d\n", f.Uint())
+		case reflect.Uint64:
+			fmt.Fprintf(buf, "%d\n", f.Uint())
+		case reflect.String:
+			fmt.Fprintf(buf, "%s\n", f.String())
+		default:
+			panic("not reached")
+		}
+	})
+}
+
+// visitProperties calls fn with the tag and the value of each property of v
+// that is set or was loaded from disk.
+func visitProperties(
+	loaded map[uintptr]struct{}, v reflect.Value, fn func(tag string, f reflect.Value),
+) {
 	vt := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		ft := vt.Field(i)
 		if ft.Type.Kind() == reflect.Struct {
 			// Embedded struct within the properties.
-			writeProperties(loaded, v.Field(i), buf)
+			visitProperties(loaded, v.Field(i), fn)
 			continue
 		}
 		tag := ft.Tag.Get("prop")
@@ -231,20 +253,7 @@ func writeProperties(loaded map[uintptr]struct{}, v reflect.Value, buf *bytes.Bu
 				continue
 			}
 		}
-
-		fmt.Fprintf(buf, "%s: ", tag)
-		switch ft.Type.Kind() {
-		case reflect.Bool:
-			fmt.Fprintf(buf, "%t\n", f.Bool())
-		case reflect.Uint32:
-			fmt.Fprintf(buf, "%d\n", f.Uint())
-		case reflect.Uint64:
-			fmt.Fprintf(buf, "%d\n", f.Uint())
-		case reflect.String:
-			fmt.Fprintf(buf, "%s\n", f.String())
-		default:
-			panic("not reached")
-		}
+		fn(tag, f)
 	}
 }
 
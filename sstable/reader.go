@@ -48,17 +48,21 @@ type Reader struct {
 	Comparer             *base.Comparer
 
 	tableFilter *tableFilterReader
+	// hasValueFilter is true if the table has a value filter (see
+	// WriterOptions.ValueFilter), using the same policy as tableFilter.
+	hasValueFilter bool
 
 	err error
 
-	indexBH      block.Handle
-	filterBH     block.Handle
-	rangeDelBH   block.Handle
-	rangeKeyBH   block.Handle
-	valueBIH     valblk.IndexHandle
-	propertiesBH block.Handle
-	metaindexBH  block.Handle
-	footerBH     block.Handle
+	indexBH       block.Handle
+	filterBH      block.Handle
+	valueFilterBH block.Handle
+	rangeDelBH    block.Handle
+	rangeKeyBH    block.Handle
+	valueBIH      valblk.IndexHandle
+	propertiesBH  block.Handle
+	metaindexBH   block.Handle
+	footerBH      block.Handle
 
 	Properties  Properties
 	tableFormat TableFormat
@@ -83,8 +87,15 @@ type IterOptions struct {
 	Transforms           IterTransforms
 	Filterer             *BlockPropertiesFilterer
 	FilterBlockSizeLimit FilterBlockSizeLimit
-	Env                  block.ReadEnv
-	ReaderProvider       valblk.ReaderProvider
+	// SkipTombstoneOnlyPrefixes, if set, allows SeekPrefixGE to consult the
+	// table's value filter (see WriterOptions.ValueFilter), and to not return
+	// any keys with a prefix that has only point tombstones in the table. It
+	// must only be set when the caller doesn't need to observe the table's
+	// point tombstones, e.g. because there is no data beneath the table that
+	// they could delete.
+	SkipTombstoneOnlyPrefixes bool
+	Env                       block.ReadEnv
+	ReaderProvider            valblk.ReaderProvider
 }
 
 // NewPointIter returns an iterator for the point keys in the table.
@@ -298,6 +309,9 @@ func (r *Reader) readIndexBlock(
 	ctx context.Context, env block.ReadEnv, readHandle objstorage.ReadHandle, bh block.Handle,
 ) (block.BufferHandle, error) {
 	ctx = objiotracing.WithBlockType(ctx, objiotracing.MetadataBlock)
+	if env.IndexBlockPins != nil {
+		return env.IndexBlockPins.Read(ctx, env, &r.blockReader, readHandle, bh, r.initIndexBlockMetadata)
+	}
 	return r.blockReader.Read(ctx, env, readHandle, bh, r.initIndexBlockMetadata)
 }
 
@@ -457,6 +471,7 @@ func (r *Reader) readMetaindex(
 		if bh, ok := meta["fullfilter."+name]; ok {
 			r.filterBH = bh
 			r.tableFilter = newTableFilterReader(fp, r.filterMetricsTracker)
+			r.valueFilterBH, r.hasValueFilter = meta[valueFilterMetaPrefix+name]
 			break
 		}
 	}
@@ -482,6 +497,11 @@ func (r *Reader) Layout() (*Layout, error) {
 	if r.filterBH.Length > 0 {
 		l.Filter = []NamedBlockHandle{{Name: "fullfilter." + r.tableFilter.policy.Name(), Handle: r.filterBH}}
 	}
+	if r.hasValueFilter {
+		l.Filter = append(l.Filter, NamedBlockHandle{
+			Name: valueFilterMetaPrefix + r.tableFilter.policy.Name(), Handle: r.valueFilterBH,
+		})
+	}
 	ctx := context.TODO()
 
 	indexH, err := r.readTopLevelIndexBlock(ctx, block.NoReadEnv, noReadHandle)
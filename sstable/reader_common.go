@@ -63,6 +63,12 @@ type (
 	SyntheticSuffix = block.SyntheticSuffix
 	// SyntheticPrefix re-exports block.SyntheticPrefix.
 	SyntheticPrefix = block.SyntheticPrefix
+	// PrefixReplacement re-exports block.PrefixReplacement.
+	PrefixReplacement = block.PrefixReplacement
+	// SuffixReplacement re-exports block.SuffixReplacement.
+	SuffixReplacement = block.SuffixReplacement
+	// SuffixReplacements re-exports block.SuffixReplacements.
+	SuffixReplacements = block.SuffixReplacements
 	// SyntheticPrefixAndSuffix re-exports block.SyntheticPrefixAndSuffix.
 	SyntheticPrefixAndSuffix = block.SyntheticPrefixAndSuffix
 )
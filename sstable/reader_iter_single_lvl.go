@@ -165,8 +165,16 @@ type singleLevelIterator[I any, PI indexBlockIterator[I], D any, PD dataBlockIte
 	// present, should be used for prefix seeks or not. In some cases it is
 	// beneficial to skip a filter block even if it exists (eg. if probability of
 	// a match is high).
-	useFilterBlock         bool
+	useFilterBlock bool
+	// useValueFilter controls whether the value filter block in this sstable
+	// should be used for prefix seeks. See IterOptions.SkipTombstoneOnlyPrefixes.
+	useValueFilter         bool
 	lastBloomFilterMatched bool
+	// filterPositives is the number of filter checks that passed, used to
+	// sample them. sampleFilterPositive is set when the outcome of the lookup
+	// following the last filter check is sampled.
+	filterPositives      uint64
+	sampleFilterPositive bool
 
 	transforms IterTransforms
 
@@ -278,6 +286,7 @@ func (i *singleLevelIterator[I, PI, D, PD]) init(
 	i.upper = opts.Upper
 	i.bpfs = opts.Filterer
 	i.useFilterBlock = shouldUseFilterBlock(r, opts.FilterBlockSizeLimit)
+	i.useValueFilter = opts.SkipTombstoneOnlyPrefixes && r.hasValueFilter
 	i.reader = r
 	i.cmp = r.Comparer.Compare
 	i.transforms = opts.Transforms
@@ -291,6 +300,7 @@ func (i *singleLevelIterator[I, PI, D, PD]) init(
 		objstorage.ReadBeforeForIndexAndFilter, &i.indexFilterRHPrealloc)
 	i.dataRH = r.blockReader.UsePreallocatedReadHandle(
 		objstorage.NoReadBefore, &i.dataRHPrealloc)
+	objstorageprovider.SetReadaheadLevel(i.dataRH, opts.Env.LevelPlusOne)
 }
 
 // Helper function to check if keys returned from iterator are within virtual bounds.
@@ -804,7 +814,9 @@ func (i *singleLevelIterator[I, PI, D, PD]) SeekPrefixGE(
 			key = i.lower
 		}
 	}
-	return i.seekPrefixGE(prefix, key, flags)
+	kv := i.seekPrefixGE(prefix, key, flags)
+	i.maybeRecordFilterPositive(prefix, kv)
+	return kv
 }
 
 func (i *singleLevelIterator[I, PI, D, PD]) seekPrefixGE(
@@ -816,7 +828,7 @@ func (i *singleLevelIterator[I, PI, D, PD]) seekPrefixGE(
 
 	err := i.err
 	i.err = nil // clear cached iteration error
-	if i.useFilterBlock {
+	if i.useFilterBlock || i.useValueFilter {
 		if !i.lastBloomFilterMatched {
 			// Iterator is not positioned based on last seek.
 			flags = flags.DisableTrySeekUsingNext()
@@ -824,7 +836,7 @@ func (i *singleLevelIterator[I, PI, D, PD]) seekPrefixGE(
 		i.lastBloomFilterMatched = false
 		// Check prefix bloom filter.
 		var mayContain bool
-		mayContain, i.err = i.bloomFilterMayContain(prefix)
+		mayContain, i.err = i.bloomFilterMayContain(prefix, i.useFilterBlock, i.useValueFilter)
 		if i.err != nil || !mayContain {
 			// In the i.err == nil case, this invalidation may not be necessary for
 			// correctness, and may be a place to optimize later by reusing the
@@ -870,7 +882,12 @@ func shouldUseFilterBlock(reader *Reader, filterBlockSizeLimit FilterBlockSizeLi
 	return reader.tableFilter != nil && reader.filterBH.Length <= uint64(filterBlockSizeLimit)
 }
 
-func (i *singleLevelIterator[I, PI, D, PD]) bloomFilterMayContain(prefix []byte) (bool, error) {
+// bloomFilterMayContain checks the table filter (if useTableFilter is set) and
+// the value filter (if useValueFilter is set) for the given prefix. It returns
+// false if the prefix is excluded by any of the checked filters.
+func (i *singleLevelIterator[I, PI, D, PD]) bloomFilterMayContain(
+	prefix []byte, useTableFilter, useValueFilter bool,
+) (bool, error) {
 	// Check prefix bloom filter.
 	prefixToCheck := prefix
 	if i.transforms.HasSyntheticPrefix() {
@@ -883,12 +900,66 @@ func (i *singleLevelIterator[I, PI, D, PD]) bloomFilterMayContain(prefix []byte)
 		}
 	}
 
-	dataH, err := i.reader.readFilterBlock(i.ctx, i.readBlockEnv, i.indexFilterRH, i.reader.filterBH)
-	if err != nil {
-		return false, err
+	mayContain := true
+	if useTableFilter {
+		dataH, err := i.reader.readFilterBlock(i.ctx, i.readBlockEnv, i.indexFilterRH, i.reader.filterBH)
+		if err != nil {
+			return false, err
+		}
+		mayContain = i.reader.tableFilter.mayContain(dataH.BlockData(), prefixToCheck)
+		dataH.Release()
+	}
+	if mayContain && useValueFilter {
+		dataH, err := i.reader.readFilterBlock(i.ctx, i.readBlockEnv, i.indexFilterRH, i.reader.valueFilterBH)
+		if err != nil {
+			return false, err
+		}
+		mayContain = i.reader.tableFilter.policy.MayContain(TableFilter, dataH.BlockData(), prefixToCheck)
+		dataH.Release()
+	}
+	i.recordFilterCheck(mayContain)
+	return mayContain, nil
+}
+
+// filterPositiveSamplingInterval is the interval at which the filter checks
+// that pass are sampled to detect false positives: the first of every
+// filterPositiveSamplingInterval positives of an iterator is sampled.
+const filterPositiveSamplingInterval = 8
+
+// recordFilterCheck records the outcome of a filter check in the iterator
+// stats, and determines whether the outcome of the lookup that follows a
+// positive check is sampled.
+func (i *singleLevelIterator[I, PI, D, PD]) recordFilterCheck(mayContain bool) {
+	stats := i.readBlockEnv.Stats
+	if stats == nil {
+		return
+	}
+	stats.Filter.Consulted++
+	if !mayContain {
+		stats.Filter.Negatives++
+		return
+	}
+	i.sampleFilterPositive = i.filterPositives%filterPositiveSamplingInterval == 0
+	i.filterPositives++
+}
+
+// maybeRecordFilterPositive records whether the lookup that followed a sampled
+// positive filter check for the given prefix found a key with the prefix.
+func (i *singleLevelIterator[I, PI, D, PD]) maybeRecordFilterPositive(
+	prefix []byte, kv *base.InternalKV,
+) {
+	if !i.sampleFilterPositive {
+		return
+	}
+	i.sampleFilterPositive = false
+	if i.err != nil {
+		return
+	}
+	stats := &i.readBlockEnv.Stats.Filter
+	stats.SampledPositives++
+	if kv == nil || !i.reader.Comparer.Equal(prefix, kv.K.UserKey[:i.reader.Comparer.Split(kv.K.UserKey)]) {
+		stats.SampledFalsePositives++
 	}
-	defer dataH.Release()
-	return i.reader.tableFilter.mayContain(dataH.BlockData(), prefixToCheck), nil
 }
 
 // virtualLast should only be called if i.vReader != nil.
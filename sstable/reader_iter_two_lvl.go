@@ -31,7 +31,10 @@ type twoLevelIterator[I any, PI indexBlockIterator[I], D any, PD dataBlockIterat
 	// useFilterBlock controls whether we consult the bloom filter in the
 	// twoLevelIterator code. Note that secondLevel.useFilterBlock is always
 	// false - any filtering happens at the top level.
-	useFilterBlock         bool
+	useFilterBlock bool
+	// useValueFilter is the value filter counterpart of useFilterBlock; see
+	// IterOptions.SkipTombstoneOnlyPrefixes.
+	useValueFilter         bool
 	lastBloomFilterMatched bool
 }
 
@@ -164,8 +167,8 @@ func newColumnBlockTwoLevelIterator(
 	i := twoLevelIterColumnBlockPool.Get().(*twoLevelIteratorColumnBlocks)
 	i.secondLevel.init(ctx, r, v, opts)
 	// Only check the bloom filter at the top level.
-	i.useFilterBlock = i.secondLevel.useFilterBlock
-	i.secondLevel.useFilterBlock = false
+	i.useFilterBlock, i.useValueFilter = i.secondLevel.useFilterBlock, i.secondLevel.useValueFilter
+	i.secondLevel.useFilterBlock, i.secondLevel.useValueFilter = false, false
 
 	var getInternalValuer block.GetInternalValueForPrefixAndValueHandler
 	if r.Properties.NumValueBlocks > 0 {
@@ -214,8 +217,8 @@ func newRowBlockTwoLevelIterator(
 	i := twoLevelIterRowBlockPool.Get().(*twoLevelIteratorRowBlocks)
 	i.secondLevel.init(ctx, r, v, opts)
 	// Only check the bloom filter at the top level.
-	i.useFilterBlock = i.secondLevel.useFilterBlock
-	i.secondLevel.useFilterBlock = false
+	i.useFilterBlock, i.useValueFilter = i.secondLevel.useFilterBlock, i.secondLevel.useValueFilter
+	i.secondLevel.useFilterBlock, i.secondLevel.useValueFilter = false, false
 	if r.tableFormat >= TableFormatPebblev3 {
 		if r.Properties.NumValueBlocks > 0 {
 			// NB: we cannot avoid this ~248 byte allocation, since valueBlockReader
@@ -409,6 +412,14 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekPrefixGE(
 			key = i.secondLevel.lower
 		}
 	}
+	kv := i.seekPrefixGE(prefix, key, flags)
+	i.secondLevel.maybeRecordFilterPositive(prefix, kv)
+	return kv
+}
+
+func (i *twoLevelIterator[I, PI, D, PD]) seekPrefixGE(
+	prefix, key []byte, flags base.SeekGEFlags,
+) *base.InternalKV {
 
 	// NOTE: prefix is only used for bloom filter checking and not later work in
 	// this method. Hence, we can use the existing iterator position if the last
@@ -420,7 +431,7 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekPrefixGE(
 	// The twoLevelIterator could be already exhausted. Utilize that when
 	// trySeekUsingNext is true. See the comment about data-exhausted, PGDE, and
 	// bounds-exhausted near the top of the file.
-	filterUsedAndDidNotMatch := i.useFilterBlock && !i.lastBloomFilterMatched
+	filterUsedAndDidNotMatch := (i.useFilterBlock || i.useValueFilter) && !i.lastBloomFilterMatched
 	if flags.TrySeekUsingNext() && !filterUsedAndDidNotMatch &&
 		(i.secondLevel.exhaustedBounds == +1 || (PD(&i.secondLevel.data).IsDataInvalidated() && PI(&i.secondLevel.index).IsDataInvalidated())) &&
 		err == nil {
@@ -429,14 +440,14 @@ func (i *twoLevelIterator[I, PI, D, PD]) SeekPrefixGE(
 	}
 
 	// Check prefix bloom filter.
-	if i.useFilterBlock {
+	if i.useFilterBlock || i.useValueFilter {
 		if !i.lastBloomFilterMatched {
 			// Iterator is not positioned based on last seek.
 			flags = flags.DisableTrySeekUsingNext()
 		}
 		i.lastBloomFilterMatched = false
 		var mayContain bool
-		mayContain, i.secondLevel.err = i.secondLevel.bloomFilterMayContain(prefix)
+		mayContain, i.secondLevel.err = i.secondLevel.bloomFilterMayContain(prefix, i.useFilterBlock, i.useValueFilter)
 		if i.secondLevel.err != nil || !mayContain {
 			// In the i.secondLevel.err == nil case, this invalidation may not be necessary for
 			// correctness, and may be a place to optimize later by reusing the
@@ -1018,6 +1029,7 @@ func (i *twoLevelIterator[I, PI, D, PD]) Close() error {
 	i.secondLevel.resetForReuse()
 	err = firstError(err, PI(&i.topLevelIndex).Close())
 	i.useFilterBlock = false
+	i.useValueFilter = false
 	i.lastBloomFilterMatched = false
 	if pool != nil {
 		pool.Put(i)
@@ -2605,3 +2605,60 @@ func TestReaderReportsCorruption(t *testing.T) {
 	require.ErrorContains(t, lastReportedCorruption, "in-mem remote storage object does not exist")
 	require.True(t, base.IsCorruptionError(lastReportedCorruption))
 }
+
+func TestReaderValueFilter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	for _, tf := range []TableFormat{TableFormatPebblev4, TableFormatMax} {
+		t.Run(tf.String(), func(t *testing.T) {
+			fs := vfs.NewMem()
+			f, err := fs.Create("test", vfs.WriteCategoryUnspecified)
+			require.NoError(t, err)
+
+			opts := WriterOptions{
+				Comparer:     testkeys.Comparer,
+				KeySchema:    &testkeysSchema,
+				TableFormat:  tf,
+				FilterPolicy: bloom.FilterPolicy(100),
+				FilterType:   base.TableFilter,
+				ValueFilter:  true,
+			}
+			w := NewRawWriter(objstorageprovider.NewFileWritable(f), opts)
+			require.NoError(t, w.Add(base.MakeInternalKey([]byte("a@1"), 3, InternalKeyKindSet), []byte("a"), false))
+			require.NoError(t, w.Add(base.MakeInternalKey([]byte("b@2"), 2, InternalKeyKindDelete), nil, false))
+			require.NoError(t, w.Add(base.MakeInternalKey([]byte("c@1"), 1, InternalKeyKindSet), []byte("c"), false))
+			require.NoError(t, w.Close())
+
+			f, err = fs.Open("test")
+			require.NoError(t, err)
+			r, err := newReader(f, ReaderOptions{
+				Comparer:   opts.Comparer,
+				KeySchemas: MakeKeySchemas(opts.KeySchema),
+				Filters:    map[string]FilterPolicy{opts.FilterPolicy.Name(): opts.FilterPolicy},
+			})
+			require.NoError(t, err)
+			defer r.Close()
+			require.True(t, r.hasValueFilter)
+
+			for _, skip := range []bool{false, true} {
+				iter, err := r.NewPointIter(context.Background(), IterOptions{
+					FilterBlockSizeLimit:      AlwaysUseFilterBlock,
+					SkipTombstoneOnlyPrefixes: skip,
+					Env:                       block.NoReadEnv,
+					ReaderProvider:            MakeTrivialReaderProvider(r),
+				})
+				require.NoError(t, err)
+				kv := iter.SeekPrefixGE([]byte("b"), []byte("b@2"), base.SeekGEFlagsNone)
+				if skip {
+					require.Nil(t, kv)
+				} else {
+					require.NotNil(t, kv)
+					require.Equal(t, InternalKeyKindDelete, kv.Kind())
+				}
+				kv = iter.SeekPrefixGE([]byte("c"), []byte("c@1"), base.SeekGEFlagsNone)
+				require.NotNil(t, kv)
+				require.Equal(t, "c@1", string(kv.K.UserKey))
+				require.NoError(t, iter.Close())
+			}
+		})
+	}
+}
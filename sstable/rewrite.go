@@ -0,0 +1,204 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// RewriteOptions configures Rewrite. The zero value of each field keeps the
+// corresponding setting of the input sstable, where the sstable records it.
+type RewriteOptions struct {
+	// ReaderOptions are used to open the input sstable. They must know the
+	// input's comparer, merger and key schema, and its filter policy for the
+	// filter to be kept.
+	ReaderOptions ReaderOptions
+	// Compression is the compression of the output's blocks. The input's
+	// compression is kept if it's DefaultCompression.
+	Compression block.Compression
+	// FilterPolicy is the filter policy of the output. The input's filter is
+	// rebuilt if it's nil and DropFilter is unset; the output has no filter if
+	// the input's filter policy is unknown to ReaderOptions.Filters.
+	FilterPolicy FilterPolicy
+	// DropFilter, if set, removes the filter from the output.
+	DropFilter bool
+	// TableFormat is the format of the output. The input's format is kept if
+	// it's TableFormatUnspecified.
+	TableFormat TableFormat
+	// BlockSize is the target size of the output's data blocks, and
+	// IndexBlockSize that of its index blocks. They default to the writer's
+	// defaults: the input doesn't record its block sizes.
+	BlockSize      int
+	IndexBlockSize int
+	// BlockPropertyCollectors recompute the block properties of the output.
+	// The properties of the input's block property collectors are not carried
+	// over, since they describe the input's blocks: the collectors used to
+	// write the input must be configured to keep its block properties.
+	BlockPropertyCollectors []func() BlockPropertyCollector
+}
+
+// Rewrite rewrites the sstable read from input to output with a different
+// compression, filter, table format or block size, without a DB. Unlike
+// CopySpan, every key is rewritten through a Writer, so that the blocks,
+// filters, index and properties are rebuilt for the new settings.
+//
+// The keys, including range deletions and range keys, and their sequence
+// numbers are preserved. The properties derived from the contents are
+// recomputed; the user properties of the input that aren't block properties
+// (see RewriteOptions.BlockPropertyCollectors) are carried over. Like
+// RewriteKeySuffixesViaWriter, the obsolete bits of the keys are lost, and the
+// output has the pebble.obsolete.is_strict property set to false.
+//
+// Sstables that reference values in blob files can't be rewritten.
+//
+// Closes input and finishes or aborts output in all cases, including on errors.
+func Rewrite(
+	ctx context.Context, input objstorage.Readable, output objstorage.Writable, o RewriteOptions,
+) (_ *WriterMetadata, err error) {
+	r, err := NewReader(ctx, input, o.ReaderOptions)
+	if err != nil {
+		output.Abort()
+		return nil, err
+	}
+	defer r.Close()
+
+	wo := inputWriterOptions(r)
+	wo.BlockSize = o.BlockSize
+	wo.IndexBlockSize = o.IndexBlockSize
+	wo.BlockPropertyCollectors = o.BlockPropertyCollectors
+	if o.Compression != block.DefaultCompression {
+		wo.Compression = o.Compression
+	}
+	if o.FilterPolicy != nil {
+		wo.FilterPolicy = o.FilterPolicy
+		wo.ValueFilter = false
+	}
+	if o.DropFilter {
+		wo.FilterPolicy = nil
+	}
+	if o.TableFormat != TableFormatUnspecified {
+		wo.TableFormat = o.TableFormat
+	}
+	if !wo.TableFormat.BlockColumnar() || !r.tableFormat.BlockColumnar() {
+		wo.KeySchema = nil
+	}
+
+	w := NewRawWriter(output, wo)
+	defer func() {
+		if w != nil {
+			// Abort output instead of finishing a truncated sstable.
+			w.setError(err)
+			_ = w.Close()
+		}
+	}()
+	if err := rewritePointKeys(r, w); err != nil {
+		return nil, err
+	}
+	rangeDelIter, err := r.NewRawRangeDelIter(ctx, NoFragmentTransforms, block.NoReadEnv)
+	if err != nil {
+		return nil, err
+	}
+	if err := rewriteSpans(rangeDelIter, w); err != nil {
+		return nil, err
+	}
+	rangeKeyIter, err := r.NewRawRangeKeyIter(ctx, NoFragmentTransforms, block.NoReadEnv)
+	if err != nil {
+		return nil, err
+	}
+	if err := rewriteSpans(rangeKeyIter, w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		w = nil
+		return nil, err
+	}
+	meta, err := w.Metadata()
+	w = nil
+	return meta, err
+}
+
+// inputWriterOptions returns the options to write an sstable with the same
+// settings as the sstable read by r, where the sstable records them.
+func inputWriterOptions(r *Reader) WriterOptions {
+	wo := WriterOptions{
+		Comparer:                r.Comparer,
+		Compression:             block.CompressionFromString(r.Properties.CompressionName),
+		MergerName:              r.Properties.MergerName,
+		TableFormat:             r.tableFormat,
+		inheritedUserProperties: inheritedUserProperties(&r.Properties),
+	}
+	if r.tableFilter != nil {
+		wo.FilterPolicy = r.tableFilter.policy
+		wo.ValueFilter = r.hasValueFilter
+	}
+	if r.tableFormat.BlockColumnar() {
+		wo.KeySchema = r.keySchema
+	}
+	return wo
+}
+
+func rewritePointKeys(r *Reader, w RawWriter) error {
+	iter, err := r.NewIter(NoTransforms, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	var buf []byte
+	for kv := iter.First(); kv != nil; kv = iter.Next() {
+		if kv.V.IsBlobValueHandle() {
+			return errors.New("pebble: sstables referencing blob files can't be rewritten")
+		}
+		val, callerOwned, err := kv.Value(buf)
+		if err != nil {
+			return err
+		}
+		if callerOwned {
+			buf = val[:0]
+		}
+		if err := w.Add(kv.K, val, false /* forceObsolete */); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// rewriteSpans adds the spans of the iterator, which may be nil, to the writer,
+// and closes the iterator.
+func rewriteSpans(iter keyspan.FragmentIterator, w RawWriter) error {
+	if iter == nil {
+		return nil
+	}
+	defer iter.Close()
+	s, err := iter.First()
+	for ; s != nil; s, err = iter.Next() {
+		if err := w.EncodeSpan(*s); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// inheritedUserProperties returns the user properties of the sstable that
+// aren't the table-level properties of block property collectors.
+func inheritedUserProperties(props *Properties) map[string]string {
+	collectors := make(map[string]struct{})
+	names := strings.TrimSuffix(strings.TrimPrefix(props.PropertyCollectorNames, "["), "]")
+	for _, name := range strings.Split(names, ",") {
+		collectors[name] = struct{}{}
+	}
+	inherited := make(map[string]string)
+	for name, prop := range props.UserProperties {
+		if _, ok := collectors[name]; !ok {
+			inherited[name] = prop
+		}
+	}
+	return inherited
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/sstable/colblk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrite(t *testing.T) {
+	f := &objstorage.MemObj{}
+	w := NewWriter(f, WriterOptions{
+		Comparer:                testkeys.Comparer,
+		Compression:             block.SnappyCompression,
+		FilterPolicy:            bloom.FilterPolicy(10),
+		TableFormat:             TableFormatPebblev3,
+		BlockSize:               100,
+		BlockPropertyCollectors: []func() BlockPropertyCollector{NewTestKeysBlockPropertyCollector},
+	})
+	for i := 0; i < 100; i++ {
+		require.NoError(t, w.Raw().Add(base.MakeInternalKey([]byte(fmt.Sprintf("a%03d@%d", i, i%10)), base.SeqNum(i+1), InternalKeyKindSet), []byte(fmt.Sprint(i)), false))
+	}
+	require.NoError(t, w.Raw().Add(base.MakeInternalKey([]byte("b"), 200, InternalKeyKindDelete), nil, false))
+	require.NoError(t, w.DeleteRange([]byte("c"), []byte("d")))
+	require.NoError(t, w.RangeKeySet([]byte("e"), []byte("f"), []byte("@5"), []byte("v")))
+	require.NoError(t, w.Close())
+	sst := f.Data()
+
+	filters := map[string]FilterPolicy{bloom.FilterPolicy(10).Name(): bloom.FilterPolicy(10)}
+	keySchema := colblk.DefaultKeySchema(testkeys.Comparer, 16)
+	readerOpts := ReaderOptions{
+		Comparer:   testkeys.Comparer,
+		KeySchemas: MakeKeySchemas(&keySchema),
+		Filters:    filters,
+	}
+	contents := func(sst []byte) string {
+		r, err := NewMemReader(sst, readerOpts)
+		require.NoError(t, err)
+		defer r.Close()
+		var s string
+		iter, err := r.NewIter(NoTransforms, nil, nil)
+		require.NoError(t, err)
+		for kv := iter.First(); kv != nil; kv = iter.Next() {
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			s += fmt.Sprintf("%s=%s\n", kv.K, v)
+		}
+		require.NoError(t, iter.Close())
+		for _, newIter := range []func(context.Context, FragmentIterTransforms, block.ReadEnv) (keyspan.FragmentIterator, error){
+			r.NewRawRangeDelIter, r.NewRawRangeKeyIter,
+		} {
+			iter, err := newIter(context.Background(), NoFragmentTransforms, block.NoReadEnv)
+			require.NoError(t, err)
+			span, err := iter.First()
+			for ; span != nil; span, err = iter.Next() {
+				s += span.String() + "\n"
+			}
+			require.NoError(t, err)
+			iter.Close()
+		}
+		return s
+	}
+	rewrite := func(o RewriteOptions) (*Reader, []byte) {
+		o.ReaderOptions = readerOpts
+		out := &objstorage.MemObj{}
+		_, err := Rewrite(context.Background(), newMemReader(sst), out, o)
+		require.NoError(t, err)
+		r, err := NewMemReader(out.Data(), readerOpts)
+		require.NoError(t, err)
+		return r, out.Data()
+	}
+
+	// By default, the settings of the input are kept.
+	r, rewritten := rewrite(RewriteOptions{})
+	require.Equal(t, contents(sst), contents(rewritten))
+	require.Equal(t, TableFormatPebblev3, r.tableFormat)
+	require.Equal(t, "Snappy", r.Properties.CompressionName)
+	require.Equal(t, bloom.FilterPolicy(10).Name(), r.Properties.FilterPolicyName)
+	// The block properties aren't carried over without their collector.
+	require.NotContains(t, r.Properties.UserProperties, testKeysBlockPropertyName)
+	require.NoError(t, r.Close())
+
+	r, rewritten = rewrite(RewriteOptions{
+		Compression:             block.ZstdCompression,
+		DropFilter:              true,
+		TableFormat:             TableFormatPebblev5,
+		BlockSize:               4096,
+		BlockPropertyCollectors: []func() BlockPropertyCollector{NewTestKeysBlockPropertyCollector},
+	})
+	require.Equal(t, contents(sst), contents(rewritten))
+	require.Equal(t, TableFormatPebblev5, r.tableFormat)
+	require.Equal(t, "ZSTD", r.Properties.CompressionName)
+	require.Empty(t, r.Properties.FilterPolicyName)
+	require.Contains(t, r.Properties.UserProperties, testKeysBlockPropertyName)
+	require.Less(t, r.Properties.NumDataBlocks, uint64(10))
+	require.NoError(t, r.Close())
+
+	// An error reading the input aborts the output rather than finishing it
+	// with the keys rewritten before the error.
+	out := &objstorage.MemObj{}
+	_, err := Rewrite(context.Background(), &failingReadable{Readable: newMemReader(sst), failFrom: 500, failTo: 600}, out, RewriteOptions{
+		ReaderOptions: readerOpts,
+	})
+	require.ErrorContains(t, err, "injected")
+	require.Empty(t, out.Data())
+}
+
+// failingReadable fails the reads starting in [failFrom, failTo).
+type failingReadable struct {
+	objstorage.Readable
+	failFrom, failTo int64
+}
+
+func (r *failingReadable) ReadAt(ctx context.Context, p []byte, off int64) error {
+	if off >= r.failFrom && off < r.failTo {
+		return errors.New("injected read error")
+	}
+	return r.Readable.ReadAt(ctx, p, off)
+}
+
+func (r *failingReadable) NewReadHandle(objstorage.ReadBeforeSize) objstorage.ReadHandle {
+	rh := objstorage.MakeNoopReadHandle(r)
+	return &rh
+}
+
+func TestInheritedUserProperties(t *testing.T) {
+	props := &Properties{
+		PropertyCollectorNames: "[obsolete-key,suffixes]",
+		UserProperties: map[string]string{
+			"obsolete-key": "\x00",
+			"suffixes":     "\x01xyz",
+			"app.version":  "3",
+		},
+	}
+	require.Equal(t, map[string]string{"app.version": "3"}, inheritedUserProperties(props))
+}
@@ -61,6 +61,9 @@ type RawRowWriter struct {
 	// sstable in order. It is intended for internal use only in the construction
 	// of invalid sstables for testing. See tool/make_test_sstables.go.
 	disableKeyOrderChecks bool
+	// inheritedUserProperties are copied from Options; see
+	// WriterOptions.inheritedUserProperties.
+	inheritedUserProperties map[string]string
 	// With two level indexes, the index/filter of a SST file is partitioned into
 	// smaller blocks with an additional top-level index on them. When reading an
 	// index/filter, only the top-level index is loaded into memory. The two level
@@ -88,7 +91,12 @@ type RawRowWriter struct {
 	// filter accumulates the filter block. If populated, the filter ingests
 	// either the output of w.split (i.e. a prefix extractor) if w.split is not
 	// nil, or the full keys otherwise.
-	filter          filterWriter
+	filter filterWriter
+
+	// valueFilter accumulates the value filter block, if enabled. See
+	// WriterOptions.ValueFilter.
+	valueFilter *valueFilterWriter
+
 	indexPartitions []bufferedIndexBlock
 
 	// indexBlockAlloc is used to bulk-allocate byte slices used to store index
@@ -763,7 +771,7 @@ func (w *RawRowWriter) addPoint(key InternalKey, value []byte, forceObsolete boo
 		w.obsoleteCollector.AddPoint(isObsolete)
 	}
 
-	w.maybeAddToFilter(key.UserKey)
+	w.maybeAddToFilter(key)
 	w.dataBlockBuf.dataBlock.AddWithOptionalValuePrefix(
 		key, isObsolete, valueStoredWithKey, maxSharedKeyLen, addPrefixToValueStoredWithKey, prefix,
 		setHasSameKeyPrefix)
@@ -972,10 +980,13 @@ func (w *RawRowWriter) addRangeKey(key InternalKey, value []byte) error {
 	return nil
 }
 
-func (w *RawRowWriter) maybeAddToFilter(key []byte) {
+func (w *RawRowWriter) maybeAddToFilter(key InternalKey) {
 	if w.filter != nil {
-		prefix := key[:w.split(key)]
+		prefix := key.UserKey[:w.split(key.UserKey)]
 		w.filter.addKey(prefix)
+		if w.valueFilter != nil {
+			w.valueFilter.addPoint(key.Kind(), prefix)
+		}
 	}
 }
 
@@ -1503,6 +1514,11 @@ func (w *RawRowWriter) Close() (err error) {
 		w.props.FilterPolicyName = w.filter.policyName()
 		w.props.FilterSize = bh.Length
 	}
+	if w.valueFilter != nil && w.valueFilter.shouldWrite() {
+		if _, err := w.layout.WriteFilterBlock(w.valueFilter); err != nil {
+			return err
+		}
+	}
 
 	if w.twoLevelIndex {
 		w.props.IndexType = twoLevelIndex
@@ -1589,6 +1605,11 @@ func (w *RawRowWriter) Close() (err error) {
 				// that the block property collector was used when writing.
 				userProps[w.blockPropCollectors[i].Name()] = prop
 			}
+			for name, prop := range w.inheritedUserProperties {
+				if _, ok := userProps[name]; !ok {
+					userProps[name] = prop
+				}
+			}
 			if len(userProps) > 0 {
 				w.props.UserProperties = userProps
 			}
@@ -1676,6 +1697,7 @@ func newRowWriter(writable objstorage.Writable, o WriterOptions) *RawRowWriter {
 		restartInterval:            o.BlockRestartInterval,
 		checksumType:               o.Checksum,
 		disableKeyOrderChecks:      o.internal.DisableKeyOrderChecks,
+		inheritedUserProperties:    o.inheritedUserProperties,
 		indexBlock:                 newIndexBlockBuf(),
 		rangeDelBlock:              rowblk.Writer{RestartInterval: 1},
 		rangeKeyBlock:              rowblk.Writer{RestartInterval: 1},
@@ -1723,6 +1745,9 @@ func newRowWriter(writable objstorage.Writable, o WriterOptions) *RawRowWriter {
 		switch o.FilterType {
 		case TableFilter:
 			w.filter = newTableFilterWriter(o.FilterPolicy)
+			if o.ValueFilter {
+				w.valueFilter = newValueFilterWriter(o.FilterPolicy)
+			}
 		default:
 			panic(fmt.Sprintf("unknown filter type: %v", o.FilterType))
 		}
@@ -1773,6 +1798,9 @@ func newRowWriter(writable objstorage.Writable, o WriterOptions) *RawRowWriter {
 func (w *RawRowWriter) rewriteSuffixes(
 	r *Reader, sst []byte, wo WriterOptions, from, to []byte, concurrency int,
 ) error {
+	// Keys that don't go through the writer's key path aren't reflected in
+	// the value filter, so it must not be written.
+	w.valueFilter = nil
 	for _, c := range w.blockPropCollectors {
 		if !c.SupportsSuffixReplacement() {
 			return errors.Errorf("block property collector %s does not support suffix replacement", c.Name())
@@ -1876,6 +1904,7 @@ func (w *RawRowWriter) rewriteSuffixes(
 func (w *RawRowWriter) copyDataBlocks(
 	ctx context.Context, blocks []indexEntry, rh objstorage.ReadHandle,
 ) error {
+	w.valueFilter = nil // See rewriteSuffixes.
 	blockOffset := blocks[0].bh.Offset
 	// The block lengths don't include their trailers, which just sit after the
 	// block length, before the next offset; We get the ones between the blocks
@@ -1903,6 +1932,7 @@ func (w *RawRowWriter) copyDataBlocks(
 
 // addDataBlock implements RawWriter.
 func (w *RawRowWriter) addDataBlock(b, sep []byte, bhp block.HandleWithProperties) error {
+	w.valueFilter = nil // See rewriteSuffixes.
 	blockBuf := &w.dataBlockBuf.blockBuf
 	pb := block.CompressAndChecksum(
 		&blockBuf.dataBuf,
@@ -1940,8 +1970,14 @@ func (w *RawRowWriter) copyProperties(props Properties) {
 	w.props.IndexType = 0
 }
 
+// setError implements RawWriter.
+func (w *RawRowWriter) setError(err error) {
+	w.err = firstError(w.err, err)
+}
+
 // copyFilter implements RawWriter.
 func (w *RawRowWriter) copyFilter(filter []byte, filterName string) error {
+	w.valueFilter = nil // See rewriteSuffixes.
 	if w.filter != nil && filterName != w.filter.policyName() {
 		return errors.New("mismatched filters")
 	}
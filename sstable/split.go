@@ -0,0 +1,286 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/block"
+)
+
+// SplitTableOptions configures SplitTable.
+type SplitTableOptions struct {
+	// ReaderOptions are used to open the input sstable. They must know the
+	// input's comparer, merger and key schema, and its filter policy for the
+	// outputs to have a filter.
+	ReaderOptions ReaderOptions
+	// SplitKeys are the user keys at which the input is split, in increasing
+	// order: the keys before SplitKeys[0] go to the first output, the keys in
+	// [SplitKeys[i-1], SplitKeys[i]) to the next one, and so on.
+	SplitKeys [][]byte
+	// TargetFileSize, if positive, additionally splits the input so that the
+	// outputs are about TargetFileSize bytes large. The outputs are only split
+	// between keys with different prefixes (see Comparer.Split), so that all
+	// the versions of a key are in the same output.
+	TargetFileSize int64
+	// BlockPropertyCollectors recompute the block properties of the outputs
+	// (see RewriteOptions.BlockPropertyCollectors).
+	BlockPropertyCollectors []func() BlockPropertyCollector
+	// NewOutput is called to create the writable of the i-th output.
+	NewOutput func(i int) (objstorage.Writable, error)
+}
+
+// SplitTable splits the sstable read from input into multiple smaller sstables,
+// without a DB. The outputs have the settings of the input (format,
+// compression, filter), their key ranges don't overlap, and they're returned in
+// key order, so that they can be ingested in place of the input. An output is
+// only created if it's not empty.
+//
+// The keys and their sequence numbers are preserved. The range deletions and
+// range keys that span a split are truncated to the split, with a fragment in
+// each output on either side. Like Rewrite, the obsolete bits of the keys are
+// lost, and the user properties of the input that aren't block properties are
+// carried over to each output.
+//
+// Sstables that reference values in blob files can't be split.
+//
+// Closes input in all cases. Finishes or aborts the outputs, including on
+// errors, in which case the outputs created so far must be discarded.
+func SplitTable(
+	ctx context.Context, input objstorage.Readable, o SplitTableOptions,
+) (_ []*WriterMetadata, err error) {
+	r, err := NewReader(ctx, input, o.ReaderOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	for i := 1; i < len(o.SplitKeys); i++ {
+		if r.Comparer.Compare(o.SplitKeys[i-1], o.SplitKeys[i]) >= 0 {
+			return nil, errors.New("pebble: split keys are not sorted")
+		}
+	}
+
+	s := splitter{
+		r:  r,
+		o:  o,
+		wo: inputWriterOptions(r),
+	}
+	s.wo.BlockPropertyCollectors = o.BlockPropertyCollectors
+	defer func() {
+		if s.w != nil {
+			// Abort the output instead of finishing a truncated sstable.
+			s.w.setError(err)
+			_ = s.w.Close()
+		}
+	}()
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+	defer s.close()
+	return s.split()
+}
+
+// splitter holds the state of a SplitTable call.
+type splitter struct {
+	r  *Reader
+	o  SplitTableOptions
+	wo WriterOptions
+	// pointIter, rangeDels and rangeKeys iterate over the input.
+	pointIter Iterator
+	kv        *base.InternalKV
+	rangeDels splitSpans
+	rangeKeys splitSpans
+	valueBuf  []byte
+	// w is the writer of the current output, if it has been created.
+	w     RawWriter
+	metas []*WriterMetadata
+}
+
+func (s *splitter) init(ctx context.Context) error {
+	var err error
+	if s.pointIter, err = s.r.NewIter(NoTransforms, nil /* lower */, nil /* upper */); err != nil {
+		return err
+	}
+	s.kv = s.pointIter.First()
+	if s.rangeDels.iter, err = s.r.NewRawRangeDelIter(ctx, NoFragmentTransforms, block.NoReadEnv); err != nil {
+		return err
+	}
+	if s.rangeKeys.iter, err = s.r.NewRawRangeKeyIter(ctx, NoFragmentTransforms, block.NoReadEnv); err != nil {
+		return err
+	}
+	if err := s.rangeDels.first(); err != nil {
+		return err
+	}
+	return s.rangeKeys.first()
+}
+
+func (s *splitter) close() {
+	if s.pointIter != nil {
+		_ = s.pointIter.Close()
+	}
+	s.rangeDels.close()
+	s.rangeKeys.close()
+}
+
+func (s *splitter) split() ([]*WriterMetadata, error) {
+	cmp := s.r.Comparer.Compare
+	splitKeys := s.o.SplitKeys
+	// Each iteration writes an output with the keys in [lower, upper), where a
+	// nil bound is unbounded.
+	var lower []byte
+	for {
+		var upper []byte
+		for len(splitKeys) > 0 && lower != nil && cmp(splitKeys[0], lower) <= 0 {
+			splitKeys = splitKeys[1:]
+		}
+		if len(splitKeys) > 0 {
+			upper = splitKeys[0]
+		}
+		var err error
+		if upper, err = s.writePointKeys(upper); err != nil {
+			return nil, err
+		}
+		if err := s.rangeDels.writeUntil(s, lower, upper); err != nil {
+			return nil, err
+		}
+		if err := s.rangeKeys.writeUntil(s, lower, upper); err != nil {
+			return nil, err
+		}
+		if err := s.finishOutput(); err != nil {
+			return nil, err
+		}
+		if upper == nil || (s.kv == nil && s.rangeDels.span == nil && s.rangeKeys.span == nil) {
+			return s.metas, nil
+		}
+		lower = upper
+	}
+}
+
+// writePointKeys writes the point keys before upper to the current output. If
+// the output reaches the target file size first, it stops at the next key
+// with a different prefix, and returns that key as the new upper bound.
+func (s *splitter) writePointKeys(upper []byte) ([]byte, error) {
+	var prevPrefix []byte
+	for ; s.kv != nil; s.kv = s.pointIter.Next() {
+		if upper != nil && s.r.Comparer.Compare(s.kv.K.UserKey, upper) >= 0 {
+			return upper, nil
+		}
+		prefix := s.kv.K.UserKey[:s.r.Comparer.Split(s.kv.K.UserKey)]
+		if s.o.TargetFileSize > 0 && s.w != nil && s.w.EstimatedSize() >= uint64(s.o.TargetFileSize) &&
+			!s.r.Comparer.Equal(prevPrefix, prefix) {
+			return append([]byte(nil), s.kv.K.UserKey...), nil
+		}
+		prevPrefix = append(prevPrefix[:0], prefix...)
+
+		if s.kv.V.IsBlobValueHandle() {
+			return nil, errors.New("pebble: sstables referencing blob files can't be split")
+		}
+		val, callerOwned, err := s.kv.Value(s.valueBuf)
+		if err != nil {
+			return nil, err
+		}
+		if callerOwned {
+			s.valueBuf = val[:0]
+		}
+		if err := s.ensureOutput(); err != nil {
+			return nil, err
+		}
+		if err := s.w.Add(s.kv.K, val, false /* forceObsolete */); err != nil {
+			return nil, err
+		}
+	}
+	return upper, s.pointIter.Error()
+}
+
+// ensureOutput creates the writer of the current output if necessary.
+func (s *splitter) ensureOutput() error {
+	if s.w != nil {
+		return nil
+	}
+	writable, err := s.o.NewOutput(len(s.metas))
+	if err != nil {
+		return err
+	}
+	s.w = NewRawWriter(writable, s.wo)
+	return nil
+}
+
+// finishOutput closes the writer of the current output, if any.
+func (s *splitter) finishOutput() error {
+	if s.w == nil {
+		return nil
+	}
+	w := s.w
+	s.w = nil
+	if err := w.Close(); err != nil {
+		return err
+	}
+	meta, err := w.Metadata()
+	if err != nil {
+		return err
+	}
+	s.metas = append(s.metas, meta)
+	return nil
+}
+
+// splitSpans iterates over the range deletions or the range keys of the
+// input of a SplitTable call.
+type splitSpans struct {
+	iter keyspan.FragmentIterator
+	// span is the current span, or nil if the iterator is exhausted. Its start
+	// may precede the lower bound of the current output, if the span was
+	// truncated to the end of the previous output.
+	span *keyspan.Span
+}
+
+func (ss *splitSpans) first() error {
+	if ss.iter == nil {
+		return nil
+	}
+	var err error
+	ss.span, err = ss.iter.First()
+	return err
+}
+
+func (ss *splitSpans) close() {
+	if ss.iter != nil {
+		ss.iter.Close()
+	}
+}
+
+// writeUntil writes the spans before upper, truncated to [lower, upper), to
+// the current output.
+func (ss *splitSpans) writeUntil(s *splitter, lower, upper []byte) error {
+	cmp := s.r.Comparer.Compare
+	for ss.span != nil && (upper == nil || cmp(ss.span.Start, upper) < 0) {
+		span := *ss.span
+		if lower != nil && cmp(span.Start, lower) < 0 {
+			span.Start = lower
+		}
+		truncated := upper != nil && cmp(span.End, upper) > 0
+		if truncated {
+			span.End = upper
+		}
+		if err := s.ensureOutput(); err != nil {
+			return err
+		}
+		if err := s.w.EncodeSpan(span); err != nil {
+			return err
+		}
+		if truncated {
+			// The rest of the span belongs to the next output.
+			return nil
+		}
+		var err error
+		if ss.span, err = ss.iter.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package sstable
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/sstable/colblk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitTable(t *testing.T) {
+	f := &objstorage.MemObj{}
+	w := NewWriter(f, WriterOptions{
+		Comparer:    testkeys.Comparer,
+		TableFormat: TableFormatPebblev5,
+		BlockSize:   100,
+	})
+	rng := rand.New(rand.NewPCG(0, 0))
+	for i := 0; i < 100; i++ {
+		for v := 3; v > 0; v-- {
+			value := make([]byte, 200)
+			for j := range value {
+				value[j] = byte(rng.Uint32())
+			}
+			key := base.MakeInternalKey([]byte(fmt.Sprintf("a%03d@%d", i, v)), base.SeqNum(i*3+v), InternalKeyKindSet)
+			require.NoError(t, w.Raw().Add(key, value, false))
+		}
+	}
+	require.NoError(t, w.DeleteRange([]byte("a010"), []byte("a020")))
+	require.NoError(t, w.DeleteRange([]byte("b"), []byte("d")))
+	require.NoError(t, w.RangeKeySet([]byte("a040"), []byte("c"), []byte("@5"), []byte("v")))
+	require.NoError(t, w.Close())
+	sst := f.Data()
+
+	keySchema := colblk.DefaultKeySchema(testkeys.Comparer, 16)
+	readerOpts := ReaderOptions{
+		Comparer:   testkeys.Comparer,
+		KeySchemas: MakeKeySchemas(&keySchema),
+	}
+	// contents returns the point keys and the spans of the sstable.
+	contents := func(sst []byte) (points, spans []string) {
+		r, err := NewMemReader(sst, readerOpts)
+		require.NoError(t, err)
+		defer r.Close()
+		iter, err := r.NewIter(NoTransforms, nil, nil)
+		require.NoError(t, err)
+		for kv := iter.First(); kv != nil; kv = iter.Next() {
+			v, _, err := kv.Value(nil)
+			require.NoError(t, err)
+			points = append(points, fmt.Sprintf("%s=%s", kv.K, v))
+		}
+		require.NoError(t, iter.Close())
+		for _, newIter := range []func(context.Context, FragmentIterTransforms, block.ReadEnv) (keyspan.FragmentIterator, error){
+			r.NewRawRangeDelIter, r.NewRawRangeKeyIter,
+		} {
+			iter, err := newIter(context.Background(), NoFragmentTransforms, block.NoReadEnv)
+			require.NoError(t, err)
+			if iter == nil {
+				continue
+			}
+			span, err := iter.First()
+			for ; span != nil; span, err = iter.Next() {
+				spans = append(spans, span.String())
+			}
+			require.NoError(t, err)
+			iter.Close()
+		}
+		return points, spans
+	}
+	split := func(o SplitTableOptions) (points, spans []string) {
+		var outputs []*objstorage.MemObj
+		o.ReaderOptions = readerOpts
+		o.NewOutput = func(i int) (objstorage.Writable, error) {
+			require.Equal(t, len(outputs), i)
+			outputs = append(outputs, &objstorage.MemObj{})
+			return outputs[i], nil
+		}
+		metas, err := SplitTable(context.Background(), newMemReader(sst), o)
+		require.NoError(t, err)
+		require.Len(t, metas, len(outputs))
+		for i := range outputs {
+			if i > 0 {
+				// The outputs don't overlap: the spans' end keys are exclusive.
+				cmp := testkeys.Comparer.Compare
+				prev, cur := metas[i-1], metas[i]
+				for _, largest := range []InternalKey{prev.LargestPoint, prev.LargestRangeDel, prev.LargestRangeKey} {
+					for _, smallest := range []InternalKey{cur.SmallestPoint, cur.SmallestRangeDel, cur.SmallestRangeKey} {
+						if largest.UserKey != nil && smallest.UserKey != nil {
+							require.LessOrEqual(t, cmp(largest.UserKey, smallest.UserKey), 0)
+						}
+					}
+				}
+				if prev.HasPointKeys && cur.HasPointKeys {
+					// The versions of a key are in the same output.
+					split := testkeys.Comparer.Split
+					prevPrefix, curPrefix := prev.LargestPoint.UserKey, cur.SmallestPoint.UserKey
+					require.Negative(t, cmp(prevPrefix[:split(prevPrefix)], curPrefix[:split(curPrefix)]))
+				}
+			}
+			p, s := contents(outputs[i].Data())
+			points = append(points, p...)
+			spans = append(spans, fmt.Sprintf("%d: %s", i, strings.Join(s, " ")))
+		}
+		return points, spans
+	}
+	inputPoints, inputSpans := contents(sst)
+	require.Equal(t, []string{
+		"a010-a020:{(#0,RANGEDEL)}",
+		"b-d:{(#0,RANGEDEL)}",
+		"a040-c:{(#0,RANGEKEYSET,@5,v)}",
+	}, inputSpans)
+
+	// Split at chosen keys. The spans that straddle a split key are truncated,
+	// and the splits with no keys don't produce an output.
+	points, spans := split(SplitTableOptions{
+		SplitKeys: [][]byte{[]byte("a015"), []byte("a050"), []byte("a099"), []byte("b5"), []byte("b6"), []byte("z")},
+	})
+	require.Equal(t, inputPoints, points)
+	require.Equal(t, []string{
+		"0: a010-a015:{(#0,RANGEDEL)}",
+		"1: a015-a020:{(#0,RANGEDEL)} a040-a050:{(#0,RANGEKEYSET,@5,v)}",
+		"2: a050-a099:{(#0,RANGEKEYSET,@5,v)}",
+		"3: b-b5:{(#0,RANGEDEL)} a099-b5:{(#0,RANGEKEYSET,@5,v)}",
+		"4: b5-b6:{(#0,RANGEDEL)} b5-b6:{(#0,RANGEKEYSET,@5,v)}",
+		"5: b6-d:{(#0,RANGEDEL)} b6-c:{(#0,RANGEKEYSET,@5,v)}",
+	}, spans)
+
+	// Split by size.
+	points, spans = split(SplitTableOptions{TargetFileSize: 16 << 10})
+	require.Equal(t, inputPoints, points)
+	require.Greater(t, len(spans), 3)
+
+	_, err := SplitTable(context.Background(), newMemReader(sst), SplitTableOptions{
+		ReaderOptions: readerOpts,
+		SplitKeys:     [][]byte{[]byte("b"), []byte("a")},
+	})
+	require.ErrorContains(t, err, "not sorted")
+}
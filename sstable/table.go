@@ -76,6 +76,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/crc"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/sstable/block"
+	"github.com/cockroachdb/pebble/vfs"
 )
 
 /*
@@ -437,6 +438,46 @@ func (f footer) encode(buf []byte) []byte {
 	return buf
 }
 
+// RewriteFooterFormat rewrites, in place, the footer of the table in f so that
+// the table has the given format. Only tables in the TableFormatRocksDBv2 and
+// TableFormatPebblev1 formats can be converted into one another: the two
+// formats share the same block and footer layouts, and only differ in the
+// magic number and version of the footer. The blocks of the table are left
+// untouched.
+func RewriteFooterFormat(f vfs.File, format TableFormat) error {
+	if format != TableFormatRocksDBv2 && format != TableFormatPebblev1 {
+		return errors.Newf("pebble/table: cannot rewrite footer to format %s", format)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := stat.Size()
+	if size < rocksDBFooterLen {
+		return base.CorruptionErrorf("pebble/table: invalid table (file size is too small)")
+	}
+	buf := make([]byte, rocksDBFooterLen)
+	off := size - rocksDBFooterLen
+	if _, err := f.ReadAt(buf, off); err != nil {
+		return err
+	}
+	foot, err := parseFooter(buf, off, size)
+	if err != nil {
+		return errors.Wrap(err, "pebble/table: invalid table")
+	}
+	if foot.format != TableFormatRocksDBv2 && foot.format != TableFormatPebblev1 {
+		return errors.Newf("pebble/table: cannot rewrite footer of table with format %s", foot.format)
+	}
+	if foot.format == format {
+		return nil
+	}
+	foot.format = format
+	if _, err := f.WriteAt(foot.encode(buf), int64(foot.footerBH.Offset)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
 func supportsTwoLevelIndex(format TableFormat) bool {
 	switch format {
 	case TableFormatLevelDB:
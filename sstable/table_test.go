@@ -656,3 +656,45 @@ func TestReadFooter(t *testing.T) {
 		})
 	}
 }
+
+func TestRewriteFooterFormat(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	fs := vfs.NewMem()
+	f, err := fs.Create("test.sst", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := NewWriter(objstorageprovider.NewFileWritable(f), WriterOptions{
+		TableFormat: TableFormatRocksDBv2,
+	})
+	require.NoError(t, w.Set([]byte("a"), []byte("1")))
+	require.NoError(t, w.Close())
+
+	readFormatAndKey := func() (TableFormat, string) {
+		f, err := fs.Open("test.sst")
+		require.NoError(t, err)
+		r, err := newReader(f, ReaderOptions{})
+		require.NoError(t, err)
+		defer r.Close()
+		tf, err := r.TableFormat()
+		require.NoError(t, err)
+		iter, err := r.NewIter(NoTransforms, nil /* lower */, nil /* upper */)
+		require.NoError(t, err)
+		defer iter.Close()
+		kv := iter.First()
+		require.NotNil(t, kv)
+		return tf, string(kv.K.UserKey)
+	}
+	rewrite := func(format TableFormat) error {
+		f, err := fs.OpenReadWrite("test.sst", vfs.WriteCategoryUnspecified)
+		require.NoError(t, err)
+		defer f.Close()
+		return RewriteFooterFormat(f, format)
+	}
+
+	for _, format := range []TableFormat{TableFormatPebblev1, TableFormatRocksDBv2} {
+		require.NoError(t, rewrite(format))
+		tf, key := readFormatAndKey()
+		require.Equal(t, format, tf)
+		require.Equal(t, "a", key)
+	}
+	require.Error(t, rewrite(TableFormatPebblev2))
+}
@@ -379,6 +379,11 @@ type RawWriter interface {
 	// used by the sstable copier that can copy parts of an sstable to a new sstable,
 	// using CopySpan().
 	copyProperties(props Properties)
+
+	// setError records err as the writer's error, so that a subsequent Close
+	// aborts the underlying writable instead of finishing a partially written
+	// table.
+	setError(err error)
 }
 
 // WriterMetadata holds info about a finished sstable.
@@ -0,0 +1,309 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/treeprinter"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// validateSuffixReplacements checks that the replacements are sorted by From
+// and that replacing the suffixes preserves the ordering of the keys.
+func validateSuffixReplacements(cmp *base.Comparer, rs sstable.SuffixReplacements) error {
+	for i := range rs {
+		if len(rs[i].From) == 0 || len(rs[i].To) == 0 {
+			return errors.New("pebble: suffix replacements must have non-empty suffixes")
+		}
+		if i == 0 {
+			continue
+		}
+		if cmp.ComparePointSuffixes(rs[i-1].From, rs[i].From) >= 0 {
+			return errors.Newf("pebble: suffix replacements not sorted by From: %s, %s",
+				cmp.FormatKey(rs[i-1].From), cmp.FormatKey(rs[i].From))
+		}
+		if cmp.ComparePointSuffixes(rs[i-1].To, rs[i].To) >= 0 {
+			return errors.Newf("pebble: suffix replacements don't preserve the order of the keys: %s, %s",
+				cmp.FormatKey(rs[i-1].To), cmp.FormatKey(rs[i].To))
+		}
+	}
+	return nil
+}
+
+// suffixReplacingIter wraps an iterator over a table with suffix replacements,
+// replacing the suffix of every key (see sstable.SuffixReplacement). Seek keys
+// and bounds are mapped to the keys physically stored in the table.
+type suffixReplacingIter struct {
+	iter internalIterator
+	cmp  *base.Comparer
+	rs   sstable.SuffixReplacements
+	err  error
+
+	kv      base.InternalKV
+	keyBuf  []byte
+	seekBuf []byte
+	// boundsBuf holds the inverted bounds of the wrapped iterator. The bounds
+	// are double-buffered since the wrapped iterator may compare its previous
+	// bounds to the new ones during SetBounds.
+	boundsBuf [2][]byte
+	boundsIdx int
+	// unpositioned is true if the last positioning operation did not position
+	// the wrapped iterator, in which case it must not be asked to seek using
+	// next.
+	unpositioned bool
+}
+
+var _ internalIterator = (*suffixReplacingIter)(nil)
+
+// invertSuffixBounds maps iterator bounds over the keys with replaced suffixes
+// to bounds over the keys physically stored in the table, appending the
+// inverted keys to buf.
+func invertSuffixBounds(
+	cmp *base.Comparer, rs sstable.SuffixReplacements, buf, lower, upper []byte,
+) (newBuf, innerLower, innerUpper []byte) {
+	buf = buf[:0]
+	if lower != nil {
+		buf, _ = rs.InvertTo(cmp, buf, lower)
+	}
+	n := len(buf)
+	if upper != nil {
+		buf, _ = rs.InvertTo(cmp, buf, upper)
+		innerUpper = buf[n:]
+	}
+	if lower != nil {
+		innerLower = buf[:n:n]
+	}
+	return buf, innerLower, innerUpper
+}
+
+// newSuffixReplacingIter returns a suffixReplacingIter with its bounds
+// initialized. The inner bounds are returned so that they can be used to
+// construct the wrapped iterator, which must be set with init.
+func newSuffixReplacingIter(
+	cmp *base.Comparer, rs sstable.SuffixReplacements, lower, upper []byte,
+) (_ *suffixReplacingIter, innerLower, innerUpper []byte) {
+	i := &suffixReplacingIter{cmp: cmp, rs: rs}
+	i.boundsBuf[0], innerLower, innerUpper = invertSuffixBounds(cmp, rs, nil, lower, upper)
+	return i, innerLower, innerUpper
+}
+
+func (i *suffixReplacingIter) init(iter internalIterator) {
+	i.iter = iter
+}
+
+func (i *suffixReplacingIter) surface(kv *base.InternalKV) *base.InternalKV {
+	i.unpositioned = false
+	if kv == nil {
+		return nil
+	}
+	var ok bool
+	if i.keyBuf, ok = i.rs.ApplyTo(i.cmp, i.keyBuf[:0], kv.K.UserKey); !ok {
+		i.err = base.CorruptionErrorf("pebble: key %s has a suffix without replacement",
+			i.cmp.FormatKey(kv.K.UserKey))
+		return nil
+	}
+	i.kv = *kv
+	i.kv.K.UserKey = i.keyBuf
+	return &i.kv
+}
+
+func (i *suffixReplacingIter) seekGEFlags(flags base.SeekGEFlags) base.SeekGEFlags {
+	if i.unpositioned {
+		flags = flags.DisableTrySeekUsingNext()
+	}
+	return flags
+}
+
+// SeekGE implements base.InternalIterator.
+func (i *suffixReplacingIter) SeekGE(key []byte, flags base.SeekGEFlags) *base.InternalKV {
+	i.err = nil
+	i.seekBuf, _ = i.rs.InvertTo(i.cmp, i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekGE(i.seekBuf, i.seekGEFlags(flags)))
+}
+
+// SeekPrefixGE implements base.InternalIterator.
+func (i *suffixReplacingIter) SeekPrefixGE(
+	prefix, key []byte, flags base.SeekGEFlags,
+) *base.InternalKV {
+	i.err = nil
+	var ok bool
+	i.seekBuf, ok = i.rs.InvertTo(i.cmp, i.seekBuf[:0], key)
+	if !ok {
+		// The key sorts after all the keys with the prefix.
+		i.unpositioned = true
+		return nil
+	}
+	return i.surface(i.iter.SeekPrefixGE(prefix, i.seekBuf, i.seekGEFlags(flags)))
+}
+
+// SeekLT implements base.InternalIterator.
+func (i *suffixReplacingIter) SeekLT(key []byte, flags base.SeekLTFlags) *base.InternalKV {
+	i.err = nil
+	i.seekBuf, _ = i.rs.InvertTo(i.cmp, i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekLT(i.seekBuf, flags))
+}
+
+// First implements base.InternalIterator.
+func (i *suffixReplacingIter) First() *base.InternalKV {
+	i.err = nil
+	return i.surface(i.iter.First())
+}
+
+// Last implements base.InternalIterator.
+func (i *suffixReplacingIter) Last() *base.InternalKV {
+	i.err = nil
+	return i.surface(i.iter.Last())
+}
+
+// Next implements base.InternalIterator.
+func (i *suffixReplacingIter) Next() *base.InternalKV {
+	return i.surface(i.iter.Next())
+}
+
+// NextPrefix implements base.InternalIterator.
+func (i *suffixReplacingIter) NextPrefix(succKey []byte) *base.InternalKV {
+	i.seekBuf, _ = i.rs.InvertTo(i.cmp, i.seekBuf[:0], succKey)
+	return i.surface(i.iter.NextPrefix(i.seekBuf))
+}
+
+// Prev implements base.InternalIterator.
+func (i *suffixReplacingIter) Prev() *base.InternalKV {
+	return i.surface(i.iter.Prev())
+}
+
+// Error implements base.InternalIterator.
+func (i *suffixReplacingIter) Error() error {
+	return errors.CombineErrors(i.err, i.iter.Error())
+}
+
+// Close implements base.InternalIterator.
+func (i *suffixReplacingIter) Close() error {
+	return i.iter.Close()
+}
+
+// SetBounds implements base.InternalIterator.
+func (i *suffixReplacingIter) SetBounds(lower, upper []byte) {
+	idx := i.boundsIdx ^ 1
+	var innerLower, innerUpper []byte
+	i.boundsBuf[idx], innerLower, innerUpper = invertSuffixBounds(i.cmp, i.rs, i.boundsBuf[idx], lower, upper)
+	i.boundsIdx = idx
+	i.iter.SetBounds(innerLower, innerUpper)
+}
+
+// SetContext implements base.InternalIterator.
+func (i *suffixReplacingIter) SetContext(ctx context.Context) {
+	i.iter.SetContext(ctx)
+}
+
+// DebugTree is part of the InternalIterator interface.
+func (i *suffixReplacingIter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p)", i, i)
+	if i.iter != nil {
+		i.iter.DebugTree(n)
+	}
+}
+
+func (i *suffixReplacingIter) String() string {
+	return i.iter.String()
+}
+
+// suffixReplacingSpanIter is the keyspan.FragmentIterator analog of
+// suffixReplacingIter, used for the range deletions of a table with suffix
+// replacements.
+type suffixReplacingSpanIter struct {
+	iter keyspan.FragmentIterator
+	cmp  *base.Comparer
+	rs   sstable.SuffixReplacements
+
+	span     keyspan.Span
+	startBuf []byte
+	endBuf   []byte
+	seekBuf  []byte
+}
+
+var _ keyspan.FragmentIterator = (*suffixReplacingSpanIter)(nil)
+
+func newSuffixReplacingSpanIter(
+	iter keyspan.FragmentIterator, cmp *base.Comparer, rs sstable.SuffixReplacements,
+) keyspan.FragmentIterator {
+	if iter == nil {
+		return nil
+	}
+	return &suffixReplacingSpanIter{iter: iter, cmp: cmp, rs: rs}
+}
+
+func (i *suffixReplacingSpanIter) surface(s *keyspan.Span, err error) (*keyspan.Span, error) {
+	if s == nil || err != nil {
+		return s, err
+	}
+	var ok1, ok2 bool
+	i.startBuf, ok1 = i.rs.ApplyTo(i.cmp, i.startBuf[:0], s.Start)
+	i.endBuf, ok2 = i.rs.ApplyTo(i.cmp, i.endBuf[:0], s.End)
+	if !ok1 || !ok2 {
+		return nil, base.CorruptionErrorf("pebble: span %s has a bound with a suffix without replacement", s)
+	}
+	i.span = *s
+	i.span.Start, i.span.End = i.startBuf, i.endBuf
+	return &i.span, nil
+}
+
+// SeekGE implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) SeekGE(key []byte) (*keyspan.Span, error) {
+	i.seekBuf, _ = i.rs.InvertTo(i.cmp, i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekGE(i.seekBuf))
+}
+
+// SeekLT implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) SeekLT(key []byte) (*keyspan.Span, error) {
+	i.seekBuf, _ = i.rs.InvertTo(i.cmp, i.seekBuf[:0], key)
+	return i.surface(i.iter.SeekLT(i.seekBuf))
+}
+
+// First implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) First() (*keyspan.Span, error) {
+	return i.surface(i.iter.First())
+}
+
+// Last implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) Last() (*keyspan.Span, error) {
+	return i.surface(i.iter.Last())
+}
+
+// Next implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) Next() (*keyspan.Span, error) {
+	return i.surface(i.iter.Next())
+}
+
+// Prev implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) Prev() (*keyspan.Span, error) {
+	return i.surface(i.iter.Prev())
+}
+
+// Close implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) Close() {
+	i.iter.Close()
+}
+
+// WrapChildren implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) WrapChildren(wrap keyspan.WrapFn) {
+	i.iter = wrap(i.iter)
+}
+
+// SetContext implements keyspan.FragmentIterator.
+func (i *suffixReplacingSpanIter) SetContext(ctx context.Context) {
+	i.iter.SetContext(ctx)
+}
+
+// DebugTree is part of the FragmentIterator interface.
+func (i *suffixReplacingSpanIter) DebugTree(tp treeprinter.Node) {
+	n := tp.Childf("%T(%p)", i, i)
+	if i.iter != nil {
+		i.iter.DebugTree(n)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// suggestedCompactionMaxQueueSize is the maximum number of pending compaction
+// suggestions. When the queue is full, the oldest suggestion is dropped.
+const suggestedCompactionMaxQueueSize = 64
+
+// suggestedCompaction is a key range that the application suggested to compact
+// through DB.SuggestCompaction.
+type suggestedCompaction struct {
+	// start and end are the bounds of the range, [start, end).
+	start, end []byte
+	reason     string
+	// level is the shallowest level that may still contain tables overlapping
+	// the range. The range is compacted level by level, from level downwards;
+	// the suggestion is complete once no level above the bottommost level has
+	// any table overlapping the range.
+	level int
+}
+
+// suggestedCompactionQueue holds the pending compaction suggestions, in order
+// of insertion.
+type suggestedCompactionQueue struct {
+	queue []*suggestedCompaction
+}
+
+// add adds a suggestion to the queue. A suggestion for a range that's already
+// queued restarts from L0.
+func (q *suggestedCompactionQueue) add(sc *suggestedCompaction, cmp base.Compare) {
+	q.queue = slices.DeleteFunc(q.queue, func(s *suggestedCompaction) bool {
+		return cmp(s.start, sc.start) == 0 && cmp(s.end, sc.end) == 0
+	})
+	if len(q.queue) == suggestedCompactionMaxQueueSize {
+		q.queue = q.queue[1:]
+	}
+	q.queue = append(q.queue, sc)
+}
+
+// SuggestCompaction records a hint that the key range [start, end) would
+// benefit from being compacted, for instance because a large range was just
+// deleted or is known to be cold. Unlike Compact, SuggestCompaction doesn't
+// block: the range is compacted by automatic compactions, level by level, with
+// a higher priority than the compactions that only reclaim space (but a lower
+// one than the compactions that keep the LSM in shape). reason describes the
+// suggestion in logs.
+//
+// The suggestion is not persisted, and is ignored while automatic compactions
+// are disabled.
+func (d *DB) SuggestCompaction(start, end []byte, reason string) error {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	if d.cmp(start, end) >= 0 {
+		return errors.Errorf("SuggestCompaction start %s is not less than end %s",
+			d.opts.Comparer.FormatKey(start), d.opts.Comparer.FormatKey(end))
+	}
+	sc := &suggestedCompaction{
+		start:  slices.Clone(start),
+		end:    slices.Clone(end),
+		reason: reason,
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.compact.suggestedCompactions.add(sc, d.cmp)
+	d.maybeScheduleCompaction()
+	return nil
+}
+
+// pickSuggestedCompaction picks a compaction for the oldest pending
+// suggestion that can make progress. Completed suggestions are removed from
+// the queue.
+func (p *compactionPickerByScore) pickSuggestedCompaction(
+	env compactionEnv,
+) (pc *pickedCompaction) {
+	q := env.suggestedCompactions
+	if q == nil {
+		return nil
+	}
+	for i := 0; i < len(q.queue); {
+		sc := q.queue[i]
+		pc, done := pickSuggestedCompactionHelper(p, sc, env)
+		if done {
+			q.queue = slices.Delete(q.queue, i, i+1)
+			continue
+		}
+		if pc != nil {
+			p.opts.Logger.Infof("pebble: compacting L%d for suggested compaction of [%s, %s): %s",
+				pc.startLevel.level, p.opts.Comparer.FormatKey(sc.start), p.opts.Comparer.FormatKey(sc.end), sc.reason)
+			return pc
+		}
+		i++
+	}
+	return nil
+}
+
+// pickSuggestedCompactionHelper picks a compaction of the shallowest level that
+// has tables overlapping the suggested range. It returns done=true if no level
+// above the bottommost level has such tables.
+func pickSuggestedCompactionHelper(
+	p *compactionPickerByScore, sc *suggestedCompaction, env compactionEnv,
+) (pc *pickedCompaction, done bool) {
+	bounds := base.UserKeyBoundsEndExclusive(sc.start, sc.end)
+	for level := sc.level; level < numLevels-1; level++ {
+		if level > 0 && level < p.baseLevel {
+			continue
+		}
+		overlaps := p.vers.Overlaps(level, bounds)
+		if overlaps.Empty() {
+			continue
+		}
+		sc.level = level
+		pc = newPickedCompaction(p.opts, p.vers, p.l0Organizer, level, defaultOutputLevel(level, p.baseLevel), p.baseLevel)
+		pc.startLevel.files = overlaps
+		if !pc.setupInputs(p.opts, env.diskAvailBytes, pc.startLevel) || inputRangeAlreadyCompacting(env, pc) {
+			// Retry later.
+			return nil, false
+		}
+		pc.kind = compactionKindSuggested
+		return pc, false
+	}
+	return nil, true
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestCompaction(t *testing.T) {
+	opts := &Options{
+		FS:     vfs.NewMem(),
+		Logger: testLogger{t: t},
+		// Prevent score-based compactions.
+		L0CompactionThreshold:     100,
+		L0CompactionFileThreshold: 100,
+		L0StopWritesThreshold:     1000,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 10; j++ {
+			key := []byte(fmt.Sprintf("%c%d", 'a'+j, i))
+			require.NoError(t, d.Set(key, key, nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	require.Equal(t, int64(3), d.Metrics().Levels[0].NumFiles)
+
+	require.Error(t, d.SuggestCompaction([]byte("b"), []byte("a"), "invalid"))
+	require.NoError(t, d.SuggestCompaction([]byte("a"), []byte("z"), "test"))
+	require.Eventually(t, func() bool {
+		m := d.Metrics()
+		return m.Levels[0].NumFiles == 0 && m.Compact.SuggestedCount > 0
+	}, 10*time.Second, time.Millisecond)
+	require.Equal(t, int64(0), d.Metrics().Levels[0].NumFiles)
+
+	// The suggestion is removed once there's nothing left to compact.
+	require.Eventually(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		// Picking a compaction removes the completed suggestions.
+		d.maybeScheduleCompaction()
+		return len(d.mu.compact.suggestedCompactions.queue) == 0
+	}, 10*time.Second, time.Millisecond)
+}
+
+func TestSuggestedCompactionQueue(t *testing.T) {
+	var q suggestedCompactionQueue
+	for i := 0; i < suggestedCompactionMaxQueueSize+1; i++ {
+		q.add(&suggestedCompaction{
+			start: []byte(fmt.Sprintf("%03d", i)),
+			end:   []byte(fmt.Sprintf("%03d", i+1)),
+		}, base.DefaultComparer.Compare)
+	}
+	// The oldest suggestion was dropped.
+	require.Len(t, q.queue, suggestedCompactionMaxQueueSize)
+	require.Equal(t, "001", string(q.queue[0].start))
+
+	// Suggesting the same range again moves it to the end of the queue, and
+	// restarts it from L0.
+	q.queue[0].level = 3
+	q.add(&suggestedCompaction{start: []byte("001"), end: []byte("002")}, base.DefaultComparer.Compare)
+	require.Len(t, q.queue, suggestedCompactionMaxQueueSize)
+	require.Equal(t, "002", string(q.queue[0].start))
+	require.Equal(t, "001", string(q.queue[len(q.queue)-1].start))
+	require.Equal(t, 0, q.queue[len(q.queue)-1].level)
+}
@@ -0,0 +1,57 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// getAccessSamplingPeriod is the average number of Gets between Gets that
+// record an access of the sstables they consult.
+const getAccessSamplingPeriod = 1 << 6
+
+// tableAccessTracker records sampled reads of sstables, maintaining the
+// per-table manifest.TableAccessStats and per-level counters that are surfaced
+// through Metrics.
+//
+// Reads are sampled by iterators (see Iterator.sampleRead) and by a fraction
+// of Gets. Sampling is disabled entirely when
+// Options.Experimental.ReadSamplingMultiplier is negative.
+type tableAccessTracker struct {
+	timeNow func() time.Time
+	// sampledReads is the number of sampled reads of tables in each level.
+	sampledReads [numLevels]atomic.Uint64
+}
+
+// record records a sampled read of the table m, which resides in the given
+// level.
+func (t *tableAccessTracker) record(m *tableMetadata, level int) {
+	m.Access.Record(t.timeNow())
+	t.sampledReads[level].Add(1)
+}
+
+// sampleGet returns the tracker if a Get should record the tables it reads,
+// or nil otherwise.
+func (d *DB) sampleGet() *tableAccessTracker {
+	if d.opts.Experimental.ReadSamplingMultiplier < 0 {
+		return nil
+	}
+	if rand.Uint32N(getAccessSamplingPeriod) != 0 {
+		return nil
+	}
+	return &d.tableAccess
+}
+
+// sampleSeek returns true if a seek or Get should be sampled for read triggered
+// compactions (see Options.Experimental.ReadCompactionSeekSamplingPeriod).
+func (d *DB) sampleSeek() bool {
+	period := d.opts.Experimental.ReadCompactionSeekSamplingPeriod
+	if period <= 0 || d.opts.Experimental.ReadSamplingMultiplier < 0 {
+		return false
+	}
+	return rand.Int64N(period) == 0
+}
@@ -5,9 +5,13 @@
 package pebble
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
@@ -40,11 +44,20 @@ import (
 //
 // When an existing database is opened, all files lack in-memory statistics.
 // These files' stats are loaded incrementally whenever the pending list is
-// empty by scanning a current readState for files missing statistics. Once a
-// job completes a scan without finding any remaining files without
-// statistics, it flips a `loadedInitial` flag. From then on, the stats
-// collection job only needs to load statistics for new files appended to the
-// pending list.
+// empty: a job scans a current readState for files missing statistics,
+// ordering them into a backlog with the files relevant to the pending
+// compaction decisions first (see tableStatsPriority), and each job loads the
+// stats of the next batch of the backlog. Once a job
+// completes a scan without finding any remaining files without statistics, it
+// flips a `loadedInitial` flag. From then on, the stats collection job only
+// needs to load statistics for new files appended to the pending list.
+//
+// A job loads the stats of its files concurrently, using up to
+// Options.Experimental.TableStatsConcurrency goroutines.
+
+// defaultTableStatsConcurrency is the default of
+// Options.Experimental.TableStatsConcurrency.
+const defaultTableStatsConcurrency = 4
 
 func (d *DB) maybeCollectTableStatsLocked() {
 	if d.shouldCollectTableStatsLocked() {
@@ -94,25 +107,42 @@ func (d *DB) collectTableStats() bool {
 	d.mu.tableStats.pending = nil
 	d.mu.tableStats.loading = true
 	jobID := d.newJobIDLocked()
+	d.mu.tableStats.loadingJobID = jobID
+	d.mu.tableStats.loadingStartTime = d.timeNow()
 	loadedInitial := d.mu.tableStats.loadedInitial
+	backlog := d.mu.tableStats.backlog
+	var prio tableStatsPriority
+	if len(pending) == 0 && len(backlog) == 0 {
+		prio = d.tableStatsPriorityLocked()
+	}
 	// Drop DB.mu before performing IO.
 	d.mu.Unlock()
 
 	// Every run of collectTableStats either collects stats from the pending
-	// list (if non-empty) or from scanning the version (loadedInitial is
-	// false). This job only runs if at least one of those conditions holds.
+	// list (if non-empty) or from the backlog of files that existed at Open
+	// (loadedInitial is false). This job only runs if at least one of those
+	// conditions holds.
 
 	// Grab a read state to scan for tables.
 	rs := d.loadReadState()
 	var collected []collectedStats
 	var hints []deleteCompactionHint
 	if len(pending) > 0 {
-		collected, hints = d.loadNewFileStats(rs, pending)
+		collected, hints, _ = d.loadNewFileStats(rs, pending)
 	} else {
-		var moreRemain bool
-		var buf [maxTableStatsPerScan]collectedStats
-		collected, hints, moreRemain = d.scanReadStateTableStats(rs, buf[:0])
-		loadedInitial = !moreRemain
+		// Rebuild the backlog from the current version if it's been consumed.
+		// The initial load is complete once a scan's files (if any) have all
+		// been loaded.
+		scanned, moreRemain := false, false
+		if len(backlog) == 0 {
+			backlog, moreRemain = d.scanReadStateTableStats(rs, prio)
+			scanned = true
+		}
+		n := min(len(backlog), maxTableStatsPerScan*d.opts.Experimental.TableStatsConcurrency)
+		var failed bool
+		collected, hints, failed = d.loadNewFileStats(rs, backlog[:n])
+		backlog = backlog[n:]
+		loadedInitial = scanned && !moreRemain && !failed && len(backlog) == 0
 	}
 	rs.unref()
 
@@ -120,6 +150,8 @@ func (d *DB) collectTableStats() bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.mu.tableStats.loading = false
+	d.mu.tableStats.backlog = backlog
+	d.mu.tableStats.loadedCount += int64(len(collected))
 	if loadedInitial && !d.mu.tableStats.loadedInitial {
 		d.mu.tableStats.loadedInitial = loadedInitial
 		d.opts.EventListener.TableStatsLoaded(TableStatsInfo{
@@ -131,7 +163,16 @@ func (d *DB) collectTableStats() bool {
 	for _, c := range collected {
 		c.tableMetadata.Stats = c.TableStats
 		maybeCompact = maybeCompact || fileCompensation(c.tableMetadata) > 0
+		// Rewrite compactions of marked virtual tables wait for their stats.
+		maybeCompact = maybeCompact || c.tableMetadata.MarkedForCompaction
 		c.tableMetadata.StatsMarkValid()
+		if c.empty && !d.opts.private.disableDeleteOnlyCompactions {
+			d.mu.compact.emptyVirtualTables = append(d.mu.compact.emptyVirtualTables, emptyVirtualTable{
+				level: c.level,
+				meta:  c.tableMetadata,
+			})
+			maybeCompact = true
+		}
 	}
 
 	d.mu.tableStats.cond.Broadcast()
@@ -156,6 +197,7 @@ func (d *DB) collectTableStats() bool {
 			}
 		}
 		d.mu.compact.deletionHints = append(d.mu.compact.deletionHints, keepHints...)
+		d.mu.compact.deleteOnlyEligible.valid = false
 	}
 	if maybeCompact {
 		d.maybeScheduleCompaction()
@@ -166,14 +208,29 @@ func (d *DB) collectTableStats() bool {
 type collectedStats struct {
 	*tableMetadata
 	manifest.TableStats
+	level int
+	// empty is true if the table is a virtual sstable that contains no
+	// visible keys. See DB.isEmptyVirtualTable.
+	empty bool
 }
 
+// loadNewFileStats loads the stats of the given tables, using up to
+// Options.Experimental.TableStatsConcurrency goroutines. Tables that already
+// have stats or are no longer in their level are skipped. The returned stats
+// are in the order of the given tables. The last return value is true if the
+// stats of some tables couldn't be loaded.
 func (d *DB) loadNewFileStats(
 	rs *readState, pending []manifest.NewTableEntry,
-) ([]collectedStats, []deleteCompactionHint) {
-	var hints []deleteCompactionHint
-	collected := make([]collectedStats, 0, len(pending))
-	for _, nf := range pending {
+) ([]collectedStats, []deleteCompactionHint, bool) {
+	type result struct {
+		collectedStats
+		hints  []deleteCompactionHint
+		ok     bool
+		failed bool
+	}
+	results := make([]result, len(pending))
+	load := func(i int) {
+		nf := pending[i]
 		// A file's stats might have been populated by an earlier call to
 		// loadNewFileStats if the file was moved.
 		// NB: We're not holding d.mu which protects f.Stats, but only
@@ -181,14 +238,14 @@ func (d *DB) loadNewFileStats(
 		// ensure only one goroutine runs it at a time through
 		// d.mu.tableStats.loading.
 		if nf.Meta.StatsValid() {
-			continue
+			return
 		}
 
 		// The file isn't guaranteed to still be live in the readState's
 		// version. It may have been deleted or moved. Skip it if it's not in
 		// the expected level.
 		if !rs.current.Contains(nf.Level, nf.Meta) {
-			continue
+			return
 		}
 
 		stats, newHints, err := d.loadTableStats(
@@ -197,28 +254,75 @@ func (d *DB) loadNewFileStats(
 		)
 		if err != nil {
 			d.opts.EventListener.BackgroundError(err)
-			continue
+			results[i].failed = true
+			return
+		}
+		empty, err := d.isEmptyVirtualTable(nf.Level, nf.Meta, &stats)
+		if err != nil {
+			d.opts.EventListener.BackgroundError(err)
+			results[i].failed = true
+			return
 		}
 		// NB: We don't update the TableMetadata yet, because we aren't holding
 		// DB.mu. We'll copy it to the TableMetadata after we're finished with
 		// IO.
-		collected = append(collected, collectedStats{
-			tableMetadata: nf.Meta,
-			TableStats:    stats,
-		})
-		hints = append(hints, newHints...)
+		results[i] = result{
+			collectedStats: collectedStats{
+				tableMetadata: nf.Meta,
+				TableStats:    stats,
+				level:         nf.Level,
+				empty:         empty,
+			},
+			hints: newHints,
+			ok:    true,
+		}
+	}
+
+	if workers := min(d.opts.Experimental.TableStatsConcurrency, len(pending)); workers <= 1 {
+		for i := range pending {
+			load(i)
+		}
+	} else {
+		var next atomic.Int64
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for range workers {
+			go func() {
+				defer wg.Done()
+				for i := int(next.Add(1) - 1); i < len(pending); i = int(next.Add(1) - 1) {
+					load(i)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	var hints []deleteCompactionHint
+	var failed bool
+	collected := make([]collectedStats, 0, len(pending))
+	for i := range results {
+		if results[i].ok {
+			collected = append(collected, results[i].collectedStats)
+			hints = append(hints, results[i].hints...)
+		}
+		failed = failed || results[i].failed
 	}
-	return collected, hints
+	return collected, hints, failed
 }
 
 // scanReadStateTableStats is run by an active stat collection job when there
 // are no pending new files, but there might be files that existed at Open for
-// which we haven't loaded table stats.
+// which we haven't loaded table stats. It returns the files without stats, in
+// the order in which their stats should be loaded, and whether some files
+// couldn't be checked and the scan should be retried.
+//
+// The files are ordered by prio, so that the stats the pending compaction
+// decisions depend on are loaded first.
 func (d *DB) scanReadStateTableStats(
-	rs *readState, fill []collectedStats,
-) ([]collectedStats, []deleteCompactionHint, bool) {
+	rs *readState, prio tableStatsPriority,
+) ([]manifest.NewTableEntry, bool) {
 	moreRemain := false
-	var hints []deleteCompactionHint
+	var backlog []manifest.NewTableEntry
 	sizesChecked := make(map[base.DiskFileNum]struct{})
 	for l, levelMetadata := range rs.current.Levels {
 		for f := range levelMetadata.All() {
@@ -231,16 +335,6 @@ func (d *DB) scanReadStateTableStats(
 				continue
 			}
 
-			// Limit how much work we do per read state. The older the read
-			// state is, the higher the likelihood files are no longer being
-			// used in the current version. If we've exhausted our allowance,
-			// return true for the last return value to signal there's more
-			// work to do.
-			if len(fill) == cap(fill) {
-				moreRemain = true
-				return fill, hints, moreRemain
-			}
-
 			// If the file is remote and not SharedForeign, we should check if its size
 			// matches. This is because checkConsistency skips over remote files.
 			//
@@ -279,23 +373,71 @@ func (d *DB) scanReadStateTableStats(
 				sizesChecked[f.FileBacking.DiskFileNum] = struct{}{}
 			}
 
-			stats, newHints, err := d.loadTableStats(
-				rs.current, l, f,
-			)
-			if err != nil {
-				// Set `moreRemain` so we'll try again.
-				moreRemain = true
-				d.opts.EventListener.BackgroundError(err)
-				continue
+			backlog = append(backlog, manifest.NewTableEntry{Level: l, Meta: f})
+		}
+	}
+	slices.SortStableFunc(backlog, prio.compare)
+	return backlog, moreRemain
+}
+
+// tableStatsPriority orders the files whose stats are loaded after Open,
+// according to the compaction decisions that are pending when the files are
+// scanned.
+type tableStatsPriority struct {
+	// scores are the compaction picker's level scores.
+	scores [numLevels]float64
+	// compacting holds the inputs of the in-progress compactions.
+	compacting map[*tableMetadata]struct{}
+}
+
+// tableStatsPriorityLocked returns the priority of the files whose stats are
+// loaded after Open, given the current compaction picker and in-progress
+// compactions.
+//
+// d.mu must be held when calling this.
+func (d *DB) tableStatsPriorityLocked() tableStatsPriority {
+	var prio tableStatsPriority
+	inProgress := d.getInProgressCompactionInfoLocked(nil)
+	if p := d.mu.versions.picker; p != nil {
+		prio.scores = p.getScores(inProgress)
+	}
+	for _, c := range inProgress {
+		for _, cl := range c.inputs {
+			for f := range cl.files.All() {
+				if prio.compacting == nil {
+					prio.compacting = make(map[*tableMetadata]struct{})
+				}
+				prio.compacting[f] = struct{}{}
 			}
-			fill = append(fill, collectedStats{
-				tableMetadata: f,
-				TableStats:    stats,
-			})
-			hints = append(hints, newHints...)
 		}
 	}
-	return fill, hints, moreRemain
+	return prio
+}
+
+// compare orders a before b if the stats of a should be loaded first:
+//
+//   - The inputs of in-progress compactions are loaded last, since they're
+//     about to be replaced by the compactions' outputs.
+//   - The files of the levels with the highest compaction scores are loaded
+//     next. The picker compacts these levels next, and the deletion estimates
+//     in the stats of their files drive its choice of files, and the scores
+//     themselves through the compensated sizes of the levels.
+//   - Within a level, the most recently written files are loaded first: they
+//     hold the newest tombstones, and they're the likeliest to be picked for
+//     compaction.
+func (p *tableStatsPriority) compare(a, b manifest.NewTableEntry) int {
+	_, aCompacting := p.compacting[a.Meta]
+	_, bCompacting := p.compacting[b.Meta]
+	if aCompacting != bCompacting {
+		if aCompacting {
+			return +1
+		}
+		return -1
+	}
+	if c := cmp.Compare(p.scores[b.Level], p.scores[a.Level]); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.Meta.LargestSeqNum, a.Meta.LargestSeqNum)
 }
 
 func (d *DB) loadTableStats(
@@ -307,6 +449,12 @@ func (d *DB) loadTableStats(
 		context.TODO(), block.NoReadEnv,
 		meta, func(r sstable.CommonReader, _ block.ReadEnv) (err error) {
 			props := r.CommonProperties()
+			switch r := r.(type) {
+			case *sstable.Reader:
+				stats.UserProperties = filterUserProperties(d.opts.UserPropertyAggregators, r.Properties.UserProperties)
+			case *sstable.VirtualReader:
+				stats.UserProperties = filterUserProperties(d.opts.UserPropertyAggregators, r.UnsafeReader().Properties.UserProperties)
+			}
 			stats.NumEntries = props.NumEntries
 			stats.NumDeletions = props.NumDeletions
 			stats.NumRangeKeySets = props.NumRangeKeySets
@@ -336,6 +484,60 @@ func (d *DB) loadTableStats(
 	return stats, compactionHints, nil
 }
 
+// isEmptyVirtualTable returns true if meta is a virtual sstable that no
+// longer contains any live keys: every point key within the virtual bounds is
+// a point tombstone and the table contains no RANGEKEYSETs. Such tables are
+// commonly left behind by excise-heavy workflows, where the live keys of a
+// backing table were excised and only tombstones remain. Note that the
+// tombstones may still shadow data in lower levels; see emptyVirtualTable.
+//
+// The provided stats are used to avoid the check for tables that cannot be
+// empty. Since the stats of a virtual sstable are estimates scaled from the
+// backing table's properties, they cannot be used to prove emptiness, so the
+// table's keys are scanned. The scan terminates at the first key that is not
+// a tombstone, which is typically the first key of a non-empty table.
+func (d *DB) isEmptyVirtualTable(
+	level int, meta *tableMetadata, stats *manifest.TableStats,
+) (bool, error) {
+	// L0 tables cannot be dropped by delete-only compactions.
+	if !meta.Virtual || level == 0 || stats.NumDeletions == 0 || stats.NumRangeKeySets > 0 {
+		return false, nil
+	}
+	iters, err := d.newIters(context.TODO(), meta, &IterOptions{
+		Category: categoryCompaction,
+		layer:    manifest.Level(level),
+	}, internalIterOpts{}, iterPointKeys|iterRangeKeys)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = iters.CloseAll() }()
+
+	pointIter := iters.Point()
+	for kv := pointIter.First(); kv != nil; kv = pointIter.Next() {
+		switch kv.Kind() {
+		case InternalKeyKindDelete, InternalKeyKindSingleDelete, InternalKeyKindDeleteSized:
+		default:
+			return false, nil
+		}
+	}
+	if err := pointIter.Error(); err != nil {
+		return false, err
+	}
+	rangeKeyIter := iters.RangeKey()
+	s, err := rangeKeyIter.First()
+	for ; s != nil; s, err = rangeKeyIter.Next() {
+		for _, k := range s.Keys {
+			if k.Kind() == base.InternalKeyKindRangeKeySet {
+				return false, nil
+			}
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // loadTablePointKeyStats calculates the point key statistics for the given
 // table. The provided manifest.TableStats are updated.
 func (d *DB) loadTablePointKeyStats(
@@ -644,7 +846,9 @@ func (d *DB) estimateReclaimedSizeBeneath(
 	return estimate, hintSeqNum, nil
 }
 
-func maybeSetStatsFromProperties(meta physicalMeta, props *sstable.Properties) bool {
+func maybeSetStatsFromProperties(
+	meta physicalMeta, props *sstable.Properties, aggs []UserPropertyAggregator,
+) bool {
 	// If a table contains range deletions or range key deletions, we defer the
 	// stats collection. There are two main reasons for this:
 	//
@@ -687,6 +891,7 @@ func maybeSetStatsFromProperties(meta physicalMeta, props *sstable.Properties) b
 	meta.Stats.RangeDeletionsBytesEstimate = 0
 	meta.Stats.ValueBlocksSize = props.ValueBlocksSize
 	meta.Stats.CompressionType = block.CompressionFromString(props.CompressionName)
+	meta.Stats.UserProperties = filterUserProperties(aggs, props.UserProperties)
 	meta.StatsMarkValid()
 	return true
 }
@@ -914,10 +1119,17 @@ func newCombinedDeletionKeyspanIter(
 	})
 	mIter.Init(comparer, transform, new(keyspanimpl.MergingBuffers))
 
+	pr := m.PrefixReplacement()
 	iter, err := cr.NewRawRangeDelIter(context.TODO(), m.FragmentIterTransforms(), block.NoReadEnv)
 	if err != nil {
 		return nil, err
 	}
+	if iter != nil && pr.IsSet() {
+		iter = newPrefixReplacingSpanIter(iter, pr)
+	}
+	if iter != nil && len(m.SuffixReplacements) > 0 {
+		iter = newSuffixReplacingSpanIter(iter, comparer, m.SuffixReplacements)
+	}
 	if iter != nil {
 		// Assert expected bounds. In previous versions of Pebble, range
 		// deletions persisted to sstables could exceed the bounds of the
@@ -969,6 +1181,9 @@ func newCombinedDeletionKeyspanIter(
 	if err != nil {
 		return nil, err
 	}
+	if iter != nil && pr.IsSet() {
+		iter = newPrefixReplacingSpanIter(iter, pr)
+	}
 	if iter != nil {
 		// Assert expected bounds in tests.
 		if invariants.Sometimes(50) {
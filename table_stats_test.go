@@ -13,6 +13,7 @@ import (
 
 	"github.com/cockroachdb/datadriven"
 	"github.com/cockroachdb/pebble/internal/keyspan"
+	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/internal/testkeys"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
 	"github.com/cockroachdb/pebble/sstable"
@@ -190,6 +191,85 @@ func TestTableStats(t *testing.T) {
 	})
 }
 
+func TestTableStatsInitialLoad(t *testing.T) {
+	mem := vfs.NewMem()
+	opts := &Options{
+		DisableAutomaticCompactions: true,
+		DisableTableStats:           true,
+		FS:                          mem,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	const numTables = 120
+	for i := 0; i < numTables; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("%04d", i)), nil, nil))
+		if i >= numTables/2 {
+			require.NoError(t, d.Delete([]byte(fmt.Sprintf("%04d", i)), nil))
+		}
+		require.NoError(t, d.Flush())
+		if i == numTables/2-1 {
+			// Compact the first half of the tables into a single table out of
+			// L0.
+			require.NoError(t, d.Compact([]byte("0000"), []byte(fmt.Sprintf("%04d", i+1)), false))
+		}
+	}
+	require.NoError(t, d.Close())
+
+	// Reopen the DB without loading the stats, to check the order of the
+	// backlog.
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	rs := d.loadReadState()
+	scan := func(prio tableStatsPriority) []manifest.NewTableEntry {
+		backlog, moreRemain := d.scanReadStateTableStats(rs, prio)
+		require.False(t, moreRemain)
+		return backlog
+	}
+	// Without pending compactions, the most recent tables are loaded first.
+	backlog := scan(tableStatsPriority{})
+	require.Len(t, backlog, numTables/2+1)
+	for i := 1; i < len(backlog); i++ {
+		require.Greater(t, backlog[i-1].Meta.LargestSeqNum, backlog[i].Meta.LargestSeqNum)
+	}
+	require.Equal(t, numLevels-1, backlog[len(backlog)-1].Level)
+	// The tables of the levels with the highest scores are loaded first, and
+	// the inputs of in-progress compactions last.
+	var prio tableStatsPriority
+	prio.scores[numLevels-1] = 2
+	prio.scores[0] = 1
+	prio.compacting = map[*tableMetadata]struct{}{backlog[0].Meta: {}}
+	reordered := scan(prio)
+	require.Equal(t, backlog[len(backlog)-1], reordered[0])
+	require.Equal(t, backlog[1:len(backlog)-1], reordered[1:len(backlog)-1])
+	require.Equal(t, backlog[0], reordered[len(reordered)-1])
+	rs.unref()
+	require.NoError(t, d.Close())
+
+	opts.DisableTableStats = false
+	opts.Experimental.TableStatsConcurrency = 8
+	d, err = Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	d.mu.Lock()
+	for d.mu.tableStats.loading || !d.mu.tableStats.loadedInitial {
+		d.mu.tableStats.cond.Wait()
+	}
+	for f := range d.mu.versions.currentVersion().Levels[0].All() {
+		require.True(t, f.StatsValid())
+		require.Equal(t, uint64(1), f.Stats.NumDeletions)
+	}
+	for f := range d.mu.versions.currentVersion().Levels[numLevels-1].All() {
+		require.True(t, f.StatsValid())
+	}
+	d.mu.Unlock()
+
+	m := d.Metrics()
+	require.True(t, m.TableStats.InitialLoadCompleted)
+	require.Equal(t, int64(numTables/2+1), m.TableStats.LoadedCount)
+	require.Zero(t, m.TableStats.BacklogCount)
+	require.Zero(t, m.TableStats.PendingCount)
+}
+
 func TestTableRangeDeletionIter(t *testing.T) {
 	var m *tableMetadata
 	cmp := testkeys.Comparer
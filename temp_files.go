@@ -0,0 +1,173 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bufio"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// defaultTempDirname is the name of the subdirectory of the DB directory that
+// holds temporary files when Options.TempDir is unset.
+const defaultTempDirname = "tmp"
+
+// tempFiles manages the temporary files created by subsystems that spill data
+// to disk (for example, to sort data that does not fit in memory). Temporary
+// files live in Options.TempDir on Options.TempFS, separate from the files
+// that make up the store. They never outlive the DB that created them: any
+// temporary files found when a DB is opened are leftovers from a previous
+// process and are removed.
+//
+// The temporary directory is created lazily, when the first temporary file is
+// created.
+type tempFiles struct {
+	fs      vfs.FS
+	dirname string
+	// dirCreated is set once the temporary directory is known to exist.
+	dirCreated atomic.Bool
+	// count and size are the number and cumulative size of the live temporary
+	// files.
+	count atomic.Int64
+	size  atomic.Int64
+}
+
+func (t *tempFiles) init(fs vfs.FS, dirname string) {
+	t.fs = fs
+	t.dirname = dirname
+}
+
+// removeLeftovers removes any temporary files left behind in the temporary
+// directory by a previous process.
+func (t *tempFiles) removeLeftovers() error {
+	ls, err := t.fs.List(t.dirname)
+	if err != nil {
+		if oserror.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, filename := range ls {
+		if ft, _, ok := base.ParseFilename(t.fs, filename); !ok || ft != base.FileTypeTemp {
+			continue
+		}
+		if err := t.fs.Remove(t.fs.PathJoin(t.dirname, filename)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// create creates a new temporary file with the provided file number. The
+// caller must call Remove on the returned file once it's no longer needed.
+func (t *tempFiles) create(fileNum base.DiskFileNum) (*tempFile, error) {
+	if !t.dirCreated.Load() {
+		if err := t.fs.MkdirAll(t.dirname, 0755); err != nil {
+			return nil, err
+		}
+		t.dirCreated.Store(true)
+	}
+	path := base.MakeFilepath(t.fs, t.dirname, base.FileTypeTemp, fileNum)
+	f, err := t.fs.Create(path, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return nil, err
+	}
+	t.count.Add(1)
+	return &tempFile{File: f, t: t, path: path}, nil
+}
+
+// tempFile is a temporary file created through tempFiles. Writes to the file
+// are accounted for in the DB's metrics until the file is removed.
+type tempFile struct {
+	vfs.File
+	t    *tempFiles
+	path string
+	size int64
+	// closed is set once File has been closed, either by Close or by readable.
+	closed bool
+}
+
+// Write implements io.Writer.
+func (f *tempFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.size += int64(n)
+	f.t.size.Add(int64(n))
+	return n, err
+}
+
+// Close implements io.Closer.
+func (f *tempFile) Close() error {
+	f.closed = true
+	return f.File.Close()
+}
+
+// writable returns an objstorage.Writable that writes to the file. Finishing
+// the writable flushes the buffered writes but leaves the file open, so that
+// it can be read back through readable.
+func (f *tempFile) writable() objstorage.Writable {
+	return &tempFileWritable{bw: bufio.NewWriter(f)}
+}
+
+// readable closes the file and reopens it for reading. The returned Readable
+// must be closed before the file is removed.
+func (f *tempFile) readable() (objstorage.Readable, error) {
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	rf, err := f.t.fs.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	return sstable.NewSimpleReadable(rf)
+}
+
+// Remove closes and removes the temporary file.
+func (f *tempFile) Remove() error {
+	var err error
+	if !f.closed {
+		err = f.Close()
+	}
+	if rmErr := f.t.fs.Remove(f.path); err == nil {
+		err = rmErr
+	}
+	f.t.count.Add(-1)
+	f.t.size.Add(-f.size)
+	f.size = 0
+	return err
+}
+
+// newTempFile creates a new temporary file in the temporary directory. The
+// caller must call Remove on the returned file once it's no longer needed.
+func (d *DB) newTempFile() (*tempFile, error) {
+	return d.tempFiles.create(d.mu.versions.getNextDiskFileNum())
+}
+
+// tempFileWritable implements objstorage.Writable for a tempFile.
+type tempFileWritable struct {
+	bw *bufio.Writer
+}
+
+var _ objstorage.Writable = (*tempFileWritable)(nil)
+
+// Write is part of the objstorage.Writable interface.
+func (w *tempFileWritable) Write(p []byte) error {
+	_, err := w.bw.Write(p)
+	return err
+}
+
+// Finish is part of the objstorage.Writable interface. The data doesn't need
+// to be durable, so the file isn't synced.
+func (w *tempFileWritable) Finish() error {
+	return w.bw.Flush()
+}
+
+// Abort is part of the objstorage.Writable interface. The file is cleaned up
+// when it's removed.
+func (w *tempFileWritable) Abort() {}
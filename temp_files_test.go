@@ -0,0 +1,144 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTempFiles(t *testing.T) {
+	mem := vfs.NewMem()
+	tempFS := vfs.NewMem()
+	opts := &Options{
+		FS:      mem,
+		TempDir: "scratch",
+		TempFS:  tempFS,
+	}
+	d, err := Open("db", opts)
+	require.NoError(t, err)
+
+	// The temporary directory is only created once a temporary file is needed.
+	_, err = tempFS.Stat("scratch")
+	require.Error(t, err)
+
+	f1, err := d.newTempFile()
+	require.NoError(t, err)
+	f2, err := d.newTempFile()
+	require.NoError(t, err)
+	_, err = f1.Write(make([]byte, 100))
+	require.NoError(t, err)
+	_, err = f2.Write(make([]byte, 50))
+	require.NoError(t, err)
+
+	m := d.Metrics()
+	require.Equal(t, int64(2), m.TempFiles.Count)
+	require.Equal(t, uint64(150), m.TempFiles.Size)
+	require.Contains(t, m.String(), "Temp files: 2 (150B)")
+
+	// Temporary files are kept out of the store directory.
+	ls, err := mem.List("db")
+	require.NoError(t, err)
+	require.False(t, slices.Contains(ls, "scratch"))
+
+	require.NoError(t, f1.Remove())
+	m = d.Metrics()
+	require.Equal(t, int64(1), m.TempFiles.Count)
+	require.Equal(t, uint64(50), m.TempFiles.Size)
+	ls, err = tempFS.List("scratch")
+	require.NoError(t, err)
+	require.Len(t, ls, 1)
+
+	// Leave f2 behind, as if the process exited without cleaning it up, and
+	// place an unrelated file in the temporary directory.
+	require.NoError(t, f2.Close())
+	require.NoError(t, d.Close())
+	unrelated, err := tempFS.Create("scratch/unrelated", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	require.NoError(t, unrelated.Close())
+
+	// Reopening the DB removes the leftover temporary file, but not the
+	// unrelated file.
+	d, err = Open("db", opts)
+	require.NoError(t, err)
+	ls, err = tempFS.List("scratch")
+	require.NoError(t, err)
+	require.Equal(t, []string{"unrelated"}, ls)
+	m = d.Metrics()
+	require.Zero(t, m.TempFiles.Count)
+	require.Zero(t, m.TempFiles.Size)
+	require.NoError(t, d.Close())
+}
+
+func TestTempFilesDefaultDir(t *testing.T) {
+	mem := vfs.NewMem()
+	d, err := Open("db", &Options{FS: mem})
+	require.NoError(t, err)
+	f, err := d.newTempFile()
+	require.NoError(t, err)
+	_, err = mem.Stat(f.path)
+	require.NoError(t, err)
+	require.Equal(t, mem.PathJoin("db", defaultTempDirname), mem.PathDir(f.path))
+	require.NoError(t, f.Remove())
+	require.NoError(t, d.Close())
+}
+
+func TestUnsortedWriter(t *testing.T) {
+	mem := vfs.NewMem()
+	tempFS := vfs.NewMem()
+	d, err := Open("db", &Options{FS: mem, TempDir: "scratch", TempFS: tempFS})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	f, err := mem.Create("out.sst", vfs.WriteCategoryUnspecified)
+	require.NoError(t, err)
+	w := d.NewUnsortedWriter(objstorageprovider.NewFileWritable(f), UnsortedWriterOptions{
+		MemoryLimit: 64,
+	})
+	rng := rand.New(rand.NewPCG(0, 0))
+	expected := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		k := fmt.Sprintf("key%03d", rng.IntN(100))
+		v := fmt.Sprintf("val%d", i)
+		require.NoError(t, w.Set([]byte(k), []byte(v)))
+		expected[k] = v
+	}
+	require.NoError(t, w.Delete([]byte("key000")))
+	delete(expected, "key000")
+
+	// The keys that didn't fit in memory were spilled to temporary files.
+	m := d.Metrics()
+	require.Greater(t, m.TempFiles.Count, int64(1))
+	require.NotZero(t, m.TempFiles.Size)
+	require.NoError(t, w.Close())
+	m = d.Metrics()
+	require.Zero(t, m.TempFiles.Count)
+	require.Zero(t, m.TempFiles.Size)
+	ls, err := tempFS.List("scratch")
+	require.NoError(t, err)
+	require.Empty(t, ls)
+
+	// The output holds the last value set for each key, in order.
+	require.NoError(t, d.Ingest(context.Background(), []string{"out.sst"}))
+	iter, err := d.NewIter(nil)
+	require.NoError(t, err)
+	var n int
+	var prev []byte
+	for valid := iter.First(); valid; valid = iter.Next() {
+		require.Less(t, string(prev), string(iter.Key()))
+		require.Equal(t, expected[string(iter.Key())], string(iter.Value()))
+		prev = slices.Clone(iter.Key())
+		n++
+	}
+	require.Equal(t, len(expected), n)
+	require.NoError(t, iter.Close())
+}
@@ -0,0 +1,16 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+//go:build !linux
+
+package pebble
+
+import "time"
+
+// startThreadCPUMeasurement returns a function that returns the CPU time
+// consumed by the calling goroutine in the meantime. The CPU time of a
+// goroutine is only measured on Linux; the returned function returns zero.
+func startThreadCPUMeasurement() (stop func() time.Duration) {
+	return func() time.Duration { return 0 }
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+//go:build linux
+
+package pebble
+
+import (
+	"runtime"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// startThreadCPUMeasurement locks the calling goroutine to its OS thread, and
+// returns a function that unlocks it and returns the CPU time consumed by the
+// thread (and thus by the goroutine) in the meantime. The returned function
+// must be called by the same goroutine.
+//
+// The Go runtime doesn't account for the CPU time of goroutines, so the
+// goroutine is pinned to its thread to make the CPU time of the thread its
+// own: while locked, no other goroutine runs on the thread. The lock is held
+// until the returned function is called, e.g. for the whole duration of a
+// compaction. Meanwhile, the goroutine can only run on this thread, and the
+// thread does nothing else while the goroutine is blocked (e.g. on I/O), so
+// the runtime may start additional threads to run the other goroutines.
+// Callers should only measure long-running goroutines whose number is bounded,
+// such as those of compactions (see Options.MaxConcurrentCompactions).
+func startThreadCPUMeasurement() (stop func() time.Duration) {
+	runtime.LockOSThread()
+	start, ok := threadCPUTime()
+	return func() time.Duration {
+		end, endOK := threadCPUTime()
+		runtime.UnlockOSThread()
+		if !ok || !endOK {
+			return 0
+		}
+		return end - start
+	}
+}
+
+func threadCPUTime() (time.Duration, bool) {
+	var ru unix.Rusage
+	if err := unix.Getrusage(unix.RUSAGE_THREAD, &ru); err != nil {
+		return 0, false
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano()), true
+}
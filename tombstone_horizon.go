@@ -0,0 +1,71 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"time"
+
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// TombstoneHorizon describes the oldest state of a key span that is still
+// protected by open snapshots, as reported by DB.TombstoneHorizon.
+type TombstoneHorizon struct {
+	// SeqNum is the sequence number of the oldest snapshot protecting the span.
+	// Keys shadowed by a newer key (or tombstone) with a sequence number greater
+	// than or equal to SeqNum may still be read through that snapshot. If no
+	// snapshot protects the span, SeqNum is the visible sequence number of the
+	// DB.
+	SeqNum base.SeqNum
+	// CreatedAt is the time at which the oldest snapshot protecting the span was
+	// created, or zero if no snapshot protects the span.
+	CreatedAt time.Time
+	// Protected is true if a snapshot protects the span.
+	Protected bool
+}
+
+// TombstoneHorizon returns the oldest state of the span that is still
+// protected by the open snapshots and eventually file-only snapshots. Regular
+// snapshots protect the whole keyspace; eventually file-only snapshots only
+// protect the span if it overlaps one of their key ranges. It allows external
+// garbage collection (for example, MVCC garbage collection) to coordinate with
+// the snapshots of the DB: deleting data that is older than the horizon does
+// not affect the reads through the snapshots.
+func (d *DB) TombstoneHorizon(span KeyRange) TombstoneHorizon {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var h TombstoneHorizon
+	protect := func(seqNum base.SeqNum, createdAt time.Time) {
+		if !h.Protected || seqNum < h.SeqNum {
+			h = TombstoneHorizon{SeqNum: seqNum, CreatedAt: createdAt, Protected: true}
+		}
+	}
+	// The snapshot list is sorted by sequence number, so the first regular
+	// snapshot is the oldest one. The eventually file-only snapshots in the list
+	// are considered below.
+	l := &d.mu.snapshots.snapshotList
+	for s := l.root.next; s != &l.root; s = s.next {
+		if s.efos == nil {
+			protect(s.seqNum, s.createdAt)
+			break
+		}
+	}
+	for es := range d.mu.snapshots.efos {
+		for i := range es.protectedRanges {
+			if es.protectedRanges[i].OverlapsKeyRange(d.cmp, span) {
+				protect(es.seqNum, es.createdAt)
+				break
+			}
+		}
+	}
+	if !h.Protected {
+		h.SeqNum = d.mu.versions.visibleSeqNum.Load()
+	}
+	return h
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTombstoneHorizon(t *testing.T) {
+	d, err := Open("", &Options{FS: vfs.NewMem()})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	spanA := KeyRange{Start: []byte("a"), End: []byte("b")}
+	spanX := KeyRange{Start: []byte("x"), End: []byte("y")}
+
+	require.NoError(t, d.Set([]byte("a"), []byte("1"), nil))
+	h := d.TombstoneHorizon(spanA)
+	require.False(t, h.Protected)
+	require.True(t, h.CreatedAt.IsZero())
+	require.Equal(t, d.mu.versions.visibleSeqNum.Load(), h.SeqNum)
+
+	// An eventually file-only snapshot only protects the spans overlapping its
+	// key ranges.
+	efos := d.NewEventuallyFileOnlySnapshot([]KeyRange{spanX})
+	require.NoError(t, d.Set([]byte("a"), []byte("2"), nil))
+	require.False(t, d.TombstoneHorizon(spanA).Protected)
+	h = d.TombstoneHorizon(spanX)
+	require.True(t, h.Protected)
+	require.Equal(t, efos.seqNum, h.SeqNum)
+	require.False(t, h.CreatedAt.IsZero())
+
+	// The protection remains once the snapshot transitions to a file-only
+	// snapshot.
+	require.NoError(t, d.Flush())
+	require.NoError(t, efos.WaitForFileOnlySnapshot(context.Background(), 0))
+	require.Equal(t, efos.seqNum, d.TombstoneHorizon(spanX).SeqNum)
+
+	// A regular snapshot protects every span.
+	snap := d.NewSnapshot()
+	require.NoError(t, d.Set([]byte("a"), []byte("3"), nil))
+	h = d.TombstoneHorizon(spanA)
+	require.True(t, h.Protected)
+	require.Equal(t, snap.seqNum, h.SeqNum)
+	// The older eventually file-only snapshot remains the horizon of its span.
+	require.Equal(t, efos.seqNum, d.TombstoneHorizon(spanX).SeqNum)
+
+	require.NoError(t, efos.Close())
+	require.Equal(t, snap.seqNum, d.TombstoneHorizon(spanX).SeqNum)
+	require.NoError(t, snap.Close())
+	require.False(t, d.TombstoneHorizon(spanX).Protected)
+}
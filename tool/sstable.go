@@ -16,12 +16,15 @@ import (
 	"text/tabwriter"
 
 	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/cache"
 	"github.com/cockroachdb/pebble/internal/humanize"
 	"github.com/cockroachdb/pebble/internal/keyspan"
 	"github.com/cockroachdb/pebble/internal/rangedel"
 	"github.com/cockroachdb/pebble/internal/sstableinternal"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/block"
 	"github.com/cockroachdb/pebble/vfs"
@@ -34,6 +37,7 @@ type sstableT struct {
 	Root       *cobra.Command
 	Check      *cobra.Command
 	Layout     *cobra.Command
+	Merge      *cobra.Command
 	Properties *cobra.Command
 	Scan       *cobra.Command
 	Space      *cobra.Command
@@ -51,6 +55,12 @@ type sstableT struct {
 	filter   key
 	count    int64
 	verbose  bool
+
+	// Merge flags.
+	targetFileSize  int64
+	bloomBitsPerKey int
+	elideTombstones bool
+	orderBySource   bool
 }
 
 func newSSTable(
@@ -85,6 +95,25 @@ is displayed or omitted.
 		Args: cobra.MinimumNArgs(1),
 		Run:  s.runLayout,
 	}
+	s.Merge = &cobra.Command{
+		Use:   "merge <output-dir> <sstables>",
+		Short: "merge sstables into non-overlapping sstables",
+		Long: `
+Merge the sstables into a sequence of non-overlapping sstables written to the
+output directory, without opening a DB. The keys are processed like in a
+compaction of the sstables: shadowed keys are dropped, merge operands are
+combined and the keys covered by newer tombstones are removed. The output
+sstables are printed along with their bounds and sizes.
+
+The --elide-tombstones flag drops the tombstones and zeroes the sequence
+numbers, which is only correct if no data lies beneath the sstables. The
+--order-by-source flag makes the keys of the later sstables in command line
+order shadow the keys of the earlier ones regardless of their sequence numbers,
+as needed for sstables built for ingestion.
+`,
+		Args: cobra.MinimumNArgs(2),
+		Run:  s.runMerge,
+	}
 	s.Properties = &cobra.Command{
 		Use:   "properties <sstables>",
 		Short: "print sstable properties",
@@ -117,7 +146,7 @@ inclusive-inclusive range specified by --start and --end.
 		Run:  s.runSpace,
 	}
 
-	s.Root.AddCommand(s.Check, s.Layout, s.Properties, s.Scan, s.Space)
+	s.Root.AddCommand(s.Check, s.Layout, s.Merge, s.Properties, s.Scan, s.Space)
 	s.Root.PersistentFlags().BoolVarP(&s.verbose, "verbose", "v", false, "verbose output")
 
 	s.Check.Flags().Var(
@@ -140,6 +169,16 @@ inclusive-inclusive range specified by --start and --end.
 		&s.filter, "filter", "only output records with matching prefix or overlapping range tombstones")
 	s.Scan.Flags().Int64Var(
 		&s.count, "count", 0, "key count for scan (0 is unlimited)")
+	s.Merge.Flags().Var(
+		&s.fmtKey, "key", "key formatter")
+	s.Merge.Flags().Int64Var(
+		&s.targetFileSize, "target-file-size", 64<<20, "target size of the output sstables")
+	s.Merge.Flags().IntVar(
+		&s.bloomBitsPerKey, "bloom-bits-per-key", 0, "bits per key of the bloom filter of the output sstables (0 is no filter)")
+	s.Merge.Flags().BoolVar(
+		&s.elideTombstones, "elide-tombstones", false, "drop the tombstones and zero the sequence numbers")
+	s.Merge.Flags().BoolVar(
+		&s.orderBySource, "order-by-source", false, "order the keys by sstable instead of by sequence number")
 
 	return s
 }
@@ -236,6 +275,121 @@ func (s *sstableT) runLayout(cmd *cobra.Command, args []string) {
 	})
 }
 
+func (s *sstableT) runMerge(cmd *cobra.Command, args []string) {
+	stdout, stderr := cmd.OutOrStdout(), cmd.OutOrStderr()
+	outDir := args[0]
+
+	// Use the comparer and merger of the sstables, and the newest table format
+	// among them.
+	var paths []string
+	var comparer *base.Comparer
+	var mergerName string
+	var tableFormat sstable.TableFormat
+	var failed bool
+	s.foreachSstable(stderr, args[1:], func(path string, r *sstable.Reader) {
+		tf, err := r.TableFormat()
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", path, err)
+			failed = true
+			return
+		}
+		if comparer == nil {
+			comparer, mergerName = r.Comparer, r.Properties.MergerName
+		} else if comparer.Name != r.Comparer.Name {
+			fmt.Fprintf(stderr, "%s: comparer %s does not match %s\n", path, r.Comparer.Name, comparer.Name)
+			failed = true
+			return
+		}
+		tableFormat = max(tableFormat, tf)
+		paths = append(paths, path)
+	})
+	if failed || len(paths) == 0 {
+		return
+	}
+	s.fmtKey.setForComparer(comparer.Name, s.comparers)
+
+	readerOpts := s.opts.MakeReaderOptions()
+	readerOpts.Comparers = s.comparers
+	readerOpts.Mergers = s.mergers
+	if m, ok := s.mergers[mergerName]; ok {
+		readerOpts.Merger = m
+	}
+	writerOpts := sstable.WriterOptions{
+		Comparer:    comparer,
+		TableFormat: tableFormat,
+	}
+	if s.bloomBitsPerKey > 0 {
+		writerOpts.FilterPolicy = bloom.FilterPolicy(s.bloomBitsPerKey)
+	}
+
+	inputs := make([]objstorage.Readable, 0, len(paths))
+	for _, path := range paths {
+		f, err := s.opts.FS.Open(path)
+		if err == nil {
+			var readable objstorage.Readable
+			if readable, err = sstable.NewSimpleReadable(f); err == nil {
+				inputs = append(inputs, readable)
+				continue
+			}
+		}
+		fmt.Fprintf(stderr, "%s: %s\n", path, err)
+		for _, input := range inputs {
+			_ = input.Close()
+		}
+		return
+	}
+	if err := s.opts.FS.MkdirAll(outDir, 0755); err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return
+	}
+	var outputs []string
+	metas, err := pebble.CompactSSTables(context.Background(), inputs, pebble.CompactSSTablesOptions{
+		ReaderOptions:   readerOpts,
+		WriterOptions:   writerOpts,
+		TargetFileSize:  s.targetFileSize,
+		ElideTombstones: s.elideTombstones,
+		OrderBySource:   s.orderBySource,
+		NewOutput: func(i int) (objstorage.Writable, error) {
+			path := s.opts.FS.PathJoin(outDir, fmt.Sprintf("%06d.sst", i+1))
+			f, err := s.opts.FS.Create(path, vfs.WriteCategoryUnspecified)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, path)
+			return objstorageprovider.NewFileWritable(f), nil
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s\n", err)
+		return
+	}
+	for i, meta := range metas {
+		// Compute the bounds of all the keys of the output.
+		var smallest, largest *base.InternalKey
+		for _, b := range []struct {
+			ok                bool
+			smallest, largest *base.InternalKey
+		}{
+			{meta.HasPointKeys, &meta.SmallestPoint, &meta.LargestPoint},
+			{meta.HasRangeDelKeys, &meta.SmallestRangeDel, &meta.LargestRangeDel},
+			{meta.HasRangeKeys, &meta.SmallestRangeKey, &meta.LargestRangeKey},
+		} {
+			if !b.ok {
+				continue
+			}
+			if smallest == nil || base.InternalCompare(comparer.Compare, *b.smallest, *smallest) < 0 {
+				smallest = b.smallest
+			}
+			if largest == nil || base.InternalCompare(comparer.Compare, *b.largest, *largest) > 0 {
+				largest = b.largest
+			}
+		}
+		fmt.Fprintf(stdout, "%s: ", outputs[i])
+		formatKeyRange(stdout, s.fmtKey, smallest, largest)
+		fmt.Fprintf(stdout, " %d entries, %s\n", meta.Properties.NumEntries, humanize.Bytes.Uint64(meta.Size))
+	}
+}
+
 func (s *sstableT) runProperties(cmd *cobra.Command, args []string) {
 	stdout, stderr := cmd.OutOrStdout(), cmd.OutOrStderr()
 	s.foreachSstable(stderr, args, func(path string, r *sstable.Reader) {
@@ -0,0 +1,287 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+)
+
+// trimBatchSize is the size past which TrimToSize commits the batch of point
+// deletions of old versions it's building.
+const trimBatchSize = 1 << 20
+
+// TrimPolicy decides which data DB.TrimToSize deletes, and in which order.
+type TrimPolicy interface {
+	// NextTrim returns the next deletion to apply, given the current disk
+	// space usage of the DB (see Metrics.DiskSpaceUsage). It returns false
+	// once the policy has nothing left to delete.
+	NextTrim(usage uint64) (TrimAction, bool)
+}
+
+// TrimPolicyFunc adapts a function to the TrimPolicy interface.
+type TrimPolicyFunc func(usage uint64) (TrimAction, bool)
+
+// NextTrim implements TrimPolicy.
+func (f TrimPolicyFunc) NextTrim(usage uint64) (TrimAction, bool) {
+	return f(usage)
+}
+
+// TrimActions returns a TrimPolicy that applies the given actions in order.
+func TrimActions(actions ...TrimAction) TrimPolicy {
+	return TrimPolicyFunc(func(uint64) (TrimAction, bool) {
+		if len(actions) == 0 {
+			return TrimAction{}, false
+		}
+		a := actions[0]
+		actions = actions[1:]
+		return a, true
+	})
+}
+
+// TrimAction is a deletion applied by DB.TrimToSize.
+type TrimAction struct {
+	// Span is the range of keys the action deletes from. Its bounds must be
+	// prefixes (keys without a suffix).
+	Span KeyRange
+	// OlderThan, if set, restricts the action to the versions of the keys in
+	// Span with a suffix equal to or older than OlderThan; older versions are
+	// those that sort after newer ones, as ordered by
+	// Comparer.ComparePointSuffixes. Keys without a suffix are kept. If unset,
+	// all the data in Span is excised.
+	OlderThan []byte
+}
+
+// TrimResult describes the outcome of DB.TrimToSize.
+type TrimResult struct {
+	// InitialUsage and FinalUsage are the disk space usage of the DB (see
+	// Metrics.DiskSpaceUsage) before and after the trim.
+	InitialUsage uint64
+	FinalUsage   uint64
+	// Actions is the number of actions of the policy that were applied.
+	Actions int
+	// VersionsDeleted is the number of old versions deleted by the actions
+	// with TrimAction.OlderThan set.
+	VersionsDeleted int64
+}
+
+// TrimToSize deletes data according to the policy until the disk space usage
+// of the DB (see Metrics.DiskSpaceUsage) is at most targetBytes, or until the
+// policy is exhausted. It's meant for cache-like deployments with hard disk
+// quotas: the caller decides which data is expendable, and in which order.
+//
+// Each action of the policy is applied in turn, and its space is reclaimed
+// before the usage is checked again:
+//
+//   - An action without TrimAction.OlderThan excises its span (see
+//     DB.Excise), which removes the data from open snapshots too, and then
+//     rewrites the virtual tables the excise leaves of the tables that
+//     overlapped the span, so that the space of their backing tables is
+//     returned.
+//   - An action with TrimAction.OlderThan deletes the old versions in its span
+//     with point tombstones, and then compacts the span. Data pinned by open
+//     snapshots isn't reclaimed.
+//
+// Tables still referenced by open iterators or by in-progress compactions
+// aren't deleted until the iterators are closed or the compactions finish, so
+// TrimToSize may apply more actions than necessary while long-lived iterators
+// are open or other compactions are running; TrimToSize only waits for the
+// compactions it runs itself. TrimToSize returns once the target is reached,
+// even if the policy has more actions; the caller can compare
+// TrimResult.FinalUsage to targetBytes to know whether the target was reached.
+func (d *DB) TrimToSize(
+	ctx context.Context, targetBytes uint64, policy TrimPolicy,
+) (TrimResult, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return TrimResult{}, ErrReadOnly
+	}
+	var res TrimResult
+	var err error
+	if res.InitialUsage, err = d.trimDiskSpaceUsage(ctx); err != nil {
+		return res, err
+	}
+	res.FinalUsage = res.InitialUsage
+	for res.FinalUsage > targetBytes {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		a, ok := policy.NextTrim(res.FinalUsage)
+		if !ok {
+			break
+		}
+		if err := d.validateTrimSpan(a.Span); err != nil {
+			return res, err
+		}
+		if a.OlderThan == nil {
+			err = d.trimSpan(ctx, a.Span)
+		} else {
+			var n int64
+			n, err = d.trimVersions(ctx, a.Span, a.OlderThan)
+			res.VersionsDeleted += n
+		}
+		if err != nil {
+			return res, err
+		}
+		res.Actions++
+		if res.FinalUsage, err = d.trimDiskSpaceUsage(ctx); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// validateTrimSpan returns an error if the span of a TrimAction is empty, or
+// if its bounds aren't prefixes.
+func (d *DB) validateTrimSpan(span KeyRange) error {
+	for _, k := range [2][]byte{span.Start, span.End} {
+		if d.opts.Comparer.Split(k) != len(k) {
+			return errors.Errorf("pebble: trim span bound %s is not a prefix", d.opts.Comparer.FormatKey(k))
+		}
+	}
+	if d.cmp(span.Start, span.End) >= 0 {
+		return errors.Errorf("pebble: trim span start %s is not less than end %s",
+			d.opts.Comparer.FormatKey(span.Start), d.opts.Comparer.FormatKey(span.End))
+	}
+	return nil
+}
+
+// trimDiskSpaceUsage returns the disk space usage of the DB, once the tables
+// made obsolete so far have been deleted. The collection of table stats
+// references the tables of older versions, so it waits for it to finish first.
+func (d *DB) trimDiskSpaceUsage(ctx context.Context) (uint64, error) {
+	d.mu.Lock()
+	err := d.trimWaitLocked(ctx, &d.mu.tableStats.cond, func() bool {
+		return !d.mu.tableStats.loading
+	})
+	d.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	d.cleanupManager.Wait()
+	m := d.Metrics()
+	return m.DiskSpaceUsage(), nil
+}
+
+// trimWaitLocked waits on cond, whose lock is d.mu, until done returns true or
+// ctx is canceled.
+//
+// d.mu must be held when calling this, but the mutex may be dropped and
+// re-acquired during the course of this method.
+func (d *DB) trimWaitLocked(ctx context.Context, cond *sync.Cond, done func() bool) error {
+	stop := context.AfterFunc(ctx, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		cond.Broadcast()
+	})
+	defer stop()
+	for !done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.closed.Load(); err != nil {
+			return err.(error)
+		}
+		cond.Wait()
+	}
+	return nil
+}
+
+// trimSpan excises span, and rewrites the virtual tables the excise leaves of
+// the tables that overlapped it.
+func (d *DB) trimSpan(ctx context.Context, span KeyRange) error {
+	backings := make(map[base.DiskFileNum]struct{})
+	d.mu.Lock()
+	v := d.mu.versions.currentVersion()
+	for level := range v.Levels {
+		for m := range v.Overlaps(level, span.UserKeyBounds()).All() {
+			backings[m.FileBacking.DiskFileNum] = struct{}{}
+		}
+	}
+	d.mu.Unlock()
+
+	if err := d.Excise(ctx, span); err != nil {
+		return err
+	}
+	findRemnants := func(v *version) (bool, [numLevels][]*tableMetadata, error) {
+		var found bool
+		var files [numLevels][]*tableMetadata
+		for level := range v.Levels {
+			for m := range v.Levels[level].All() {
+				if _, ok := backings[m.FileBacking.DiskFileNum]; ok && m.Virtual {
+					files[level] = append(files[level], m)
+					found = true
+				}
+			}
+		}
+		return found, files, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.markFilesLocked(findRemnants); err != nil {
+		return err
+	}
+	// Wait for the rewrites of the remnants only, rather than for all the files
+	// marked for compaction.
+	d.maybeScheduleCompaction()
+	return d.trimWaitLocked(ctx, &d.mu.compact.cond, func() bool {
+		found, _, _ := findRemnants(d.mu.versions.currentVersion())
+		return !found
+	})
+}
+
+// trimVersions deletes the versions of the keys in span with a suffix equal to
+// or older than olderThan, and compacts the span. It returns the number of
+// versions deleted.
+func (d *DB) trimVersions(ctx context.Context, span KeyRange, olderThan []byte) (int64, error) {
+	iter, err := d.NewIterWithContext(ctx, &IterOptions{LowerBound: span.Start, UpperBound: span.End})
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	b := d.NewBatch()
+	commit := func() error {
+		if b.Empty() {
+			return nil
+		}
+		err := b.Commit(NoSync)
+		b.Reset()
+		return err
+	}
+	for valid := iter.First(); valid; valid = iter.Next() {
+		key := iter.Key()
+		i := d.opts.Comparer.Split(key)
+		if i == len(key) || d.opts.Comparer.ComparePointSuffixes(key[i:], olderThan) < 0 {
+			continue
+		}
+		if err := b.Delete(key, nil); err != nil {
+			return n, errors.CombineErrors(err, iter.Close())
+		}
+		n++
+		if b.Len() >= trimBatchSize {
+			if err := commit(); err != nil {
+				return n, errors.CombineErrors(err, iter.Close())
+			}
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return n, err
+	}
+	if err := commit(); err != nil {
+		return n, err
+	}
+	if err := b.Close(); err != nil {
+		return n, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, d.compactRange(ctx, span.Start, span.End, false /* parallelize */, nil /* onLevel */)
+}
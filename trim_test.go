@@ -0,0 +1,111 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimToSize(t *testing.T) {
+	opts := &Options{
+		FS:                 vfs.NewMem(),
+		Comparer:           testkeys.Comparer,
+		FormatMajorVersion: FormatNewest,
+		// The usage is then that of the tables only.
+		DisableWAL: true,
+	}
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	rng := rand.New(rand.NewPCG(0, 0))
+	value := func() []byte {
+		v := make([]byte, 1<<10)
+		for i := range v {
+			v[i] = byte(rng.Uint32())
+		}
+		return v
+	}
+	for i := 0; i < 300; i++ {
+		for ts := 1; ts <= 3; ts++ {
+			key := []byte(fmt.Sprintf("k%03d@%d", i, ts))
+			require.NoError(t, d.Set(key, value(), NoSync))
+		}
+	}
+	require.NoError(t, d.Set([]byte("k150"), value(), NoSync))
+	require.NoError(t, d.Compact([]byte("k"), []byte("l"), false))
+	count := func(lower, upper string) int {
+		iter, err := d.NewIter(&IterOptions{LowerBound: []byte(lower), UpperBound: []byte(upper)})
+		require.NoError(t, err)
+		n := 0
+		for valid := iter.First(); valid; valid = iter.Next() {
+			n++
+		}
+		require.NoError(t, iter.Close())
+		return n
+	}
+	require.Equal(t, 901, count("k", "l"))
+	ctx := context.Background()
+
+	// Nothing is deleted if the DB already fits the target.
+	res, err := d.TrimToSize(ctx, 1<<40, TrimActions(TrimAction{Span: KeyRange{Start: []byte("k"), End: []byte("l")}}))
+	require.NoError(t, err)
+	require.Zero(t, res.Actions)
+	require.Equal(t, 901, count("k", "l"))
+
+	// The bounds of the spans must be prefixes.
+	_, err = d.TrimToSize(ctx, 0, TrimActions(TrimAction{Span: KeyRange{Start: []byte("k"), End: []byte("k150@1")}}))
+	require.ErrorContains(t, err, "is not a prefix")
+
+	// Nothing is deleted once the context is canceled.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	res, err = d.TrimToSize(canceled, 0, TrimActions(TrimAction{Span: KeyRange{Start: []byte("k"), End: []byte("l")}}))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, res.Actions)
+	require.Equal(t, 901, count("k", "l"))
+
+	// Old versions are deleted first; the newest versions and the unversioned
+	// key are kept.
+	usage := res.InitialUsage
+	policy := TrimActions(
+		TrimAction{Span: KeyRange{Start: []byte("k"), End: []byte("l")}, OlderThan: []byte("@2")},
+		TrimAction{Span: KeyRange{Start: []byte("k100"), End: []byte("k200")}},
+		TrimAction{Span: KeyRange{Start: []byte("k000"), End: []byte("k100")}},
+	)
+	res, err = d.TrimToSize(ctx, usage/2, policy)
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Actions)
+	require.Equal(t, int64(600), res.VersionsDeleted)
+	require.Equal(t, usage, res.InitialUsage)
+	require.LessOrEqual(t, res.FinalUsage, usage/2)
+	require.Equal(t, 301, count("k", "l"))
+	require.Equal(t, 1, count("k000", "k001"))
+
+	// Spans are excised, and the space of the tables they overlapped is
+	// returned.
+	usage = res.FinalUsage
+	res, err = d.TrimToSize(ctx, usage/2, policy)
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Actions)
+	require.Zero(t, res.VersionsDeleted)
+	require.LessOrEqual(t, res.FinalUsage, usage/2)
+	require.Zero(t, count("k", "k200"))
+	require.Equal(t, 100, count("k200", "l"))
+	require.Zero(t, d.Metrics().NumVirtual())
+
+	// The policy is exhausted.
+	res, err = d.TrimToSize(ctx, 0, policy)
+	require.NoError(t, err)
+	require.Zero(t, res.Actions)
+	require.Equal(t, 100, count("k200", "l"))
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// defaultUnsortedWriterMemoryLimit is the default value of
+// UnsortedWriterOptions.MemoryLimit.
+const defaultUnsortedWriterMemoryLimit = 64 << 20
+
+// UnsortedWriterOptions configures an UnsortedWriter.
+type UnsortedWriterOptions struct {
+	// WriterOptions are used to write the output sstable, as well as the
+	// sorted runs spilled to temporary files.
+	WriterOptions sstable.WriterOptions
+	// MemoryLimit is the number of bytes of keys and values buffered in memory
+	// before they're sorted and spilled to a temporary file. If zero, defaults
+	// to 64 MiB.
+	MemoryLimit int
+}
+
+// UnsortedWriter writes an sstable out of point keys added in any order, for
+// instance to build an sstable for ingestion from data that isn't sorted. Keys
+// are buffered in memory, and once the buffer reaches
+// UnsortedWriterOptions.MemoryLimit, they're sorted and spilled to a temporary
+// file in Options.TempDir. The spilled runs are merged into the output when
+// the writer is closed.
+//
+// If a user key is added several times, the last key added is kept.
+type UnsortedWriter struct {
+	d    *DB
+	dst  objstorage.Writable
+	opts UnsortedWriterOptions
+	cmp  base.Compare
+	// buf holds the keys added since the last spill, in the order they were
+	// added. bufSize is the size of their user keys and values.
+	buf     []unsortedKV
+	bufSize int
+	// runs holds the temporary files the sorted runs were spilled to, in the
+	// order they were spilled.
+	runs []*tempFile
+	err  error
+}
+
+type unsortedKV struct {
+	kind  base.InternalKeyKind
+	key   []byte
+	value []byte
+}
+
+// NewUnsortedWriter returns an UnsortedWriter that writes an sstable to dst.
+// The writer takes ownership of dst, which is finished or aborted when the
+// writer is closed.
+func (d *DB) NewUnsortedWriter(dst objstorage.Writable, opts UnsortedWriterOptions) *UnsortedWriter {
+	if opts.WriterOptions.Comparer == nil {
+		opts.WriterOptions.Comparer = d.opts.Comparer
+	}
+	if opts.MemoryLimit <= 0 {
+		opts.MemoryLimit = defaultUnsortedWriterMemoryLimit
+	}
+	return &UnsortedWriter{
+		d:    d,
+		dst:  dst,
+		opts: opts,
+		cmp:  opts.WriterOptions.Comparer.Compare,
+	}
+}
+
+// Set adds a SET key with the provided value. The writer makes copies of key
+// and value.
+func (w *UnsortedWriter) Set(key, value []byte) error {
+	return w.add(base.InternalKeyKindSet, key, value)
+}
+
+// Delete adds a DEL key. The writer makes a copy of key.
+func (w *UnsortedWriter) Delete(key []byte) error {
+	return w.add(base.InternalKeyKindDelete, key, nil)
+}
+
+func (w *UnsortedWriter) add(kind base.InternalKeyKind, key, value []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.buf = append(w.buf, unsortedKV{
+		kind:  kind,
+		key:   slices.Clone(key),
+		value: slices.Clone(value),
+	})
+	w.bufSize += len(key) + len(value)
+	if w.bufSize >= w.opts.MemoryLimit {
+		w.err = w.spill()
+	}
+	return w.err
+}
+
+// sortBuf sorts the buffered keys, keeping only the last key added for each
+// user key.
+func (w *UnsortedWriter) sortBuf() {
+	slices.SortStableFunc(w.buf, func(a, b unsortedKV) int {
+		return w.cmp(a.key, b.key)
+	})
+	j := 0
+	for i := range w.buf {
+		if i+1 < len(w.buf) && w.cmp(w.buf[i].key, w.buf[i+1].key) == 0 {
+			continue
+		}
+		w.buf[j] = w.buf[i]
+		j++
+	}
+	clear(w.buf[j:])
+	w.buf = w.buf[:j]
+}
+
+// writeBuf sorts the buffered keys and writes them to the provided writable.
+func (w *UnsortedWriter) writeBuf(dst objstorage.Writable) error {
+	w.sortBuf()
+	tw := sstable.NewWriter(dst, w.opts.WriterOptions)
+	for _, kv := range w.buf {
+		var err error
+		if kv.kind == base.InternalKeyKindDelete {
+			err = tw.Delete(kv.key)
+		} else {
+			err = tw.Set(kv.key, kv.value)
+		}
+		if err != nil {
+			_ = tw.Close()
+			return err
+		}
+	}
+	w.buf = w.buf[:0]
+	w.bufSize = 0
+	return tw.Close()
+}
+
+// spill sorts the buffered keys and writes them to a new temporary file.
+func (w *UnsortedWriter) spill() error {
+	f, err := w.d.newTempFile()
+	if err != nil {
+		return err
+	}
+	w.runs = append(w.runs, f)
+	return w.writeBuf(f.writable())
+}
+
+// Close writes the output sstable and removes the temporary files. The output
+// is aborted if the writer failed.
+func (w *UnsortedWriter) Close() (err error) {
+	defer func() {
+		for _, f := range w.runs {
+			err = firstError(err, f.Remove())
+		}
+		w.runs = nil
+	}()
+	if w.err != nil {
+		w.dst.Abort()
+		return w.err
+	}
+	if len(w.runs) == 0 {
+		// Everything fit in memory.
+		return w.writeBuf(w.dst)
+	}
+	if len(w.buf) > 0 {
+		if err := w.spill(); err != nil {
+			w.dst.Abort()
+			return err
+		}
+	}
+	srcs := make([]objstorage.Readable, 0, len(w.runs))
+	for _, f := range w.runs {
+		r, err := f.readable()
+		if err != nil {
+			for _, r := range srcs {
+				_ = r.Close()
+			}
+			w.dst.Abort()
+			return err
+		}
+		srcs = append(srcs, r)
+	}
+	mergeOpts := sstable.MergeOptions{
+		WriterOptions: w.opts.WriterOptions,
+		// Each run holds a single key per user key, and the runs are ordered
+		// by the time their keys were added.
+		Policy: sstable.MergeKeepLastSource,
+	}
+	if ks := w.opts.WriterOptions.KeySchema; ks != nil {
+		mergeOpts.ReaderOptions.KeySchemas = sstable.MakeKeySchemas(ks)
+	}
+	// Merge takes ownership of srcs and dst.
+	_, err = sstable.Merge(context.Background(), w.dst, srcs, mergeOpts)
+	return errors.Wrap(err, "pebble: merging sorted runs")
+}
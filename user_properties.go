@@ -0,0 +1,232 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import "github.com/cockroachdb/pebble/internal/manifest"
+
+// UserPropertyAggregator rolls up the table-level property of a
+// BlockPropertyCollector (the value returned by its FinishTable) across
+// tables. See Options.UserPropertyAggregators.
+//
+// For instance, an aggregator for a collector that records the minimum and
+// maximum timestamps of the keys can compute the minimum and maximum
+// timestamps of each level, and an aggregator for a collector that records
+// per-tenant byte counts can sum them up.
+type UserPropertyAggregator interface {
+	// Name returns the name of the BlockPropertyCollector whose property is
+	// aggregated.
+	Name() string
+	// Aggregate folds the property of a table into the aggregate acc, and
+	// returns the new aggregate. acc is nil for the first table. Aggregate may
+	// modify and return acc, but must not retain prop.
+	//
+	// Aggregates are computed incrementally: prop may also be the aggregate of
+	// a set of tables, which is folded into the aggregate of another set. The
+	// aggregation must therefore be associative and commutative.
+	Aggregate(acc, prop []byte) []byte
+}
+
+// UserPropertyAggregate holds the aggregates computed by a
+// UserPropertyAggregator.
+type UserPropertyAggregate struct {
+	// Levels holds the aggregate of the tables of each level, or nil if no
+	// table of the level has the property.
+	Levels [numLevels][]byte
+	// Total holds the aggregate of all the tables, or nil if no table has the
+	// property.
+	Total []byte
+}
+
+// filterUserProperties returns the user properties aggregated by aggs, with
+// the collector's short ID stripped. Only these are retained in the table
+// stats. It returns nil if no property is aggregated.
+func filterUserProperties(
+	aggs []UserPropertyAggregator, props map[string]string,
+) map[string]string {
+	var res map[string]string
+	for _, agg := range aggs {
+		// The first byte of the property is the collector's short ID. An empty
+		// property means the collector didn't produce one.
+		if prop, ok := props[agg.Name()]; ok && len(prop) > 1 {
+			if res == nil {
+				res = make(map[string]string, len(aggs))
+			}
+			res[agg.Name()] = prop[1:]
+		}
+	}
+	return res
+}
+
+// userPropertyAnnotators maintains the aggregates of
+// Options.UserPropertyAggregators incrementally, using annotations of the
+// B-trees of the levels.
+//
+// The property of a physical table is aggregated by the annotator of its
+// aggregator. A virtual table contributes the property of its backing table,
+// since properties can't be apportioned to a part of a table, and several
+// virtual tables sharing a backing table must contribute it once. The virtual
+// tables are therefore collected by backing by a separate annotator, and the
+// properties of the backings are folded into the aggregates when they're
+// read.
+type userPropertyAnnotators struct {
+	aggs     []UserPropertyAggregator
+	physical []manifest.Annotator[userPropertyAnnotation]
+	virtual  manifest.Annotator[virtualBackingTables]
+}
+
+func makeUserPropertyAnnotators(aggs []UserPropertyAggregator) userPropertyAnnotators {
+	a := userPropertyAnnotators{
+		aggs:     aggs,
+		physical: make([]manifest.Annotator[userPropertyAnnotation], len(aggs)),
+		virtual: manifest.Annotator[virtualBackingTables]{
+			Aggregator: virtualBackingTablesAggregator{},
+		},
+	}
+	for i, agg := range aggs {
+		a.physical[i].Aggregator = userPropertyAggregator{agg: agg}
+	}
+	return a
+}
+
+// aggregate computes the aggregates over the tables of v. Tables whose stats
+// haven't been loaded yet (see Options.DisableTableStats) are not included.
+func (a *userPropertyAnnotators) aggregate(v *version) map[string]UserPropertyAggregate {
+	if len(a.aggs) == 0 {
+		return nil
+	}
+	var levelVirtual [numLevels]virtualBackingTables
+	var allVirtual virtualBackingTables
+	for level := range v.Levels {
+		if v.Levels[level].NumVirtual == 0 {
+			continue
+		}
+		levelVirtual[level] = *a.virtual.LevelAnnotation(v.Levels[level])
+		for backing, f := range levelVirtual[level] {
+			if allVirtual == nil {
+				allVirtual = make(virtualBackingTables)
+			}
+			allVirtual[backing] = f
+		}
+	}
+	res := make(map[string]UserPropertyAggregate, len(a.aggs))
+	for i, agg := range a.aggs {
+		var r UserPropertyAggregate
+		for level := range v.Levels {
+			if prop := a.physical[i].LevelAnnotation(v.Levels[level]).v; prop != nil {
+				r.Levels[level] = agg.Aggregate(nil, prop)
+				r.Total = agg.Aggregate(r.Total, prop)
+			}
+			r.Levels[level] = levelVirtual[level].aggregate(agg, r.Levels[level])
+		}
+		r.Total = allVirtual.aggregate(agg, r.Total)
+		res[agg.Name()] = r
+	}
+	return res
+}
+
+// userPropertyAnnotation is the aggregate of the property of a set of
+// physical tables, or nil if none of them has the property.
+type userPropertyAnnotation struct {
+	v []byte
+}
+
+// userPropertyAggregator is a manifest.AnnotationAggregator that aggregates
+// the property of physical tables with a UserPropertyAggregator. Virtual
+// tables are skipped (see userPropertyAnnotators). The aggregate changes once
+// a table's stats are loaded, so a table's contribution is only cacheable once
+// its stats have been loaded.
+type userPropertyAggregator struct {
+	agg UserPropertyAggregator
+}
+
+func (a userPropertyAggregator) Zero(dst *userPropertyAnnotation) *userPropertyAnnotation {
+	if dst == nil {
+		return new(userPropertyAnnotation)
+	}
+	*dst = userPropertyAnnotation{}
+	return dst
+}
+
+func (a userPropertyAggregator) Accumulate(
+	f *tableMetadata, dst *userPropertyAnnotation,
+) (v *userPropertyAnnotation, cacheOK bool) {
+	if f.Virtual {
+		return dst, true
+	}
+	if !f.StatsValid() {
+		return dst, false
+	}
+	if prop, ok := f.Stats.UserProperties[a.agg.Name()]; ok {
+		dst.v = a.agg.Aggregate(dst.v, []byte(prop))
+	}
+	return dst, true
+}
+
+func (a userPropertyAggregator) Merge(
+	src *userPropertyAnnotation, dst *userPropertyAnnotation,
+) *userPropertyAnnotation {
+	if src.v != nil {
+		dst.v = a.agg.Aggregate(dst.v, src.v)
+	}
+	return dst
+}
+
+// virtualBackingTables maps the backings of a set of virtual tables to one of
+// the virtual tables using them whose stats have been loaded.
+type virtualBackingTables map[*fileBacking]*tableMetadata
+
+// aggregate folds the property of each backing into acc.
+func (t virtualBackingTables) aggregate(agg UserPropertyAggregator, acc []byte) []byte {
+	for _, f := range t {
+		if prop, ok := f.Stats.UserProperties[agg.Name()]; ok {
+			acc = agg.Aggregate(acc, []byte(prop))
+		}
+	}
+	return acc
+}
+
+// virtualBackingTablesAggregator is a manifest.AnnotationAggregator that
+// collects the backings of virtual tables.
+type virtualBackingTablesAggregator struct{}
+
+func (virtualBackingTablesAggregator) Zero(dst *virtualBackingTables) *virtualBackingTables {
+	if dst == nil {
+		return new(virtualBackingTables)
+	}
+	clear(*dst)
+	return dst
+}
+
+func (virtualBackingTablesAggregator) Accumulate(
+	f *tableMetadata, dst *virtualBackingTables,
+) (v *virtualBackingTables, cacheOK bool) {
+	if !f.Virtual {
+		return dst, true
+	}
+	if !f.StatsValid() {
+		return dst, false
+	}
+	if *dst == nil {
+		*dst = make(virtualBackingTables)
+	}
+	if _, ok := (*dst)[f.FileBacking]; !ok {
+		(*dst)[f.FileBacking] = f
+	}
+	return dst, true
+}
+
+func (virtualBackingTablesAggregator) Merge(
+	src *virtualBackingTables, dst *virtualBackingTables,
+) *virtualBackingTables {
+	if len(*src) > 0 && *dst == nil {
+		*dst = make(virtualBackingTables, len(*src))
+	}
+	for backing, f := range *src {
+		if _, ok := (*dst)[backing]; !ok {
+			(*dst)[backing] = f
+		}
+	}
+	return dst
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/cockroachdb/pebble/internal/testkeys"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// suffixIntervalAggregator aggregates the suffix intervals recorded by the
+// testkeys block property collector into the [lower, upper) interval that
+// contains all of them, encoded like the property as the lower bound and the
+// length of the interval.
+type suffixIntervalAggregator struct{}
+
+func (suffixIntervalAggregator) Name() string { return "pebble.internal.testkeys.suffixes" }
+
+func (suffixIntervalAggregator) Aggregate(acc, prop []byte) []byte {
+	// The property holds the lower bound and the length of the interval.
+	lower, n := binary.Uvarint(prop)
+	length, _ := binary.Uvarint(prop[n:])
+	upper := lower + length
+	if acc != nil {
+		accLower, n := binary.Uvarint(acc)
+		accLength, _ := binary.Uvarint(acc[n:])
+		lower, upper = min(lower, accLower), max(upper, accLower+accLength)
+	}
+	acc = binary.AppendUvarint(acc[:0], lower)
+	return binary.AppendUvarint(acc, upper-lower)
+}
+
+func decodeSuffixInterval(t *testing.T, b []byte) [2]uint64 {
+	t.Helper()
+	if b == nil {
+		return [2]uint64{}
+	}
+	lower, n := binary.Uvarint(b)
+	require.Greater(t, n, 0)
+	length, m := binary.Uvarint(b[n:])
+	require.Greater(t, m, 0)
+	return [2]uint64{lower, lower + length}
+}
+
+func TestUserPropertyAggregators(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:       vfs.NewMem(),
+		Logger:   testLogger{t: t},
+		Comparer: testkeys.Comparer,
+		BlockPropertyCollectors: []func() BlockPropertyCollector{
+			sstable.NewTestKeysBlockPropertyCollector,
+		},
+		UserPropertyAggregators:     []UserPropertyAggregator{suffixIntervalAggregator{}},
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	agg := d.Metrics().UserProperties["pebble.internal.testkeys.suffixes"]
+	require.Nil(t, agg.Total)
+
+	require.NoError(t, d.Set([]byte("a@3"), nil, nil))
+	require.NoError(t, d.Set([]byte("b@5"), nil, nil))
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Set([]byte("c@10"), nil, nil))
+	require.NoError(t, d.Flush())
+
+	agg = d.Metrics().UserProperties["pebble.internal.testkeys.suffixes"]
+	require.Equal(t, [2]uint64{3, 11}, decodeSuffixInterval(t, agg.Levels[0]))
+	require.Equal(t, [2]uint64{3, 11}, decodeSuffixInterval(t, agg.Total))
+	require.Nil(t, agg.Levels[6])
+
+	require.NoError(t, d.Compact([]byte("a"), []byte("d"), false /* parallelize */))
+	require.NoError(t, d.Set([]byte("d@1"), nil, nil))
+	require.NoError(t, d.Flush())
+
+	agg = d.Metrics().UserProperties["pebble.internal.testkeys.suffixes"]
+	require.Equal(t, [2]uint64{1, 2}, decodeSuffixInterval(t, agg.Levels[0]))
+	require.Equal(t, [2]uint64{3, 11}, decodeSuffixInterval(t, agg.Levels[6]))
+	require.Equal(t, [2]uint64{1, 11}, decodeSuffixInterval(t, agg.Total))
+}
+
+// keyCountCollector is a block property collector whose table-level property
+// is the number of point keys of the table, encoded as a uvarint.
+type keyCountCollector struct {
+	n uint64
+}
+
+var _ BlockPropertyCollector = (*keyCountCollector)(nil)
+
+func (c *keyCountCollector) Name() string { return "key-count" }
+
+func (c *keyCountCollector) AddPointKey(InternalKey, []byte) error {
+	c.n++
+	return nil
+}
+
+func (c *keyCountCollector) AddRangeKeys(sstable.Span) error { return nil }
+
+func (c *keyCountCollector) AddCollectedWithSuffixReplacement([]byte, []byte, []byte) error {
+	return nil
+}
+
+func (c *keyCountCollector) SupportsSuffixReplacement() bool { return false }
+
+func (c *keyCountCollector) FinishDataBlock(buf []byte) ([]byte, error) { return buf, nil }
+
+func (c *keyCountCollector) AddPrevDataBlockToIndexBlock() {}
+
+func (c *keyCountCollector) FinishIndexBlock(buf []byte) ([]byte, error) { return buf, nil }
+
+func (c *keyCountCollector) FinishTable(buf []byte) ([]byte, error) {
+	return binary.AppendUvarint(buf, c.n), nil
+}
+
+// keyCountAggregator sums up the key counts of keyCountCollector.
+type keyCountAggregator struct{}
+
+func (keyCountAggregator) Name() string { return "key-count" }
+
+func (keyCountAggregator) Aggregate(acc, prop []byte) []byte {
+	n, _ := binary.Uvarint(prop)
+	if acc != nil {
+		m, _ := binary.Uvarint(acc)
+		n += m
+	}
+	return binary.AppendUvarint(acc[:0], n)
+}
+
+func TestUserPropertyAggregatorsVirtualTables(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:     vfs.NewMem(),
+		Logger: testLogger{t: t},
+		BlockPropertyCollectors: []func() BlockPropertyCollector{
+			func() BlockPropertyCollector { return &keyCountCollector{} },
+			sstable.NewTestKeysBlockPropertyCollector,
+		},
+		UserPropertyAggregators:     []UserPropertyAggregator{keyCountAggregator{}},
+		DisableAutomaticCompactions: true,
+		FormatMajorVersion:          FormatNewest,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	count := func(b []byte) uint64 {
+		n, _ := binary.Uvarint(b)
+		return n
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, d.Set([]byte(fmt.Sprintf("k%d", i)), nil, nil))
+	}
+	require.NoError(t, d.Flush())
+	require.NoError(t, d.Compact([]byte("k"), []byte("l"), false /* parallelize */))
+	agg := d.Metrics().UserProperties["key-count"]
+	require.Equal(t, uint64(10), count(agg.Levels[6]))
+	require.Equal(t, uint64(10), count(agg.Total))
+
+	// Only the aggregated properties are retained in the table stats.
+	d.mu.Lock()
+	for f := range d.mu.versions.currentVersion().Levels[6].All() {
+		require.Equal(t, []string{"key-count"}, slices.Collect(maps.Keys(f.Stats.UserProperties)))
+	}
+	d.mu.Unlock()
+
+	// Excising the middle of the table leaves two virtual tables sharing the
+	// table as their backing, which contribute its property once.
+	require.NoError(t, d.Excise(context.Background(), KeyRange{Start: []byte("k3"), End: []byte("k6")}))
+	d.mu.Lock()
+	d.waitTableStats()
+	require.Equal(t, uint64(2), d.mu.versions.currentVersion().Levels[6].NumVirtual)
+	d.mu.Unlock()
+	agg = d.Metrics().UserProperties["key-count"]
+	require.Equal(t, uint64(10), count(agg.Levels[6]))
+	require.Equal(t, uint64(10), count(agg.Total))
+
+	// Adding a table in L0 updates the cached aggregates.
+	require.NoError(t, d.Set([]byte("k1"), nil, nil))
+	require.NoError(t, d.Flush())
+	agg = d.Metrics().UserProperties["key-count"]
+	require.Equal(t, uint64(1), count(agg.Levels[0]))
+	require.Equal(t, uint64(10), count(agg.Levels[6]))
+	require.Equal(t, uint64(11), count(agg.Total))
+}
@@ -6,6 +6,7 @@ package pebble
 
 import (
 	"cmp"
+	"fmt"
 	"slices"
 	"time"
 
@@ -15,17 +16,52 @@ import (
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/internal/manifest"
 	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/blob"
 )
 
+// valueTemperature classifies how frequently a separated value is expected to
+// be read.
+type valueTemperature uint8
+
+const (
+	// valueHot is the temperature of values that are expected to be read
+	// frequently. It's the temperature of all values when no classification is
+	// configured.
+	valueHot valueTemperature = iota
+	// valueCold is the temperature of values that are expected to be read
+	// rarely.
+	valueCold
+	numValueTemperatures
+)
+
+// String implements fmt.Stringer.
+func (t valueTemperature) String() string {
+	switch t {
+	case valueHot:
+		return "hot"
+	case valueCold:
+		return "cold"
+	default:
+		return fmt.Sprintf("unknown(%d)", t)
+	}
+}
+
 // writeNewBlobFiles implements the strategy and mechanics for separating values
 // into external blob files.
 type writeNewBlobFiles struct {
 	comparer *base.Comparer
-	// newBlobObject constructs a new blob object for use in the compaction.
-	newBlobObject      func() (objstorage.Writable, objstorage.ObjectMetadata, error)
+	// newBlobObject constructs a new blob object for use in the compaction. It's
+	// provided the temperature of the values that will be written to the blob
+	// file, so that blob files holding cold values may be placed on colder
+	// storage.
+	newBlobObject      func(valueTemperature) (objstorage.Writable, objstorage.ObjectMetadata, error)
 	shortAttrExtractor ShortAttributeExtractor
+	// classifyValue, if non-nil, classifies each separated value as hot or
+	// cold. Hot and cold values are written to separate blob files. If nil, all
+	// values are hot and at most one blob file is written per output sstable.
+	classifyValue func(userKey, value []byte) valueTemperature
 	// writerOpts is used to configure all constructed blob writers.
 	writerOpts blob.FileWriterOptions
 	// minimumSize imposes a lower bound on the size of values that can be
@@ -38,30 +74,74 @@ type writeNewBlobFiles struct {
 	// separation.
 	requiredInPlaceValueBound UserKeyPrefixBound
 
-	// Current blob writer state
-	writer  *blob.FileWriter
-	objMeta objstorage.ObjectMetadata
+	// Current blob writer state, indexed by value temperature.
+	outputs [numValueTemperatures]newBlobFileOutput
+	// numOutputs is the number of blob files created for the current output
+	// sstable. Blob files are assigned reference IDs in order of creation.
+	numOutputs int
 
 	buf []byte
 }
 
+// newWriteNewBlobFiles returns a writeNewBlobFiles that separates the values
+// of at least minimumSize bytes written by the compaction c into new blob
+// files. Values classified as cold by Options.Experimental.IsColdBlobValue are
+// written to separate blob files, created on shared storage if it's
+// configured.
+func (d *DB) newWriteNewBlobFiles(jobID JobID, c *compaction, minimumSize int) *writeNewBlobFiles {
+	vs := &writeNewBlobFiles{
+		comparer: d.opts.Comparer,
+		newBlobObject: func(temp valueTemperature) (objstorage.Writable, objstorage.ObjectMetadata, error) {
+			preferShared := remote.ShouldCreateShared(d.opts.Experimental.CreateOnShared, c.outputLevel.level)
+			if temp == valueCold && d.opts.Experimental.CreateOnShared != remote.CreateOnSharedNone {
+				preferShared = true
+			}
+			return d.newCompactionOutputObj(jobID, c, base.FileTypeBlob, preferShared)
+		},
+		shortAttrExtractor:        d.opts.Experimental.ShortAttributeExtractor,
+		writerOpts:                d.opts.MakeBlobWriterOptions(c.outputLevel.level, d.FormatMajorVersion().MaxBlobFileFormat()),
+		minimumSize:               minimumSize,
+		requiredInPlaceValueBound: d.opts.Experimental.RequiredInPlaceValueBound,
+	}
+	if isCold := d.opts.Experimental.IsColdBlobValue; isCold != nil {
+		vs.classifyValue = func(userKey, value []byte) valueTemperature {
+			if isCold(userKey, value) {
+				return valueCold
+			}
+			return valueHot
+		}
+	}
+	return vs
+}
+
+// newBlobFileOutput holds the state of a blob file being written by
+// writeNewBlobFiles.
+type newBlobFileOutput struct {
+	writer      *blob.FileWriter
+	objMeta     objstorage.ObjectMetadata
+	referenceID blob.ReferenceID
+}
+
 // Assert that *writeNewBlobFiles implements the compact.ValueSeparation interface.
 var _ compact.ValueSeparation = (*writeNewBlobFiles)(nil)
 
 // EstimatedFileSize returns an estimate of the disk space consumed by the current
-// blob file if it were closed now.
+// blob files if they were closed now.
 func (vs *writeNewBlobFiles) EstimatedFileSize() uint64 {
-	if vs.writer == nil {
-		return 0
+	var size uint64
+	for i := range vs.outputs {
+		if vs.outputs[i].writer != nil {
+			size += vs.outputs[i].writer.EstimatedSize()
+		}
 	}
-	return vs.writer.EstimatedSize()
+	return size
 }
 
 // EstimatedReferenceSize returns an estimate of the disk space consumed by the
 // current output sstable's blob references so far.
 func (vs *writeNewBlobFiles) EstimatedReferenceSize() uint64 {
-	// When we're writing to new blob files, the size of the blob file itself is
-	// a better estimate of the disk space consumed than the uncompressed value
+	// When we're writing to new blob files, the size of the blob files is a
+	// better estimate of the disk space consumed than the uncompressed value
 	// sizes.
 	return vs.EstimatedFileSize()
 }
@@ -117,33 +197,41 @@ func (vs *writeNewBlobFiles) Add(
 		}
 	}
 
-	// If we don't have an open blob writer, create one. We create blob objects
-	// lazily so that we don't create them unless a compaction will actually
-	// write to a blob file. This avoids creating and deleting empty blob files
-	// on every compaction in parts of the keyspace that a) are required to be
-	// in-place or b) have small values.
-	if vs.writer == nil {
-		writable, objMeta, err := vs.newBlobObject()
+	temperature := valueHot
+	if vs.classifyValue != nil {
+		temperature = vs.classifyValue(kv.K.UserKey, v)
+	}
+
+	// If we don't have an open blob writer for values of this temperature,
+	// create one. We create blob objects lazily so that we don't create them
+	// unless a compaction will actually write to a blob file. This avoids
+	// creating and deleting empty blob files on every compaction in parts of
+	// the keyspace that a) are required to be in-place or b) have small values.
+	out := &vs.outputs[temperature]
+	if out.writer == nil {
+		writable, objMeta, err := vs.newBlobObject(temperature)
 		if err != nil {
 			return err
 		}
-		vs.objMeta = objMeta
-		vs.writer = blob.NewFileWriter(objMeta.DiskFileNum, writable, vs.writerOpts)
+		out.objMeta = objMeta
+		out.writer = blob.NewFileWriter(objMeta.DiskFileNum, writable, vs.writerOpts)
+		out.referenceID = blob.ReferenceID(vs.numOutputs)
+		vs.numOutputs++
 	}
 
 	// Append the value to the blob file.
-	handle := vs.writer.AddValue(v)
+	handle := out.writer.AddValue(v)
 
 	// Write the key and the handle to the sstable. We need to map the
 	// blob.Handle into a blob.InlineHandle. Everything is copied verbatim,
 	// except the FileNum is translated into a reference index.
 	inlineHandle := blob.InlineHandle{
 		InlineHandlePreface: blob.InlineHandlePreface{
-			// Since we're writing blob files and maintaining a 1:1 mapping
-			// between sstables and blob files, the reference index is always 0
-			// here. Only compactions that don't rewrite blob files will produce
-			// handles with nonzero reference indices.
-			ReferenceID: 0,
+			// Since we're writing new blob files, the reference index is the
+			// index of the blob file among those created for the current
+			// output sstable. Only compactions that don't rewrite blob files
+			// will produce handles referencing other blob files.
+			ReferenceID: out.referenceID,
 			ValueLen:    handle.ValueLen,
 		},
 		HandleSuffix: blob.HandleSuffix{
@@ -154,33 +242,50 @@ func (vs *writeNewBlobFiles) Add(
 	return tw.AddWithBlobHandle(kv.K, inlineHandle, shortAttr, forceObsolete)
 }
 
-// FinishOutput closes the current blob file (if any). It returns the stats
-// and metadata of the now completed blob file.
+// FinishOutput closes the current blob files (if any). It returns the stats
+// and metadata of the now completed blob files.
 func (vs *writeNewBlobFiles) FinishOutput() (compact.ValueSeparationMetadata, error) {
-	if vs.writer == nil {
+	if vs.numOutputs == 0 {
 		return compact.ValueSeparationMetadata{}, nil
 	}
-	stats, err := vs.writer.Close()
+	meta := compact.ValueSeparationMetadata{
+		BlobReferences:     make(manifest.BlobReferences, vs.numOutputs),
+		BlobReferenceDepth: manifest.BlobReferenceDepth(vs.numOutputs),
+		NewBlobFiles:       make([]compact.OutputBlob, vs.numOutputs),
+	}
+	var err error
+	for i := range vs.outputs {
+		out := &vs.outputs[i]
+		if out.writer == nil {
+			continue
+		}
+		stats, closeErr := out.writer.Close()
+		out.writer = nil
+		if closeErr != nil {
+			err = errors.CombineErrors(err, closeErr)
+			continue
+		}
+		meta.BlobReferences[out.referenceID] = manifest.BlobReference{
+			FileNum:   out.objMeta.DiskFileNum,
+			ValueSize: stats.UncompressedValueBytes,
+		}
+		meta.BlobReferenceSize += stats.UncompressedValueBytes
+		meta.NewBlobFiles[out.referenceID] = compact.OutputBlob{
+			Stats:   stats,
+			ObjMeta: out.objMeta,
+			Metadata: &manifest.BlobFileMetadata{
+				FileNum:      out.objMeta.DiskFileNum,
+				Size:         stats.FileLen,
+				ValueSize:    stats.UncompressedValueBytes,
+				CreationTime: uint64(time.Now().Unix()),
+			},
+		}
+	}
+	vs.numOutputs = 0
 	if err != nil {
 		return compact.ValueSeparationMetadata{}, err
 	}
-	vs.writer = nil
-	return compact.ValueSeparationMetadata{
-		BlobReferences: manifest.BlobReferences{{
-			FileNum:   vs.objMeta.DiskFileNum,
-			ValueSize: stats.UncompressedValueBytes,
-		}},
-		BlobReferenceSize:  stats.UncompressedValueBytes,
-		BlobReferenceDepth: 1,
-		BlobFileStats:      stats,
-		BlobFileObject:     vs.objMeta,
-		BlobFileMetadata: &manifest.BlobFileMetadata{
-			FileNum:      vs.objMeta.DiskFileNum,
-			Size:         stats.FileLen,
-			ValueSize:    stats.UncompressedValueBytes,
-			CreationTime: uint64(time.Now().Unix()),
-		},
-	}, nil
+	return meta, nil
 }
 
 // preserveBlobReferences implements the compact.ValueSeparation interface. When
@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/pebble/internal/testkeys"
 	"github.com/cockroachdb/pebble/objstorage"
 	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/objstorage/remote"
 	"github.com/cockroachdb/pebble/sstable"
 	"github.com/cockroachdb/pebble/sstable/blob"
 	"github.com/cockroachdb/pebble/vfs"
@@ -36,6 +37,8 @@ func TestValueSeparationPolicy(t *testing.T) {
 		tw  sstable.RawWriter
 		fn  base.DiskFileNum
 		buf bytes.Buffer
+		// coldBlobFiles records the blob files created for cold values.
+		coldBlobFiles = make(map[base.DiskFileNum]bool)
 		// Set up a logging FS to capture the filesystem operations throughout
 		// the test. When testing a value separation policy that writes new blob
 		// files, this demonstrates the creation of new blob files and that
@@ -91,11 +94,23 @@ func TestValueSeparationPolicy(t *testing.T) {
 				case "write-new-blob-files":
 					newSep := &writeNewBlobFiles{
 						comparer: testkeys.Comparer,
-						newBlobObject: func() (objstorage.Writable, objstorage.ObjectMetadata, error) {
+						newBlobObject: func(temp valueTemperature) (objstorage.Writable, objstorage.ObjectMetadata, error) {
 							fn++
+							if temp == valueCold {
+								coldBlobFiles[fn] = true
+							}
 							return objStore.Create(ctx, base.FileTypeBlob, fn, objstorage.CreateOptions{})
 						},
 					}
+					if cmdArg, ok := d.Arg("cold-prefix"); ok {
+						coldPrefix := []byte(cmdArg.SingleVal(t))
+						newSep.classifyValue = func(userKey, value []byte) valueTemperature {
+							if bytes.HasPrefix(userKey, coldPrefix) {
+								return valueCold
+							}
+							return valueHot
+						}
+					}
 					d.MaybeScanArgs(t, "minimum-size", &newSep.minimumSize)
 					if cmdArg, ok := d.Arg("required-in-place"); ok {
 						cmdArg.ExpectNumVals(t, 2)
@@ -138,11 +153,16 @@ func TestValueSeparationPolicy(t *testing.T) {
 
 				meta, err := vs.FinishOutput()
 				require.NoError(t, err)
-				if meta.BlobFileObject.DiskFileNum == 0 {
+				if len(meta.NewBlobFiles) == 0 {
 					fmt.Fprintln(&buf, "no blob file created")
-				} else {
-					fmt.Fprintf(&buf, "Blob file created: %s\n", meta.BlobFileMetadata)
-					fmt.Fprintln(&buf, meta.BlobFileStats)
+				}
+				for _, b := range meta.NewBlobFiles {
+					if coldBlobFiles[b.ObjMeta.DiskFileNum] {
+						fmt.Fprintf(&buf, "Cold blob file created: %s\n", b.Metadata)
+					} else {
+						fmt.Fprintf(&buf, "Blob file created: %s\n", b.Metadata)
+					}
+					fmt.Fprintln(&buf, b.Stats)
 				}
 				if len(meta.BlobReferences) == 0 {
 					fmt.Fprintln(&buf, "blobrefs:[]")
@@ -161,6 +181,44 @@ func TestValueSeparationPolicy(t *testing.T) {
 		})
 }
 
+func TestWriteNewBlobFilesPlacement(t *testing.T) {
+	var opts Options
+	opts.FS = vfs.NewMem()
+	opts.Experimental.RemoteStorage = remote.MakeSimpleFactory(map[remote.Locator]remote.Storage{
+		"": remote.NewInMem(),
+	})
+	opts.Experimental.CreateOnShared = remote.CreateOnSharedTiered
+	opts.Experimental.IsColdBlobValue = func(userKey, value []byte) bool {
+		return bytes.HasPrefix(userKey, []byte("cold"))
+	}
+	opts.Logger = testLogger{t}
+	d, err := Open("", &opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+	require.NoError(t, d.SetCreatorID(1))
+
+	c := &compaction{kind: compactionKindDefault, outputLevel: &compactionLevel{level: 6}}
+	vs := d.newWriteNewBlobFiles(d.newJobID(), c, 0 /* minimumSize */)
+	require.Equal(t, valueCold, vs.classifyValue([]byte("cold-a"), nil))
+	require.Equal(t, valueHot, vs.classifyValue([]byte("hot-a"), nil))
+
+	// Tables are created locally until they're tiered, but the blob files
+	// holding cold values are created on shared storage right away.
+	for _, tc := range []struct {
+		temp   valueTemperature
+		remote bool
+	}{
+		{temp: valueHot, remote: false},
+		{temp: valueCold, remote: true},
+	} {
+		w, meta, err := vs.newBlobObject(tc.temp)
+		require.NoError(t, err)
+		require.Equal(t, base.FileTypeBlob, meta.FileType)
+		require.Equal(t, tc.remote, meta.IsRemote(), "temperature %s", tc.temp)
+		w.Abort()
+	}
+}
+
 // loggingRawWriter wraps a sstable.RawWriter and logs calls to Add and
 // AddWithBlobHandle to provide observability into the separation of values into
 // blob files.
@@ -7,6 +7,8 @@ package pebble
 import (
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -24,6 +26,10 @@ const numLevels = manifest.NumLevels
 
 const manifestMarkerName = `manifest`
 
+// rocksDBCurrentFilename is the name of the file that names the current
+// manifest of a RocksDB DB.
+const rocksDBCurrentFilename = `CURRENT`
+
 // Provide type aliases for the various manifest structs.
 type bulkVersionEdit = manifest.BulkVersionEdit
 type deletedFileEntry = manifest.DeletedTableEntry
@@ -59,6 +65,9 @@ type versionSet struct {
 	// Immutable fields.
 	dirname  string
 	provider objstorage.Provider
+	// inlineTables is set if provider serves the tables stored inline in the
+	// MANIFEST.
+	inlineTables *inlineTableProvider
 	// Set to DB.mu.
 	mu   *sync.Mutex
 	opts *Options
@@ -112,6 +121,12 @@ type versionSet struct {
 	// the next version.
 	virtualBackings manifest.VirtualBackings
 
+	// namedSnapshots maps the names of the named snapshots recorded in the
+	// MANIFEST to their sequence numbers. It is modified under DB.mu and the log
+	// lock, once the version edit that creates or releases a named snapshot is
+	// written.
+	namedSnapshots map[string]base.SeqNum
+
 	// minUnflushedLogNum is the smallest WAL log file number corresponding to
 	// mutations that have not been flushed to an sstable.
 	minUnflushedLogNum base.DiskFileNum
@@ -153,6 +168,7 @@ func (vs *versionSet) init(
 ) {
 	vs.dirname = dirname
 	vs.provider = provider
+	vs.inlineTables, _ = provider.(*inlineTableProvider)
 	vs.mu = mu
 	vs.writerCond.L = mu
 	vs.opts = opts
@@ -236,6 +252,7 @@ func (vs *versionSet) load(
 
 	// Read the versionEdits in the manifest file.
 	var bve bulkVersionEdit
+	var inlineTables map[base.DiskFileNum][]byte
 	bve.AddedTablesByFileNum = make(map[base.FileNum]*tableMetadata)
 	manifestFile, err := vs.fs.Open(manifestPath)
 	if err != nil {
@@ -273,6 +290,13 @@ func (vs *versionSet) load(
 		if err := bve.Accumulate(&ve); err != nil {
 			return err
 		}
+		for _, t := range ve.InlineTables {
+			if inlineTables == nil {
+				inlineTables = make(map[base.DiskFileNum][]byte)
+			}
+			inlineTables[t.FileNum] = t.Data
+		}
+		vs.applyNamedSnapshots(&ve)
 		if ve.MinUnflushedLogNum != 0 {
 			vs.minUnflushedLogNum = ve.MinUnflushedLogNum
 		}
@@ -349,6 +373,22 @@ func (vs *versionSet) load(
 	vs.l0Organizer.InitCompactingFileInfo(nil /* in-progress compactions */)
 	vs.append(newVersion)
 
+	// Register the contents of the live inline tables. The contents of the
+	// tables that were removed later in the manifest are dropped.
+	if len(inlineTables) > 0 {
+		if vs.inlineTables == nil {
+			return errors.Errorf("pebble: manifest file %q for DB %q contains inline tables",
+				errors.Safe(manifestFilename), dirname)
+		}
+		live := make(map[base.DiskFileNum]struct{})
+		vs.addLiveFileNums(live)
+		for fileNum, data := range inlineTables {
+			if _, ok := live[fileNum]; ok {
+				vs.inlineTables.add(fileNum, data)
+			}
+		}
+	}
+
 	for i := range vs.metrics.Levels {
 		l := &vs.metrics.Levels[i]
 		l.NumFiles = int64(newVersion.Levels[i].Len())
@@ -671,6 +711,7 @@ func (vs *versionSet) logAndApply(
 
 	// Install the new version.
 	vs.append(newVersion)
+	vs.applyNamedSnapshots(ve)
 
 	if ve.MinUnflushedLogNum != 0 {
 		vs.minUnflushedLogNum = ve.MinUnflushedLogNum
@@ -876,6 +917,14 @@ func (vs *versionSet) incrementCompactions(
 		vs.metrics.Compact.Count++
 		vs.metrics.Compact.CopyCount++
 
+	case compactionKindTiering:
+		vs.metrics.Compact.Count++
+		vs.metrics.Compact.TieringCount++
+
+	case compactionKindSuggested:
+		vs.metrics.Compact.Count++
+		vs.metrics.Compact.SuggestedCount++
+
 	default:
 		if invariants.Enabled {
 			panic("unhandled compaction kind")
@@ -886,6 +935,29 @@ func (vs *versionSet) incrementCompactions(
 	}
 }
 
+// recordCompactionUsage aggregates the resources used by a completed
+// compaction into the compaction metrics.
+func (vs *versionSet) recordCompactionUsage(c *compaction, u CompactionUsage) {
+	levels := CompactionLevels{StartLevel: -1, OutputLevel: -1}
+	if c.startLevel != nil {
+		levels.StartLevel = c.startLevel.level
+	}
+	if c.outputLevel != nil {
+		levels.OutputLevel = c.outputLevel.level
+	}
+	m := &vs.metrics.Compact
+	if m.UsageByLevels == nil {
+		m.UsageByLevels = make(map[CompactionLevels]CompactionUsage)
+		m.UsageByReason = make(map[string]CompactionUsage)
+	}
+	byLevels := m.UsageByLevels[levels]
+	byLevels.Add(u)
+	m.UsageByLevels[levels] = byLevels
+	byReason := m.UsageByReason[c.kind.String()]
+	byReason.Add(u)
+	m.UsageByReason[c.kind.String()] = byReason
+}
+
 func (vs *versionSet) incrementCompactionBytes(numBytes int64) {
 	vs.atomicInProgressBytes.Add(numBytes)
 }
@@ -934,6 +1006,13 @@ func (vs *versionSet) createManifest(
 
 	snapshot.CreatedBackingTables = virtualBackings
 
+	// The contents of the live inline tables must be carried over to the new
+	// manifest.
+	snapshot.InlineTables = vs.liveInlineTables(virtualBackings)
+
+	// As are the named snapshots that haven't been released.
+	snapshot.NamedSnapshots = vs.liveNamedSnapshots()
+
 	// When creating a version snapshot for an existing DB, this snapshot VersionEdit will be
 	// immediately followed by another VersionEdit (being written in logAndApply()). That
 	// VersionEdit always contains a LastSeqNum, so we don't need to include that in the snapshot.
@@ -1129,6 +1208,35 @@ func findCurrentManifest(
 	return marker, manifestNum, true, nil
 }
 
+// findRocksDBManifest returns the manifest named by the CURRENT file of a
+// RocksDB DB. RocksDB (like Pebble before the introduction of the manifest
+// marker) names the current manifest in the CURRENT file, followed by a
+// newline.
+func findRocksDBManifest(
+	fs vfs.FS, dirname string, ls []string,
+) (manifestNum base.DiskFileNum, exists bool, err error) {
+	if !slices.Contains(ls, rocksDBCurrentFilename) {
+		return 0, false, nil
+	}
+	f, err := fs.Open(fs.PathJoin(dirname, rocksDBCurrentFilename))
+	if err != nil {
+		return 0, false, err
+	}
+	data, err := io.ReadAll(f)
+	if err = errors.CombineErrors(err, f.Close()); err != nil {
+		return 0, false, err
+	}
+	filename, ok := strings.CutSuffix(string(data), "\n")
+	if !ok {
+		return 0, false, base.CorruptionErrorf("pebble: CURRENT file %q is malformed", errors.Safe(data))
+	}
+	fileType, manifestNum, ok := base.ParseFilename(fs, filename)
+	if !ok || fileType != base.FileTypeManifest {
+		return 0, false, base.CorruptionErrorf("pebble: MANIFEST name %q is malformed", errors.Safe(filename))
+	}
+	return manifestNum, true, nil
+}
+
 func newFileMetrics(newFiles []manifest.NewTableEntry) map[int]*LevelMetrics {
 	m := map[int]*LevelMetrics{}
 	for _, nf := range newFiles {
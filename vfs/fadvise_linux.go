@@ -17,3 +17,9 @@ func fadviseRandom(f uintptr) error {
 func fadviseSequential(f uintptr) error {
 	return unix.Fadvise(int(f), 0, 0, unix.FADV_SEQUENTIAL)
 }
+
+// Calls Fadvise with FADV_DONTNEED to allow the OS to drop the provided range
+// of a file descriptor from its page cache.
+func fadviseDontNeed(f uintptr, offset, length int64) error {
+	return unix.Fadvise(int(f), offset, length, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package vfs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/crlib/crtime"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// IOFileType classifies files for the purpose of I/O instrumentation.
+type IOFileType uint8
+
+const (
+	// IOFileTypeWAL is the type of write-ahead log files.
+	IOFileTypeWAL IOFileType = iota
+	// IOFileTypeTable is the type of sstables.
+	IOFileTypeTable
+	// IOFileTypeManifest is the type of MANIFEST files.
+	IOFileTypeManifest
+	// IOFileTypeBlob is the type of blob files.
+	IOFileTypeBlob
+	// IOFileTypeOther is the type of all other files (e.g. OPTIONS files and
+	// directories).
+	IOFileTypeOther
+	// NumIOFileTypes is the number of IOFileTypes.
+	NumIOFileTypes
+)
+
+// String implements fmt.Stringer.
+func (t IOFileType) String() string {
+	switch t {
+	case IOFileTypeWAL:
+		return "wal"
+	case IOFileTypeTable:
+		return "sstable"
+	case IOFileTypeManifest:
+		return "manifest"
+	case IOFileTypeBlob:
+		return "blob"
+	default:
+		return "other"
+	}
+}
+
+// IOFileTypeForName returns the type of the file with the given base name,
+// according to the naming conventions of Pebble's files.
+func IOFileTypeForName(name string) IOFileType {
+	switch {
+	case strings.HasSuffix(name, ".log"):
+		return IOFileTypeWAL
+	case strings.HasSuffix(name, ".sst"):
+		return IOFileTypeTable
+	case strings.HasPrefix(name, "MANIFEST-"):
+		return IOFileTypeManifest
+	case strings.HasSuffix(name, ".blob"):
+		return IOFileTypeBlob
+	default:
+		return IOFileTypeOther
+	}
+}
+
+var (
+	// IOLatencyBuckets are the prometheus histogram buckets of the latency
+	// histograms of an InstrumentedFS, in nanoseconds.
+	IOLatencyBuckets = prometheus.ExponentialBucketsRange(float64(time.Microsecond), float64(10*time.Second), 50)
+	// IOSizeBuckets are the prometheus histogram buckets of the size histograms
+	// of an InstrumentedFS, in bytes.
+	IOSizeBuckets = prometheus.ExponentialBuckets(512, 2, 16)
+)
+
+// IOFileMetrics contains histograms of the I/O operations performed on the
+// files of a single type. The number of operations of each kind, and so the
+// IOPS, can be derived from the sample counts of the latency histograms.
+type IOFileMetrics struct {
+	// ReadLatency is the latency of Read and ReadAt calls, in nanoseconds.
+	ReadLatency prometheus.Histogram
+	// ReadBytes is the number of bytes read by Read and ReadAt calls.
+	ReadBytes prometheus.Histogram
+	// WriteLatency is the latency of Write and WriteAt calls, in nanoseconds.
+	WriteLatency prometheus.Histogram
+	// WriteBytes is the number of bytes written by Write and WriteAt calls.
+	WriteBytes prometheus.Histogram
+	// SyncLatency is the latency of Sync, SyncData and SyncTo calls, in
+	// nanoseconds.
+	SyncLatency prometheus.Histogram
+}
+
+// IOMetrics contains the I/O metrics recorded by an InstrumentedFS, indexed by
+// IOFileType.
+type IOMetrics [NumIOFileTypes]IOFileMetrics
+
+// InstrumentedFS is an FS that records the latency and size of the reads,
+// writes and syncs performed on its files, per file type. See
+// WithInstrumentation.
+type InstrumentedFS struct {
+	FS
+	metrics IOMetrics
+}
+
+var _ FS = (*InstrumentedFS)(nil)
+
+// WithInstrumentation wraps an FS and records histograms of the I/O operations
+// performed on the files it opens or creates. The files are classified by type
+// according to their names (see IOFileTypeForName).
+//
+// When the FS passed in Options.FS is an InstrumentedFS, the metrics are also
+// exposed through pebble's Metrics.
+func WithInstrumentation(fs FS) *InstrumentedFS {
+	ifs := &InstrumentedFS{FS: fs}
+	for t := range ifs.metrics {
+		m := &ifs.metrics[t]
+		m.ReadLatency = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOLatencyBuckets})
+		m.ReadBytes = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOSizeBuckets})
+		m.WriteLatency = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOLatencyBuckets})
+		m.WriteBytes = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOSizeBuckets})
+		m.SyncLatency = prometheus.NewHistogram(prometheus.HistogramOpts{Buckets: IOLatencyBuckets})
+	}
+	return ifs
+}
+
+// Metrics returns the metrics recorded by the FS. The histograms are shared
+// with the FS, and continue to be updated.
+func (fs *InstrumentedFS) Metrics() *IOMetrics {
+	return &fs.metrics
+}
+
+// Unwrap returns the wrapped FS.
+func (fs *InstrumentedFS) Unwrap() FS {
+	return fs.FS
+}
+
+func (fs *InstrumentedFS) wrap(f File, name string) File {
+	return &instrumentedFile{
+		File:    f,
+		metrics: &fs.metrics[IOFileTypeForName(fs.PathBase(name))],
+	}
+}
+
+// Create implements FS.Create.
+func (fs *InstrumentedFS) Create(name string, category DiskWriteCategory) (File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil {
+		return nil, err
+	}
+	return fs.wrap(f, name), nil
+}
+
+// Open implements FS.Open.
+func (fs *InstrumentedFS) Open(name string, opts ...OpenOption) (File, error) {
+	f, err := fs.FS.Open(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fs.wrap(f, name), nil
+}
+
+// OpenReadWrite implements FS.OpenReadWrite.
+func (fs *InstrumentedFS) OpenReadWrite(
+	name string, category DiskWriteCategory, opts ...OpenOption,
+) (File, error) {
+	f, err := fs.FS.OpenReadWrite(name, category, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fs.wrap(f, name), nil
+}
+
+// OpenDir implements FS.OpenDir.
+func (fs *InstrumentedFS) OpenDir(name string) (File, error) {
+	f, err := fs.FS.OpenDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedFile{File: f, metrics: &fs.metrics[IOFileTypeOther]}, nil
+}
+
+// ReuseForWrite implements FS.ReuseForWrite.
+func (fs *InstrumentedFS) ReuseForWrite(
+	oldname, newname string, category DiskWriteCategory,
+) (File, error) {
+	f, err := fs.FS.ReuseForWrite(oldname, newname, category)
+	if err != nil {
+		return nil, err
+	}
+	return fs.wrap(f, newname), nil
+}
+
+type instrumentedFile struct {
+	File
+	metrics *IOFileMetrics
+}
+
+var _ File = (*instrumentedFile)(nil)
+
+func (f *instrumentedFile) Read(p []byte) (int, error) {
+	start := crtime.NowMono()
+	n, err := f.File.Read(p)
+	f.metrics.ReadLatency.Observe(float64(start.Elapsed()))
+	f.metrics.ReadBytes.Observe(float64(n))
+	return n, err
+}
+
+func (f *instrumentedFile) ReadAt(p []byte, off int64) (int, error) {
+	start := crtime.NowMono()
+	n, err := f.File.ReadAt(p, off)
+	f.metrics.ReadLatency.Observe(float64(start.Elapsed()))
+	f.metrics.ReadBytes.Observe(float64(n))
+	return n, err
+}
+
+func (f *instrumentedFile) Write(p []byte) (int, error) {
+	start := crtime.NowMono()
+	n, err := f.File.Write(p)
+	f.metrics.WriteLatency.Observe(float64(start.Elapsed()))
+	f.metrics.WriteBytes.Observe(float64(n))
+	return n, err
+}
+
+func (f *instrumentedFile) WriteAt(p []byte, off int64) (int, error) {
+	start := crtime.NowMono()
+	n, err := f.File.WriteAt(p, off)
+	f.metrics.WriteLatency.Observe(float64(start.Elapsed()))
+	f.metrics.WriteBytes.Observe(float64(n))
+	return n, err
+}
+
+func (f *instrumentedFile) Sync() error {
+	start := crtime.NowMono()
+	err := f.File.Sync()
+	f.metrics.SyncLatency.Observe(float64(start.Elapsed()))
+	return err
+}
+
+func (f *instrumentedFile) SyncData() error {
+	start := crtime.NowMono()
+	err := f.File.SyncData()
+	f.metrics.SyncLatency.Observe(float64(start.Elapsed()))
+	return err
+}
+
+func (f *instrumentedFile) SyncTo(length int64) (fullSync bool, err error) {
+	start := crtime.NowMono()
+	fullSync, err = f.File.SyncTo(length)
+	f.metrics.SyncLatency.Observe(float64(start.Elapsed()))
+	return fullSync, err
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package vfs
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	io_prometheus_client "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIOFileTypeForName(t *testing.T) {
+	for name, expected := range map[string]IOFileType{
+		"000001.log":      IOFileTypeWAL,
+		"000001-002.log":  IOFileTypeWAL,
+		"000002.sst":      IOFileTypeTable,
+		"MANIFEST-000003": IOFileTypeManifest,
+		"000004.blob":     IOFileTypeBlob,
+		"OPTIONS-000005":  IOFileTypeOther,
+		"LOCK":            IOFileTypeOther,
+	} {
+		require.Equal(t, expected, IOFileTypeForName(name), name)
+	}
+}
+
+func TestInstrumentedFS(t *testing.T) {
+	fs := WithInstrumentation(NewMem())
+	samples := func(h prometheus.Histogram) (count uint64, sum float64) {
+		var m io_prometheus_client.Metric
+		require.NoError(t, h.Write(&m))
+		return m.Histogram.GetSampleCount(), m.Histogram.GetSampleSum()
+	}
+
+	f, err := fs.Create("000001.log", WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("world"), 5)
+	require.NoError(t, err)
+	require.NoError(t, f.Sync())
+	require.NoError(t, f.Close())
+
+	f, err = fs.Open("000001.log")
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = f.ReadAt(buf, 2)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	m := fs.Metrics()[IOFileTypeWAL]
+	count, sum := samples(m.WriteBytes)
+	require.EqualValues(t, 2, count)
+	require.EqualValues(t, 10, sum)
+	count, _ = samples(m.WriteLatency)
+	require.EqualValues(t, 2, count)
+	count, _ = samples(m.SyncLatency)
+	require.EqualValues(t, 1, count)
+	count, sum = samples(m.ReadBytes)
+	require.EqualValues(t, 1, count)
+	require.EqualValues(t, 4, sum)
+
+	// Operations on other file types are recorded separately.
+	count, _ = samples(fs.Metrics()[IOFileTypeTable].WriteBytes)
+	require.Zero(t, count)
+	f, err = fs.Create("000002.sst", WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("table"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	count, _ = samples(fs.Metrics()[IOFileTypeTable].WriteBytes)
+	require.EqualValues(t, 1, count)
+	count, _ = samples(m.WriteBytes)
+	require.EqualValues(t, 2, count)
+}
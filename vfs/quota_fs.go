@@ -0,0 +1,305 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package vfs
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrQuotaExceeded is returned by the files of a QuotaFS when a write would
+// exceed the FS's budget. It wraps syscall.ENOSPC, so that it is handled like
+// an out-of-disk-space error (e.g. by OnDiskFull).
+var ErrQuotaExceeded = errors.Wrap(syscall.ENOSPC, "vfs: disk quota exceeded")
+
+// QuotaFS is an FS that tracks the bytes used by the files written through it,
+// per directory, and enforces a budget on their total. See WithQuota.
+type QuotaFS struct {
+	FS
+	budget int64
+
+	mu struct {
+		sync.Mutex
+		used int64
+		// files maps the paths of the tracked files to their entries.
+		files map[string]*quotaEntry
+		// dirs maps directories to the bytes used by the tracked files within
+		// them.
+		dirs map[string]int64
+	}
+}
+
+type quotaEntry struct {
+	dir  string
+	size int64
+	// removed is set once the file is removed. The writes to files that remain
+	// open after being removed are not accounted for.
+	removed bool
+}
+
+var _ FS = (*QuotaFS)(nil)
+
+// WithQuota wraps an FS and enforces a budget of budgetBytes on the total size
+// of the files it writes: a write that would grow the files beyond the budget
+// fails with ErrQuotaExceeded. Files that exist before they are written to
+// through the QuotaFS are not accounted for, unless their directory is passed
+// to TrackDir. Hard links don't use additional space, so the files created
+// through Link are not tracked.
+//
+// GetDiskUsage reports the budget as the total disk space, and the unused
+// budget (or the space available on the wrapped FS, if lower) as the available
+// disk space. When the QuotaFS is used as the Options.FS of a DB, the DB's
+// LowDiskSpace events and Options.MinFreeSpaceForWrites are therefore relative
+// to the budget.
+func WithQuota(fs FS, budgetBytes int64) *QuotaFS {
+	qfs := &QuotaFS{FS: fs, budget: budgetBytes}
+	qfs.mu.files = make(map[string]*quotaEntry)
+	qfs.mu.dirs = make(map[string]int64)
+	return qfs
+}
+
+// TrackDir starts accounting for the existing files in the given directory
+// (non-recursively). Files that are already tracked are ignored.
+func (fs *QuotaFS) TrackDir(dir string) error {
+	ls, err := fs.FS.List(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range ls {
+		path := fs.PathJoin(dir, name)
+		stat, err := fs.FS.Stat(path)
+		if err != nil {
+			return err
+		}
+		if stat.IsDir() {
+			continue
+		}
+		fs.mu.Lock()
+		if _, ok := fs.mu.files[path]; !ok {
+			fs.trackLocked(path, stat.Size())
+		}
+		fs.mu.Unlock()
+	}
+	return nil
+}
+
+// BudgetBytes returns the budget of the FS.
+func (fs *QuotaFS) BudgetBytes() int64 {
+	return fs.budget
+}
+
+// UsedBytes returns the total size of the files tracked by the FS.
+func (fs *QuotaFS) UsedBytes() int64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.mu.used
+}
+
+// DirUsedBytes returns the total size of the files tracked by the FS within
+// the given directory (non-recursively).
+func (fs *QuotaFS) DirUsedBytes(dir string) int64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.mu.dirs[dir]
+}
+
+// Unwrap returns the wrapped FS.
+func (fs *QuotaFS) Unwrap() FS {
+	return fs.FS
+}
+
+func (fs *QuotaFS) trackLocked(path string, size int64) *quotaEntry {
+	e := &quotaEntry{dir: fs.PathDir(path), size: size}
+	fs.mu.files[path] = e
+	fs.addLocked(e.dir, size)
+	return e
+}
+
+func (fs *QuotaFS) untrackLocked(path string) {
+	e, ok := fs.mu.files[path]
+	if !ok {
+		return
+	}
+	delete(fs.mu.files, path)
+	e.removed = true
+	fs.addLocked(e.dir, -e.size)
+}
+
+func (fs *QuotaFS) addLocked(dir string, bytes int64) {
+	fs.mu.used += bytes
+	if fs.mu.dirs[dir] += bytes; fs.mu.dirs[dir] == 0 {
+		delete(fs.mu.dirs, dir)
+	}
+}
+
+// entry returns the entry of the file at path, tracking it if necessary.
+func (fs *QuotaFS) entry(path string) *quotaEntry {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if e, ok := fs.mu.files[path]; ok {
+		return e
+	}
+	var size int64
+	if stat, err := fs.FS.Stat(path); err == nil {
+		size = stat.Size()
+	}
+	return fs.trackLocked(path, size)
+}
+
+// grow reserves the space for the growth of the file of the given entry to
+// newSize.
+func (fs *QuotaFS) grow(e *quotaEntry, newSize int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	growth := newSize - e.size
+	if growth <= 0 || e.removed {
+		return nil
+	}
+	if fs.mu.used+growth > fs.budget {
+		return ErrQuotaExceeded
+	}
+	e.size = newSize
+	fs.addLocked(e.dir, growth)
+	return nil
+}
+
+func (fs *QuotaFS) wrap(f File, path string) File {
+	return &quotaFile{File: f, fs: fs, entry: fs.entry(path)}
+}
+
+// Create implements FS.Create.
+func (fs *QuotaFS) Create(name string, category DiskWriteCategory) (File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil {
+		return nil, err
+	}
+	// The file is truncated if it existed.
+	fs.mu.Lock()
+	fs.untrackLocked(name)
+	fs.trackLocked(name, 0)
+	fs.mu.Unlock()
+	return fs.wrap(f, name), nil
+}
+
+// OpenReadWrite implements FS.OpenReadWrite.
+func (fs *QuotaFS) OpenReadWrite(
+	name string, category DiskWriteCategory, opts ...OpenOption,
+) (File, error) {
+	f, err := fs.FS.OpenReadWrite(name, category, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return fs.wrap(f, name), nil
+}
+
+// ReuseForWrite implements FS.ReuseForWrite.
+func (fs *QuotaFS) ReuseForWrite(
+	oldname, newname string, category DiskWriteCategory,
+) (File, error) {
+	f, err := fs.FS.ReuseForWrite(oldname, newname, category)
+	if err != nil {
+		return nil, err
+	}
+	fs.rename(oldname, newname)
+	return fs.wrap(f, newname), nil
+}
+
+// Remove implements FS.Remove.
+func (fs *QuotaFS) Remove(name string) error {
+	if err := fs.FS.Remove(name); err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	fs.untrackLocked(name)
+	fs.mu.Unlock()
+	return nil
+}
+
+// RemoveAll implements FS.RemoveAll.
+func (fs *QuotaFS) RemoveAll(name string) error {
+	if err := fs.FS.RemoveAll(name); err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for path := range fs.mu.files {
+		// MemFS uses forward slashes as separators on all platforms.
+		if path == name || strings.HasPrefix(path, name+"/") ||
+			strings.HasPrefix(path, name+string(os.PathSeparator)) {
+			fs.untrackLocked(path)
+		}
+	}
+	return nil
+}
+
+// Rename implements FS.Rename.
+func (fs *QuotaFS) Rename(oldname, newname string) error {
+	if err := fs.FS.Rename(oldname, newname); err != nil {
+		return err
+	}
+	fs.rename(oldname, newname)
+	return nil
+}
+
+func (fs *QuotaFS) rename(oldname, newname string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	// A file replaced by the rename no longer uses space.
+	fs.untrackLocked(newname)
+	// Move the entry, which may be referenced by open files.
+	if e, ok := fs.mu.files[oldname]; ok {
+		delete(fs.mu.files, oldname)
+		fs.addLocked(e.dir, -e.size)
+		e.dir = fs.PathDir(newname)
+		fs.mu.files[newname] = e
+		fs.addLocked(e.dir, e.size)
+	}
+}
+
+// GetDiskUsage implements FS.GetDiskUsage.
+func (fs *QuotaFS) GetDiskUsage(path string) (DiskUsage, error) {
+	used := fs.UsedBytes()
+	usage := DiskUsage{
+		TotalBytes: uint64(fs.budget),
+		UsedBytes:  uint64(used),
+	}
+	if used < fs.budget {
+		usage.AvailBytes = uint64(fs.budget - used)
+	}
+	if inner, err := fs.FS.GetDiskUsage(path); err == nil && inner.AvailBytes < usage.AvailBytes {
+		usage.AvailBytes = inner.AvailBytes
+	}
+	return usage, nil
+}
+
+type quotaFile struct {
+	File
+	fs    *QuotaFS
+	entry *quotaEntry
+	// off is the offset of the next Write.
+	off int64
+}
+
+var _ File = (*quotaFile)(nil)
+
+func (f *quotaFile) Write(p []byte) (int, error) {
+	if err := f.fs.grow(f.entry, f.off+int64(len(p))); err != nil {
+		return 0, err
+	}
+	n, err := f.File.Write(p)
+	f.off += int64(n)
+	return n, err
+}
+
+func (f *quotaFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.fs.grow(f.entry, off+int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, off)
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package vfs
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaFS(t *testing.T) {
+	mem := NewMem()
+	require.NoError(t, mem.MkdirAll("a", 0755))
+	require.NoError(t, mem.MkdirAll("b", 0755))
+
+	// A file written before the QuotaFS is created is only accounted for once
+	// its directory is tracked.
+	f, err := mem.Create("a/existing", WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, 10))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	fs := WithQuota(mem, 100)
+	require.Equal(t, int64(0), fs.UsedBytes())
+	require.NoError(t, fs.TrackDir("a"))
+	require.Equal(t, int64(10), fs.UsedBytes())
+
+	f, err = fs.Create("b/foo", WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, 30))
+	require.NoError(t, err)
+	_, err = f.WriteAt(make([]byte, 10), 40)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), fs.UsedBytes())
+	require.Equal(t, int64(10), fs.DirUsedBytes("a"))
+	require.Equal(t, int64(50), fs.DirUsedBytes("b"))
+
+	// Overwriting existing data doesn't use additional space.
+	_, err = f.WriteAt(make([]byte, 20), 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(60), fs.UsedBytes())
+
+	usage, err := fs.GetDiskUsage("b")
+	require.NoError(t, err)
+	require.Equal(t, DiskUsage{AvailBytes: 40, TotalBytes: 100, UsedBytes: 60}, usage)
+
+	// Writes beyond the budget fail.
+	_, err = f.Write(make([]byte, 80))
+	require.True(t, errors.Is(err, ErrQuotaExceeded))
+	require.True(t, errors.Is(err, syscall.ENOSPC))
+	require.Equal(t, int64(60), fs.UsedBytes())
+	require.NoError(t, f.Close())
+
+	// Renames move the accounting between directories.
+	require.NoError(t, fs.Rename("b/foo", "a/foo"))
+	require.Equal(t, int64(60), fs.DirUsedBytes("a"))
+	require.Equal(t, int64(0), fs.DirUsedBytes("b"))
+
+	// Removing files frees their space.
+	require.NoError(t, fs.Remove("a/existing"))
+	require.Equal(t, int64(50), fs.UsedBytes())
+	require.NoError(t, fs.RemoveAll("a"))
+	require.Equal(t, int64(0), fs.UsedBytes())
+
+	f, err = fs.Create("b/bar", WriteCategoryUnspecified)
+	require.NoError(t, err)
+	_, err = f.Write(make([]byte, 100))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	usage, err = fs.GetDiskUsage("b")
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), usage.AvailBytes)
+}
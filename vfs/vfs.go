@@ -381,6 +381,19 @@ func (sequentialReadsOption) Apply(f File) {
 	}
 }
 
+// AdviseDontNeed advises the OS that the range [offset, offset+length) of the
+// file will not be accessed in the near future, by calling fadvise() with
+// POSIX_FADV_DONTNEED on Linux systems. This allows the OS to drop the range
+// from its page cache. A length of zero extends the range to the end of the
+// file. It is a no-op on other systems and for files without a file
+// descriptor.
+func AdviseDontNeed(f File, offset, length int64) error {
+	if fd := f.Fd(); fd != InvalidFd {
+		return fadviseDontNeed(fd, offset, length)
+	}
+	return nil
+}
+
 // Copy copies the contents of oldname to newname. If newname exists, it will
 // be overwritten.
 func Copy(fs FS, oldname, newname string) error {
@@ -290,6 +290,32 @@ func TestVFSGetDiskUsage(t *testing.T) {
 	require.Nil(t, err)
 }
 
+func TestAdviseDontNeed(t *testing.T) {
+	dir, err := os.MkdirTemp("", "test-advise-dont-need")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for _, fs := range []FS{Default, NewMem()} {
+		require.NoError(t, fs.MkdirAll(dir, 0755))
+		path := fs.PathJoin(dir, "file")
+		f, err := fs.Create(path, WriteCategoryUnspecified)
+		require.NoError(t, err)
+		_, err = f.Write([]byte("hello world"))
+		require.NoError(t, err)
+		require.NoError(t, f.Sync())
+		require.NoError(t, AdviseDontNeed(f, 0, 5))
+		require.NoError(t, AdviseDontNeed(f, 0, 0))
+
+		// The advice doesn't affect the file's contents.
+		buf := make([]byte, 11)
+		_, err = f.ReadAt(buf, 0)
+		require.NoError(t, err)
+		require.Equal(t, "hello world", string(buf))
+		require.NoError(t, f.Close())
+	}
+}
+
 func TestVFSCreateLinkSemantics(t *testing.T) {
 	dir, err := os.MkdirTemp("", "test-create-link")
 	require.NoError(t, err)
@@ -0,0 +1,343 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/internal/base"
+	"github.com/cockroachdb/pebble/internal/manifest"
+	"github.com/cockroachdb/pebble/sstable"
+)
+
+// VirtualTableSpec describes a virtual sstable created by
+// DB.ReplaceWithVirtualTables over the backing of an existing sstable.
+type VirtualTableSpec struct {
+	// Bounds restricts the virtual sstable to the keys of the existing sstable
+	// within the range. As with Excise, Bounds.Start and Bounds.End must be
+	// prefix keys (i.e. without a suffix).
+	Bounds KeyRange
+	// ContentPrefix and SyntheticPrefix, if set, move the keys of the virtual
+	// sstable to a different part of the keyspace: ContentPrefix is replaced
+	// with SyntheticPrefix in every key. ContentPrefix must be a prefix of
+	// every key in the existing sstable, which must be a physical sstable
+	// without a synthetic prefix. After the replacement, the keys of the
+	// virtual sstable must not overlap any other data in the DB. Setting
+	// ContentPrefix requires FormatContentPrefix.
+	ContentPrefix   []byte
+	SyntheticPrefix []byte
+}
+
+// moved returns true if the spec moves the keys to a different part of the
+// keyspace.
+func (s *VirtualTableSpec) moved() bool {
+	return len(s.ContentPrefix) > 0 || len(s.SyntheticPrefix) > 0
+}
+
+// ReplaceWithVirtualTables atomically replaces the sstable with the given file
+// number with virtual sstables over the same backing, one per spec, in the
+// same level. No data is rewritten. This allows hosts to cheaply split the
+// data of an sstable, or to move it to a different part of the keyspace,
+// without going through IngestAndExcise.
+//
+// Keys of the sstable that aren't covered by any spec are removed, including
+// from open snapshots, as with Excise. Only currently-open iterators still
+// observe the removed data. The bounds of the resulting virtual sstables must
+// not overlap each other. A spec whose bounds contain no keys of the sstable
+// does not produce a virtual sstable. ReplaceWithVirtualTables returns the
+// created virtual sstables, in the order of specs.
+//
+// ReplaceWithVirtualTables returns an error if the sstable does not exist or
+// is being compacted, in which case the caller may look up the sstable again
+// (e.g. through DB.SSTables) and retry.
+func (d *DB) ReplaceWithVirtualTables(
+	ctx context.Context, fileNum FileNum, specs []VirtualTableSpec,
+) ([]TableInfo, error) {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	if d.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	requiredVersion := FormatVirtualSSTables
+	for i := range specs {
+		s := &specs[i]
+		if !s.Bounds.Valid() || d.cmp(s.Bounds.Start, s.Bounds.End) >= 0 {
+			return nil, errors.Errorf("pebble: invalid virtual table bounds %s",
+				s.Bounds.UserKeyBounds().Format(d.opts.Comparer.FormatKey))
+		}
+		if d.opts.Comparer.Split(s.Bounds.Start) != len(s.Bounds.Start) ||
+			d.opts.Comparer.Split(s.Bounds.End) != len(s.Bounds.End) {
+			return nil, errors.New("pebble: virtual table bounds must not have a suffix")
+		}
+		if len(s.ContentPrefix) > 0 {
+			requiredVersion = max(requiredVersion, FormatContentPrefix)
+		} else if s.moved() {
+			requiredVersion = max(requiredVersion, FormatSyntheticPrefixSuffix)
+		}
+	}
+	if v := d.FormatMajorVersion(); v < requiredVersion {
+		return nil, errors.Newf(
+			"store has format major version %d; ReplaceWithVirtualTables requires at least %d",
+			v, requiredVersion,
+		)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	jobID := d.newJobIDLocked()
+	// Lock the manifest to serialize with flushes, compactions and ingestions.
+	// logAndApply unconditionally releases the manifest lock, but any earlier
+	// returns must unlock the manifest.
+	d.mu.versions.logLock()
+	ve, level, err := d.replaceWithVirtualTablesLocked(ctx, fileNum, specs)
+	if err != nil {
+		d.mu.versions.logUnlock()
+		return nil, err
+	}
+	levelMetrics := &LevelMetrics{}
+	for _, m := range ve.DeletedTables {
+		levelMetrics.NumFiles--
+		levelMetrics.Size -= int64(m.Size)
+	}
+	for i := range ve.NewTables {
+		levelMetrics.NumFiles++
+		levelMetrics.Size += int64(ve.NewTables[i].Meta.Size)
+	}
+	metrics := map[int]*LevelMetrics{level: levelMetrics}
+	if err := d.mu.versions.logAndApply(jobID, ve, metrics, false /* forceRotation */, func() []compactionInfo {
+		return d.getInProgressCompactionInfoLocked(nil)
+	}); err != nil {
+		return nil, err
+	}
+	d.updateReadStateLocked(d.opts.DebugCheck)
+	if d.scanCache != nil {
+		d.scanCache.clear()
+	}
+	// updateReadStateLocked could have generated obsolete tables, schedule a
+	// cleanup job if necessary.
+	d.deleteObsoleteFiles(jobID)
+	d.updateTableStatsLocked(ve.NewTables)
+	d.maybeScheduleCompaction()
+
+	infos := make([]TableInfo, len(ve.NewTables))
+	for i := range ve.NewTables {
+		infos[i] = ve.NewTables[i].Meta.TableInfo()
+	}
+	return infos, nil
+}
+
+// replaceWithVirtualTablesLocked builds the version edit for
+// ReplaceWithVirtualTables, returning it along with the level of the replaced
+// table. d.mu and the manifest lock must be held.
+func (d *DB) replaceWithVirtualTablesLocked(
+	ctx context.Context, fileNum FileNum, specs []VirtualTableSpec,
+) (_ *versionEdit, level int, _ error) {
+	current := d.mu.versions.currentVersion()
+	var m *tableMetadata
+	for l := range current.Levels {
+		for f := range current.Levels[l].All() {
+			if f.FileNum == fileNum {
+				m, level = f, l
+				break
+			}
+		}
+		if m != nil {
+			break
+		}
+	}
+	switch {
+	case m == nil:
+		return nil, 0, errors.Errorf("pebble: table %s not found", fileNum)
+	case m.IsCompacting():
+		return nil, 0, errors.Errorf("pebble: table %s is being compacted", fileNum)
+	}
+	// Don't remove data that an eventually file-only snapshot expects to find
+	// once it transitions.
+	mBounds := m.UserKeyBounds()
+	for s := d.mu.snapshots.root.next; s != &d.mu.snapshots.root; s = s.next {
+		if s.efos == nil || s.efos.hasTransitioned() {
+			continue
+		}
+		for i := range s.efos.protectedRanges {
+			b := s.efos.protectedRanges[i].UserKeyBounds()
+			if b.Overlaps(d.cmp, &mBounds) {
+				return nil, 0, errors.Errorf("pebble: table %s overlaps an eventually file-only snapshot", fileNum)
+			}
+		}
+	}
+
+	iters, err := d.newIters(ctx, m, &IterOptions{
+		Category: categoryIngest,
+		layer:    manifest.Level(level),
+	}, internalIterOpts{}, iterPointKeys|iterRangeDeletions|iterRangeKeys)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iters.CloseAll()
+
+	ve := &versionEdit{
+		DeletedTables: map[deletedFileEntry]*tableMetadata{
+			{Level: level, FileNum: m.FileNum}: m,
+		},
+	}
+	for i := range specs {
+		s := &specs[i]
+		var pr sstable.PrefixReplacement
+		if s.moved() {
+			if m.Virtual || m.SyntheticPrefixAndSuffix.HasPrefix() {
+				return nil, 0, errors.Errorf(
+					"pebble: cannot move keys of table %s: table is virtual or has a synthetic prefix", fileNum)
+			}
+			if !bytes.HasPrefix(m.Smallest.UserKey, s.ContentPrefix) ||
+				!bytes.HasPrefix(m.Largest.UserKey, s.ContentPrefix) {
+				return nil, 0, errors.Errorf("pebble: table %s has keys without content prefix %q", fileNum, s.ContentPrefix)
+			}
+			pr = sstable.PrefixReplacement{ContentPrefix: s.ContentPrefix, SyntheticPrefix: s.SyntheticPrefix}
+		}
+		vt := &tableMetadata{
+			Virtual:     true,
+			FileBacking: m.FileBacking,
+			FileNum:     d.mu.versions.getNextFileNum(),
+			// Note that these are loose bounds for smallest/largest seqnums, but
+			// they're sufficient for maintaining correctness.
+			SmallestSeqNum:           m.SmallestSeqNum,
+			LargestSeqNum:            m.LargestSeqNum,
+			LargestSeqNumAbsolute:    m.LargestSeqNumAbsolute,
+			SyntheticPrefixAndSuffix: m.SyntheticPrefixAndSuffix,
+			ContentPrefix:            m.ContentPrefix,
+			SuffixReplacements:       m.SuffixReplacements,
+		}
+		if s.moved() {
+			vt.SyntheticPrefixAndSuffix = sstable.MakeSyntheticPrefixAndSuffix(
+				s.SyntheticPrefix, m.SyntheticPrefixAndSuffix.Suffix())
+			vt.ContentPrefix = slices.Clone(s.ContentPrefix)
+		}
+		if err := d.extendVirtualTableBounds(iters, m, s.Bounds, s.moved(), pr, vt); err != nil {
+			return nil, 0, err
+		}
+		if !vt.HasPointKeys && !vt.HasRangeKeys {
+			// No keys of m fall within the bounds.
+			continue
+		}
+		// The size is estimated on the keys of m, before any prefix replacement.
+		smallest, largest := vt.Smallest.UserKey, vt.Largest.UserKey
+		if s.moved() {
+			smallest, largest = pr.Invert(smallest), pr.Invert(largest)
+		}
+		vt.Size, err = d.fileCache.estimateSize(m, smallest, largest)
+		if err != nil {
+			return nil, 0, err
+		}
+		if vt.Size == 0 {
+			// estimateSize can return 0 for tables that only contain range
+			// deletions or range keys. This can cause panics in places where we
+			// divide by file sizes.
+			vt.Size = 1
+		}
+		if err := vt.Validate(d.cmp, d.opts.Comparer.FormatKey); err != nil {
+			return nil, 0, err
+		}
+		vt.ValidateVirtual(m)
+
+		vtBounds := vt.UserKeyBounds()
+		for j := range ve.NewTables {
+			if ve.NewTables[j].Meta.Overlaps(d.cmp, &vtBounds) {
+				return nil, 0, errors.Errorf("pebble: virtual tables %s and %s overlap",
+					ve.NewTables[j].Meta.UserKeyBounds().Format(d.opts.Comparer.FormatKey),
+					vtBounds.Format(d.opts.Comparer.FormatKey))
+			}
+		}
+		if s.moved() {
+			if err := d.checkVirtualTableMoveLocked(current, vt); err != nil {
+				return nil, 0, err
+			}
+		}
+		ve.NewTables = append(ve.NewTables, newTableEntry{Level: level, Meta: vt})
+	}
+	if len(ve.NewTables) > 0 && !m.Virtual {
+		// If m is virtual, then its file backing is already known to the
+		// manifest. Note that there must be only one CreatedBackingTables entry
+		// per backing sstable.
+		ve.CreatedBackingTables = append(ve.CreatedBackingTables, m.FileBacking)
+	}
+	return ve, level, nil
+}
+
+// extendVirtualTableBounds extends the bounds of vt to tightly cover the keys
+// of m within bounds, after applying the prefix replacement pr if moved is
+// set. The bounds are computed as with excise: the keys at or after
+// bounds.Start are those to the right of an excise ending at bounds.Start, and
+// the keys before bounds.End are those to the left of an excise starting at
+// bounds.End.
+func (d *DB) extendVirtualTableBounds(
+	iters iterSet,
+	m *tableMetadata,
+	bounds KeyRange,
+	moved bool,
+	pr sstable.PrefixReplacement,
+	vt *tableMetadata,
+) error {
+	var left, right tableMetadata
+	if err := determineLeftTableBounds(d.cmp, m, &left, bounds.End, iters); err != nil {
+		return err
+	}
+	if err := determineRightTableBounds(d.cmp, m, &right, base.UserKeyExclusive(bounds.Start), iters); err != nil {
+		return err
+	}
+	replace := func(k base.InternalKey) base.InternalKey {
+		if moved {
+			k.UserKey = pr.Apply(k.UserKey)
+		} else {
+			// The bounds may alias the caller's KeyRange.
+			k.UserKey = slices.Clone(k.UserKey)
+		}
+		return k
+	}
+	// nonEmpty returns true if [smallest, largest] contains any key.
+	nonEmpty := func(smallest, largest base.InternalKey) bool {
+		b := base.UserKeyBoundsFromInternal(smallest, largest)
+		return b.Valid(d.cmp)
+	}
+	if left.HasPointKeys && right.HasPointKeys &&
+		nonEmpty(right.SmallestPointKey, left.LargestPointKey) {
+		vt.ExtendPointKeyBounds(d.cmp, replace(right.SmallestPointKey), replace(left.LargestPointKey))
+	}
+	if left.HasRangeKeys && right.HasRangeKeys &&
+		nonEmpty(right.SmallestRangeKey, left.LargestRangeKey) {
+		// We call ExtendRangeKeyBounds so any internal boundType fields are
+		// set correctly.
+		vt.ExtendRangeKeyBounds(d.cmp, replace(right.SmallestRangeKey), replace(left.LargestRangeKey))
+	}
+	return nil
+}
+
+// checkVirtualTableMoveLocked returns an error if the bounds of vt, a virtual
+// table whose keys are moved to a different part of the keyspace, overlap any
+// data in the memtables or the LSM. d.mu must be held.
+func (d *DB) checkVirtualTableMoveLocked(current *version, vt *tableMetadata) error {
+	bounds := vt.UserKeyBounds()
+	for l := range current.Levels {
+		if overlaps := current.Overlaps(l, bounds); !overlaps.Empty() {
+			return errors.Errorf("pebble: moved virtual table %s overlaps data in L%d",
+				bounds.Format(d.opts.Comparer.FormatKey), l)
+		}
+	}
+	for _, mem := range d.mu.mem.queue {
+		var anyOverlaps bool
+		mem.computePossibleOverlaps(func(bounded) shouldContinue {
+			anyOverlaps = true
+			return stopIteration
+		}, vt)
+		if anyOverlaps {
+			return errors.Errorf("pebble: moved virtual table %s overlaps unflushed data",
+				bounds.Format(d.opts.Comparer.FormatKey))
+		}
+	}
+	return nil
+}
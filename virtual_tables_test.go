@@ -0,0 +1,148 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceWithVirtualTables(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		FormatMajorVersion:          FormatNewest,
+		DisableAutomaticCompactions: true,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	writeTable := func(prefix string) FileNum {
+		for c := 'a'; c <= 'z'; c++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("%s%c", prefix, c)), []byte("v"), nil))
+		}
+		require.NoError(t, d.Flush())
+		tables, err := d.SSTables()
+		require.NoError(t, err)
+		var fileNum FileNum
+		for _, ti := range tables[0] {
+			fileNum = max(fileNum, ti.FileNum)
+		}
+		return fileNum
+	}
+	requireKeys := func(present bool, keys ...string) {
+		t.Helper()
+		for _, k := range keys {
+			_, closer, err := d.Get([]byte(k))
+			if !present {
+				require.ErrorIs(t, err, ErrNotFound, k)
+				continue
+			}
+			require.NoError(t, err, k)
+			require.NoError(t, closer.Close())
+		}
+	}
+	ctx := context.Background()
+
+	// Split a table, dropping the keys outside of the specs.
+	fileNum := writeTable("a/")
+	infos, err := d.ReplaceWithVirtualTables(ctx, fileNum, []VirtualTableSpec{
+		{Bounds: KeyRange{Start: []byte("a/b"), End: []byte("a/e")}},
+		{Bounds: KeyRange{Start: []byte("a/m"), End: []byte("a/p")}},
+		{Bounds: KeyRange{Start: []byte("b"), End: []byte("c")}},
+	})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "a/b", string(infos[0].Smallest.UserKey))
+	require.Equal(t, "a/d", string(infos[0].Largest.UserKey))
+	require.Equal(t, "a/m", string(infos[1].Smallest.UserKey))
+	require.Equal(t, "a/o", string(infos[1].Largest.UserKey))
+	requireKeys(true, "a/b", "a/d", "a/m", "a/o")
+	requireKeys(false, "a/a", "a/e", "a/l", "a/p", "a/z")
+
+	// The replaced table no longer exists.
+	_, err = d.ReplaceWithVirtualTables(ctx, fileNum, nil)
+	require.Error(t, err)
+
+	// Overlapping specs are rejected.
+	fileNum = writeTable("b/")
+	_, err = d.ReplaceWithVirtualTables(ctx, fileNum, []VirtualTableSpec{
+		{Bounds: KeyRange{Start: []byte("b/a"), End: []byte("b/f")}},
+		{Bounds: KeyRange{Start: []byte("b/c"), End: []byte("b/z")}},
+	})
+	require.Error(t, err)
+
+	// Moving keys on top of existing data is rejected.
+	_, err = d.ReplaceWithVirtualTables(ctx, fileNum, []VirtualTableSpec{{
+		Bounds:          KeyRange{Start: []byte("b/"), End: []byte("b0")},
+		ContentPrefix:   []byte("b/"),
+		SyntheticPrefix: []byte("a/"),
+	}})
+	require.Error(t, err)
+
+	// Move part of a table to a different prefix and keep the rest in place.
+	infos, err = d.ReplaceWithVirtualTables(ctx, fileNum, []VirtualTableSpec{
+		{Bounds: KeyRange{Start: []byte("b/a"), End: []byte("b/n")}},
+		{
+			Bounds:          KeyRange{Start: []byte("b/n"), End: []byte("b0")},
+			ContentPrefix:   []byte("b/"),
+			SyntheticPrefix: []byte("c/"),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "c/n", string(infos[1].Smallest.UserKey))
+	require.Equal(t, "c/z", string(infos[1].Largest.UserKey))
+	requireKeys(true, "b/a", "b/m", "c/n", "c/z")
+	requireKeys(false, "b/n", "b/z", "c/a", "c/m")
+
+	iter, err := d.NewIter(&IterOptions{LowerBound: []byte("c/"), UpperBound: []byte("c0")})
+	require.NoError(t, err)
+	var n int
+	for valid := iter.First(); valid; valid = iter.Next() {
+		n++
+	}
+	require.NoError(t, iter.Close())
+	require.Equal(t, 13, n)
+	require.NoError(t, d.CheckLevels(nil))
+}
+
+func TestReplaceWithVirtualTablesFormatMajorVersion(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                 vfs.NewMem(),
+		FormatMajorVersion: FormatContentPrefix - 1,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	require.NoError(t, d.Set([]byte("a/a"), []byte("v"), nil))
+	require.NoError(t, d.Flush())
+	tables, err := d.SSTables()
+	require.NoError(t, err)
+	fileNum := tables[0][0].FileNum
+
+	// A content prefix is stored in the manifest, which requires
+	// FormatContentPrefix.
+	_, err = d.ReplaceWithVirtualTables(context.Background(), fileNum, []VirtualTableSpec{{
+		Bounds:          KeyRange{Start: []byte("a/"), End: []byte("a0")},
+		ContentPrefix:   []byte("a/"),
+		SyntheticPrefix: []byte("b/"),
+	}})
+	require.Error(t, err)
+
+	require.NoError(t, d.RatchetFormatMajorVersion(FormatContentPrefix))
+	_, err = d.ReplaceWithVirtualTables(context.Background(), fileNum, []VirtualTableSpec{{
+		Bounds:          KeyRange{Start: []byte("a/"), End: []byte("a0")},
+		ContentPrefix:   []byte("a/"),
+		SyntheticPrefix: []byte("b/"),
+	}})
+	require.NoError(t, err)
+	_, closer, err := d.Get([]byte("b/a"))
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+}
@@ -16,11 +16,13 @@ import (
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/internal/invariants"
 	"github.com/cockroachdb/pebble/vfs"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// dirProber probes the primary dir, until it is confirmed to be healthy. If
-// it doesn't have enough samples, it is deemed to be unhealthy. It is only
-// used for failback to the primary.
+// dirProber probes a dir, until it is confirmed to be healthy. If it doesn't
+// have enough samples, it is deemed to be unhealthy. It is only used for
+// failback to the primary, or to a secondary that precedes the current dir in
+// the order of failover dirs.
 type dirProber struct {
 	fs vfs.FS
 	// The full path of the file to use for the probe. The probe is destructive
@@ -191,17 +193,20 @@ func (p *dirProber) getMeanMax(interval time.Duration) (time.Duration, time.Dura
 	return mean, max
 }
 
+// dirIndex is the index of a dir in failoverMonitorOptions.dirs. The primary
+// is at index 0, followed by the secondaries in failover order.
 type dirIndex int
 
 const (
 	primaryDirIndex dirIndex = iota
 	secondaryDirIndex
-	numDirIndices
 )
 
 type dirAndFileHandle struct {
 	Dir
 	vfs.File
+	// fsyncLatency, if non-nil, records the fsync latency of the dir.
+	fsyncLatency prometheus.Histogram
 }
 
 // switchableWriter is a subset of failoverWriter needed by failoverMonitor.
@@ -211,20 +216,26 @@ type switchableWriter interface {
 }
 
 type failoverMonitorOptions struct {
-	// The primary and secondary dir.
-	dirs [numDirIndices]dirAndFileHandle
+	// The primary dir, followed by the secondary dirs in failover order.
+	dirs []dirAndFileHandle
 
 	FailoverOptions
-	stopper *stopper
+	stopper       *stopper
+	eventListener EventListener
 }
 
 // failoverMonitor monitors the latency and error observed by the
-// switchableWriter, and does failover by switching the dir. It also monitors
-// the primary dir for failback.
+// switchableWriter, and does failover by switching to the next dir. It also
+// monitors the dirs preceding the current dir for failback.
 type failoverMonitor struct {
-	opts   failoverMonitorOptions
-	prober dirProber
-	mu     struct {
+	opts failoverMonitorOptions
+	// probers[i] probes dirs[i]. The probers of the dirs preceding the current
+	// dir are enabled. There is no prober for the last dir, since there is no
+	// dir to fail back from.
+	probers []dirProber
+	// forceCh is used to send forceDirRequests to the monitorLoop.
+	forceCh chan forceDirRequest
+	mu      struct {
 		sync.Mutex
 		// dirIndex and lastFailbackTime are only modified by monitorLoop. They
 		// are protected by the mutex for concurrent reads.
@@ -237,23 +248,32 @@ type failoverMonitor struct {
 		lastFailBackTime time.Time
 		// The current failoverWriter, exposed via a narrower interface.
 		writer switchableWriter
+		// forced is true if dirIndex was forced through forceDir. It is only
+		// modified by monitorLoop.
+		forced bool
 
 		// Stats.
 		dirSwitchCount              int64
 		lastAccumulateIntoDurations time.Time
 		primaryWriteDuration        time.Duration
-		secondaryWriteDuration      time.Duration
+		// secondaryWriteDuration is the duration spent writing to any of the
+		// secondary dirs.
+		secondaryWriteDuration time.Duration
 	}
 }
 
 func newFailoverMonitor(opts failoverMonitorOptions) *failoverMonitor {
 	m := &failoverMonitor{
-		opts: opts,
+		opts:    opts,
+		probers: make([]dirProber, len(opts.dirs)-1),
+		forceCh: make(chan forceDirRequest),
 	}
 	m.mu.lastAccumulateIntoDurations = opts.timeSource.now()
-	m.prober.init(opts.dirs[primaryDirIndex].FS,
-		opts.dirs[primaryDirIndex].FS.PathJoin(opts.dirs[primaryDirIndex].Dirname, "probe-file"),
-		opts.PrimaryDirProbeInterval, opts.stopper, opts.timeSource, opts.proberIterationForTesting)
+	for i := range m.probers {
+		dir := opts.dirs[i]
+		m.probers[i].init(dir.FS, dir.FS.PathJoin(dir.Dirname, "probe-file"),
+			opts.PrimaryDirProbeInterval, opts.stopper, opts.timeSource, opts.proberIterationForTesting)
+	}
 	opts.stopper.runAsync(func() {
 		m.monitorLoop(opts.stopper.shouldQuiesce())
 	})
@@ -283,7 +303,7 @@ func (m *failoverMonitor) newWriter(writerCreateFunc func(dir dirAndFileHandle)
 func (m *failoverMonitor) elevateWriteStallThresholdForFailover() bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if m.mu.dirIndex == secondaryDirIndex {
+	if m.mu.dirIndex != primaryDirIndex {
 		return true
 	}
 	intervalSinceFailedback := m.opts.timeSource.now().Sub(m.mu.lastFailBackTime)
@@ -312,6 +332,8 @@ func (m *failoverMonitor) stats() FailoverStats {
 		DirSwitchCount:         m.mu.dirSwitchCount,
 		PrimaryWriteDuration:   m.mu.primaryWriteDuration,
 		SecondaryWriteDuration: m.mu.secondaryWriteDuration,
+		ActiveDirIndex:         int(m.mu.dirIndex),
+		Forced:                 m.mu.forced,
 	}
 }
 
@@ -321,7 +343,41 @@ type lastWriterInfo struct {
 	writer                 switchableWriter
 	numSwitches            int
 	ongoingLatencyAtSwitch time.Duration
-	errorCounts            [numDirIndices]int
+	// errorCounts is indexed by dirIndex.
+	errorCounts []int
+}
+
+// nextDir returns the dir to fail over to from the given dir, which is the
+// next dir in failover order, wrapping around to the primary after the last
+// secondary. Secondaries that have seen a high number of errors with the
+// current writer are skipped. It returns false if there is no dir to fail
+// over to.
+func (m *failoverMonitor) nextDir(from dirIndex, errorCounts []int) (dirIndex, bool) {
+	// Arbitrary value.
+	const highSecondaryErrorCountThreshold = 2
+	n := dirIndex(len(m.opts.dirs))
+	for i := (from + 1) % n; i != from; i = (i + 1) % n {
+		// It is more likely that someone has misconfigured a secondary with a
+		// high number of errors e.g. wrong permissions or not enough disk
+		// space. We only remember the error history in the context of the
+		// lastWriter since an operator can fix the underlying misconfiguration.
+		if i == primaryDirIndex || errorCounts[i] < highSecondaryErrorCountThreshold {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// failbackDir returns the first dir preceding the current dir that the probes
+// have found to be healthy again, if any.
+func (m *failoverMonitor) failbackDir(cur dirIndex) (dirIndex, bool) {
+	for i := primaryDirIndex; i < cur; i++ {
+		mean, max := m.probers[i].getMeanMax(m.opts.HealthyInterval)
+		if mean < m.opts.HealthyProbeLatencyThreshold && max < m.opts.HealthyProbeLatencyThreshold {
+			return i, true
+		}
+	}
+	return 0, false
 }
 
 func (m *failoverMonitor) monitorLoop(shouldQuiesce <-chan struct{}) {
@@ -331,19 +387,38 @@ func (m *failoverMonitor) monitorLoop(shouldQuiesce <-chan struct{}) {
 	}
 	tickerCh := ticker.ch()
 	dirIndex := primaryDirIndex
+	// forced is true if the dir was forced through forceDir, in which case
+	// the switching heuristics are suspended.
+	var forced bool
 	var lastWriter lastWriterInfo
 	for {
 		select {
 		case <-shouldQuiesce:
 			ticker.stop()
-			m.prober.stop()
+			for i := range m.probers {
+				m.probers[i].stop()
+			}
 			return
+		case req := <-m.forceCh:
+			forced = req.dirIndex >= 0
+			if forced && req.dirIndex != dirIndex {
+				m.switchDir(dirIndex, req.dirIndex, "forced")
+				dirIndex = req.dirIndex
+			}
+			m.mu.Lock()
+			m.mu.forced = forced
+			m.mu.Unlock()
+			close(req.done)
+			continue
 		case <-tickerCh:
 			writerOngoingLatency, writerErr := func() (time.Duration, error) {
 				m.mu.Lock()
 				defer m.mu.Unlock()
-				if m.mu.writer != lastWriter.writer {
-					lastWriter = lastWriterInfo{writer: m.mu.writer}
+				if m.mu.writer != lastWriter.writer || lastWriter.errorCounts == nil {
+					lastWriter = lastWriterInfo{
+						writer:      m.mu.writer,
+						errorCounts: make([]int, len(m.opts.dirs)),
+					}
 				}
 				if lastWriter.writer == nil {
 					return 0, nil
@@ -351,23 +426,19 @@ func (m *failoverMonitor) monitorLoop(shouldQuiesce <-chan struct{}) {
 				return lastWriter.writer.ongoingLatencyOrErrorForCurDir()
 			}()
 			switchDir := false
-			// Arbitrary value.
-			const highSecondaryErrorCountThreshold = 2
-			// We don't consider a switch if currently using the primary dir and the
-			// secondary dir has high enough errors. It is more likely that someone
-			// has misconfigured a secondary e.g. wrong permissions or not enough
-			// disk space. We only remember the error history in the context of the
-			// lastWriter since an operator can fix the underlying misconfiguration.
+			var reason string
+			// We don't consider a failover if all the dirs we could fail over to
+			// have high enough errors (see nextDir).
 			unhealthyThreshold, failoverEnabled := m.opts.UnhealthyOperationLatencyThreshold()
+			nextDirIndex, haveNextDir := m.nextDir(dirIndex, lastWriter.errorCounts)
 
-			if !(lastWriter.errorCounts[secondaryDirIndex] >= highSecondaryErrorCountThreshold &&
-				dirIndex == primaryDirIndex) && failoverEnabled {
+			if haveNextDir && failoverEnabled && !forced {
 				// Switching heuristics. Subject to change based on real world experience.
 				if writerErr != nil {
 					// An error causes an immediate switch, since a LogWriter with an
 					// error is useless.
 					lastWriter.errorCounts[dirIndex]++
-					switchDir = true
+					switchDir, reason = true, "error"
 				} else if writerOngoingLatency > unhealthyThreshold {
 					// Arbitrary value.
 					const switchImmediatelyCountThreshold = 2
@@ -378,44 +449,21 @@ func (m *failoverMonitor) monitorLoop(shouldQuiesce <-chan struct{}) {
 					// switch.
 					if lastWriter.numSwitches < switchImmediatelyCountThreshold ||
 						writerOngoingLatency > 2*lastWriter.ongoingLatencyAtSwitch {
-						switchDir = true
+						switchDir, reason = true, "high latency"
 						lastWriter.ongoingLatencyAtSwitch = writerOngoingLatency
 					}
 					// Else high latency, but not high enough yet to motivate switch.
-				} else if dirIndex == secondaryDirIndex {
-					// The writer looks healthy. We can still switch if the writer is using the
-					// secondary dir and the primary is healthy again.
-					primaryMean, primaryMax := m.prober.getMeanMax(m.opts.HealthyInterval)
-					if primaryMean < m.opts.HealthyProbeLatencyThreshold &&
-						primaryMax < m.opts.HealthyProbeLatencyThreshold {
-						switchDir = true
-					}
+				} else if dirIndex != primaryDirIndex {
+					// The writer looks healthy. We can still switch if the writer is using a
+					// secondary dir and a preceding dir is healthy again.
+					nextDirIndex, switchDir = m.failbackDir(dirIndex)
+					reason = "failback"
 				}
 			}
 			if switchDir {
 				lastWriter.numSwitches++
-				if dirIndex == secondaryDirIndex {
-					// Switching back to primary, so don't need to probe to see if
-					// primary is healthy.
-					m.prober.disableProbing()
-					dirIndex = primaryDirIndex
-				} else {
-					m.prober.enableProbing()
-					dirIndex = secondaryDirIndex
-				}
-				dir := m.opts.dirs[dirIndex]
-				m.mu.Lock()
-				now := m.opts.timeSource.now()
-				m.accumulateDurationLocked(now)
-				m.mu.dirIndex = dirIndex
-				m.mu.dirSwitchCount++
-				if dirIndex == primaryDirIndex {
-					m.mu.lastFailBackTime = now
-				}
-				if m.mu.writer != nil {
-					m.mu.writer.switchToNewDir(dir)
-				}
-				m.mu.Unlock()
+				m.switchDir(dirIndex, nextDirIndex, reason)
+				dirIndex = nextDirIndex
 			}
 		}
 		if m.opts.monitorStateForTesting != nil {
@@ -427,6 +475,69 @@ func (m *failoverMonitor) monitorLoop(shouldQuiesce <-chan struct{}) {
 	}
 }
 
+// switchDir switches the writer from dir from to dir to, and starts or stops
+// probing the dirs preceding the new dir. It is only called by monitorLoop.
+func (m *failoverMonitor) switchDir(from, to dirIndex, reason string) {
+	// Probe the dirs preceding the new dir, to see if they are healthy
+	// again. There is no need to probe the others.
+	for i := range m.probers {
+		if wasEnabled, enabled := i < int(from), i < int(to); wasEnabled != enabled {
+			if enabled {
+				m.probers[i].enableProbing()
+			} else {
+				m.probers[i].disableProbing()
+			}
+		}
+	}
+	info := DirSwitchInfo{
+		From:        int(from),
+		To:          int(to),
+		FromDirname: m.opts.dirs[from].Dirname,
+		ToDirname:   m.opts.dirs[to].Dirname,
+		Reason:      reason,
+	}
+	dir := m.opts.dirs[to]
+	m.mu.Lock()
+	now := m.opts.timeSource.now()
+	m.accumulateDurationLocked(now)
+	m.mu.dirIndex = to
+	m.mu.dirSwitchCount++
+	if to == primaryDirIndex {
+		m.mu.lastFailBackTime = now
+	}
+	if m.mu.writer != nil {
+		m.mu.writer.switchToNewDir(dir)
+	}
+	m.mu.Unlock()
+	if m.opts.eventListener != nil {
+		m.opts.eventListener.DirSwitched(info)
+	}
+}
+
+// forceDirRequest is a request to the monitorLoop to force the dir.
+type forceDirRequest struct {
+	// dirIndex is the dir to force, or negative to resume automatic failover.
+	dirIndex dirIndex
+	// done is closed once the request has been handled.
+	done chan struct{}
+}
+
+// forceDir forces the writer to use the given dir, suspending the switching
+// heuristics, or resumes them if index is negative.
+func (m *failoverMonitor) forceDir(index int) error {
+	if index >= len(m.opts.dirs) {
+		return errors.Errorf("pebble: WAL dir index %d out of range [0,%d)", index, len(m.opts.dirs))
+	}
+	req := forceDirRequest{dirIndex: dirIndex(max(index, -1)), done: make(chan struct{})}
+	select {
+	case m.forceCh <- req:
+	case <-m.opts.stopper.shouldQuiesce():
+		return errors.New("pebble: WAL manager is closed")
+	}
+	<-req.done
+	return nil
+}
+
 type logicalLogWithSizesEtc struct {
 	num      NumWAL
 	segments []segmentWithSizeEtc
@@ -450,7 +561,7 @@ type failoverManager struct {
 
 	// TODO(jackson/sumeer): read-path etc.
 
-	dirHandles [numDirIndices]vfs.File
+	dirHandles []vfs.File
 	stopper    *stopper
 	monitor    *failoverMonitor
 	mu         struct {
@@ -478,43 +589,51 @@ func (wm *failoverManager) init(o Options, initial Logs) error {
 	}
 	o.FailoverOptions.EnsureDefaults()
 
-	// Synchronously ensure that we're able to write to the secondary before we
-	// proceed. An operator doesn't want to encounter an issue writing to the
+	// Synchronously ensure that we're able to write to the secondaries before
+	// we proceed. An operator doesn't want to encounter an issue writing to a
 	// secondary the first time there's a need to failover. We write a bit of
-	// metadata to a file in the secondary's directory.
-	f, err := o.Secondary.FS.Create(o.Secondary.FS.PathJoin(o.Secondary.Dirname, "failover_source"), "pebble-wal")
-	if err != nil {
-		return errors.Newf("failed to write to WAL secondary dir: %v", err)
-	}
-	if _, err := io.WriteString(f, fmt.Sprintf("primary: %s\nprocess start: %s\n",
-		o.Primary.Dirname,
-		time.Now(),
-	)); err != nil {
-		return errors.Newf("failed to write metadata to WAL secondary dir: %v", err)
-	}
-	if err := errors.CombineErrors(f.Sync(), f.Close()); err != nil {
-		return err
+	// metadata to a file in each secondary's directory.
+	for _, dir := range o.Dirs()[1:] {
+		f, err := dir.FS.Create(dir.FS.PathJoin(dir.Dirname, "failover_source"), "pebble-wal")
+		if err != nil {
+			return errors.Newf("failed to write to WAL secondary dir: %v", err)
+		}
+		if _, err := io.WriteString(f, fmt.Sprintf("primary: %s\nprocess start: %s\n",
+			o.Primary.Dirname,
+			time.Now(),
+		)); err != nil {
+			return errors.Newf("failed to write metadata to WAL secondary dir: %v", err)
+		}
+		if err := errors.CombineErrors(f.Sync(), f.Close()); err != nil {
+			return err
+		}
 	}
 
 	stopper := newStopper()
-	var dirs [numDirIndices]dirAndFileHandle
-	for i, dir := range []Dir{o.Primary, o.Secondary} {
+	dirs := make([]dirAndFileHandle, len(o.Dirs()))
+	dirHandles := make([]vfs.File, len(dirs))
+	for i, dir := range o.Dirs() {
 		dirs[i].Dir = dir
 		f, err := dir.FS.OpenDir(dir.Dirname)
 		if err != nil {
 			return err
 		}
 		dirs[i].File = f
+		dirHandles[i] = f
+		if i < len(o.DirFsyncLatencies) {
+			dirs[i].fsyncLatency = o.DirFsyncLatencies[i]
+		}
 	}
 	fmOpts := failoverMonitorOptions{
 		dirs:            dirs,
 		FailoverOptions: o.FailoverOptions,
 		stopper:         stopper,
+		eventListener:   o.EventListener,
 	}
 	monitor := newFailoverMonitor(fmOpts)
 	*wm = failoverManager{
 		opts:       o,
-		dirHandles: [numDirIndices]vfs.File{dirs[primaryDirIndex].File, dirs[secondaryDirIndex].File},
+		dirHandles: dirHandles,
 		stopper:    stopper,
 		monitor:    monitor,
 	}
@@ -625,6 +744,9 @@ func (wm *failoverManager) Create(wn NumWAL, jobID int) (Writer, error) {
 		bytesPerSync:                wm.opts.BytesPerSync,
 		preallocateSize:             wm.opts.PreallocateSize,
 		minSyncInterval:             wm.opts.MinSyncInterval,
+		syncInterval:                wm.opts.SyncInterval,
+		maxGroupCommitSize:          wm.opts.MaxGroupCommitSize,
+		onSynced:                    wm.opts.OnSynced,
 		fsyncLatency:                wm.opts.FsyncLatency,
 		queueSemChan:                wm.opts.QueueSemChan,
 		stopper:                     wm.stopper,
@@ -656,6 +778,11 @@ func (wm *failoverManager) ElevateWriteStallThresholdForFailover() bool {
 	return wm.monitor.elevateWriteStallThresholdForFailover()
 }
 
+// SetForcedDir implements Manager.
+func (wm *failoverManager) SetForcedDir(index int) error {
+	return wm.monitor.forceDir(index)
+}
+
 func (wm *failoverManager) writerClosed(llse logicalLogWithSizesEtc) {
 	wm.monitor.noWriter()
 	wm.mu.Lock()
@@ -669,6 +796,7 @@ func (wm *failoverManager) Stats() Stats {
 	obsoleteLogsCount, obsoleteLogSize := wm.recycler.Stats()
 	failoverStats := wm.monitor.stats()
 	failoverStats.FailoverWriteAndSyncLatency = wm.opts.FailoverWriteAndSyncLatency
+	failoverStats.DirFsyncLatencies = wm.opts.DirFsyncLatencies
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 	var liveFileCount int
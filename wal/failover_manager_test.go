@@ -297,7 +297,7 @@ func TestManagerFailover(t *testing.T) {
 	var fm *failoverManager
 	var fw *failoverWriter
 	var allowFailover bool
-	dirs := [numDirIndices]string{"pri", "sec"}
+	dirs := [2]string{"pri", "sec"}
 	datadriven.RunTest(t, "testdata/manager_failover",
 		func(t *testing.T, td *datadriven.TestData) string {
 			switch td.Cmd {
@@ -481,7 +481,7 @@ func TestManagerFailover(t *testing.T) {
 				}
 				if td.HasArg("wait-prober") {
 					recvWithDeadline(t, td, "prober", proberIterationForTesting)
-					fmt.Fprintf(&b, "prober state:%s\n", fm.monitor.prober.printStateForTesting())
+					fmt.Fprintf(&b, "prober state:%s\n", fm.monitor.probers[primaryDirIndex].printStateForTesting())
 				}
 				if td.HasArg("wait-ongoing-io") {
 					waitForOngoingLatencyOrErr(t, td, fw)
@@ -608,6 +608,152 @@ func TestFailoverManager_SecondaryIsWritable(t *testing.T) {
 	}, nil /* initial  logs */), "failed to write to WAL secondary dir: injected error")
 }
 
+func TestFailoverManager_AdditionalSecondaries(t *testing.T) {
+	memFS := vfs.NewMem()
+	for _, dir := range []string{"primary", "secondary", "tertiary"} {
+		require.NoError(t, memFS.MkdirAll(dir, os.ModePerm))
+	}
+	var m failoverManager
+	require.NoError(t, m.init(Options{
+		Primary:                     Dir{FS: memFS, Dirname: "primary"},
+		Secondary:                   Dir{FS: memFS, Dirname: "secondary"},
+		AdditionalSecondaries:       []Dir{{FS: memFS, Dirname: "tertiary"}},
+		PreallocateSize:             func() int { return 4 },
+		FailoverWriteAndSyncLatency: prometheus.NewHistogram(prometheus.HistogramOpts{}),
+	}, nil /* initial  logs */))
+	defer func() { require.NoError(t, m.Close()) }()
+	// Every secondary is checked to be writable.
+	for _, dir := range []string{"secondary", "tertiary"} {
+		_, err := memFS.Stat(memFS.PathJoin(dir, "failover_source"))
+		require.NoError(t, err)
+	}
+	// The primary and secondary are probed for failback; the tertiary is not.
+	require.Len(t, m.monitor.probers, 2)
+}
+
+type testDirSwitchListener struct {
+	mu       sync.Mutex
+	switches []DirSwitchInfo
+}
+
+func (l *testDirSwitchListener) LogCreated(CreateInfo) {}
+
+func (l *testDirSwitchListener) DirSwitched(info DirSwitchInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.switches = append(l.switches, info)
+}
+
+func TestFailoverManager_SetForcedDir(t *testing.T) {
+	memFS := vfs.NewMem()
+	for _, dir := range []string{"primary", "secondary", "tertiary"} {
+		require.NoError(t, memFS.MkdirAll(dir, os.ModePerm))
+	}
+	listener := &testDirSwitchListener{}
+	var m failoverManager
+	require.NoError(t, m.init(Options{
+		Primary:                     Dir{FS: memFS, Dirname: "primary"},
+		Secondary:                   Dir{FS: memFS, Dirname: "secondary"},
+		AdditionalSecondaries:       []Dir{{FS: memFS, Dirname: "tertiary"}},
+		PreallocateSize:             func() int { return 4 },
+		EventListener:               listener,
+		DirFsyncLatencies:           []prometheus.Histogram{nil, nil, prometheus.NewHistogram(prometheus.HistogramOpts{})},
+		FailoverWriteAndSyncLatency: prometheus.NewHistogram(prometheus.HistogramOpts{}),
+	}, nil /* initial  logs */))
+	defer func() { require.NoError(t, m.Close()) }()
+
+	require.Error(t, m.SetForcedDir(3))
+	require.NoError(t, m.SetForcedDir(2))
+	stats := m.Stats().Failover
+	require.Equal(t, 2, stats.ActiveDirIndex)
+	require.True(t, stats.Forced)
+	require.EqualValues(t, 1, stats.DirSwitchCount)
+	require.Equal(t, []DirSwitchInfo{{
+		From: 0, To: 2, FromDirname: "primary", ToDirname: "tertiary", Reason: "forced",
+	}}, listener.switches)
+
+	// New logs are written to the forced dir.
+	w, err := m.Create(NumWAL(1), 1)
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	var syncErr error
+	wg.Add(1)
+	_, err = w.WriteRecord([]byte("hello world"), SyncOptions{Done: &wg, Err: &syncErr}, nil)
+	require.NoError(t, err)
+	wg.Wait()
+	require.NoError(t, syncErr)
+	_, err = w.Close()
+	require.NoError(t, err)
+	logs := m.List()
+	require.Len(t, logs, 1)
+	require.Equal(t, "tertiary", logs[0].segments[0].dir.Dirname)
+	var metric io_prometheus_client.Metric
+	require.NoError(t, m.Stats().Failover.DirFsyncLatencies[2].Write(&metric))
+	require.NotZero(t, metric.Histogram.GetSampleCount())
+
+	require.NoError(t, m.SetForcedDir(-1))
+	stats = m.Stats().Failover
+	require.Equal(t, 2, stats.ActiveDirIndex)
+	require.False(t, stats.Forced)
+}
+
+func TestFailoverMonitorDirSelection(t *testing.T) {
+	m := &failoverMonitor{
+		opts: failoverMonitorOptions{
+			dirs: make([]dirAndFileHandle, 3),
+			FailoverOptions: FailoverOptions{
+				HealthyProbeLatencyThreshold: 25 * time.Millisecond,
+				HealthyInterval:              2 * time.Second,
+			},
+		},
+		probers: make([]dirProber, 2),
+	}
+	for i := range m.probers {
+		m.probers[i].interval = time.Second
+	}
+	errorCounts := make([]int, 3)
+	next := func(from dirIndex) string {
+		i, ok := m.nextDir(from, errorCounts)
+		if !ok {
+			return "none"
+		}
+		return fmt.Sprint(i)
+	}
+	// Fail over to the next dir in order, wrapping around to the primary.
+	require.Equal(t, "1", next(0))
+	require.Equal(t, "2", next(1))
+	require.Equal(t, "0", next(2))
+	// Secondaries with many errors are skipped.
+	errorCounts[1] = 2
+	require.Equal(t, "2", next(0))
+	errorCounts[2] = 2
+	require.Equal(t, "none", next(0))
+	require.Equal(t, "0", next(1))
+
+	// Fail back to the first preceding dir whose probes are healthy.
+	probe := func(i int, latency time.Duration) {
+		p := &m.probers[i]
+		p.mu.firstProbeIndex = 0
+		p.mu.nextProbeIndex = 2
+		p.mu.history[0], p.mu.history[1] = latency, latency
+	}
+	_, ok := m.failbackDir(2)
+	require.False(t, ok)
+	probe(1, time.Millisecond)
+	i, ok := m.failbackDir(2)
+	require.True(t, ok)
+	require.Equal(t, secondaryDirIndex, i)
+	_, ok = m.failbackDir(1)
+	require.False(t, ok)
+	probe(0, time.Millisecond)
+	i, ok = m.failbackDir(2)
+	require.True(t, ok)
+	require.Equal(t, primaryDirIndex, i)
+	probe(0, time.Second)
+	i, _ = m.failbackDir(2)
+	require.Equal(t, secondaryDirIndex, i)
+}
+
 // TODO(sumeer): test wrap around of history in dirProber.
 
 // TODO(sumeer): the failover datadriven test cases are not easy to write,
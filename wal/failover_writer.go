@@ -454,10 +454,13 @@ type failoverWriterOpts struct {
 	preallocateSize func() int
 
 	// Options for record.LogWriter.
-	minSyncInterval func() time.Duration
-	fsyncLatency    prometheus.Histogram
-	queueSemChan    chan struct{}
-	stopper         *stopper
+	minSyncInterval    func() time.Duration
+	syncInterval       time.Duration
+	maxGroupCommitSize func() int
+	onSynced           func(numSyncs int)
+	fsyncLatency       prometheus.Histogram
+	queueSemChan       chan struct{}
+	stopper            *stopper
 
 	failoverWriteAndSyncLatency prometheus.Histogram
 	writerClosed                func(logicalLogWithSizesEtc)
@@ -479,6 +482,22 @@ func simpleLogCreator(
 	return f, 0, err
 }
 
+// teeHistogram is a prometheus.Histogram that records observations in an
+// additional histogram, if non-nil. All other methods are those of the
+// embedded histogram.
+type teeHistogram struct {
+	prometheus.Histogram
+	other prometheus.Histogram
+}
+
+// Observe implements prometheus.Histogram.
+func (h teeHistogram) Observe(v float64) {
+	h.Histogram.Observe(v)
+	if h.other != nil {
+		h.other.Observe(v)
+	}
+}
+
 type logCreator func(
 	dir Dir, wn NumWAL, li LogNameIndex, r *latencyAndErrorRecorder, jobID int,
 ) (f vfs.File, initialFileSize uint64, err error)
@@ -630,10 +649,16 @@ func (ww *failoverWriter) switchToNewDir(dir dirAndFileHandle) error {
 		// map to a single NumWAL, a file used for NumWAL n at index m will
 		// never get recycled for NumWAL n at a later index (since recycling
 		// happens when n as a whole is obsolete).
+		fsyncLatency := ww.opts.fsyncLatency
+		if dir.fsyncLatency != nil {
+			fsyncLatency = teeHistogram{Histogram: dir.fsyncLatency, other: fsyncLatency}
+		}
 		w := record.NewLogWriter(recorderAndWriter, base.DiskFileNum(ww.opts.wn),
 			record.LogWriterConfig{
 				WALMinSyncInterval:        ww.opts.minSyncInterval,
-				WALFsyncLatency:           ww.opts.fsyncLatency,
+				WALSyncInterval:           ww.opts.syncInterval,
+				WALMaxGroupCommitSize:     ww.opts.maxGroupCommitSize,
+				WALFsyncLatency:           fsyncLatency,
 				QueueSemChan:              ww.opts.queueSemChan,
 				ExternalSyncQueueCallback: ww.doneSyncCallback,
 				WriteWALSyncOffsets:       ww.opts.writeWALSyncOffsets,
@@ -720,6 +745,9 @@ func (ww *failoverWriter) doneSyncCallback(doneSync record.PendingSyncIndex, err
 			<-ww.opts.queueSemChan
 		}
 	}
+	if numSyncsPopped > 0 && ww.opts.onSynced != nil {
+		ww.opts.onSynced(numSyncsPopped)
+	}
 }
 
 // ongoingLatencyOrErrorForCurDir implements switchableWriter.
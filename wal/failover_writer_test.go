@@ -39,11 +39,11 @@ const (
 func TestFailoverWriter(t *testing.T) {
 	datadriven.Walk(t, "testdata/failover_writer", func(t *testing.T, path string) {
 		memFS := vfs.NewCrashableMem()
-		dirs := [numDirIndices]dirAndFileHandle{
+		dirs := [2]dirAndFileHandle{
 			{Dir: Dir{Dirname: "pri"}},
 			{Dir: Dir{Dirname: "sec"}},
 		}
-		var testDirs [numDirIndices]dirAndFileHandle
+		var testDirs [2]dirAndFileHandle
 		for i, dir := range dirs {
 			require.NoError(t, memFS.MkdirAll(dir.Dirname, 0755))
 			f, err := memFS.OpenDir("")
@@ -52,7 +52,7 @@ func TestFailoverWriter(t *testing.T) {
 			require.NoError(t, f.Close())
 			testDirs[i].Dir = dir.Dir
 		}
-		setDirsFunc := func(t *testing.T, fs vfs.FS, dirs *[numDirIndices]dirAndFileHandle) {
+		setDirsFunc := func(t *testing.T, fs vfs.FS, dirs *[2]dirAndFileHandle) {
 			for i := range *dirs {
 				f := (*dirs)[i].File
 				if f != nil {
@@ -618,7 +618,7 @@ func TestConcurrentWritersWithManyRecords(t *testing.T) {
 	}
 	const numLogWriters = 4
 	memFS := vfs.NewCrashableMem()
-	dirs := [numDirIndices]dirAndFileHandle{{Dir: Dir{Dirname: "pri"}}, {Dir: Dir{Dirname: "sec"}}}
+	dirs := [2]dirAndFileHandle{{Dir: Dir{Dirname: "pri"}}, {Dir: Dir{Dirname: "sec"}}}
 	for _, dir := range dirs {
 		require.NoError(t, memFS.MkdirAll(dir.Dirname, 0755))
 		f, err := memFS.OpenDir("")
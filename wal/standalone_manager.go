@@ -8,6 +8,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble/internal/base"
 	"github.com/cockroachdb/pebble/record"
 	"github.com/cockroachdb/pebble/vfs"
@@ -194,10 +195,13 @@ func (m *StandaloneManager) Create(wn NumWAL, jobID int) (Writer, error) {
 		PreallocateSize: m.o.PreallocateSize(),
 	})
 	w := record.NewLogWriter(newLogFile, newLogNum, record.LogWriterConfig{
-		WALFsyncLatency:     m.o.FsyncLatency,
-		WALMinSyncInterval:  m.o.MinSyncInterval,
-		QueueSemChan:        m.o.QueueSemChan,
-		WriteWALSyncOffsets: m.o.WriteWALSyncOffsets,
+		WALFsyncLatency:       m.o.FsyncLatency,
+		WALMinSyncInterval:    m.o.MinSyncInterval,
+		WALSyncInterval:       m.o.SyncInterval,
+		WALMaxGroupCommitSize: m.o.MaxGroupCommitSize,
+		QueueSemChan:          m.o.QueueSemChan,
+		WriteWALSyncOffsets:   m.o.WriteWALSyncOffsets,
+		OnSynced:              m.o.OnSynced,
 	})
 	m.w = &standaloneWriter{
 		m: m,
@@ -214,6 +218,11 @@ func (m *StandaloneManager) ElevateWriteStallThresholdForFailover() bool {
 	return false
 }
 
+// SetForcedDir implements Manager.
+func (m *StandaloneManager) SetForcedDir(index int) error {
+	return errors.New("pebble: WAL failover is not configured")
+}
+
 // Stats implements Manager.
 func (m *StandaloneManager) Stats() Stats {
 	obsoleteLogsCount, obsoleteLogSize := m.recycler.Stats()
@@ -100,6 +100,10 @@ type Options struct {
 	// Secondary is used for failover. Optional. It must already be created and
 	// synced up to the root.
 	Secondary Dir
+	// AdditionalSecondaries are used for failover, in order, when the
+	// Secondary is unhealthy as well. Optional, and only used if Secondary is
+	// set. They must already be created and synced up to the root.
+	AdditionalSecondaries []Dir
 
 	// MinUnflushedLogNum is the smallest WAL number corresponding to
 	// mutations that have not been flushed to a sstable.
@@ -122,11 +126,22 @@ type Options struct {
 
 	// MinSyncInterval is documented in Options.WALMinSyncInterval.
 	MinSyncInterval func() time.Duration
+	// SyncInterval is documented in Options.WALSyncInterval.
+	SyncInterval time.Duration
+	// MaxGroupCommitSize is documented in Options.WALMaxGroupCommitSize.
+	MaxGroupCommitSize func() int
+	// OnSynced, if non-nil, is invoked after records written with
+	// SyncOptions.Done != nil are durably synced, with the number of such
+	// records. Records are always synced in the order in which they were
+	// written. OnSynced must not block.
+	OnSynced func(numSyncs int)
 	// FsyncLatency records fsync latency. This doesn't differentiate between
-	// fsyncs on the primary and secondary dir.
-	//
-	// TODO(sumeer): consider separating out into two histograms.
+	// fsyncs on the primary and secondary dirs; see DirFsyncLatencies.
 	FsyncLatency prometheus.Histogram
+	// DirFsyncLatencies optionally records the fsync latency of each dir in
+	// failover mode, indexed like Dirs(). Fsyncs are recorded in FsyncLatency
+	// as well.
+	DirFsyncLatencies []prometheus.Histogram
 	// QueueSemChan is the channel to pop from when popping from queued records
 	// that have requested a sync. It's original purpose was to function as a
 	// semaphore that prevents the record.LogWriter.flusher.syncQueue from
@@ -167,21 +182,24 @@ func Init(o Options, initial Logs) (Manager, error) {
 	return m, nil
 }
 
-// Dirs returns the primary Dir and the secondary if provided.
+// Dirs returns the primary Dir and the secondaries if provided, in failover
+// order.
 func (o *Options) Dirs() []Dir {
 	if o.Secondary == (Dir{}) {
 		return []Dir{o.Primary}
 	}
-	return []Dir{o.Primary, o.Secondary}
+	return append([]Dir{o.Primary, o.Secondary}, o.AdditionalSecondaries...)
 }
 
 // FailoverOptions are options that are specific to failover mode.
 type FailoverOptions struct {
 	// PrimaryDirProbeInterval is the interval for probing the primary dir, when
-	// the WAL is being written to the secondary, to decide when to fail back.
+	// the WAL is being written to a secondary, to decide when to fail back. The
+	// same interval is used for probing secondaries preceding the one being
+	// written to.
 	PrimaryDirProbeInterval time.Duration
 	// HealthyProbeLatencyThreshold is the latency threshold to declare that the
-	// primary is healthy again.
+	// primary (or a preceding secondary) is healthy again.
 	HealthyProbeLatencyThreshold time.Duration
 	// HealthyInterval is the time interval over which the probes have to be
 	// healthy. That is, we look at probe history of length
@@ -241,6 +259,22 @@ func (o *FailoverOptions) EnsureDefaults() {
 type EventListener interface {
 	// LogCreated informs the listener of a log file creation.
 	LogCreated(CreateInfo)
+	// DirSwitched informs the listener that WAL writing switched to a
+	// different dir in failover mode.
+	DirSwitched(DirSwitchInfo)
+}
+
+// DirSwitchInfo contains info about WAL writing switching to a different dir.
+type DirSwitchInfo struct {
+	// From and To are the indexes, in Options.Dirs(), of the dirs that WAL
+	// writing switched from and to. Index 0 is the primary.
+	From, To int
+	// FromDirname and ToDirname are the names of the dirs.
+	FromDirname, ToDirname string
+	// Reason describes why the switch happened: "error" or "high latency" for
+	// failovers, "failback" when a preceding dir is healthy again, or "forced"
+	// for a switch requested through Manager.SetForcedDir.
+	Reason string
 }
 
 // CreateInfo contains info about a log file creation event.
@@ -253,7 +287,7 @@ type CreateInfo struct {
 	// Path to the file. This includes the NumWAL, and implicitly or explicitly
 	// includes the logNameIndex.
 	Path string
-	// IsSecondary is true if the file was created on the secondary.
+	// IsSecondary is true if the file was created on a secondary.
 	IsSecondary bool
 	// Num is the WAL number.
 	Num NumWAL
@@ -306,8 +340,16 @@ type FailoverStats struct {
 	// using the primary directory.
 	PrimaryWriteDuration time.Duration
 	// SecondaryWriteDuration is the cumulative duration for which WAL writes
-	// are using the secondary directory.
+	// are using any of the secondary directories.
 	SecondaryWriteDuration time.Duration
+	// ActiveDirIndex is the index, in Options.Dirs(), of the directory WAL
+	// writes are using. Index 0 is the primary.
+	ActiveDirIndex int
+	// Forced is true if WAL writes are forced to the active directory through
+	// Manager.SetForcedDir, suspending automatic failover and failback.
+	Forced bool
+	// DirFsyncLatencies is Options.DirFsyncLatencies.
+	DirFsyncLatencies []prometheus.Histogram
 
 	// FailoverWriteAndSyncLatency measures the latency of writing and syncing a
 	// set of writes that were synced together. Each sample represents the
@@ -348,6 +390,12 @@ type Manager interface {
 	// use a high write stall threshold because the WALs are being written to
 	// the secondary dir.
 	ElevateWriteStallThresholdForFailover() bool
+	// SetForcedDir forces WAL writes to the dir with the given index in
+	// Options.Dirs(), suspending automatic failover and failback until
+	// SetForcedDir is called with a negative index. The switch is
+	// asynchronous, as with automatic failover. SetForcedDir returns an error
+	// if failover is not configured.
+	SetForcedDir(index int) error
 	// Stats returns the latest Stats.
 	Stats() Stats
 	// Close the manager.
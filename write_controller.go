@@ -0,0 +1,333 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/crlib/crtime"
+)
+
+// LSMHealth holds the signals about the health of the LSM that a
+// WriteController bases its decisions on.
+type LSMHealth struct {
+	// L0Sublevels is the number of L0 sublevels, i.e. the read amplification
+	// of L0.
+	L0Sublevels int
+	// L0Files is the number of tables in L0.
+	L0Files int
+	// MemTableCount is the number of queued memtables, including the mutable
+	// memtable.
+	MemTableCount int
+	// MemTableBytes is the total size of the queued memtables.
+	MemTableBytes uint64
+	// CompactionDebt is an estimate of the number of bytes that need to be
+	// compacted for the LSM to reach a stable state.
+	CompactionDebt uint64
+}
+
+// WriteStallReason is the reason for which a WriteController stalls writes.
+type WriteStallReason int8
+
+const (
+	// WriteStallNone indicates that writes are not stalled.
+	WriteStallNone WriteStallReason = iota
+	// WriteStallMemTable indicates that writes are stalled because too many
+	// memtables are queued for flushing.
+	WriteStallMemTable
+	// WriteStallL0 indicates that writes are stalled because the read
+	// amplification of L0 is too high.
+	WriteStallL0
+	// WriteStallOther indicates that writes are stalled for a reason specific
+	// to the WriteController.
+	WriteStallOther
+)
+
+// String implements fmt.Stringer. The string is used as the reason of
+// WriteStallBeginInfo.
+func (r WriteStallReason) String() string {
+	switch r {
+	case WriteStallNone:
+		return "none"
+	case WriteStallMemTable:
+		return "memtable count limit reached"
+	case WriteStallL0:
+		return "L0 file count limit exceeded"
+	case WriteStallOther:
+		return "write controller stall"
+	default:
+		return fmt.Sprintf("WriteStallReason(%d)", int8(r))
+	}
+}
+
+// A WriteController decides whether and when writes are admitted, based on the
+// health of the LSM. It allows integrating Pebble's backpressure with
+// application-level admission control. See Options.Experimental.WriteController.
+type WriteController interface {
+	// ShouldStall is called before the mutable memtable is rotated, with the
+	// current health of the LSM. If it returns a reason other than
+	// WriteStallNone, writes are stalled until the next flush or compaction
+	// completes, at which point ShouldStall is called again.
+	//
+	// ShouldStall is called with DB.mu held, and must not block or call into
+	// the DB.
+	ShouldStall(health LSMHealth) WriteStallReason
+
+	// BatchDelay is called before each batch of the given size is committed,
+	// with the health of the LSM as of the last flush, compaction or memtable
+	// rotation. The batch is delayed by the returned duration.
+	//
+	// BatchDelay is called without any DB locks held.
+	BatchDelay(health LSMHealth, batchSize int) time.Duration
+}
+
+// defaultWriteController implements Pebble's built-in write stall heuristics:
+// writes are stalled if the memtables reach Options.MemTableStopWritesThreshold,
+// or if the read amplification of L0 reaches Options.L0StopWritesThreshold.
+// Batches are never delayed.
+type defaultWriteController struct {
+	d *DB
+}
+
+var _ WriteController = defaultWriteController{}
+
+// ShouldStall is part of the WriteController interface.
+func (c defaultWriteController) ShouldStall(health LSMHealth) WriteStallReason {
+	d := c.d
+	// If ElevateWriteStallThresholdForFailover is true, we give an unlimited
+	// memory budget for memtables. This is simpler than trying to configure an
+	// explicit value, given that memory resources can vary. When using WAL
+	// failover in CockroachDB, an OOM risk is worth tolerating for workloads
+	// that have a strict latency SLO. Also, an unlimited budget here does not
+	// mean that the disk stall in the primary will go unnoticed until the OOM
+	// -- CockroachDB is monitoring disk stalls, and we expect it to fail the
+	// node after ~60s if the primary is stalled.
+	if health.MemTableBytes >= uint64(d.opts.MemTableStopWritesThreshold)*d.opts.MemTableSize &&
+		!d.mu.log.manager.ElevateWriteStallThresholdForFailover() {
+		return WriteStallMemTable
+	}
+	if health.L0Sublevels >= d.opts.L0StopWritesThreshold {
+		return WriteStallL0
+	}
+	return WriteStallNone
+}
+
+// BatchDelay is part of the WriteController interface.
+func (c defaultWriteController) BatchDelay(LSMHealth, int) time.Duration {
+	return 0
+}
+
+// lsmHealthLocked returns the current health of the LSM.
+//
+// d.mu must be held when calling this.
+func (d *DB) lsmHealthLocked() LSMHealth {
+	h := LSMHealth{
+		L0Sublevels:   d.mu.versions.l0Organizer.ReadAmplification(),
+		MemTableCount: len(d.mu.mem.queue),
+	}
+	if v := d.mu.versions.currentVersion(); v != nil {
+		h.L0Files = v.Levels[0].Len()
+	}
+	for i := range d.mu.mem.queue {
+		h.MemTableBytes += d.mu.mem.queue[i].totalBytes()
+	}
+	if p := d.mu.versions.picker; p != nil {
+		h.CompactionDebt = p.estimatedCompactionDebt(0)
+	}
+	return h
+}
+
+// maybeDelayBatch consults the WriteController about delaying the given batch,
+// and sleeps for the returned delay.
+func (d *DB) maybeDelayBatch(b *Batch) time.Duration {
+	h := d.lsmHealth.Load()
+	if h == nil {
+		return 0
+	}
+	delay := d.writeController.BatchDelay(*h, len(b.data))
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return delay
+}
+
+// WriteCapacityHint holds signals about the headroom the DB has to absorb
+// writes before it stalls them. It allows host-level admission control to
+// throttle producers before Pebble resorts to write stalls. See
+// DB.WriteCapacityHint.
+type WriteCapacityHint struct {
+	LSMHealth
+	// MemTableFill is the size of the queued memtables relative to the size at
+	// which writes are stalled (Options.MemTableStopWritesThreshold memtables
+	// of Options.MemTableSize). Writes are stalled once it reaches 1.
+	MemTableFill float64
+	// L0Fill is the number of L0 sublevels relative to the number at which
+	// writes are stalled (Options.L0StopWritesThreshold). Writes are stalled
+	// once it reaches 1.
+	L0Fill float64
+	// Headroom summarizes MemTableFill and L0Fill: it is 1 minus the larger of
+	// the two, clamped to [0, 1]. Zero indicates that writes are stalled or
+	// about to be.
+	Headroom float64
+	// EstimatedSustainableWriteRate is an estimate of the rate, in bytes per
+	// second, of incoming writes that flushes and compactions can keep up
+	// with. It is derived from the throughput of flushes and compactions while
+	// they run, the maximum compaction concurrency, and the windowed write
+	// amplification of flushes and compactions (see WindowedAmpMetrics). It is
+	// zero if there isn't enough history to estimate it.
+	EstimatedSustainableWriteRate float64
+}
+
+// WriteCapacityHint returns the current headroom of the DB to absorb writes.
+// Note that the signals are based on Pebble's built-in write stall heuristics,
+// and don't reflect the decisions of a custom WriteController (see
+// Options.Experimental.WriteController).
+func (d *DB) WriteCapacityHint() WriteCapacityHint {
+	if err := d.closed.Load(); err != nil {
+		panic(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h := WriteCapacityHint{LSMHealth: d.lsmHealthLocked()}
+	h.MemTableFill = float64(h.MemTableBytes) /
+		float64(uint64(d.opts.MemTableStopWritesThreshold)*d.opts.MemTableSize)
+	h.L0Fill = float64(h.L0Sublevels) / float64(d.opts.L0StopWritesThreshold)
+	h.Headroom = min(1, max(0, 1-max(h.MemTableFill, h.L0Fill)))
+	h.EstimatedSustainableWriteRate = d.estimatedSustainableWriteRateLocked()
+	return h
+}
+
+// estimatedSustainableWriteRateLocked estimates the rate of incoming writes, in
+// bytes per second, that flushes and compactions can keep up with. Each
+// incoming byte requires flushAmp bytes to be flushed and compAmp bytes to be
+// compacted, as observed during the amplification window. The rate is limited
+// by whichever of flushes and compactions (running at their peak rate, with
+// the maximum concurrency) saturates first.
+//
+// d.mu must be held when calling this.
+func (d *DB) estimatedSustainableWriteRateLocked() float64 {
+	w := d.mu.ampWindow.metrics()
+	flushPeak := float64(d.mu.compact.flushWriteThroughput.PeakRate())
+	flushAmp := w.WriteAmpByKind[compactionKindFlush.String()]
+	if flushPeak <= 0 || flushAmp <= 0 {
+		return 0
+	}
+	rate := flushPeak / flushAmp
+	var compAmp float64
+	for kind, amp := range w.WriteAmpByKind {
+		if kind != compactionKindFlush.String() {
+			compAmp += amp
+		}
+	}
+	var bytesCompacted uint64
+	for i := range d.mu.versions.metrics.Levels {
+		bytesCompacted += d.mu.versions.metrics.Levels[i].BytesCompacted
+	}
+	if compAmp > 0 && bytesCompacted > 0 && d.mu.compact.duration > 0 {
+		compactPeak := float64(bytesCompacted) / d.mu.compact.duration.Seconds()
+		rate = min(rate, compactPeak*float64(d.opts.MaxConcurrentCompactions())/compAmp)
+	}
+	return rate
+}
+
+// BatchPriority is the priority of a batch in the commit pipeline. While
+// writes are stalled, batches are admitted into the commit pipeline in order of
+// priority, and low priority batches are held back before writes are stalled,
+// so that foreground traffic keeps flowing at the expense of background
+// traffic. See Batch.SetPriority.
+type BatchPriority int8
+
+const (
+	// BatchPriorityNormal is the default priority. Normal priority batches are
+	// held back while writes are stalled.
+	BatchPriorityNormal BatchPriority = iota
+	// BatchPriorityLow is intended for background traffic (e.g. reindexing).
+	// Low priority batches are held back while writes are stalled, and while a
+	// write stall is imminent: that is, while a flush or compaction is pending
+	// and the WriteController would stall writes once one more memtable is
+	// queued and one more L0 sublevel is added.
+	BatchPriorityLow
+	// BatchPriorityHigh batches are never held back before entering the commit
+	// pipeline, while normal and low priority batches wait outside of it.
+	// Within the pipeline they're treated like any other batch: they're
+	// committed after the batches that entered it before them, and they're
+	// subject to the pipeline's own stalls (e.g. waiting for a memtable to be
+	// flushed when too many are queued).
+	BatchPriorityHigh
+)
+
+// String implements fmt.Stringer.
+func (p BatchPriority) String() string {
+	switch p {
+	case BatchPriorityNormal:
+		return "normal"
+	case BatchPriorityLow:
+		return "low"
+	case BatchPriorityHigh:
+		return "high"
+	default:
+		return fmt.Sprintf("BatchPriority(%d)", int8(p))
+	}
+}
+
+// setWriteStalledLocked records whether a write stall is in progress.
+//
+// d.mu must be held when calling this.
+func (d *DB) setWriteStalledLocked(stalled bool) {
+	d.writeAdmission.Lock()
+	defer d.writeAdmission.Unlock()
+	d.writeAdmission.stalled = stalled
+	if !stalled {
+		d.writeAdmission.cond.Broadcast()
+	}
+}
+
+// updateLowPriorityStallLocked determines whether a write stall is imminent,
+// in which case low priority batches are held back.
+//
+// d.mu must be held when calling this.
+func (d *DB) updateLowPriorityStallLocked(h LSMHealth) {
+	// Only consider a stall imminent while a flush or compaction is pending,
+	// since this is reevaluated when it completes. Otherwise low priority
+	// batches could be held back indefinitely.
+	stall := false
+	if len(d.mu.mem.queue) > 1 || d.mu.compact.compactingCount > 0 {
+		h.MemTableCount++
+		h.MemTableBytes += d.opts.MemTableSize
+		h.L0Sublevels++
+		reason := d.writeController.ShouldStall(h)
+		stall = reason != WriteStallNone &&
+			(!d.mu.compact.bulkLoad || reason == WriteStallMemTable)
+	}
+	d.writeAdmission.Lock()
+	defer d.writeAdmission.Unlock()
+	d.writeAdmission.lowPriorityStalled = stall
+	if !stall {
+		d.writeAdmission.cond.Broadcast()
+	}
+}
+
+// waitForWriteAdmission waits until the given batch can enter the commit
+// pipeline given its priority, and returns the time spent waiting.
+func (d *DB) waitForWriteAdmission(b *Batch) time.Duration {
+	if b.priority == BatchPriorityHigh {
+		return 0
+	}
+	d.writeAdmission.Lock()
+	defer d.writeAdmission.Unlock()
+	held := func() bool {
+		return d.writeAdmission.stalled ||
+			(b.priority == BatchPriorityLow && d.writeAdmission.lowPriorityStalled)
+	}
+	if !held() {
+		return 0
+	}
+	start := crtime.NowMono()
+	for held() {
+		d.writeAdmission.cond.Wait()
+	}
+	return start.Elapsed()
+}
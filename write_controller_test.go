@@ -0,0 +1,179 @@
+// Copyright 2025 The LevelDB-Go and Pebble Authors. All rights reserved. Use
+// of this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+
+package pebble
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+type testWriteController struct {
+	stall      atomic.Bool
+	delay      atomic.Int64
+	lastHealth atomic.Pointer[LSMHealth]
+}
+
+func (c *testWriteController) ShouldStall(health LSMHealth) WriteStallReason {
+	if c.stall.Load() {
+		return WriteStallOther
+	}
+	return WriteStallNone
+}
+
+func (c *testWriteController) BatchDelay(health LSMHealth, batchSize int) time.Duration {
+	c.lastHealth.Store(&health)
+	return time.Duration(c.delay.Load())
+}
+
+func TestWriteController(t *testing.T) {
+	wc := &testWriteController{}
+	stallBegin := make(chan string, 1)
+	opts := &Options{
+		FS: vfs.NewMem(),
+		EventListener: &EventListener{
+			WriteStallBegin: func(info WriteStallBeginInfo) { stallBegin <- info.Reason },
+		},
+	}
+	opts.Experimental.WriteController = wc
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	// Batches are delayed as dictated by the controller.
+	wc.delay.Store(int64(10 * time.Millisecond))
+	b := d.NewBatch()
+	require.NoError(t, b.Set([]byte("a"), []byte("a"), nil))
+	require.NoError(t, b.Commit(nil))
+	require.GreaterOrEqual(t, b.CommitStats().WriteControllerWaitDuration, 10*time.Millisecond)
+	require.NoError(t, b.Close())
+	require.Equal(t, 1, wc.lastHealth.Load().MemTableCount)
+	wc.delay.Store(0)
+
+	// Memtable rotations are stalled as dictated by the controller.
+	wc.stall.Store(true)
+	flushed := make(chan error, 1)
+	go func() { flushed <- d.Flush() }()
+	require.Equal(t, WriteStallOther.String(), <-stallBegin)
+	select {
+	case <-flushed:
+		t.Fatal("flush completed while writes are stalled")
+	case <-time.After(10 * time.Millisecond):
+	}
+	wc.stall.Store(false)
+	d.mu.Lock()
+	d.mu.compact.cond.Broadcast()
+	d.mu.Unlock()
+	require.NoError(t, <-flushed)
+}
+
+func TestBatchPriority(t *testing.T) {
+	wc := &testWriteController{}
+	stallBegin := make(chan struct{}, 1)
+	opts := &Options{
+		FS: vfs.NewMem(),
+		EventListener: &EventListener{
+			WriteStallBegin: func(WriteStallBeginInfo) { stallBegin <- struct{}{} },
+		},
+	}
+	opts.Experimental.WriteController = wc
+	d, err := Open("", opts)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	commit := func(key string, p BatchPriority) <-chan *Batch {
+		ch := make(chan *Batch, 1)
+		go func() {
+			b := d.NewBatch()
+			b.SetPriority(p)
+			require.NoError(t, b.Set([]byte(key), nil, nil))
+			require.NoError(t, b.Commit(nil))
+			ch <- b
+		}()
+		return ch
+	}
+	requireBlocked := func(ch <-chan *Batch) {
+		select {
+		case <-ch:
+			t.Fatal("batch committed while it should be held back")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// While writes are stalled, normal priority batches are held back before
+	// entering the commit pipeline.
+	wc.stall.Store(true)
+	flushed := make(chan error, 1)
+	go func() { flushed <- d.Flush() }()
+	<-stallBegin
+	normal := commit("a", BatchPriorityNormal)
+	high := commit("b", BatchPriorityHigh)
+	requireBlocked(normal)
+	wc.stall.Store(false)
+	d.mu.Lock()
+	d.mu.compact.cond.Broadcast()
+	d.mu.Unlock()
+	require.NoError(t, <-flushed)
+	b := <-normal
+	require.Greater(t, b.CommitStats().PriorityWaitDuration, time.Duration(0))
+	require.NoError(t, b.Close())
+	b = <-high
+	require.Zero(t, b.CommitStats().PriorityWaitDuration)
+	require.NoError(t, b.Close())
+
+	// While a write stall is imminent, only low priority batches are held back.
+	d.writeAdmission.Lock()
+	d.writeAdmission.lowPriorityStalled = true
+	d.writeAdmission.Unlock()
+	low := commit("c", BatchPriorityLow)
+	b = <-commit("d", BatchPriorityNormal)
+	require.NoError(t, b.Close())
+	requireBlocked(low)
+	d.mu.Lock()
+	d.updateLowPriorityStallLocked(d.lsmHealthLocked())
+	d.mu.Unlock()
+	b = <-low
+	require.Greater(t, b.CommitStats().PriorityWaitDuration, time.Duration(0))
+	require.NoError(t, b.Close())
+}
+
+func TestWriteCapacityHint(t *testing.T) {
+	d, err := Open("", &Options{
+		FS:                          vfs.NewMem(),
+		DisableAutomaticCompactions: true,
+		L0StopWritesThreshold:       4,
+	})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, d.Close()) }()
+
+	h := d.WriteCapacityHint()
+	require.Equal(t, 0, h.L0Sublevels)
+	require.Zero(t, h.L0Fill)
+	require.Equal(t, 1-h.MemTableFill, h.Headroom)
+	require.Zero(t, h.EstimatedSustainableWriteRate)
+
+	// Each flush of overlapping keys adds an L0 sublevel.
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 100; j++ {
+			require.NoError(t, d.Set([]byte(fmt.Sprintf("k%03d", j)), make([]byte, 100), nil))
+		}
+		require.NoError(t, d.Flush())
+	}
+	h = d.WriteCapacityHint()
+	require.Equal(t, 2, h.L0Sublevels)
+	require.Equal(t, 0.5, h.L0Fill)
+	require.Equal(t, 0.5, h.Headroom)
+	require.Greater(t, h.EstimatedSustainableWriteRate, 0.0)
+
+	require.NoError(t, d.Compact([]byte("k"), []byte("l"), false /* parallelize */))
+	h = d.WriteCapacityHint()
+	require.Equal(t, 0, h.L0Sublevels)
+	require.Greater(t, h.Headroom, 0.5)
+	require.Greater(t, h.EstimatedSustainableWriteRate, 0.0)
+}